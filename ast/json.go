@@ -0,0 +1,350 @@
+package ast
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// jsonNode is the JSON form of every node type; each uses the fields
+// MarshalJSON lists for it.
+type jsonNode struct {
+	Kind       string          `json:"kind"`
+	Name       string          `json:"name,omitempty"`
+	Scope      string          `json:"scope,omitempty"`
+	Op         string          `json:"op,omitempty"`
+	Value      json.RawMessage `json:"value,omitempty"`
+	Attr       string          `json:"attr,omitempty"`
+	Secs       json.Number     `json:"secs,omitempty"`
+	Offset     int             `json:"offset,omitempty"`
+	Left       *jsonNode       `json:"left,omitempty"`
+	Right      *jsonNode       `json:"right,omitempty"`
+	Expr       *jsonNode       `json:"expr,omitempty"`
+	Condition  *jsonNode       `json:"condition,omitempty"`
+	True       *jsonNode       `json:"true,omitempty"`
+	False      *jsonNode       `json:"false,omitempty"`
+	Record     *jsonNode       `json:"record,omitempty"`
+	Container  *jsonNode       `json:"container,omitempty"`
+	Index      *jsonNode       `json:"index,omitempty"`
+	Inner      *jsonNode       `json:"inner,omitempty"`
+	Elements   []*jsonNode     `json:"elements,omitempty"`
+	Args       []*jsonNode     `json:"args,omitempty"`
+	Attributes []*jsonNode     `json:"attributes,omitempty"`
+}
+
+var scopeNames = map[AttributeScope]string{MyScope: "MY", TargetScope: "TARGET", ParentScope: "PARENT"}
+
+// MarshalJSON encodes the tree rooted at n as JSON, for tools outside Go.
+// Each node is an object whose "kind" is its Go type name, with these fields
+// (a list or child that is empty or nil is left out, as is an unscoped
+// reference's scope):
+//
+//	ClassAd, RecordLiteral   attributes: [AttributeAssignment...]
+//	AttributeAssignment      name, value: node
+//	IntegerLiteral           value: number
+//	RealLiteral              value: number, or "NaN", "+Inf", "-Inf"
+//	StringLiteral            value: string
+//	BooleanLiteral           value: bool
+//	UndefinedLiteral, ErrorLiteral
+//	AttributeReference       name, scope: "MY", "TARGET" or "PARENT"
+//	BinaryOp                 op, left, right
+//	UnaryOp                  op, expr
+//	ListLiteral              elements
+//	FunctionCall             name, args
+//	ConditionalExpr          condition, true, false
+//	ElvisExpr                left, right
+//	SelectExpr               record, attr
+//	SubscriptExpr            container, index
+//	ParenExpr                inner
+//	AbsTimeLiteral           secs, offset (seconds east of UTC)
+//	RelTimeLiteral           secs
+//
+// For example, MY.Cpus >= 4 is
+//
+//	{"kind":"BinaryOp","op":">=","left":{"kind":"AttributeReference","name":"Cpus","scope":"MY"},"right":{"kind":"IntegerLiteral","value":4}}
+//
+// The encoding is stable: the same tree always encodes to the same bytes, and
+// UnmarshalJSON reads it back.
+func MarshalJSON(n Node) ([]byte, error) {
+	if n == nil {
+		return []byte("null"), nil
+	}
+	j, err := toJSON(n)
+	if err != nil {
+		return nil, err
+	}
+	return marshal(j)
+}
+
+// marshal is json.Marshal without the escaping of '<', '>' and '&' for HTML,
+// which would garble every comparison operator.
+func marshal(v any) ([]byte, error) {
+	var b bytes.Buffer
+	enc := json.NewEncoder(&b)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+	return bytes.TrimSuffix(b.Bytes(), []byte("\n")), nil
+}
+
+func toJSON(n Node) (*jsonNode, error) {
+	var err error
+	child := func(c Node) *jsonNode {
+		if c == nil || err != nil {
+			return nil
+		}
+		var j *jsonNode
+		j, err = toJSON(c)
+		return j
+	}
+	children := func(cs []Expr) []*jsonNode {
+		var out []*jsonNode
+		for _, c := range cs {
+			out = append(out, child(c))
+		}
+		return out
+	}
+	attrs := func(ad *ClassAd) []*jsonNode {
+		var out []*jsonNode
+		if ad != nil {
+			for _, a := range ad.Attributes {
+				out = append(out, child(a))
+			}
+		}
+		return out
+	}
+	value := func(v any) json.RawMessage {
+		b, merr := marshal(v)
+		if merr != nil && err == nil {
+			err = merr
+		}
+		return b
+	}
+
+	j := &jsonNode{Kind: strings.TrimPrefix(fmt.Sprintf("%T", n), "*ast.")}
+	switch n := n.(type) {
+	case *ClassAd:
+		j.Attributes = attrs(n)
+	case *RecordLiteral:
+		j.Attributes = attrs(n.ClassAd)
+	case *AttributeAssignment:
+		j.Name = n.Name
+		if c := child(n.Value); c != nil {
+			j.Value = value(c)
+		}
+	case *IntegerLiteral:
+		j.Value = value(n.Value)
+	case *RealLiteral:
+		switch {
+		case math.IsNaN(n.Value):
+			j.Value = value("NaN")
+		case math.IsInf(n.Value, 0):
+			j.Value = value(strconv.FormatFloat(n.Value, 'g', -1, 64))
+		default:
+			j.Value = value(n.Value)
+		}
+	case *StringLiteral:
+		j.Value = value(n.Value)
+	case *BooleanLiteral:
+		j.Value = value(n.Value)
+	case *UndefinedLiteral, *ErrorLiteral:
+	case *AttributeReference:
+		j.Name, j.Scope = n.Name, scopeNames[n.Scope]
+	case *BinaryOp:
+		j.Op, j.Left, j.Right = n.Op, child(n.Left), child(n.Right)
+	case *UnaryOp:
+		j.Op, j.Expr = n.Op, child(n.Expr)
+	case *ListLiteral:
+		j.Elements = children(n.Elements)
+	case *FunctionCall:
+		j.Name, j.Args = n.Name, children(n.Args)
+	case *ConditionalExpr:
+		j.Condition, j.True, j.False = child(n.Condition), child(n.TrueExpr), child(n.FalseExpr)
+	case *ElvisExpr:
+		j.Left, j.Right = child(n.Left), child(n.Right)
+	case *SelectExpr:
+		j.Record, j.Attr = child(n.Record), n.Attr
+	case *SubscriptExpr:
+		j.Container, j.Index = child(n.Container), child(n.Index)
+	case *ParenExpr:
+		j.Inner = child(n.Inner)
+	case *AbsTimeLiteral:
+		j.Secs, j.Offset = json.Number(strconv.FormatInt(n.Secs, 10)), n.Offset
+	case *RelTimeLiteral:
+		j.Secs = json.Number(strconv.FormatFloat(n.Secs, 'g', -1, 64))
+	default:
+		return nil, fmt.Errorf("ast: cannot encode node type %T", n)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return j, nil
+}
+
+// UnmarshalJSON decodes a tree MarshalJSON encoded. It returns an error for
+// an unknown kind or a node missing a child it needs.
+func UnmarshalJSON(data []byte) (Node, error) {
+	if bytes.Equal(bytes.TrimSpace(data), []byte("null")) {
+		return nil, nil
+	}
+	var j jsonNode
+	if err := json.Unmarshal(data, &j); err != nil {
+		return nil, fmt.Errorf("ast: %w", err)
+	}
+	return fromJSON(&j)
+}
+
+func fromJSON(j *jsonNode) (Node, error) {
+	var err error
+	fail := func(format string, args ...any) {
+		if err == nil {
+			err = fmt.Errorf("ast: %s: "+format, append([]any{j.Kind}, args...)...)
+		}
+	}
+	expr := func(c *jsonNode, field string) Expr {
+		if c == nil {
+			fail("missing %s", field)
+			return nil
+		}
+		if err != nil {
+			return nil
+		}
+		n, cerr := fromJSON(c)
+		if cerr != nil {
+			err = cerr
+			return nil
+		}
+		e, ok := n.(Expr)
+		if !ok {
+			fail("%s is a %s, not an expression", field, c.Kind)
+		}
+		return e
+	}
+	exprs := func(cs []*jsonNode, field string) []Expr {
+		out := make([]Expr, 0, len(cs))
+		for _, c := range cs {
+			out = append(out, expr(c, field))
+		}
+		return out
+	}
+	classAd := func() *ClassAd {
+		ad := &ClassAd{Attributes: make([]*AttributeAssignment, 0, len(j.Attributes))}
+		for _, c := range j.Attributes {
+			if c == nil || c.Kind != "AttributeAssignment" {
+				fail("attributes holds something other than an AttributeAssignment")
+				return nil
+			}
+			n, cerr := fromJSON(c)
+			if cerr != nil {
+				err = cerr
+				return nil
+			}
+			ad.Attributes = append(ad.Attributes, n.(*AttributeAssignment))
+		}
+		return ad
+	}
+	literal := func(v any) {
+		if len(j.Value) == 0 {
+			fail("missing value")
+		} else if uerr := json.Unmarshal(j.Value, v); uerr != nil {
+			fail("%v", uerr)
+		}
+	}
+
+	var n Node
+	switch j.Kind {
+	case "ClassAd":
+		n = classAd()
+	case "RecordLiteral":
+		n = &RecordLiteral{ClassAd: classAd()}
+	case "AttributeAssignment":
+		var v *jsonNode
+		if len(j.Value) > 0 {
+			literal(&v)
+		}
+		n = &AttributeAssignment{Name: j.Name, Value: expr(v, "value")}
+	case "IntegerLiteral":
+		l := &IntegerLiteral{}
+		literal(&l.Value)
+		n = l
+	case "RealLiteral":
+		l := &RealLiteral{}
+		var s string
+		if json.Unmarshal(j.Value, &s) == nil {
+			v, perr := strconv.ParseFloat(s, 64)
+			if perr != nil {
+				fail("bad value %q", s)
+			}
+			l.Value = v
+		} else {
+			literal(&l.Value)
+		}
+		n = l
+	case "StringLiteral":
+		l := &StringLiteral{}
+		literal(&l.Value)
+		n = l
+	case "BooleanLiteral":
+		l := &BooleanLiteral{}
+		literal(&l.Value)
+		n = l
+	case "UndefinedLiteral":
+		n = &UndefinedLiteral{}
+	case "ErrorLiteral":
+		n = &ErrorLiteral{}
+	case "AttributeReference":
+		scope := NoScope
+		if j.Scope != "" {
+			scope = -1
+			for s, name := range scopeNames {
+				if name == j.Scope {
+					scope = s
+				}
+			}
+			if scope < 0 {
+				fail("unknown scope %q", j.Scope)
+			}
+		}
+		n = NewAttributeReference(j.Name, scope)
+	case "BinaryOp":
+		n = &BinaryOp{Op: j.Op, Left: expr(j.Left, "left"), Right: expr(j.Right, "right")}
+	case "UnaryOp":
+		n = &UnaryOp{Op: j.Op, Expr: expr(j.Expr, "expr")}
+	case "ListLiteral":
+		n = &ListLiteral{Elements: exprs(j.Elements, "elements")}
+	case "FunctionCall":
+		n = &FunctionCall{Name: j.Name, Args: exprs(j.Args, "args")}
+	case "ConditionalExpr":
+		n = &ConditionalExpr{Condition: expr(j.Condition, "condition"), TrueExpr: expr(j.True, "true"), FalseExpr: expr(j.False, "false")}
+	case "ElvisExpr":
+		n = &ElvisExpr{Left: expr(j.Left, "left"), Right: expr(j.Right, "right")}
+	case "SelectExpr":
+		n = &SelectExpr{Record: expr(j.Record, "record"), Attr: j.Attr}
+	case "SubscriptExpr":
+		n = &SubscriptExpr{Container: expr(j.Container, "container"), Index: expr(j.Index, "index")}
+	case "ParenExpr":
+		n = &ParenExpr{Inner: expr(j.Inner, "inner")}
+	case "AbsTimeLiteral":
+		secs, perr := j.Secs.Int64()
+		if perr != nil {
+			fail("bad secs %q", j.Secs)
+		}
+		n = &AbsTimeLiteral{Secs: secs, Offset: j.Offset}
+	case "RelTimeLiteral":
+		secs, perr := j.Secs.Float64()
+		if perr != nil {
+			fail("bad secs %q", j.Secs)
+		}
+		n = &RelTimeLiteral{Secs: secs}
+	default:
+		return nil, fmt.Errorf("ast: unknown node kind %q", j.Kind)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return n, nil
+}
@@ -0,0 +1,52 @@
+package ast
+
+import "fmt"
+
+// Pos is a location in ClassAd source text: a 1-based line and column (the
+// column counts runes, as the parser's error messages do) and the 0-based byte
+// offset from the start of the input.
+type Pos struct {
+	Line   int
+	Col    int
+	Offset int
+}
+
+func (p Pos) String() string {
+	return fmt.Sprintf("%d:%d", p.Line, p.Col)
+}
+
+// IsValid reports whether p was set (a zero Pos is not a location).
+func (p Pos) IsValid() bool {
+	return p.Line > 0
+}
+
+// Span is the source range a node was parsed from, from Start up to (not
+// including) End.
+type Span struct {
+	Start Pos
+	End   Pos
+}
+
+func (s Span) String() string {
+	return s.Start.String() + "-" + s.End.String()
+}
+
+// Positions maps the nodes of a parsed tree to their source spans. Nodes carry
+// no position themselves -- most parses never need one, and the hot parse paths
+// stay allocation-light -- so a caller that does (a linter, an editor, an error
+// that underlines a sub-expression) asks the parser for this side table
+// (parser.ParseWithPositions, parser.ParseExprWithPositions). A node built or
+// rewritten after parsing has no entry.
+type Positions map[Node]Span
+
+// Span returns the source span of n.
+func (p Positions) Span(n Node) (Span, bool) {
+	s, ok := p[n]
+	return s, ok
+}
+
+// Pos returns the start position of n.
+func (p Positions) Pos(n Node) (Pos, bool) {
+	s, ok := p[n]
+	return s.Start, ok
+}
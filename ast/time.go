@@ -0,0 +1,74 @@
+package ast
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+)
+
+// AbsTimeLiteral represents an absolute-time constant: Unix seconds plus the UTC
+// offset (seconds east of UTC) the time is expressed in. The grammar has no
+// time-literal syntax -- source spells one absTime("...") -- so the parser never
+// produces this node; flattening, decoding and programmatic construction do. It
+// prints as the absTime() call that re-creates it, like the reference unparser.
+type AbsTimeLiteral struct {
+	Secs   int64
+	Offset int
+}
+
+func (a *AbsTimeLiteral) String() string {
+	return "absTime(" + QuoteString(FormatAbsTime(a.Secs, a.Offset)) + ")"
+}
+
+func (a *AbsTimeLiteral) exprNode() {}
+
+// RelTimeLiteral represents a relative-time (interval) constant in seconds. Like
+// AbsTimeLiteral it has no source syntax and prints as relTime("...").
+type RelTimeLiteral struct {
+	Secs float64
+}
+
+func (r *RelTimeLiteral) String() string {
+	return "relTime(" + QuoteString(FormatRelTime(r.Secs)) + ")"
+}
+
+func (r *RelTimeLiteral) exprNode() {}
+
+// FormatAbsTime renders an absolute time as the reference engine does,
+// "YYYY-MM-DDThh:mm:ss+hh:mm", with the wall-clock fields taken in the given
+// offset.
+func FormatAbsTime(secs int64, offset int) string {
+	t := time.Unix(secs, 0).In(time.FixedZone("", offset))
+	sign := byte('+')
+	if offset < 0 {
+		sign = '-'
+		offset = -offset
+	}
+	return fmt.Sprintf("%s%c%02d:%02d", t.Format("2006-01-02T15:04:05"), sign, offset/3600, offset%3600/60)
+}
+
+// FormatRelTime renders a relative time as the reference engine does,
+// "[-][D+]hh:mm:ss": the day count only when non-zero, and any fractional
+// seconds appended to the seconds field ("00:00:01.5").
+func FormatRelTime(secs float64) string {
+	sign := ""
+	if secs < 0 {
+		sign = "-"
+		secs = -secs
+	}
+	whole := math.Floor(secs)
+	frac := secs - whole
+	total := int64(whole)
+	days := total / 86400
+	hrs := total % 86400 / 3600
+	mins := total % 3600 / 60
+	sec := fmt.Sprintf("%02d", total%60)
+	if frac != 0 {
+		sec += strconv.FormatFloat(frac, 'f', -1, 64)[1:]
+	}
+	if days != 0 {
+		return fmt.Sprintf("%s%d+%02d:%02d:%s", sign, days, hrs, mins, sec)
+	}
+	return fmt.Sprintf("%s%02d:%02d:%s", sign, hrs, mins, sec)
+}
@@ -0,0 +1,228 @@
+package ast
+
+import "fmt"
+
+// A Visitor's Visit method is called by Walk for each node. If it returns a
+// non-nil Visitor w, Walk visits each of the node's children with w, then
+// calls w.Visit(nil).
+type Visitor interface {
+	Visit(node Node) (w Visitor)
+}
+
+// Walk traverses the tree rooted at node depth-first, in source order: it
+// calls v.Visit(node), then walks each non-nil child with the visitor that
+// returned. A RecordLiteral's child is its *ClassAd, whose children are its
+// *AttributeAssignments.
+//
+// Walk knows every node type of this package and panics on any other, so a
+// traversal built on it cannot silently skip part of a tree.
+func Walk(node Node, v Visitor) {
+	if node == nil {
+		return
+	}
+	if v = v.Visit(node); v == nil {
+		return
+	}
+	switch n := node.(type) {
+	case *ClassAd:
+		for _, a := range n.Attributes {
+			walkIf(a, v)
+		}
+	case *AttributeAssignment:
+		walkIf(n.Value, v)
+	case *BinaryOp:
+		walkIf(n.Left, v)
+		walkIf(n.Right, v)
+	case *UnaryOp:
+		walkIf(n.Expr, v)
+	case *ListLiteral:
+		for _, e := range n.Elements {
+			walkIf(e, v)
+		}
+	case *RecordLiteral:
+		walkIf(n.ClassAd, v)
+	case *FunctionCall:
+		for _, e := range n.Args {
+			walkIf(e, v)
+		}
+	case *ConditionalExpr:
+		walkIf(n.Condition, v)
+		walkIf(n.TrueExpr, v)
+		walkIf(n.FalseExpr, v)
+	case *ElvisExpr:
+		walkIf(n.Left, v)
+		walkIf(n.Right, v)
+	case *SelectExpr:
+		walkIf(n.Record, v)
+	case *SubscriptExpr:
+		walkIf(n.Container, v)
+		walkIf(n.Index, v)
+	case *ParenExpr:
+		walkIf(n.Inner, v)
+	case *IntegerLiteral, *RealLiteral, *StringLiteral, *BooleanLiteral,
+		*UndefinedLiteral, *ErrorLiteral, *AttributeReference,
+		*AbsTimeLiteral, *RelTimeLiteral:
+		// no children
+	default:
+		panic(fmt.Sprintf("ast.Walk: unexpected node type %T", node))
+	}
+	v.Visit(nil)
+}
+
+// walkIf walks n unless it is nil, a nil *ClassAd or *AttributeAssignment
+// included.
+func walkIf(n Node, v Visitor) {
+	switch n := n.(type) {
+	case nil:
+		return
+	case *ClassAd:
+		if n == nil {
+			return
+		}
+	case *AttributeAssignment:
+		if n == nil {
+			return
+		}
+	}
+	Walk(n, v)
+}
+
+type inspector func(Node) bool
+
+func (f inspector) Visit(node Node) Visitor {
+	if f(node) {
+		return f
+	}
+	return nil
+}
+
+// Inspect traverses the tree rooted at node as Walk does, calling f for each
+// node and then f(nil) after its children. If f returns false, the node's
+// children are skipped.
+//
+//	ast.Inspect(expr, func(n ast.Node) bool {
+//		if ref, ok := n.(*ast.AttributeReference); ok {
+//			names = append(names, ref.Name)
+//		}
+//		return true
+//	})
+func Inspect(node Node, f func(Node) bool) {
+	Walk(node, inspector(f))
+}
+
+// Rewrite returns expr with the sub-expressions fn replaces replaced. fn is
+// called top-down, on each expression before those below it, and returns
+// either a replacement, which Rewrite puts in the expression's place without
+// looking into it, or nil, to keep the expression and go on to its children.
+// The attribute values of a record are rewritten; fn is not called for the
+// assignments themselves.
+//
+// Rewrite never modifies its input. A node is copied only when something
+// below it was replaced, so the result shares every untouched subtree with
+// expr -- and is expr itself when fn replaces nothing.
+//
+// To rewrite bottom-up, or with context from above, fn calls Rewrite on the
+// children of the node it was given and builds the replacement from them.
+func Rewrite(expr Expr, fn func(Expr) Expr) Expr {
+	if expr == nil {
+		return nil
+	}
+	if r := fn(expr); r != nil {
+		return r
+	}
+	switch n := expr.(type) {
+	case *ClassAd:
+		if attrs, ok := rewriteAttrs(n.Attributes, fn); ok {
+			return &ClassAd{Attributes: attrs}
+		}
+	case *BinaryOp:
+		l, r := Rewrite(n.Left, fn), Rewrite(n.Right, fn)
+		if l != n.Left || r != n.Right {
+			return &BinaryOp{Op: n.Op, Left: l, Right: r}
+		}
+	case *UnaryOp:
+		if e := Rewrite(n.Expr, fn); e != n.Expr {
+			return &UnaryOp{Op: n.Op, Expr: e}
+		}
+	case *ListLiteral:
+		if elems, ok := rewriteList(n.Elements, fn); ok {
+			return &ListLiteral{Elements: elems}
+		}
+	case *RecordLiteral:
+		if n.ClassAd != nil {
+			if attrs, ok := rewriteAttrs(n.ClassAd.Attributes, fn); ok {
+				return &RecordLiteral{ClassAd: &ClassAd{Attributes: attrs}}
+			}
+		}
+	case *FunctionCall:
+		if args, ok := rewriteList(n.Args, fn); ok {
+			return &FunctionCall{Name: n.Name, Args: args}
+		}
+	case *ConditionalExpr:
+		c, t, f := Rewrite(n.Condition, fn), Rewrite(n.TrueExpr, fn), Rewrite(n.FalseExpr, fn)
+		if c != n.Condition || t != n.TrueExpr || f != n.FalseExpr {
+			return &ConditionalExpr{Condition: c, TrueExpr: t, FalseExpr: f}
+		}
+	case *ElvisExpr:
+		l, r := Rewrite(n.Left, fn), Rewrite(n.Right, fn)
+		if l != n.Left || r != n.Right {
+			return &ElvisExpr{Left: l, Right: r}
+		}
+	case *SelectExpr:
+		if r := Rewrite(n.Record, fn); r != n.Record {
+			return &SelectExpr{Record: r, Attr: n.Attr}
+		}
+	case *SubscriptExpr:
+		c, i := Rewrite(n.Container, fn), Rewrite(n.Index, fn)
+		if c != n.Container || i != n.Index {
+			return &SubscriptExpr{Container: c, Index: i}
+		}
+	case *ParenExpr:
+		if i := Rewrite(n.Inner, fn); i != n.Inner {
+			return &ParenExpr{Inner: i}
+		}
+	case *IntegerLiteral, *RealLiteral, *StringLiteral, *BooleanLiteral,
+		*UndefinedLiteral, *ErrorLiteral, *AttributeReference,
+		*AbsTimeLiteral, *RelTimeLiteral:
+		// no children
+	default:
+		panic(fmt.Sprintf("ast.Rewrite: unexpected node type %T", expr))
+	}
+	return expr
+}
+
+// rewriteList rewrites each of exprs, returning a new slice and true if any
+// changed.
+func rewriteList(exprs []Expr, fn func(Expr) Expr) ([]Expr, bool) {
+	var out []Expr
+	for i, e := range exprs {
+		r := Rewrite(e, fn)
+		if r != e && out == nil {
+			out = make([]Expr, len(exprs))
+			copy(out, exprs[:i])
+		}
+		if out != nil {
+			out[i] = r
+		}
+	}
+	return out, out != nil
+}
+
+// rewriteAttrs is rewriteList for the values of a record's assignments.
+func rewriteAttrs(attrs []*AttributeAssignment, fn func(Expr) Expr) ([]*AttributeAssignment, bool) {
+	var out []*AttributeAssignment
+	for i, a := range attrs {
+		r := Rewrite(a.Value, fn)
+		if r != a.Value && out == nil {
+			out = make([]*AttributeAssignment, len(attrs))
+			copy(out, attrs[:i])
+		}
+		if out != nil {
+			if r != a.Value {
+				a = &AttributeAssignment{Name: a.Name, Value: r}
+			}
+			out[i] = a
+		}
+	}
+	return out, out != nil
+}
@@ -0,0 +1,193 @@
+package ast
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// everyKind builds a tree holding a node of every type.
+func everyKind() *ClassAd {
+	return &ClassAd{Attributes: []*AttributeAssignment{
+		{Name: "a", Value: &BinaryOp{Op: "+", Left: &IntegerLiteral{Value: 1}, Right: &RealLiteral{Value: 2.5}}},
+		{Name: "b", Value: &UnaryOp{Op: "!", Expr: &BooleanLiteral{Value: true}}},
+		{Name: "c", Value: &ListLiteral{Elements: []Expr{&StringLiteral{Value: `q"s`}, &UndefinedLiteral{}, &ErrorLiteral{}}}},
+		{Name: "d", Value: &RecordLiteral{ClassAd: &ClassAd{Attributes: []*AttributeAssignment{
+			{Name: "x", Value: &AttributeReference{Name: "Cpus", Scope: TargetScope}},
+		}}}},
+		{Name: "e", Value: &FunctionCall{Name: "strcat", Args: []Expr{&AttributeReference{Name: "Owner"}}}},
+		{Name: "f", Value: &ConditionalExpr{Condition: &AttributeReference{Name: "p", Scope: MyScope}, TrueExpr: &IntegerLiteral{Value: 2}, FalseExpr: &IntegerLiteral{Value: 3}}},
+		{Name: "g", Value: &ElvisExpr{Left: &AttributeReference{Name: "q", Scope: ParentScope}, Right: &IntegerLiteral{Value: 4}}},
+		{Name: "h", Value: &SelectExpr{Record: &AttributeReference{Name: "r"}, Attr: "s"}},
+		{Name: "i", Value: &SubscriptExpr{Container: &AttributeReference{Name: "l"}, Index: &ParenExpr{Inner: &IntegerLiteral{Value: 0}}}},
+		{Name: "j", Value: &AbsTimeLiteral{Secs: 1700000000, Offset: -3600}},
+		{Name: "k", Value: &RelTimeLiteral{Secs: 90.5}},
+	}}
+}
+
+type recorder struct{ kinds *[]string }
+
+func (r recorder) Visit(n Node) Visitor {
+	if n == nil {
+		*r.kinds = append(*r.kinds, "end")
+		return nil
+	}
+	*r.kinds = append(*r.kinds, strings.TrimPrefix(fmt.Sprintf("%T", n), "*ast."))
+	return r
+}
+
+func TestWalk(t *testing.T) {
+	var kinds []string
+	Walk(&BinaryOp{Op: "&&", Left: &AttributeReference{Name: "a"}, Right: &ParenExpr{Inner: &IntegerLiteral{Value: 1}}}, recorder{&kinds})
+	want := []string{"BinaryOp", "AttributeReference", "end", "ParenExpr", "IntegerLiteral", "end", "end", "end"}
+	if !reflect.DeepEqual(kinds, want) {
+		t.Errorf("Walk visited %v, want %v", kinds, want)
+	}
+
+	seen := map[string]bool{}
+	Inspect(everyKind(), func(n Node) bool {
+		if n != nil {
+			seen[strings.TrimPrefix(fmt.Sprintf("%T", n), "*ast.")] = true
+		}
+		return true
+	})
+	if len(seen) != 21 {
+		t.Errorf("Inspect saw %d node types, want 21: %v", len(seen), seen)
+	}
+
+	// Walk(nil) and nil children are skipped.
+	Walk(nil, recorder{&kinds})
+	Inspect(&RecordLiteral{}, func(Node) bool { return true })
+}
+
+func TestInspectPrune(t *testing.T) {
+	var refs []string
+	Inspect(everyKind(), func(n Node) bool {
+		switch n := n.(type) {
+		case *RecordLiteral:
+			return false
+		case *AttributeReference:
+			refs = append(refs, n.String())
+		}
+		return true
+	})
+	want := []string{"Owner", "MY.p", "PARENT.q", "r", "l"}
+	if !reflect.DeepEqual(refs, want) {
+		t.Errorf("references outside records = %v, want %v", refs, want)
+	}
+}
+
+func TestRewrite(t *testing.T) {
+	in := everyKind()
+	before := in.String()
+	out := Rewrite(in, func(e Expr) Expr {
+		if r, ok := e.(*AttributeReference); ok && r.Name == "Owner" {
+			return &StringLiteral{Value: "alice"}
+		}
+		return nil
+	}).(*ClassAd)
+	if in.String() != before {
+		t.Fatal("Rewrite modified its input")
+	}
+	if got := out.Attributes[4].Value.String(); got != `strcat("alice")` {
+		t.Errorf("rewritten e = %s", got)
+	}
+	for i, a := range out.Attributes {
+		if shared := a == in.Attributes[i]; shared != (i != 4) {
+			t.Errorf("attribute %s shared = %v", a.Name, shared)
+		}
+	}
+
+	// Nothing replaced: the input itself comes back.
+	if got := Rewrite(in, func(Expr) Expr { return nil }); got != Expr(in) {
+		t.Error("Rewrite copied a tree it did not change")
+	}
+
+	// Inside a record, and bottom-up by calling Rewrite from fn.
+	var fold func(Expr) Expr
+	fold = func(e Expr) Expr {
+		switch n := e.(type) {
+		case *AttributeReference:
+			return &IntegerLiteral{Value: 10}
+		case *BinaryOp:
+			l, r := Rewrite(n.Left, fold), Rewrite(n.Right, fold)
+			li, lok := l.(*IntegerLiteral)
+			ri, rok := r.(*IntegerLiteral)
+			if lok && rok && n.Op == "*" {
+				return &IntegerLiteral{Value: li.Value * ri.Value}
+			}
+			return &BinaryOp{Op: n.Op, Left: l, Right: r}
+		}
+		return nil
+	}
+	rec := &RecordLiteral{ClassAd: &ClassAd{Attributes: []*AttributeAssignment{{Name: "m", Value: &BinaryOp{Op: "*", Left: &AttributeReference{Name: "x"}, Right: &IntegerLiteral{Value: 3}}}}}}
+	if got := Rewrite(rec, fold).String(); got != "[m = 30]" {
+		t.Errorf("folded record = %s", got)
+	}
+}
+
+func TestJSONRoundTrip(t *testing.T) {
+	in := everyKind()
+	data, err := MarshalJSON(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	again, _ := MarshalJSON(in)
+	if string(again) != string(data) {
+		t.Error("MarshalJSON is not stable")
+	}
+	out, err := UnmarshalJSON(data)
+	if err != nil {
+		t.Fatalf("UnmarshalJSON: %v\n%s", err, data)
+	}
+	if out.String() != in.String() {
+		t.Errorf("round trip = %s\nwant %s", out, in)
+	}
+	redata, _ := MarshalJSON(out)
+	if string(redata) != string(data) {
+		t.Errorf("re-encoding differs:\n%s\n%s", redata, data)
+	}
+
+	data, _ = MarshalJSON(&BinaryOp{Op: ">=", Left: &AttributeReference{Name: "Cpus", Scope: MyScope}, Right: &IntegerLiteral{Value: 4}})
+	want := `{"kind":"BinaryOp","op":">=","left":{"kind":"AttributeReference","name":"Cpus","scope":"MY"},"right":{"kind":"IntegerLiteral","value":4}}`
+	if string(data) != want {
+		t.Errorf("MarshalJSON = %s\nwant %s", data, want)
+	}
+
+	for _, v := range []float64{math.NaN(), math.Inf(1), math.Inf(-1), 1e300, 2} {
+		data, err := MarshalJSON(&RealLiteral{Value: v})
+		if err != nil {
+			t.Fatalf("MarshalJSON(%g): %v", v, err)
+		}
+		n, err := UnmarshalJSON(data)
+		got := n.(*RealLiteral).Value
+		if err != nil || !(got == v || math.IsNaN(v) && math.IsNaN(got)) {
+			t.Errorf("real %g round trip = %v, %v (%s)", v, got, err, data)
+		}
+	}
+	n, err := UnmarshalJSON([]byte(`{"kind":"IntegerLiteral","value":9223372036854775807}`))
+	if err != nil || n.(*IntegerLiteral).Value != math.MaxInt64 {
+		t.Errorf("max int64 = %v, %v", n, err)
+	}
+}
+
+func TestUnmarshalJSONErrors(t *testing.T) {
+	for _, in := range []string{
+		`{"kind":"Nope"}`,
+		`{"kind":"BinaryOp","op":"+","left":{"kind":"IntegerLiteral","value":1}}`,
+		`{"kind":"AttributeReference","name":"x","scope":"OTHER"}`,
+		`{"kind":"IntegerLiteral","value":"1"}`,
+		`{"kind":"ListLiteral","elements":[{"kind":"AttributeAssignment","name":"a","value":{"kind":"UndefinedLiteral"}}]}`,
+		`{"kind":"ClassAd","attributes":[{"kind":"IntegerLiteral","value":1}]}`,
+		`[1]`,
+	} {
+		if n, err := UnmarshalJSON([]byte(in)); err == nil {
+			t.Errorf("UnmarshalJSON(%s) = %v, want an error", in, n)
+		}
+	}
+	if n, err := UnmarshalJSON([]byte("null")); n != nil || err != nil {
+		t.Errorf("UnmarshalJSON(null) = %v, %v", n, err)
+	}
+}
@@ -0,0 +1,293 @@
+package classad
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/PelicanPlatform/classad/ast"
+)
+
+// This file is a builder for expressions, so a program can assemble a
+// constraint from Go values instead of formatting and re-parsing text:
+//
+//	expr := classad.Attr("Cpus").Ge(4).And(classad.Attr("Owner").Eq(classad.Str(user)))
+//
+// A value from the program is always a literal of the tree, never source
+// text, so a user name holding quotes (or "|| true") cannot change the
+// expression's meaning the way it could through fmt.Sprintf and Quote.
+//
+// Wherever an operand is an any, it is converted as Lit converts it. The
+// builder panics on an operand it cannot convert (a channel, say) or a
+// function name that is not an identifier: both are mistakes in the program,
+// not in its input.
+//
+// Each method returns a new *Expr and leaves its receiver and operands alone;
+// they share subtrees, which is safe because nothing modifies an Expr.
+
+// Attr returns a reference to the attribute name, resolved as an unscoped
+// name in the expression is: in the ad it is evaluated in, then its parents,
+// then the target ad. The name is used as given; it is not parsed, so
+// Attr("MY.Cpus") is the attribute named "MY.Cpus", not Cpus of MY.
+func Attr(name string) *Expr { return ref(name, ast.NoScope) }
+
+// MyAttr returns the reference MY.name.
+func MyAttr(name string) *Expr { return ref(name, ast.MyScope) }
+
+// TargetAttr returns the reference TARGET.name.
+func TargetAttr(name string) *Expr { return ref(name, ast.TargetScope) }
+
+// ParentAttr returns the reference PARENT.name.
+func ParentAttr(name string) *Expr { return ref(name, ast.ParentScope) }
+
+func ref(name string, scope ast.AttributeScope) *Expr {
+	return &Expr{expr: ast.NewAttributeReference(name, scope)}
+}
+
+// Lit returns v as an expression. v is converted as Marshal converts a field:
+// a bool, integer, float or string is a literal of that type, a slice a list,
+// a map with string keys or a struct a record, a time.Time its Unix seconds,
+// and nil undefined. A *Expr is used as is, a *ClassAd as a record, and a
+// Value as the literal LiteralExpr returns for it.
+func Lit(v any) *Expr { return &Expr{expr: operand(v)} }
+
+// Str returns the string literal s.
+func Str(s string) *Expr { return &Expr{expr: &ast.StringLiteral{Value: s}} }
+
+// Int returns the integer literal i.
+func Int(i int64) *Expr { return &Expr{expr: &ast.IntegerLiteral{Value: i}} }
+
+// Real returns the real literal r.
+func Real(r float64) *Expr { return &Expr{expr: &ast.RealLiteral{Value: r}} }
+
+// Bool returns the literal true or false.
+func Bool(b bool) *Expr { return &Expr{expr: &ast.BooleanLiteral{Value: b}} }
+
+// Undefined returns the literal undefined.
+func Undefined() *Expr { return &Expr{expr: &ast.UndefinedLiteral{}} }
+
+// List returns the list literal { elems... }.
+func List(elems ...any) *Expr {
+	list := &ast.ListLiteral{Elements: make([]ast.Expr, len(elems))}
+	for i, e := range elems {
+		list.Elements[i] = operand(e)
+	}
+	return &Expr{expr: list}
+}
+
+// Record returns the record literal [ name = value; ... ] with an attribute
+// for each entry of attrs, in name order.
+func Record(attrs map[string]any) *Expr {
+	names := make([]string, 0, len(attrs))
+	for name := range attrs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	ad := &ast.ClassAd{Attributes: make([]*ast.AttributeAssignment, len(names))}
+	for i, name := range names {
+		ad.Attributes[i] = &ast.AttributeAssignment{Name: name, Value: operand(attrs[name])}
+	}
+	return &Expr{expr: &ast.RecordLiteral{ClassAd: ad}}
+}
+
+// Call returns a call of the function name, builtin or registered, with args.
+// It panics if name is not an identifier.
+func Call(name string, args ...any) *Expr {
+	if name == "" || ast.QuoteAttributeName(name) != name {
+		panic(fmt.Sprintf("classad: function name %q is not an identifier", name))
+	}
+	call := &ast.FunctionCall{Name: name, Args: make([]ast.Expr, len(args))}
+	for i, a := range args {
+		call.Args[i] = operand(a)
+	}
+	return &Expr{expr: call}
+}
+
+// Cond returns the conditional cond ? then : otherwise.
+func Cond(cond, then, otherwise any) *Expr {
+	return &Expr{expr: &ast.ConditionalExpr{
+		Condition: paren(operand(cond), precOr),
+		TrueExpr:  operand(then),
+		FalseExpr: paren(operand(otherwise), precCond),
+	}}
+}
+
+// And returns e && v.
+func (e *Expr) And(v any) *Expr { return e.binary("&&", v) }
+
+// Or returns e || v.
+func (e *Expr) Or(v any) *Expr { return e.binary("||", v) }
+
+// Eq returns e == v.
+func (e *Expr) Eq(v any) *Expr { return e.binary("==", v) }
+
+// Ne returns e != v.
+func (e *Expr) Ne(v any) *Expr { return e.binary("!=", v) }
+
+// Is returns e =?= v, which compares without undefined propagating: it is
+// true or false for any operands.
+func (e *Expr) Is(v any) *Expr { return e.binary("is", v) }
+
+// Isnt returns e =!= v, the negation of Is.
+func (e *Expr) Isnt(v any) *Expr { return e.binary("isnt", v) }
+
+// Lt returns e < v.
+func (e *Expr) Lt(v any) *Expr { return e.binary("<", v) }
+
+// Le returns e <= v.
+func (e *Expr) Le(v any) *Expr { return e.binary("<=", v) }
+
+// Gt returns e > v.
+func (e *Expr) Gt(v any) *Expr { return e.binary(">", v) }
+
+// Ge returns e >= v.
+func (e *Expr) Ge(v any) *Expr { return e.binary(">=", v) }
+
+// Add returns e + v.
+func (e *Expr) Add(v any) *Expr { return e.binary("+", v) }
+
+// Sub returns e - v.
+func (e *Expr) Sub(v any) *Expr { return e.binary("-", v) }
+
+// Mul returns e * v.
+func (e *Expr) Mul(v any) *Expr { return e.binary("*", v) }
+
+// Div returns e / v.
+func (e *Expr) Div(v any) *Expr { return e.binary("/", v) }
+
+// Mod returns e % v.
+func (e *Expr) Mod(v any) *Expr { return e.binary("%", v) }
+
+// Not returns !e.
+func (e *Expr) Not() *Expr { return e.unary("!") }
+
+// Neg returns -e.
+func (e *Expr) Neg() *Expr { return e.unary("-") }
+
+// Elvis returns e ?: v, which is e unless e is undefined, and v then.
+func (e *Expr) Elvis(v any) *Expr {
+	return &Expr{expr: &ast.ElvisExpr{
+		Left:  paren(e.operand(), precElvis),
+		Right: paren(operand(v), precPostfix),
+	}}
+}
+
+// Attr returns e.name, the attribute name of the record e.
+func (e *Expr) Attr(name string) *Expr {
+	return &Expr{expr: &ast.SelectExpr{Record: paren(e.operand(), precPostfix), Attr: name}}
+}
+
+// Index returns e[i], element i of the list e.
+func (e *Expr) Index(i any) *Expr {
+	return &Expr{expr: &ast.SubscriptExpr{Container: paren(e.operand(), precPostfix), Index: operand(i)}}
+}
+
+// Unparse returns e as the reference engine unparses it (the form unparse()
+// and string() produce), which, unlike String, parenthesizes only where the
+// source did -- for a built expression, only where precedence requires.
+func (e *Expr) Unparse() string {
+	if e.internal() == nil {
+		return "undefined"
+	}
+	return unparseExprString(e.expr)
+}
+
+func (e *Expr) binary(op string, v any) *Expr {
+	p := binaryPrec[op]
+	return &Expr{expr: &ast.BinaryOp{Op: op, Left: paren(e.operand(), p), Right: paren(operand(v), p+1)}}
+}
+
+func (e *Expr) unary(op string) *Expr {
+	return &Expr{expr: &ast.UnaryOp{Op: op, Expr: paren(e.operand(), precUnary)}}
+}
+
+// operand is the tree of e as an operand: undefined for a nil or empty Expr.
+func (e *Expr) operand() ast.Expr {
+	if e.internal() == nil {
+		return &ast.UndefinedLiteral{}
+	}
+	return e.expr
+}
+
+// operand converts a Go value to a tree as Lit documents.
+func operand(v any) ast.Expr {
+	switch v := v.(type) {
+	case *Expr:
+		return v.operand()
+	case Value:
+		return (*ClassAd)(nil).valueToExpr(v)
+	case nil:
+		return &ast.UndefinedLiteral{}
+	}
+	expr, err := marshalValue(reflect.ValueOf(v))
+	if err != nil {
+		panic(fmt.Sprintf("classad: cannot use %T in an expression: %v", v, err))
+	}
+	return expr
+}
+
+// Operator precedence, loosest first, as the grammar (parser/classad.y) has
+// it. The reference unparser echoes only the parentheses in the tree, so the
+// builder adds a ParenExpr wherever an operand binds more loosely than its
+// position needs, and the unparsed text re-parses to the same tree.
+const (
+	precCond = iota + 1
+	precOr
+	precAnd
+	precBitOr
+	precBitXor
+	precBitAnd
+	precEq
+	precRel
+	precShift
+	precAdd
+	precMul
+	precUnary
+	precElvis // a ?: b unparses as the postfix elvis operator
+	precPostfix
+	precPrimary
+)
+
+var binaryPrec = map[string]int{
+	"||": precOr, "&&": precAnd, "|": precBitOr, "^": precBitXor, "&": precBitAnd,
+	"==": precEq, "!=": precEq, "is": precEq, "isnt": precEq,
+	"<": precRel, "<=": precRel, ">": precRel, ">=": precRel,
+	"<<": precShift, ">>": precShift, ">>>": precShift,
+	"+": precAdd, "-": precAdd, "*": precMul, "/": precMul, "%": precMul,
+}
+
+// prec returns how tightly e binds as unparsed.
+func prec(e ast.Expr) int {
+	switch e := e.(type) {
+	case *ast.ConditionalExpr:
+		return precCond
+	case *ast.BinaryOp:
+		return binaryPrec[e.Op]
+	case *ast.UnaryOp:
+		return precUnary
+	case *ast.ElvisExpr:
+		return precElvis
+	case *ast.SelectExpr, *ast.SubscriptExpr:
+		return precPostfix
+	case *ast.IntegerLiteral:
+		// A negative literal unparses with its sign, which parses as a unary
+		// minus: -5 .a is -(5 .a).
+		if e.Value < 0 {
+			return precUnary
+		}
+	case *ast.RealLiteral:
+		if strings.HasPrefix(classadReal(e.Value), "-") {
+			return precUnary
+		}
+	}
+	return precPrimary
+}
+
+// paren returns e, parenthesized if it binds more loosely than min.
+func paren(e ast.Expr, min int) ast.Expr {
+	if prec(e) < min {
+		return &ast.ParenExpr{Inner: e}
+	}
+	return e
+}
@@ -0,0 +1,128 @@
+package classad
+
+import (
+	"regexp"
+	"testing"
+	"time"
+)
+
+var negativeLiteral = regexp.MustCompile(`-[0-9]`)
+
+func TestBuilder(t *testing.T) {
+	user := `o"brien`
+	e := Attr("Cpus").Ge(4).And(Attr("Owner").Eq(Str(user)))
+	if got, want := e.Unparse(), `Cpus >= 4 && Owner == "o\"brien"`; got != want {
+		t.Errorf("Unparse = %s, want %s", got, want)
+	}
+	if got, want := e.String(), `((Cpus >= 4) && (Owner == "o\"brien"))`; got != want {
+		t.Errorf("String = %s, want %s", got, want)
+	}
+	ad, _ := Parse(`[Cpus = 8; Owner = "o\"brien"]`)
+	if b, err := e.Eval(ad).BoolValue(); err != nil || !b {
+		t.Errorf("Eval = %v, %v", b, err)
+	}
+
+	tests := []struct {
+		e    *Expr
+		want string
+	}{
+		{Attr("a").Or(Attr("b")).And(Attr("c")), "(a || b) && c"},
+		{Attr("a").And(Attr("b").Or(Attr("c"))), "a && (b || c)"},
+		{Attr("a").Sub(Attr("b").Sub(1)), "a - (b - 1)"},
+		{Attr("a").Sub(Attr("b")).Sub(1), "a - b - 1"},
+		{Attr("a").Add(1).Mul(Int(-2)), "(a + 1) * -2"},
+		{Attr("a").Mul(2).Neg(), " -(a * 2)"},
+		{Attr("a").Not().Is(Bool(true)), " !a is true"},
+		{Int(-5).Attr("b"), "(-5).b"},
+		{Attr("a").Add(1).Attr("b").Index(0), "(a + 1).b[0]"},
+		{MyAttr("x").Elvis(TargetAttr("y")).Elvis(ParentAttr("z")), "MY.x ?: TARGET.y ?: PARENT.z"},
+		{Attr("x").Elvis(Attr("y").Elvis(0)), "x ?: (y ?: 0)"},
+		{Attr("x").Add(1).Elvis(Attr("y").Add(2)), "(x + 1) ?: (y + 2)"},
+		{Attr("x").Elvis(1).Neg(), " -x ?: 1"}, // elvis binds tighter than unary minus
+		{Cond(Attr("a").Gt(1), Cond(Attr("b"), 1, 2), Cond(Attr("c"), 3, 4)), "a > 1 ? b ? 1 : 2 : c ? 3 : 4"},
+		{Cond(Cond(Attr("a"), true, false), 1, 2).Add(1), "((a ? true : false) ? 1 : 2) + 1"},
+		{Call("ifThenElse", Attr("a").Isnt(Undefined()), "yes", nil), `ifThenElse(a isnt undefined,"yes",undefined)`},
+		{List(1, 2.5, "s", List(), Record(map[string]any{"b": 2, "a": Attr("x").Add(1)})), `{ 1,2.500000000000000E+00,"s",{  },[ a = x + 1; b = 2 ] }`},
+		{Attr("not an identifier").Eq(Attr("true")), "'not an identifier' == 'true'"},
+	}
+	for _, tt := range tests {
+		if got := tt.e.Unparse(); got != tt.want {
+			t.Errorf("Unparse = %q, want %q (tree %s)", got, tt.want, tt.e)
+			continue
+		}
+		// The unparsed text re-parses to the built tree, but for a negative
+		// literal, which parses as a unary minus.
+		back, err := ParseExpr(tt.e.Unparse())
+		if err != nil {
+			t.Errorf("ParseExpr(%q): %v", tt.e.Unparse(), err)
+		} else if back.Unparse() != tt.e.Unparse() || !negativeLiteral.MatchString(tt.want) && back.String() != tt.e.String() {
+			t.Errorf("%q re-parses as %s, built %s", tt.e.Unparse(), back, tt.e)
+		}
+	}
+}
+
+func TestBuilderOperands(t *testing.T) {
+	when := time.Unix(1700000000, 0)
+	ad, _ := Parse("[x = 1]")
+	tests := []struct {
+		v    any
+		want string
+	}{
+		{4, "4"},
+		{uint8(4), "4"},
+		{2.5, "2.500000000000000E+00"},
+		{"a\"b", `"a\"b"`},
+		{true, "true"},
+		{nil, "undefined"},
+		{(*Expr)(nil), "undefined"},
+		{[]string{"a", "b"}, `{ "a","b" }`},
+		{map[string]int{"z": 1, "a": 2}, "[ a = 2; z = 1 ]"},
+		{when, "1700000000"},
+		{90 * time.Second, "90"},
+		{NewStringValue("v"), `"v"`},
+		{NewListValue([]Value{NewIntValue(1)}), "{ 1 }"},
+		{ad, "[ x = 1 ]"},
+		{Attr("y"), "y"},
+	}
+	for _, tt := range tests {
+		if got := Attr("a").Eq(tt.v).Unparse(); got != "a == "+tt.want {
+			t.Errorf("operand %#v = %s, want a == %s", tt.v, got, tt.want)
+		}
+	}
+
+	for name, f := range map[string]func(){
+		"channel operand":   func() { Lit(make(chan int)) },
+		"call expression":   func() { Call("f() || g") },
+		"empty call name":   func() { Call("") },
+		"keyword call name": func() { Call("true") },
+	} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("%s did not panic", name)
+				}
+			}()
+			f()
+		}()
+	}
+}
+
+func TestBuilderSharing(t *testing.T) {
+	cpus := Attr("Cpus")
+	a, b := cpus.Gt(1), cpus.Lt(8)
+	both := a.And(b)
+	if a.String() != "(Cpus > 1)" || b.String() != "(Cpus < 8)" || cpus.String() != "Cpus" {
+		t.Errorf("building changed an operand: %s, %s, %s", a, b, cpus)
+	}
+	ad, _ := Parse("[Cpus = 4]")
+	if v := both.Eval(ad); !v.IsBool() {
+		t.Errorf("Eval = %v", v)
+	}
+	var empty *Expr
+	if got := empty.Or(true).Unparse(); got != "undefined || true" {
+		t.Errorf("nil receiver = %s", got)
+	}
+	if got := empty.Unparse(); got != "undefined" {
+		t.Errorf("nil Unparse = %s", got)
+	}
+}
@@ -0,0 +1,179 @@
+package classad
+
+import (
+	"sync/atomic"
+
+	"github.com/PelicanPlatform/classad/ast"
+)
+
+// Per-ad evaluation cache. A negotiator-style loop evaluates the same Rank and
+// Requirements thousands of times against ads that change slowly; re-walking
+// each attribute's AST every time is the dominant cost. An ad with the cache
+// enabled keeps, per attribute, a Program compiled from its expression (the
+// classad package cannot import the bytecode compiler, collections/vm, so the
+// caller supplies it -- vm.CompileAttr), and memoizes the value of each of its
+// attributes within one evaluation pass, so A + A, or a Requirements that names
+// TARGET.Memory three times, evaluates the attribute once. Insert, Set, Delete
+// and Clear invalidate both.
+
+// Program is an attribute expression compiled for repeated evaluation.
+type Program interface {
+	// Exec evaluates the program in ev's current scope. It must produce the
+	// value the tree-walking evaluator produces for the source expression.
+	Exec(ev *Evaluator) Value
+}
+
+// ProgramCompiler compiles an attribute expression for a ClassAd's evaluation
+// cache, or returns nil to leave the attribute to the tree-walking evaluator.
+type ProgramCompiler func(expr ast.Expr) Program
+
+// evalCache is the state of an ad with EnableCache.
+type evalCache struct {
+	compile ProgramCompiler
+	// progs holds the compiled program of each attribute evaluated so far, by
+	// normalized name.
+	progs map[string]compiled
+	// memo holds the values of attributes evaluated during pass memoPass.
+	memo     map[string]Value
+	memoPass uint64
+}
+
+// compiled is an attribute's program and the expression it was compiled from.
+// An attribute inherited through ChainToAd can be rebound in the parent
+// without c noticing, so the program is reused only for the same expression.
+type compiled struct {
+	expr ast.Expr
+	prog Program // nil if the compiler declined
+}
+
+// evalPasses numbers evaluation passes (see Evaluator.passID).
+var evalPasses atomic.Uint64
+
+// EnableCache turns on c's evaluation cache: each attribute's expression is
+// compiled once with compile (nil to memoize only, evaluating by tree-walk) and
+// the compiled program reused until the attribute changes, and within one
+// evaluation -- one EvaluateAttr or EvaluateExpr call, or one match -- every
+// attribute of c is evaluated at most once. Values never outlive the
+// evaluation: one that reads TARGET or CurrentTime is recomputed by the next.
+//
+// Results are those of the uncached evaluator, except that a nondeterministic
+// function (random) referenced through an attribute is called once per
+// evaluation rather than once per reference. Like evaluation itself, the cache
+// is not safe for concurrent use. Calling EnableCache again discards the cache.
+//
+// Example:
+//
+//	job.EnableCache(vm.CompileAttr)
+//	for _, slot := range slots {
+//	    job.SetTarget(slot)
+//	    rank, _ := job.EvaluateAttrNumber("Rank")
+//	    ...
+//	}
+func (c *ClassAd) EnableCache(compile ProgramCompiler) {
+	c.cache = &evalCache{compile: compile, progs: map[string]compiled{}, memo: map[string]Value{}}
+}
+
+// DisableCache turns off and discards c's evaluation cache.
+func (c *ClassAd) DisableCache() {
+	c.cache = nil
+}
+
+// CacheEnabled reports whether c's evaluation cache is on.
+func (c *ClassAd) CacheEnabled() bool {
+	return c.cache != nil
+}
+
+// invalidateCache drops what c's evaluation cache holds for the normalized
+// attribute name norm ("" for every attribute) after a change to c. Every
+// memoized value goes, since any of them may depend on the attribute.
+func (c *ClassAd) invalidateCache(norm string) {
+	if c.cache == nil {
+		return
+	}
+	if norm == "" {
+		clear(c.cache.progs)
+	} else {
+		delete(c.cache.progs, norm)
+	}
+	clear(c.cache.memo)
+}
+
+// program returns the compiled program of the attribute norm bound to expr,
+// compiling it on first use.
+func (k *evalCache) program(norm string, expr ast.Expr) Program {
+	if k.compile == nil {
+		return nil
+	}
+	p, ok := k.progs[norm]
+	if !ok || p.expr != expr {
+		p = compiled{expr: expr, prog: k.compile(expr)}
+		k.progs[norm] = p
+	}
+	return p.prog
+}
+
+// memoized returns the value of attribute norm memoized during pass.
+func (k *evalCache) memoized(pass uint64, norm string) (Value, bool) {
+	if k.memoPass != pass {
+		return Value{}, false
+	}
+	v, ok := k.memo[norm]
+	return v, ok
+}
+
+// remember memoizes the value of attribute norm for pass, dropping the values
+// of any earlier pass.
+func (k *evalCache) remember(pass uint64, norm string, v Value) {
+	if k.memoPass != pass {
+		clear(k.memo)
+		k.memoPass = pass
+	}
+	k.memo[norm] = v
+}
+
+// evaluateAttrCached is evaluateAttr for an ad with the cache enabled, kept
+// apart so the evaluator of an uncached evaluation stays off the heap.
+func (c *ClassAd) evaluateAttrCached(norm string, expr ast.Expr, limits *evalBudget, dialect *Dialect) (result Value) {
+	defer recoverCyclic(&result)
+	evaluator := NewEvaluator(c)
+	evaluator.limits = limits
+	if dialect != nil {
+		evaluator.dialect = dialect
+	}
+	return evaluator.evalAttrExpr(c, norm, expr)
+}
+
+// passID returns the number of e's evaluation pass, starting one on first use.
+// A pass ends with SetScope; the child evaluators of a pass share its number.
+func (e *Evaluator) passID() uint64 {
+	if e.pass == 0 {
+		e.pass = evalPasses.Add(1)
+	}
+	return e.pass
+}
+
+// evalCached is evalAttrExpr's evaluation of an attribute of an ad with the
+// cache enabled: the attribute's compiled program when it has one, else its
+// expression, with the value memoized for the rest of the pass. The caller has
+// checked the memo and marked the attribute as being evaluated.
+func (e *Evaluator) evalCached(ad *ClassAd, norm string, expr ast.Expr) Value {
+	k := ad.cache
+	pass := e.passID()
+	var v Value
+	if p := k.program(norm, expr); p != nil {
+		// A program runs flat, so count the attribute as one level of
+		// recursion: a chain of references still reaches maxEvalDepth.
+		if e.depth >= maxEvalDepth {
+			panic(cyclicEvalError{})
+		}
+		// Run it on a child: handing e itself to the interface call would
+		// move every evaluator, cached or not, to the heap.
+		ev := e.child(ad)
+		ev.depth++
+		v = p.Exec(ev)
+	} else {
+		v = e.Evaluate(expr)
+	}
+	k.remember(pass, norm, v)
+	return v
+}
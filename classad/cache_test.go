@@ -0,0 +1,100 @@
+package classad
+
+import (
+	"testing"
+
+	"github.com/PelicanPlatform/classad/ast"
+)
+
+// walkProgram is a Program that tree-walks its expression, counting compiles.
+type walkProgram struct{ expr ast.Expr }
+
+func (p walkProgram) Exec(ev *Evaluator) Value { return ev.Evaluate(p.expr) }
+
+func TestEnableCache_Memo(t *testing.T) {
+	calls := 0
+	if err := RegisterFunction("testCacheCount", ExactArity(0), func([]Value) Value {
+		calls++
+		return NewIntValue(int64(calls))
+	}); err != nil {
+		t.Fatal(err)
+	}
+	defer UnregisterFunction("testCacheCount")
+
+	ad, _ := Parse(`[A = testCacheCount(); B = A + A; C = B * 2 + A]`)
+	// Uncached, every reference to A calls the function again: (1+2)*2 + 3.
+	if v, _ := ad.EvaluateAttrInt("C"); v != 9 || calls != 3 {
+		t.Errorf("uncached C = %d after %d calls, want 9 after 3", v, calls)
+	}
+	calls = 0
+	ad.EnableCache(nil)
+	if v, _ := ad.EvaluateAttrInt("C"); v != 5 || calls != 1 {
+		t.Errorf("cached C = %d after %d calls, want 5 after 1", v, calls)
+	}
+	// The memo lasts one evaluation.
+	if v, _ := ad.EvaluateAttrInt("B"); v != 4 || calls != 2 {
+		t.Errorf("next pass: B = %d after %d calls, want 4 after 2", v, calls)
+	}
+	ad.Insert("A", &ast.IntegerLiteral{Value: 10})
+	if v, _ := ad.EvaluateAttrInt("C"); v != 50 {
+		t.Errorf("after Insert: C = %d, want 50", v)
+	}
+}
+
+func TestEnableCache_Programs(t *testing.T) {
+	compiles := map[string]int{}
+	compile := func(expr ast.Expr) Program {
+		compiles[expr.String()]++
+		return walkProgram{expr}
+	}
+	ad, _ := Parse(`[A = 2; B = A * 3; R = B + TARGET.X]`)
+	ad.EnableCache(compile)
+	target, _ := Parse(`[X = 1]`)
+	ad.SetTarget(target)
+
+	for i, want := range []int64{7, 7} {
+		if v, _ := ad.EvaluateAttrInt("R"); v != want {
+			t.Errorf("pass %d: R = %d, want %d", i, v, want)
+		}
+	}
+	target.InsertAttr("X", 100)
+	if v, _ := ad.EvaluateAttrInt("R"); v != 106 {
+		t.Errorf("after a target change: R = %d, want 106", v)
+	}
+	if compiles["(A * 3)"] != 1 || compiles["(B + TARGET.X)"] != 1 {
+		t.Errorf("compiles = %v, want each attribute once", compiles)
+	}
+
+	if err := ad.Set("A", 5); err != nil {
+		t.Fatal(err)
+	}
+	ad.Insert("B", &ast.BinaryOp{Op: "*", Left: ast.NewAttributeReference("A", ast.NoScope), Right: &ast.IntegerLiteral{Value: 4}})
+	if v, _ := ad.EvaluateAttrInt("R"); v != 120 {
+		t.Errorf("after Set and Insert: R = %d, want 120", v)
+	}
+	if compiles["(A * 4)"] != 1 || compiles["(B + TARGET.X)"] != 1 {
+		t.Errorf("compiles = %v, want only B recompiled", compiles)
+	}
+	ad.Delete("B")
+	if v := ad.EvaluateAttr("R"); !v.IsUndefined() {
+		t.Errorf("after Delete: R = %v, want undefined", v)
+	}
+
+	ad.DisableCache()
+	if ad.CacheEnabled() || ad.EvaluateAttr("A").String() != "5" {
+		t.Error("DisableCache")
+	}
+}
+
+func TestEnableCache_Cycles(t *testing.T) {
+	ad, _ := Parse(`[A = B; B = A + 1; L = {L}[0]; E = isError(B)]`)
+	ad.EnableCache(func(expr ast.Expr) Program { return walkProgram{expr} })
+	for _, name := range []string{"A", "B", "L"} {
+		if v := ad.EvaluateAttr(name); !v.IsError() {
+			t.Errorf("%s = %v, want error", name, v)
+		}
+	}
+	if v := ad.EvaluateAttr("E"); !v.IsError() {
+		t.Errorf("E = %v, want error (a cycle is not an error value)", v)
+	}
+}
@@ -0,0 +1,103 @@
+package classad
+
+// Chained ads. HTCondor stores a job as a proc ad chained to its cluster ad:
+// the proc ad holds what differs per job, and every other attribute is read
+// through to the cluster ad shared by all the jobs of the cluster. ChainToAd
+// models this (as the reference engine's ClassAd::ChainToAd does); it is
+// distinct from SetParent, which sets the lexical PARENT scope of a nested ad.
+
+// ChainToAd chains c to parent: a lookup of an attribute c does not define
+// falls through to parent (and on up parent's own chain), and Insert into c
+// shadows parent's binding without changing parent. An attribute found in the
+// chain is evaluated in c's context, so its references resolve in c first --
+// a cluster ad's expression sees the proc ad's values.
+//
+// Lookup, the EvaluateAttr family, GetAttributes, Flatten and evaluation see
+// the whole chain. Size, Equal, String and the other serializations cover c's
+// own attributes only, as the reference engine's do; use Materialize to fold
+// the chain into one ad. Delete of an attribute parent defines masks it with
+// undefined.
+//
+// Chaining replaces any previous chained parent; a nil parent is Unchain. The
+// parent is shared, not copied. ChainToAd panics if the chain would be a
+// cycle.
+//
+// Example:
+//
+//	cluster, _ := classad.Parse(`[Owner = "alice"; RequestMemory = 1024 * Cpus]`)
+//	proc, _ := classad.Parse(`[ProcId = 3; Cpus = 2]`)
+//	proc.ChainToAd(cluster)
+//	mem, _ := proc.EvaluateAttrInt("RequestMemory") // 2048
+func (c *ClassAd) ChainToAd(parent *ClassAd) {
+	for ad := parent; ad != nil; ad = ad.chained {
+		if ad == c {
+			panic("classad: ChainToAd would chain an ad to itself")
+		}
+	}
+	c.chained = parent
+	c.invalidateCache("")
+}
+
+// Unchain removes c's chained parent, if any, leaving c with only its own
+// attributes. It returns the parent it was chained to (nil if none).
+func (c *ClassAd) Unchain() *ClassAd {
+	parent := c.chained
+	c.ChainToAd(nil)
+	return parent
+}
+
+// GetChainedParent returns the ad c is chained to, if any.
+func (c *ClassAd) GetChainedParent() *ClassAd {
+	return c.chained
+}
+
+// Materialize returns a new, unchained ad holding every attribute visible
+// through c: c's own bindings, then those of its chain that c does not
+// shadow. The new ad shares expressions with c and its chain and evaluates in
+// c's dialect; its scope (SetParent, SetTarget) and evaluation cache are not
+// carried over.
+func (c *ClassAd) Materialize() *ClassAd {
+	out := New()
+	out.dialect = c.dialect
+	for ad := c; ad != nil; ad = ad.chained {
+		if ad.ad == nil {
+			continue
+		}
+		for _, attr := range ad.ad.Attributes {
+			if out.lookupOwn(attr.Name) == nil {
+				out.Insert(attr.Name, attr.Value)
+			}
+		}
+	}
+	return out
+}
+
+// chainAttributes is GetAttributes for a chained ad.
+func (c *ClassAd) chainAttributes() []string {
+	seen := map[string]bool{}
+	names := []string{}
+	for ad := c; ad != nil; ad = ad.chained {
+		if ad.ad == nil {
+			continue
+		}
+		for _, attr := range ad.ad.Attributes {
+			norm := normalizeName(attr.Name)
+			if !seen[norm] {
+				seen[norm] = true
+				names = append(names, attr.Name)
+			}
+		}
+	}
+	return names
+}
+
+// definer returns the ad of c's chain (c itself, or an ad it is chained to)
+// that binds the normalized name norm, or nil if none does.
+func (c *ClassAd) definer(norm string) *ClassAd {
+	for ad := c; ad != nil; ad = ad.chained {
+		if ad.lookupOwnNorm(norm) != nil {
+			return ad
+		}
+	}
+	return nil
+}
@@ -0,0 +1,152 @@
+package classad
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/PelicanPlatform/classad/ast"
+)
+
+func TestChainToAd(t *testing.T) {
+	cluster, _ := Parse(`[Owner = "alice"; Cpus = 1; RequestMemory = 1024 * Cpus; Cmd = "/bin/sleep"]`)
+	proc, _ := Parse(`[ProcId = 3; Cpus = 2]`)
+	proc.ChainToAd(cluster)
+	if proc.GetChainedParent() != cluster {
+		t.Fatal("GetChainedParent did not return the parent")
+	}
+
+	// Lookups fall through, and inherited expressions evaluate in the child.
+	if owner, ok := proc.EvaluateAttrString("Owner"); !ok || owner != "alice" {
+		t.Errorf("Owner = %q, %v", owner, ok)
+	}
+	if mem, _ := proc.EvaluateAttrInt("RequestMemory"); mem != 2048 {
+		t.Errorf("RequestMemory = %d, want 2048 (Cpus from the proc ad)", mem)
+	}
+	if mem, _ := cluster.EvaluateAttrInt("RequestMemory"); mem != 1024 {
+		t.Errorf("cluster RequestMemory = %d, want 1024", mem)
+	}
+	if e, ok := proc.Lookup("cmd"); !ok || e.String() != `"/bin/sleep"` {
+		t.Errorf("Lookup(cmd) = %v, %v", e, ok)
+	}
+	v := mustParseExpr(t, `MY.Owner == "alice" && ProcId == 3`).Eval(proc)
+	if ok, _ := v.BoolValue(); !ok {
+		t.Errorf("expression over the chain = %v", v)
+	}
+
+	// Own attributes come first; shadowed ones are listed once.
+	want := []string{"ProcId", "Cpus", "Owner", "RequestMemory", "Cmd"}
+	if got := proc.GetAttributes(); !slices.Equal(got, want) {
+		t.Errorf("GetAttributes = %v, want %v", got, want)
+	}
+	if proc.Size() != 2 || proc.String() != "[Cpus = 2; ProcId = 3]" {
+		t.Errorf("Size = %d, String = %s; want the own attributes only", proc.Size(), proc)
+	}
+	flat := proc.Materialize()
+	if flat.GetChainedParent() != nil || flat.String() != `[Cmd = "/bin/sleep"; Cpus = 2; Owner = "alice"; ProcId = 3; RequestMemory = (1024 * Cpus)]` {
+		t.Errorf("Materialize = %s", flat)
+	}
+	expr, _ := ParseExpr("RequestMemory + ProcId")
+	if got := proc.Flatten(expr).String(); got != "2051" {
+		t.Errorf("Flatten = %s, want 2051", got)
+	}
+
+	// Insert shadows without touching the parent; Delete masks.
+	proc.InsertAttrString("Owner", "bob")
+	if owner, _ := proc.EvaluateAttrString("Owner"); owner != "bob" {
+		t.Errorf("shadowed Owner = %q", owner)
+	}
+	if owner, _ := cluster.EvaluateAttrString("Owner"); owner != "alice" {
+		t.Errorf("parent Owner = %q after the child's Insert", owner)
+	}
+	if !proc.Delete("Owner") || !proc.EvaluateAttr("Owner").IsUndefined() {
+		t.Errorf("Owner after Delete = %v, want undefined", proc.EvaluateAttr("Owner"))
+	}
+	if e, _ := proc.Lookup("Owner"); e == nil || e.String() != "undefined" {
+		t.Errorf("Delete did not mask the parent's Owner: %v", e)
+	}
+	if proc.Delete("NoSuchAttr") {
+		t.Error("Delete of a missing attribute reported true")
+	}
+
+	if proc.Unchain() != cluster || proc.GetChainedParent() != nil {
+		t.Error("Unchain did not return and drop the parent")
+	}
+	if _, ok := proc.Lookup("Cmd"); ok {
+		t.Error("Cmd still visible after Unchain")
+	}
+	if proc.Unchain() != nil {
+		t.Error("second Unchain returned a parent")
+	}
+}
+
+func TestChainToAd_Chains(t *testing.T) {
+	defaults, _ := Parse(`[Universe = 5; Priority = 0]`)
+	cluster, _ := Parse(`[Priority = 10]`)
+	proc := New()
+	cluster.ChainToAd(defaults)
+	proc.ChainToAd(cluster)
+
+	if u, _ := proc.EvaluateAttrInt("Universe"); u != 5 {
+		t.Errorf("Universe = %d through two links", u)
+	}
+	if p, _ := proc.EvaluateAttrInt("Priority"); p != 10 {
+		t.Errorf("Priority = %d, want the nearer binding", p)
+	}
+
+	for _, parent := range []*ClassAd{proc, cluster} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Error("ChainToAd into a cycle did not panic")
+				}
+			}()
+			defaults.ChainToAd(parent)
+		}()
+	}
+	if defaults.GetChainedParent() != nil {
+		t.Error("a refused ChainToAd changed the chain")
+	}
+}
+
+func TestChainToAd_TraceAndCache(t *testing.T) {
+	cluster, _ := Parse(`[Limit = 10; Rank = Limit * Weight]`)
+	proc, _ := Parse(`[Weight = 2]`)
+	proc.ChainToAd(cluster)
+
+	expr, _ := ParseExpr("Rank + MY.Limit")
+	trace := expr.EvalTrace(proc, nil)
+	if v, _ := trace.Value().IntValue(); v != 30 {
+		t.Fatalf("Value = %v, want 30", trace.Value())
+	}
+	for _, n := range trace.Root.Children {
+		if n.Scope != RefChainedParent || n.Ad != cluster {
+			t.Errorf("%s resolved in %v (%p), want the chained parent (%p)", n.Expr, n.Scope, n.Ad, cluster)
+		}
+	}
+
+	// A cached program of an inherited attribute follows the parent's rebinding.
+	compiles := 0
+	proc.EnableCache(func(e ast.Expr) Program {
+		compiles++
+		return nil
+	})
+	if r, _ := proc.EvaluateAttrInt("Rank"); r != 20 {
+		t.Errorf("Rank = %d, want 20", r)
+	}
+	cluster.InsertExpr("Rank", mustParseExpr(t, "Limit + Weight"))
+	if r, _ := proc.EvaluateAttrInt("Rank"); r != 12 {
+		t.Errorf("Rank after rebinding in the parent = %d, want 12", r)
+	}
+	if compiles != 2 {
+		t.Errorf("compiled %d times, want 2", compiles)
+	}
+}
+
+func mustParseExpr(t *testing.T, s string) *Expr {
+	t.Helper()
+	e, err := ParseExpr(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return e
+}
@@ -50,6 +50,16 @@ func ParseExpr(input string) (*Expr, error) {
 	return &Expr{expr: expr}, nil
 }
 
+// LiteralExpr returns an expression that evaluates to v: a literal, or a list
+// or record of literals. It stores an evaluated value back into an ad.
+//
+// Example:
+//
+//	ad.InsertExpr("Memory", classad.LiteralExpr(ad.EvaluateAttr("RequestMemory")))
+func LiteralExpr(v Value) *Expr {
+	return &Expr{expr: (*ClassAd)(nil).valueToExpr(v)}
+}
+
 // Quote escapes a string for safe use in ClassAd expressions.
 // It adds surrounding quotes and escapes special characters according to ClassAd syntax.
 //
@@ -152,6 +162,15 @@ type ClassAd struct {
 	// evaluation; without this guard the Go evaluator would recurse until the
 	// stack overflows.
 	evaluating map[string]bool
+	// cache, when non-nil, holds compiled programs and memoized values of the
+	// attributes (see EnableCache).
+	cache *evalCache
+	// chained, when non-nil, is the ad that lookups of attributes c does not
+	// define fall through to (see ChainToAd).
+	chained *ClassAd
+	// dialect, when non-nil, selects the reference-engine quirks evaluations
+	// in this ad's scope follow (see SetDialect).
+	dialect *Dialect
 }
 
 // Equal reports whether two ClassAds have the same attributes and values, ignoring
@@ -442,6 +461,7 @@ func (c *ClassAd) Insert(name string, expr ast.Expr) {
 	c.markDirty()
 
 	normalized := normalizeName(name)
+	c.invalidateCache(normalized)
 	if ptr, ok := c.index[normalized]; ok {
 		*ptr = expr
 		return
@@ -588,6 +608,7 @@ func (c *ClassAd) InsertListElement(name string, element *Expr) {
 		astExpr = element.internal()
 	}
 
+	c.invalidateCache(normalizeName(name))
 	if ptr, ok := c.index[normalizeName(name)]; ok {
 		if list, ok := (*ptr).(*ast.ListLiteral); ok {
 			list.Elements = append(list.Elements, astExpr)
@@ -605,7 +626,8 @@ func (c *ClassAd) InsertListElement(name string, element *Expr) {
 	c.index[normalizeName(name)] = &c.ad.Attributes[len(c.ad.Attributes)-1].Value
 }
 
-// Lookup returns the unevaluated expression for an attribute.
+// Lookup returns the unevaluated expression for an attribute, falling through
+// to the chained parent, if any, when the ad does not define it.
 // Returns nil if the attribute doesn't exist.
 // This is useful for inspecting or copying expressions without evaluating them.
 //
@@ -617,21 +639,26 @@ func (c *ClassAd) InsertListElement(name string, element *Expr) {
 //	    fmt.Println(expr.String())  // Prints: x * 2
 //	}
 func (c *ClassAd) Lookup(name string) (*Expr, bool) {
-	if c.ad == nil {
-		return nil, false
-	}
-	c.ensureIndex()
-
-	if ptr, ok := c.index[normalizeName(name)]; ok {
-		return &Expr{expr: *ptr}, true
+	if expr := c.lookupInternal(name); expr != nil {
+		return &Expr{expr: expr}, true
 	}
 	return nil, false
 }
 
-// lookupInternal finds an expression bound to an attribute name.
-// Returns nil if the attribute doesn't exist.
+// lookupInternal finds an expression bound to an attribute name, in c or the
+// ads it is chained to. Returns nil if the attribute doesn't exist.
 // This is the internal version that returns ast.Expr for backward compatibility.
 func (c *ClassAd) lookupInternal(name string) ast.Expr {
+	for ad := c; ad != nil; ad = ad.chained {
+		if expr := ad.lookupOwn(name); expr != nil {
+			return expr
+		}
+	}
+	return nil
+}
+
+// lookupOwn is lookupInternal ignoring any chained parent.
+func (c *ClassAd) lookupOwn(name string) ast.Expr {
 	if c.ad == nil {
 		return nil
 	}
@@ -665,7 +692,7 @@ func (c *ClassAd) lookupInternal(name string) ast.Expr {
 			return nil
 		}
 	}
-	return c.lookupNorm(normalizeName(name))
+	return c.lookupOwnNorm(normalizeName(name))
 }
 
 // lookupNorm is lookupInternal for an already-normalized (lower-cased) name. Hot
@@ -673,6 +700,16 @@ func (c *ClassAd) lookupInternal(name string) ast.Expr {
 // resolution) normalize once and call this to avoid a strings.ToLower allocation
 // per lookup.
 func (c *ClassAd) lookupNorm(norm string) ast.Expr {
+	for ad := c; ad != nil; ad = ad.chained {
+		if expr := ad.lookupOwnNorm(norm); expr != nil {
+			return expr
+		}
+	}
+	return nil
+}
+
+// lookupOwnNorm is lookupNorm ignoring any chained parent.
+func (c *ClassAd) lookupOwnNorm(norm string) ast.Expr {
 	if c.ad == nil {
 		return nil
 	}
@@ -815,7 +852,21 @@ func GetOr[T any](c *ClassAd, name string, defaultValue T) T {
 
 // Delete removes an attribute from the ClassAd.
 // Returns true if the attribute was found and deleted.
+//
+// On an ad chained to a parent that defines the attribute (see ChainToAd),
+// Delete binds the attribute to undefined instead, masking the parent's
+// binding, as the reference engine does.
 func (c *ClassAd) Delete(name string) bool {
+	deleted := c.deleteOwn(name)
+	if c.chained != nil && c.chained.lookupInternal(name) != nil {
+		c.Insert(name, &ast.UndefinedLiteral{})
+		return true
+	}
+	return deleted
+}
+
+// deleteOwn removes an attribute bound in c itself.
+func (c *ClassAd) deleteOwn(name string) bool {
 	if c.ad == nil {
 		return false
 	}
@@ -827,6 +878,7 @@ func (c *ClassAd) Delete(name string) bool {
 	if !ok {
 		return false
 	}
+	c.invalidateCache(normalized)
 
 	// Find the matching attribute by pointer equality on Value.
 	for i := range c.ad.Attributes {
@@ -839,7 +891,8 @@ func (c *ClassAd) Delete(name string) bool {
 	return false
 }
 
-// Size returns the number of attributes in the ClassAd.
+// Size returns the number of attributes in the ClassAd, not counting those
+// of a chained parent.
 func (c *ClassAd) Size() int {
 	if c.ad == nil {
 		return 0
@@ -847,17 +900,23 @@ func (c *ClassAd) Size() int {
 	return len(c.ad.Attributes)
 }
 
-// Clear removes all attributes from the ClassAd.
+// Clear removes all of the ClassAd's own attributes. A chained parent stays
+// chained.
 func (c *ClassAd) Clear() {
 	if c.ad != nil {
 		c.ad.Attributes = []*ast.AttributeAssignment{}
 	}
 	c.index = map[string]*ast.Expr{}
 	c.attrsDirty = false
+	c.invalidateCache("")
 }
 
-// GetAttributes returns a list of all attribute names.
+// GetAttributes returns a list of all attribute names: the ad's own, then
+// those of the ads it is chained to (see ChainToAd) that it does not shadow.
 func (c *ClassAd) GetAttributes() []string {
+	if c.chained != nil {
+		return c.chainAttributes()
+	}
 	if c.ad == nil {
 		return []string{}
 	}
@@ -892,7 +951,14 @@ func (c *ClassAd) GetTarget() *ClassAd {
 }
 
 // EvaluateAttr evaluates an attribute and returns its value.
-func (c *ClassAd) EvaluateAttr(name string) (result Value) {
+func (c *ClassAd) EvaluateAttr(name string) Value {
+	return c.evaluateAttr(name, nil, nil)
+}
+
+// evaluateAttr is EvaluateAttr within an evaluation budget (nil for none), for
+// an evaluation that reaches into a nested ad, and in dialect (nil for c's own),
+// for a match.
+func (c *ClassAd) evaluateAttr(name string, limits *evalBudget, dialect *Dialect) (result Value) {
 	expr := c.lookupInternal(name)
 	if expr == nil {
 		// CurrentTime is a magic attribute: when the ad does not define it, it resolves to
@@ -903,8 +969,15 @@ func (c *ClassAd) EvaluateAttr(name string) (result Value) {
 		return NewUndefinedValue()
 	}
 
+	if c.cache != nil {
+		return c.evaluateAttrCached(normalizeName(name), expr, limits, dialect)
+	}
 	defer recoverCyclic(&result)
 	evaluator := NewEvaluator(c)
+	evaluator.limits = limits
+	if dialect != nil {
+		evaluator.dialect = dialect
+	}
 	return evaluator.Evaluate(expr)
 }
 
@@ -1091,61 +1164,21 @@ func (c *ClassAd) collectRefs(expr ast.Expr) []string {
 	return result
 }
 
-// collectRefsHelper is a recursive helper for collectRefs
+// collectRefsHelper adds the unscoped attribute references of expr to refs.
+// A record literal's attributes are its own, so it is not looked into.
 func (c *ClassAd) collectRefsHelper(expr ast.Expr, refs map[string]bool) {
-	switch v := expr.(type) {
-	case *ast.ParenExpr:
-		c.collectRefsHelper(v.Inner, refs)
-
-	case *ast.AttributeReference:
-		// Only collect non-scoped references (no MY., TARGET., PARENT.)
-		if v.Scope == ast.NoScope {
-			refs[v.Name] = true
-		}
-
-	case *ast.BinaryOp:
-		c.collectRefsHelper(v.Left, refs)
-		c.collectRefsHelper(v.Right, refs)
-
-	case *ast.UnaryOp:
-		c.collectRefsHelper(v.Expr, refs)
-
-	case *ast.ConditionalExpr:
-		c.collectRefsHelper(v.Condition, refs)
-		c.collectRefsHelper(v.TrueExpr, refs)
-		c.collectRefsHelper(v.FalseExpr, refs)
-
-	case *ast.ElvisExpr:
-		c.collectRefsHelper(v.Left, refs)
-		c.collectRefsHelper(v.Right, refs)
-
-	case *ast.FunctionCall:
-		for _, arg := range v.Args {
-			c.collectRefsHelper(arg, refs)
-		}
-
-	case *ast.ListLiteral:
-		for _, elem := range v.Elements {
-			c.collectRefsHelper(elem, refs)
-		}
-
-	case *ast.ClassAd:
-		for _, attr := range v.Attributes {
-			c.collectRefsHelper(attr.Value, refs)
+	ast.Inspect(expr, func(n ast.Node) bool {
+		switch v := n.(type) {
+		case *ast.RecordLiteral:
+			return false
+		case *ast.AttributeReference:
+			// Only collect non-scoped references (no MY., TARGET., PARENT.)
+			if v.Scope == ast.NoScope {
+				refs[v.Name] = true
+			}
 		}
-
-	case *ast.SelectExpr:
-		c.collectRefsHelper(v.Record, refs)
-
-	case *ast.SubscriptExpr:
-		c.collectRefsHelper(v.Container, refs)
-		c.collectRefsHelper(v.Index, refs)
-
-	// Literals don't contain references
-	case *ast.IntegerLiteral, *ast.RealLiteral, *ast.StringLiteral,
-		*ast.BooleanLiteral, *ast.UndefinedLiteral, *ast.ErrorLiteral:
-		// Nothing to do
-	}
+		return true
+	})
 }
 
 // Flatten partially evaluates an expression in the context of this ClassAd.
@@ -1169,10 +1202,13 @@ func (c *ClassAd) Flatten(expr *Expr) *Expr {
 
 // flattenExpr recursively flattens an AST expression
 func (c *ClassAd) flattenExpr(expr ast.Expr) ast.Expr {
-	if expr == nil {
-		return nil
-	}
+	return ast.Rewrite(expr, c.flattenNode)
+}
 
+// flattenNode is the ast.Rewrite step of flattenExpr. An operator or call has
+// its operands flattened here, so that it can fold them; lists, selections and
+// subscripts are left to ast.Rewrite, and literals and records are kept.
+func (c *ClassAd) flattenNode(expr ast.Expr) ast.Expr {
 	switch v := expr.(type) {
 	case *ast.ParenExpr:
 		inner := c.flattenExpr(v.Inner)
@@ -1359,29 +1395,8 @@ func (c *ClassAd) flattenExpr(expr ast.Expr) ast.Expr {
 
 		return &ast.FunctionCall{Name: v.Name, Args: args}
 
-	case *ast.ListLiteral:
-		elements := make([]ast.Expr, len(v.Elements))
-		for i, elem := range v.Elements {
-			elements[i] = c.flattenExpr(elem)
-		}
-		return &ast.ListLiteral{
-			Elements: elements,
-		}
-
-	case *ast.SelectExpr:
-		record := c.flattenExpr(v.Record)
-		return &ast.SelectExpr{
-			Record: record,
-			Attr:   v.Attr,
-		}
-
-	case *ast.SubscriptExpr:
-		container := c.flattenExpr(v.Container)
-		index := c.flattenExpr(v.Index)
-		return &ast.SubscriptExpr{
-			Container: container,
-			Index:     index,
-		}
+	case *ast.ListLiteral, *ast.SelectExpr, *ast.SubscriptExpr:
+		return nil
 
 	// Literals are already fully evaluated
 	default:
@@ -1404,6 +1419,10 @@ func (c *ClassAd) exprToValue(expr ast.Expr) Value {
 		return NewUndefinedValue()
 	case *ast.ErrorLiteral:
 		return NewErrorValue()
+	case *ast.AbsTimeLiteral:
+		return newAbsTime(v.Secs, v.Offset)
+	case *ast.RelTimeLiteral:
+		return newRelTime(v.Secs)
 	default:
 		return NewUndefinedValue()
 	}
@@ -1442,6 +1461,10 @@ func (c *ClassAd) valueToExpr(val Value) ast.Expr {
 		if err == nil && adVal != nil {
 			return &ast.RecordLiteral{ClassAd: adVal.ad}
 		}
+	case AbsTimeValue:
+		return &ast.AbsTimeLiteral{Secs: val.absSecs(), Offset: val.absOffset()}
+	case RelTimeValue:
+		return &ast.RelTimeLiteral{Secs: val.real()}
 	case UndefinedValue:
 		return &ast.UndefinedLiteral{}
 	case ErrorValue:
@@ -1494,6 +1517,12 @@ func exprEqual(a, b ast.Expr) bool {
 	case *ast.ErrorLiteral:
 		_, ok := b.(*ast.ErrorLiteral)
 		return ok
+	case *ast.AbsTimeLiteral:
+		bv, ok := b.(*ast.AbsTimeLiteral)
+		return ok && av.Secs == bv.Secs && av.Offset == bv.Offset
+	case *ast.RelTimeLiteral:
+		bv, ok := b.(*ast.RelTimeLiteral)
+		return ok && floatEqual(av.Secs, bv.Secs)
 	case *ast.AttributeReference:
 		bv, ok := b.(*ast.AttributeReference)
 		return ok && av.Scope == bv.Scope && strings.EqualFold(av.Name, bv.Name)
@@ -1608,6 +1637,16 @@ func (c *ClassAd) MarshalJSON() ([]byte, error) { return c.marshalJSON(false) }
 func (c *ClassAd) MarshalJSONWithPrivate() ([]byte, error) { return c.marshalJSON(true) }
 
 func (c *ClassAd) marshalJSON(includePrivate bool) ([]byte, error) {
+	b, err := c.jsonBytes(includePrivate)
+	if err != nil {
+		return nil, err
+	}
+	return escapeJSONExprs(b), nil
+}
+
+// jsonBytes encodes c as a JSON object with expressions as "/Expr(...)/"
+// strings, before the HTCondor escaping of escapeJSONExprs.
+func (c *ClassAd) jsonBytes(includePrivate bool) ([]byte, error) {
 	if c.ad == nil {
 		return []byte("{}"), nil
 	}
@@ -1641,12 +1680,14 @@ func (c *ClassAd) marshalJSON(includePrivate bool) ([]byte, error) {
 		buf.Write(valBytes)
 	}
 	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
 
-	jsonBytes := buf.Bytes()
-	jsonBytes = []byte(strings.ReplaceAll(string(jsonBytes), "\"/Expr(", "\"\\/Expr("))
-	jsonBytes = []byte(strings.ReplaceAll(string(jsonBytes), ")/\"", ")\\/\""))
-
-	return jsonBytes, nil
+// escapeJSONExprs writes the "/Expr(...)/" strings of encoded JSON the way
+// HTCondor does, as "\/Expr(...)\/".
+func escapeJSONExprs(b []byte) []byte {
+	b = []byte(strings.ReplaceAll(string(b), "\"/Expr(", "\"\\/Expr("))
+	return []byte(strings.ReplaceAll(string(b), ")/\"", ")\\/\""))
 }
 
 // marshalValue converts an AST expression to a JSON-serializable value.
@@ -1677,7 +1718,7 @@ func (c *ClassAd) marshalValue(expr ast.Expr) (interface{}, error) {
 		// Nested ClassAd: serialize deterministically and embed as raw JSON.
 		nested := &ClassAd{ad: v.ClassAd, attrsDirty: true}
 		nested.rebuildIndex()
-		nestedBytes, err := nested.MarshalJSON()
+		nestedBytes, err := nested.jsonBytes(false)
 		if err != nil {
 			return nil, err
 		}
@@ -1723,6 +1764,7 @@ func (c *ClassAd) UnmarshalJSON(data []byte) error {
 	c.ad = &ast.ClassAd{Attributes: attributes}
 	c.attrsDirty = true
 	c.rebuildIndex()
+	c.invalidateCache("")
 	return nil
 }
 
@@ -1757,7 +1799,13 @@ func (c *ClassAd) unmarshalValue(value interface{}) (ast.Expr, error) {
 		// Check if it's an expression string.
 		if strings.HasPrefix(v, "/Expr(") && strings.HasSuffix(v, ")/") {
 			exprStr := v[6 : len(v)-2] // Remove "/Expr(" and ")/"
-			return c.parseExpression(exprStr)
+			expr, err := c.parseExpression(exprStr)
+			if err != nil {
+				return nil, err
+			}
+			// A time value marshals as the absTime("...")/relTime("...") call
+			// that re-creates it; decode that back to the literal.
+			return foldTimeLiteral(expr), nil
 		}
 		// Regular string literal
 		return &ast.StringLiteral{Value: v}, nil
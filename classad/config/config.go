@@ -0,0 +1,469 @@
+// Package config reads HTCondor configuration files, so tools can look up
+// START, RANK, JOB_TRANSFORM_* and the rest of a pool's knobs offline, the way
+// condor_config_val does.
+//
+// A configuration is a sequence of "NAME = value" definitions; a later
+// definition replaces an earlier one. Values reference other macros as
+// $(NAME) and are expanded when looked up, so a macro may be used before it
+// is defined -- except that a definition's references to its own name expand
+// at once to the previous value, which is how "DAEMON_LIST = $(DAEMON_LIST)
+// STARTD" appends. Lookups are case-insensitive.
+//
+//	cfg, err := config.Load("/etc/condor/condor_config", &config.Options{Subsystem: "STARTD"})
+//	start, err := cfg.Expr("START")         // parsed as a ClassAd expression
+//	v, err := cfg.Eval("START", machineAd)  // condor_config_val -eval
+//	n, err := cfg.Int("NUM_CPUS")
+//
+// The directives supported are:
+//
+//	include [ifexist] : path             read another file (relative to this one)
+//	use CATEGORY : name[, name...]       expand a configuration template
+//	if / elif / else / endif             conditional blocks
+//	error : message                      fail the load
+//	NAME @=tag ... @tag                  a multi-line value
+//
+// An if or elif condition is expanded and then tested as "defined NAME",
+// "version OP x.y.z", a boolean or integer literal, or a ClassAd expression
+// that must evaluate to a boolean; "!" negates it.
+//
+// Besides $(NAME) and $(NAME:default), values may use $ENV(NAME), $INT(expr),
+// $REAL(expr) and $SUBSTR(NAME, start[, length]); see Config.Value. Load also
+// reads the files LOCAL_CONFIG_DIR and LOCAL_CONFIG_FILE name, as HTCondor
+// does. Running a command ("include command :" or a trailing "|") is not
+// supported.
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/PelicanPlatform/classad/classad/internal/expand"
+)
+
+// maxIncludeDepth bounds nested include and use directives.
+const maxIncludeDepth = 20
+
+// ErrUndefined is returned (wrapped) by the lookups of a macro that is not
+// defined.
+var ErrUndefined = errors.New("config: macro not defined")
+
+// Options configures Load and Parse. The zero Options is valid.
+type Options struct {
+	// Macros are defined before the first file is read, as HTCondor's
+	// built-in macros (FULL_HOSTNAME, RELEASE_DIR, ...) are.
+	Macros map[string]string
+	// Subsystem is the daemon the configuration is read for, such as
+	// "STARTD". A lookup of NAME returns SUBSYSTEM.NAME when that is
+	// defined, and $(SUBSYSTEM) expands to it.
+	Subsystem string
+	// Version is the HTCondor version "if version" conditions compare
+	// against, such as "24.0.1". Such a condition is an error when it is
+	// empty.
+	Version string
+	// Templates adds or replaces the templates of use directives, by
+	// "CATEGORY:NAME" (case-insensitive). A template is configuration text.
+	Templates map[string]string
+	// LookupEnv resolves $ENV(NAME). Nil is os.LookupEnv.
+	LookupEnv func(name string) (string, bool)
+}
+
+// Config is a parsed configuration.
+type Config struct {
+	macros map[string]*macro // by lower-cased name
+	opts   Options
+}
+
+// macro is one definition: the last one of its name.
+type macro struct {
+	name  string
+	value string // raw: not expanded, except for self-references
+	file  string
+	line  int
+}
+
+// New returns a Config holding only opts.Macros. opts may be nil.
+func New(opts *Options) *Config {
+	c := &Config{macros: map[string]*macro{}}
+	if opts != nil {
+		c.opts = *opts
+	}
+	if c.opts.LookupEnv == nil {
+		c.opts.LookupEnv = os.LookupEnv
+	}
+	for name, value := range c.opts.Macros {
+		c.define(name, value, "", 0)
+	}
+	if c.opts.Subsystem != "" {
+		if _, ok := c.macros["subsystem"]; !ok {
+			c.define("SUBSYSTEM", c.opts.Subsystem, "", 0)
+		}
+	}
+	return c
+}
+
+// Load reads the configuration file at path and the files it includes, then
+// the local configuration it names: each file of LOCAL_CONFIG_DIR in name
+// order, then each of LOCAL_CONFIG_FILE. opts may be nil.
+func Load(path string, opts *Options) (*Config, error) {
+	c := New(opts)
+	if err := c.ReadFile(path); err != nil {
+		return nil, err
+	}
+	if dir, err := c.Value("LOCAL_CONFIG_DIR"); err == nil && dir != "" {
+		for _, d := range splitList(dir) {
+			if err := c.readDir(d); err != nil {
+				return nil, err
+			}
+		}
+	} else if err != nil && !errors.Is(err, ErrUndefined) {
+		return nil, err
+	}
+	if files, err := c.Value("LOCAL_CONFIG_FILE"); err == nil {
+		for _, f := range splitList(files) {
+			if err := c.ReadFile(f); err != nil {
+				return nil, err
+			}
+		}
+	} else if !errors.Is(err, ErrUndefined) {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Parse parses configuration text. Includes are relative to the working
+// directory. opts may be nil.
+func Parse(src string, opts *Options) (*Config, error) {
+	c := New(opts)
+	if err := c.Read(strings.NewReader(src), ""); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// ReadFile reads the configuration file at path into c, on top of what c
+// already defines.
+func (c *Config) ReadFile(path string) error {
+	return (&loader{c: c}).file(path, false)
+}
+
+// Read reads configuration text from r into c, on top of what c already
+// defines. name is the file name errors cite and includes are relative to;
+// it may be empty.
+func (c *Config) Read(r io.Reader, name string) error {
+	return (&loader{c: c}).read(r, name)
+}
+
+// Set defines name as a line "name = value" would.
+func (c *Config) Set(name, value string) {
+	c.define(name, value, "", 0)
+}
+
+// define records a definition, expanding the value's references to its own
+// name to the previous value.
+func (c *Config) define(name, value, file string, line int) {
+	key := strings.ToLower(name)
+	prev := ""
+	if m, ok := c.macros[key]; ok {
+		prev = m.value
+	}
+	value = strings.TrimSpace(expandSelf(value, name, prev))
+	c.macros[key] = &macro{name: name, value: value, file: file, line: line}
+}
+
+// readDir reads the files of a LOCAL_CONFIG_DIR in name order, skipping
+// hidden files and editor backups.
+func (c *Config) readDir(dir string) error {
+	ents, err := os.ReadDir(dir)
+	if err != nil {
+		return fmt.Errorf("config: %w", err)
+	}
+	names := make([]string, 0, len(ents))
+	for _, e := range ents {
+		n := e.Name()
+		if e.IsDir() || strings.HasPrefix(n, ".") || strings.HasSuffix(n, "~") ||
+			strings.HasSuffix(n, ".rpmsave") || strings.HasSuffix(n, ".rpmnew") {
+			continue
+		}
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	for _, n := range names {
+		if err := c.ReadFile(filepath.Join(dir, n)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// splitList splits a list-valued macro on commas and whitespace.
+func splitList(s string) []string {
+	return strings.FieldsFunc(s, func(r rune) bool {
+		return r == ',' || r == ' ' || r == '\t' || r == '\n'
+	})
+}
+
+// loader reads one chain of files: the one read and those it includes.
+type loader struct {
+	c     *Config
+	stack []string // the files being read, outermost first
+}
+
+// file reads the file at path. With ifExist, a missing file is skipped.
+func (ld *loader) file(path string, ifExist bool) error {
+	if abs, err := filepath.Abs(path); err == nil {
+		path = abs
+	}
+	for _, p := range ld.stack {
+		if p == path {
+			return fmt.Errorf("config: %s includes itself", path)
+		}
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		if ifExist && errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return fmt.Errorf("config: %w", err)
+	}
+	defer f.Close()
+	return ld.read(f, path)
+}
+
+// read parses the text of r, named name, applying it to ld.c.
+func (ld *loader) read(r io.Reader, name string) error {
+	if len(ld.stack) >= maxIncludeDepth {
+		return fmt.Errorf("config: %s: include and use nested too deeply", name)
+	}
+	ld.stack = append(ld.stack, name)
+	defer func() { ld.stack = ld.stack[:len(ld.stack)-1] }()
+
+	lines, err := expand.ReadLines(r)
+	if err != nil {
+		return fmt.Errorf("config: %s: %w", name, err)
+	}
+	var conds []cond
+	for i := 0; i < len(lines); i++ {
+		l := lines[i]
+		text := strings.TrimSpace(l.Text)
+		if text == "" || text[0] == '#' {
+			continue
+		}
+		fail := func(err error) error {
+			if n, ok := err.(nestedError); ok {
+				return n.error // already cites its own file and line
+			}
+			if name == "" {
+				return fmt.Errorf("config: line %d: %v", l.Num, err)
+			}
+			return fmt.Errorf("config: %s:%d: %v", name, l.Num, err)
+		}
+
+		word, rest := firstWord(text)
+		switch strings.ToLower(word) {
+		case "if", "elif", "else", "endif":
+			if !isDirective(rest) {
+				break
+			}
+			var err error
+			if conds, err = ld.conditional(conds, strings.ToLower(word), rest); err != nil {
+				return fail(err)
+			}
+			continue
+		}
+		if !active(conds) {
+			continue
+		}
+
+		if d, arg, ok := directive(text); ok {
+			if err := ld.directive(d, arg, name); err != nil {
+				return fail(err)
+			}
+			continue
+		}
+		eq := strings.IndexByte(text, '=')
+		if eq < 0 {
+			return fail(fmt.Errorf("expected NAME = value, got %q", text))
+		}
+		key := strings.TrimSpace(text[:eq])
+		value := text[eq+1:]
+		if strings.HasSuffix(key, "@") {
+			// NAME @=tag: the value is the following lines up to "@tag".
+			key = strings.TrimSpace(key[:len(key)-1])
+			tag := strings.TrimSpace(value)
+			var body []string
+			j := i + 1
+			for ; j < len(lines); j++ {
+				if strings.HasPrefix(strings.TrimSpace(lines[j].Text), "@"+tag) {
+					break
+				}
+				body = append(body, lines[j].Text)
+			}
+			if j == len(lines) {
+				return fail(fmt.Errorf("missing @%s ending the value of %s", tag, key))
+			}
+			value, i = strings.Join(body, "\n"), j
+		}
+		if key == "" || strings.ContainsAny(key, " \t@") {
+			return fail(fmt.Errorf("invalid macro name %q", key))
+		}
+		ld.c.define(key, value, name, l.Num)
+	}
+	if len(conds) > 0 {
+		return fmt.Errorf("config: %s: if without endif", name)
+	}
+	return nil
+}
+
+// directive recognizes an "include", "use" or "error" line, returning its
+// keyword (lower-cased, with any ifexist/command option) and the argument
+// after the colon. A colon that comes after an '=' is part of a definition.
+func directive(text string) (kw, arg string, ok bool) {
+	colon := strings.IndexByte(text, ':')
+	if colon < 0 {
+		return "", "", false
+	}
+	if eq := strings.IndexByte(text, '='); eq >= 0 && eq < colon {
+		return "", "", false
+	}
+	head := strings.Fields(strings.ToLower(text[:colon]))
+	if len(head) == 0 {
+		return "", "", false
+	}
+	head[0] = strings.TrimPrefix(head[0], "@")
+	switch head[0] {
+	case "include", "error", "warning":
+		if len(head) > 2 || (head[0] != "include" && len(head) > 1) {
+			return "", "", false
+		}
+	case "use":
+		if len(head) != 2 {
+			return "", "", false
+		}
+	default:
+		return "", "", false
+	}
+	return strings.Join(head, " "), strings.TrimSpace(text[colon+1:]), true
+}
+
+// directive applies an include, use, error or warning line read from file.
+func (ld *loader) directive(kw, arg, file string) error {
+	word, opt := firstWord(kw)
+	switch word {
+	case "include":
+		if opt == "command" || strings.HasSuffix(arg, "|") {
+			return errors.New("include of command output is not supported")
+		}
+		if opt != "" && opt != "ifexist" {
+			return fmt.Errorf("unknown include option %q", opt)
+		}
+		path, err := ld.c.expand(arg, nil)
+		if err != nil {
+			return err
+		}
+		if !filepath.IsAbs(path) && file != "" {
+			path = filepath.Join(filepath.Dir(file), path)
+		}
+		if err := ld.file(path, opt == "ifexist"); err != nil {
+			return nestedError{err}
+		}
+		return nil
+	case "use":
+		for _, name := range splitList(arg) {
+			text, ok := ld.c.template(opt, name)
+			if !ok {
+				return fmt.Errorf("unknown template %s:%s", strings.ToUpper(opt), name)
+			}
+			if err := ld.read(strings.NewReader(text), "use "+strings.ToUpper(opt)+":"+name); err != nil {
+				return nestedError{err}
+			}
+		}
+		return nil
+	case "error":
+		msg, err := ld.c.expand(arg, nil)
+		if err != nil {
+			return err
+		}
+		return errors.New(msg)
+	}
+	return nil // warning
+}
+
+// nestedError is the error of an included file or template, which already
+// cites where in it the error is.
+type nestedError struct{ error }
+
+// cond is an open if block.
+type cond struct {
+	outer  bool // the enclosing block is active
+	active bool // the current branch is taken
+	taken  bool // some branch has been taken
+	inElse bool
+}
+
+// active reports whether lines at the current nesting are read.
+func active(conds []cond) bool {
+	return len(conds) == 0 || conds[len(conds)-1].active
+}
+
+// conditional applies an if, elif, else or endif line to the block stack.
+func (ld *loader) conditional(conds []cond, kw, arg string) ([]cond, error) {
+	switch kw {
+	case "if":
+		outer := active(conds)
+		ok := false
+		if outer {
+			var err error
+			if ok, err = ld.c.test(arg); err != nil {
+				return nil, err
+			}
+		}
+		return append(conds, cond{outer: outer, active: ok, taken: ok}), nil
+	case "elif", "else":
+		if len(conds) == 0 || conds[len(conds)-1].inElse {
+			return nil, fmt.Errorf("%s without if", kw)
+		}
+		top := &conds[len(conds)-1]
+		if kw == "else" {
+			if strings.TrimSpace(arg) != "" {
+				return nil, errors.New("else takes no condition")
+			}
+			top.active, top.taken, top.inElse = top.outer && !top.taken, true, true
+			return conds, nil
+		}
+		top.active = false
+		if top.outer && !top.taken {
+			ok, err := ld.c.test(arg)
+			if err != nil {
+				return nil, err
+			}
+			top.active, top.taken = ok, ok
+		}
+		return conds, nil
+	default: // endif
+		if len(conds) == 0 {
+			return nil, errors.New("endif without if")
+		}
+		return conds[:len(conds)-1], nil
+	}
+}
+
+// firstWord splits text at its first space or tab.
+func firstWord(text string) (word, rest string) {
+	i := strings.IndexAny(text, " \t")
+	if i < 0 {
+		return text, ""
+	}
+	return text[:i], strings.TrimSpace(text[i:])
+}
+
+// isDirective reports whether the text after an if/elif/else/endif keyword
+// makes it a directive rather than the start of a definition of a macro so
+// named.
+func isDirective(rest string) bool {
+	return !strings.HasPrefix(rest, "=") || strings.HasPrefix(rest, "==")
+}
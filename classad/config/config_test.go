@@ -0,0 +1,308 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/PelicanPlatform/classad/classad"
+)
+
+func parse(t *testing.T, src string, opts *Options) *Config {
+	t.Helper()
+	c, err := Parse(src, opts)
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	return c
+}
+
+func value(t *testing.T, c *Config, name string) string {
+	t.Helper()
+	v, err := c.Value(name)
+	if err != nil {
+		t.Fatalf("Value(%s): %v", name, err)
+	}
+	return v
+}
+
+func TestExpansion(t *testing.T) {
+	c := parse(t, `
+# comment
+LOCAL_DIR = $(RELEASE_DIR)/local
+LOG       = $(local_dir)/log
+DAEMON_LIST = MASTER
+DAEMON_LIST = $(DAEMON_LIST) STARTD
+Greeting  = hello \
+            world
+Missing   = [$(NOPE)] [$(NOPE:dflt)] $(DOLLAR)(x)
+FromEnv   = $ENV(CONFIG_TEST_HOME) $ENV(CONFIG_TEST_NONE:none)
+MEMORY    = 1024 * 3
+Doubled   = $INT(MEMORY) $INT(2.9) $INT(MEMORY,%05d) $REAL($(MEMORY) / 5.0) $REAL(1,%.2f)
+Host      = compute-17.example.org
+Short     = $SUBSTR(Host, 0, 10) $SUBSTR(Host, -3) $SUBSTR(Host, 11, -4)
+Nested    = $($(Which))
+Which     = HOST
+START     = Memory > $(MEMORY) && $(NOPE:True)
+Multi @=end
+  line one
+  line two
+@end
+`, &Options{
+		Macros: map[string]string{"RELEASE_DIR": "/usr"},
+		LookupEnv: func(name string) (string, bool) {
+			return map[string]string{"CONFIG_TEST_HOME": "/home/x"}[name], name == "CONFIG_TEST_HOME"
+		},
+	})
+
+	for name, want := range map[string]string{
+		"LOG":         "/usr/local/log",
+		"DAEMON_LIST": "MASTER STARTD",
+		"Greeting":    "hello world",
+		"Missing":     "[] [dflt] $(x)",
+		"FromEnv":     "/home/x none",
+		"Doubled":     "3072 2 03072 614.4 1.00",
+		"Short":       "compute-17 org example",
+		"Nested":      "compute-17.example.org",
+		"start":       "Memory > 1024 * 3 && True",
+		"Multi":       "line one\n  line two",
+	} {
+		if got := value(t, c, name); got != want {
+			t.Errorf("%s = %q, want %q", name, got, want)
+		}
+	}
+	if raw, _ := c.Raw("DAEMON_LIST"); raw != "MASTER STARTD" {
+		t.Errorf("Raw(DAEMON_LIST) = %q: self-reference not expanded at definition", raw)
+	}
+	if raw, _ := c.Raw("LOG"); raw != "$(local_dir)/log" {
+		t.Errorf("Raw(LOG) = %q", raw)
+	}
+	if _, line, ok := c.Source("log"); !ok || line != 4 {
+		t.Errorf("Source(log) line = %d, %v", line, ok)
+	}
+}
+
+func TestRecursion(t *testing.T) {
+	c := parse(t, "A = $(B)\nB = x $(C)\nC = $(A)\nD = $(D:fallback)", nil)
+	_, err := c.Value("A")
+	if err == nil || !strings.Contains(err.Error(), "recursive definition of A (A -> B -> C -> A)") {
+		t.Errorf("Value(A) error = %v", err)
+	}
+	if got := value(t, c, "D"); got != "fallback" {
+		t.Errorf("D = %q: a self-reference with no previous value takes its default", got)
+	}
+	if _, err := c.Value("NOPE"); !errors.Is(err, ErrUndefined) {
+		t.Errorf("Value(NOPE) error = %v, want ErrUndefined", err)
+	}
+}
+
+func TestConditionals(t *testing.T) {
+	c := parse(t, `
+FEATURE = on
+if defined FEATURE
+  A = 1
+  if $(NOT_SET:false)
+    A = wrong
+  elif version >= 23.10
+    B = new
+  else
+    B = old
+  endif
+elif true
+  A = wrong
+else
+  A = wrong
+endif
+if ! defined OTHER
+  C = $(A)$(B)
+endif
+if 1 + 1 == 3
+  D = wrong
+elif "$(FEATURE)" == "off"
+  D = skipped
+else
+  D = $INT(4)
+endif
+if 0
+  error : never reached
+endif
+`, &Options{Version: "24.0.1"})
+	for name, want := range map[string]string{"A": "1", "B": "new", "C": "1new", "D": "4"} {
+		if got := value(t, c, name); got != want {
+			t.Errorf("%s = %q, want %q", name, got, want)
+		}
+	}
+}
+
+func TestUseAndTemplates(t *testing.T) {
+	c := parse(t, `
+DAEMON_LIST = MASTER
+use ROLE : Submit, Execute
+@use policy : always_run_jobs
+use LOCAL : Mine
+`, &Options{Templates: map[string]string{"local:mine": "WHO = me\nSTART = $(START) && Owner == \"$(WHO)\""}})
+	if got := value(t, c, "DAEMON_LIST"); got != "MASTER SCHEDD STARTD" {
+		t.Errorf("DAEMON_LIST = %q", got)
+	}
+	if got := value(t, c, "START"); got != `True && Owner == "me"` {
+		t.Errorf("START = %q", got)
+	}
+	if b, err := c.Bool("WANT_SUSPEND"); err != nil || b {
+		t.Errorf("Bool(WANT_SUSPEND) = %v, %v", b, err)
+	}
+}
+
+func TestTypedLookups(t *testing.T) {
+	c := parse(t, `
+NUM_CPUS = 8
+HALF     = $(NUM_CPUS) / 2
+RATIO    = 0.75
+LOAD     = $(RATIO) * 2
+FLAG     = Yes
+EXPRFLAG = $(NUM_CPUS) > 4
+NUMFLAG  = 0
+STRING   = "text"
+START    = TARGET.RequestCpus <= $(NUM_CPUS) && MY.Memory >= 1024
+`, nil)
+	if n, err := c.Int("HALF"); err != nil || n != 4 {
+		t.Errorf("Int(HALF) = %d, %v", n, err)
+	}
+	if f, err := c.Float("LOAD"); err != nil || f != 1.5 {
+		t.Errorf("Float(LOAD) = %v, %v", f, err)
+	}
+	for name, want := range map[string]bool{"FLAG": true, "EXPRFLAG": true, "NUMFLAG": false} {
+		if b, err := c.Bool(name); err != nil || b != want {
+			t.Errorf("Bool(%s) = %v, %v", name, b, err)
+		}
+	}
+	if _, err := c.Int("STRING"); err == nil {
+		t.Error("Int(STRING) succeeded")
+	}
+	if _, err := c.Bool("RATIO"); err == nil {
+		t.Error("Bool(RATIO) succeeded")
+	}
+
+	expr, err := c.Expr("START")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := expr.String(); got != "((TARGET.RequestCpus <= 8) && (MY.Memory >= 1024))" {
+		t.Errorf("Expr(START) = %s", got)
+	}
+	machine, _ := classad.Parse(`[Memory = 2048; RequestCpus = 4]`)
+	v, err := c.Eval("NUM_CPUS", machine)
+	if n, _ := v.IntValue(); err != nil || n != 8 {
+		t.Errorf("Eval(NUM_CPUS) = %v, %v", v, err)
+	}
+	job, _ := classad.Parse(`[RequestCpus = 4]`)
+	if got := expr.EvalWithContext(machine, job); !got.IsBool() {
+		t.Errorf("START against a job = %v", got)
+	} else if b, _ := got.BoolValue(); !b {
+		t.Errorf("START against a job = %v, want true", got)
+	}
+
+	c.Set("NUM_CPUS", "$(NUM_CPUS) * 2")
+	if n, _ := c.Int("NUM_CPUS"); n != 16 {
+		t.Errorf("Int(NUM_CPUS) after Set = %d", n)
+	}
+}
+
+func TestSubsystem(t *testing.T) {
+	c := parse(t, "NAME = generic\nSTARTD.NAME = startd-$(SUBSYSTEM)\nOTHER = $(NAME)", &Options{Subsystem: "STARTD"})
+	if got := value(t, c, "NAME"); got != "startd-STARTD" {
+		t.Errorf("NAME = %q", got)
+	}
+	if got := value(t, c, "OTHER"); got != "startd-STARTD" {
+		t.Errorf("OTHER = %q", got)
+	}
+	if got := parse(t, "NAME = generic\nSTARTD.NAME = startd", nil); value(t, got, "NAME") != "generic" {
+		t.Error("subsystem override applied without a subsystem")
+	}
+}
+
+func TestLoad(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, text string) string {
+		t.Helper()
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(text), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	main := write("condor_config", `
+ORDER = main
+include : sub/common.conf
+include ifexist : missing.conf
+LOCAL_CONFIG_DIR = $(CONFIG_ROOT)/config.d
+LOCAL_CONFIG_FILE = `+filepath.Join(dir, "local")+`
+`)
+	write("sub/common.conf", "ORDER = $(ORDER) common\ninclude : deeper.conf\n")
+	write("sub/deeper.conf", "ORDER = $(ORDER) deeper\n")
+	write("config.d/20-b", "ORDER = $(ORDER) b\n")
+	write("config.d/10-a", "ORDER = $(ORDER) a\n")
+	write("config.d/30-c~", "ORDER = $(ORDER) backup\n")
+	write("config.d/.hidden", "ORDER = $(ORDER) hidden\n")
+	write("local", "ORDER = $(ORDER) local\n")
+
+	c, err := Load(main, &Options{Macros: map[string]string{"CONFIG_ROOT": dir}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := value(t, c, "ORDER"); got != "main common deeper a b local" {
+		t.Errorf("ORDER = %q", got)
+	}
+	if file, line, _ := c.Source("ORDER"); file != filepath.Join(dir, "local") || line != 1 {
+		t.Errorf("Source(ORDER) = %s:%d", file, line)
+	}
+	want := []string{"CONFIG_ROOT", "LOCAL_CONFIG_DIR", "LOCAL_CONFIG_FILE", "ORDER"}
+	if got := c.Names(); !slices.Equal(got, want) {
+		t.Errorf("Names = %v, want %v", got, want)
+	}
+
+	loop := write("loop", "include : loop\n")
+	if _, err := Load(loop, nil); err == nil || !strings.Contains(err.Error(), "includes itself") {
+		t.Errorf("self-include error = %v", err)
+	}
+}
+
+func TestErrors(t *testing.T) {
+	tests := map[string]string{
+		"JUNK":                        "line 1: expected NAME = value",
+		"if true\nA = 1":              "if without endif",
+		"endif":                       "line 1: endif without if",
+		"if true\nelse\nelse\nendif":  "line 3: else without if",
+		"else x":                      "line 1: else without if",
+		"if version > 1\nendif":       "Options.Version is not set",
+		"if \"str\"\nendif":           "not a boolean",
+		"use ROLE : Nope":             "line 1: unknown template ROLE:Nope",
+		"include command : ls":        "not supported",
+		"include : /nonexistent/file": "no such file",
+		"X = 1\nerror : bad $(X)":     "line 2: bad 1",
+		"A @=end\nno end":             "missing @end",
+	}
+	for src, want := range tests {
+		if _, err := Parse(src, nil); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("Parse(%q) error = %v, want %q", src, err, want)
+		}
+	}
+
+	// A directive-like line after an '=' is a definition.
+	c := parse(t, "include = x : y\nif = 3", nil)
+	if got := value(t, c, "include"); got != "x : y" {
+		t.Errorf("include = %q", got)
+	}
+}
+
+func TestNestedErrorLocation(t *testing.T) {
+	_, err := Parse("A = 1\nuse LOCAL : Bad", &Options{Templates: map[string]string{"LOCAL:Bad": "X = 1\nJUNK"}})
+	if err == nil || err.Error() != `config: use LOCAL:Bad:2: expected NAME = value, got "JUNK"` {
+		t.Errorf("error = %v", err)
+	}
+}
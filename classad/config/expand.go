@@ -0,0 +1,309 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/PelicanPlatform/classad/classad"
+	"github.com/PelicanPlatform/classad/classad/internal/expand"
+)
+
+// lookupRaw returns the unexpanded definition of name, preferring the
+// subsystem-qualified SUBSYSTEM.name.
+func (c *Config) lookupRaw(name string) (*macro, bool) {
+	key := strings.ToLower(strings.TrimSpace(name))
+	if c.opts.Subsystem != "" {
+		if m, ok := c.macros[strings.ToLower(c.opts.Subsystem)+"."+key]; ok {
+			return m, true
+		}
+	}
+	m, ok := c.macros[key]
+	return m, ok
+}
+
+// syntax is the references a configuration value can make.
+var syntax = expand.Syntax{Funcs: []string{"ENV", "INT", "REAL", "SUBSTR"}}
+
+// expand replaces the macro references in s. stack holds the macros being
+// expanded, outermost first, so that a reference cycle is an error rather
+// than a hang.
+func (c *Config) expand(s string, stack []string) (string, error) {
+	return syntax.Expand(s, len(stack), func(fn, arg string, _ int) (string, error) {
+		return c.call(fn, arg, stack)
+	})
+}
+
+// call evaluates one reference whose argument, arg, is already expanded.
+func (c *Config) call(fn, arg string, stack []string) (string, error) {
+	switch fn {
+	case "":
+		name, def, hasDef := strings.Cut(arg, ":")
+		return c.ref(strings.TrimSpace(name), def, hasDef, stack)
+	case "ENV":
+		name, def, _ := strings.Cut(arg, ":")
+		if v, ok := c.opts.LookupEnv(strings.TrimSpace(name)); ok {
+			return v, nil
+		}
+		return def, nil
+	case "INT", "REAL":
+		src, format, _ := strings.Cut(arg, ",")
+		v, err := c.evalNumber(src, stack)
+		if err != nil {
+			return "", fmt.Errorf("$%s(%s): %v", fn, arg, err)
+		}
+		format = strings.TrimSpace(format)
+		if fn == "INT" {
+			n := int64(v)
+			if format != "" {
+				return fmt.Sprintf(format, n), nil
+			}
+			return strconv.FormatInt(n, 10), nil
+		}
+		if format != "" {
+			return fmt.Sprintf(format, v), nil
+		}
+		return strconv.FormatFloat(v, 'g', -1, 64), nil
+	default: // SUBSTR
+		return c.substr(arg, stack)
+	}
+}
+
+// ref expands a $(name) or $(name:default) reference.
+func (c *Config) ref(name, def string, hasDef bool, stack []string) (string, error) {
+	if strings.EqualFold(name, "DOLLAR") {
+		return "$", nil
+	}
+	m, ok := c.lookupRaw(name)
+	if !ok {
+		return def, nil
+	}
+	for k, s := range stack {
+		if strings.EqualFold(s, m.name) {
+			return "", fmt.Errorf("recursive definition of %s (%s -> %s)", m.name, strings.Join(stack[k:], " -> "), m.name)
+		}
+	}
+	return c.expand(m.value, append(stack[:len(stack):len(stack)], m.name))
+}
+
+// evalNumber evaluates the argument of $INT or $REAL: the value of the macro
+// it names, or else the argument itself, as a number or ClassAd expression.
+func (c *Config) evalNumber(src string, stack []string) (float64, error) {
+	src = strings.TrimSpace(src)
+	if m, ok := c.lookupRaw(src); ok && isName(src) {
+		v, err := c.ref(m.name, "", false, stack)
+		if err != nil {
+			return 0, err
+		}
+		src = v
+	}
+	if n, err := strconv.ParseInt(src, 10, 64); err == nil {
+		return float64(n), nil
+	}
+	v, err := evalExpr(src, nil)
+	if err != nil {
+		return 0, err
+	}
+	f, err := v.NumberValue()
+	if err != nil {
+		return 0, fmt.Errorf("%q is %v, not a number", src, v)
+	}
+	return f, nil
+}
+
+// substr expands $SUBSTR(name, start[, length]): a negative start counts from
+// the end, and a negative length leaves that many characters off the end.
+func (c *Config) substr(arg string, stack []string) (string, error) {
+	parts := strings.Split(arg, ",")
+	if len(parts) < 2 || len(parts) > 3 {
+		return "", fmt.Errorf("$SUBSTR(%s): want name, start[, length]", arg)
+	}
+	s, err := c.ref(strings.TrimSpace(parts[0]), "", false, stack)
+	if err != nil {
+		return "", err
+	}
+	nums := make([]int, len(parts)-1)
+	for k, p := range parts[1:] {
+		if nums[k], err = strconv.Atoi(strings.TrimSpace(p)); err != nil {
+			return "", fmt.Errorf("$SUBSTR(%s): %q is not an integer", arg, strings.TrimSpace(p))
+		}
+	}
+	start := nums[0]
+	if start < 0 {
+		start += len(s)
+	}
+	start = min(max(start, 0), len(s))
+	end := len(s)
+	if len(nums) == 2 {
+		if n := nums[1]; n < 0 {
+			end = len(s) + n
+		} else {
+			end = start + n
+		}
+		end = min(max(end, start), len(s))
+	}
+	return s[start:end], nil
+}
+
+// expandSelf replaces value's references to name with prev.
+func expandSelf(value, name, prev string) string {
+	var b strings.Builder
+	for i := 0; i < len(value); {
+		if fn, open := syntax.RefAt(value, i); value[i] == '$' && open >= 0 && fn == "" {
+			if end := expand.ClosingParen(value, open); end >= 0 {
+				ref, def, hasDef := strings.Cut(value[open+1:end], ":")
+				if strings.EqualFold(strings.TrimSpace(ref), name) {
+					if prev == "" && hasDef {
+						b.WriteString(def)
+					} else {
+						b.WriteString(prev)
+					}
+					i = end + 1
+					continue
+				}
+			}
+		}
+		b.WriteByte(value[i])
+		i++
+	}
+	return b.String()
+}
+
+// isName reports whether s could be a macro name.
+func isName(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, r := range s {
+		if r != '_' && r != '.' && (r < '0' || r > '9') && (r < 'a' || r > 'z') && (r < 'A' || r > 'Z') {
+			return false
+		}
+	}
+	return true
+}
+
+// test evaluates the condition of an if or elif.
+func (c *Config) test(text string) (bool, error) {
+	text, err := c.expand(strings.TrimSpace(text), nil)
+	if err != nil {
+		return false, err
+	}
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return false, errors.New("if without a condition")
+	}
+	if rest, ok := strings.CutPrefix(text, "!"); ok && !strings.HasPrefix(rest, "=") {
+		ok, err := c.test(rest)
+		return !ok, err
+	}
+	word, rest := firstWord(text)
+	switch strings.ToLower(word) {
+	case "defined":
+		if rest == "" {
+			return false, nil // $(X) expanded to nothing
+		}
+		if _, ok := c.lookupRaw(rest); ok {
+			return true, nil
+		}
+		return isBuiltinDefined(rest), nil
+	case "version":
+		return c.testVersion(rest)
+	}
+	if b, ok := parseBool(text); ok {
+		return b, nil
+	}
+	if n, err := strconv.ParseInt(text, 10, 64); err == nil {
+		return n != 0, nil
+	}
+	v, err := evalExpr(text, nil)
+	if err != nil {
+		return false, err
+	}
+	b, err := v.BoolValue()
+	if err != nil {
+		return false, fmt.Errorf("condition %q is %v, not a boolean", text, v)
+	}
+	return b, nil
+}
+
+// isBuiltinDefined reports whether name is a macro expand defines without a
+// definition.
+func isBuiltinDefined(name string) bool {
+	return strings.EqualFold(name, "DOLLAR")
+}
+
+// testVersion evaluates "version OP x.y.z" against Options.Version.
+func (c *Config) testVersion(cond string) (bool, error) {
+	if c.opts.Version == "" {
+		return false, errors.New("if version: Options.Version is not set")
+	}
+	op := strings.TrimRight(cond, "0123456789. \t")
+	want := strings.TrimSpace(cond[len(op):])
+	op = strings.TrimSpace(op)
+	if want == "" {
+		return false, fmt.Errorf("if version: missing version in %q", cond)
+	}
+	cmp := compareVersions(c.opts.Version, want)
+	switch op {
+	case ">":
+		return cmp > 0, nil
+	case ">=":
+		return cmp >= 0, nil
+	case "<":
+		return cmp < 0, nil
+	case "<=":
+		return cmp <= 0, nil
+	case "==", "":
+		return cmp == 0, nil
+	case "!=":
+		return cmp != 0, nil
+	}
+	return false, fmt.Errorf("if version: unknown operator %q", op)
+}
+
+// compareVersions compares dotted versions, a missing component comparing as
+// 0.
+func compareVersions(a, b string) int {
+	as, bs := strings.Split(a, "."), strings.Split(b, ".")
+	for k := 0; k < max(len(as), len(bs)); k++ {
+		var x, y int
+		if k < len(as) {
+			x, _ = strconv.Atoi(as[k])
+		}
+		if k < len(bs) {
+			y, _ = strconv.Atoi(bs[k])
+		}
+		if x != y {
+			if x < y {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
+
+// parseBool reads a configuration boolean literal: true/false, yes/no or
+// t/f, in any case.
+func parseBool(s string) (bool, bool) {
+	switch strings.ToLower(s) {
+	case "true", "t", "yes":
+		return true, true
+	case "false", "f", "no":
+		return false, true
+	}
+	return false, false
+}
+
+// evalExpr parses src as a ClassAd expression and evaluates it in ad.
+func evalExpr(src string, ad *classad.ClassAd) (classad.Value, error) {
+	expr, err := classad.ParseExpr(src)
+	if err != nil {
+		return classad.Value{}, err
+	}
+	if ad == nil {
+		ad = classad.New()
+	}
+	return expr.Eval(ad), nil
+}
@@ -0,0 +1,174 @@
+package config
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/PelicanPlatform/classad/classad"
+)
+
+// Raw returns the definition of name as written, without expanding it (but
+// with any references to its own name already replaced by the value they had,
+// as the definition was read).
+func (c *Config) Raw(name string) (string, bool) {
+	m, ok := c.lookupRaw(name)
+	if !ok {
+		return "", false
+	}
+	return m.value, true
+}
+
+// Defined reports whether name has a definition.
+func (c *Config) Defined(name string) bool {
+	_, ok := c.lookupRaw(name)
+	return ok
+}
+
+// Source returns the file and line of the definition of name in effect. Both
+// are zero for Options.Macros, Set and templates' definitions.
+func (c *Config) Source(name string) (file string, line int, ok bool) {
+	m, ok := c.lookupRaw(name)
+	if !ok {
+		return "", 0, false
+	}
+	return m.file, m.line, true
+}
+
+// Names returns the names of every definition, sorted case-insensitively.
+func (c *Config) Names() []string {
+	names := make([]string, 0, len(c.macros))
+	for _, m := range c.macros {
+		names = append(names, m.name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		return strings.ToLower(names[i]) < strings.ToLower(names[j])
+	})
+	return names
+}
+
+// Value returns the value of name with every macro reference expanded, as
+// condor_config_val prints it. References expand as follows:
+//
+//	$(NAME)              the value of NAME, or "" if it is not defined
+//	$(NAME:default)      the value of NAME, or default
+//	$(DOLLAR)            a literal "$"
+//	$ENV(NAME[:default]) the environment variable NAME
+//	$INT(X[,format])     X (a macro name, number or ClassAd expression)
+//	                     evaluated to an integer, formatted with printf
+//	$REAL(X[,format])    likewise, as a real
+//	$SUBSTR(NAME,start[,length])
+//	                     part of NAME's value; negative start counts from
+//	                     the end, negative length stops short of it
+//
+// A macro whose expansion reaches itself is an error. Value returns an error
+// wrapping ErrUndefined if name is not defined.
+func (c *Config) Value(name string) (string, error) {
+	m, ok := c.lookupRaw(name)
+	if !ok {
+		return "", fmt.Errorf("%w: %s", ErrUndefined, name)
+	}
+	v, err := c.expand(m.value, []string{m.name})
+	if err != nil {
+		return "", fmt.Errorf("config: %s: %v", name, err)
+	}
+	return strings.TrimSpace(v), nil
+}
+
+// Bool returns the value of name as a boolean: true/false, yes/no or t/f in
+// any case, an integer (non-zero is true), or a ClassAd expression that
+// evaluates to a boolean.
+func (c *Config) Bool(name string) (bool, error) {
+	v, err := c.Value(name)
+	if err != nil {
+		return false, err
+	}
+	if b, ok := parseBool(v); ok {
+		return b, nil
+	}
+	if n, err := strconv.ParseInt(v, 10, 64); err == nil {
+		return n != 0, nil
+	}
+	val, err := c.Eval(name, nil)
+	if err != nil {
+		return false, err
+	}
+	b, err := val.BoolValue()
+	if err != nil {
+		return false, fmt.Errorf("config: %s = %s is %v, not a boolean", name, v, val)
+	}
+	return b, nil
+}
+
+// Int returns the value of name as an integer: a decimal integer, or a
+// ClassAd expression that evaluates to a number (a real is truncated).
+func (c *Config) Int(name string) (int64, error) {
+	v, err := c.Value(name)
+	if err != nil {
+		return 0, err
+	}
+	if n, err := strconv.ParseInt(v, 10, 64); err == nil {
+		return n, nil
+	}
+	val, err := c.Eval(name, nil)
+	if err != nil {
+		return 0, err
+	}
+	if n, err := val.IntValue(); err == nil {
+		return n, nil
+	}
+	f, err := val.NumberValue()
+	if err != nil {
+		return 0, fmt.Errorf("config: %s = %s is %v, not a number", name, v, val)
+	}
+	return int64(f), nil
+}
+
+// Float returns the value of name as a real: a number, or a ClassAd
+// expression that evaluates to one.
+func (c *Config) Float(name string) (float64, error) {
+	v, err := c.Value(name)
+	if err != nil {
+		return 0, err
+	}
+	if f, err := strconv.ParseFloat(v, 64); err == nil {
+		return f, nil
+	}
+	val, err := c.Eval(name, nil)
+	if err != nil {
+		return 0, err
+	}
+	f, err := val.NumberValue()
+	if err != nil {
+		return 0, fmt.Errorf("config: %s = %s is %v, not a number", name, v, val)
+	}
+	return f, nil
+}
+
+// Expr returns the expanded value of name parsed as a ClassAd expression,
+// as a daemon reads START or RANK.
+func (c *Config) Expr(name string) (*classad.Expr, error) {
+	v, err := c.Value(name)
+	if err != nil {
+		return nil, err
+	}
+	expr, err := classad.ParseExpr(v)
+	if err != nil {
+		return nil, fmt.Errorf("config: %s: %v", name, err)
+	}
+	return expr, nil
+}
+
+// Eval evaluates the value of name as a ClassAd expression in ad, as
+// condor_config_val -eval does. A nil ad is an empty one.
+func (c *Config) Eval(name string, ad *classad.ClassAd) (classad.Value, error) {
+	expr, err := c.Expr(name)
+	if err != nil {
+		return classad.Value{}, err
+	}
+	if ad == nil {
+		ad = classad.New()
+	}
+	return expr.Eval(ad), nil
+}
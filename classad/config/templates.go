@@ -0,0 +1,37 @@
+package config
+
+import "strings"
+
+// templates is the use templates built in, by lower-cased "CATEGORY:NAME":
+// the roles, and the policy of a dedicated execute node.
+// Options.Templates adds others.
+var templates = map[string]string{
+	"role:centralmanager": `DAEMON_LIST = $(DAEMON_LIST) COLLECTOR NEGOTIATOR`,
+	"role:submit":         `DAEMON_LIST = $(DAEMON_LIST) SCHEDD`,
+	"role:execute":        `DAEMON_LIST = $(DAEMON_LIST) STARTD`,
+	"role:personal": `
+CONDOR_HOST = 127.0.0.1
+DAEMON_LIST = $(DAEMON_LIST) COLLECTOR NEGOTIATOR STARTD SCHEDD
+`,
+	"policy:always_run_jobs": `
+START = True
+SUSPEND = False
+CONTINUE = True
+PREEMPT = False
+KILL = False
+WANT_SUSPEND = False
+WANT_VACATE = False
+`,
+}
+
+// template returns the text of the template category:name.
+func (c *Config) template(category, name string) (string, bool) {
+	key := strings.ToLower(category + ":" + name)
+	for k, text := range c.opts.Templates {
+		if strings.ToLower(k) == key {
+			return text, true
+		}
+	}
+	text, ok := templates[key]
+	return text, ok
+}
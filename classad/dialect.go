@@ -0,0 +1,183 @@
+package classad
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/PelicanPlatform/classad/parser"
+)
+
+// Dialect selects reference-engine (libclassad) behaviors that this engine
+// deliberately does not follow by default: each field reproduces one quirk
+// catalogued in fuzz/CPP_QUIRKS.md, so a consumer that must agree bit for bit
+// with an HTCondor release can opt in to exactly the ones it needs. The zero
+// Dialect is the native behavior.
+//
+// A Dialect applies to parsing through its Parse, ParseOld and ParseExpr
+// methods, and to evaluation through ClassAd.SetDialect (an ad parsed by a
+// Dialect carries it), EvalOptions.Dialect and MatchClassAd.SetDialect.
+//
+// The quirks not listed here cannot be selected: the hangs of #2 and #3 are
+// bugs no caller wants reproduced, #4, #5, #7 and #13 are already matched,
+// and the bracket and operator recovery of #11 and #12 is not a lexer
+// leniency the grammar can take on.
+type Dialect struct {
+	// WrapIntOverflow parses a decimal integer literal too large for int64
+	// as 0 instead of rejecting the ad (CPP_QUIRKS #1).
+	WrapIntOverflow bool
+	// LenientDoubleDot parses a number with a doubled leading dot ("..5") as
+	// the real 0.0 instead of rejecting the ad (CPP_QUIRKS #10).
+	LenientDoubleDot bool
+	// CaseSensitiveVersionFuncs recognizes versionGT, versionGE, versionLT,
+	// versionLE and versionEQ only in that camelCase spelling; any other
+	// casing is an unknown function (CPP_QUIRKS #6).
+	CaseSensitiveVersionFuncs bool
+	// X86IntConversion makes int(), floor(), ceiling() and round() of NaN,
+	// an infinity or a real out of int64 range INT64_MIN, as the unguarded
+	// C++ cast yields on x86-64, instead of saturating (CPP_QUIRKS #8).
+	X86IntConversion bool
+	// ListIsByRepresentation makes =?= and =!= between a list literal and a
+	// list returned by split(), splitUserName() or splitSlotName() false and
+	// true, as libclassad's distinct list representations compare, instead
+	// of error (CPP_QUIRKS #9).
+	ListIsByRepresentation bool
+}
+
+// StrictCppDialect returns the Dialect with every selectable quirk enabled:
+// the closest this engine comes to libclassad on x86-64.
+func StrictCppDialect() Dialect {
+	return Dialect{
+		WrapIntOverflow:           true,
+		LenientDoubleDot:          true,
+		CaseSensitiveVersionFuncs: true,
+		X86IntConversion:          true,
+		ListIsByRepresentation:    true,
+	}
+}
+
+// parserOptions returns the lexer leniencies d enables.
+func (d Dialect) parserOptions() parser.Options {
+	return parser.Options{WrapIntOverflow: d.WrapIntOverflow, LenientDoubleDot: d.LenientDoubleDot}
+}
+
+// Parse is classad.Parse with d's parsing quirks. The ad it returns evaluates
+// in d (see ClassAd.SetDialect).
+func (d Dialect) Parse(input string) (*ClassAd, error) {
+	ad, err := parser.ParseClassAdWithOptions(input, d.parserOptions())
+	if err != nil {
+		return nil, err
+	}
+	obj := &ClassAd{ad: ad, attrsDirty: true}
+	obj.rebuildIndex()
+	obj.SetDialect(d)
+	return obj, nil
+}
+
+// ParseOld is classad.ParseOld with d's parsing quirks. The ad it returns
+// evaluates in d.
+func (d Dialect) ParseOld(input string) (*ClassAd, error) {
+	ad, err := parser.ParseOldClassAdWithOptions(input, d.parserOptions())
+	if err != nil {
+		return nil, err
+	}
+	if ad == nil {
+		return nil, fmt.Errorf("failed to parse old ClassAd")
+	}
+	obj := &ClassAd{ad: ad, attrsDirty: true}
+	obj.rebuildIndex()
+	obj.SetDialect(d)
+	return obj, nil
+}
+
+// ParseExpr is classad.ParseExpr with d's parsing quirks. An expression has no
+// dialect of its own: it evaluates in that of its scope ad, or of
+// EvalOptions.Dialect.
+func (d Dialect) ParseExpr(input string) (*Expr, error) {
+	expr, err := parser.ParseExprWithOptions(input, d.parserOptions())
+	if err != nil {
+		return nil, err
+	}
+	return &Expr{expr: expr}, nil
+}
+
+// SetDialect makes evaluations in c's scope follow d: EvaluateAttr and its
+// family, Expr.Eval with c as the scope, and matches c takes part in. The
+// zero Dialect restores the native behavior.
+func (c *ClassAd) SetDialect(d Dialect) {
+	if d == (Dialect{}) {
+		c.dialect = nil
+	} else {
+		c.dialect = &d
+	}
+	c.invalidateCache("")
+}
+
+// Dialect returns the dialect c evaluates in (see SetDialect).
+func (c *ClassAd) Dialect() Dialect {
+	if c == nil || c.dialect == nil {
+		return Dialect{}
+	}
+	return *c.dialect
+}
+
+// SetDialect makes the match evaluate in d, whatever the dialects of its ads,
+// which it leaves as they are. Until it is called a match evaluates each side
+// in the dialect of that side's ad.
+func (m *MatchClassAd) SetDialect(d Dialect) {
+	m.dialect = &d
+}
+
+// dialectOf returns the dialect an evaluation scoped to ad starts in: nil,
+// for the native behavior, unless SetDialect gave ad one.
+func dialectOf(ad *ClassAd) *Dialect {
+	if ad == nil {
+		return nil
+	}
+	return ad.dialect
+}
+
+// versionFuncSpelling holds the only spellings CaseSensitiveVersionFuncs
+// accepts, by lower-cased name.
+var versionFuncSpelling = map[string]string{
+	"versiongt": "versionGT",
+	"versionge": "versionGE",
+	"versionlt": "versionLT",
+	"versionle": "versionLE",
+	"versioneq": "versionEQ",
+}
+
+// x86IntConversion is the X86IntConversion result of int(), floor(),
+// ceiling() or round() of arg: INT64_MIN when arg is a real (or a numeric
+// string) the C++ cast cannot represent, which cvttsd2si turns into its
+// "integer indefinite" value. ok is false when the conversion is in range and
+// the builtin's own result stands.
+func x86IntConversion(arg Value) (v Value, ok bool) {
+	if arg.valueType != RealValue && arg.valueType != StringValue {
+		return Value{}, false
+	}
+	f, valid := coerceToReal(arg)
+	if !valid || (!math.IsNaN(f) && f < 0x1p63 && f >= -0x1p63) {
+		return Value{}, false
+	}
+	return NewIntValue(math.MinInt64), true
+}
+
+// listIsByRepresentation is =?= between two lists under
+// ListIsByRepresentation: libclassad compares the lists' representation tags
+// first, so a literal (an ExprList) against a split() result (an SList) is a
+// type mismatch, false, while two of the same representation are error.
+func listIsByRepresentation(left, right Value) Value {
+	if left.list.stringList != right.list.stringList {
+		return NewBoolValue(false)
+	}
+	return NewErrorValue()
+}
+
+// markStringList marks v, a list a split function returned, as the reference
+// engine's string-list representation.
+func markStringList(v Value) Value {
+	if v.IsList() {
+		v.list.stringList = true
+	}
+	return v
+}
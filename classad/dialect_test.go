@@ -0,0 +1,133 @@
+package classad
+
+import (
+	"math"
+	"testing"
+)
+
+func TestDialect_Parse(t *testing.T) {
+	src := `[a = 9223372036854775808; b = ..5; c = -9223372036854775808]`
+	if _, err := Parse(src); err == nil {
+		t.Fatal("Parse accepted an overflowing literal and ..5")
+	}
+	ad, err := StrictCppDialect().Parse(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a, ok := ad.EvaluateAttrInt("a"); !ok || a != 0 {
+		t.Errorf("a = %v, want 0", ad.EvaluateAttr("a"))
+	}
+	if b := ad.EvaluateAttr("b"); !b.IsReal() {
+		t.Errorf("b = %v, want 0.0", b)
+	}
+	if c, _ := ad.EvaluateAttrInt("c"); c != math.MinInt64 {
+		t.Errorf("c = %d, want INT64_MIN", c)
+	}
+	if ad.Dialect() != StrictCppDialect() {
+		t.Error("the parsed ad does not carry the dialect")
+	}
+	if _, err := StrictCppDialect().ParseOld("a = 99999999999999999999\nb = ..5"); err != nil {
+		t.Errorf("ParseOld: %v", err)
+	}
+	if _, err := (Dialect{LenientDoubleDot: true}).ParseExpr("99999999999999999999"); err == nil {
+		t.Error("ParseExpr wrapped an overflow it was not asked to")
+	}
+}
+
+func TestDialect_Evaluate(t *testing.T) {
+	src := `[
+		L = split("a b");
+		Mixed = {"a"} =?= L;
+		MixedNot = {"a"} =!= L;
+		Same = L =?= L;
+		Literals = {1} =?= {1};
+		NaN = int(real("nan"));
+		Inf = int("inf");
+		Big = floor(1e30);
+		InList = {round(-1e300)}[0];
+		Fine = int(2.5);
+		Lower = versiongt("2.0", "1.0");
+		Camel = versionGT("2.0", "1.0")
+	]`
+	native, err := Parse(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cpp, _ := Parse(src)
+	cpp.SetDialect(StrictCppDialect())
+
+	for _, tc := range []struct {
+		attr         string
+		native, want string
+	}{
+		{"Mixed", "error", "false"},
+		{"MixedNot", "error", "true"},
+		{"Same", "error", "error"},
+		{"Literals", "error", "error"},
+		{"NaN", "0", "-9223372036854775808"},
+		{"Inf", "9223372036854775807", "-9223372036854775808"},
+		{"InList", "-9223372036854775808", "-9223372036854775808"},
+		{"Fine", "2", "2"},
+		{"Lower", "true", "error"},
+		{"Camel", "true", "true"},
+	} {
+		if got := native.EvaluateAttr(tc.attr).String(); got != tc.native {
+			t.Errorf("native %s = %s, want %s", tc.attr, got, tc.native)
+		}
+		if got := cpp.EvaluateAttr(tc.attr).String(); got != tc.want {
+			t.Errorf("C++ %s = %s, want %s", tc.attr, got, tc.want)
+		}
+	}
+	if got := cpp.EvaluateAttr("Big").String(); got != "-9223372036854775808" {
+		t.Errorf("C++ Big = %s", got)
+	}
+
+	// EvalOptions.Dialect overrides the scope ad's.
+	expr := mustParseExpr(t, `versiongt("2.0", "1.0")`)
+	d := StrictCppDialect()
+	if v, _ := expr.EvalWithOptions(native, nil, EvalOptions{Dialect: &d}); !v.IsError() {
+		t.Errorf("EvalWithOptions in the C++ dialect = %v, want error", v)
+	}
+	if v, _ := expr.EvalWithOptions(cpp, nil, EvalOptions{Dialect: &Dialect{}}); !v.IsBool() {
+		t.Errorf("EvalWithOptions in the native dialect = %v, want true", v)
+	}
+	cpp.SetDialect(Dialect{})
+	if v := cpp.EvaluateAttr("Mixed"); !v.IsError() {
+		t.Errorf("Mixed after clearing the dialect = %v", v)
+	}
+}
+
+func TestDialect_Match(t *testing.T) {
+	job, _ := Parse(`[Requirements = versionge(TARGET.Version, "25.0")]`)
+	machine, _ := Parse(`[Version = "25.1"; Requirements = true]`)
+	m := NewMatchClassAd(job, machine)
+	if !m.Match() {
+		t.Fatal("native match failed")
+	}
+	m.SetDialect(Dialect{CaseSensitiveVersionFuncs: true})
+	if m.Match() {
+		t.Error("lower-case versionge matched in the C++ dialect")
+	}
+	job.InsertExpr("Requirements", mustParseExpr(t, `versionGE(TARGET.Version, "25.0")`))
+	if !m.Match() {
+		t.Error("versionGE did not match in the C++ dialect")
+	}
+	// The dialect is the match's: the ads keep their own.
+	if job.Dialect() != (Dialect{}) || machine.Dialect() != (Dialect{}) {
+		t.Errorf("ad dialects = %+v, %+v, want native", job.Dialect(), machine.Dialect())
+	}
+	if v := job.EvaluateAttr("Requirements"); !v.IsBool() {
+		t.Errorf("job Requirements outside the match = %v", v)
+	}
+}
+
+func TestDialect_NestedAd(t *testing.T) {
+	ad, _ := StrictCppDialect().Parse(`[Inner = [V = versiongt("2", "1")]]`)
+	inner, err := ad.EvaluateAttr("Inner").ClassAdValue()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v := inner.EvaluateAttr("V"); !v.IsError() {
+		t.Errorf("nested V = %v, want error in the C++ dialect", v)
+	}
+}
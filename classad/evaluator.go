@@ -29,6 +29,10 @@ const (
 	ListValue
 	// ClassAdValue represents a nested ClassAd value
 	ClassAdValue
+	// AbsTimeValue represents an absolute time (an instant and its UTC offset)
+	AbsTimeValue
+	// RelTimeValue represents a relative time (an interval in seconds)
+	RelTimeValue
 )
 
 // Value represents the result of evaluating a ClassAd expression.
@@ -42,7 +46,9 @@ type Value struct {
 	// intVal holds an integer value, a boolean (0 or 1), and -- for a RealValue --
 	// the IEEE-754 bits of the float64 (see real()/NewRealValue). Folding all three
 	// scalar payloads here keeps Value one word smaller than a separate bool/real
-	// field would, and the bits round-trip exactly (NaN/Inf/-0 included).
+	// field would, and the bits round-trip exactly (NaN/Inf/-0 included). A
+	// RelTimeValue stores its seconds the same way as a real, and an AbsTimeValue
+	// packs its seconds and UTC offset together (see timevalue.go).
 	intVal     int64
 	strVal     string
 	classAdVal *ClassAd
@@ -75,6 +81,18 @@ type listData struct {
 	exprs []ast.Expr
 	scope *ClassAd
 	depth int
+	// funcs is the scoped function registry of the evaluator that created a lazy
+	// list, so its elements resolve user functions the same way when materialized.
+	funcs *FunctionRegistry
+	// limits is the evaluation budget of that evaluator, so materializing the
+	// elements stays within it.
+	limits *evalBudget
+	// dialect is that evaluator's dialect, for the same reason.
+	dialect *Dialect
+	// stringList marks a list returned by split() and its kin, which
+	// libclassad represents apart from a list literal (see
+	// Dialect.ListIsByRepresentation).
+	stringList bool
 }
 
 // NewUndefinedValue creates an undefined value.
@@ -290,7 +308,12 @@ func (v Value) lazyEvaluator(parent *Evaluator) *Evaluator {
 	if parent != nil && parent.depth > depth {
 		depth = parent.depth
 	}
-	return &Evaluator{classad: v.list.scope, depth: depth}
+	ev := &Evaluator{classad: v.list.scope, depth: depth, funcs: v.list.funcs, limits: v.list.limits, dialect: v.list.dialect}
+	if parent != nil {
+		ev.trace = parent.trace
+		ev.pass = parent.pass
+	}
+	return ev
 }
 
 // ClassAdValue returns the ClassAd value. Returns error if not a ClassAd.
@@ -327,6 +350,10 @@ func (v Value) String() string {
 			return v.classAdVal.String()
 		}
 		return "[]"
+	case AbsTimeValue:
+		return (&ast.AbsTimeLiteral{Secs: v.absSecs(), Offset: v.absOffset()}).String()
+	case RelTimeValue:
+		return (&ast.RelTimeLiteral{Secs: v.real()}).String()
 	default:
 		return "unknown"
 	}
@@ -337,17 +364,26 @@ func (v Value) String() string {
 // top-level evaluation entry points.
 type cyclicEvalError struct{}
 
-// recoverCyclic recovers a cyclicEvalError panic and stores an error value in
+// recoverCyclic recovers a cyclicEvalError panic -- or the limitExceeded panic
+// of an evaluation that ran out of budget -- and stores an error value in
 // result; any other panic is re-raised. Use as `defer recoverCyclic(&result)`
 // with a named return value.
 func recoverCyclic(result *Value) {
 	if r := recover(); r != nil {
-		if _, ok := r.(cyclicEvalError); ok {
-			*result = NewErrorValue()
-			return
-		}
-		panic(r)
+		recovered(r, result)
+	}
+}
+
+// recovered handles a recovered panic value for recoverCyclic and RecoverCyclic,
+// which must each call recover() themselves: recover only stops a panic when
+// called directly by the deferred function.
+func recovered(r any, result *Value) {
+	switch r.(type) {
+	case cyclicEvalError, limitExceeded:
+		*result = NewErrorValue()
+		return
 	}
+	panic(r)
 }
 
 // Evaluator handles evaluation of ClassAd expressions.
@@ -363,6 +399,18 @@ type Evaluator struct {
 	// scope (see SetResolver). It lets a caller evaluate against an alternate
 	// backing (e.g. an encoded ad) without materializing a ClassAd.
 	resolver func(name string, scope ast.AttributeScope) Value
+	// funcs, when non-nil, is a scoped registry of user-defined functions
+	// consulted before the default registry (see SetFunctions).
+	funcs *FunctionRegistry
+	// trace, when non-nil, records every evaluated node (see Expr.EvalTrace).
+	trace *tracer
+	// limits, when non-nil, bounds the evaluation (see SetOptions).
+	limits *evalBudget
+	// pass numbers the evaluation for the memo of ads with EnableCache (see
+	// passID); 0 until the evaluation first reaches such an ad.
+	pass uint64
+	// dialect, when non-nil, selects reference-engine quirks (see Dialect).
+	dialect *Dialect
 }
 
 // maxEvalDepth bounds evaluation recursion. It is far below what overflows the
@@ -371,13 +419,13 @@ const maxEvalDepth = 2000
 
 // NewEvaluator creates a new evaluator for the given ClassAd.
 func NewEvaluator(ad *ClassAd) *Evaluator {
-	return &Evaluator{classad: ad}
+	return &Evaluator{classad: ad, dialect: dialectOf(ad)}
 }
 
 // child creates a sub-evaluator for ad that continues this evaluator's
 // recursion-depth accounting.
 func (e *Evaluator) child(ad *ClassAd) *Evaluator {
-	return &Evaluator{classad: ad, depth: e.depth}
+	return &Evaluator{classad: ad, depth: e.depth, funcs: e.funcs, trace: e.trace, limits: e.limits, pass: e.pass, dialect: e.dialect}
 }
 
 // Evaluate evaluates an expression in the context of the ClassAd.
@@ -401,8 +449,16 @@ func (e *Evaluator) Evaluate(expr ast.Expr) Value {
 		// Too deep to be anything but a cycle; treat it as one.
 		panic(cyclicEvalError{})
 	}
+	if e.limits != nil {
+		e.limits.step(e.depth)
+	}
 	e.depth++
-	v := e.evalNode(expr)
+	var v Value
+	if e.trace != nil {
+		v = e.trace.eval(e, expr)
+	} else {
+		v = e.evalNode(expr)
+	}
 	e.depth--
 	return v
 }
@@ -434,6 +490,12 @@ func (e *Evaluator) evalNode(expr ast.Expr) Value {
 	case *ast.ErrorLiteral:
 		return NewErrorValue()
 
+	case *ast.AbsTimeLiteral:
+		return newAbsTime(v.Secs, v.Offset)
+
+	case *ast.RelTimeLiteral:
+		return newRelTime(v.Secs)
+
 	case *ast.AttributeReference:
 		return e.evaluateAttributeReference(v)
 
@@ -453,10 +515,16 @@ func (e *Evaluator) evalNode(expr ast.Expr) Value {
 		return e.evaluateList(v)
 
 	case *ast.RecordLiteral:
-		return NewClassAdValue(&ClassAd{ad: v.ClassAd})
+		// The nested ad evaluates its own attributes in the dialect it was
+		// built in.
+		return NewClassAdValue(&ClassAd{ad: v.ClassAd, dialect: e.dialect})
 
 	case *ast.FunctionCall:
-		return e.evaluateFunctionCall(v)
+		result := e.evaluateFunctionCall(v)
+		if e.limits != nil {
+			e.limits.charge(result)
+		}
+		return result
 
 	case *ast.SelectExpr:
 		return e.evaluateSelectExpr(v)
@@ -579,6 +647,12 @@ func (e *Evaluator) evalAttrExpr(ad *ClassAd, norm string, expr ast.Expr) Value
 	if isLiteralExpr(expr) {
 		return e.Evaluate(expr)
 	}
+	cached := ad.cache != nil && e.trace == nil
+	if cached {
+		if v, ok := ad.cache.memoized(e.pass, norm); ok {
+			return v
+		}
+	}
 	if ad.evaluating[norm] {
 		panic(cyclicEvalError{})
 	}
@@ -593,6 +667,9 @@ func (e *Evaluator) evalAttrExpr(ad *ClassAd, norm string, expr ast.Expr) Value
 		delete(ad.evaluating, norm)
 	}()
 
+	if cached {
+		return e.evalCached(ad, norm, expr)
+	}
 	return e.Evaluate(expr)
 }
 
@@ -604,7 +681,8 @@ func (e *Evaluator) evalAttrExpr(ad *ClassAd, norm string, expr ast.Expr) Value
 func isLiteralExpr(e ast.Expr) bool {
 	switch e.(type) {
 	case *ast.IntegerLiteral, *ast.RealLiteral, *ast.StringLiteral,
-		*ast.BooleanLiteral, *ast.UndefinedLiteral, *ast.ErrorLiteral:
+		*ast.BooleanLiteral, *ast.UndefinedLiteral, *ast.ErrorLiteral,
+		*ast.AbsTimeLiteral, *ast.RelTimeLiteral:
 		return true
 	}
 	return false
@@ -651,6 +729,14 @@ func (e *Evaluator) applyBinaryValues(op string, left, right Value) Value {
 		return NewErrorValue()
 	}
 
+	// Time values have their own arithmetic and ordering (timevalue.go); ==
+	// and != on them are handled by valuesEqual.
+	if left.isTime() || right.isTime() {
+		if v, ok := timeBinary(op, left, right); ok {
+			return v
+		}
+	}
+
 	switch op {
 	// Arithmetic operators
 	case "+":
@@ -714,10 +800,13 @@ func (e *Evaluator) applyUnaryValue(op string, val Value) Value {
 			realVal, _ := val.RealValue()
 			return NewRealValue(-realVal)
 		}
+		if val.IsRelTime() {
+			return newRelTime(-val.real())
+		}
 		return NewErrorValue()
 
 	case "+":
-		if val.IsNumber() {
+		if val.IsNumber() || val.IsRelTime() {
 			return val
 		}
 		return NewErrorValue()
@@ -802,7 +891,7 @@ func (e *Evaluator) evaluateList(list *ast.ListLiteral) Value {
 	if exprs == nil {
 		exprs = []ast.Expr{}
 	}
-	return Value{valueType: ListValue, list: &listData{exprs: exprs, scope: e.classad, depth: e.depth}}
+	return Value{valueType: ListValue, list: &listData{exprs: exprs, scope: e.classad, depth: e.depth, funcs: e.funcs, limits: e.limits, dialect: e.dialect}}
 }
 
 func (e *Evaluator) evaluateSelectExpr(sel *ast.SelectExpr) Value {
@@ -935,7 +1024,7 @@ func (e *Evaluator) evaluateSubscriptExpr(sub *ast.SubscriptExpr) Value {
 
 		ad, _ := containerVal.ClassAdValue()
 		key, _ := indexVal.StringValue()
-		return ad.EvaluateAttr(key)
+		return ad.evaluateAttr(key, e.limits, nil)
 	}
 
 	return NewErrorValue()
@@ -1300,6 +1389,11 @@ func valuesEqual(left, right Value) Value {
 		leftStr, _ := left.StringValue()
 		rightStr, _ := right.StringValue()
 		return NewBoolValue(compareStringsFold(leftStr, rightStr) == 0)
+	case AbsTimeValue, RelTimeValue:
+		// Absolute times are equal when they are the same instant, whatever
+		// offsets they are expressed in.
+		cmp, _ := timeCompare(left, right)
+		return NewBoolValue(cmp == 0)
 	default:
 		return NewErrorValue()
 	}
@@ -1348,10 +1442,18 @@ func (e *Evaluator) evaluateIs(left, right Value) Value {
 		leftStr, _ := left.StringValue()
 		rightStr, _ := right.StringValue()
 		return NewBoolValue(leftStr == rightStr)
+	case AbsTimeValue:
+		// Identity also requires the same offset.
+		return NewBoolValue(left.intVal == right.intVal)
+	case RelTimeValue:
+		return NewBoolValue(left.real() == right.real())
 	case ListValue, ClassAdValue:
 		// The reference engine cannot compare lists or classads with =?= / =!=:
 		// such a comparison is an error (only the type-mismatch case above
 		// yields a boolean, e.g. {1} =?= 1 is false).
+		if left.IsList() && e.dialect != nil && e.dialect.ListIsByRepresentation {
+			return listIsByRepresentation(left, right)
+		}
 		return NewErrorValue()
 	default:
 		return NewErrorValue()
@@ -1566,14 +1668,12 @@ func (e *Evaluator) evaluateEval(args []ast.Expr) Value {
 	// Parse it as "[__eval_tmp = <expression>]" and extract the expression
 	wrappedStr := "[__eval_tmp = " + exprStr + "]"
 
-	node, err := parser.Parse(wrappedStr)
-	if err != nil {
-		return NewErrorValue()
+	var opts parser.Options
+	if e.dialect != nil {
+		opts = e.dialect.parserOptions()
 	}
-
-	// The result should be a ClassAd
-	classAd, ok := node.(*ast.ClassAd)
-	if !ok || len(classAd.Attributes) != 1 {
+	classAd, err := parser.ParseClassAdWithOptions(wrappedStr, opts)
+	if err != nil || len(classAd.Attributes) != 1 {
 		return NewErrorValue()
 	}
 
@@ -1764,7 +1864,11 @@ var functionArity = map[string]funcArity{
 	"stringlistmax": {1, 2}, "stringlistsintersect": {2, 3},
 	"stringlistsubsetmatch": {2, 3}, "stringlistregexpmember": {2, 4},
 	"regexpmember": {2, 3}, "regexps": {3, 4}, "replace": {3, 4},
-	"replaceall": {3, 4},
+	"replaceall": {3, 4}, "abstime": {0, 2}, "reltime": {1, 1}, "splittime": {1, 1},
+	"daytime": {0, 0}, "getyear": {1, 1}, "getmonth": {1, 1}, "getdayofyear": {1, 1},
+	"getdayofmonth": {1, 1}, "getdayofweek": {1, 1}, "getdays": {1, 1},
+	"gethours": {1, 1}, "getminutes": {1, 1}, "getseconds": {1, 1},
+	"indays": {1, 1}, "inhours": {1, 1}, "inminutes": {1, 1}, "inseconds": {1, 1},
 }
 
 // evaluateStrcat implements strcat with the reference engine's short-circuit:
@@ -1816,8 +1920,19 @@ func (e *Evaluator) evaluateFunctionCall(fc *ast.FunctionCall) Value {
 	// reference engine (which checks arity before evaluating args). So
 	// A((A0)) and pow(A0) -- with A0 the attribute itself -- are error without
 	// the cyclic argument ever being evaluated.
+	//
+	// A name that is not a built-in resolves through the user-function registries
+	// (see userfuncs.go), which apply the same arity-before-arguments rule.
 	arity, known := functionArity[funcName]
-	if !known || !arity.accepts(len(fc.Args)) {
+	if !known {
+		return e.callUserFunc(funcName, fc.Args)
+	}
+	if e.dialect != nil && e.dialect.CaseSensitiveVersionFuncs {
+		if spelling, ok := versionFuncSpelling[funcName]; ok && fc.Name != spelling {
+			return NewErrorValue()
+		}
+	}
+	if !arity.accepts(len(fc.Args)) {
 		return NewErrorValue()
 	}
 
@@ -1846,6 +1961,15 @@ func (e *Evaluator) evaluateFunctionCall(fc *ast.FunctionCall) Value {
 		args[i] = e.Evaluate(arg)
 	}
 
+	if e.dialect != nil && e.dialect.X86IntConversion && len(args) == 1 {
+		switch funcName {
+		case "int", "floor", "ceiling", "ceil", "round":
+			if v, ok := x86IntConversion(args[0]); ok {
+				return v
+			}
+		}
+	}
+
 	// Dispatch to the appropriate function (funcName is already lower-cased)
 	switch funcName {
 	// String functions
@@ -1895,6 +2019,25 @@ func (e *Evaluator) evaluateFunctionCall(fc *ast.FunctionCall) Value {
 	// Time functions
 	case "time":
 		return builtinTime(args)
+	case "abstime":
+		return builtinAbsTime(args)
+	case "reltime":
+		return builtinRelTime(args)
+	case "daytime":
+		return builtinDayTime(args)
+	case "splittime":
+		return builtinSplitTime(args)
+	case "getyear", "getmonth", "getdayofyear", "getdayofmonth", "getdayofweek",
+		"getdays", "gethours", "getminutes", "getseconds":
+		return builtinTimeField(funcName, args)
+	case "indays":
+		return builtinInUnits(86400, args)
+	case "inhours":
+		return builtinInUnits(3600, args)
+	case "inminutes":
+		return builtinInUnits(60, args)
+	case "inseconds":
+		return builtinInUnits(1, args)
 
 	// List functions
 	case "member":
@@ -1937,11 +2080,11 @@ func (e *Evaluator) evaluateFunctionCall(fc *ast.FunctionCall) Value {
 	case "join":
 		return builtinJoin(args)
 	case "split":
-		return builtinSplit(args)
+		return markStringList(builtinSplit(args))
 	case "splitusername":
-		return builtinSplitUserName(args)
+		return markStringList(builtinSplitUserName(args))
 	case "splitslotname":
-		return builtinSplitSlotName(args)
+		return markStringList(builtinSplitSlotName(args))
 	case "strcmp":
 		return builtinStrcmp(args)
 	case "stricmp":
@@ -342,3 +342,16 @@ func TestExprWithoutContext(t *testing.T) {
 		t.Errorf("Expected 14, got %d", value)
 	}
 }
+
+func TestLiteralExpr(t *testing.T) {
+	ad, err := Parse(`[RequestMemory = 1024 * 2; Names = {"a", toUpper("b")}]`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, want := range map[string]string{"RequestMemory": "2048", "Names": `{"a", "B"}`} {
+		expr := LiteralExpr(ad.EvaluateAttr(name))
+		if got := expr.String(); got != want {
+			t.Errorf("LiteralExpr(%s) = %s, want %s", name, got, want)
+		}
+	}
+}
@@ -0,0 +1,122 @@
+package format
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/PelicanPlatform/classad/classad"
+)
+
+// AutoFormat is a compiled -autoformat (-af): its arguments printed one
+// line per ad, space-separated, each as %v prints it. The option letters
+// that follow "-af:" change that:
+//
+//	j  start each line with the job id, ClusterId.ProcId
+//	l  label each value: "Attr = value"
+//	h  print a header line of the arguments (see Header)
+//	n  end each value with a newline instead of separating them
+//	t  separate values with a tab
+//	,  separate values with a comma
+//	r  print each argument's expression unevaluated (%r)
+//	V  print strings quoted (%V)
+type AutoFormat struct {
+	args                  []*arg
+	verb                  byte
+	jobID, labels, header bool
+	newline               bool
+	sep                   string
+}
+
+// ParseAutoFormat compiles -af:opts args... ; opts may be empty.
+func ParseAutoFormat(opts string, args ...string) (*AutoFormat, error) {
+	af := &AutoFormat{verb: 'v', sep: " "}
+	for _, c := range opts {
+		switch c {
+		case 'j':
+			af.jobID = true
+		case 'l':
+			af.labels = true
+		case 'h':
+			af.header = true
+		case 'n':
+			af.newline = true
+		case 't':
+			af.sep = "\t"
+		case ',':
+			af.sep = ","
+		case 'r':
+			af.verb = 'r'
+		case 'V':
+			af.verb = 'V'
+		default:
+			return nil, fmt.Errorf("format: unknown -af option %q", c)
+		}
+	}
+	if len(args) == 0 {
+		return nil, fmt.Errorf("format: -af needs an argument")
+	}
+	for _, src := range args {
+		a, err := parseArg(src)
+		if err != nil {
+			return nil, err
+		}
+		af.args = append(af.args, a)
+	}
+	return af, nil
+}
+
+// Header returns the header line the h option prints before the first ad --
+// the arguments, laid out as Sprint lays out their values -- or "" without
+// h.
+func (af *AutoFormat) Header() string {
+	if !af.header {
+		return ""
+	}
+	cells := make([]string, 0, len(af.args)+1)
+	if af.jobID {
+		cells = append(cells, "ID")
+	}
+	for _, a := range af.args {
+		cells = append(cells, a.src)
+	}
+	return af.join(cells)
+}
+
+// Sprint returns the line(s) af prints for ad.
+func (af *AutoFormat) Sprint(ad *classad.ClassAd) string {
+	cells := make([]string, 0, len(af.args)+1)
+	if af.jobID {
+		cluster, _ := jobIDPart.convert('v', ad)
+		proc, _ := procIDPart.convert('v', ad)
+		cells = append(cells, fmt.Sprintf("%s.%s", cluster, proc))
+	}
+	for _, a := range af.args {
+		v, _ := a.convert(af.verb, ad)
+		if af.labels {
+			cells = append(cells, a.src+" = "+v.(string))
+		} else {
+			cells = append(cells, v.(string))
+		}
+	}
+	return af.join(cells)
+}
+
+var (
+	jobIDPart  = &arg{src: "ClusterId", expr: mustExpr("ClusterId"), attr: true}
+	procIDPart = &arg{src: "ProcId", expr: mustExpr("ProcId"), attr: true}
+)
+
+func mustExpr(src string) *classad.Expr {
+	e, err := classad.ParseExpr(src)
+	if err != nil {
+		panic(err)
+	}
+	return e
+}
+
+func (af *AutoFormat) join(cells []string) string {
+	if af.newline {
+		return strings.Join(cells, "\n") + "\n"
+	}
+	return strings.Join(cells, af.sep) + "\n"
+}
@@ -0,0 +1,318 @@
+// Package format renders ClassAd attributes the way condor_q and
+// condor_status print them: -format's printf conversions, -autoformat's
+// option letters, and column tables with fixed or automatic widths.
+//
+//	f, err := format.Parse("%-12s %6d %.1f\n", "Owner", "ClusterId", "RequestMemory / 1024.0")
+//	for _, ad := range ads {
+//	    fmt.Print(f.Sprint(ad))
+//	}
+//
+//	af, err := format.ParseAutoFormat("jh", "Owner", "JobStatus") // -af:jh Owner JobStatus
+//	fmt.Print(af.Header())
+//	for _, ad := range ads {
+//	    fmt.Print(af.Sprint(ad))
+//	}
+//
+// Each argument is an attribute name or an expression, evaluated in the ad.
+// A conversion coerces the value as HTCondor does: %d truncates a real and
+// reads a numeric string, %f widens an integer, a boolean is 1 or 0 to
+// either, and %s prints a string's contents or any other value unparsed. %v
+// is %s that also prints undefined and error; %V prints the value unparsed,
+// strings quoted; %r prints the argument's expression unevaluated. A value
+// a conversion cannot take -- undefined, error, or "abc" to %d -- skips the
+// whole format for that ad, as an undefined attribute skips a -format.
+//
+// The package works on *classad.ClassAd. A collections.RawAd, as
+// QueryRawProjected yields it, is formatted by building its ad with ParseRaw.
+package format
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/PelicanPlatform/classad/classad"
+)
+
+// Format is a compiled -format: a printf-style format string and the
+// argument each of its conversions prints.
+type Format struct {
+	parts []part
+}
+
+// part is a literal run of text (verb == 0) or one conversion: spec is its
+// Go fmt directive (flags, width and precision with the C length modifiers
+// dropped) and arg the argument it prints.
+type part struct {
+	text string
+	verb byte
+	spec string
+	arg  *arg
+}
+
+// arg is an attribute name or expression argument.
+type arg struct {
+	src  string
+	expr *classad.Expr
+	attr bool // src names an attribute, so %r prints its definition
+}
+
+// Parse compiles format with one argument per conversion, in order.
+func Parse(format string, args ...string) (*Format, error) {
+	f := &Format{}
+	var text strings.Builder
+	n := 0
+	for i := 0; i < len(format); i++ {
+		c := format[i]
+		if c != '%' {
+			text.WriteByte(c)
+			continue
+		}
+		if i+1 < len(format) && format[i+1] == '%' {
+			text.WriteByte('%')
+			i++
+			continue
+		}
+		j := i + 1
+		for j < len(format) && strings.IndexByte("-+ #0", format[j]) >= 0 {
+			j++
+		}
+		for j < len(format) && (format[j] >= '0' && format[j] <= '9' || format[j] == '.') {
+			j++
+		}
+		flags := format[i+1 : j]
+		for j < len(format) && strings.IndexByte("hlLqjzt", format[j]) >= 0 {
+			j++
+		}
+		if j == len(format) {
+			return nil, fmt.Errorf("format: %q: incomplete conversion at end", format)
+		}
+		verb := format[j]
+		if strings.IndexByte("sdiuxXocfFeEgGvVrR", verb) < 0 {
+			return nil, fmt.Errorf("format: %q: unsupported conversion %%%c", format, verb)
+		}
+		if n == len(args) {
+			return nil, fmt.Errorf("format: %q has more conversions than the %d arguments", format, len(args))
+		}
+		a, err := parseArg(args[n])
+		if err != nil {
+			return nil, err
+		}
+		n++
+		if text.Len() > 0 {
+			f.parts = append(f.parts, part{text: text.String()})
+			text.Reset()
+		}
+		f.parts = append(f.parts, part{verb: verb, spec: "%" + flags + goVerb(verb), arg: a})
+		i = j
+	}
+	if n != len(args) {
+		return nil, fmt.Errorf("format: %q has %d conversions for %d arguments", format, n, len(args))
+	}
+	if text.Len() > 0 {
+		f.parts = append(f.parts, part{text: text.String()})
+	}
+	return f, nil
+}
+
+// goVerb is the Go fmt verb that prints a coerced value for a C conversion.
+func goVerb(verb byte) string {
+	switch verb {
+	case 'd', 'i', 'u':
+		return "d"
+	case 'F':
+		return "f"
+	case 'v', 'V', 'r', 'R':
+		return "s"
+	}
+	return string(verb)
+}
+
+func parseArg(src string) (*arg, error) {
+	expr, err := classad.ParseExpr(src)
+	if err != nil {
+		return nil, fmt.Errorf("format: argument %q: %w", src, err)
+	}
+	return &arg{src: src, expr: expr, attr: isAttrName(src)}, nil
+}
+
+func isAttrName(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if !(c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || i > 0 && c >= '0' && c <= '9') {
+			return false
+		}
+	}
+	return true
+}
+
+// Append appends ad formatted by f to dst; it appends nothing if a
+// conversion cannot take its value.
+func (f *Format) Append(dst []byte, ad *classad.ClassAd) []byte {
+	start := len(dst)
+	for _, p := range f.parts {
+		if p.verb == 0 {
+			dst = append(dst, p.text...)
+			continue
+		}
+		v, ok := p.arg.convert(p.verb, ad)
+		if !ok {
+			return dst[:start]
+		}
+		dst = fmt.Appendf(dst, p.spec, v)
+	}
+	return dst
+}
+
+// Sprint returns ad formatted by f, or "" if a conversion cannot take its
+// value.
+func (f *Format) Sprint(ad *classad.ClassAd) string {
+	return string(f.Append(nil, ad))
+}
+
+// Fprint writes ad formatted by f to w.
+func (f *Format) Fprint(w io.Writer, ad *classad.ClassAd) error {
+	_, err := w.Write(f.Append(nil, ad))
+	return err
+}
+
+// convert evaluates a in ad and coerces the value for verb, reporting false
+// if verb cannot print it.
+func (a *arg) convert(verb byte, ad *classad.ClassAd) (any, bool) {
+	switch verb {
+	case 'r', 'R':
+		return a.raw(ad), true
+	}
+	v := a.expr.Eval(ad)
+	switch verb {
+	case 'v':
+		return text(v), true
+	case 'V':
+		return unparse(v), true
+	}
+	if v.IsUndefined() || v.IsError() {
+		return nil, false
+	}
+	switch verb {
+	case 's':
+		return text(v), true
+	case 'f', 'F', 'e', 'E', 'g', 'G':
+		return toFloat(v)
+	case 'c':
+		n, ok := toInt(v)
+		if !ok {
+			return nil, false
+		}
+		return rune(n.(int64)), true
+	}
+	return toInt(v)
+}
+
+// raw is a's expression unevaluated: the definition of the attribute it
+// names, or the expression itself.
+func (a *arg) raw(ad *classad.ClassAd) string {
+	if !a.attr {
+		return a.expr.String()
+	}
+	if e, ok := ad.Lookup(a.src); ok {
+		return e.String()
+	}
+	return "undefined"
+}
+
+// text is v as %s and %v print it: a string's contents, anything else
+// unparsed.
+func text(v classad.Value) string {
+	if s, err := v.StringValue(); err == nil {
+		return s
+	}
+	return unparse(v)
+}
+
+// unparse is v in ClassAd syntax.
+func unparse(v classad.Value) string {
+	return classad.LiteralExpr(v).String()
+}
+
+func toInt(v classad.Value) (any, bool) {
+	switch {
+	case v.IsInteger():
+		n, _ := v.IntValue()
+		return n, true
+	case v.IsBool():
+		b, _ := v.BoolValue()
+		return boolInt(b), true
+	case v.IsNumber():
+		f, _ := v.NumberValue()
+		if math.IsNaN(f) || math.IsInf(f, 0) {
+			return nil, false
+		}
+		return int64(f), true
+	case v.IsString():
+		s, _ := v.StringValue()
+		s = strings.TrimSpace(s)
+		if n, err := strconv.ParseInt(s, 0, 64); err == nil {
+			return n, true
+		}
+		if f, err := strconv.ParseFloat(s, 64); err == nil && !math.IsNaN(f) && !math.IsInf(f, 0) {
+			return int64(f), true
+		}
+	}
+	return nil, false
+}
+
+func toFloat(v classad.Value) (any, bool) {
+	switch {
+	case v.IsNumber():
+		f, _ := v.NumberValue()
+		return f, true
+	case v.IsBool():
+		b, _ := v.BoolValue()
+		return float64(boolInt(b)), true
+	case v.IsString():
+		s, _ := v.StringValue()
+		if f, err := strconv.ParseFloat(strings.TrimSpace(s), 64); err == nil {
+			return f, true
+		}
+	}
+	return nil, false
+}
+
+func boolInt(b bool) int64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// ParseRaw builds the ad a collections.RawAd carries -- its "Name = Value"
+// expressions and type tags, as QueryRawProjected yields them -- so it can
+// be formatted: format.ParseRaw(ra.Exprs, ra.MyType, ra.TargetType). A RawAd
+// aliases a buffer its iterator reuses; the ad does not.
+func ParseRaw(exprs [][]byte, myType, targetType string) (*classad.ClassAd, error) {
+	var b bytes.Buffer
+	for _, e := range exprs {
+		b.Write(e)
+		b.WriteByte('\n')
+	}
+	ad := classad.New()
+	if b.Len() > 0 {
+		var err error
+		if ad, err = classad.ParseOld(b.String()); err != nil {
+			return nil, fmt.Errorf("format: raw ad: %w", err)
+		}
+	}
+	if myType != "" {
+		ad.InsertAttrString("MyType", myType)
+	}
+	if targetType != "" {
+		ad.InsertAttrString("TargetType", targetType)
+	}
+	return ad, nil
+}
@@ -0,0 +1,176 @@
+package format
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/PelicanPlatform/classad/classad"
+)
+
+func mustAd(t *testing.T, src string) *classad.ClassAd {
+	t.Helper()
+	ad, err := classad.Parse(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ad
+}
+
+const job = `[ClusterId = 42; ProcId = 3; Owner = "alice"; RequestMemory = 2048; RequestCpus = 2 * 2;
+	Rank = 1.5; Done = true; Count = "17"; Name = "x"; Tags = {"a", "b"}]`
+
+func TestFormat(t *testing.T) {
+	ad := mustAd(t, job)
+	tests := []struct {
+		format string
+		args   []string
+		want   string
+	}{
+		{"%s %d\n", []string{"Owner", "RequestMemory"}, "alice 2048\n"},
+		{"%-8s|%5i|", []string{"Owner", "RequestCpus"}, "alice   |    4|"},
+		{"%ld %lld %u", []string{"Rank", "Done", "Count"}, "1 1 17"},
+		{"%.2f %e %g", []string{"RequestMemory", "Rank", "Count"}, "2048.00 1.500000e+00 17"},
+		{"%x %X %o %c", []string{"255", "255", "8", "65"}, "ff FF 10 A"},
+		{"%s|%v|%V", []string{"Tags", "Name", "Name"}, `{"a", "b"}|x|"x"`},
+		{"%v %V", []string{"NoSuch", "NoSuch"}, "undefined undefined"},
+		{"%r / %r", []string{"RequestCpus", "RequestMemory / 1024"}, "(2 * 2) / (RequestMemory / 1024)"},
+		{"%d%%", []string{"RequestMemory * 100 / 4096"}, "50%"},
+		{"id=%d.%d", []string{"ClusterId", "ProcId"}, "id=42.3"},
+		{"no conversions", nil, "no conversions"},
+
+		// a value the conversion cannot take skips the whole format
+		{"%s\n", []string{"NoSuch"}, ""},
+		{"%s %d\n", []string{"Owner", "Name"}, ""},
+		{"%f", []string{"1/0"}, ""},
+	}
+	for _, tt := range tests {
+		f, err := Parse(tt.format, tt.args...)
+		if err != nil {
+			t.Errorf("Parse(%q): %v", tt.format, err)
+			continue
+		}
+		if got := f.Sprint(ad); got != tt.want {
+			t.Errorf("Parse(%q, %q).Sprint = %q, want %q", tt.format, tt.args, got, tt.want)
+		}
+	}
+}
+
+func TestFormatErrors(t *testing.T) {
+	tests := []struct {
+		format string
+		args   []string
+		want   string
+	}{
+		{"%s %s", []string{"A"}, "more conversions than the 1 arguments"},
+		{"%s", []string{"A", "B"}, "has 1 conversions for 2 arguments"},
+		{"%p", []string{"A"}, "unsupported conversion %p"},
+		{"%*d", []string{"A"}, "unsupported conversion %*"},
+		{"50%", nil, "incomplete conversion"},
+		{"%s", []string{"A +"}, `argument "A +"`},
+	}
+	for _, tt := range tests {
+		if _, err := Parse(tt.format, tt.args...); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("Parse(%q) error = %v, want %q", tt.format, err, tt.want)
+		}
+	}
+}
+
+func TestAutoFormat(t *testing.T) {
+	ad := mustAd(t, job)
+	tests := []struct {
+		opts   string
+		args   []string
+		header string
+		want   string
+	}{
+		{"", []string{"Owner", "RequestCpus", "NoSuch"}, "", "alice 4 undefined\n"},
+		{"jh", []string{"Owner", "Rank"}, "ID Owner Rank\n", "42.3 alice 1.5\n"},
+		{"l,", []string{"Owner", "Done"}, "", "Owner = alice,Done = true\n"},
+		{"tV", []string{"Owner", "Tags"}, "", "\"alice\"\t{\"a\", \"b\"}\n"},
+		{"rn", []string{"RequestCpus", "Owner"}, "", "(2 * 2)\n\"alice\"\n"},
+		{"hl", []string{"RequestMemory / 1024"}, "RequestMemory / 1024\n", "RequestMemory / 1024 = 2\n"},
+	}
+	for _, tt := range tests {
+		af, err := ParseAutoFormat(tt.opts, tt.args...)
+		if err != nil {
+			t.Errorf("ParseAutoFormat(%q): %v", tt.opts, err)
+			continue
+		}
+		if got := af.Header(); got != tt.header {
+			t.Errorf("-af:%s Header = %q, want %q", tt.opts, got, tt.header)
+		}
+		if got := af.Sprint(ad); got != tt.want {
+			t.Errorf("-af:%s Sprint = %q, want %q", tt.opts, got, tt.want)
+		}
+	}
+
+	if _, err := ParseAutoFormat("x", "A"); err == nil {
+		t.Error("unknown -af option accepted")
+	}
+	if _, err := ParseAutoFormat("j"); err == nil {
+		t.Error("-af without arguments accepted")
+	}
+}
+
+func TestTable(t *testing.T) {
+	ads := []*classad.ClassAd{
+		mustAd(t, `[Owner = "alice"; ClusterId = 7; RequestMemory = 2048; Cmd = "/usr/bin/simulate"]`),
+		mustAd(t, `[Owner = "bartholomew"; ClusterId = 1234; RequestMemory = 512.5]`),
+	}
+	tab, err := NewTable(
+		Column{Expr: "ClusterId", Label: "ID", Width: 6},
+		Column{Expr: "Owner"},
+		Column{Expr: "RequestMemory / 1024", Label: "MEM", Printf: "%.1f", Width: 5},
+		Column{Expr: "Cmd", Width: -8, Truncate: true},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var b strings.Builder
+	if err := tab.Write(&b, slices.Values(ads)); err != nil {
+		t.Fatal(err)
+	}
+	want := "" +
+		"    ID Owner         MEM Cmd\n" +
+		"     7 alice         2.0 /usr/bin\n" +
+		"  1234 bartholomew   0.5\n"
+	if b.String() != want {
+		t.Errorf("table =\n%s\nwant\n%s", b.String(), want)
+	}
+
+	tab, _ = NewTable(Column{Expr: "Owner", Width: -6}, Column{Expr: "ClusterId", Width: 4})
+	tab.Sep, tab.NoHeader = " | ", true
+	b.Reset()
+	if err := tab.Write(&b, slices.Values(ads)); err != nil {
+		t.Fatal(err)
+	}
+	if want := "alice  |    7\nbartholomew | 1234\n"; b.String() != want {
+		t.Errorf("table =\n%s\nwant\n%s", b.String(), want)
+	}
+	if got := tab.Row(ads[1]); !slices.Equal(got, []string{"bartholomew", "1234"}) {
+		t.Errorf("Row = %q", got)
+	}
+
+	if _, err := NewTable(Column{Expr: "A", Printf: "%d %d"}); err == nil || !strings.Contains(err.Error(), `column "A"`) {
+		t.Errorf("NewTable error = %v", err)
+	}
+}
+
+func TestParseRaw(t *testing.T) {
+	exprs := [][]byte{[]byte(`Owner = "alice"`), []byte("RequestCpus = 4")}
+	ad, err := ParseRaw(exprs, "Job", "Machine")
+	if err != nil {
+		t.Fatal(err)
+	}
+	f, _ := Parse("%s %d %s", "Owner", "RequestCpus", "MyType")
+	if got := f.Sprint(ad); got != "alice 4 Job" {
+		t.Errorf("Sprint = %q", got)
+	}
+	if ad, err := ParseRaw(nil, "", ""); err != nil || ad.Size() != 0 {
+		t.Errorf("ParseRaw(nil) = %v, %v", ad, err)
+	}
+	if _, err := ParseRaw([][]byte{[]byte("Owner = (")}, "", ""); err == nil {
+		t.Error("ParseRaw accepted a malformed expression")
+	}
+}
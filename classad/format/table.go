@@ -0,0 +1,161 @@
+package format
+
+import (
+	"fmt"
+	"io"
+	"iter"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/PelicanPlatform/classad/classad"
+)
+
+// Column is one column of a Table: an attribute or expression, its
+// heading, and how its cells are laid out.
+type Column struct {
+	// Expr is the attribute name or expression the column prints.
+	Expr string
+	// Label heads the column; empty uses Expr.
+	Label string
+	// Printf converts the value, as a -format conversion ("%.1f", "%d");
+	// empty is "%s". A value it cannot take, such as undefined, prints an
+	// empty cell.
+	Printf string
+	// Width pads each cell to that many characters, right-aligned; a
+	// negative width left-aligns, as printf's "%-10s" does. Zero sizes the
+	// column to its widest cell, left-aligned.
+	Width int
+	// Truncate cuts a cell wider than Width to fit, rather than letting it
+	// push the rest of the row right.
+	Truncate bool
+}
+
+// Table prints ads as aligned columns under a header line, as condor_q's
+// default and -print-format output does.
+type Table struct {
+	// Sep separates columns; empty is a single space.
+	Sep string
+	// NoHeader omits the header line.
+	NoHeader bool
+
+	cols    []Column
+	formats []*Format
+}
+
+// NewTable compiles a table of cols.
+func NewTable(cols ...Column) (*Table, error) {
+	t := &Table{cols: cols}
+	for _, c := range cols {
+		conv := c.Printf
+		if conv == "" {
+			conv = "%s"
+		}
+		f, err := Parse(conv, c.Expr)
+		if err != nil {
+			return nil, fmt.Errorf("format: column %q: %w", c.label(), err)
+		}
+		t.formats = append(t.formats, f)
+	}
+	return t, nil
+}
+
+func (c Column) label() string {
+	if c.Label != "" {
+		return c.Label
+	}
+	return c.Expr
+}
+
+// Row returns the cells of ad's row, unpadded.
+func (t *Table) Row(ad *classad.ClassAd) []string {
+	cells := make([]string, len(t.formats))
+	for i, f := range t.formats {
+		cells[i] = f.Sprint(ad)
+	}
+	return cells
+}
+
+// Write writes the header and a row per ad to w. A table with an
+// automatically sized column reads every ad before writing; one without
+// writes each row as it reads its ad.
+func (t *Table) Write(w io.Writer, ads iter.Seq[*classad.ClassAd]) error {
+	header := make([]string, len(t.cols))
+	for i, c := range t.cols {
+		header[i] = c.label()
+	}
+	widths := make([]int, len(t.cols))
+	auto := false
+	for i, c := range t.cols {
+		widths[i] = c.Width
+		if c.Width == 0 {
+			auto = true
+			if !t.NoHeader {
+				widths[i] = -utf8.RuneCountInString(header[i])
+			}
+		}
+	}
+	if !auto {
+		if !t.NoHeader {
+			if err := t.writeRow(w, header, widths); err != nil {
+				return err
+			}
+		}
+		for ad := range ads {
+			if err := t.writeRow(w, t.Row(ad), widths); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	var rows [][]string
+	for ad := range ads {
+		row := t.Row(ad)
+		for i, c := range t.cols {
+			if n := utf8.RuneCountInString(row[i]); c.Width == 0 && n > -widths[i] {
+				widths[i] = -n
+			}
+		}
+		rows = append(rows, row)
+	}
+	if !t.NoHeader {
+		rows = append([][]string{header}, rows...)
+	}
+	for _, row := range rows {
+		if err := t.writeRow(w, row, widths); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (t *Table) writeRow(w io.Writer, cells []string, widths []int) error {
+	sep := t.Sep
+	if sep == "" {
+		sep = " "
+	}
+	var b strings.Builder
+	for i, cell := range cells {
+		if i > 0 {
+			b.WriteString(sep)
+		}
+		width := widths[i]
+		if width < 0 {
+			width = -width
+		}
+		if t.cols[i].Truncate && width > 0 && utf8.RuneCountInString(cell) > width {
+			cell = string([]rune(cell)[:width])
+		}
+		pad := strings.Repeat(" ", max(width-utf8.RuneCountInString(cell), 0))
+		switch {
+		case widths[i] > 0:
+			b.WriteString(pad)
+			b.WriteString(cell)
+		default:
+			b.WriteString(cell)
+			b.WriteString(pad)
+		}
+	}
+	_, err := io.WriteString(w, strings.TrimRight(b.String(), " ")+"\n")
+	return err
+}
@@ -232,6 +232,9 @@ func unparseValueNested(v Value) (string, bool) {
 		return unparseString(s), true
 	case v.IsList():
 		return unparseList(v)
+	case v.isTime():
+		// Nested, a time is its literal form: absTime("...").
+		return v.String(), true
 	default:
 		return classadScalarString(v)
 	}
@@ -317,6 +320,8 @@ func classadScalarString(v Value) (string, bool) {
 	case v.IsReal():
 		r, _ := v.RealValue()
 		return classadReal(r), true
+	case v.isTime():
+		return timeValueString(v), true
 	default:
 		return "", false
 	}
@@ -594,6 +599,15 @@ func builtinInt(args []Value) Value {
 		return NewIntValue(floatToInt64(num))
 	}
 
+	// A time converts to its seconds: since the epoch for an absolute time,
+	// truncated toward zero for a relative one.
+	if args[0].IsAbsTime() {
+		return NewIntValue(args[0].absSecs())
+	}
+	if args[0].IsRelTime() {
+		return NewIntValue(floatToInt64(args[0].real()))
+	}
+
 	if args[0].IsBool() {
 		b, _ := args[0].BoolValue()
 		if b {
@@ -638,6 +652,13 @@ func builtinReal(args []Value) Value {
 		return NewRealValue(float64(num))
 	}
 
+	if args[0].IsAbsTime() {
+		return NewRealValue(float64(args[0].absSecs()))
+	}
+	if args[0].IsRelTime() {
+		return NewRealValue(args[0].real())
+	}
+
 	// Booleans convert to 1.0 / 0.0, matching the reference engine.
 	if args[0].IsBool() {
 		b, _ := args[0].BoolValue()
@@ -1842,11 +1863,15 @@ func builtinFormatTime(args []Value) Value {
 		if args[0].IsError() {
 			return NewErrorValue()
 		}
-		if !args[0].IsInteger() {
+		if args[0].IsAbsTime() {
+			// An absolute time is formatted in its own offset.
+			t, _ = args[0].AbsTimeValue()
+		} else if !args[0].IsInteger() {
 			return NewErrorValue()
+		} else {
+			timestamp, _ := args[0].IntValue()
+			t = time.Unix(timestamp, 0).UTC()
 		}
-		timestamp, _ := args[0].IntValue()
-		t = time.Unix(timestamp, 0).UTC()
 	} else {
 		t = time.Now().UTC()
 	}
@@ -0,0 +1,125 @@
+// Package expand holds what HTCondor configuration, submit descriptions and
+// job transforms share: logical lines joined at a trailing backslash, and the
+// syntax of $(name) references. The packages reading those differ in what a
+// reference means, so each resolves references its own way.
+package expand
+
+import (
+	"fmt"
+	"strings"
+)
+
+// MaxDepth bounds nested expansion, so a macro defined in terms of itself is
+// an error rather than a hang.
+const MaxDepth = 64
+
+// Syntax is the references a text recognizes beside the plain $(arg).
+type Syntax struct {
+	// Funcs are the macro functions $FN(arg), by upper-case name. Any other
+	// $FN( is left as written.
+	Funcs []string
+	// KeepDollarDollar leaves $$(arg) as written, for the schedd to expand
+	// at match time.
+	KeepDollarDollar bool
+}
+
+// Resolver returns the text a reference expands to. fn is the upper-case
+// function name, "" for $(arg), and arg is already expanded. depth is the
+// nesting of the reference; a resolver expanding a macro's value expands it
+// at depth+1.
+type Resolver func(fn, arg string, depth int) (string, error)
+
+// Expand replaces every reference in s, at nesting depth, with the text
+// resolve returns for it. A '$' that starts no reference is kept.
+func (x Syntax) Expand(s string, depth int, resolve Resolver) (string, error) {
+	if !strings.Contains(s, "$") {
+		return s, nil
+	}
+	if depth > MaxDepth {
+		return "", fmt.Errorf("macro expansion too deep in %q (recursive definition?)", s)
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); {
+		if s[i] != '$' {
+			b.WriteByte(s[i])
+			i++
+			continue
+		}
+		if x.KeepDollarDollar && strings.HasPrefix(s[i:], "$$(") {
+			end := strings.IndexByte(s[i:], ')')
+			if end < 0 {
+				b.WriteString(s[i:])
+				break
+			}
+			b.WriteString(s[i : i+end+1])
+			i += end + 1
+			continue
+		}
+		fn, open := x.RefAt(s, i)
+		if open < 0 {
+			b.WriteByte('$')
+			i++
+			continue
+		}
+		end := ClosingParen(s, open)
+		if end < 0 {
+			return "", fmt.Errorf("unterminated $%s( in %q", fn, s)
+		}
+		arg, err := x.Expand(s[open+1:end], depth+1, resolve)
+		if err != nil {
+			return "", err
+		}
+		val, err := resolve(fn, arg, depth)
+		if err != nil {
+			return "", err
+		}
+		b.WriteString(val)
+		i = end + 1
+	}
+	return b.String(), nil
+}
+
+// RefAt recognizes a reference at s[i], a '$': $(...) or one of x's
+// functions $FN(...). It returns the function name ("" for a plain
+// reference) and the index of the '(' (-1 if s[i] starts no reference).
+func (x Syntax) RefAt(s string, i int) (fn string, open int) {
+	j := i + 1
+	for j < len(s) && isLetter(s[j]) {
+		j++
+	}
+	if j >= len(s) || s[j] != '(' {
+		return "", -1
+	}
+	fn = strings.ToUpper(s[i+1 : j])
+	if fn == "" {
+		return "", j
+	}
+	for _, f := range x.Funcs {
+		if fn == f {
+			return fn, j
+		}
+	}
+	return "", -1
+}
+
+// ClosingParen returns the index of the ')' matching the '(' at s[open], or
+// -1.
+func ClosingParen(s string, open int) int {
+	depth := 0
+	for i := open; i < len(s); i++ {
+		switch s[i] {
+		case '(':
+			depth++
+		case ')':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+func isLetter(c byte) bool {
+	return c >= 'A' && c <= 'Z' || c >= 'a' && c <= 'z'
+}
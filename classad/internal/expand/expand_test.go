@@ -0,0 +1,62 @@
+package expand
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestExpand(t *testing.T) {
+	vars := map[string]string{"a": "1", "b": "$(a)2", "name": "a", "loop": "$(loop)"}
+	syntax := Syntax{Funcs: []string{"UP"}, KeepDollarDollar: true}
+	var resolve Resolver
+	resolve = func(fn, arg string, depth int) (string, error) {
+		if fn == "UP" {
+			return strings.ToUpper(arg), nil
+		}
+		return syntax.Expand(vars[arg], depth+1, resolve)
+	}
+	tests := map[string]string{
+		"plain":          "plain",
+		"$(a)-$(b)":      "1-12",
+		"$($(name))":     "1",
+		"$up(x$(a))":     "X1",
+		"$$(a) $(a)":     "$$(a) 1",
+		"$other(a) $ 5$": "$other(a) $ 5$",
+		"f($(a))":        "f(1)",
+	}
+	for in, want := range tests {
+		if got, err := syntax.Expand(in, 0, resolve); err != nil || got != want {
+			t.Errorf("Expand(%q) = %q, %v; want %q", in, got, err, want)
+		}
+	}
+	for in, want := range map[string]string{
+		"$(loop)": "too deep",
+		"$(a":     "unterminated $(",
+		"$up(a":   "unterminated $UP(",
+	} {
+		if _, err := syntax.Expand(in, 0, resolve); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("Expand(%q) error = %v, want %q", in, err, want)
+		}
+	}
+	// Without KeepDollarDollar the second '$' starts a reference.
+	if got, _ := (Syntax{}).Expand("$$(a)", 0, resolve); got != "$1" {
+		t.Errorf("Expand($$(a)) = %q, want $1", got)
+	}
+}
+
+func TestReadLines(t *testing.T) {
+	src := "A = 1 \\\n  + 2\nB = x\\\n# dropped\n  y\nC = \\"
+	lines, err := ReadLines(strings.NewReader(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Line{{1, "A = 1 + 2"}, {3, "B = xy"}, {6, "C = "}}
+	if len(lines) != len(want) {
+		t.Fatalf("ReadLines = %q, want %q", lines, want)
+	}
+	for i := range want {
+		if lines[i] != want[i] {
+			t.Errorf("line %d = %q, want %q", i, lines[i], want[i])
+		}
+	}
+}
@@ -0,0 +1,50 @@
+package expand
+
+import (
+	"bufio"
+	"io"
+	"strings"
+)
+
+// Line is a logical line: physical lines joined at a trailing backslash,
+// numbered by the first.
+type Line struct {
+	Num  int
+	Text string
+}
+
+// ReadLines splits r into logical lines. A comment line inside a continued
+// line is dropped.
+func ReadLines(r io.Reader) ([]Line, error) {
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	var lines []Line
+	var cur strings.Builder
+	start, n := 0, 0
+	continued := false
+	for sc.Scan() {
+		n++
+		text := strings.TrimRight(sc.Text(), " \t\r")
+		if !continued {
+			start = n
+		} else if text = strings.TrimLeft(text, " \t"); strings.HasPrefix(text, "#") {
+			continue
+		}
+		if strings.HasSuffix(text, `\`) {
+			cur.WriteString(text[:len(text)-1])
+			continued = true
+			continue
+		}
+		cur.WriteString(text)
+		lines = append(lines, Line{Num: start, Text: cur.String()})
+		cur.Reset()
+		continued = false
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	if continued {
+		lines = append(lines, Line{Num: start, Text: cur.String()})
+	}
+	return lines, nil
+}
@@ -0,0 +1,283 @@
+package classad
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"iter"
+)
+
+// jsonStream is the state of a Reader over a JSON document.
+type jsonStream struct {
+	br    *bufio.Reader
+	dec   *json.Decoder // nil until the first Next
+	array bool          // the ads are the elements of a top-level array
+	index int           // the number of ads read
+}
+
+// NewJSONReader creates a new Reader for parsing JSON ClassAds, one per JSON
+// object (see UnmarshalJSON): either the elements of one top-level array, as
+// condor_q -json and condor_status -json print them, or a stream of objects,
+// one per line (JSON Lines / NDJSON) or otherwise separated by whitespace. The
+// form is detected from the first byte; ads are decoded one at a time, so the
+// document is never held in memory.
+// Example format:
+//
+//	[
+//	{"Foo": 1, "Bar": "\/Expr(Foo + 1)\/"},
+//	{"Baz": 3}
+//	]
+func NewJSONReader(r io.Reader) *Reader {
+	return &Reader{
+		json: &jsonStream{br: bufio.NewReader(r)},
+	}
+}
+
+// nextJSON reads the next ad of a JSON document.
+func (r *Reader) nextJSON() bool {
+	s := r.json
+	if s.dec == nil {
+		first, err := skipJSONSpace(s.br)
+		if err == io.EOF {
+			return false
+		}
+		if err != nil {
+			r.err = err
+			return false
+		}
+		s.dec = json.NewDecoder(s.br)
+		if first == '[' {
+			if _, err := s.dec.Token(); err != nil {
+				r.err = fmt.Errorf("classad: json: %w", err)
+				return false
+			}
+			s.array = true
+		}
+	}
+
+	if s.array && !s.dec.More() {
+		r.err = s.endArray()
+		return false
+	}
+	var raw json.RawMessage
+	if err := s.dec.Decode(&raw); err != nil {
+		if err == io.EOF && !s.array {
+			return false
+		}
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		r.err = fmt.Errorf("classad: json: ad %d: %w", s.index, err)
+		return false
+	}
+	if len(raw) == 0 || raw[0] != '{' {
+		r.err = fmt.Errorf("classad: json: ad %d: %s is not an object", s.index, jsonKind(raw))
+		return false
+	}
+	ad := &ClassAd{}
+	if err := ad.UnmarshalJSON(raw); err != nil {
+		r.err = fmt.Errorf("classad: json: ad %d: %w", s.index, err)
+		return false
+	}
+	s.index++
+	r.current = ad
+	return true
+}
+
+// endArray consumes the closing bracket of the top-level array and checks that
+// nothing follows it.
+func (s *jsonStream) endArray() error {
+	tok, err := s.dec.Token()
+	if err == io.EOF {
+		return fmt.Errorf("classad: json: %w", io.ErrUnexpectedEOF)
+	}
+	if err != nil {
+		return fmt.Errorf("classad: json: %w", err)
+	}
+	if tok != json.Delim(']') {
+		return fmt.Errorf("classad: json: unexpected %v in array", tok)
+	}
+	if _, err := s.dec.Token(); err != io.EOF {
+		if err == nil {
+			err = errors.New("data after the array")
+		}
+		return fmt.Errorf("classad: json: %w", err)
+	}
+	return nil
+}
+
+// skipJSONSpace discards the leading whitespace of br and returns the first
+// other byte without consuming it.
+func skipJSONSpace(br *bufio.Reader) (byte, error) {
+	for {
+		b, err := br.ReadByte()
+		if err != nil {
+			return 0, err
+		}
+		switch b {
+		case ' ', '\t', '\r', '\n':
+			continue
+		}
+		return b, br.UnreadByte()
+	}
+}
+
+// jsonKind names the kind of an encoded JSON value, for errors.
+func jsonKind(raw json.RawMessage) string {
+	if len(raw) == 0 {
+		return "empty value"
+	}
+	switch raw[0] {
+	case '[':
+		return "an array"
+	case '"':
+		return "a string"
+	case 't', 'f':
+		return "a boolean"
+	case 'n':
+		return "null"
+	}
+	return "a number"
+}
+
+// AllJSON returns an iterator over all ClassAds of a JSON document (an array
+// of objects, or JSON Lines).
+// This function is compatible with Go 1.23+ range-over-function syntax.
+//
+// Example usage (Go 1.23+):
+//
+//	out, _ := exec.Command("condor_q", "-json").Output()
+//	for ad := range classad.AllJSON(bytes.NewReader(out)) {
+//	    // Process ad...
+//	}
+func AllJSON(r io.Reader) iter.Seq[*ClassAd] {
+	return func(yield func(*ClassAd) bool) {
+		reader := NewJSONReader(r)
+		for reader.Next() {
+			if !yield(reader.ClassAd()) {
+				return
+			}
+		}
+	}
+}
+
+// AllJSONWithIndex returns an iterator over all ClassAds of a JSON document
+// with their index.
+// This function is compatible with Go 1.23+ range-over-function syntax.
+func AllJSONWithIndex(r io.Reader) iter.Seq2[int, *ClassAd] {
+	return func(yield func(int, *ClassAd) bool) {
+		reader := NewJSONReader(r)
+		index := 0
+		for reader.Next() {
+			if !yield(index, reader.ClassAd()) {
+				return
+			}
+			index++
+		}
+	}
+}
+
+// AllJSONWithError returns an iterator for a JSON document that captures any
+// error.
+func AllJSONWithError(r io.Reader, errPtr *error) iter.Seq[*ClassAd] {
+	return func(yield func(*ClassAd) bool) {
+		reader := NewJSONReader(r)
+		for reader.Next() {
+			if !yield(reader.ClassAd()) {
+				return
+			}
+		}
+		if reader.Err() != nil && errPtr != nil {
+			*errPtr = reader.Err()
+		}
+	}
+}
+
+// JSONWriter writes ClassAds as JSON, one ad at a time: by default as one
+// array in the layout of condor_q -json, an ad per line between "[" and "]";
+// with Lines set, as JSON Lines (NDJSON), an ad per line and no array. Call
+// Close to finish the document.
+//
+// Example:
+//
+//	w := classad.NewJSONWriter(os.Stdout)
+//	w.Lines = true
+//	for _, ad := range ads {
+//	    if err := w.Write(ad); err != nil {
+//	        return err
+//	    }
+//	}
+//	return w.Close()
+type JSONWriter struct {
+	// Lines writes JSON Lines instead of an array.
+	Lines bool
+	// EscapeExprs writes expressions the way HTCondor does, as
+	// "\/Expr(x + 3)\/" rather than "/Expr(x + 3)/". JSON decoders, including
+	// UnmarshalJSON, read both the same.
+	EscapeExprs bool
+	// IncludePrivate writes each ad as MarshalJSONWithPrivate does rather
+	// than as MarshalJSON does.
+	IncludePrivate bool
+
+	w       io.Writer
+	written int
+	closed  bool
+}
+
+// NewJSONWriter returns a JSONWriter writing to w.
+func NewJSONWriter(w io.Writer) *JSONWriter {
+	return &JSONWriter{w: w}
+}
+
+// Write appends ad to the document.
+func (x *JSONWriter) Write(ad *ClassAd) error {
+	if x.closed {
+		return fmt.Errorf("classad: json: write after Close")
+	}
+	b, err := ad.jsonBytes(x.IncludePrivate)
+	if err != nil {
+		return err
+	}
+	if x.EscapeExprs {
+		b = escapeJSONExprs(b)
+	}
+	var sep string
+	switch {
+	case x.Lines:
+	case x.written == 0:
+		sep = "[\n"
+	default:
+		sep = ",\n"
+	}
+	if _, err := io.WriteString(x.w, sep); err != nil {
+		return err
+	}
+	if _, err := x.w.Write(b); err != nil {
+		return err
+	}
+	x.written++
+	if x.Lines {
+		_, err = io.WriteString(x.w, "\n")
+	}
+	return err
+}
+
+// Close ends the document (writing an empty array if nothing was written and
+// Lines is not set). It does not close the underlying writer.
+func (x *JSONWriter) Close() error {
+	if x.closed {
+		return nil
+	}
+	x.closed = true
+	switch {
+	case x.Lines:
+		return nil
+	case x.written == 0:
+		_, err := io.WriteString(x.w, "[]\n")
+		return err
+	}
+	_, err := io.WriteString(x.w, "\n]\n")
+	return err
+}
@@ -0,0 +1,120 @@
+package classad
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestJSONWriterAndReader(t *testing.T) {
+	ad1, _ := Parse(`[Name = "slot1"; Cpus = 4; Requirements = MY.Cpus > 2]`)
+	ad2, _ := Parse(`[Name = "slot2"; Memory = 2048]`)
+
+	for _, tt := range []struct {
+		lines, escape bool
+		want          string
+	}{
+		{false, true, `[
+{"Cpus":4,"Name":"slot1","Requirements":"\/Expr((MY.Cpus \u003e 2))\/"},
+{"Memory":2048,"Name":"slot2"}
+]
+`},
+		{true, false, `{"Cpus":4,"Name":"slot1","Requirements":"/Expr((MY.Cpus \u003e 2))/"}
+{"Memory":2048,"Name":"slot2"}
+`},
+	} {
+		var buf strings.Builder
+		w := NewJSONWriter(&buf)
+		w.Lines, w.EscapeExprs = tt.lines, tt.escape
+		for _, ad := range []*ClassAd{ad1, ad2} {
+			if err := w.Write(ad); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := w.Close(); err != nil {
+			t.Fatal(err)
+		}
+		if buf.String() != tt.want {
+			t.Errorf("JSONWriter (Lines=%v) output:\n%s\nwant:\n%s", tt.lines, buf.String(), tt.want)
+		}
+		if err := w.Write(ad1); err == nil {
+			t.Error("Write after Close succeeded")
+		}
+
+		var got []*ClassAd
+		var err error
+		for ad := range AllJSONWithError(strings.NewReader(buf.String()), &err) {
+			got = append(got, ad)
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(got) != 2 || !got[0].Equal(ad1) || !got[1].Equal(ad2) {
+			t.Fatalf("read back %d ads: %v", len(got), got)
+		}
+		if ok, _ := got[0].EvaluateAttrBool("Requirements"); !ok {
+			t.Error("Requirements did not survive the round trip")
+		}
+	}
+}
+
+func TestJSONWriter_Empty(t *testing.T) {
+	var buf strings.Builder
+	if err := NewJSONWriter(&buf).Close(); err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != "[]\n" {
+		t.Errorf("empty array = %q", buf.String())
+	}
+	for _, doc := range []string{buf.String(), "", "  \n"} {
+		reader := NewJSONReader(strings.NewReader(doc))
+		if reader.Next() || reader.Err() != nil {
+			t.Errorf("empty document %q: Next() = true or Err() = %v", doc, reader.Err())
+		}
+	}
+}
+
+func TestNewJSONReader_Forms(t *testing.T) {
+	for _, doc := range []string{
+		"\n [ {\"A\": 1}, {\"A\": 2},\n{\"A\": 3} ] \n",
+		"{\"A\": 1}\n{\"A\": 2}\n\n{\"A\": 3}",
+		`{"A": 1}{"A": 2} {"A": 3}`,
+	} {
+		var sum int64
+		for i, ad := range AllJSONWithIndex(strings.NewReader(doc)) {
+			a, _ := ad.EvaluateAttrInt("A")
+			if a != int64(i+1) {
+				t.Errorf("%q: ad %d has A = %d", doc, i, a)
+			}
+			sum += a
+		}
+		if sum != 6 {
+			t.Errorf("%q: read A values summing to %d, want 6", doc, sum)
+		}
+	}
+}
+
+func TestNewJSONReader_Errors(t *testing.T) {
+	for _, tt := range []struct {
+		doc, want string
+	}{
+		{`[{"A": 1}, 7]`, "ad 1: a number is not an object"},
+		{`{"A": 1} [1]`, "ad 1: an array is not an object"},
+		{`[{"A": 1}, {"A": `, "ad 1: unexpected EOF"},
+		{`[{"A": 1}`, "unexpected end"},
+		{`[{"A": 1}] {"B": 2}`, "data after the array"},
+		{`[{"A": 1}, {"B": "/Expr(1 +)/"}]`, "ad 1: failed to unmarshal attribute B"},
+		{`{"A": 1} {"A" 2}`, "ad 1: invalid character"},
+	} {
+		reader := NewJSONReader(strings.NewReader(tt.doc))
+		if !reader.Next() {
+			t.Errorf("%s: expected the first ClassAd, got error: %v", tt.doc, reader.Err())
+			continue
+		}
+		if reader.Next() {
+			t.Errorf("%s: expected failure after the first ClassAd", tt.doc)
+		}
+		if err := reader.Err(); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: Err() = %v, want %q", tt.doc, err, tt.want)
+		}
+	}
+}
@@ -3,6 +3,7 @@ package classad
 import (
 	"os"
 	"regexp"
+	"sort"
 	"testing"
 )
 
@@ -55,3 +56,15 @@ func TestUnknownFunctionDoesNotEvaluateArgs(t *testing.T) {
 		t.Errorf("A0 = %v, want true", v)
 	}
 }
+
+func TestBuiltinFunctions(t *testing.T) {
+	names := BuiltinFunctions()
+	if len(names) != len(functionArity) || !sort.StringsAreSorted(names) {
+		t.Fatalf("BuiltinFunctions = %d names, sorted %v", len(names), sort.StringsAreSorted(names))
+	}
+	for _, name := range names {
+		if !IsBuiltinFunction(name) {
+			t.Errorf("%s is not a built-in", name)
+		}
+	}
+}
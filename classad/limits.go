@@ -0,0 +1,216 @@
+package classad
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// EvalOptions bounds an evaluation, for expressions that come from an untrusted
+// source (a query constraint sent to a shared daemon, say). A zero field is no
+// limit; the zero EvalOptions evaluates exactly like Eval.
+//
+// An evaluation that exceeds a limit stops at once and yields error -- not the
+// value of some partial evaluation, and regardless of operators such as
+// isError() that would otherwise absorb an error. The entry point that ran it
+// reports why with a *LimitError.
+type EvalOptions struct {
+	// MaxSteps caps the evaluation steps: one per expression node evaluated
+	// (including the nodes of every attribute the expression reaches), and one
+	// per instruction of a compiled program.
+	MaxSteps int64
+	// MaxDepth caps the nesting depth of the evaluation, counting each
+	// attribute reference as a level. It cannot raise the evaluator's own
+	// recursion bound, past which an evaluation is treated as cyclic.
+	MaxDepth int
+	// MaxAllocBytes caps the approximate bytes of the values function calls
+	// build: strings, and lists with their elements. It is what stops a
+	// strcat() or split() from growing without bound.
+	MaxAllocBytes int64
+	// Context, when non-nil, cancels the evaluation once it is done. It is
+	// polled every contextPollSteps steps, not on every step.
+	Context context.Context
+	// Dialect, when non-nil, is the dialect to evaluate in, in place of the
+	// scope ad's (see Dialect). It sets no limit.
+	Dialect *Dialect
+}
+
+// bounded reports whether o sets any limit.
+func (o EvalOptions) bounded() bool {
+	return o.MaxSteps > 0 || o.MaxDepth > 0 || o.MaxAllocBytes > 0 || o.Context != nil
+}
+
+// The limits an EvalOptions evaluation can exceed, as the Err of a LimitError.
+var (
+	ErrMaxSteps      = errors.New("evaluation step limit exceeded")
+	ErrMaxDepth      = errors.New("evaluation depth limit exceeded")
+	ErrMaxAllocBytes = errors.New("evaluation allocation limit exceeded")
+)
+
+// LimitError is the cause of the error value of an evaluation stopped by its
+// EvalOptions. Err is ErrMaxSteps, ErrMaxDepth, ErrMaxAllocBytes, or the
+// Context's error (context.Canceled or context.DeadlineExceeded), so callers
+// can test it with errors.Is.
+type LimitError struct {
+	Err   error
+	Limit int64 // the limit exceeded; 0 for a cancelled Context
+}
+
+func (e *LimitError) Error() string {
+	if e.Limit > 0 {
+		return fmt.Sprintf("classad: %v (limit %d)", e.Err, e.Limit)
+	}
+	return fmt.Sprintf("classad: evaluation stopped: %v", e.Err)
+}
+
+func (e *LimitError) Unwrap() error { return e.Err }
+
+// limitExceeded is panicked when an evaluation exceeds its budget, unwinding it
+// to the entry point, where recoverCyclic turns it into an error value.
+type limitExceeded struct{}
+
+// contextPollSteps is how often, in steps, a bounded evaluation checks its
+// Context. Checking costs far more than a step, and a few hundred steps take
+// microseconds.
+const contextPollSteps = 256
+
+// evalBudget is the state of a bounded evaluation: its limits and what it has
+// used. It is shared by the evaluator and every child evaluator it creates.
+type evalBudget struct {
+	opts  EvalOptions
+	steps int64
+	alloc int64
+	err   *LimitError
+}
+
+// reset starts a new evaluation under the same limits.
+func (b *evalBudget) reset() {
+	b.steps, b.alloc, b.err = 0, 0, nil
+}
+
+// step counts one evaluation step at depth. Once the budget is exhausted every
+// step fails, so an evaluation that recovered locally (a lazy list element)
+// still cannot continue.
+func (b *evalBudget) step(depth int) {
+	if b.err != nil {
+		panic(limitExceeded{})
+	}
+	b.steps++
+	switch {
+	case b.opts.MaxSteps > 0 && b.steps > b.opts.MaxSteps:
+		b.fail(ErrMaxSteps, b.opts.MaxSteps)
+	case b.opts.MaxDepth > 0 && depth >= b.opts.MaxDepth:
+		b.fail(ErrMaxDepth, int64(b.opts.MaxDepth))
+	case b.opts.Context != nil && b.steps%contextPollSteps == 1:
+		if err := b.opts.Context.Err(); err != nil {
+			b.fail(err, 0)
+		}
+	}
+}
+
+// charge counts the bytes of a value a function call built.
+func (b *evalBudget) charge(v Value) {
+	if b.opts.MaxAllocBytes <= 0 {
+		return
+	}
+	b.alloc += valueBytes(v)
+	if b.alloc > b.opts.MaxAllocBytes {
+		b.fail(ErrMaxAllocBytes, b.opts.MaxAllocBytes)
+	}
+}
+
+func (b *evalBudget) fail(err error, limit int64) {
+	b.err = &LimitError{Err: err, Limit: limit}
+	panic(limitExceeded{})
+}
+
+// valueBytes approximates the memory a value holds beyond its Value header: a
+// string's bytes, and a list's element headers plus what they hold. A lazy
+// list's elements are still expressions of the source, already paid for.
+func valueBytes(v Value) int64 {
+	switch v.valueType {
+	case StringValue:
+		return int64(len(v.strVal))
+	case ListValue:
+		if v.list.exprs != nil {
+			return int64(len(v.list.exprs)) * 16
+		}
+		n := int64(len(v.list.vals)) * 48
+		for _, el := range v.list.vals {
+			n += valueBytes(el)
+		}
+		return n
+	}
+	return 0
+}
+
+// SetOptions bounds every subsequent evaluation by this evaluator to opts (see
+// EvalOptions); zero options remove the bounds. Each evaluation gets the full
+// budget: SetScope starts a new one. After an evaluation yields error, LimitErr
+// says whether a limit was the cause. A non-nil opts.Dialect replaces the
+// evaluator's dialect.
+func (e *Evaluator) SetOptions(opts EvalOptions) {
+	if opts.Dialect != nil {
+		e.dialect = opts.Dialect
+	}
+	if !opts.bounded() {
+		e.limits = nil
+		return
+	}
+	e.limits = &evalBudget{opts: opts}
+}
+
+// LimitErr returns the *LimitError that stopped the current evaluation, or nil
+// if it ran within its limits (or none were set).
+func (e *Evaluator) LimitErr() error {
+	if e.limits == nil || e.limits.err == nil {
+		return nil
+	}
+	return e.limits.err
+}
+
+// Bounded reports whether SetOptions set limits on the evaluator.
+func (e *Evaluator) Bounded() bool { return e.limits != nil }
+
+// Step counts one step of an evaluation the caller drives itself (an
+// interpreter running a compiled program over the evaluator's hooks), with depth
+// levels of nesting below the evaluator's own depth. When the budget is
+// exhausted it unwinds the evaluation as a cyclic reference does, so the caller
+// must run under RecoverCyclic. Without SetOptions it does nothing.
+func (e *Evaluator) Step(depth int) {
+	if e.limits != nil {
+		e.limits.step(e.depth + depth)
+	}
+}
+
+// EvalWithOptions is EvalWithContext bounded by opts. When a limit stops the
+// evaluation the value is error and the error a *LimitError; otherwise the
+// error is nil.
+//
+// Example:
+//
+//	ctx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+//	defer cancel()
+//	v, err := expr.EvalWithOptions(ad, nil, classad.EvalOptions{MaxSteps: 100000, Context: ctx})
+//	if errors.Is(err, classad.ErrMaxSteps) { ... }
+func (e *Expr) EvalWithOptions(scope, target *ClassAd, opts EvalOptions) (result Value, err error) {
+	if e.expr == nil {
+		return NewUndefinedValue(), nil
+	}
+	if scope != nil && target != nil {
+		oldTarget := scope.target
+		scope.target = target
+		defer func() { scope.target = oldTarget }()
+	}
+
+	evaluator := NewEvaluator(scope)
+	evaluator.SetOptions(opts)
+	result = func() (v Value) {
+		defer recoverCyclic(&v)
+		return evaluator.Evaluate(e.expr)
+	}()
+	if err := evaluator.LimitErr(); err != nil {
+		return NewErrorValue(), err
+	}
+	return result, nil
+}
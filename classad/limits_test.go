@@ -0,0 +1,115 @@
+package classad
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestEvalWithOptions_Limits(t *testing.T) {
+	ad, _ := Parse(`[
+		A = 1; B = A + A; C = B + B; D = C + C;
+		S0 = "x,x,x,x,x,x,x,x,"; S1 = strcat(S0, S0); S2 = strcat(S1, S1); S3 = strcat(S2, S2);
+		L = split(S3, ",")
+	]`)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	tests := []struct {
+		expr string
+		opts EvalOptions
+		want error // nil: within the limits
+	}{
+		{"D", EvalOptions{MaxSteps: 100}, nil},
+		{"D", EvalOptions{MaxSteps: 10}, ErrMaxSteps},
+		{"isError(D)", EvalOptions{MaxSteps: 10}, ErrMaxSteps},
+		{"D", EvalOptions{MaxDepth: 8}, nil},
+		{"D", EvalOptions{MaxDepth: 4}, ErrMaxDepth},
+		{"size(S3)", EvalOptions{MaxAllocBytes: 1000}, nil},
+		{"size(S3)", EvalOptions{MaxAllocBytes: 100}, ErrMaxAllocBytes},
+		{"size(L)", EvalOptions{MaxAllocBytes: 1000}, ErrMaxAllocBytes},
+		{"{D, 1}[1]", EvalOptions{MaxSteps: 100}, nil},
+		{"D", EvalOptions{Context: ctx}, context.Canceled},
+	}
+	for _, tt := range tests {
+		expr, err := ParseExpr(tt.expr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		v, err := expr.EvalWithOptions(ad, nil, tt.opts)
+		if tt.want == nil {
+			if err != nil || v.String() != expr.Eval(ad).String() {
+				t.Errorf("%s %+v = %v, %v; want %v", tt.expr, tt.opts, v, err, expr.Eval(ad))
+			}
+			continue
+		}
+		var le *LimitError
+		if !errors.As(err, &le) || !errors.Is(err, tt.want) {
+			t.Errorf("%s %+v: err = %v, want %v", tt.expr, tt.opts, err, tt.want)
+		}
+		if !v.IsError() {
+			t.Errorf("%s %+v = %v, want error", tt.expr, tt.opts, v)
+		}
+	}
+}
+
+func TestEvalWithOptions_LazyListElement(t *testing.T) {
+	// A lazy list element recovers a failure locally; the budget must still
+	// fail the whole evaluation.
+	ad, _ := Parse(`[A = 1; B = A + A + A + A + A + A + A + A + A + A]`)
+	expr, _ := ParseExpr(`isError({B}[0]) ? "absorbed" : "ok"`)
+	v, err := expr.EvalWithOptions(ad, nil, EvalOptions{MaxSteps: 12})
+	if !errors.Is(err, ErrMaxSteps) || !v.IsError() {
+		t.Errorf("= %v, %v; want error, ErrMaxSteps", v, err)
+	}
+}
+
+func TestEvaluator_SetOptionsPerScope(t *testing.T) {
+	small, _ := Parse(`[X = 1]`)
+	big, _ := Parse(`[X = 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1]`)
+	ev := NewEvaluator(nil)
+	ev.SetOptions(EvalOptions{MaxSteps: 6})
+	expr, _ := ParseExpr("X > 0")
+
+	eval := func(ad *ClassAd) (v Value) {
+		ev.SetScope(ad)
+		defer RecoverCyclic(&v)
+		return ev.Evaluate(expr.internal())
+	}
+	if v := eval(big); !v.IsError() || ev.LimitErr() == nil {
+		t.Errorf("big: %v, %v; want error over the step limit", v, ev.LimitErr())
+	}
+	// SetScope gives the next evaluation a fresh budget.
+	if v := eval(small); v.String() != "true" || ev.LimitErr() != nil {
+		t.Errorf("small: %v, %v; want true", v, ev.LimitErr())
+	}
+	if err := (&LimitError{Err: ErrMaxSteps, Limit: 6}).Error(); !strings.Contains(err, "limit 6") {
+		t.Errorf("Error() = %q", err)
+	}
+}
+
+func TestMatchClassAd_SetOptions(t *testing.T) {
+	job, _ := Parse(`[Requirements = TARGET.Cpus > 0; Rank = TARGET.Cpus]`)
+	small, _ := Parse(`[Cpus = 4; Requirements = true]`)
+	big, _ := Parse(`[Cpus = 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1; Requirements = true]`)
+	m := NewMatchClassAd(job, nil)
+	m.SetOptions(EvalOptions{MaxSteps: 10})
+
+	m.ReplaceRightAd(big)
+	if m.Match() || !errors.Is(m.LimitErr(), ErrMaxSteps) {
+		t.Errorf("big: matched or LimitErr = %v; want no match over the step limit", m.LimitErr())
+	}
+	// Each evaluation gets the full budget.
+	m.ReplaceRightAd(small)
+	if !m.Match() || m.LimitErr() != nil {
+		t.Errorf("small: no match or LimitErr = %v; want a match", m.LimitErr())
+	}
+	if r, ok := m.EvaluateRankLeft(); !ok || r != 4 {
+		t.Errorf("small: Rank = %v, %v; want 4", r, ok)
+	}
+	m.SetOptions(EvalOptions{})
+	m.ReplaceRightAd(big)
+	if !m.Match() || m.LimitErr() != nil {
+		t.Errorf("unbounded: no match or LimitErr = %v", m.LimitErr())
+	}
+}
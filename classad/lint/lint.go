@@ -0,0 +1,900 @@
+// Package lint statically checks ClassAd expressions and ads for mistakes that
+// parse cleanly but cannot evaluate as their author intended: calls to unknown
+// functions or with the wrong number of arguments, comparisons between values
+// of incompatible types, references to attributes a schema says never exist,
+// reference cycles, == against undefined where =?= was meant, and conjuncts
+// that make an && chain always false.
+//
+// It is meant to catch a submit file's Requirements bug before the expression
+// reaches a schedd:
+//
+//	diags, err := lint.Expr(`TARGET.Memroy > 1024 && OpSys == undefined`, &lint.Options{
+//	    Schema: &lint.Schema{Target: machineAttrs},
+//	})
+//	for _, d := range diags {
+//	    fmt.Println(d)
+//	}
+//
+// Nothing is evaluated except constant sub-expressions, so linting is safe on
+// untrusted input.
+package lint
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/PelicanPlatform/classad/ast"
+	"github.com/PelicanPlatform/classad/classad"
+	"github.com/PelicanPlatform/classad/parser"
+)
+
+// Severity is how sure a check is that it found a bug.
+type Severity int
+
+const (
+	// Warning: almost certainly not what was meant, but the expression
+	// evaluates.
+	Warning Severity = iota
+	// Error: the expression (or part of it) always evaluates to error.
+	Error
+)
+
+func (s Severity) String() string {
+	if s == Error {
+		return "error"
+	}
+	return "warning"
+}
+
+// Check names the check that produced a Diagnostic.
+type Check string
+
+const (
+	CheckUnknownFunction  Check = "unknown-function"  // a call to a function no registry defines
+	CheckArity            Check = "arity"             // a call with the wrong number of arguments
+	CheckTypeMismatch     Check = "type-mismatch"     // a comparison between incompatible types
+	CheckUnknownAttribute Check = "unknown-attribute" // a reference the schema says never resolves
+	CheckCycle            Check = "cycle"             // attributes that refer to themselves
+	CheckUndefinedCompare Check = "undefined-compare" // == or != against undefined
+	CheckConstantFalse    Check = "constant-false"    // a conjunct that makes && always false
+)
+
+// Diagnostic is one problem found by the linter.
+type Diagnostic struct {
+	Check    Check
+	Severity Severity
+	// Attr is the attribute of the linted ad whose expression has the problem
+	// ("" when linting a lone expression).
+	Attr string
+	// Span is where in the source the problem is; zero when the tree was
+	// linted without positions (Node with nil Positions).
+	Span    ast.Span
+	Message string
+}
+
+// String formats the diagnostic as "line:col: severity: attr: message [check]".
+func (d Diagnostic) String() string {
+	var b strings.Builder
+	if d.Span.Start.IsValid() {
+		b.WriteString(d.Span.Start.String())
+		b.WriteString(": ")
+	}
+	b.WriteString(d.Severity.String())
+	b.WriteString(": ")
+	if d.Attr != "" {
+		b.WriteString(d.Attr)
+		b.WriteString(": ")
+	}
+	b.WriteString(d.Message)
+	b.WriteString(" [")
+	b.WriteString(string(d.Check))
+	b.WriteString("]")
+	return b.String()
+}
+
+// Type is the static type of an attribute or sub-expression. Booleans,
+// integers and reals compare with each other; any other pair of distinct
+// types compares to error.
+type Type int
+
+const (
+	Any Type = iota // unknown, or any type
+	Boolean
+	Integer
+	Real
+	String
+	List
+	ClassAd
+	AbsTime
+	RelTime
+)
+
+func (t Type) String() string {
+	switch t {
+	case Boolean:
+		return "boolean"
+	case Integer:
+		return "integer"
+	case Real:
+		return "real"
+	case String:
+		return "string"
+	case List:
+		return "list"
+	case ClassAd:
+		return "classad"
+	case AbsTime:
+		return "absolute time"
+	case RelTime:
+		return "relative time"
+	default:
+		return "any"
+	}
+}
+
+// class groups the types that compare with each other without error.
+func (t Type) class() Type {
+	switch t {
+	case Boolean, Integer, Real:
+		return Real
+	}
+	return t
+}
+
+// Schema describes the ads an expression is evaluated against: every attribute
+// each can define, with its type (Any when it varies). Names match
+// case-insensitively. A nil map means that ad is not described, and references
+// into it are not checked.
+type Schema struct {
+	My     map[string]Type
+	Target map[string]Type
+}
+
+// Options configures the linter. A nil *Options runs every check with no schema.
+type Options struct {
+	// Schema enables CheckUnknownAttribute and types attribute references for
+	// CheckTypeMismatch. An unscoped reference resolves in MY and then TARGET,
+	// so it is reported only when both My and Target are set.
+	Schema *Schema
+	// Functions are user-defined functions known besides the built-ins and the
+	// default registry (classad.RegisterFunction).
+	Functions *classad.FunctionRegistry
+}
+
+// Expr parses src as an expression and lints it. The error is a parse error.
+func Expr(src string, opts *Options) ([]Diagnostic, error) {
+	expr, pos, err := parser.ParseExprWithPositions(src)
+	if err != nil {
+		return nil, err
+	}
+	return newLinter(src, pos, opts).expr(expr), nil
+}
+
+// Ad parses src as a new-format ad ("[A = 1; B = A + 1]") and lints every
+// attribute's expression, and the references between them for cycles.
+func Ad(src string, opts *Options) ([]Diagnostic, error) {
+	node, pos, err := parser.ParseWithPositions(src)
+	if err != nil {
+		return nil, err
+	}
+	ad, ok := node.(*ast.ClassAd)
+	if !ok {
+		return nil, fmt.Errorf("lint: input is an expression, not a ClassAd")
+	}
+	return newLinter(src, pos, opts).classAd(ad), nil
+}
+
+// Node lints an already-parsed ad (*ast.ClassAd) or expression. pos, when
+// non-nil, gives the diagnostics their spans.
+func Node(n ast.Node, pos ast.Positions, opts *Options) []Diagnostic {
+	l := newLinter("", pos, opts)
+	switch n := n.(type) {
+	case *ast.ClassAd:
+		return l.classAd(n)
+	case ast.Expr:
+		return l.expr(n)
+	}
+	return nil
+}
+
+// linter holds the state of one lint run.
+type linter struct {
+	src   string
+	pos   ast.Positions
+	funcs *classad.FunctionRegistry
+	my    map[string]Type // lower-cased Schema.My; nil when not described
+	tgt   map[string]Type // lower-cased Schema.Target
+
+	attr   string            // the attribute being linted
+	scopes []map[string]Type // names defined by the enclosing ads, innermost last
+	diags  []Diagnostic
+}
+
+func newLinter(src string, pos ast.Positions, opts *Options) *linter {
+	l := &linter{src: src, pos: pos}
+	if opts == nil {
+		return l
+	}
+	l.funcs = opts.Functions
+	if s := opts.Schema; s != nil {
+		l.my, l.tgt = lowerKeys(s.My), lowerKeys(s.Target)
+	}
+	return l
+}
+
+func lowerKeys(m map[string]Type) map[string]Type {
+	if m == nil {
+		return nil
+	}
+	out := make(map[string]Type, len(m))
+	for k, t := range m {
+		out[strings.ToLower(k)] = t
+	}
+	return out
+}
+
+func (l *linter) expr(e ast.Expr) []Diagnostic {
+	l.walk(e)
+	return l.diags
+}
+
+func (l *linter) classAd(ad *ast.ClassAd) []Diagnostic {
+	l.scopes = append(l.scopes, l.adScope(ad))
+	for _, a := range ad.Attributes {
+		l.attr = a.Name
+		l.walk(a.Value)
+	}
+	l.attr = ""
+	l.cycles(ad)
+	l.scopes = l.scopes[:len(l.scopes)-1]
+	return l.diags
+}
+
+// adScope returns the names ad defines, each typed by its expression where that
+// needs no reference to resolve.
+func (l *linter) adScope(ad *ast.ClassAd) map[string]Type {
+	scope := make(map[string]Type, len(ad.Attributes))
+	for _, a := range ad.Attributes {
+		scope[strings.ToLower(a.Name)] = Any
+	}
+	for _, a := range ad.Attributes {
+		scope[strings.ToLower(a.Name)] = typeOf(a.Value, nil)
+	}
+	return scope
+}
+
+func (l *linter) report(check Check, sev Severity, n ast.Node, format string, args ...any) {
+	d := Diagnostic{Check: check, Severity: sev, Attr: l.attr, Message: fmt.Sprintf(format, args...)}
+	if span, ok := l.pos.Span(n); ok {
+		d.Span = span
+	}
+	l.diags = append(l.diags, d)
+}
+
+// text renders e for a message: its source text when known, else its debug form.
+func (l *linter) text(e ast.Expr) string {
+	if span, ok := l.pos.Span(e); ok && l.src != "" && span.End.Offset <= len(l.src) {
+		return l.src[span.Start.Offset:span.End.Offset]
+	}
+	return e.String()
+}
+
+func (l *linter) walk(e ast.Expr) {
+	switch v := e.(type) {
+	case *ast.ParenExpr:
+		l.walk(v.Inner)
+	case *ast.AttributeReference:
+		l.checkRef(v)
+	case *ast.BinaryOp:
+		if v.Op == "&&" {
+			conj := conjuncts(v, nil)
+			l.checkConjuncts(conj)
+			for _, c := range conj {
+				l.walk(c)
+			}
+			return
+		}
+		l.checkBinary(v)
+		l.walk(v.Left)
+		l.walk(v.Right)
+	case *ast.UnaryOp:
+		l.walk(v.Expr)
+	case *ast.ConditionalExpr:
+		l.walk(v.Condition)
+		l.walk(v.TrueExpr)
+		l.walk(v.FalseExpr)
+	case *ast.ElvisExpr:
+		l.walk(v.Left)
+		l.walk(v.Right)
+	case *ast.FunctionCall:
+		l.checkCall(v)
+		for _, a := range v.Args {
+			l.walk(a)
+		}
+	case *ast.ListLiteral:
+		for _, el := range v.Elements {
+			l.walk(el)
+		}
+	case *ast.RecordLiteral:
+		if v.ClassAd == nil {
+			return
+		}
+		l.scopes = append(l.scopes, l.adScope(v.ClassAd))
+		for _, a := range v.ClassAd.Attributes {
+			l.walk(a.Value)
+		}
+		l.scopes = l.scopes[:len(l.scopes)-1]
+	case *ast.SelectExpr:
+		l.walk(v.Record)
+	case *ast.SubscriptExpr:
+		l.walk(v.Container)
+		l.walk(v.Index)
+	}
+}
+
+func (l *linter) checkCall(fc *ast.FunctionCall) {
+	arity, ok := classad.FunctionArity(fc.Name)
+	if !ok && l.funcs != nil {
+		arity, ok = l.funcs.Lookup(fc.Name)
+	}
+	if !ok {
+		l.report(CheckUnknownFunction, Error, fc, "unknown function %s", fc.Name)
+		return
+	}
+	if arity.Accepts(len(fc.Args)) {
+		return
+	}
+	var want string
+	switch {
+	case arity.Max < 0:
+		want = "at least " + plural(arity.Min, "argument")
+	case arity.Min == arity.Max:
+		want = plural(arity.Min, "argument")
+	default:
+		want = fmt.Sprintf("%d to %d arguments", arity.Min, arity.Max)
+	}
+	l.report(CheckArity, Error, fc, "%s takes %s, called with %d", fc.Name, want, len(fc.Args))
+}
+
+func plural(n int, noun string) string {
+	if n == 1 {
+		return "1 " + noun
+	}
+	return fmt.Sprintf("%d %ss", n, noun)
+}
+
+func (l *linter) checkRef(ref *ast.AttributeReference) {
+	norm := ref.NormalizedName()
+	var known bool
+	switch ref.Scope {
+	case ast.MyScope:
+		if l.my == nil {
+			return
+		}
+		_, known = l.my[norm]
+		if len(l.scopes) > 0 {
+			_, inAd := l.scopes[len(l.scopes)-1][norm]
+			known = known || inAd
+		}
+	case ast.TargetScope:
+		if l.tgt == nil {
+			return
+		}
+		_, known = l.tgt[norm]
+	case ast.NoScope:
+		if l.my == nil || l.tgt == nil || norm == "currenttime" {
+			return
+		}
+		_, inMy := l.my[norm]
+		_, inTarget := l.tgt[norm]
+		known = inMy || inTarget || l.inScope(norm)
+	default:
+		return
+	}
+	if !known {
+		l.report(CheckUnknownAttribute, Warning, ref, "%s is never defined, so it is always undefined", l.text(ref))
+	}
+}
+
+// inScope reports whether an enclosing ad defines the lower-cased name.
+func (l *linter) inScope(norm string) bool {
+	for _, s := range l.scopes {
+		if _, ok := s[norm]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+func isComparison(op string) bool {
+	switch op {
+	case "==", "!=", "<", "<=", ">", ">=":
+		return true
+	}
+	return false
+}
+
+func (l *linter) checkBinary(b *ast.BinaryOp) {
+	if !isComparison(b.Op) {
+		return
+	}
+	left, right := unparen(b.Left), unparen(b.Right)
+	if b.Op == "==" || b.Op == "!=" {
+		if isUndefinedLit(left) || isUndefinedLit(right) {
+			alt := "=?="
+			if b.Op == "!=" {
+				alt = "=!="
+			}
+			l.report(CheckUndefinedCompare, Warning, b,
+				"%s is never true: %s against undefined is undefined; use %s", l.text(b), b.Op, alt)
+			return
+		}
+	}
+	// Only a comparison against a literal is reported: both sides inferred
+	// from references is as likely a wrong schema as a wrong expression.
+	if !isLiteral(left) && !isLiteral(right) {
+		return
+	}
+	lt, rt := l.typeOf(left), l.typeOf(right)
+	if lt != Any && rt != Any && lt.class() != rt.class() {
+		l.report(CheckTypeMismatch, Error, b, "%s compares %s with %s, which is always error", l.text(b), article(lt), article(rt))
+	}
+}
+
+func article(t Type) string {
+	s := t.String()
+	if strings.ContainsAny(s[:1], "aeiou") {
+		return "an " + s
+	}
+	return "a " + s
+}
+
+func unparen(e ast.Expr) ast.Expr {
+	for {
+		p, ok := e.(*ast.ParenExpr)
+		if !ok {
+			return e
+		}
+		e = p.Inner
+	}
+}
+
+func isUndefinedLit(e ast.Expr) bool {
+	_, ok := e.(*ast.UndefinedLiteral)
+	return ok
+}
+
+func isLiteral(e ast.Expr) bool {
+	switch v := e.(type) {
+	case *ast.IntegerLiteral, *ast.RealLiteral, *ast.StringLiteral, *ast.BooleanLiteral,
+		*ast.ListLiteral, *ast.RecordLiteral, *ast.AbsTimeLiteral, *ast.RelTimeLiteral:
+		return true
+	case *ast.UnaryOp:
+		return (v.Op == "-" || v.Op == "+") && isLiteral(unparen(v.Expr))
+	}
+	return false
+}
+
+// typeOf infers e's type, typing attribute references from the enclosing ads
+// and the schema.
+func (l *linter) typeOf(e ast.Expr) Type {
+	return typeOf(e, func(ref *ast.AttributeReference) Type {
+		norm := ref.NormalizedName()
+		switch ref.Scope {
+		case ast.MyScope:
+			if len(l.scopes) > 0 {
+				if t, ok := l.scopes[len(l.scopes)-1][norm]; ok {
+					return t
+				}
+			}
+			return l.my[norm]
+		case ast.TargetScope:
+			return l.tgt[norm]
+		case ast.NoScope:
+			for i := len(l.scopes) - 1; i >= 0; i-- {
+				if t, ok := l.scopes[i][norm]; ok {
+					return t
+				}
+			}
+			if t, ok := l.my[norm]; ok {
+				return t
+			}
+			return l.tgt[norm]
+		}
+		return Any
+	})
+}
+
+// typeOf infers e's static type; refType types attribute references (nil: Any).
+func typeOf(e ast.Expr, refType func(*ast.AttributeReference) Type) Type {
+	switch v := e.(type) {
+	case *ast.ParenExpr:
+		return typeOf(v.Inner, refType)
+	case *ast.IntegerLiteral:
+		return Integer
+	case *ast.RealLiteral:
+		return Real
+	case *ast.StringLiteral:
+		return String
+	case *ast.BooleanLiteral:
+		return Boolean
+	case *ast.ListLiteral:
+		return List
+	case *ast.RecordLiteral:
+		return ClassAd
+	case *ast.AbsTimeLiteral:
+		return AbsTime
+	case *ast.RelTimeLiteral:
+		return RelTime
+	case *ast.AttributeReference:
+		if refType == nil {
+			return Any
+		}
+		return refType(v)
+	case *ast.UnaryOp:
+		if v.Op == "!" {
+			return Boolean
+		}
+		if t := typeOf(v.Expr, refType); t == Integer || t == Real {
+			return t
+		}
+	case *ast.BinaryOp:
+		switch v.Op {
+		case "==", "!=", "<", "<=", ">", ">=", "is", "isnt", "&&", "||":
+			return Boolean
+		case "+", "-", "*", "/", "%":
+			lt, rt := typeOf(v.Left, refType), typeOf(v.Right, refType)
+			switch {
+			case lt == Real && (rt == Real || rt == Integer), lt == Integer && rt == Real:
+				return Real
+			case lt == Integer && rt == Integer:
+				return Integer
+			}
+		}
+	case *ast.ConditionalExpr:
+		if t := typeOf(v.TrueExpr, refType); t == typeOf(v.FalseExpr, refType) {
+			return t
+		}
+	case *ast.FunctionCall:
+		return funcTypes[strings.ToLower(v.Name)]
+	}
+	return Any
+}
+
+// funcTypes are the result types of the built-ins that always return one type
+// (or error).
+var funcTypes = map[string]Type{
+	"strcat": String, "substr": String, "tolower": String, "toupper": String,
+	"string": String, "join": String, "unparse": String, "formattime": String,
+	"interval": String, "replace": String, "replaceall": String, "regexps": String,
+	"size": Integer, "int": Integer, "floor": Integer, "ceiling": Integer, "ceil": Integer,
+	"round": Integer, "strcmp": Integer, "stricmp": Integer, "versioncmp": Integer,
+	"countmatches": Integer, "stringlistsize": Integer, "time": Integer,
+	"real":        Real,
+	"isundefined": Boolean, "iserror": Boolean, "isstring": Boolean, "isinteger": Boolean,
+	"isreal": Boolean, "isboolean": Boolean, "islist": Boolean, "isclassad": Boolean,
+	"member": Boolean, "identicalmember": Boolean, "stringlistmember": Boolean,
+	"stringlistimember": Boolean, "regexp": Boolean, "regexpmember": Boolean,
+	"stringlistregexpmember": Boolean, "stringlistsintersect": Boolean,
+	"stringlistsubsetmatch": Boolean, "bool": Boolean, "anycompare": Boolean,
+	"allcompare": Boolean, "version_in_range": Boolean, "versionge": Boolean,
+	"versiongt": Boolean, "versionle": Boolean, "versionlt": Boolean, "versioneq": Boolean,
+	"split": List, "splitusername": List, "splitslotname": List,
+	"splittime": ClassAd, "abstime": AbsTime, "reltime": RelTime,
+}
+
+// conjuncts flattens an && chain (through parentheses) into its operands.
+func conjuncts(e ast.Expr, out []ast.Expr) []ast.Expr {
+	if b, ok := unparen(e).(*ast.BinaryOp); ok && b.Op == "&&" {
+		out = conjuncts(b.Left, out)
+		return conjuncts(b.Right, out)
+	}
+	return append(out, e)
+}
+
+// checkConjuncts reports a conjunct that is constant false, or that together
+// with the conjuncts before it leaves a referenced attribute no possible value.
+func (l *linter) checkConjuncts(conj []ast.Expr) {
+	ranges := map[refKey]*valueRange{}
+	for _, c := range conj {
+		if isConstant(c) {
+			v := classad.New().EvaluateExpr(c)
+			if b, err := v.BoolValue(); err == nil && !b {
+				l.report(CheckConstantFalse, Warning, c, "%s is always false, so the && is never true", l.text(c))
+			}
+			continue
+		}
+		key, op, lit, ok := refComparison(c)
+		if !ok {
+			continue
+		}
+		r := ranges[key]
+		if r == nil {
+			r = &valueRange{}
+			ranges[key] = r
+		}
+		if !r.constrain(op, lit) {
+			l.report(CheckConstantFalse, Warning, c, "%s contradicts an earlier condition on %s, so the && is never true",
+				l.text(c), key.name)
+		}
+	}
+}
+
+// nonConstantFuncs are the built-ins whose result depends on more than their
+// arguments.
+var nonConstantFuncs = map[string]bool{
+	"time": true, "random": true, "daytime": true, "abstime": true, "formattime": true, "eval": true,
+}
+
+// isConstant reports whether e evaluates the same everywhere: no attribute
+// references and no clock, randomness or eval.
+func isConstant(e ast.Expr) bool {
+	switch v := e.(type) {
+	case *ast.IntegerLiteral, *ast.RealLiteral, *ast.StringLiteral, *ast.BooleanLiteral,
+		*ast.UndefinedLiteral, *ast.ErrorLiteral, *ast.AbsTimeLiteral, *ast.RelTimeLiteral:
+		return true
+	case *ast.ParenExpr:
+		return isConstant(v.Inner)
+	case *ast.UnaryOp:
+		return isConstant(v.Expr)
+	case *ast.BinaryOp:
+		return isConstant(v.Left) && isConstant(v.Right)
+	case *ast.ConditionalExpr:
+		return isConstant(v.Condition) && isConstant(v.TrueExpr) && isConstant(v.FalseExpr)
+	case *ast.ElvisExpr:
+		return isConstant(v.Left) && isConstant(v.Right)
+	case *ast.ListLiteral:
+		for _, el := range v.Elements {
+			if !isConstant(el) {
+				return false
+			}
+		}
+		return true
+	case *ast.FunctionCall:
+		name := strings.ToLower(v.Name)
+		if nonConstantFuncs[name] || !classad.IsBuiltinFunction(name) {
+			return false
+		}
+		for _, a := range v.Args {
+			if !isConstant(a) {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// refKey identifies an attribute reference across conjuncts.
+type refKey struct {
+	scope ast.AttributeScope
+	norm  string
+	name  string // as first written, for messages
+}
+
+// refComparison matches "ref op literal" (either way round) for a number or,
+// with ==, a string; op is normalized to have the reference on the left.
+func refComparison(e ast.Expr) (refKey, string, classad.Value, bool) {
+	b, ok := unparen(e).(*ast.BinaryOp)
+	if !ok {
+		return refKey{}, "", classad.Value{}, false
+	}
+	op := b.Op
+	left, right := unparen(b.Left), unparen(b.Right)
+	ref, isRef := left.(*ast.AttributeReference)
+	lit := right
+	if !isRef {
+		ref, isRef = right.(*ast.AttributeReference)
+		lit = left
+		op = flipped[op]
+	}
+	if !isRef || op == "" || !isLiteral(lit) {
+		return refKey{}, "", classad.Value{}, false
+	}
+	v := classad.New().EvaluateExpr(lit)
+	if !v.IsNumber() && !(v.IsString() && op == "==") {
+		return refKey{}, "", classad.Value{}, false
+	}
+	return refKey{scope: ref.Scope, norm: ref.NormalizedName(), name: ref.String()}, op, v, true
+}
+
+// flipped maps a comparison to the one with its operands swapped.
+var flipped = map[string]string{"==": "==", "<": ">", "<=": ">=", ">": "<", ">=": "<="}
+
+// valueRange is what the conjuncts so far allow an attribute's value to be.
+type valueRange struct {
+	hasLo, hasHi       bool
+	lo, hi             float64
+	loStrict, hiStrict bool
+	str                *string // the string it must equal (case-insensitively)
+}
+
+// constrain narrows r by "attr op v" and reports whether any value remains.
+func (r *valueRange) constrain(op string, v classad.Value) bool {
+	if s, err := v.StringValue(); err == nil {
+		if r.str != nil && !strings.EqualFold(*r.str, s) || r.hasLo || r.hasHi {
+			return false
+		}
+		r.str = &s
+		return true
+	}
+	if r.str != nil {
+		return false
+	}
+	n, _ := v.NumberValue()
+	if op == "==" || op == ">" || op == ">=" {
+		strict := op == ">"
+		if !r.hasLo || n > r.lo || n == r.lo && strict {
+			r.hasLo, r.lo, r.loStrict = true, n, strict
+		}
+	}
+	if op == "==" || op == "<" || op == "<=" {
+		strict := op == "<"
+		if !r.hasHi || n < r.hi || n == r.hi && strict {
+			r.hasHi, r.hi, r.hiStrict = true, n, strict
+		}
+	}
+	return !(r.hasLo && r.hasHi && (r.lo > r.hi || r.lo == r.hi && (r.loStrict || r.hiStrict)))
+}
+
+// cycles reports each set of ad's attributes that refer to each other in a
+// cycle, once, at the first of them in the ad.
+func (l *linter) cycles(ad *ast.ClassAd) {
+	index := make(map[string]int, len(ad.Attributes))
+	for i, a := range ad.Attributes {
+		index[strings.ToLower(a.Name)] = i
+	}
+	deps := make([][]int, len(ad.Attributes))
+	for i, a := range ad.Attributes {
+		seen := map[int]bool{}
+		forEachRef(a.Value, 0, func(ref *ast.AttributeReference) {
+			if j, ok := index[ref.NormalizedName()]; ok && !seen[j] {
+				seen[j] = true
+				deps[i] = append(deps[i], j)
+			}
+		})
+	}
+	for _, comp := range stronglyConnected(deps) {
+		first := comp[0]
+		if len(comp) == 1 && !slices.Contains(deps[first], first) {
+			continue
+		}
+		in := map[int]bool{}
+		for _, i := range comp {
+			in[i] = true
+		}
+		path := cyclePath(deps, in, first)
+		names := make([]string, len(path))
+		for i, p := range path {
+			names[i] = ad.Attributes[p].Name
+		}
+		l.attr = ad.Attributes[first].Name
+		if len(path) == 2 {
+			l.report(CheckCycle, Error, ad.Attributes[first], "%s refers to itself", names[0])
+		} else {
+			l.report(CheckCycle, Error, ad.Attributes[first], "reference cycle %s", strings.Join(names, " -> "))
+		}
+	}
+	l.attr = ""
+}
+
+// forEachRef calls fn for each reference in e that resolves in the ad e belongs
+// to: unscoped or MY, and not shadowed by a nested ad at nesting depth > 0.
+func forEachRef(e ast.Expr, depth int, fn func(*ast.AttributeReference)) {
+	switch v := e.(type) {
+	case *ast.AttributeReference:
+		if depth == 0 && (v.Scope == ast.NoScope || v.Scope == ast.MyScope) {
+			fn(v)
+		}
+	case *ast.ParenExpr:
+		forEachRef(v.Inner, depth, fn)
+	case *ast.BinaryOp:
+		forEachRef(v.Left, depth, fn)
+		forEachRef(v.Right, depth, fn)
+	case *ast.UnaryOp:
+		forEachRef(v.Expr, depth, fn)
+	case *ast.ConditionalExpr:
+		forEachRef(v.Condition, depth, fn)
+		forEachRef(v.TrueExpr, depth, fn)
+		forEachRef(v.FalseExpr, depth, fn)
+	case *ast.ElvisExpr:
+		forEachRef(v.Left, depth, fn)
+		forEachRef(v.Right, depth, fn)
+	case *ast.FunctionCall:
+		for _, a := range v.Args {
+			forEachRef(a, depth, fn)
+		}
+	case *ast.ListLiteral:
+		for _, el := range v.Elements {
+			forEachRef(el, depth, fn)
+		}
+	case *ast.SelectExpr:
+		forEachRef(v.Record, depth, fn)
+	case *ast.SubscriptExpr:
+		forEachRef(v.Container, depth, fn)
+		forEachRef(v.Index, depth, fn)
+	case *ast.RecordLiteral:
+		// A nested ad's references resolve in it first; the attributes of the
+		// enclosing ad they might reach are not worth modelling here.
+	}
+}
+
+// stronglyConnected returns the strongly connected components of the graph,
+// each sorted, ordered by their smallest node (Tarjan's algorithm).
+func stronglyConnected(deps [][]int) [][]int {
+	n := len(deps)
+	index, low := make([]int, n), make([]int, n)
+	onStack := make([]bool, n)
+	for i := range index {
+		index[i] = -1
+	}
+	var stack []int
+	var comps [][]int
+	next := 0
+	var visit func(v int)
+	visit = func(v int) {
+		index[v], low[v] = next, next
+		next++
+		stack = append(stack, v)
+		onStack[v] = true
+		for _, w := range deps[v] {
+			if index[w] < 0 {
+				visit(w)
+				low[v] = min(low[v], low[w])
+			} else if onStack[w] {
+				low[v] = min(low[v], index[w])
+			}
+		}
+		if low[v] != index[v] {
+			return
+		}
+		var comp []int
+		for {
+			w := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			onStack[w] = false
+			comp = append(comp, w)
+			if w == v {
+				break
+			}
+		}
+		sort.Ints(comp)
+		comps = append(comps, comp)
+	}
+	for v := range deps {
+		if index[v] < 0 {
+			visit(v)
+		}
+	}
+	sort.Slice(comps, func(i, j int) bool { return comps[i][0] < comps[j][0] })
+	return comps
+}
+
+// cyclePath returns a cycle through start within the component in, as a path
+// that begins and ends at start.
+func cyclePath(deps [][]int, in map[int]bool, start int) []int {
+	visited := map[int]bool{}
+	var path []int
+	var find func(v int) bool
+	find = func(v int) bool {
+		path = append(path, v)
+		for _, w := range deps[v] {
+			if w == start {
+				path = append(path, w)
+				return true
+			}
+			if in[w] && !visited[w] {
+				visited[w] = true
+				if find(w) {
+					return true
+				}
+			}
+		}
+		path = path[:len(path)-1]
+		return false
+	}
+	find(start)
+	return path
+}
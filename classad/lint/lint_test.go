@@ -0,0 +1,136 @@
+package lint
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/PelicanPlatform/classad/classad"
+	"github.com/PelicanPlatform/classad/parser"
+)
+
+func checks(diags []Diagnostic) []Check {
+	out := make([]Check, len(diags))
+	for i, d := range diags {
+		out[i] = d.Check
+	}
+	return out
+}
+
+func TestExpr(t *testing.T) {
+	machine := &Schema{
+		My:     map[string]Type{"RequestMemory": Integer, "Owner": String},
+		Target: map[string]Type{"Memory": Integer, "OpSys": String, "Arch": String},
+	}
+	tests := []struct {
+		src  string
+		want []Check
+	}{
+		{`TARGET.Memory >= RequestMemory && OpSys == "LINUX"`, nil},
+		{`fooBar(1)`, []Check{CheckUnknownFunction}},
+		{`substr("abc")`, []Check{CheckArity}},
+		{`strcat() == ""`, nil},
+		{`TARGET.OpSys == 7`, []Check{CheckTypeMismatch}},
+		{`size(Owner) > "3"`, []Check{CheckTypeMismatch}},
+		{`TARGET.Memory == true`, nil},
+		{`TARGET.Memroy > 1024`, []Check{CheckUnknownAttribute}},
+		{`Memroy > 1024`, []Check{CheckUnknownAttribute}},
+		{`[Memroy = 1].Memroy > 0`, nil},
+		{`CurrentTime > 0`, nil},
+		{`OpSys == undefined`, []Check{CheckUndefinedCompare}},
+		{`OpSys =?= undefined`, nil},
+		{`Memory > 0 && 1 > 2`, []Check{CheckConstantFalse}},
+		{`Memory > 8 && (Arch == "X86_64" && Memory < 4)`, []Check{CheckConstantFalse}},
+		{`Memory >= 4 && Memory <= 4`, nil},
+		{`4 < Memory && Memory < 5`, nil},
+		{`Arch == "x86_64" && Arch == "X86_64"`, nil},
+		{`Arch == "x86_64" && Arch == "ARM"`, []Check{CheckConstantFalse}},
+		{`false`, nil},
+	}
+	for _, tt := range tests {
+		diags, err := Expr(tt.src, &Options{Schema: machine})
+		if err != nil {
+			t.Fatalf("%s: %v", tt.src, err)
+		}
+		if got := checks(diags); !slices.Equal(got, tt.want) {
+			t.Errorf("%s: %v, want %v\n%v", tt.src, got, tt.want, diags)
+		}
+	}
+}
+
+func TestExpr_Diagnostic(t *testing.T) {
+	diags, err := Expr("Cpus > 0 &&\n  OpSys == undefined", nil)
+	if err != nil || len(diags) != 1 {
+		t.Fatalf("got %v, %v", diags, err)
+	}
+	want := `2:3: warning: OpSys == undefined is never true: == against undefined is undefined; use =?= [undefined-compare]`
+	if got := diags[0].String(); got != want {
+		t.Errorf("String() = %q\nwant %q", got, want)
+	}
+	if _, err := Expr("1 +", nil); err == nil {
+		t.Error("want a parse error")
+	}
+}
+
+func TestExpr_Functions(t *testing.T) {
+	r := classad.NewFunctionRegistry()
+	if err := r.Register("siteQuota", classad.ExactArity(1), func([]classad.Value) classad.Value {
+		return classad.NewBoolValue(true)
+	}); err != nil {
+		t.Fatal(err)
+	}
+	diags, _ := Expr(`siteQuota("alice") && siteQuota()`, &Options{Functions: r})
+	if got := checks(diags); !slices.Equal(got, []Check{CheckArity}) {
+		t.Errorf("got %v", diags)
+	}
+	if !strings.Contains(diags[0].Message, "takes 1 argument, called with 0") {
+		t.Errorf("message = %q", diags[0].Message)
+	}
+}
+
+func TestAd(t *testing.T) {
+	diags, err := Ad(`[
+		A = B + 1;
+		B = C * 2;
+		C = A;
+		D = D + 1;
+		E = [D = 1; F = D];
+		Requirements = Cpus > 0 && E.F == "one"
+	]`, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, d := range diags {
+		if d.Check == CheckCycle {
+			got = append(got, d.Attr+": "+d.Message)
+		}
+	}
+	want := []string{"A: reference cycle A -> B -> C -> A", "D: D refers to itself"}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("cycles:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+
+	// A sibling attribute's literal value types a reference to it.
+	diags, _ = Ad(`[Cpus = 4; Requirements = Cpus == "4"]`, nil)
+	if len(diags) != 1 || diags[0].Check != CheckTypeMismatch || diags[0].Attr != "Requirements" {
+		t.Errorf("got %v", diags)
+	}
+	if _, err := Ad(`1 + 2`, nil); err == nil {
+		t.Error("an expression linted as an ad")
+	}
+}
+
+func TestNode_OldFormat(t *testing.T) {
+	ad, err := parser.ParseOldClassAd("A = B\nB = A\nC = foo()")
+	if err != nil {
+		t.Fatal(err)
+	}
+	diags := Node(ad, nil, nil)
+	if got := checks(diags); !slices.Equal(got, []Check{CheckUnknownFunction, CheckCycle}) {
+		t.Errorf("got %v", diags)
+	}
+	if diags[0].Span.Start.IsValid() || strings.HasPrefix(diags[0].String(), "0:") {
+		t.Errorf("positionless diagnostic = %q", diags[0])
+	}
+}
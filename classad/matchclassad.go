@@ -14,6 +14,13 @@ import (
 type MatchClassAd struct {
 	left  *ClassAd // Typically the "job" or requesting ClassAd
 	right *ClassAd // Typically the "machine" or offering ClassAd
+
+	// dialect, when non-nil, is the dialect both sides evaluate in (see
+	// SetDialect).
+	dialect *Dialect
+
+	// limits, when non-nil, bounds each evaluation (see SetOptions).
+	limits *evalBudget
 }
 
 // NewMatchClassAd creates a new MatchClassAd with two ClassAds.
@@ -51,7 +58,7 @@ func (m *MatchClassAd) EvaluateAttrLeft(name string) Value {
 	if m.left == nil {
 		return NewUndefinedValue()
 	}
-	return m.left.EvaluateAttr(name)
+	return m.left.evaluateAttr(name, m.budget(), m.dialect)
 }
 
 // EvaluateAttrRight evaluates an attribute in the right ClassAd.
@@ -59,7 +66,7 @@ func (m *MatchClassAd) EvaluateAttrRight(name string) Value {
 	if m.right == nil {
 		return NewUndefinedValue()
 	}
-	return m.right.EvaluateAttr(name)
+	return m.right.evaluateAttr(name, m.budget(), m.dialect)
 }
 
 // Symmetry checks if both ClassAds' Requirements evaluate to true.
@@ -155,7 +162,7 @@ func (m *MatchClassAd) EvaluateExprLeft(expr ast.Expr) Value {
 	if m.left == nil {
 		return NewUndefinedValue()
 	}
-	return m.left.EvaluateExpr(expr)
+	return m.evaluateExpr(m.left, expr)
 }
 
 // EvaluateExprRight evaluates an expression in the context of the right ClassAd.
@@ -163,5 +170,49 @@ func (m *MatchClassAd) EvaluateExprRight(expr ast.Expr) Value {
 	if m.right == nil {
 		return NewUndefinedValue()
 	}
-	return m.right.EvaluateExpr(expr)
+	return m.evaluateExpr(m.right, expr)
+}
+
+// evaluateExpr is ad.EvaluateExpr in the match's dialect.
+func (m *MatchClassAd) evaluateExpr(ad *ClassAd, expr ast.Expr) (result Value) {
+	defer recoverCyclic(&result)
+	evaluator := NewEvaluator(ad)
+	evaluator.limits = m.budget()
+	if m.dialect != nil {
+		evaluator.dialect = m.dialect
+	}
+	return evaluator.Evaluate(expr)
+}
+
+// SetOptions bounds every subsequent evaluation of the match -- each side's
+// Requirements and Rank, and EvaluateExprLeft/Right -- to opts, as
+// Evaluator.SetOptions does; zero options remove the bounds. Each evaluation
+// gets the full budget. A non-nil opts.Dialect is SetDialect.
+func (m *MatchClassAd) SetOptions(opts EvalOptions) {
+	if opts.Dialect != nil {
+		m.SetDialect(*opts.Dialect)
+	}
+	if !opts.bounded() {
+		m.limits = nil
+		return
+	}
+	m.limits = &evalBudget{opts: opts}
+}
+
+// LimitErr returns the *LimitError that stopped the match's most recent
+// evaluation, or nil. A Match that a limit stopped is false, so check LimitErr
+// after a failed match to tell the two apart.
+func (m *MatchClassAd) LimitErr() error {
+	if m.limits == nil || m.limits.err == nil {
+		return nil
+	}
+	return m.limits.err
+}
+
+// budget starts the budget of one evaluation, or returns nil if unbounded.
+func (m *MatchClassAd) budget() *evalBudget {
+	if m.limits != nil {
+		m.limits.reset()
+	}
+	return m.limits
 }
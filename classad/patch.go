@@ -0,0 +1,231 @@
+package classad
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/PelicanPlatform/classad/ast"
+	"github.com/PelicanPlatform/classad/parser"
+)
+
+// Patch is the difference between two versions of a ClassAd: the attributes
+// set (added or changed) with their new expressions, and the attributes
+// deleted. Diff computes one and Apply replays it, so an update can be shipped
+// as a Patch instead of the whole ad.
+//
+// A Patch has a text form, one change per line -- "Name = expr" for a set and
+// "-Name" for a delete -- and a JSON form, {"set": {...}, "delete": [...]}
+// with the values encoded as by MarshalJSON. Both omit private attributes
+// (see IsPrivateAttribute) unless written WithPrivate.
+type Patch struct {
+	// Set holds the attributes set, sorted by normalized name.
+	Set []PatchAttr
+	// Delete holds the names of the attributes deleted, sorted by normalized
+	// name.
+	Delete []string
+}
+
+// PatchAttr is an attribute a Patch sets.
+type PatchAttr struct {
+	Name string
+	Expr *Expr
+}
+
+// Diff returns the Patch that turns old into new: every attribute of new that
+// old lacks or binds to a different expression (compared structurally, as
+// Equal does), and every attribute of old that new lacks. Attribute names
+// compare case-insensitively; a set carries new's spelling. A nil ad is empty.
+// The patch shares its expressions with new.
+func Diff(old, new *ClassAd) *Patch {
+	p := &Patch{}
+	for _, attr := range adAttributes(new) {
+		var prev ast.Expr
+		if old != nil {
+			prev = old.lookupOwn(attr.Name)
+		}
+		if prev == nil || !exprEqual(prev, attr.Value) {
+			p.Set = append(p.Set, PatchAttr{Name: attr.Name, Expr: &Expr{expr: attr.Value}})
+		}
+	}
+	for _, attr := range adAttributes(old) {
+		if new == nil || new.lookupOwn(attr.Name) == nil {
+			p.Delete = append(p.Delete, attr.Name)
+		}
+	}
+	return p
+}
+
+// adAttributes returns c's attributes sorted by normalized name (none for a
+// nil ad).
+func adAttributes(c *ClassAd) []*ast.AttributeAssignment {
+	if c == nil || c.ad == nil {
+		return nil
+	}
+	c.ensureSorted()
+	return c.ad.Attributes
+}
+
+// Empty reports whether p changes nothing.
+func (p *Patch) Empty() bool {
+	return p == nil || len(p.Set) == 0 && len(p.Delete) == 0
+}
+
+// Apply applies p to c: Insert for each attribute set, then Delete for each
+// attribute deleted. A nil patch changes nothing.
+func (c *ClassAd) Apply(p *Patch) {
+	if p == nil {
+		return
+	}
+	for _, a := range p.Set {
+		c.InsertExpr(a.Name, a.Expr)
+	}
+	for _, name := range p.Delete {
+		c.Delete(name)
+	}
+}
+
+// String renders p in its text form, excluding private attributes set.
+//
+// Example:
+//
+//	Cpus = 8
+//	Requirements = (TARGET.Memory > 1024)
+//	-LastHeardFrom
+func (p *Patch) String() string { return p.text(false) }
+
+// StringWithPrivate is String including private attributes. Prefer String for
+// anything client-facing; see IsPrivateAttribute.
+func (p *Patch) StringWithPrivate() string { return p.text(true) }
+
+func (p *Patch) text(includePrivate bool) string {
+	if p == nil {
+		return ""
+	}
+	var b strings.Builder
+	for _, a := range p.Set {
+		if !includePrivate && IsPrivateAttribute(a.Name) {
+			continue
+		}
+		b.WriteString(unparseAttrName(a.Name))
+		b.WriteString(" = ")
+		b.WriteString(a.Expr.String())
+		b.WriteByte('\n')
+	}
+	for _, name := range p.Delete {
+		b.WriteByte('-')
+		b.WriteString(unparseAttrName(name))
+		b.WriteByte('\n')
+	}
+	return b.String()
+}
+
+// ParsePatch parses the text form of a Patch (see Patch.String). Blank lines
+// are ignored.
+func ParsePatch(text string) (*Patch, error) {
+	p := &Patch{}
+	for i, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		if name, ok := strings.CutPrefix(line, "-"); ok {
+			ref, ok := parsePatchName(strings.TrimSpace(name))
+			if !ok {
+				return nil, fmt.Errorf("classad: patch line %d: bad attribute name %q", i+1, name)
+			}
+			p.Delete = append(p.Delete, ref)
+			continue
+		}
+		ad, err := parser.ParseClassAd("[" + line + "]")
+		if err != nil || len(ad.Attributes) != 1 {
+			if err == nil {
+				err = fmt.Errorf("want one Name = expr")
+			}
+			return nil, fmt.Errorf("classad: patch line %d: %w", i+1, err)
+		}
+		attr := ad.Attributes[0]
+		p.Set = append(p.Set, PatchAttr{Name: attr.Name, Expr: &Expr{expr: attr.Value}})
+	}
+	p.sort()
+	return p, nil
+}
+
+// parsePatchName parses an attribute name, bare or quoted.
+func parsePatchName(s string) (string, bool) {
+	e, err := parser.ParseExpr(s)
+	if err != nil {
+		return "", false
+	}
+	ref, ok := e.(*ast.AttributeReference)
+	if !ok || ref.Scope != ast.NoScope {
+		return "", false
+	}
+	return ref.Name, true
+}
+
+// sort puts p's sets and deletes in normalized-name order.
+func (p *Patch) sort() {
+	sort.SliceStable(p.Set, func(i, j int) bool {
+		return normalizeName(p.Set[i].Name) < normalizeName(p.Set[j].Name)
+	})
+	sort.SliceStable(p.Delete, func(i, j int) bool {
+		return normalizeName(p.Delete[i]) < normalizeName(p.Delete[j])
+	})
+}
+
+// patchJSON is the JSON form of a Patch.
+type patchJSON struct {
+	Set    json.RawMessage `json:"set,omitempty"`
+	Delete []string        `json:"delete,omitempty"`
+}
+
+// MarshalJSON implements json.Marshaler, excluding private attributes set (as
+// ClassAd.MarshalJSON does).
+func (p *Patch) MarshalJSON() ([]byte, error) { return p.marshalJSON(false) }
+
+// MarshalJSONWithPrivate is MarshalJSON including private attributes. Prefer
+// MarshalJSON for anything client-facing.
+func (p *Patch) MarshalJSONWithPrivate() ([]byte, error) { return p.marshalJSON(true) }
+
+func (p *Patch) marshalJSON(includePrivate bool) ([]byte, error) {
+	var out patchJSON
+	if p != nil {
+		set := New()
+		for _, a := range p.Set {
+			set.InsertExpr(a.Name, a.Expr)
+		}
+		if set.Size() > 0 {
+			b, err := set.marshalJSON(includePrivate)
+			if err != nil {
+				return nil, err
+			}
+			if string(b) != "{}" {
+				out.Set = b
+			}
+		}
+		out.Delete = p.Delete
+	}
+	return json.Marshal(out)
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (p *Patch) UnmarshalJSON(data []byte) error {
+	var in patchJSON
+	if err := json.Unmarshal(data, &in); err != nil {
+		return err
+	}
+	*p = Patch{Delete: in.Delete}
+	if len(in.Set) > 0 {
+		var set ClassAd
+		if err := set.UnmarshalJSON(in.Set); err != nil {
+			return err
+		}
+		for _, attr := range adAttributes(&set) {
+			p.Set = append(p.Set, PatchAttr{Name: attr.Name, Expr: &Expr{expr: attr.Value}})
+		}
+	}
+	p.sort()
+	return nil
+}
@@ -0,0 +1,93 @@
+package classad
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestDiffApply(t *testing.T) {
+	old, _ := Parse(`[Cpus = 4; Memory = 2048; State = "Idle"; Requirements = (TARGET.Memory > 1024); LastHeard = 10]`)
+	new, _ := Parse(`[cpus = 4; Memory = 4096; State = "Busy"; Requirements = TARGET.Memory > 1024; Activity = "Busy"; ClaimId = "secret"]`)
+
+	p := Diff(old, new)
+	want := "Activity = \"Busy\"\nMemory = 4096\nState = \"Busy\"\n-LastHeard\n"
+	if got := p.String(); got != want {
+		t.Errorf("String() =\n%s\nwant\n%s", got, want)
+	}
+	if !strings.Contains(p.StringWithPrivate(), `ClaimId = "secret"`) {
+		t.Errorf("StringWithPrivate() lacks the private attribute:\n%s", p.StringWithPrivate())
+	}
+
+	old.Apply(p)
+	if !old.Equal(new) {
+		t.Errorf("Apply: got %s, want %s", old.StringWithPrivate(), new.StringWithPrivate())
+	}
+	if p := Diff(old, new); !p.Empty() {
+		t.Errorf("Diff of equal ads = %q", p.StringWithPrivate())
+	}
+
+	if p := Diff(nil, new); len(p.Set) != new.Size() || len(p.Delete) != 0 {
+		t.Errorf("Diff(nil, new) = %+v", p)
+	}
+	if p := Diff(new, nil); len(p.Delete) != new.Size() || len(p.Set) != 0 {
+		t.Errorf("Diff(new, nil) = %+v", p)
+	}
+}
+
+func TestParsePatch(t *testing.T) {
+	text := "Memory = 4096\n\n'odd name' = {1, 2}\nRank = TARGET.Cpus * 2\n- LastHeard\n-'gone too'\n"
+	p, err := ParsePatch(text)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "Memory = 4096\n'odd name' = {1, 2}\nRank = (TARGET.Cpus * 2)\n-'gone too'\n-LastHeard\n"
+	if got := p.String(); got != want {
+		t.Errorf("round trip =\n%s\nwant\n%s", got, want)
+	}
+	again, err := ParsePatch(p.String())
+	if err != nil || again.String() != want {
+		t.Errorf("reparse = %q, %v", again.String(), err)
+	}
+
+	for _, bad := range []string{"Memory = ", "A = 1; B = 2", "-TARGET.Memory", "-1"} {
+		if _, err := ParsePatch(bad); err == nil {
+			t.Errorf("ParsePatch(%q) succeeded", bad)
+		}
+	}
+}
+
+func TestPatchJSON(t *testing.T) {
+	old, _ := Parse(`[A = 1; B = 2; ClaimId = "x"]`)
+	new, _ := Parse(`[A = 1; B = A + 1; C = "c"; ClaimId = "y"]`)
+	p := Diff(old, new)
+
+	b, err := json.Marshal(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"set":{"B":"\/Expr((A + 1))\/","C":"c"}}`
+	if string(b) != want {
+		t.Errorf("MarshalJSON = %s\nwant %s", b, want)
+	}
+
+	b, err = p.MarshalJSONWithPrivate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var back Patch
+	if err := json.Unmarshal(b, &back); err != nil {
+		t.Fatal(err)
+	}
+	if back.StringWithPrivate() != p.StringWithPrivate() {
+		t.Errorf("JSON round trip =\n%s\nwant\n%s", back.StringWithPrivate(), p.StringWithPrivate())
+	}
+
+	var del Patch
+	if err := json.Unmarshal([]byte(`{"delete":["B","A"]}`), &del); err != nil {
+		t.Fatal(err)
+	}
+	if del.String() != "-A\n-B\n" {
+		t.Errorf("delete-only patch = %q", del.String())
+	}
+}
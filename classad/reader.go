@@ -2,6 +2,7 @@ package classad
 
 import (
 	"bufio"
+	"encoding/xml"
 	"io"
 	"iter"
 	"strings"
@@ -10,13 +11,15 @@ import (
 )
 
 // Reader provides an iterator for parsing multiple ClassAds from an io.Reader.
-// It supports both new-style (bracketed) and old-style (newline-delimited) formats.
+// It supports new-style (bracketed), old-style (newline-delimited), XML and JSON formats.
 // New-style ClassAds can be concatenated without delimiters or whitespace.
 type Reader struct {
 	reader   *bufio.Reader
 	parser   *parser.ReaderParser
 	scanner  *bufio.Scanner
 	oldStyle bool
+	xml      *xml.Decoder
+	json     *jsonStream
 	err      error
 	current  *ClassAd
 }
@@ -60,6 +63,12 @@ func (r *Reader) Next() bool {
 		return false
 	}
 
+	if r.xml != nil {
+		return r.nextXML()
+	}
+	if r.json != nil {
+		return r.nextJSON()
+	}
 	if r.oldStyle {
 		return r.nextOld()
 	}
@@ -2,10 +2,21 @@ package classad
 
 import (
 	"fmt"
+	"math"
 	"reflect"
+	"sort"
 	"strings"
+	"time"
 
 	"github.com/PelicanPlatform/classad/ast"
+	"github.com/PelicanPlatform/classad/parser"
+)
+
+var (
+	timeType     = reflect.TypeOf(time.Time{})
+	durationType = reflect.TypeOf(time.Duration(0))
+	exprType     = reflect.TypeOf((*Expr)(nil))
+	classAdType  = reflect.TypeOf((*ClassAd)(nil))
 )
 
 // Marshal converts a Go value to a ClassAd string representation.
@@ -17,6 +28,14 @@ import (
 //   - Field name: classad:"custom_name" or json:"custom_name"
 //   - Omit field: classad:"-" or json:"-"
 //   - Omit if empty: classad:"name,omitempty" or json:"name,omitempty"
+//   - Store a string field as an expression: classad:"name,expr"
+//   - Fail Unmarshal if the attribute is missing: classad:"name,required"
+//
+// A time.Time is stored as Unix seconds and a time.Duration as seconds (an
+// integer when whole, a real otherwise). A *Expr field is stored as its
+// unevaluated expression. The fields of an embedded struct without a tag name
+// are flattened into the parent ad; when names collide, the least deeply
+// embedded field wins, and the first declared among equals.
 //
 // Example:
 //
@@ -49,7 +68,9 @@ func Marshal(v interface{}) (string, error) {
 // Unmarshal parses a ClassAd string and stores the result in the value pointed to by v.
 // It works similarly to encoding/json.Unmarshal but expects ClassAd format.
 // Struct fields can use "classad" struct tags to control unmarshaling behavior.
-// If no "classad" tag is present, it falls back to the "json" tag.
+// If no "classad" tag is present, it falls back to the "json" tag. Field
+// mappings and tag options are as for Marshal; a field tagged "required" whose
+// attribute is missing is an error.
 //
 // Example:
 //
@@ -101,6 +122,13 @@ func marshalValue(val reflect.Value) (ast.Expr, error) {
 		val = val.Elem()
 	}
 
+	switch val.Type() {
+	case timeType:
+		return &ast.IntegerLiteral{Value: val.Interface().(time.Time).Unix()}, nil
+	case durationType:
+		return durationLiteral(time.Duration(val.Int())), nil
+	}
+
 	switch val.Kind() {
 	case reflect.Bool:
 		return &ast.BooleanLiteral{Value: val.Bool()}, nil
@@ -132,11 +160,13 @@ func marshalValue(val reflect.Value) (ast.Expr, error) {
 		if val.Type().Key().Kind() != reflect.String {
 			return nil, fmt.Errorf("maps must have string keys, got %v", val.Type().Key().Kind())
 		}
-		attributes := make([]*ast.AttributeAssignment, 0, val.Len())
-		iter := val.MapRange()
-		for iter.Next() {
-			key := iter.Key().String()
-			value, err := marshalValue(iter.Value())
+		// Keys in order, so the same map always marshals the same way.
+		keys := val.MapKeys()
+		sort.Slice(keys, func(i, j int) bool { return keys[i].String() < keys[j].String() })
+		attributes := make([]*ast.AttributeAssignment, 0, len(keys))
+		for _, k := range keys {
+			key := k.String()
+			value, err := marshalValue(val.MapIndex(k))
 			if err != nil {
 				return nil, fmt.Errorf("map key %q: %w", key, err)
 			}
@@ -155,39 +185,45 @@ func marshalValue(val reflect.Value) (ast.Expr, error) {
 	}
 }
 
+// durationLiteral returns d in seconds: an integer when whole, a real
+// otherwise.
+func durationLiteral(d time.Duration) ast.Expr {
+	if d%time.Second == 0 {
+		return &ast.IntegerLiteral{Value: int64(d / time.Second)}
+	}
+	return &ast.RealLiteral{Value: d.Seconds()}
+}
+
 // marshalStruct converts a struct to a ClassAd record
 func marshalStruct(val reflect.Value) (ast.Expr, error) {
-	typ := val.Type()
-	attributes := make([]*ast.AttributeAssignment, 0)
-
-	for i := 0; i < typ.NumField(); i++ {
-		field := typ.Field(i)
-		fieldVal := val.Field(i)
+	fields := structFields(val.Type())
+	attributes := make([]*ast.AttributeAssignment, 0, len(fields))
 
-		// Skip unexported fields
-		if !field.IsExported() {
-			continue
-		}
-
-		// Get field name and options from struct tags
-		name, opts := parseStructTag(field)
-		if name == "-" {
-			continue // Skip this field
+	for _, f := range fields {
+		fieldVal, ok := fieldByIndex(val, f.index)
+		if !ok {
+			continue // inside a nil embedded pointer
 		}
 
 		// Handle omitempty
-		if opts.omitEmpty && isEmptyValue(fieldVal) {
+		if f.opts.omitEmpty && isEmptyValue(fieldVal) {
 			continue
 		}
 
 		// Marshal the field value
-		expr, err := marshalValue(fieldVal)
+		var expr ast.Expr
+		var err error
+		if f.opts.expr {
+			expr, err = marshalExprString(fieldVal)
+		} else {
+			expr, err = marshalValue(fieldVal)
+		}
 		if err != nil {
-			return nil, fmt.Errorf("field %s: %w", field.Name, err)
+			return nil, fmt.Errorf("field %s: %w", f.goName, err)
 		}
 
 		attributes = append(attributes, &ast.AttributeAssignment{
-			Name:  name,
+			Name:  f.name,
 			Value: expr,
 		})
 	}
@@ -195,6 +231,145 @@ func marshalStruct(val reflect.Value) (ast.Expr, error) {
 	return &ast.RecordLiteral{ClassAd: &ast.ClassAd{Attributes: attributes}}, nil
 }
 
+// marshalExprString parses the string of an ",expr" field. The empty string
+// is undefined.
+func marshalExprString(val reflect.Value) (ast.Expr, error) {
+	if val.Kind() != reflect.String {
+		return nil, fmt.Errorf("expr option requires a string field, got %v", val.Type())
+	}
+	if strings.TrimSpace(val.String()) == "" {
+		return &ast.UndefinedLiteral{}, nil
+	}
+	expr, err := parser.ParseExpr(val.String())
+	if err != nil {
+		return nil, fmt.Errorf("invalid expression %q: %w", val.String(), err)
+	}
+	return expr, nil
+}
+
+// structField is a struct field mapped to a ClassAd attribute, possibly
+// promoted from an embedded struct.
+type structField struct {
+	name   string // attribute name
+	goName string // Go field name, for errors
+	index  []int  // as for reflect.Value.FieldByIndex
+	depth  int    // embedding depth
+	opts   tagOptions
+}
+
+// structFields returns the fields of typ that map to attributes, in
+// declaration order, with the fields of embedded structs flattened in place.
+// Of fields sharing a name, only the least deeply embedded (and the first
+// declared among equals) is kept.
+func structFields(typ reflect.Type) []structField {
+	var all []structField
+	var walk func(t reflect.Type, index []int, visiting map[reflect.Type]bool)
+	walk = func(t reflect.Type, index []int, visiting map[reflect.Type]bool) {
+		visiting[t] = true
+		defer delete(visiting, t)
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			name, opts := parseStructTag(field)
+			if name == "-" {
+				continue
+			}
+			fieldIndex := append(append([]int(nil), index...), i)
+			if embedded := embeddedStruct(field); embedded != nil && !hasTagName(field) {
+				if !visiting[embedded] {
+					walk(embedded, fieldIndex, visiting)
+				}
+				continue
+			}
+			if !field.IsExported() {
+				continue
+			}
+			all = append(all, structField{
+				name:   name,
+				goName: field.Name,
+				index:  fieldIndex,
+				depth:  len(index),
+				opts:   opts,
+			})
+		}
+	}
+	walk(typ, nil, map[reflect.Type]bool{})
+
+	best := make(map[string]int, len(all))
+	for _, f := range all {
+		if d, ok := best[f.name]; !ok || f.depth < d {
+			best[f.name] = f.depth
+		}
+	}
+	fields := all[:0]
+	kept := make(map[string]bool, len(all))
+	for _, f := range all {
+		if f.depth == best[f.name] && !kept[f.name] {
+			kept[f.name] = true
+			fields = append(fields, f)
+		}
+	}
+	return fields
+}
+
+// embeddedStruct returns the struct type of an embedded field whose fields
+// are flattened into the parent, or nil. time.Time is a value, not a set of
+// fields, and a pointer to an unexported struct cannot be allocated.
+func embeddedStruct(field reflect.StructField) reflect.Type {
+	if !field.Anonymous {
+		return nil
+	}
+	t := field.Type
+	if t.Kind() == reflect.Ptr {
+		if !field.IsExported() {
+			return nil
+		}
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct || t == timeType {
+		return nil
+	}
+	return t
+}
+
+// hasTagName reports whether field's tag gives it an attribute name.
+func hasTagName(field reflect.StructField) bool {
+	tag, ok := field.Tag.Lookup("classad")
+	if !ok {
+		tag, ok = field.Tag.Lookup("json")
+	}
+	return ok && strings.Split(tag, ",")[0] != ""
+}
+
+// fieldByIndex is reflect.Value.FieldByIndex, reporting false instead of
+// panicking at a nil embedded pointer.
+func fieldByIndex(val reflect.Value, index []int) (reflect.Value, bool) {
+	for i, x := range index {
+		if i > 0 && val.Kind() == reflect.Ptr {
+			if val.IsNil() {
+				return reflect.Value{}, false
+			}
+			val = val.Elem()
+		}
+		val = val.Field(x)
+	}
+	return val, true
+}
+
+// fieldByIndexAlloc is reflect.Value.FieldByIndex, allocating nil embedded
+// pointers on the way.
+func fieldByIndexAlloc(val reflect.Value, index []int) reflect.Value {
+	for i, x := range index {
+		if i > 0 && val.Kind() == reflect.Ptr {
+			if val.IsNil() {
+				val.Set(reflect.New(val.Type().Elem()))
+			}
+			val = val.Elem()
+		}
+		val = val.Field(x)
+	}
+	return val
+}
+
 // unmarshalInto unmarshals a ClassAd into a Go value
 func unmarshalInto(ad *ClassAd, v interface{}) error {
 	val := reflect.ValueOf(v)
@@ -242,46 +417,57 @@ func unmarshalValue(node *ast.ClassAd, val reflect.Value) error {
 
 // unmarshalStruct unmarshals a ClassAd into a struct
 func unmarshalStruct(node *ast.ClassAd, val reflect.Value) error {
-	typ := val.Type()
+	fields := structFields(val.Type())
 
-	// Create a map of classad names to struct field indices
-	fieldMap := make(map[string]int)
-	for i := 0; i < typ.NumField(); i++ {
-		field := typ.Field(i)
-		if !field.IsExported() {
-			continue
-		}
-		name, _ := parseStructTag(field)
-		if name != "-" {
-			fieldMap[name] = i
-		}
+	// Create a map of classad names to struct fields
+	fieldMap := make(map[string]*structField, len(fields))
+	for i := range fields {
+		fieldMap[fields[i].name] = &fields[i]
 	}
+	found := make(map[string]bool)
+	// Attributes are evaluated in the context of the ClassAd
+	ad := &ClassAd{ad: node}
 
 	// Iterate over ClassAd attributes
 	for _, attr := range node.Attributes {
-		fieldIdx, ok := fieldMap[attr.Name]
+		f, ok := fieldMap[attr.Name]
 		if !ok {
 			// Ignore unknown fields
 			continue
 		}
+		found[f.name] = true
 
-		field := typ.Field(fieldIdx)
-		fieldVal := val.Field(fieldIdx)
+		fieldVal := fieldByIndexAlloc(val, f.index)
 
-		// Special handling for *Expr fields - don't evaluate
-		if field.Type == reflect.TypeOf((*Expr)(nil)) {
+		// Special handling for *Expr and ",expr" fields - don't evaluate
+		if fieldVal.Type() == exprType {
 			expr := &Expr{expr: attr.Value}
 			fieldVal.Set(reflect.ValueOf(expr))
 			continue
 		}
+		if f.opts.expr {
+			if fieldVal.Kind() != reflect.String {
+				return fmt.Errorf("field %s: expr option requires a string field, got %v", f.goName, fieldVal.Type())
+			}
+			if _, ok := attr.Value.(*ast.UndefinedLiteral); ok {
+				fieldVal.SetString("")
+			} else {
+				fieldVal.SetString(attr.Value.String())
+			}
+			continue
+		}
 
-		// Evaluate the attribute in the context of the ClassAd
-		ad := &ClassAd{ad: node}
 		result := ad.EvaluateAttr(attr.Name)
 
 		// Unmarshal the result into the field
 		if err := unmarshalValueInto(result, fieldVal); err != nil {
-			return fmt.Errorf("field %s: %w", field.Name, err)
+			return fmt.Errorf("field %s: %w", f.goName, err)
+		}
+	}
+
+	for _, f := range fields {
+		if f.opts.required && !found[f.name] {
+			return fmt.Errorf("field %s: missing required attribute %s", f.goName, f.name)
 		}
 	}
 
@@ -294,6 +480,11 @@ func unmarshalMap(node *ast.ClassAd, val reflect.Value) error {
 	elemType := val.Type().Elem()
 
 	for _, attr := range node.Attributes {
+		if elemType == exprType {
+			val.SetMapIndex(reflect.ValueOf(attr.Name), reflect.ValueOf(&Expr{expr: attr.Value}))
+			continue
+		}
+
 		result := ad.EvaluateAttr(attr.Name)
 
 		// Create a new element of the appropriate type
@@ -316,7 +507,7 @@ func unmarshalValueInto(result Value, val reflect.Value) error {
 	// Handle pointers - check for special types first
 	if val.Kind() == reflect.Ptr {
 		// Check if target is *ClassAd
-		if val.Type() == reflect.TypeOf((*ClassAd)(nil)) {
+		if val.Type() == classAdType {
 			if result.IsClassAd() {
 				nestedAd, err := result.ClassAdValue()
 				if err != nil {
@@ -328,17 +519,40 @@ func unmarshalValueInto(result Value, val reflect.Value) error {
 			return fmt.Errorf("expected ClassAd, got %v", result.Type())
 		}
 		// Check if target is *Expr
-		if val.Type() == reflect.TypeOf((*Expr)(nil)) {
+		if val.Type() == exprType {
 			// *Expr fields are handled in unmarshalStruct to preserve the unevaluated expression
 			return fmt.Errorf("*Expr fields should be handled in unmarshalStruct")
 		}
 
+		// undefined leaves the pointer nil, as a nil pointer marshals to
+		// undefined
+		if result.IsUndefined() {
+			val.Set(reflect.Zero(val.Type()))
+			return nil
+		}
 		if val.IsNil() {
 			val.Set(reflect.New(val.Type().Elem()))
 		}
 		val = val.Elem()
 	}
 
+	switch val.Type() {
+	case timeType:
+		t, err := valueToTime(result)
+		if err != nil {
+			return err
+		}
+		val.Set(reflect.ValueOf(t))
+		return nil
+	case durationType:
+		d, err := valueToDuration(result)
+		if err != nil {
+			return err
+		}
+		val.SetInt(int64(d))
+		return nil
+	}
+
 	switch val.Kind() {
 	case reflect.Bool:
 		if result.IsBool() {
@@ -471,6 +685,40 @@ func unmarshalValueInto(result Value, val reflect.Value) error {
 	return nil
 }
 
+// valueToTime converts an absolute time, or a number of Unix seconds, to a
+// time.Time.
+func valueToTime(result Value) (time.Time, error) {
+	switch {
+	case result.IsAbsTime():
+		return result.AbsTimeValue()
+	case result.IsInteger():
+		v, _ := result.IntValue()
+		return time.Unix(v, 0), nil
+	case result.IsReal():
+		v, _ := result.RealValue()
+		secs := math.Floor(v)
+		return time.Unix(int64(secs), int64((v-secs)*1e9)), nil
+	}
+	return time.Time{}, fmt.Errorf("expected time, got %v", result.Type())
+}
+
+// valueToDuration converts a relative time, or a number of seconds, to a
+// time.Duration.
+func valueToDuration(result Value) (time.Duration, error) {
+	switch {
+	case result.IsInteger():
+		v, _ := result.IntValue()
+		return time.Duration(v) * time.Second, nil
+	case result.IsReal():
+		v, _ := result.RealValue()
+		return time.Duration(v * float64(time.Second)), nil
+	case result.IsRelTime():
+		v, _ := result.RelTimeValue()
+		return time.Duration(v * float64(time.Second)), nil
+	}
+	return 0, fmt.Errorf("expected duration, got %v", result.Type())
+}
+
 // valueToInterface converts a Value to a Go interface{} type
 func valueToInterface(result Value) interface{} {
 	if result.IsUndefined() {
@@ -533,6 +781,8 @@ func valueToInterface(result Value) interface{} {
 // tagOptions represents parsed struct tag options
 type tagOptions struct {
 	omitEmpty bool
+	expr      bool // store a string field as an expression
+	required  bool // fail Unmarshal when the attribute is missing
 }
 
 // parseStructTag parses a struct field's tags to determine the ClassAd field name and options
@@ -565,8 +815,13 @@ func parseTag(tag, defaultName string, opts *tagOptions) (string, tagOptions) {
 
 	// Parse options
 	for i := 1; i < len(parts); i++ {
-		if parts[i] == "omitempty" {
+		switch parts[i] {
+		case "omitempty":
 			opts.omitEmpty = true
+		case "expr":
+			opts.expr = true
+		case "required":
+			opts.required = true
 		}
 	}
 
@@ -588,6 +843,10 @@ func isEmptyValue(v reflect.Value) bool {
 		return v.Float() == 0
 	case reflect.Interface, reflect.Ptr:
 		return v.IsNil()
+	case reflect.Struct:
+		if v.Type() == timeType {
+			return v.Interface().(time.Time).IsZero()
+		}
 	}
 	return false
 }
@@ -3,6 +3,7 @@ package classad
 import (
 	"strings"
 	"testing"
+	"time"
 )
 
 func TestMarshal_SimpleStruct(t *testing.T) {
@@ -824,3 +825,192 @@ func TestMarshal_NilClassAdAndExprFields(t *testing.T) {
 		t.Errorf("Expected Formula to be undefined, got %v", formulaVal.Type())
 	}
 }
+
+func TestMarshal_TimeAndDuration(t *testing.T) {
+	type Job struct {
+		QDate     time.Time
+		Completed time.Time `classad:"CompletionDate,omitempty"`
+		Walltime  time.Duration
+		Timeout   time.Duration
+		Started   *time.Time
+	}
+	start := time.Unix(1700000100, 0)
+	job := Job{QDate: time.Unix(1700000000, 0), Walltime: 90 * time.Minute, Timeout: 1500 * time.Millisecond, Started: &start}
+
+	result, err := Marshal(job)
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	want := "[QDate = 1700000000; Walltime = 5400; Timeout = 1.5; Started = 1700000100]"
+	if result != want {
+		t.Errorf("Marshal = %s, want %s", result, want)
+	}
+
+	var back Job
+	if err := Unmarshal(result, &back); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	if !back.QDate.Equal(job.QDate) || back.Walltime != job.Walltime || back.Timeout != job.Timeout ||
+		back.Started == nil || !back.Started.Equal(start) || !back.Completed.IsZero() {
+		t.Errorf("round trip = %+v, want %+v", back, job)
+	}
+
+	// Time values and reals convert too.
+	var fromTimes Job
+	err = Unmarshal(`[QDate = absTime("2023-11-14T22:13:20Z"); Walltime = relTime("1:30:00"); Timeout = 0.25]`, &fromTimes)
+	if err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	if fromTimes.QDate.Unix() != 1700000000 || fromTimes.Walltime != 90*time.Minute || fromTimes.Timeout != 250*time.Millisecond {
+		t.Errorf("Unmarshal of time values = %+v", fromTimes)
+	}
+	if err := Unmarshal(`[QDate = "yesterday"]`, &fromTimes); err == nil {
+		t.Error("Unmarshal of a string into time.Time succeeded")
+	}
+}
+
+type Resources struct {
+	Cpus   int
+	Memory int
+}
+
+// jobMeta is unexported; its Cpus ties with Resources.Cpus, which is declared
+// first and wins.
+type jobMeta struct {
+	Owner string
+	Cpus  int
+}
+
+type Placement struct {
+	Pool string
+}
+
+func TestMarshal_EmbeddedStruct(t *testing.T) {
+	type Job struct {
+		Resources
+		jobMeta
+		*Placement
+		ID   int `classad:"ClusterId"`
+		Cpus int `classad:"RequestCpus"`
+	}
+	type Outer struct {
+		Job
+		Memory int // shallower than Job.Resources.Memory
+	}
+
+	result, err := Marshal(Outer{Job: Job{Resources: Resources{Cpus: 4, Memory: 2048}, jobMeta: jobMeta{Owner: "bob"}, ID: 7, Cpus: 2}, Memory: 1})
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	// The nil *Placement contributes nothing.
+	want := `[Cpus = 4; Owner = "bob"; ClusterId = 7; RequestCpus = 2; Memory = 1]`
+	if result != want {
+		t.Errorf("Marshal = %s, want %s", result, want)
+	}
+
+	var back Outer
+	if err := Unmarshal(`[Cpus = 8; Memory = 512; Owner = "alice"; Pool = "chtc"; ClusterId = 9]`, &back); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	if back.Resources.Cpus != 8 || back.jobMeta.Cpus != 0 || back.Memory != 512 || back.Resources.Memory != 0 ||
+		back.Owner != "alice" || back.ID != 9 {
+		t.Errorf("Unmarshal = %+v", back)
+	}
+	if back.Placement == nil || back.Pool != "chtc" {
+		t.Errorf("embedded pointer = %+v", back.Placement)
+	}
+
+	// A tag name keeps an embedded struct nested.
+	type Named struct {
+		Resources `classad:"Resources"`
+	}
+	result, err = Marshal(Named{Resources{Cpus: 1, Memory: 2}})
+	if err != nil || result != "[Resources = [Cpus = 1; Memory = 2]]" {
+		t.Errorf("Marshal(Named) = %s, %v", result, err)
+	}
+}
+
+func TestMarshal_ExprTag(t *testing.T) {
+	type Job struct {
+		Requirements string `classad:"Requirements,expr"`
+		Rank         string `classad:",expr,omitempty"`
+		Periodic     string `classad:"PeriodicHold,expr"`
+	}
+	result, err := Marshal(Job{Requirements: "TARGET.Memory > 1024 && Cpus >= 2"})
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	want := "[Requirements = ((TARGET.Memory > 1024) && (Cpus >= 2)); PeriodicHold = undefined]"
+	if result != want {
+		t.Errorf("Marshal = %s, want %s", result, want)
+	}
+
+	var back Job
+	if err := Unmarshal(`[Requirements = TARGET.Memory > 1024; Rank = "x"; PeriodicHold = undefined]`, &back); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	if back.Requirements != "(TARGET.Memory > 1024)" || back.Rank != `"x"` || back.Periodic != "" {
+		t.Errorf("Unmarshal = %+v", back)
+	}
+
+	if _, err := Marshal(Job{Requirements: "1 +"}); err == nil {
+		t.Error("Marshal of an invalid expression succeeded")
+	}
+	type BadTag struct {
+		N int `classad:"N,expr"`
+	}
+	if _, err := Marshal(BadTag{1}); err == nil {
+		t.Error("Marshal of an int ,expr field succeeded")
+	}
+	if err := Unmarshal(`[N = 1]`, &BadTag{}); err == nil {
+		t.Error("Unmarshal into an int ,expr field succeeded")
+	}
+}
+
+func TestUnmarshal_Required(t *testing.T) {
+	type Inner struct {
+		Host string `classad:"Host,required"`
+	}
+	type Job struct {
+		ID    int `classad:"ClusterId,required"`
+		Name  string
+		Inner Inner
+	}
+
+	var job Job
+	if err := Unmarshal(`[ClusterId = 1; Inner = [Host = "h"]]`, &job); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	err := Unmarshal(`[Name = "x"]`, &job)
+	if err == nil || !strings.Contains(err.Error(), "missing required attribute ClusterId") {
+		t.Errorf("missing ClusterId: err = %v", err)
+	}
+	err = Unmarshal(`[ClusterId = 1; Inner = []]`, &job)
+	if err == nil || !strings.Contains(err.Error(), "missing required attribute Host") {
+		t.Errorf("missing Inner.Host: err = %v", err)
+	}
+}
+
+func TestUnmarshal_ExprMap(t *testing.T) {
+	var m map[string]*Expr
+	if err := Unmarshal(`[A = B + 1; B = 2]`, &m); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	if m["A"] == nil || m["A"].String() != "(B + 1)" || m["B"].String() != "2" {
+		t.Errorf("Unmarshal = %v", m)
+	}
+}
+
+func TestUnmarshal_UndefinedPointer(t *testing.T) {
+	type Job struct {
+		Cpus   *int64
+		Memory *int64
+	}
+	job := Job{Cpus: new(int64)}
+	if err := Unmarshal(`[Cpus = undefined; Memory = Missing; Disk = 1]`, &job); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	if job.Cpus != nil || job.Memory != nil {
+		t.Errorf("undefined pointers = %v, %v, want nil", job.Cpus, job.Memory)
+	}
+}
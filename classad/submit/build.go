@@ -0,0 +1,194 @@
+package submit
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/PelicanPlatform/classad/classad"
+)
+
+// Options configures Build. The zero Options is valid.
+type Options struct {
+	// Macros are variables defined before the file's first line, as
+	// condor_submit -append or a config file would. The file's own
+	// definitions take precedence.
+	Macros map[string]string
+	// Dir is the submit directory: the default Iwd, and what "queue from"
+	// files and "queue matching" patterns are relative to. Empty is the
+	// process's working directory, and leaves Iwd unset.
+	Dir string
+	// Owner is the job owner, set as Owner when non-empty.
+	Owner string
+	// ClusterID is the cluster number the jobs are queued in.
+	ClusterID int
+	// ReadFile reads the file of a "queue from" statement. Nil is
+	// os.ReadFile.
+	ReadFile func(name string) ([]byte, error)
+}
+
+// Submission is the jobs a submit description queues.
+type Submission struct {
+	// Cluster holds the attributes every job has in common, and ClusterId.
+	Cluster *classad.ClassAd
+	// Procs holds one ad per job, in queue order, with the attributes that
+	// differ between jobs and ProcId. Each is chained to Cluster; use
+	// Materialize for a self-contained job ad.
+	Procs []*classad.ClassAd
+}
+
+// Build expands f's queue statements into job ads. Each queue statement
+// queues its jobs with the commands that precede it; a variable is expanded
+// per job with the value it had at that queue statement, so a later
+// definition does not affect jobs already queued. opts may be nil.
+func (f *File) Build(opts *Options) (*Submission, error) {
+	if opts == nil {
+		opts = &Options{}
+	}
+	readFile := opts.ReadFile
+	if readFile == nil {
+		readFile = os.ReadFile
+	}
+	optMacros := make(map[string]string, len(opts.Macros))
+	for k, v := range opts.Macros {
+		optMacros[strings.ToLower(k)] = v
+	}
+
+	vars := map[string]string{}
+	var cmds []entry
+	var jobs []*classad.ClassAd
+	queued := false
+	for _, e := range f.entries {
+		if e.cmd != nil {
+			if !e.cmd.attr {
+				vars[strings.ToLower(e.cmd.key)] = e.cmd.value
+			}
+			cmds = append(cmds, e)
+			continue
+		}
+		queued = true
+		m := &macros{vars: vars, opts: optMacros}
+		count := 1
+		if e.q.count != "" {
+			s, err := m.expand(e.q.count)
+			if err != nil {
+				return nil, fmt.Errorf("submit: line %d: %v", e.line, err)
+			}
+			if count, err = strconv.Atoi(strings.TrimSpace(s)); err != nil || count < 0 {
+				return nil, fmt.Errorf("submit: line %d: invalid queue count %q", e.line, s)
+			}
+		}
+		items, err := e.q.resolveItems(m.expand, opts.Dir, readFile)
+		if err != nil {
+			return nil, fmt.Errorf("submit: line %d: %v", e.line, err)
+		}
+		for row, vals := range items {
+			for step := 0; step < count; step++ {
+				job := map[string]string{
+					"clusterid": strconv.Itoa(opts.ClusterID),
+					"cluster":   strconv.Itoa(opts.ClusterID),
+					"procid":    strconv.Itoa(len(jobs)),
+					"process":   strconv.Itoa(len(jobs)),
+					"step":      strconv.Itoa(step),
+					"itemindex": strconv.Itoa(row),
+					"row":       strconv.Itoa(row),
+				}
+				for k, name := range e.q.vars {
+					job[strings.ToLower(name)] = vals[k]
+				}
+				ad, err := buildJob(cmds, &macros{job: job, vars: vars, opts: optMacros}, opts, len(jobs))
+				if err != nil {
+					return nil, err
+				}
+				jobs = append(jobs, ad)
+			}
+		}
+	}
+	if !queued {
+		return nil, errors.New("submit: no queue statement")
+	}
+	return split(jobs, opts.ClusterID), nil
+}
+
+// buildJob builds the ad of one job from the commands preceding its queue
+// statement, expanding their values in m.
+func buildJob(cmds []entry, m *macros, opts *Options, proc int) (*classad.ClassAd, error) {
+	ad := classad.New()
+	ad.InsertAttr("ClusterId", int64(opts.ClusterID))
+	ad.InsertAttr("ProcId", int64(proc))
+	ad.InsertAttr("JobStatus", statusIdle)
+	ad.InsertAttr("JobUniverse", universes["vanilla"])
+	ad.InsertAttr("RequestCpus", 1)
+	for _, attr := range []string{"In", "Out", "Err"} {
+		ad.InsertAttrString(attr, "/dev/null")
+	}
+	if opts.Owner != "" {
+		ad.InsertAttrString("Owner", opts.Owner)
+	}
+	if opts.Dir != "" {
+		ad.InsertAttrString("Iwd", opts.Dir)
+	}
+
+	for _, e := range cmds {
+		value, err := m.expand(e.cmd.value)
+		if err != nil {
+			return nil, fmt.Errorf("submit: line %d: %v", e.line, err)
+		}
+		if e.cmd.attr {
+			err = insertPassthrough(ad, e.cmd.key, value)
+		} else if s, ok := commands[strings.ToLower(e.cmd.key)]; ok {
+			err = s.apply(ad, value)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("submit: line %d: %v", e.line, err)
+		}
+	}
+	if cmd, ok := ad.EvaluateAttrString("Cmd"); !ok || cmd == "" {
+		return nil, errors.New("submit: no executable")
+	}
+	return ad, nil
+}
+
+// split divides jobs into a cluster ad of the attributes all of them bind to
+// the same expression and proc ads of the rest, chained to it.
+func split(jobs []*classad.ClassAd, clusterID int) *Submission {
+	cluster := classad.New()
+	cluster.InsertAttr("ClusterId", int64(clusterID))
+	sub := &Submission{Cluster: cluster}
+	if len(jobs) == 0 {
+		return sub
+	}
+
+	shared := map[string]bool{}
+	for _, name := range jobs[0].GetAttributes() {
+		if strings.EqualFold(name, "ProcId") {
+			continue
+		}
+		first, _ := jobs[0].Lookup(name)
+		same := true
+		for _, job := range jobs[1:] {
+			if e, ok := job.Lookup(name); !ok || e.String() != first.String() {
+				same = false
+				break
+			}
+		}
+		if same {
+			shared[strings.ToLower(name)] = true
+			cluster.InsertExpr(name, first)
+		}
+	}
+	for _, job := range jobs {
+		proc := classad.New()
+		for _, name := range job.GetAttributes() {
+			if !shared[strings.ToLower(name)] {
+				e, _ := job.Lookup(name)
+				proc.InsertExpr(name, e)
+			}
+		}
+		proc.ChainToAd(cluster)
+		sub.Procs = append(sub.Procs, proc)
+	}
+	return sub
+}
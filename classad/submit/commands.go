@@ -0,0 +1,235 @@
+package submit
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/PelicanPlatform/classad/classad"
+)
+
+// kind is how a submit command's value becomes a job attribute.
+type kind int
+
+const (
+	kindString   kind = iota // a string, as written
+	kindExpr                 // a ClassAd expression
+	kindBool                 // true/false (yes/no), else an expression
+	kindInt                  // an integer, else an expression
+	kindMemory               // a size in MB; a bare number is MB
+	kindDisk                 // a size in KB; a bare number is KB
+	kindUniverse             // a universe name, as JobUniverse
+	kindArgs                 // a string with any surrounding quotes removed
+	kindHold                 // hold = true queues the job held
+)
+
+// spec maps a submit command to the job attribute it sets.
+type spec struct {
+	attr string
+	kind kind
+}
+
+// commands is the submit commands the package turns into job attributes, by
+// lower-cased name.
+var commands = map[string]spec{
+	"executable":              {"Cmd", kindString},
+	"arguments":               {"Arguments", kindArgs},
+	"universe":                {"JobUniverse", kindUniverse},
+	"input":                   {"In", kindString},
+	"output":                  {"Out", kindString},
+	"error":                   {"Err", kindString},
+	"log":                     {"UserLog", kindString},
+	"initialdir":              {"Iwd", kindString},
+	"environment":             {"Environment", kindArgs},
+	"getenv":                  {"GetEnv", kindBool},
+	"request_cpus":            {"RequestCpus", kindInt},
+	"request_gpus":            {"RequestGPUs", kindInt},
+	"request_memory":          {"RequestMemory", kindMemory},
+	"request_disk":            {"RequestDisk", kindDisk},
+	"requirements":            {"Requirements", kindExpr},
+	"rank":                    {"Rank", kindExpr},
+	"priority":                {"JobPrio", kindInt},
+	"hold":                    {"JobStatus", kindHold},
+	"notify_user":             {"NotifyUser", kindString},
+	"accounting_group":        {"AcctGroup", kindString},
+	"accounting_group_user":   {"AcctGroupUser", kindString},
+	"batch_name":              {"JobBatchName", kindString},
+	"max_retries":             {"MaxRetries", kindInt},
+	"docker_image":            {"DockerImage", kindString},
+	"container_image":         {"ContainerImage", kindString},
+	"transfer_executable":     {"TransferExecutable", kindBool},
+	"transfer_input_files":    {"TransferInput", kindString},
+	"transfer_output_files":   {"TransferOutput", kindString},
+	"should_transfer_files":   {"ShouldTransferFiles", kindString},
+	"when_to_transfer_output": {"WhenToTransferOutput", kindString},
+	"stream_output":           {"StreamOut", kindBool},
+	"stream_error":            {"StreamErr", kindBool},
+	"nice_user":               {"NiceUser", kindBool},
+	"leave_in_queue":          {"LeaveJobInQueue", kindExpr},
+	"periodic_hold":           {"PeriodicHold", kindExpr},
+	"periodic_release":        {"PeriodicRelease", kindExpr},
+	"periodic_remove":         {"PeriodicRemove", kindExpr},
+	"on_exit_hold":            {"OnExitHold", kindExpr},
+	"on_exit_remove":          {"OnExitRemove", kindExpr},
+	"job_max_vacate_time":     {"JobMaxVacateTime", kindInt},
+}
+
+// universes is the JobUniverse number of each universe name.
+var universes = map[string]int64{
+	"standard":  1,
+	"vanilla":   5,
+	"docker":    5,
+	"container": 5,
+	"scheduler": 7,
+	"grid":      9,
+	"java":      10,
+	"parallel":  11,
+	"local":     12,
+	"vm":        13,
+}
+
+// Job statuses a submit file can queue a job in.
+const (
+	statusIdle = 1
+	statusHeld = 5
+)
+
+// apply sets the attribute s maps value to.
+func (s spec) apply(ad *classad.ClassAd, value string) error {
+	switch s.kind {
+	case kindString:
+		ad.InsertAttrString(s.attr, value)
+	case kindArgs:
+		ad.InsertAttrString(s.attr, unquote(value))
+	case kindExpr:
+		return insertExpr(ad, s.attr, value)
+	case kindBool:
+		if b, ok := parseBool(value); ok {
+			ad.InsertAttrBool(s.attr, b)
+			return nil
+		}
+		return insertExpr(ad, s.attr, value)
+	case kindInt:
+		if n, err := strconv.ParseInt(value, 10, 64); err == nil {
+			ad.InsertAttr(s.attr, n)
+			return nil
+		}
+		return insertExpr(ad, s.attr, value)
+	case kindMemory, kindDisk:
+		unit := int64(1 << 10)
+		if s.kind == kindDisk {
+			unit = 1
+		}
+		if n, ok := parseSize(value, unit); ok {
+			ad.InsertAttr(s.attr, n)
+			return nil
+		}
+		return insertExpr(ad, s.attr, value)
+	case kindUniverse:
+		u, ok := universes[strings.ToLower(value)]
+		if !ok {
+			return fmt.Errorf("unknown universe %q", value)
+		}
+		ad.InsertAttr(s.attr, u)
+		if strings.EqualFold(value, "docker") && !hasAttr(ad, "WantDocker") {
+			ad.InsertAttrBool("WantDocker", true)
+		}
+	case kindHold:
+		b, ok := parseBool(value)
+		if !ok {
+			return fmt.Errorf("hold must be true or false, got %q", value)
+		}
+		if b {
+			ad.InsertAttr(s.attr, statusHeld)
+		} else {
+			ad.InsertAttr(s.attr, statusIdle)
+		}
+	}
+	return nil
+}
+
+// insertExpr parses value as a ClassAd expression and binds it to name.
+func insertExpr(ad *classad.ClassAd, name, value string) error {
+	expr, err := classad.ParseExpr(value)
+	if err != nil {
+		return fmt.Errorf("%s: %v", name, err)
+	}
+	ad.InsertExpr(name, expr)
+	return nil
+}
+
+// insertPassthrough binds a "+Attr" or "MY.Attr" command's value verbatim.
+// The pair is read as an old-format ad line, so the name is checked the way
+// a job ad's would be.
+func insertPassthrough(ad *classad.ClassAd, name, value string) error {
+	parsed, err := classad.ParseOld(name + " = " + value)
+	if err != nil {
+		return fmt.Errorf("+%s: %v", name, err)
+	}
+	expr, ok := parsed.Lookup(name)
+	if !ok || parsed.Size() != 1 {
+		return fmt.Errorf("+%s: invalid value %q", name, value)
+	}
+	ad.InsertExpr(name, expr)
+	return nil
+}
+
+func hasAttr(ad *classad.ClassAd, name string) bool {
+	_, ok := ad.Lookup(name)
+	return ok
+}
+
+// parseBool reads a submit-file boolean: true/false, yes/no or t/f, in any
+// case.
+func parseBool(s string) (bool, bool) {
+	switch strings.ToLower(s) {
+	case "true", "t", "yes", "y":
+		return true, true
+	case "false", "f", "no", "n":
+		return false, true
+	}
+	return false, false
+}
+
+// sizeUnits is the size in KB of each unit suffix. Units are binary, as in
+// condor_submit.
+var sizeUnits = map[string]float64{
+	"k": 1, "kb": 1,
+	"m": 1 << 10, "mb": 1 << 10,
+	"g": 1 << 20, "gb": 1 << 20,
+	"t": 1 << 30, "tb": 1 << 30,
+}
+
+// parseSize reads a size such as "2GB", "512M" or "100", returning it in
+// units of unit KB (rounded up). A bare number is already in that unit. ok is
+// false when s is not a number with an optional unit suffix, and so is an
+// expression.
+func parseSize(s string, unit int64) (n int64, ok bool) {
+	s = strings.TrimSpace(s)
+	end := strings.LastIndexAny(s, "0123456789.") + 1
+	if end == 0 {
+		return 0, false
+	}
+	num, err := strconv.ParseFloat(s[:end], 64)
+	if err != nil || num < 0 {
+		return 0, false
+	}
+	suffix := strings.ToLower(strings.TrimSpace(s[end:]))
+	if suffix == "" {
+		return int64(math.Ceil(num)), true
+	}
+	kb, ok := sizeUnits[suffix]
+	if !ok {
+		return 0, false
+	}
+	return int64(math.Ceil(num * kb / float64(unit))), true
+}
+
+// unquote strips one pair of surrounding double quotes.
+func unquote(s string) string {
+	if len(s) >= 2 && s[0] == '"' && s[len(s)-1] == '"' {
+		return s[1 : len(s)-1]
+	}
+	return s
+}
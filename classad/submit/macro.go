@@ -0,0 +1,51 @@
+package submit
+
+import (
+	"os"
+	"strings"
+
+	"github.com/PelicanPlatform/classad/classad/internal/expand"
+)
+
+// syntax is the references a submit description can make. $$(name) is left
+// as written: the schedd expands it at match time.
+var syntax = expand.Syntax{Funcs: []string{"ENV"}, KeepDollarDollar: true}
+
+// macros is the variable scope one job's values expand in. Names are
+// case-insensitive, as in condor_submit.
+type macros struct {
+	job  map[string]string // per-job: ClusterId, ProcId, the queue variables
+	vars map[string]string // submit variables defined before the queue statement
+	opts map[string]string // Options.Macros
+}
+
+func (m *macros) lookup(name string) (string, bool) {
+	key := strings.ToLower(name)
+	for _, scope := range []map[string]string{m.job, m.vars, m.opts} {
+		if v, ok := scope[key]; ok {
+			return v, true
+		}
+	}
+	return "", false
+}
+
+// expand replaces every $(name) in s with its value. $(name:default) expands
+// to default when name is undefined, and an undefined name without one
+// expands to "". $ENV(name) is the process environment.
+func (m *macros) expand(s string) (string, error) {
+	return syntax.Expand(s, 0, m.resolve)
+}
+
+func (m *macros) resolve(fn, arg string, depth int) (string, error) {
+	if fn == "ENV" {
+		return os.Getenv(arg), nil
+	}
+	name, def, hasDef := strings.Cut(arg, ":")
+	if v, ok := m.lookup(strings.TrimSpace(name)); ok {
+		return syntax.Expand(v, depth+1, m.resolve)
+	}
+	if hasDef {
+		return def, nil
+	}
+	return "", nil
+}
@@ -0,0 +1,288 @@
+package submit
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/PelicanPlatform/classad/classad/internal/expand"
+)
+
+// queueMode is how a queue statement gets its items.
+type queueMode int
+
+const (
+	queueCount    queueMode = iota // queue [N]
+	queueIn                        // queue [N] var in (item, ...)
+	queueFrom                      // queue [N] vars from file, or from ( rows )
+	queueMatching                  // queue [N] var matching pattern ...
+)
+
+// queueStmt is a parsed queue statement. Each item (or, for queueCount, the
+// statement once) queues count jobs.
+type queueStmt struct {
+	count  string // the job count per item as written; "" is 1
+	vars   []string
+	mode   queueMode
+	items  []string // the items of "in", or the rows of an inline "from"
+	inline bool     // "from" rows were given in parentheses
+	source string   // the file of "from", or the patterns of "matching"
+	dirs   bool     // "matching dirs" rather than files
+}
+
+// isQueue reports whether text is a queue statement.
+func isQueue(text string) bool {
+	if len(text) < len("queue") || !strings.EqualFold(text[:len("queue")], "queue") {
+		return false
+	}
+	rest := text[len("queue"):]
+	if rest != "" && rest[0] != ' ' && rest[0] != '\t' {
+		return false
+	}
+	return !strings.HasPrefix(strings.TrimSpace(rest), "=")
+}
+
+// parseQueue parses the queue statement starting at lines[i]. A parenthesized
+// item list may continue over the following lines; next is the index of the
+// statement's last line.
+func parseQueue(lines []expand.Line, i int) (q *queueStmt, next int, err error) {
+	num := lines[i].Num
+	rest := strings.TrimSpace(strings.TrimSpace(lines[i].Text)[len("queue"):])
+	q = &queueStmt{}
+
+	kw, left, right := splitQueueKeyword(rest)
+	fields := strings.FieldsFunc(left, isItemSep)
+	if len(fields) > 0 && isCount(fields[0]) {
+		q.count, fields = fields[0], fields[1:]
+	}
+	if kw == "" {
+		if len(fields) > 0 {
+			return nil, i, fmt.Errorf("submit: line %d: unexpected %q in queue statement", num, strings.Join(fields, " "))
+		}
+		return q, i, nil
+	}
+	q.vars = fields
+	if len(q.vars) == 0 {
+		q.vars = []string{"Item"}
+	}
+
+	right = strings.TrimSpace(right)
+	if kw == "matching" {
+		word, after, _ := strings.Cut(right, " ")
+		switch strings.ToLower(word) {
+		case "files":
+			right = after
+		case "dirs":
+			right, q.dirs = after, true
+		}
+		right = strings.TrimSpace(right)
+	}
+	var list string
+	inline := strings.HasPrefix(right, "(")
+	if inline {
+		list, next, err = parenList(lines, i, right)
+		if err != nil {
+			return nil, i, err
+		}
+	} else {
+		list, next = right, i
+	}
+
+	switch kw {
+	case "in":
+		q.mode = queueIn
+		q.items = strings.FieldsFunc(list, isItemSep)
+	case "from":
+		q.mode = queueFrom
+		if inline {
+			q.inline, q.items = true, rows(list)
+		} else {
+			q.source = list
+		}
+	case "matching":
+		q.mode = queueMatching
+		q.source = list
+	}
+	if q.mode != queueIn && strings.TrimSpace(list) == "" && !q.inline {
+		return nil, i, fmt.Errorf("submit: line %d: queue %s needs an argument", num, kw)
+	}
+	return q, next, nil
+}
+
+// splitQueueKeyword finds the in/from/matching keyword of a queue statement's
+// arguments, returning it lower-cased with the text before and after it.
+func splitQueueKeyword(rest string) (kw, left, right string) {
+	fields := strings.Fields(rest)
+	off := 0
+	for _, f := range fields {
+		off = strings.Index(rest[off:], f) + off
+		switch lower := strings.ToLower(f); lower {
+		case "in", "from", "matching":
+			return lower, rest[:off], rest[off+len(f):]
+		}
+		if strings.HasPrefix(f, "(") {
+			break
+		}
+		off += len(f)
+	}
+	return "", rest, ""
+}
+
+// isCount reports whether a queue statement's first argument is the job
+// count: a number or a macro that expands to one.
+func isCount(s string) bool {
+	if strings.HasPrefix(s, "$(") {
+		return true
+	}
+	_, err := strconv.Atoi(s)
+	return err == nil
+}
+
+// parenList returns the text between the parentheses that open first,
+// reading on through the following lines until the one that closes them.
+func parenList(lines []expand.Line, i int, first string) (string, int, error) {
+	body := first[1:]
+	if end := strings.LastIndexByte(body, ')'); end >= 0 {
+		return body[:end], i, nil
+	}
+	var b strings.Builder
+	b.WriteString(body)
+	for j := i + 1; j < len(lines); j++ {
+		text := strings.TrimSpace(lines[j].Text)
+		if end := strings.LastIndexByte(text, ')'); end >= 0 && end == len(text)-1 {
+			b.WriteByte('\n')
+			b.WriteString(text[:end])
+			return b.String(), j, nil
+		}
+		b.WriteByte('\n')
+		b.WriteString(text)
+	}
+	return "", i, fmt.Errorf("submit: line %d: unterminated ( in queue statement", lines[i].Num)
+}
+
+// isItemSep separates the items of an "in" list and the fields of a row.
+func isItemSep(r rune) bool {
+	return r == ',' || r == ' ' || r == '\t' || r == '\n'
+}
+
+// rows returns the non-blank, non-comment lines of text.
+func rows(text string) []string {
+	var out []string
+	for _, l := range strings.Split(text, "\n") {
+		l = strings.TrimSpace(l)
+		if l != "" && l[0] != '#' {
+			out = append(out, l)
+		}
+	}
+	return out
+}
+
+// splitRow assigns the fields of a "from" row to n variables: one field each,
+// the last variable taking the rest of the row.
+func splitRow(row string, n int) []string {
+	vals := make([]string, n)
+	for k := 0; k < n; k++ {
+		row = strings.TrimLeft(row, ", \t")
+		if k == n-1 {
+			vals[k] = strings.TrimSpace(row)
+			break
+		}
+		end := strings.IndexFunc(row, isItemSep)
+		if end < 0 {
+			vals[k], row = row, ""
+			continue
+		}
+		vals[k], row = row[:end], row[end:]
+	}
+	return vals
+}
+
+// resolveItems returns the rows of q, each as the values of q.vars, reading a
+// "from" file or globbing "matching" patterns relative to dir.
+func (q *queueStmt) resolveItems(expand func(string) (string, error), dir string, readFile func(string) ([]byte, error)) ([][]string, error) {
+	var list []string
+	switch q.mode {
+	case queueCount:
+		return [][]string{nil}, nil
+	case queueIn:
+		list = q.items
+	case queueFrom:
+		list = q.items
+		if !q.inline {
+			name, err := expand(q.source)
+			if err != nil {
+				return nil, err
+			}
+			data, err := readFile(inDir(dir, name))
+			if err != nil {
+				return nil, err
+			}
+			list = rows(string(data))
+		}
+	case queueMatching:
+		src, err := expand(q.source)
+		if err != nil {
+			return nil, err
+		}
+		if list, err = match(dir, strings.Fields(src), q.dirs); err != nil {
+			return nil, err
+		}
+	}
+
+	out := make([][]string, 0, len(list))
+	for _, item := range list {
+		item, err := expand(item)
+		if err != nil {
+			return nil, err
+		}
+		if q.mode == queueFrom {
+			out = append(out, splitRow(item, len(q.vars)))
+			continue
+		}
+		vals := make([]string, len(q.vars))
+		vals[0] = item
+		out = append(out, vals)
+	}
+	return out, nil
+}
+
+// match returns the files (or directories) matching patterns in dir, sorted
+// and without duplicates, as paths relative to dir.
+func match(dir string, patterns []string, dirs bool) ([]string, error) {
+	seen := map[string]bool{}
+	var out []string
+	for _, pat := range patterns {
+		paths, err := filepath.Glob(inDir(dir, pat))
+		if err != nil {
+			return nil, err
+		}
+		for _, p := range paths {
+			info, err := os.Stat(p)
+			if err != nil || info.IsDir() != dirs {
+				continue
+			}
+			if !filepath.IsAbs(pat) && dir != "" {
+				if rel, err := filepath.Rel(dir, p); err == nil {
+					p = rel
+				}
+			}
+			if !seen[p] {
+				seen[p] = true
+				out = append(out, p)
+			}
+		}
+	}
+	sort.Strings(out)
+	return out, nil
+}
+
+// inDir resolves a relative name against dir.
+func inDir(dir, name string) string {
+	if dir == "" || filepath.IsAbs(name) {
+		return name
+	}
+	return filepath.Join(dir, name)
+}
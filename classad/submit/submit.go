@@ -0,0 +1,117 @@
+// Package submit parses HTCondor submit description files and builds the job
+// ClassAds condor_submit would queue from them.
+//
+// A submit description is a sequence of "key = value" commands and queue
+// statements. Commands set submit variables; those condor_submit knows
+// (executable, request_memory, requirements, ...) also become job attributes,
+// and "+Attr = expr" or "MY.Attr = expr" puts an attribute into the job ad
+// verbatim. Values may reference variables as $(name), expanded when a queue
+// statement queues jobs, so a variable may be used before it is defined:
+//
+//	f, err := submit.Parse(`
+//	    executable     = /bin/sleep
+//	    arguments      = $(seconds)
+//	    request_memory = 2GB
+//	    +ProjectName   = "chemistry"
+//	    queue seconds in (10, 20, 30)
+//	`)
+//	sub, err := f.Build(&submit.Options{Owner: "alice", ClusterID: 42})
+//	for _, proc := range sub.Procs {
+//	    fmt.Println(proc.EvaluateAttrString("Arguments")) // "10", "20", "30"
+//	}
+//
+// Build returns one cluster ad holding the attributes every job shares and a
+// proc ad per job, chained to the cluster ad (see classad.ClassAd.ChainToAd),
+// holding the ones that differ -- the way a schedd stores them.
+//
+// The package covers the commands most submit files use; an unknown command
+// is a submit variable only, as it is to condor_submit. It does not check the
+// executable or the input files, rewrite paths, or add the default
+// Requirements a schedd would.
+package submit
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/PelicanPlatform/classad/classad/internal/expand"
+)
+
+// File is a parsed submit description: its commands and queue statements in
+// the order they appear. A File may be built any number of times.
+type File struct {
+	entries []entry
+}
+
+// entry is one command or one queue statement.
+type entry struct {
+	line int
+	cmd  *command
+	q    *queueStmt
+}
+
+// command is a "key = value" line. For a "+Attr" or "MY.Attr" command, attr
+// is set and key is the attribute name.
+type command struct {
+	key   string
+	value string
+	attr  bool
+}
+
+// Parse parses the submit description src.
+func Parse(src string) (*File, error) {
+	return ParseReader(strings.NewReader(src))
+}
+
+// ParseReader parses a submit description read from r.
+func ParseReader(r io.Reader) (*File, error) {
+	lines, err := expand.ReadLines(r)
+	if err != nil {
+		return nil, fmt.Errorf("submit: %w", err)
+	}
+	f := &File{}
+	for i := 0; i < len(lines); i++ {
+		l := lines[i]
+		text := strings.TrimSpace(l.Text)
+		if text == "" || text[0] == '#' {
+			continue
+		}
+		if isQueue(text) {
+			q, next, err := parseQueue(lines, i)
+			if err != nil {
+				return nil, err
+			}
+			f.entries = append(f.entries, entry{line: l.Num, q: q})
+			i = next
+			continue
+		}
+		cmd, err := parseCommand(text)
+		if err != nil {
+			return nil, fmt.Errorf("submit: line %d: %v", l.Num, err)
+		}
+		f.entries = append(f.entries, entry{line: l.Num, cmd: cmd})
+	}
+	return f, nil
+}
+
+// parseCommand parses a "key = value" line.
+func parseCommand(text string) (*command, error) {
+	eq := strings.IndexByte(text, '=')
+	if eq < 0 {
+		return nil, fmt.Errorf("expected key = value, got %q", text)
+	}
+	key := strings.TrimSpace(text[:eq])
+	value := strings.TrimSpace(text[eq+1:])
+	cmd := &command{key: key, value: value}
+	switch {
+	case strings.HasPrefix(key, "+"):
+		cmd.key, cmd.attr = strings.TrimSpace(key[1:]), true
+	case len(key) > 3 && strings.EqualFold(key[:3], "MY."):
+		cmd.key, cmd.attr = key[3:], true
+	}
+	if cmd.key == "" || strings.ContainsAny(cmd.key, " \t") {
+		return nil, fmt.Errorf("invalid key %q", key)
+	}
+	return cmd, nil
+}
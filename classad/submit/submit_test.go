@@ -0,0 +1,250 @@
+package submit
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/PelicanPlatform/classad/classad"
+)
+
+func build(t *testing.T, src string, opts *Options) *Submission {
+	t.Helper()
+	f, err := Parse(src)
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	sub, err := f.Build(opts)
+	if err != nil {
+		t.Fatalf("Build: %v", err)
+	}
+	return sub
+}
+
+func attrStrings(procs []*classad.ClassAd, name string) []string {
+	var out []string
+	for _, p := range procs {
+		s, _ := p.EvaluateAttrString(name)
+		out = append(out, s)
+	}
+	return out
+}
+
+func TestBuild(t *testing.T) {
+	sub := build(t, `
+# a comment
+universe       = vanilla
+executable     = /bin/sleep
+arguments      = "$(seconds) \
+                  --verbose"
+request_memory = 2GB
+request_disk   = 1.5 MB
+request_cpus   = 2
+requirements   = OpSys == "LINUX" && Memory >= RequestMemory
+getenv         = yes
++ProjectName   = "chemistry"
+MY.Wanted      = RequestCpus * 2
+log            = job.log
+queue seconds in (10, 20, 30)
+`, &Options{Owner: "alice", ClusterID: 42, Dir: "/home/alice"})
+
+	if len(sub.Procs) != 3 {
+		t.Fatalf("got %d procs, want 3", len(sub.Procs))
+	}
+	if got := attrStrings(sub.Procs, "Arguments"); !slices.Equal(got, []string{"10 --verbose", "20 --verbose", "30 --verbose"}) {
+		t.Errorf("Arguments = %q", got)
+	}
+	c := sub.Cluster
+	for name, want := range map[string]string{
+		"ClusterId":     "42",
+		"Cmd":           `"/bin/sleep"`,
+		"Owner":         `"alice"`,
+		"Iwd":           `"/home/alice"`,
+		"RequestMemory": "2048",
+		"RequestDisk":   "1536",
+		"RequestCpus":   "2",
+		"JobUniverse":   "5",
+		"JobStatus":     "1",
+		"GetEnv":        "true",
+		"ProjectName":   `"chemistry"`,
+		"Wanted":        "(RequestCpus * 2)",
+		"UserLog":       `"job.log"`,
+		"In":            `"/dev/null"`,
+	} {
+		if e, ok := c.Lookup(name); !ok || e.String() != want {
+			t.Errorf("cluster %s = %v, want %s", name, e, want)
+		}
+	}
+	if _, ok := c.Lookup("ProcId"); ok {
+		t.Error("ProcId in the cluster ad")
+	}
+	if _, ok := c.Lookup("Arguments"); ok {
+		t.Error("Arguments differs per job but is in the cluster ad")
+	}
+	for i, p := range sub.Procs {
+		if id, _ := p.EvaluateAttrInt("ProcId"); id != int64(i) {
+			t.Errorf("proc %d: ProcId = %d", i, id)
+		}
+		if p.GetChainedParent() != c {
+			t.Errorf("proc %d is not chained to the cluster ad", i)
+		}
+		if w, _ := p.EvaluateAttrInt("Wanted"); w != 4 {
+			t.Errorf("proc %d: Wanted = %d, want 4", i, w)
+		}
+		if p.Size() != 2 {
+			t.Errorf("proc %d = %s, want ProcId and Arguments only", i, p)
+		}
+	}
+}
+
+func TestQueueForms(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"b.dat", "a.dat", "c.txt"} {
+		if err := os.WriteFile(filepath.Join(dir, name), nil, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(dir, "rows.txt"), []byte("x 1 one\n# skipped\ny, 2, two words\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		src  string
+		attr string
+		want []string
+	}{
+		{"bare", "queue", "Arguments", []string{"0.0"}},
+		{"count", "queue 3", "Arguments", []string{"0.0", "0.1", "0.2"}},
+		{"count macro", "count = 2\nqueue $(count)", "Arguments", []string{"0.0", "0.1"}},
+		{"in", "queue 2 Item in (a, b)", "Arguments", []string{"0.0 a", "0.1 a", "1.0 b", "1.1 b"}},
+		{"in default var", "queue in (a b)", "Arguments", []string{"0.0 a", "1.0 b"}},
+		{"in multiline", "queue Item in (\n  a\n  b, c\n)", "Arguments", []string{"0.0 a", "1.0 b", "2.0 c"}},
+		{"from inline", "queue Item, Extra from (\n x 1\n y 2 3\n)", "Arguments", []string{"0.0 x 1", "1.0 y 2 3"}},
+		{"from file", "queue Item, N, Extra from rows.txt", "Arguments", []string{"0.0 x one 1", "1.0 y two words 2"}},
+		{"matching", "queue Item matching *.dat", "Arguments", []string{"0.0 a.dat", "1.0 b.dat"}},
+		{"matching files", "queue Item matching files (*.dat *.txt)", "Arguments", []string{"0.0 a.dat", "1.0 b.dat", "2.0 c.txt", "3.0 rows.txt"}},
+		{"two statements", "queue\nextra = z\nqueue", "Arguments", []string{"0.0", "0.0 z"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			src := "executable = x\narguments = $(ItemIndex).$(Step) $(Item) $(Extra) $(N:)\n" + tt.src + "\n"
+			sub := build(t, src, &Options{Dir: dir})
+			got := attrStrings(sub.Procs, tt.attr)
+			for i := range got {
+				got[i] = strings.Join(strings.Fields(got[i]), " ")
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("%s = %q, want %q", tt.attr, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMacros(t *testing.T) {
+	sub := build(t, `
+executable = $(dir)/$(Name)
+Name       = prog
+arguments  = $(Cluster).$(Process) $(undefined) $(missing:fallback) $(OUTER) $$(Memory) $ENV(SUBMIT_TEST_VAR)
+dir        = $(base)/bin
+queue
+`, &Options{ClusterID: 7, Macros: map[string]string{"BASE": "/opt", "outer": "$(name)!"}})
+	p := sub.Procs[0]
+	if cmd, _ := p.EvaluateAttrString("Cmd"); cmd != "/opt/bin/prog" {
+		t.Errorf("Cmd = %q", cmd)
+	}
+	want := "7.0  fallback prog! $$(Memory) "
+	if args, _ := p.EvaluateAttrString("Arguments"); args != want {
+		t.Errorf("Arguments = %q, want %q", args, want)
+	}
+
+	t.Setenv("SUBMIT_TEST_VAR", "env")
+	sub = build(t, "executable = x\narguments = $ENV(SUBMIT_TEST_VAR)\nqueue", nil)
+	if args, _ := sub.Procs[0].EvaluateAttrString("Arguments"); args != "env" {
+		t.Errorf("$ENV = %q", args)
+	}
+
+	// Variables are read as of their queue statement.
+	sub = build(t, "executable = x\narguments = $(v)\nv = 1\nqueue\nv = 2\nqueue", nil)
+	if got := attrStrings(sub.Procs, "Arguments"); !slices.Equal(got, []string{"1", "2"}) {
+		t.Errorf("Arguments = %q, want [1 2]", got)
+	}
+}
+
+func TestSizes(t *testing.T) {
+	tests := []struct {
+		memory, disk string
+		wantMem      string
+		wantDisk     string
+	}{
+		{"1024", "1024", "1024", "1024"},
+		{"2GB", "2GB", "2048", "2097152"},
+		{"512m", "512 M", "512", "524288"},
+		{"1536K", "100kb", "2", "100"},
+		{"1T", "1.5", "1048576", "2"},
+		{"MemoryUsage * 2", "DiskUsage + 10", "(MemoryUsage * 2)", "(DiskUsage + 10)"},
+	}
+	for _, tt := range tests {
+		sub := build(t, "executable = x\nrequest_memory = "+tt.memory+"\nrequest_disk = "+tt.disk+"\nqueue", nil)
+		if e, _ := sub.Cluster.Lookup("RequestMemory"); e.String() != tt.wantMem {
+			t.Errorf("request_memory = %s: RequestMemory = %s, want %s", tt.memory, e, tt.wantMem)
+		}
+		if e, _ := sub.Cluster.Lookup("RequestDisk"); e.String() != tt.wantDisk {
+			t.Errorf("request_disk = %s: RequestDisk = %s, want %s", tt.disk, e, tt.wantDisk)
+		}
+	}
+}
+
+func TestUniverseAndHold(t *testing.T) {
+	sub := build(t, "universe = Docker\ndocker_image = alpine\nexecutable = x\nhold = true\nqueue", nil)
+	c := sub.Cluster
+	if u, _ := c.EvaluateAttrInt("JobUniverse"); u != 5 {
+		t.Errorf("JobUniverse = %d", u)
+	}
+	if w, _ := c.EvaluateAttrBool("WantDocker"); !w {
+		t.Error("WantDocker not set for the docker universe")
+	}
+	if s, _ := c.EvaluateAttrInt("JobStatus"); s != 5 {
+		t.Errorf("JobStatus = %d, want 5 (held)", s)
+	}
+}
+
+func TestErrors(t *testing.T) {
+	parseErrors := map[string]string{
+		"executable":                "line 1: expected key = value",
+		"= x":                       "line 1: invalid key",
+		"queue x in (a\n":           "line 1: unterminated (",
+		"a = 1\nqueue 2 stray":      `line 2: unexpected "stray"`,
+		"queue Item from":           "line 1: queue from needs an argument",
+		"my key = 1":                "invalid key",
+		"executable = x\n\\\n+ = 1": "invalid key",
+	}
+	for src, want := range parseErrors {
+		if _, err := Parse(src); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("Parse(%q) error = %v, want %q", src, err, want)
+		}
+	}
+
+	buildErrors := map[string]string{
+		"executable = x":                           "no queue statement",
+		"arguments = 1\nqueue":                     "no executable",
+		"executable = x\nuniverse = bogus\nqueue":  "line 2: unknown universe",
+		"executable = x\nrequirements = (\nqueue":  "line 2: Requirements",
+		"executable = x\n+Foo = [\nqueue":          "line 2: +Foo",
+		"executable = $(a)\na = $(a)\nqueue":       "too deep",
+		"executable = x\nqueue $(n:abc)":           "invalid queue count",
+		"executable = x\nqueue Item from none.txt": "line 2:",
+	}
+	for src, want := range buildErrors {
+		f, err := Parse(src)
+		if err != nil {
+			t.Errorf("Parse(%q): %v", src, err)
+			continue
+		}
+		if _, err := f.Build(&Options{Dir: t.TempDir()}); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("Build(%q) error = %v, want %q", src, err, want)
+		}
+	}
+}
@@ -0,0 +1,653 @@
+package classad
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/PelicanPlatform/classad/ast"
+)
+
+// Absolute and relative time values, after the reference engine's
+// ABSOLUTE_TIME_VALUE and RELATIVE_TIME_VALUE. An absolute time is an instant
+// (Unix seconds) together with the UTC offset it is expressed in; a relative
+// time is a signed interval in (possibly fractional) seconds.
+//
+// Arithmetic follows the reference engine:
+//
+//	abstime - abstime = reltime     abstime +/- reltime = abstime
+//	reltime +/- reltime = reltime   reltime * number = reltime
+//	reltime / number = reltime      -reltime = reltime
+//
+// Any other combination with a time operand is error. Times compare (< <= > >=
+// == !=) only against the same kind; =?= additionally requires equal offsets.
+
+// An absolute time packs both of its fields into Value.intVal so Value does not
+// grow: the seconds occupy the high bits and the biased offset the low
+// absTimeOffsetBits. That bounds the offset to +/-18 hours (any real zone) and
+// the seconds to +/-2^46 (about two million years either side of 1970).
+const (
+	absTimeOffsetBits = 17
+	absTimeOffsetBias = 1 << (absTimeOffsetBits - 1)
+	maxAbsTimeOffset  = 18 * 3600
+	maxAbsTimeSecs    = 1<<(63-absTimeOffsetBits) - 1
+)
+
+// NewAbsTimeValue creates an absolute-time value for t, keeping t's UTC offset.
+// Sub-second precision is dropped.
+func NewAbsTimeValue(t time.Time) Value {
+	_, offset := t.Zone()
+	return newAbsTime(t.Unix(), offset)
+}
+
+// NewRelTimeValue creates a relative-time value of secs seconds. (Seconds
+// rather than a time.Duration: an interval may be longer than a Duration can
+// hold.) A NaN or infinite interval is an error value.
+func NewRelTimeValue(secs float64) Value {
+	return newRelTime(secs)
+}
+
+func newAbsTime(secs int64, offset int) Value {
+	if offset < -maxAbsTimeOffset || offset > maxAbsTimeOffset || secs < -maxAbsTimeSecs || secs > maxAbsTimeSecs {
+		return NewErrorValue()
+	}
+	return Value{valueType: AbsTimeValue, intVal: secs<<absTimeOffsetBits | int64(offset+absTimeOffsetBias)}
+}
+
+func newRelTime(secs float64) Value {
+	if math.IsNaN(secs) || math.IsInf(secs, 0) {
+		return NewErrorValue()
+	}
+	return Value{valueType: RelTimeValue, intVal: int64(math.Float64bits(secs))}
+}
+
+// absSecs and absOffset unpack an absolute time. Valid only when valueType ==
+// AbsTimeValue.
+func (v Value) absSecs() int64 { return v.intVal >> absTimeOffsetBits }
+
+func (v Value) absOffset() int {
+	return int(v.intVal&(1<<absTimeOffsetBits-1)) - absTimeOffsetBias
+}
+
+// IsAbsTime returns true if the value is an absolute time.
+func (v Value) IsAbsTime() bool {
+	return v.valueType == AbsTimeValue
+}
+
+// IsRelTime returns true if the value is a relative time.
+func (v Value) IsRelTime() bool {
+	return v.valueType == RelTimeValue
+}
+
+// AbsTimeValue returns the absolute time, in a fixed zone carrying the value's
+// UTC offset. Returns error if not an absolute time.
+func (v Value) AbsTimeValue() (time.Time, error) {
+	if v.valueType != AbsTimeValue {
+		return time.Time{}, fmt.Errorf("value is not an absolute time")
+	}
+	return time.Unix(v.absSecs(), 0).In(time.FixedZone("", v.absOffset())), nil
+}
+
+// RelTimeValue returns the relative time in seconds. Returns error if not a
+// relative time.
+func (v Value) RelTimeValue() (float64, error) {
+	if v.valueType != RelTimeValue {
+		return 0, fmt.Errorf("value is not a relative time")
+	}
+	return v.real(), nil
+}
+
+// timeValueString is the bare text of a time value (string(), strcat()):
+// "2024-05-01T12:00:00+00:00" or "1+02:00:00".
+func timeValueString(v Value) string {
+	if v.valueType == AbsTimeValue {
+		return ast.FormatAbsTime(v.absSecs(), v.absOffset())
+	}
+	return ast.FormatRelTime(v.real())
+}
+
+// timeBinary applies a binary operator with at least one time operand (the
+// caller has already propagated error). handled is false for an operator it
+// leaves to the general path (==, !=, is, isnt, logical); every other
+// combination involving a time is decided here, mostly as error.
+func timeBinary(op string, left, right Value) (result Value, handled bool) {
+	switch op {
+	case "+", "-", "*", "/":
+	case "<":
+		return timeRelational(left, right, func(c int) bool { return c < 0 }), true
+	case "<=":
+		return timeRelational(left, right, func(c int) bool { return c <= 0 }), true
+	case ">":
+		return timeRelational(left, right, func(c int) bool { return c > 0 }), true
+	case ">=":
+		return timeRelational(left, right, func(c int) bool { return c >= 0 }), true
+	default:
+		return Value{}, false
+	}
+	if left.IsUndefined() || right.IsUndefined() {
+		return NewUndefinedValue(), true
+	}
+	la, lr := left.IsAbsTime(), left.IsRelTime()
+	ra, rr := right.IsAbsTime(), right.IsRelTime()
+	switch op {
+	case "+":
+		switch {
+		case la && rr:
+			return addAbsTime(left, right.real()), true
+		case lr && ra:
+			return addAbsTime(right, left.real()), true
+		case lr && rr:
+			return newRelTime(left.real() + right.real()), true
+		}
+	case "-":
+		switch {
+		case la && ra:
+			return newRelTime(float64(left.absSecs() - right.absSecs())), true
+		case la && rr:
+			return addAbsTime(left, -right.real()), true
+		case lr && rr:
+			return newRelTime(left.real() - right.real()), true
+		}
+	case "*":
+		if lr {
+			if n, _, _, f := numericOperand(right); n {
+				return newRelTime(left.real() * f), true
+			}
+		}
+		if rr {
+			if n, _, _, f := numericOperand(left); n {
+				return newRelTime(f * right.real()), true
+			}
+		}
+	case "/":
+		if lr {
+			if n, _, _, f := numericOperand(right); n && f != 0 {
+				return newRelTime(left.real() / f), true
+			}
+		}
+	}
+	return NewErrorValue(), true
+}
+
+// addAbsTime shifts an absolute time by delta seconds (truncated to whole
+// seconds, the absolute-time resolution), keeping its offset.
+func addAbsTime(t Value, delta float64) Value {
+	return newAbsTime(t.absSecs()+floatToInt64(delta), t.absOffset())
+}
+
+// timeCompare orders two time values of the same kind, reporting ok=false when
+// they are not both absolute or both relative times.
+func timeCompare(left, right Value) (cmp int, ok bool) {
+	var l, r float64
+	switch {
+	case left.IsAbsTime() && right.IsAbsTime():
+		l, r = float64(left.absSecs()), float64(right.absSecs())
+	case left.IsRelTime() && right.IsRelTime():
+		l, r = left.real(), right.real()
+	default:
+		return 0, false
+	}
+	switch {
+	case l < r:
+		return -1, true
+	case l > r:
+		return 1, true
+	default:
+		return 0, true
+	}
+}
+
+// timeRelational orders two operands for < <= > >=: undefined propagates, and
+// anything but two times of the same kind is error.
+func timeRelational(left, right Value, keep func(int) bool) Value {
+	if left.IsUndefined() || right.IsUndefined() {
+		return NewUndefinedValue()
+	}
+	cmp, ok := timeCompare(left, right)
+	if !ok {
+		return NewErrorValue()
+	}
+	return NewBoolValue(keep(cmp))
+}
+
+func (v Value) isTime() bool {
+	return v.valueType == AbsTimeValue || v.valueType == RelTimeValue
+}
+
+// foldTimeLiteral turns an absTime("...") or relTime("...") call with a
+// constant string argument -- the form a time value is written in -- or a
+// relTime call with a constant number of seconds into the literal it denotes.
+// Any other expression, or text that does not parse, is returned unchanged (to
+// evaluate as the call it is).
+func foldTimeLiteral(expr ast.Expr) ast.Expr {
+	fc, ok := expr.(*ast.FunctionCall)
+	if !ok || len(fc.Args) != 1 {
+		return expr
+	}
+	var arg Value
+	switch lit := fc.Args[0].(type) {
+	case *ast.StringLiteral:
+		arg = NewStringValue(lit.Value)
+	case *ast.IntegerLiteral:
+		arg = NewIntValue(lit.Value)
+	case *ast.RealLiteral:
+		arg = NewRealValue(lit.Value)
+	default:
+		return expr
+	}
+	switch strings.ToLower(fc.Name) {
+	case "abstime":
+		// A number of seconds takes the local offset: leave it to evaluate.
+		if !arg.IsString() {
+			return expr
+		}
+		if v := builtinAbsTime([]Value{arg}); v.IsAbsTime() {
+			return &ast.AbsTimeLiteral{Secs: v.absSecs(), Offset: v.absOffset()}
+		}
+	case "reltime":
+		if v := builtinRelTime([]Value{arg}); v.IsRelTime() {
+			return &ast.RelTimeLiteral{Secs: v.real()}
+		}
+	}
+	return expr
+}
+
+// localOffset is the local zone's UTC offset at Unix time secs.
+func localOffset(secs int64) int {
+	_, off := time.Unix(secs, 0).Zone()
+	return off
+}
+
+// parseAbsTime parses the reference engine's absolute-time text,
+// "YYYY-MM-DD[T ]hh:mm:ss[Z|+hh:mm|-hh:mm]"; the date and time separators and
+// the offset's colon are optional ("20240501T120000+0000"). A missing offset
+// means local time.
+func parseAbsTime(s string) (secs int64, offset int, ok bool) {
+	s = strings.TrimSpace(s)
+	p := 0
+	num := func(width int) (int, bool) {
+		if p+width > len(s) {
+			return 0, false
+		}
+		n := 0
+		for _, c := range []byte(s[p : p+width]) {
+			if c < '0' || c > '9' {
+				return 0, false
+			}
+			n = n*10 + int(c-'0')
+		}
+		p += width
+		return n, true
+	}
+	sep := func(c byte) {
+		if p < len(s) && s[p] == c {
+			p++
+		}
+	}
+	var f [6]int
+	widths := [6]int{4, 2, 2, 2, 2, 2}
+	seps := [6]byte{0, '-', '-', 'T', ':', ':'}
+	for i := range f {
+		if seps[i] == 'T' {
+			if p < len(s) && (s[p] == 'T' || s[p] == 't' || s[p] == ' ') {
+				p++
+			}
+		} else if seps[i] != 0 {
+			sep(seps[i])
+		}
+		n, ok := num(widths[i])
+		if !ok {
+			return 0, 0, false
+		}
+		f[i] = n
+	}
+	if f[1] < 1 || f[1] > 12 || f[2] < 1 || f[2] > 31 || f[3] > 23 || f[4] > 59 || f[5] > 60 {
+		return 0, 0, false
+	}
+	utc := time.Date(f[0], time.Month(f[1]), f[2], f[3], f[4], f[5], 0, time.UTC)
+	// time.Date rolls a day past the month's end into the next month.
+	if y, m, d := utc.Date(); y != f[0] || int(m) != f[1] || d != f[2] {
+		return 0, 0, false
+	}
+	wall := utc.Unix()
+	switch {
+	case p == len(s):
+		// No offset: the wall-clock fields are local time.
+		t := time.Date(f[0], time.Month(f[1]), f[2], f[3], f[4], f[5], 0, time.Local)
+		return t.Unix(), localOffset(t.Unix()), true
+	case p+1 == len(s) && (s[p] == 'Z' || s[p] == 'z'):
+		return wall, 0, true
+	case s[p] == '+' || s[p] == '-':
+		neg := s[p] == '-'
+		p++
+		hh, ok := num(2)
+		if !ok {
+			return 0, 0, false
+		}
+		sep(':')
+		mm, ok := num(2)
+		if !ok || p != len(s) || hh > 18 || mm > 59 {
+			return 0, 0, false
+		}
+		offset = hh*3600 + mm*60
+		if neg {
+			offset = -offset
+		}
+		return wall - int64(offset), offset, true
+	}
+	return 0, 0, false
+}
+
+// parseRelTime parses relative-time text: the reference form
+// "[-][D+]hh:mm:ss[.fff]" (leading fields optional, so "90", "01:30" and
+// "1+00:00:00" all parse), or unit-suffixed "[-]1d2h3m4.5s" with any subset of
+// the units in that order.
+func parseRelTime(s string) (float64, bool) {
+	s = strings.TrimSpace(s)
+	neg := false
+	if strings.HasPrefix(s, "-") {
+		neg = true
+		s = s[1:]
+	} else if strings.HasPrefix(s, "+") {
+		s = s[1:]
+	}
+	if s == "" {
+		return 0, false
+	}
+	var secs float64
+	var ok bool
+	if strings.ContainsAny(s, "dDhHmMsS") {
+		secs, ok = parseRelTimeUnits(s)
+	} else {
+		secs, ok = parseRelTimeClock(s)
+	}
+	if !ok {
+		return 0, false
+	}
+	if neg {
+		secs = -secs
+	}
+	return secs, true
+}
+
+func parseRelTimeClock(s string) (float64, bool) {
+	var days int64
+	if i := strings.IndexByte(s, '+'); i >= 0 {
+		d, err := strconv.ParseInt(s[:i], 10, 64)
+		if err != nil || d < 0 {
+			return 0, false
+		}
+		days, s = d, s[i+1:]
+	}
+	fields := strings.Split(s, ":")
+	if len(fields) > 3 {
+		return 0, false
+	}
+	var secs float64
+	for i, f := range fields {
+		last := i == len(fields)-1
+		var n float64
+		if last {
+			v, err := strconv.ParseFloat(f, 64)
+			if err != nil || v < 0 || strings.ContainsAny(f, "eEnNiI") {
+				return 0, false
+			}
+			n = v
+		} else {
+			v, err := strconv.ParseUint(f, 10, 32)
+			if err != nil {
+				return 0, false
+			}
+			n = float64(v)
+		}
+		secs = secs*60 + n
+	}
+	return float64(days)*86400 + secs, true
+}
+
+func parseRelTimeUnits(s string) (float64, bool) {
+	units := []struct {
+		suffix byte
+		scale  float64
+	}{{'d', 86400}, {'h', 3600}, {'m', 60}, {'s', 1}}
+	var secs float64
+	next := 0
+	for s != "" {
+		i := strings.IndexAny(s, "dDhHmMsS")
+		if i <= 0 {
+			return 0, false
+		}
+		v, err := strconv.ParseFloat(s[:i], 64)
+		if err != nil || v < 0 || strings.ContainsAny(s[:i], "eEnNiI") {
+			return 0, false
+		}
+		u := lowerASCII(s[i])
+		for next < len(units) && units[next].suffix != u {
+			next++
+		}
+		if next == len(units) {
+			return 0, false // out of order or repeated
+		}
+		secs += v * units[next].scale
+		next++
+		s = s[i+1:]
+	}
+	return secs, true
+}
+
+// builtinAbsTime implements absTime(): the current time in the local zone;
+// absTime(s) parses the reference text form; absTime(secs [, offset]) builds
+// one from Unix seconds and an offset (default local). An absolute time
+// argument is returned as-is.
+func builtinAbsTime(args []Value) Value {
+	if len(args) == 0 {
+		return NewAbsTimeValue(time.Now())
+	}
+	for _, a := range args {
+		if a.IsError() {
+			return NewErrorValue()
+		}
+		if a.IsUndefined() {
+			return NewUndefinedValue()
+		}
+	}
+	arg := args[0]
+	if len(args) == 1 {
+		switch {
+		case arg.IsAbsTime():
+			return arg
+		case arg.IsString():
+			secs, off, ok := parseAbsTime(arg.strVal)
+			if !ok {
+				return NewErrorValue()
+			}
+			return newAbsTime(secs, off)
+		}
+	}
+	n, isInt, iv, rv := numericOperand(arg)
+	if !n || arg.IsBool() {
+		return NewErrorValue()
+	}
+	secs := iv
+	if !isInt {
+		secs = floatToInt64(rv)
+	}
+	offset := localOffset(secs)
+	if len(args) == 2 {
+		on, oInt, ov, orv := numericOperand(args[1])
+		if !on || args[1].IsBool() {
+			return NewErrorValue()
+		}
+		if !oInt {
+			ov = floatToInt64(orv)
+		}
+		if ov < -maxAbsTimeOffset || ov > maxAbsTimeOffset {
+			return NewErrorValue()
+		}
+		offset = int(ov)
+	}
+	return newAbsTime(secs, offset)
+}
+
+// builtinRelTime implements relTime(x): a number of seconds, or relative-time
+// text (see parseRelTime). A relative time argument is returned as-is.
+func builtinRelTime(args []Value) Value {
+	arg := args[0]
+	switch {
+	case arg.IsError():
+		return NewErrorValue()
+	case arg.IsUndefined():
+		return NewUndefinedValue()
+	case arg.IsRelTime():
+		return arg
+	case arg.IsString():
+		secs, ok := parseRelTime(arg.strVal)
+		if !ok {
+			return NewErrorValue()
+		}
+		return newRelTime(secs)
+	case arg.IsNumber():
+		_, _, _, f := numericOperand(arg)
+		return newRelTime(f)
+	}
+	return NewErrorValue()
+}
+
+// builtinDayTime implements dayTime(): the relative time elapsed since local
+// midnight.
+func builtinDayTime(args []Value) Value {
+	now := time.Now()
+	h, m, s := now.Clock()
+	return newRelTime(float64(h*3600 + m*60 + s))
+}
+
+// builtinSplitTime implements splitTime(t). An absolute time splits into
+// [Type = "AbsoluteTime"; Year; Month; Day; Hours; Minutes; Seconds; Offset],
+// its fields taken in its own offset; a relative time into
+// [Type = "RelativeTime"; Days; Hours; Minutes; Seconds], with a negative
+// interval negating every field and fractional seconds kept in Seconds.
+func builtinSplitTime(args []Value) Value {
+	arg := args[0]
+	switch {
+	case arg.IsError():
+		return NewErrorValue()
+	case arg.IsUndefined():
+		return NewUndefinedValue()
+	case arg.IsAbsTime():
+		t, _ := arg.AbsTimeValue()
+		ad := New()
+		ad.InsertAttrString("Type", "AbsoluteTime")
+		ad.InsertAttr("Year", int64(t.Year()))
+		ad.InsertAttr("Month", int64(t.Month()))
+		ad.InsertAttr("Day", int64(t.Day()))
+		ad.InsertAttr("Hours", int64(t.Hour()))
+		ad.InsertAttr("Minutes", int64(t.Minute()))
+		ad.InsertAttr("Seconds", int64(t.Second()))
+		ad.InsertAttr("Offset", int64(arg.absOffset()))
+		return NewClassAdValue(ad)
+	case arg.IsRelTime():
+		d, h, m, s := splitRelTime(arg.real())
+		ad := New()
+		ad.InsertAttrString("Type", "RelativeTime")
+		ad.InsertAttr("Days", d)
+		ad.InsertAttr("Hours", h)
+		ad.InsertAttr("Minutes", m)
+		if s == math.Trunc(s) {
+			ad.InsertAttr("Seconds", int64(s))
+		} else {
+			ad.InsertAttrFloat("Seconds", s)
+		}
+		return NewClassAdValue(ad)
+	}
+	return NewErrorValue()
+}
+
+// splitRelTime breaks an interval into days, hours, minutes and seconds, every
+// field carrying the interval's sign.
+func splitRelTime(secs float64) (days, hrs, mins int64, s float64) {
+	sign := int64(1)
+	if secs < 0 {
+		sign, secs = -1, -secs
+	}
+	whole := int64(secs)
+	frac := secs - float64(whole)
+	days = whole / 86400
+	hrs = whole % 86400 / 3600
+	mins = whole % 3600 / 60
+	s = float64(whole%60) + frac
+	return sign * days, sign * hrs, sign * mins, float64(sign) * s
+}
+
+// builtinTimeField implements the getYear()-style accessors. An absolute time
+// answers every field (in its own offset; getMonth is 1-12, getDayOfWeek 0-6
+// from Sunday, getDayOfYear 0-365); a relative time answers getDays, getHours,
+// getMinutes and getSeconds from its splitTime breakdown. Anything else is
+// error.
+func builtinTimeField(field string, args []Value) Value {
+	arg := args[0]
+	switch {
+	case arg.IsError():
+		return NewErrorValue()
+	case arg.IsUndefined():
+		return NewUndefinedValue()
+	case arg.IsAbsTime():
+		t, _ := arg.AbsTimeValue()
+		switch field {
+		case "getyear":
+			return NewIntValue(int64(t.Year()))
+		case "getmonth":
+			return NewIntValue(int64(t.Month()))
+		case "getdayofyear":
+			return NewIntValue(int64(t.YearDay() - 1))
+		case "getdayofmonth":
+			return NewIntValue(int64(t.Day()))
+		case "getdayofweek":
+			return NewIntValue(int64(t.Weekday()))
+		case "gethours":
+			return NewIntValue(int64(t.Hour()))
+		case "getminutes":
+			return NewIntValue(int64(t.Minute()))
+		case "getseconds":
+			return NewIntValue(int64(t.Second()))
+		}
+	case arg.IsRelTime():
+		d, h, m, s := splitRelTime(arg.real())
+		switch field {
+		case "getdays":
+			return NewIntValue(d)
+		case "gethours":
+			return NewIntValue(h)
+		case "getminutes":
+			return NewIntValue(m)
+		case "getseconds":
+			if s == math.Trunc(s) {
+				return NewIntValue(int64(s))
+			}
+			return NewRealValue(s)
+		}
+	}
+	return NewErrorValue()
+}
+
+// builtinInUnits implements inDays()/inHours()/inMinutes()/inSeconds(): a time
+// value (an absolute time counts from the epoch) or a number of seconds,
+// expressed as a real in the given unit.
+func builtinInUnits(scale float64, args []Value) Value {
+	arg := args[0]
+	switch {
+	case arg.IsError():
+		return NewErrorValue()
+	case arg.IsUndefined():
+		return NewUndefinedValue()
+	case arg.IsAbsTime():
+		return NewRealValue(float64(arg.absSecs()) / scale)
+	case arg.IsRelTime():
+		return NewRealValue(arg.real() / scale)
+	case arg.IsNumber():
+		_, _, _, f := numericOperand(arg)
+		return NewRealValue(f / scale)
+	}
+	return NewErrorValue()
+}
@@ -0,0 +1,175 @@
+package classad
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+func evalTimeExpr(t *testing.T, src string) Value {
+	t.Helper()
+	expr, err := ParseExpr(src)
+	if err != nil {
+		t.Fatalf("ParseExpr(%q): %v", src, err)
+	}
+	return expr.Eval(New())
+}
+
+func TestTimeValueExpressions(t *testing.T) {
+	tests := []struct {
+		expr string
+		want string // Value.String() of the result
+	}{
+		{`absTime("2024-05-01T12:00:00+00:00")`, `absTime("2024-05-01T12:00:00+00:00")`},
+		{`absTime("2024-05-01T12:00:00Z")`, `absTime("2024-05-01T12:00:00+00:00")`},
+		{`absTime("20240501T140000+0200")`, `absTime("2024-05-01T14:00:00+02:00")`},
+		{`absTime(0, 0)`, `absTime("1970-01-01T00:00:00+00:00")`},
+		{`absTime(3600, -3600)`, `absTime("1970-01-01T00:00:00-01:00")`},
+		{`absTime("not a time")`, `error`},
+		{`absTime("2024-02-30T00:00:00Z")`, `error`},
+		{`absTime("2023-02-29T00:00:00Z")`, `error`},
+		{`absTime("2024-02-29T00:00:00Z")`, `absTime("2024-02-29T00:00:00+00:00")`},
+		{`absTime(0, 100000)`, `error`},
+		{`absTime(undefined)`, `undefined`},
+		{`relTime(90)`, `relTime("00:01:30")`},
+		{`relTime("1+02:03:04")`, `relTime("1+02:03:04")`},
+		{`relTime("01:30")`, `relTime("00:01:30")`},
+		{`relTime("1d2h")`, `relTime("1+02:00:00")`},
+		{`relTime(-1.5)`, `relTime("-00:00:01.5")`},
+		{`relTime("x")`, `error`},
+
+		// Arithmetic.
+		{`absTime("2024-05-02T00:00:00Z") - absTime("2024-05-01T00:00:00Z")`, `relTime("1+00:00:00")`},
+		{`absTime("2024-05-01T00:00:00Z") + relTime(3600)`, `absTime("2024-05-01T01:00:00+00:00")`},
+		{`relTime(3600) + absTime("2024-05-01T00:00:00Z")`, `absTime("2024-05-01T01:00:00+00:00")`},
+		{`absTime("2024-05-01T00:00:00Z") - relTime(60)`, `absTime("2024-04-30T23:59:00+00:00")`},
+		{`relTime(60) + relTime(30)`, `relTime("00:01:30")`},
+		{`relTime(60) * 2`, `relTime("00:02:00")`},
+		{`2 * relTime(60)`, `relTime("00:02:00")`},
+		{`relTime(60) / 4`, `relTime("00:00:15")`},
+		{`-relTime(60)`, `relTime("-00:01:00")`},
+		{`absTime(0, 0) + absTime(0, 0)`, `error`},
+		{`relTime(60) - absTime(0, 0)`, `error`},
+		{`absTime(0, 0) + 1`, `error`},
+		{`relTime(60) / 0`, `error`},
+		{`relTime(60) % 7`, `error`},
+		{`relTime(60) + undefined`, `undefined`},
+
+		// Comparison.
+		{`absTime("2024-05-01T12:00:00Z") < absTime("2024-05-01T13:00:00Z")`, `true`},
+		{`absTime("2024-05-01T12:00:00Z") == absTime("2024-05-01T14:00:00+02:00")`, `true`},
+		{`absTime("2024-05-01T12:00:00Z") =?= absTime("2024-05-01T14:00:00+02:00")`, `false`},
+		{`absTime("2024-05-01T12:00:00Z") =?= absTime("2024-05-01T12:00:00Z")`, `true`},
+		{`relTime(60) >= relTime(60)`, `true`},
+		{`relTime(60) != relTime(61)`, `true`},
+		{`relTime(60) < 61`, `error`},
+		{`relTime(60) == 60`, `error`},
+		{`relTime(60) =?= 60`, `false`},
+
+		// Accessors and conversions.
+		{`getYear(absTime("2024-05-01T12:34:56+02:00"))`, `2024`},
+		{`getMonth(absTime("2024-05-01T12:34:56+02:00"))`, `5`},
+		{`getDayOfMonth(absTime("2024-05-01T12:34:56+02:00"))`, `1`},
+		{`getDayOfWeek(absTime("2024-05-01T12:34:56+02:00"))`, `3`},
+		{`getDayOfYear(absTime("2024-02-01T00:00:00Z"))`, `31`},
+		{`getHours(absTime("2024-05-01T12:34:56+02:00"))`, `12`},
+		{`getMinutes(absTime("2024-05-01T12:34:56+02:00"))`, `34`},
+		{`getSeconds(absTime("2024-05-01T12:34:56+02:00"))`, `56`},
+		{`getDays(relTime("2+03:04:05"))`, `2`},
+		{`getHours(relTime("2+03:04:05"))`, `3`},
+		{`getDays(absTime(0, 0))`, `error`},
+		{`getYear(relTime(1))`, `error`},
+		{`inHours(relTime(5400))`, `1.5`},
+		{`inDays(relTime("2+00:00:00"))`, `2`},
+		{`int(absTime("2024-05-01T00:00:00Z"))`, `1714521600`},
+		{`int(relTime(-1.5))`, `-1`},
+		{`real(relTime(1.5))`, `1.5`},
+		{`string(absTime("2024-05-01T00:00:00Z"))`, `"2024-05-01T00:00:00+00:00"`},
+		{`string(relTime(61))`, `"00:01:01"`},
+		{`string({relTime(61)})`, `"{ relTime(61) }"`},
+		{`formatTime(absTime("2024-05-01T12:00:00+02:00"), "%H:%M")`, `"12:00"`},
+		{`splitTime(absTime("2024-05-01T12:34:56+02:00")).Offset`, `7200`},
+		{`splitTime(relTime("1+00:00:30.5")).Seconds`, `30.5`},
+		{`splitTime(relTime(-90)).Minutes`, `-1`},
+		{`splitTime(5)`, `error`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			if got := evalTimeExpr(t, tt.expr).String(); got != tt.want {
+				t.Errorf("%s = %s, want %s", tt.expr, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTimeValueGoAPI(t *testing.T) {
+	at := time.Date(2024, 5, 1, 12, 0, 0, 0, time.FixedZone("", -5*3600))
+	v := NewAbsTimeValue(at)
+	if !v.IsAbsTime() || v.Type() != AbsTimeValue {
+		t.Fatalf("NewAbsTimeValue type = %v", v.Type())
+	}
+	got, err := v.AbsTimeValue()
+	if err != nil || !got.Equal(at) {
+		t.Fatalf("AbsTimeValue() = %v, %v; want %v", got, err, at)
+	}
+	if _, off := got.Zone(); off != -5*3600 {
+		t.Errorf("offset = %d, want %d", off, -5*3600)
+	}
+	// Negative seconds survive the packed representation.
+	before := time.Date(1900, 1, 1, 0, 0, 0, 0, time.UTC)
+	if got, _ := NewAbsTimeValue(before).AbsTimeValue(); !got.Equal(before) {
+		t.Errorf("pre-epoch time round-trip = %v, want %v", got, before)
+	}
+
+	r := NewRelTimeValue(1e10) // longer than a time.Duration holds
+	if secs, err := r.RelTimeValue(); err != nil || secs != 1e10 {
+		t.Errorf("RelTimeValue() = %v, %v", secs, err)
+	}
+	if _, err := r.AbsTimeValue(); err == nil {
+		t.Error("AbsTimeValue() on a relative time succeeded")
+	}
+}
+
+func TestTimeValueFlattenAndJSON(t *testing.T) {
+	ad, err := Parse(`[Start = absTime("2024-05-01T00:00:00+01:00"); Limit = relTime(3600); End = Start + Limit]`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	end, _ := ParseExpr("End")
+	limit, _ := ParseExpr("Limit")
+	flat := ad.Flatten(end)
+	if got, want := flat.String(), `absTime("2024-05-01T01:00:00+01:00")`; got != want {
+		t.Errorf("Flatten(End) = %s, want %s", got, want)
+	}
+
+	out := New()
+	out.InsertExpr("When", flat)
+	out.InsertExpr("Span", ad.Flatten(limit))
+	data, err := json.Marshal(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), `"\/Expr(absTime(\"2024-05-01T01:00:00+01:00\"))\/"`) {
+		t.Errorf("MarshalJSON = %s", data)
+	}
+	var back ClassAd
+	if err := json.Unmarshal(data, &back); err != nil {
+		t.Fatal(err)
+	}
+	if !out.Equal(&back) {
+		t.Errorf("JSON round trip: got %s, want %s", back.String(), out.String())
+	}
+	// A relTime of a number of seconds decodes as the literal too.
+	var grace ClassAd
+	if err := json.Unmarshal([]byte(`{"Grace": "\/Expr(relTime(90))\/"}`), &grace); err != nil {
+		t.Fatal(err)
+	}
+	if g, _ := grace.Lookup("Grace"); g.String() != `relTime("00:01:30")` {
+		t.Errorf("decoded Grace = %s, want the reltime literal", g)
+	}
+	when, _ := back.Lookup("When")
+	if got := when.Eval(&back); !got.IsAbsTime() {
+		t.Errorf("decoded When evaluates to %v", got)
+	}
+}
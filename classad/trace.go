@@ -0,0 +1,292 @@
+package classad
+
+import (
+	"strings"
+
+	"github.com/PelicanPlatform/classad/ast"
+)
+
+// RefScope says where an attribute reference found its attribute during a
+// traced evaluation.
+type RefScope int
+
+const (
+	// RefNone marks a trace node that is not an attribute reference.
+	RefNone RefScope = iota
+	// RefUnresolved: no scope defines the attribute, so the reference is undefined.
+	RefUnresolved
+	// RefMy: the ad being evaluated (a MY. reference, or an unscoped one found there).
+	RefMy
+	// RefTarget: the TARGET ad (a TARGET. reference, or an unscoped one not
+	// found in MY and its enclosing ads -- the old-ClassAd matchmaking fallthrough).
+	RefTarget
+	// RefParent: a PARENT. reference.
+	RefParent
+	// RefChainedParent: a reference found in an enclosing ad up the parent
+	// scope chain, or in an ad chained to with ChainToAd, rather than in the ad
+	// itself.
+	RefChainedParent
+	// RefBuiltin: an undefined CurrentTime, which evaluates to the current time.
+	RefBuiltin
+)
+
+func (s RefScope) String() string {
+	switch s {
+	case RefUnresolved:
+		return "unresolved"
+	case RefMy:
+		return "MY"
+	case RefTarget:
+		return "TARGET"
+	case RefParent:
+		return "PARENT"
+	case RefChainedParent:
+		return "chained parent"
+	case RefBuiltin:
+		return "builtin"
+	default:
+		return ""
+	}
+}
+
+// TraceNode is one evaluated sub-expression of a Trace.
+type TraceNode struct {
+	// Expr is the sub-expression. Parentheses are not traced separately.
+	Expr *Expr
+	// Value is what the sub-expression evaluated to. A node cut short by a
+	// cyclic reference has an error value.
+	Value Value
+	// Scope and Ad say where an attribute reference resolved (Scope is RefNone
+	// for any other node, and Ad is nil unless an ad defines the attribute).
+	Scope RefScope
+	Ad    *ClassAd
+	// Children are the sub-expressions evaluated to produce Value, in
+	// evaluation order. An attribute reference that resolved has one child: the
+	// attribute's own expression, evaluated in the ad that defines it. Operands
+	// skipped by short-circuiting or an untaken branch do not appear.
+	Children []*TraceNode
+}
+
+// Trace is the record of one evaluation: every sub-expression evaluated, with
+// its value, as a tree rooted at the whole expression.
+type Trace struct {
+	Root *TraceNode
+	// FirstUndefined and FirstError are the first nodes, in evaluation order,
+	// to produce undefined or error -- where the value first appeared, before
+	// any operator propagated it. Nil if no node did.
+	FirstUndefined *TraceNode
+	FirstError     *TraceNode
+}
+
+// Value returns the value of the traced expression.
+func (t *Trace) Value() Value {
+	if t == nil || t.Root == nil {
+		return NewUndefinedValue()
+	}
+	return t.Root.Value
+}
+
+// EvalTrace evaluates the expression like EvalWithContext and records how it
+// got its value: each sub-expression's value, the scope every attribute
+// reference resolved in, and where undefined or error first appeared. It is
+// meant for explaining a surprising result ("why is Requirements undefined?"),
+// not for the matchmaking path; tracing allocates a node per evaluation step.
+//
+// Example:
+//
+//	expr, _ := classad.ParseExpr("TARGET.Memory >= RequestMemory && Cpus > 0")
+//	fmt.Print(expr.EvalTrace(jobAd, machineAd))
+func (e *Expr) EvalTrace(scope, target *ClassAd) *Trace {
+	if e == nil || e.expr == nil {
+		return &Trace{Root: &TraceNode{Expr: e, Value: NewUndefinedValue()}}
+	}
+	if scope != nil && target != nil {
+		oldTarget := scope.target
+		scope.target = target
+		defer func() { scope.target = oldTarget }()
+	}
+
+	t := &tracer{}
+	evaluator := NewEvaluator(scope)
+	evaluator.trace = t
+	func() {
+		var result Value
+		defer recoverCyclic(&result)
+		evaluator.Evaluate(e.expr)
+	}()
+	return &Trace{Root: t.root, FirstUndefined: t.firstUndefined, FirstError: t.firstError}
+}
+
+// TraceAttrLeft traces the evaluation of an attribute of the left ClassAd, with
+// the right ClassAd as its TARGET (see Expr.EvalTrace). The root of the trace is
+// the reference MY.name.
+func (m *MatchClassAd) TraceAttrLeft(name string) *Trace {
+	return traceAttr(m.left, name)
+}
+
+// TraceAttrRight traces the evaluation of an attribute of the right ClassAd.
+func (m *MatchClassAd) TraceAttrRight(name string) *Trace {
+	return traceAttr(m.right, name)
+}
+
+func traceAttr(ad *ClassAd, name string) *Trace {
+	ref := &Expr{expr: ast.NewAttributeReference(name, ast.MyScope)}
+	return ref.EvalTrace(ad, nil)
+}
+
+// String renders the trace as an indented report, one sub-expression per line
+// with its value, the resolving scope of each attribute reference, and markers
+// on the nodes where undefined and error first appeared:
+//
+//	TARGET.Memory >= RequestMemory && Cpus > 0 => undefined
+//	  TARGET.Memory >= RequestMemory => undefined
+//	    TARGET.Memory => 2048  [TARGET]
+//	    RequestMemory => undefined  [unresolved]  <- first undefined
+//	  Cpus > 0 => true
+//	    Cpus => 4  [MY]
+//	    0 => 0
+//
+// The literal value of a resolved attribute is not repeated beneath its
+// reference.
+func (t *Trace) String() string {
+	var b strings.Builder
+	if t != nil && t.Root != nil {
+		t.writeNode(&b, t.Root, 0)
+	}
+	return b.String()
+}
+
+func (t *Trace) writeNode(b *strings.Builder, n *TraceNode, depth int) {
+	for i := 0; i < depth; i++ {
+		b.WriteString("  ")
+	}
+	if n.Expr != nil {
+		b.WriteString(unparseExprString(n.Expr.expr))
+	} else {
+		b.WriteString("undefined")
+	}
+	b.WriteString(" => ")
+	b.WriteString(n.Value.String())
+	if n.Scope != RefNone {
+		b.WriteString("  [")
+		b.WriteString(n.Scope.String())
+		b.WriteString("]")
+	}
+	if n == t.FirstUndefined {
+		b.WriteString("  <- first undefined")
+	}
+	if n == t.FirstError {
+		b.WriteString("  <- first error")
+	}
+	b.WriteByte('\n')
+	if n.Scope != RefNone && len(n.Children) == 1 && n.Children[0] != t.FirstUndefined &&
+		n.Children[0] != t.FirstError && isLiteralExpr(n.Children[0].Expr.expr) {
+		return
+	}
+	for _, c := range n.Children {
+		t.writeNode(b, c, depth+1)
+	}
+}
+
+// tracer builds a Trace as an evaluator (and the child evaluators it creates)
+// evaluates; Evaluate hands it every node when one is installed.
+type tracer struct {
+	stack          []*TraceNode
+	root           *TraceNode
+	firstUndefined *TraceNode
+	firstError     *TraceNode
+}
+
+// eval evaluates expr with e, recording a node for it under the node being
+// evaluated. A cyclic-reference panic unwinds through here: the deferred
+// cleanup keeps the stack balanced and records the cut-short node as error,
+// so a recover further up (a lazy list element, or EvalTrace itself) leaves a
+// consistent tree.
+func (t *tracer) eval(e *Evaluator, expr ast.Expr) (v Value) {
+	if _, ok := expr.(*ast.ParenExpr); ok {
+		return e.evalNode(expr)
+	}
+	n := &TraceNode{Expr: &Expr{expr: expr}}
+	if ref, ok := expr.(*ast.AttributeReference); ok {
+		n.Scope, n.Ad = e.locateRef(ref)
+	}
+	if len(t.stack) == 0 {
+		if t.root == nil {
+			t.root = n
+		}
+	} else {
+		parent := t.stack[len(t.stack)-1]
+		parent.Children = append(parent.Children, n)
+	}
+	t.stack = append(t.stack, n)
+	done := false
+	defer func() {
+		if !done {
+			v = NewErrorValue()
+		}
+		n.Value = v
+		t.stack = t.stack[:len(t.stack)-1]
+		if v.IsUndefined() && t.firstUndefined == nil {
+			t.firstUndefined = n
+		}
+		if v.IsError() && t.firstError == nil {
+			t.firstError = n
+		}
+	}()
+	v = e.evalNode(expr)
+	done = true
+	return v
+}
+
+// locateRef reports where ref resolves from e's current scope, following the
+// same search as resolveAttributeReference but without evaluating anything.
+func (e *Evaluator) locateRef(ref *ast.AttributeReference) (RefScope, *ClassAd) {
+	norm := ref.NormalizedName()
+	definer := func(ad *ClassAd) *ClassAd {
+		if ad == nil {
+			return nil
+		}
+		return ad.definer(norm)
+	}
+	switch ref.Scope {
+	case ast.MyScope:
+		if ad := definer(e.classad); ad != nil {
+			if ad == e.classad {
+				return RefMy, ad
+			}
+			return RefChainedParent, ad
+		}
+	case ast.TargetScope:
+		if e.classad != nil {
+			if ad := definer(e.classad.target); ad != nil {
+				return RefTarget, ad
+			}
+		}
+	case ast.ParentScope:
+		if e.classad != nil {
+			if ad := definer(e.classad.parent); ad != nil {
+				return RefParent, ad
+			}
+		}
+	default:
+		for scope := e.classad; scope != nil; scope = scope.parent {
+			if ad := definer(scope); ad != nil {
+				if ad == e.classad {
+					return RefMy, ad
+				}
+				return RefChainedParent, ad
+			}
+		}
+		if e.classad != nil {
+			for scope := e.classad.target; scope != nil; scope = scope.parent {
+				if ad := definer(scope); ad != nil {
+					return RefTarget, ad
+				}
+			}
+		}
+	}
+	if isCurrentTimeRef(ref) {
+		return RefBuiltin, nil
+	}
+	return RefUnresolved, nil
+}
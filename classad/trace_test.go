@@ -0,0 +1,129 @@
+package classad
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestEvalTrace_Report(t *testing.T) {
+	job, _ := Parse(`[RequestMemory = DiskMB * 2; Cpus = 4]`)
+	machine, _ := Parse(`[Memory = 2048]`)
+	expr, _ := ParseExpr("TARGET.Memory >= RequestMemory && (Cpus > 0)")
+
+	trace := expr.EvalTrace(job, machine)
+	if !trace.Value().IsUndefined() {
+		t.Fatalf("Value = %v, want undefined", trace.Value())
+	}
+	want := `TARGET.Memory >= RequestMemory && (Cpus > 0) => undefined
+  TARGET.Memory >= RequestMemory => undefined
+    TARGET.Memory => 2048  [TARGET]
+    RequestMemory => undefined  [MY]
+      DiskMB * 2 => undefined
+        DiskMB => undefined  [unresolved]  <- first undefined
+        2 => 2
+  Cpus > 0 => true
+    Cpus => 4  [MY]
+    0 => 0
+`
+	if got := trace.String(); got != want {
+		t.Errorf("report:\n%s\nwant:\n%s", got, want)
+	}
+	if trace.FirstError != nil {
+		t.Errorf("FirstError = %v, want nil", trace.FirstError.Expr)
+	}
+	if job.GetTarget() != nil {
+		t.Error("EvalTrace left the target set")
+	}
+}
+
+func TestEvalTrace_Scopes(t *testing.T) {
+	outer, _ := Parse(`[Limit = 10; Inner = [X = Limit; Y = PARENT.Limit]]`)
+	other, _ := Parse(`[Owner = "alice"]`)
+	outer.SetTarget(other)
+	defer outer.SetTarget(nil)
+
+	expr, _ := ParseExpr("Inner.X + Inner.Y")
+	trace := expr.EvalTrace(outer, nil)
+	if v, _ := trace.Value().IntValue(); v != 20 {
+		t.Fatalf("Value = %v, want 20", trace.Value())
+	}
+	scopes := map[string]RefScope{}
+	var walk func(n *TraceNode)
+	walk = func(n *TraceNode) {
+		if n.Scope != RefNone {
+			scopes[n.Expr.String()] = n.Scope
+		}
+		for _, c := range n.Children {
+			walk(c)
+		}
+	}
+	walk(trace.Root)
+	if scopes["Limit"] != RefChainedParent || scopes["PARENT.Limit"] != RefParent || scopes["Inner"] != RefMy {
+		t.Errorf("scopes = %v", scopes)
+	}
+
+	// An unscoped name missing from MY falls through to TARGET.
+	expr, _ = ParseExpr(`Owner == "alice"`)
+	trace = expr.EvalTrace(outer, nil)
+	if ref := trace.Root.Children[0]; ref.Scope != RefTarget || ref.Ad != other {
+		t.Errorf("Owner resolved in %v (%p), want TARGET (%p)", ref.Scope, ref.Ad, other)
+	}
+}
+
+func TestEvalTrace_MatchesEval(t *testing.T) {
+	ad, _ := Parse(`[A = 1; B = "x"; L = {1, B, undefined}; C = A + B; D = D + 1]`)
+	for _, src := range []string{
+		"A + 1",
+		"C",
+		"D",
+		"size(L) == 3 && L[1] == \"x\"",
+		"false && D",
+		"isUndefined(Missing) ? A : error",
+		"CurrentTime > 0",
+	} {
+		expr, err := ParseExpr(src)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := expr.EvalTrace(ad, nil)
+		want := expr.Eval(ad)
+		if got.Value().String() != want.String() {
+			t.Errorf("%s: trace value %v, Eval %v", src, got.Value(), want)
+		}
+	}
+
+	// A cyclic reference is an error where the cycle closes.
+	expr, _ := ParseExpr("D")
+	trace := expr.EvalTrace(ad, nil)
+	if trace.FirstError == nil || !strings.Contains(trace.String(), "<- first error") {
+		t.Errorf("cycle not marked:\n%s", trace)
+	}
+	if trace.FirstUndefined != nil {
+		t.Errorf("FirstUndefined = %v, want nil", trace.FirstUndefined.Expr)
+	}
+
+	expr, _ = ParseExpr("CurrentTime")
+	if trace := expr.EvalTrace(ad, nil); trace.Root.Scope != RefBuiltin {
+		t.Errorf("CurrentTime resolved in %v, want builtin", trace.Root.Scope)
+	}
+}
+
+func TestMatchClassAd_TraceAttr(t *testing.T) {
+	job, _ := Parse(`[Requirements = TARGET.Arch == "X86_64" && TARGET.Disk > 100]`)
+	machine, _ := Parse(`[Arch = "X86_64"; Requirements = true]`)
+	m := NewMatchClassAd(job, machine)
+
+	trace := m.TraceAttrLeft("Requirements")
+	if !trace.Value().IsUndefined() {
+		t.Fatalf("Value = %v, want undefined", trace.Value())
+	}
+	if n := trace.FirstUndefined; n == nil || n.Expr.String() != "TARGET.Disk" || n.Scope != RefUnresolved {
+		t.Errorf("FirstUndefined = %+v, want TARGET.Disk unresolved", n)
+	}
+	if v, _ := m.TraceAttrRight("Requirements").Value().BoolValue(); !v {
+		t.Error("right Requirements should trace to true")
+	}
+	if trace := m.TraceAttrLeft("Rank"); trace.Root.Scope != RefUnresolved {
+		t.Errorf("missing attribute traced as %v", trace.Root.Scope)
+	}
+}
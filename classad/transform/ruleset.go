@@ -0,0 +1,80 @@
+package transform
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/PelicanPlatform/classad/classad"
+	"github.com/PelicanPlatform/classad/classad/config"
+)
+
+// Ruleset is an ordered list of transforms, applied one after another as a
+// schedd applies JOB_TRANSFORM_NAMES: each sees the ad the ones before it
+// left.
+type Ruleset []*Transform
+
+// Apply applies each transform of rs whose REQUIREMENTS ad satisfies, in
+// order, returning how many applied. On error, ad keeps the changes of the
+// transforms before the failing one.
+func (rs Ruleset) Apply(ad *classad.ClassAd) (int, error) {
+	n := 0
+	for _, t := range rs {
+		ok, err := t.Apply(ad)
+		if err != nil {
+			return n, err
+		}
+		if ok {
+			n++
+		}
+	}
+	return n, nil
+}
+
+// Hook returns rs as a write hook for db.Config.OnNewClassAd: it transforms
+// each ad stored with db.Txn.NewClassAd before the write is buffered, and
+// fails the transaction if a transform fails.
+func (rs Ruleset) Hook() func(key string, ad *classad.ClassAd) error {
+	return func(_ string, ad *classad.ClassAd) error {
+		_, err := rs.Apply(ad)
+		return err
+	}
+}
+
+// FromConfig reads the transforms an HTCondor configuration defines under
+// prefix ("JOB_TRANSFORM" or "SUBMIT_TRANSFORM"): those <prefix>_NAMES lists,
+// in that order, each from <prefix>_<name>. A transform without a NAME takes
+// its configuration name. Macros a transform does not define expand to the
+// configuration's values. A configuration without <prefix>_NAMES has none.
+func FromConfig(cfg *config.Config, prefix string) (Ruleset, error) {
+	names, err := cfg.Value(prefix + "_NAMES")
+	if errors.Is(err, config.ErrUndefined) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	outer := func(name string) (string, bool) {
+		v, err := cfg.Value(name)
+		return v, err == nil
+	}
+	var rs Ruleset
+	for _, name := range strings.FieldsFunc(names, func(r rune) bool {
+		return r == ',' || r == ' ' || r == '\t' || r == '\n'
+	}) {
+		text, ok := cfg.Raw(prefix + "_" + name)
+		if !ok {
+			return nil, fmt.Errorf("transform: %s_%s is not defined", prefix, name)
+		}
+		t, err := Parse(text)
+		if err != nil {
+			return nil, fmt.Errorf("%s_%s: %w", prefix, name, err)
+		}
+		if t.name == "" {
+			t.name = name
+		}
+		t.outer = outer
+		rs = append(rs, t)
+	}
+	return rs, nil
+}
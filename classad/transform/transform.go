@@ -0,0 +1,459 @@
+// Package transform applies HTCondor job transforms (JOB_TRANSFORM_* and
+// SUBMIT_TRANSFORM_*) to ClassAds, so ads can be normalized the way a schedd
+// rewrites jobs as they are submitted.
+//
+// A transform is a sequence of statements, one per line (a line ending in a
+// backslash continues on the next):
+//
+//	NAME        name                    names the transform (for errors)
+//	REQUIREMENTS expr                   apply only to ads for which expr is true
+//	SET         Attr expr               bind Attr to expr
+//	DEFAULT     Attr expr               bind Attr to expr unless Attr is defined
+//	EVALSET     Attr expr               bind Attr to the value of expr in the ad
+//	EVALMACRO   name expr               set macro name to the value of expr
+//	COPY        Attr NewAttr            copy Attr's expression to NewAttr
+//	RENAME      Attr NewAttr            move Attr to NewAttr
+//	DELETE      Attr                    delete Attr
+//	name = value                        define macro name
+//
+// COPY, RENAME and DELETE also take a /regex/ in place of the attribute name,
+// acting on every attribute whose name it matches (case-insensitively); the
+// new name of COPY and RENAME may then refer to the match as \0 and its
+// groups as \1 to \9:
+//
+//	RENAME /^(.*)_Old$/ \1
+//
+// Statement arguments expand macros before they are used: $(name) is a macro
+// of the transform (or an EVALMACRO result), $(name:default) one with a
+// default, and $(MY.Attr) the ad's Attr -- a string's value, or any other
+// expression's text. Statements apply in order, so an EVALSET sees the
+// attributes a SET before it bound.
+//
+// The older ClassAd-syntax transforms ([ set_Attr = ...; ]) and the TRANSFORM
+// loop statement are not supported.
+package transform
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/PelicanPlatform/classad/classad"
+	"github.com/PelicanPlatform/classad/classad/internal/expand"
+)
+
+// Transform is one parsed transform. A Transform is safe for concurrent use.
+type Transform struct {
+	name  string
+	req   *stmt // REQUIREMENTS, nil if none
+	stmts []*stmt
+	// outer resolves the macros the transform does not define (see
+	// FromConfig); nil if none.
+	outer func(name string) (string, bool)
+}
+
+// op is a transform statement's keyword.
+type op int
+
+const (
+	opSet op = iota
+	opDefault
+	opEvalSet
+	opEvalMacro
+	opCopy
+	opRename
+	opDelete
+	opMacro
+	opRequirements
+)
+
+var ops = map[string]op{
+	"set":          opSet,
+	"default":      opDefault,
+	"evalset":      opEvalSet,
+	"evalmacro":    opEvalMacro,
+	"copy":         opCopy,
+	"rename":       opRename,
+	"delete":       opDelete,
+	"requirements": opRequirements,
+}
+
+// stmt is one statement. The target and expression are as written, unless
+// they hold no macros and were parsed ahead of time.
+type stmt struct {
+	line   int
+	op     op
+	name   string         // the attribute or macro the statement sets, copies or deletes
+	re     *regexp.Regexp // the /regex/ of COPY, RENAME or DELETE
+	reSrc  string         // the regex as written, when it has macros
+	target string         // the new name of COPY and RENAME
+	text   string         // the expression, or a macro's value
+	expr   *classad.Expr  // text parsed, when it has no macros
+}
+
+// Parse parses the text of a transform. A line ending in a backslash
+// continues on the next, as in a configuration file.
+func Parse(src string) (*Transform, error) {
+	t := &Transform{}
+	lines, err := expand.ReadLines(strings.NewReader(src))
+	if err != nil {
+		return nil, fmt.Errorf("transform: %w", err)
+	}
+	for _, l := range lines {
+		text := strings.TrimSpace(l.Text)
+		if text == "" || text[0] == '#' {
+			continue
+		}
+		s, err := parseStmt(text, l.Num)
+		if err != nil {
+			return nil, t.errorf(l.Num, "%v", err)
+		}
+		switch {
+		case s == nil: // NAME
+			t.name = strings.TrimSpace(text[len("name"):])
+		case s.op == opRequirements:
+			if t.req != nil {
+				return nil, t.errorf(l.Num, "more than one REQUIREMENTS")
+			}
+			t.req = s
+		default:
+			t.stmts = append(t.stmts, s)
+		}
+	}
+	return t, nil
+}
+
+// Name returns the transform's NAME, or "" if it has none.
+func (t *Transform) Name() string { return t.name }
+
+func (t *Transform) errorf(line int, format string, args ...any) error {
+	if t.name != "" {
+		return fmt.Errorf("transform %s: line %d: %s", t.name, line, fmt.Sprintf(format, args...))
+	}
+	return fmt.Errorf("transform: line %d: %s", line, fmt.Sprintf(format, args...))
+}
+
+// parseStmt parses one non-blank line. A NAME line parses to nil.
+func parseStmt(text string, line int) (*stmt, error) {
+	word, rest := cut(text)
+	if strings.Contains(word, "=") || strings.HasPrefix(rest, "=") {
+		// name = value
+		name, value, _ := strings.Cut(text, "=")
+		name = strings.TrimSpace(name)
+		if name == "" || strings.ContainsAny(name, " \t") {
+			return nil, fmt.Errorf("invalid macro name %q", name)
+		}
+		return &stmt{line: line, op: opMacro, name: name, text: strings.TrimSpace(value)}, nil
+	}
+	if strings.EqualFold(word, "name") {
+		return nil, nil
+	}
+	o, ok := ops[strings.ToLower(word)]
+	if !ok {
+		return nil, fmt.Errorf("unknown statement %q", word)
+	}
+	s := &stmt{line: line, op: o}
+	switch o {
+	case opRequirements:
+		s.text = rest
+	case opSet, opDefault, opEvalSet, opEvalMacro:
+		s.name, s.text = cut(rest)
+	case opCopy, opRename, opDelete:
+		var err error
+		if strings.HasPrefix(rest, "/") {
+			if s.reSrc, rest, err = parseRegex(rest); err != nil {
+				return nil, err
+			}
+			if !strings.Contains(s.reSrc, "$(") {
+				if s.re, err = compileRegex(s.reSrc); err != nil {
+					return nil, err
+				}
+				s.reSrc = ""
+			}
+		} else {
+			s.name, rest = cut(rest)
+		}
+		if o == opDelete {
+			if rest != "" {
+				return nil, fmt.Errorf("DELETE takes one attribute, got %q after it", rest)
+			}
+		} else if s.target, rest = cut(rest); rest != "" {
+			return nil, fmt.Errorf("%s takes two attributes, got %q after them", strings.ToUpper(word), rest)
+		}
+		if s.name == "" && s.re == nil && s.reSrc == "" || o != opDelete && s.target == "" {
+			return nil, fmt.Errorf("%s needs an attribute", strings.ToUpper(word))
+		}
+		return s, nil
+	}
+	if s.op != opRequirements && s.name == "" {
+		return nil, fmt.Errorf("%s needs an attribute", strings.ToUpper(word))
+	}
+	if s.text == "" {
+		return nil, fmt.Errorf("%s needs an expression", strings.ToUpper(word))
+	}
+	if !strings.Contains(s.text, "$(") {
+		expr, err := classad.ParseExpr(s.text)
+		if err != nil {
+			return nil, err
+		}
+		s.expr = expr
+	}
+	return s, nil
+}
+
+// parseRegex parses a leading /regex/ (a '/' inside it escaped as \/) and any
+// flags after it, returning the regex and the text after it.
+func parseRegex(text string) (string, string, error) {
+	var b strings.Builder
+	i := 1
+	for ; i < len(text) && text[i] != '/'; i++ {
+		if text[i] == '\\' && i+1 < len(text) && text[i+1] == '/' {
+			i++
+		}
+		b.WriteByte(text[i])
+	}
+	if i == len(text) {
+		return "", "", fmt.Errorf("unterminated regex %s", text)
+	}
+	rest := text[i+1:]
+	if flags, after := cut(rest); rest != "" && rest[0] != ' ' && rest[0] != '\t' {
+		if strings.Trim(flags, "i") != "" {
+			return "", "", fmt.Errorf("unknown regex flags %q", flags)
+		}
+		rest = after
+	}
+	return b.String(), rest, nil
+}
+
+// compileRegex compiles the regex of a COPY, RENAME or DELETE. Matching is
+// always case-insensitive, as attribute names are.
+func compileRegex(src string) (*regexp.Regexp, error) {
+	return regexp.Compile("(?i)" + src)
+}
+
+// cut splits text at its first run of spaces or tabs.
+func cut(text string) (word, rest string) {
+	text = strings.TrimSpace(text)
+	i := strings.IndexAny(text, " \t")
+	if i < 0 {
+		return text, ""
+	}
+	return text[:i], strings.TrimSpace(text[i:])
+}
+
+// Matches reports whether ad satisfies the transform's REQUIREMENTS (true if
+// it has none).
+func (t *Transform) Matches(ad *classad.ClassAd) (bool, error) {
+	if t.req == nil {
+		return true, nil
+	}
+	expr, err := t.exprOf(t.req, t.newScope(ad))
+	if err != nil {
+		return false, err
+	}
+	ok, _ := expr.Eval(ad).BoolValue()
+	return ok, nil
+}
+
+// Apply transforms ad in place if it satisfies the REQUIREMENTS, reporting
+// whether it did. On error ad is unchanged.
+func (t *Transform) Apply(ad *classad.ClassAd) (bool, error) {
+	if ok, err := t.Matches(ad); !ok || err != nil {
+		return false, err
+	}
+
+	// Work on a copy of ad's own attributes, chained and evaluated as ad is,
+	// then apply the difference: a failing statement leaves ad untouched.
+	work := classad.New()
+	work.Apply(classad.Diff(nil, ad))
+	work.ChainToAd(ad.GetChainedParent())
+	work.SetDialect(ad.Dialect())
+
+	sc := t.newScope(work)
+	for _, s := range t.stmts {
+		if err := t.exec(s, sc); err != nil {
+			return false, err
+		}
+	}
+	ad.Apply(classad.Diff(ad, work))
+	return true, nil
+}
+
+// exec applies one statement to sc.ad.
+func (t *Transform) exec(s *stmt, sc *scope) error {
+	name, err := sc.expand(s.name)
+	if err != nil {
+		return t.errorf(s.line, "%v", err)
+	}
+	switch s.op {
+	case opMacro:
+		sc.macros[strings.ToLower(name)] = s.text // expanded when used
+		return nil
+	case opCopy, opRename, opDelete:
+		return t.move(s, name, sc)
+	case opDefault:
+		if _, ok := sc.ad.Lookup(name); ok {
+			return nil
+		}
+	}
+	expr, err := t.exprOf(s, sc)
+	if err != nil {
+		return err
+	}
+	switch s.op {
+	case opSet, opDefault:
+		sc.ad.InsertExpr(name, expr)
+	case opEvalSet:
+		sc.ad.InsertExpr(name, classad.LiteralExpr(expr.Eval(sc.ad)))
+	case opEvalMacro:
+		v := expr.Eval(sc.ad)
+		text := v.String()
+		if str, err := v.StringValue(); err == nil {
+			text = str
+		}
+		sc.macros[strings.ToLower(name)] = text
+	}
+	return nil
+}
+
+// move applies a COPY, RENAME or DELETE to the attribute name, or to every
+// attribute s.re matches.
+func (t *Transform) move(s *stmt, name string, sc *scope) error {
+	type pair struct{ from, to string }
+	var pairs []pair
+	re := s.re
+	if s.reSrc != "" {
+		src, err := sc.expand(s.reSrc)
+		if err == nil {
+			re, err = compileRegex(src)
+		}
+		if err != nil {
+			return t.errorf(s.line, "%v", err)
+		}
+	}
+	if re == nil {
+		target, err := sc.expand(s.target)
+		if err != nil {
+			return t.errorf(s.line, "%v", err)
+		}
+		pairs = append(pairs, pair{name, target})
+	} else {
+		tmpl := backrefs(s.target)
+		for _, attr := range sc.ad.GetAttributes() {
+			m := re.FindStringSubmatchIndex(attr)
+			if m == nil {
+				continue
+			}
+			to, err := sc.expand(string(re.ExpandString(nil, tmpl, attr, m)))
+			if err != nil {
+				return t.errorf(s.line, "%v", err)
+			}
+			pairs = append(pairs, pair{attr, to})
+		}
+	}
+	for _, p := range pairs {
+		expr, ok := sc.ad.Lookup(p.from)
+		if !ok {
+			continue
+		}
+		if s.op != opDelete {
+			if p.to == "" {
+				return t.errorf(s.line, "empty new name for %s", p.from)
+			}
+			sc.ad.InsertExpr(p.to, expr)
+		}
+		if s.op != opCopy && !strings.EqualFold(p.from, p.to) {
+			sc.ad.Delete(p.from)
+		}
+	}
+	return nil
+}
+
+// backrefs rewrites the \0-\9 references of a COPY or RENAME target into
+// regexp.Expand's ${0}-${9}, escaping any other '$'.
+func backrefs(target string) string {
+	var b strings.Builder
+	for i := 0; i < len(target); i++ {
+		switch c := target[i]; {
+		case c == '\\' && i+1 < len(target) && target[i+1] >= '0' && target[i+1] <= '9':
+			b.WriteString("${" + target[i+1:i+2] + "}")
+			i++
+		case c == '$':
+			b.WriteString("$$") // a macro is expanded after the match
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
+
+// exprOf returns the parsed expression of s, expanding its macros first.
+func (t *Transform) exprOf(s *stmt, sc *scope) (*classad.Expr, error) {
+	if s.expr != nil {
+		return s.expr, nil
+	}
+	text, err := sc.expand(s.text)
+	if err != nil {
+		return nil, t.errorf(s.line, "%v", err)
+	}
+	expr, err := classad.ParseExpr(text)
+	if err != nil {
+		return nil, t.errorf(s.line, "%v", err)
+	}
+	return expr, nil
+}
+
+// scope is what a transform's macros expand in: its macros, by lower-cased
+// name, and the ad for $(MY.Attr).
+type scope struct {
+	ad     *classad.ClassAd
+	macros map[string]string
+	outer  func(name string) (string, bool)
+}
+
+func (t *Transform) newScope(ad *classad.ClassAd) *scope {
+	return &scope{ad: ad, macros: map[string]string{}, outer: t.outer}
+}
+
+// expand replaces the macro references in s. A transform has no macro
+// functions: $(...) is its only reference.
+func (sc *scope) expand(s string) (string, error) {
+	return expand.Syntax{}.Expand(s, 0, sc.resolve)
+}
+
+func (sc *scope) resolve(_, ref string, depth int) (string, error) {
+	name, def, _ := strings.Cut(ref, ":")
+	name = strings.TrimSpace(name)
+	if attr, ok := cutPrefixFold(name, "MY."); ok {
+		if expr, ok := sc.ad.Lookup(attr); ok {
+			if v, ok := stringLiteral(expr); ok {
+				return v, nil
+			}
+			return expr.String(), nil
+		}
+	} else if v, ok := sc.macros[strings.ToLower(name)]; ok {
+		return expand.Syntax{}.Expand(v, depth+1, sc.resolve)
+	} else if sc.outer != nil {
+		if v, ok := sc.outer(name); ok {
+			return v, nil
+		}
+	}
+	return def, nil
+}
+
+// stringLiteral returns the value of expr if it is a string literal.
+func stringLiteral(expr *classad.Expr) (string, bool) {
+	text := expr.String()
+	if !strings.HasPrefix(text, `"`) {
+		return "", false
+	}
+	v, err := classad.Unquote(text)
+	return v, err == nil
+}
+
+func cutPrefixFold(s, prefix string) (string, bool) {
+	if len(s) >= len(prefix) && strings.EqualFold(s[:len(prefix)], prefix) {
+		return s[len(prefix):], true
+	}
+	return s, false
+}
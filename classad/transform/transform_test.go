@@ -0,0 +1,184 @@
+package transform
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/PelicanPlatform/classad/classad"
+	"github.com/PelicanPlatform/classad/classad/config"
+)
+
+func mustParse(t *testing.T, src string) *Transform {
+	t.Helper()
+	tr, err := Parse(src)
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	return tr
+}
+
+func mustAd(t *testing.T, src string) *classad.ClassAd {
+	t.Helper()
+	ad, err := classad.Parse(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ad
+}
+
+func TestApply(t *testing.T) {
+	tr := mustParse(t, `
+# normalize GPU jobs
+NAME gpu
+REQUIREMENTS RequestGPUs > 0 && JobUniverse == 5
+SET Queue "gpu"
+DEFAULT RequestMemory 4096
+DEFAULT RequestCpus 4
+EVALSET MemoryPerGPU RequestMemory / RequestGPUs
+EVALMACRO owner_upper toUpper(Owner)
+SET AcctGroup "group_$(owner_upper).$(MY.Project:none)"
+suffix = _Legacy
+COPY Cmd OriginalCmd
+RENAME /^(.*)$(suffix)$/ \1
+DELETE /^Tmp/
+DELETE Scratch
+`)
+	if tr.Name() != "gpu" {
+		t.Errorf("Name = %q", tr.Name())
+	}
+	ad := mustAd(t, `[Owner = "alice"; RequestGPUs = 2; JobUniverse = 5; RequestCpus = 1; Cmd = "/bin/train";
+		Site_Legacy = "chtc"; TmpA = 1; tmpB = 2; Scratch = 3; Project = "ml"]`)
+	ok, err := tr.Apply(ad)
+	if err != nil || !ok {
+		t.Fatalf("Apply = %v, %v", ok, err)
+	}
+	want := `[AcctGroup = "group_ALICE.ml"; Cmd = "/bin/train"; JobUniverse = 5; MemoryPerGPU = 2048; OriginalCmd = "/bin/train"; Owner = "alice"; Project = "ml"; Queue = "gpu"; RequestCpus = 1; RequestGPUs = 2; RequestMemory = 4096; Site = "chtc"]`
+	if got := ad.String(); got != want {
+		t.Errorf("ad =\n%s\nwant\n%s", got, want)
+	}
+
+	cpu := mustAd(t, `[RequestGPUs = 0; JobUniverse = 5]`)
+	if ok, err := tr.Apply(cpu); ok || err != nil {
+		t.Errorf("Apply to an ad failing REQUIREMENTS = %v, %v", ok, err)
+	}
+	if cpu.Size() != 2 {
+		t.Errorf("ad failing REQUIREMENTS changed: %s", cpu)
+	}
+}
+
+func TestApplyErrorLeavesAdUnchanged(t *testing.T) {
+	tr := mustParse(t, "SET A 1\nSET B $(MY.Bad)")
+	ad := mustAd(t, `[Bad = "1 +"]`)
+	if _, err := tr.Apply(ad); err == nil || !strings.Contains(err.Error(), "line 2") {
+		t.Fatalf("Apply error = %v", err)
+	}
+	if ad.String() != `[Bad = "1 +"]` {
+		t.Errorf("ad after a failed transform = %s", ad)
+	}
+}
+
+func TestChainedAd(t *testing.T) {
+	cluster := mustAd(t, `[Owner = "bob"; RequestMemory = 1024]`)
+	proc := mustAd(t, `[ProcId = 1]`)
+	proc.ChainToAd(cluster)
+	tr := mustParse(t, "DEFAULT RequestMemory 2048\nEVALSET Who Owner\n")
+	if _, err := tr.Apply(proc); err != nil {
+		t.Fatal(err)
+	}
+	if proc.String() != `[ProcId = 1; Who = "bob"]` || proc.GetChainedParent() != cluster {
+		t.Errorf("proc = %s: DEFAULT should see the cluster's RequestMemory", proc)
+	}
+}
+
+func TestRuleset(t *testing.T) {
+	cfg, err := config.Parse(`
+SITE = chtc
+JOB_TRANSFORM_NAMES = First, Second
+JOB_TRANSFORM_First @=end
+  SET Stage 1
+  SET Site "$(SITE)"
+@end
+JOB_TRANSFORM_Second @=end
+  REQUIREMENTS Stage == 1
+  EVALSET Stage Stage + 1
+@end
+`, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rs, err := FromConfig(cfg, "JOB_TRANSFORM")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rs) != 2 || rs[0].Name() != "First" {
+		t.Fatalf("FromConfig = %d transforms", len(rs))
+	}
+	ad := classad.New()
+	if n, err := rs.Apply(ad); n != 2 || err != nil {
+		t.Errorf("Apply = %d, %v", n, err)
+	}
+	if ad.String() != `[Site = "chtc"; Stage = 2]` {
+		t.Errorf("ad = %s", ad)
+	}
+
+	if err := rs.Hook()("job.1", classad.New()); err != nil {
+		t.Errorf("Hook: %v", err)
+	}
+	bad := Ruleset{mustParse(t, "SET X $(MY.Y)")}
+	ad = mustAd(t, `[Y = "("]`)
+	if err := bad.Hook()("job.2", ad); err == nil || !strings.HasPrefix(err.Error(), "transform: line 1") {
+		t.Errorf("Hook error = %v", err)
+	}
+
+	if rs, err := FromConfig(cfg, "SUBMIT_TRANSFORM"); rs != nil || err != nil {
+		t.Errorf("FromConfig without names = %v, %v", rs, err)
+	}
+}
+
+func TestContinuationLines(t *testing.T) {
+	tr := mustParse(t, `NAME wrapped
+REQUIREMENTS JobUniverse == 5 && \
+    RequestGPUs > 0
+SET Queue \
+    ifThenElse(RequestGPUs > 1, \
+# a comment inside a continued line is dropped
+               "multi", "single")
+DEFAULT RequestMemory 2048`)
+	ad := mustAd(t, `[JobUniverse = 5; RequestGPUs = 2]`)
+	if ok, err := tr.Apply(ad); !ok || err != nil {
+		t.Fatalf("Apply = %v, %v", ok, err)
+	}
+	if q, _ := ad.EvaluateAttrString("Queue"); q != "multi" {
+		t.Errorf("Queue = %q, want multi", q)
+	}
+	if m, _ := ad.EvaluateAttrInt("RequestMemory"); m != 2048 {
+		t.Errorf("RequestMemory = %d, want 2048", m)
+	}
+	// A statement is numbered by the line it starts on.
+	if _, err := Parse("SET A 1\nSET B \\\n  (1"); err == nil || !strings.Contains(err.Error(), "line 2") {
+		t.Errorf("error = %v, want one on line 2", err)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := map[string]string{
+		"FROB X 1":                              `unknown statement "FROB"`,
+		"SET X":                                 "SET needs an expression",
+		"SET":                                   "SET needs an attribute",
+		"SET X (1":                              "line 1",
+		"COPY A":                                "COPY needs an attribute",
+		"RENAME A B C":                          "takes two attributes",
+		"DELETE /a(/":                           "error parsing regexp",
+		"DELETE /abc":                           "unterminated regex",
+		"DELETE /a/x":                           "unknown regex flags",
+		"DELETE A B":                            "DELETE takes one attribute",
+		"REQUIREMENTS true\nREQUIREMENTS false": "line 2: more than one REQUIREMENTS",
+		"NAME t\nSET":                           "transform t: line 2",
+		"= 3":                                   "invalid macro name",
+	}
+	for src, want := range tests {
+		if _, err := Parse(src); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("Parse(%q) error = %v, want %q", src, err, want)
+		}
+	}
+}
@@ -71,6 +71,14 @@ func unparseExpr(b *strings.Builder, e ast.Expr, old bool) {
 		b.WriteString("undefined")
 	case *ast.ErrorLiteral:
 		b.WriteString("error")
+	case *ast.AbsTimeLiteral:
+		b.WriteString("absTime(")
+		b.WriteString(unparseString(ast.FormatAbsTime(v.Secs, v.Offset)))
+		b.WriteByte(')')
+	case *ast.RelTimeLiteral:
+		b.WriteString("relTime(")
+		b.WriteString(unparseString(ast.FormatRelTime(v.Secs)))
+		b.WriteByte(')')
 	case *ast.AttributeReference:
 		switch v.Scope {
 		case ast.MyScope:
@@ -0,0 +1,279 @@
+package classad
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/PelicanPlatform/classad/ast"
+)
+
+// User-defined functions. The built-in table (functionArity and the dispatch
+// switch in evaluateFunctionCall) is closed: a name it does not list is an error
+// before its arguments are evaluated. A FunctionRegistry extends that table with
+// site functions (userMap(), checkQuota(), ...) without forking the evaluator.
+//
+// Lookup order for a call is: built-in, then the evaluator's scoped registry (see
+// Evaluator.SetFunctions / Expr.EvalWithFunctions), then the process-wide default
+// registry (RegisterFunction). Built-in names cannot be registered, so a
+// registration never changes the meaning of an expression that already evaluates.
+//
+// A user function receives its arguments already evaluated, exactly like a
+// built-in such as strcmp: the registry checks arity first (a wrong-arity call is
+// error without evaluating any argument), then evaluates every argument left to
+// right, then calls the implementation. Undefined/error arguments are passed
+// through; the implementation decides whether to propagate them.
+
+// FunctionImpl implements a user-defined function over evaluated arguments. It
+// must be safe for concurrent use: one registration serves every evaluation in
+// the process (or every evaluation sharing a scoped registry).
+type FunctionImpl func(args []Value) Value
+
+// Arity is the accepted argument-count range of a function: [Min, Max], with
+// Max == -1 meaning unbounded (variadic).
+type Arity struct {
+	Min, Max int
+}
+
+// ExactArity returns the Arity of a function taking exactly n arguments.
+func ExactArity(n int) Arity { return Arity{Min: n, Max: n} }
+
+// VariadicArity returns the Arity of a function taking at least min arguments.
+func VariadicArity(min int) Arity { return Arity{Min: min, Max: -1} }
+
+// Accepts reports whether a call with n arguments is within the arity.
+func (a Arity) Accepts(n int) bool {
+	return funcArity{a.Min, a.Max}.accepts(n)
+}
+
+func (a Arity) valid() bool {
+	return a.Min >= 0 && (a.Max == -1 || a.Max >= a.Min)
+}
+
+type userFunc struct {
+	name  string // as registered (original casing), for diagnostics
+	arity Arity
+	impl  FunctionImpl
+}
+
+// FunctionRegistry is a set of user-defined functions keyed by case-insensitive
+// name. The zero value is not usable; create one with NewFunctionRegistry. A
+// registry is safe for concurrent registration and lookup.
+type FunctionRegistry struct {
+	mu    sync.RWMutex
+	funcs map[string]userFunc
+}
+
+// NewFunctionRegistry returns an empty registry, for use as a scoped registry
+// (Evaluator.SetFunctions, Expr.EvalWithFunctions) layered over the default one.
+func NewFunctionRegistry() *FunctionRegistry {
+	return &FunctionRegistry{funcs: map[string]userFunc{}}
+}
+
+// defaultFunctions backs RegisterFunction and is consulted by every evaluator.
+var defaultFunctions = NewFunctionRegistry()
+
+// Register adds (or replaces) the function name in r. It fails for an empty
+// name, a nil implementation, an invalid arity, or a name that is a built-in.
+func (r *FunctionRegistry) Register(name string, arity Arity, impl FunctionImpl) error {
+	if name == "" {
+		return fmt.Errorf("classad: register function: empty name")
+	}
+	if impl == nil {
+		return fmt.Errorf("classad: register function %q: nil implementation", name)
+	}
+	if !arity.valid() {
+		return fmt.Errorf("classad: register function %q: invalid arity [%d, %d]", name, arity.Min, arity.Max)
+	}
+	norm := strings.ToLower(name)
+	if IsBuiltinFunction(norm) {
+		return fmt.Errorf("classad: register function %q: name is a built-in function", name)
+	}
+	r.mu.Lock()
+	r.funcs[norm] = userFunc{name: name, arity: arity, impl: impl}
+	r.mu.Unlock()
+	return nil
+}
+
+// Unregister removes name from r, reporting whether it was registered.
+func (r *FunctionRegistry) Unregister(name string) bool {
+	norm := strings.ToLower(name)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.funcs[norm]; !ok {
+		return false
+	}
+	delete(r.funcs, norm)
+	return true
+}
+
+// Lookup reports the arity of the function name registered in r.
+func (r *FunctionRegistry) Lookup(name string) (Arity, bool) {
+	f, ok := r.lookup(strings.ToLower(name))
+	return f.arity, ok
+}
+
+// Names returns the registered names (as registered, in no particular order).
+func (r *FunctionRegistry) Names() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	out := make([]string, 0, len(r.funcs))
+	for _, f := range r.funcs {
+		out = append(out, f.name)
+	}
+	return out
+}
+
+func (r *FunctionRegistry) lookup(norm string) (userFunc, bool) {
+	if r == nil {
+		return userFunc{}, false
+	}
+	r.mu.RLock()
+	f, ok := r.funcs[norm]
+	r.mu.RUnlock()
+	return f, ok
+}
+
+// RegisterFunction registers a user-defined function in the process-wide default
+// registry, making it callable from every expression evaluated afterwards --
+// by the tree-walking evaluator, collections/vm programs, and the wire-native
+// match path alike.
+//
+// Example:
+//
+//	classad.RegisterFunction("checkQuota", classad.ExactArity(1), func(args []classad.Value) classad.Value {
+//	    owner, err := args[0].StringValue()
+//	    if err != nil {
+//	        return classad.NewErrorValue()
+//	    }
+//	    return classad.NewBoolValue(quotaOK(owner))
+//	})
+func RegisterFunction(name string, arity Arity, impl FunctionImpl) error {
+	return defaultFunctions.Register(name, arity, impl)
+}
+
+// UnregisterFunction removes a function from the default registry.
+func UnregisterFunction(name string) bool {
+	return defaultFunctions.Unregister(name)
+}
+
+// IsBuiltinFunction reports whether name (case-insensitive) is a built-in
+// function of the engine.
+func IsBuiltinFunction(name string) bool {
+	_, ok := functionArity[strings.ToLower(name)]
+	return ok
+}
+
+// BuiltinFunctions returns the names of the engine's built-in functions,
+// lower-cased and sorted -- for a tool that offers them, such as an editor's
+// completion.
+func BuiltinFunctions() []string {
+	names := make([]string, 0, len(functionArity))
+	for name := range functionArity {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// IsKnownFunction reports whether name resolves to a built-in or to a function in
+// the default registry.
+func IsKnownFunction(name string) bool {
+	norm := strings.ToLower(name)
+	if _, ok := functionArity[norm]; ok {
+		return true
+	}
+	_, ok := defaultFunctions.lookup(norm)
+	return ok
+}
+
+// FunctionArity reports the accepted argument counts of the function name
+// (case-insensitive): a built-in, or else a function in the default registry.
+// Static checkers use it to flag a call that would evaluate to error.
+func FunctionArity(name string) (Arity, bool) {
+	norm := strings.ToLower(name)
+	if a, ok := functionArity[norm]; ok {
+		return Arity{Min: a.min, Max: a.max}, true
+	}
+	return defaultFunctions.Lookup(norm)
+}
+
+// lookupUserFunc resolves a lower-cased, non-built-in function name: the
+// evaluator's scoped registry first, then the default registry.
+func (e *Evaluator) lookupUserFunc(norm string) (userFunc, bool) {
+	if f, ok := e.funcs.lookup(norm); ok {
+		return f, true
+	}
+	return defaultFunctions.lookup(norm)
+}
+
+// callUserFunc evaluates a call to a user-defined function: arity is checked
+// before the arguments are evaluated (as for a built-in), then every argument is
+// evaluated and the implementation called. An unknown name is error.
+func (e *Evaluator) callUserFunc(norm string, argExprs []ast.Expr) Value {
+	f, ok := e.lookupUserFunc(norm)
+	if !ok || !f.arity.Accepts(len(argExprs)) {
+		return NewErrorValue()
+	}
+	args := make([]Value, len(argExprs))
+	for i, a := range argExprs {
+		args[i] = e.Evaluate(a)
+	}
+	return f.impl(args)
+}
+
+// CallFunction calls the function name (built-ins excluded) on already-evaluated
+// arguments, resolving it the way the evaluator does: the evaluator's scoped
+// registry, then the default registry. An unknown name or wrong argument count is
+// error. It is the hook collections/vm uses to run a user function natively, with
+// its arguments computed by the interpreter.
+func (e *Evaluator) CallFunction(name string, args []Value) Value {
+	norm := strings.ToLower(name)
+	if _, builtin := functionArity[norm]; builtin {
+		return NewErrorValue()
+	}
+	f, ok := e.lookupUserFunc(norm)
+	if !ok || !f.arity.Accepts(len(args)) {
+		return NewErrorValue()
+	}
+	result := f.impl(args)
+	if e.limits != nil {
+		e.limits.charge(result)
+	}
+	return result
+}
+
+// CanCallFunction reports whether a call to the user-defined function name with
+// argc arguments would resolve (CallFunction would invoke it rather than return
+// error). collections/vm checks it before evaluating a call's arguments, keeping
+// the tree-walker's rule that a bad call never evaluates its arguments.
+func (e *Evaluator) CanCallFunction(name string, argc int) bool {
+	norm := strings.ToLower(name)
+	if _, builtin := functionArity[norm]; builtin {
+		return false
+	}
+	f, ok := e.lookupUserFunc(norm)
+	return ok && f.arity.Accepts(argc)
+}
+
+// SetFunctions installs (or clears, with nil) a scoped function registry,
+// consulted before the default registry. Child evaluators created during
+// evaluation (nested-ad and list-element scopes) inherit it. The Evaluator is
+// not safe for concurrent use.
+func (e *Evaluator) SetFunctions(r *FunctionRegistry) {
+	e.funcs = r
+}
+
+// EvalWithFunctions is Eval with a scoped function registry layered over the
+// default one, so a caller can expose functions to one evaluation without
+// registering them process-wide.
+func (e *Expr) EvalWithFunctions(scope *ClassAd, funcs *FunctionRegistry) (result Value) {
+	if e.expr == nil {
+		return NewUndefinedValue()
+	}
+	defer recoverCyclic(&result)
+	evaluator := NewEvaluator(scope)
+	evaluator.funcs = funcs
+	return evaluator.Evaluate(e.expr)
+}
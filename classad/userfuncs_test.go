@@ -0,0 +1,141 @@
+package classad
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestRegisterFunctionEvaluates checks that a function registered in the default
+// registry is callable (case-insensitively) from any expression, and that an
+// unregistered name remains an error.
+func TestRegisterFunctionEvaluates(t *testing.T) {
+	if err := RegisterFunction("testDouble", ExactArity(1), func(args []Value) Value {
+		n, err := args[0].IntValue()
+		if err != nil {
+			return NewErrorValue()
+		}
+		return NewIntValue(2 * n)
+	}); err != nil {
+		t.Fatalf("RegisterFunction: %v", err)
+	}
+	defer UnregisterFunction("testDouble")
+
+	ad, err := Parse(`[Cpus = 4; Want = TESTDOUBLE(Cpus) + 1; Bad = testDouble(Cpus, 1); Gone = noSuchFn(Cpus)]`)
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	if got, ok := ad.EvaluateAttrInt("Want"); !ok || got != 9 {
+		t.Errorf("Want = %v, want 9", ad.EvaluateAttr("Want"))
+	}
+	if v := ad.EvaluateAttr("Bad"); !v.IsError() {
+		t.Errorf("wrong-arity call = %v, want error", v)
+	}
+	if v := ad.EvaluateAttr("Gone"); !v.IsError() {
+		t.Errorf("unknown function = %v, want error", v)
+	}
+	if !IsKnownFunction("testdouble") || IsBuiltinFunction("testDouble") {
+		t.Error("testDouble should be known but not built-in")
+	}
+
+	UnregisterFunction("testDouble")
+	if v := ad.EvaluateAttr("Want"); !v.IsError() {
+		t.Errorf("after unregister: %v, want error", v)
+	}
+}
+
+// TestRegisterFunctionRejects checks the registration guards: a built-in name,
+// a nil implementation and an invalid arity are refused.
+func TestRegisterFunctionRejects(t *testing.T) {
+	impl := func([]Value) Value { return NewBoolValue(true) }
+	cases := []struct {
+		name  string
+		arity Arity
+		impl  FunctionImpl
+	}{
+		{"strcat", ExactArity(1), impl},
+		{"SIZE", ExactArity(1), impl},
+		{"", ExactArity(0), impl},
+		{"fine", ExactArity(0), nil},
+		{"fine", Arity{Min: 2, Max: 1}, impl},
+		{"fine", Arity{Min: -1, Max: 1}, impl},
+	}
+	for _, tc := range cases {
+		if err := NewFunctionRegistry().Register(tc.name, tc.arity, tc.impl); err == nil {
+			t.Errorf("Register(%q, %+v) succeeded, want error", tc.name, tc.arity)
+		}
+	}
+}
+
+// TestUserFunctionArityBeforeArgs guards that a wrong-arity call to a user
+// function does not evaluate its arguments, like a built-in: a cyclic argument
+// leaves the surrounding =!= comparison true rather than error.
+func TestUserFunctionArityBeforeArgs(t *testing.T) {
+	if err := RegisterFunction("testOneArg", ExactArity(1), func(args []Value) Value { return args[0] }); err != nil {
+		t.Fatal(err)
+	}
+	defer UnregisterFunction("testOneArg")
+	ad, err := Parse(`[ A0 = 0 =!= testOneArg(A0, 1) ]`)
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	if got, ok := ad.EvaluateAttrBool("A0"); !ok || !got {
+		t.Errorf("A0 = %v, want true", ad.EvaluateAttr("A0"))
+	}
+}
+
+// TestScopedFunctionRegistry checks that a scoped registry is consulted before the
+// default one, is visible only to the evaluation it is passed to, and follows
+// evaluation into lazy list elements and nested ads.
+func TestScopedFunctionRegistry(t *testing.T) {
+	if err := RegisterFunction("testWho", ExactArity(0), func([]Value) Value { return NewStringValue("global") }); err != nil {
+		t.Fatal(err)
+	}
+	defer UnregisterFunction("testWho")
+
+	scoped := NewFunctionRegistry()
+	if err := scoped.Register("testWho", ExactArity(0), func([]Value) Value { return NewStringValue("scoped") }); err != nil {
+		t.Fatal(err)
+	}
+	if err := scoped.Register("userMap", VariadicArity(1), func(args []Value) Value {
+		parts := make([]string, 0, len(args))
+		for _, a := range args {
+			s, _ := a.StringValue()
+			parts = append(parts, s)
+		}
+		return NewStringValue(strings.Join(parts, "/"))
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	ad, err := Parse(`[Owner = "alice"]`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cases := []struct {
+		expr string
+		want string
+	}{
+		{`testWho()`, `"scoped"`},
+		{`userMap("users", Owner)`, `"users/alice"`},
+		{`{userMap(Owner)}[0]`, `"alice"`},
+		{`[x = testWho()].x`, `"scoped"`},
+	}
+	for _, tc := range cases {
+		expr, err := ParseExpr(tc.expr)
+		if err != nil {
+			t.Fatalf("parse %q: %v", tc.expr, err)
+		}
+		if got := expr.EvalWithFunctions(ad, scoped).String(); got != tc.want {
+			t.Errorf("%s with scoped registry = %s, want %s", tc.expr, got, tc.want)
+		}
+	}
+
+	expr, _ := ParseExpr(`testWho()`)
+	if got, _ := expr.Eval(ad).StringValue(); got != "global" {
+		t.Errorf("testWho() without scope = %q, want global", got)
+	}
+	expr, _ = ParseExpr(`userMap(Owner)`)
+	if v := expr.Eval(ad); !v.IsError() {
+		t.Errorf("scoped-only function leaked to an unscoped evaluation: %v", v)
+	}
+}
@@ -58,11 +58,16 @@ func (e *Evaluator) ResolveRefNode(ref *ast.AttributeReference) Value {
 // the Evaluator, and each attribute evaluation removes its own marker on the way
 // out, so nothing carries over between scopes. Depth is balanced by Evaluate's
 // deferred decrement even when a cyclic panic unwinds, so it is 0 here after any
-// completed or recovered evaluation; the reset is a defensive guarantee. The
+// completed or recovered evaluation; the reset is a defensive guarantee. It
+// also starts a fresh budget under the limits of SetOptions, if any. The
 // Evaluator is not safe for concurrent use.
 func (e *Evaluator) SetScope(ad *ClassAd) {
 	e.classad = ad
 	e.depth = 0
+	e.pass = 0
+	if e.limits != nil {
+		e.limits.reset()
+	}
 }
 
 // SetResolver installs (or clears, with nil) a custom attribute resolver. While
@@ -114,6 +119,13 @@ func (c *ClassAd) AST() *ast.ClassAd {
 	return c.ad
 }
 
+// AST returns the expression's syntax tree, for packages (e.g. collections/vm)
+// that compile an expression built in Go rather than parsed from text. The tree
+// may be shared with other expressions and must not be mutated.
+func (e *Expr) AST() ast.Expr {
+	return e.internal()
+}
+
 // FromAST wraps an ast.ClassAd in a ClassAd. The ast.ClassAd is adopted (not
 // copied); callers should not mutate it afterward. It is the inverse of AST for
 // serialization layers that decode to an ast.ClassAd.
@@ -141,9 +153,13 @@ func FoldConstants(e ast.Expr) ast.Expr {
 // RecoverCyclic converts a cyclic-reference panic raised during evaluation into
 // an error value, matching the tree-walker's top-level entry points. Use it as
 // `defer classad.RecoverCyclic(&result)` around a bytecode run so a cyclic
-// reference resolves to error rather than crashing.
+// reference resolves to error rather than crashing. It also recovers the
+// evaluation of an evaluator that ran out of its SetOptions budget (see
+// Evaluator.LimitErr).
 func RecoverCyclic(result *Value) {
-	recoverCyclic(result)
+	if r := recover(); r != nil {
+		recovered(r, result)
+	}
 }
 
 // CompareStringsFold compares two strings the way every ClassAd string comparison operator does:
@@ -154,3 +170,16 @@ func RecoverCyclic(result *Value) {
 // to box each element and re-dispatch on the operator, but must still produce exactly what the
 // tree-walking evaluator produces. Sharing the function is what makes that a fact rather than a hope.
 func CompareStringsFold(a, b string) int { return compareStringsFold(a, b) }
+
+// TimeLiteralValue returns the value of an *ast.AbsTimeLiteral or
+// *ast.RelTimeLiteral, with ok=false for any other node. It lets a compiler
+// constant-load a time literal exactly as the evaluator would.
+func TimeLiteralValue(e ast.Expr) (v Value, ok bool) {
+	switch lit := e.(type) {
+	case *ast.AbsTimeLiteral:
+		return newAbsTime(lit.Secs, lit.Offset), true
+	case *ast.RelTimeLiteral:
+		return newRelTime(lit.Secs), true
+	}
+	return Value{}, false
+}
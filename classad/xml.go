@@ -0,0 +1,585 @@
+package classad
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"iter"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/PelicanPlatform/classad/ast"
+	"github.com/PelicanPlatform/classad/parser"
+)
+
+// XML ClassAds. HTCondor tools emit and accept an XML form of a ClassAd
+// (condor_q -xml, condor_status -xml, condor_history -xml):
+//
+//	<?xml version="1.0"?>
+//	<!DOCTYPE classads SYSTEM "classads.dtd">
+//	<classads>
+//	<c>
+//	    <a n="Cpus"><i>4</i></a>
+//	    <a n="Requirements"><e>TARGET.Memory &gt;= 1024</e></a>
+//	</c>
+//	</classads>
+//
+// Each <a n="name"> holds exactly one value element:
+//
+//	<i>      integer            <r>      real ("INF", "-INF", "NaN" allowed)
+//	<s>      string             <b v="t"/> / <b v="f"/> boolean
+//	<un/>    undefined          <er/>    error
+//	<at>     absolute time      <rt>     relative time
+//	<l>      list of values     <c>      nested ClassAd of <a> elements
+//	<e>      any other expression, in ClassAd syntax
+//
+// Anything that is not a literal, list or nested ad round-trips through <e>.
+
+const (
+	xmlDoctype = `DOCTYPE classads SYSTEM "classads.dtd"`
+	xmlIndent  = "    "
+)
+
+// MarshalXML implements xml.Marshaler, writing the ad as a <c> element whatever
+// name the encoder proposes (the XML form has no other spelling for an ad), so
+// xml.Marshal(ad) yields "<c><a n=...>...</a></c>". Like MarshalJSON it
+// excludes private attributes; an XMLWriter with IncludePrivate set writes them.
+// Use XMLWriter to produce a complete <classads> document.
+func (c *ClassAd) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	return c.marshalXML(e, false, "")
+}
+
+func (c *ClassAd) marshalXML(e *xml.Encoder, includePrivate bool, indent string) error {
+	var attrs []*ast.AttributeAssignment
+	if c.ad != nil {
+		c.ensureSorted()
+		attrs = c.ad.Attributes
+	}
+	return encodeXMLAd(e, attrs, includePrivate, indent)
+}
+
+// encodeXMLAd writes a <c> element holding attrs. With a non-empty indent each
+// <a> goes on its own line (the layout HTCondor writes at top level); nested
+// ads are always compact.
+func encodeXMLAd(e *xml.Encoder, attrs []*ast.AttributeAssignment, includePrivate bool, indent string) error {
+	cStart := xml.StartElement{Name: xml.Name{Local: "c"}}
+	if err := e.EncodeToken(cStart); err != nil {
+		return err
+	}
+	for _, attr := range attrs {
+		if !includePrivate && IsPrivateAttribute(attr.Name) {
+			continue
+		}
+		if indent != "" {
+			if err := e.EncodeToken(xml.CharData("\n" + indent)); err != nil {
+				return err
+			}
+		}
+		aStart := xml.StartElement{
+			Name: xml.Name{Local: "a"},
+			Attr: []xml.Attr{{Name: xml.Name{Local: "n"}, Value: attr.Name}},
+		}
+		if err := e.EncodeToken(aStart); err != nil {
+			return err
+		}
+		if err := encodeXMLValue(e, attr.Value, includePrivate); err != nil {
+			return fmt.Errorf("failed to marshal attribute %s: %w", attr.Name, err)
+		}
+		if err := e.EncodeToken(aStart.End()); err != nil {
+			return err
+		}
+	}
+	if indent != "" {
+		if err := e.EncodeToken(xml.CharData("\n")); err != nil {
+			return err
+		}
+	}
+	return e.EncodeToken(cStart.End())
+}
+
+// encodeXMLValue writes the value element for expr.
+func encodeXMLValue(e *xml.Encoder, expr ast.Expr, includePrivate bool) error {
+	switch v := expr.(type) {
+	case *ast.IntegerLiteral:
+		return encodeXMLText(e, "i", strconv.FormatInt(v.Value, 10))
+	case *ast.RealLiteral:
+		return encodeXMLText(e, "r", xmlReal(v.Value))
+	case *ast.StringLiteral:
+		return encodeXMLText(e, "s", v.Value)
+	case *ast.BooleanLiteral:
+		b := "f"
+		if v.Value {
+			b = "t"
+		}
+		return encodeXMLEmpty(e, "b", xml.Attr{Name: xml.Name{Local: "v"}, Value: b})
+	case *ast.UndefinedLiteral:
+		return encodeXMLEmpty(e, "un")
+	case *ast.ErrorLiteral:
+		return encodeXMLEmpty(e, "er")
+	case *ast.AbsTimeLiteral:
+		return encodeXMLText(e, "at", ast.FormatAbsTime(v.Secs, v.Offset))
+	case *ast.RelTimeLiteral:
+		return encodeXMLText(e, "rt", ast.FormatRelTime(v.Secs))
+	case *ast.ListLiteral:
+		lStart := xml.StartElement{Name: xml.Name{Local: "l"}}
+		if err := e.EncodeToken(lStart); err != nil {
+			return err
+		}
+		for _, elem := range v.Elements {
+			if err := encodeXMLValue(e, elem, includePrivate); err != nil {
+				return err
+			}
+		}
+		return e.EncodeToken(lStart.End())
+	case *ast.RecordLiteral:
+		var attrs []*ast.AttributeAssignment
+		if v.ClassAd != nil {
+			attrs = v.ClassAd.Attributes
+		}
+		return encodeXMLAd(e, attrs, includePrivate, "")
+	case *ast.FunctionCall:
+		// absTime("...")/relTime("...") spell time constants in source; write
+		// them as the <at>/<rt> values they denote.
+		if lit := foldTimeLiteral(v); lit != expr {
+			return encodeXMLValue(e, lit, includePrivate)
+		}
+		return encodeXMLText(e, "e", unparseExprString(expr))
+	default:
+		return encodeXMLText(e, "e", unparseExprString(expr))
+	}
+}
+
+func encodeXMLText(e *xml.Encoder, name, text string) error {
+	start := xml.StartElement{Name: xml.Name{Local: name}}
+	if err := e.EncodeToken(start); err != nil {
+		return err
+	}
+	if err := e.EncodeToken(xml.CharData(text)); err != nil {
+		return err
+	}
+	return e.EncodeToken(start.End())
+}
+
+func encodeXMLEmpty(e *xml.Encoder, name string, attrs ...xml.Attr) error {
+	start := xml.StartElement{Name: xml.Name{Local: name}, Attr: attrs}
+	if err := e.EncodeToken(start); err != nil {
+		return err
+	}
+	return e.EncodeToken(start.End())
+}
+
+// xmlReal renders a real for <r>: the reference form for finite values and the
+// bare INF/-INF/NaN spellings (the real("...") call form is an expression).
+func xmlReal(r float64) string {
+	switch {
+	case math.IsNaN(r):
+		return "NaN"
+	case math.IsInf(r, -1):
+		return "-INF"
+	case math.IsInf(r, 1):
+		return "INF"
+	default:
+		return classadReal(r)
+	}
+}
+
+// UnmarshalXML implements xml.Unmarshaler, replacing c's attributes with those
+// of the <c> element at start. An unknown value element or an <e> that does not
+// parse is an error.
+//
+// Example:
+//
+//	var ad classad.ClassAd
+//	err := xml.Unmarshal([]byte(`<c><a n="Cpus"><i>4</i></a></c>`), &ad)
+func (c *ClassAd) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	attrs, err := decodeXMLAttrs(d)
+	if err != nil {
+		return err
+	}
+	c.ad = &ast.ClassAd{Attributes: attrs}
+	c.attrsDirty = true
+	c.rebuildIndex()
+	c.invalidateCache("")
+	return nil
+}
+
+// decodeXMLAttrs reads the <a> children of a <c> element through its end tag.
+func decodeXMLAttrs(d *xml.Decoder) ([]*ast.AttributeAssignment, error) {
+	var attrs []*ast.AttributeAssignment
+	for {
+		start, ok, err := nextXMLElement(d)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			return attrs, nil
+		}
+		if start.Name.Local != "a" {
+			return nil, fmt.Errorf("classad: xml: unexpected <%s> in <c>, want <a>", start.Name.Local)
+		}
+		name := xmlAttr(start, "n")
+		if name == "" {
+			return nil, fmt.Errorf("classad: xml: <a> without an n attribute")
+		}
+		valStart, ok, err := nextXMLElement(d)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			return nil, fmt.Errorf("classad: xml: attribute %s has no value", name)
+		}
+		value, err := decodeXMLValue(d, valStart)
+		if err != nil {
+			return nil, fmt.Errorf("failed to unmarshal attribute %s: %w", name, err)
+		}
+		if _, ok, err := nextXMLElement(d); err != nil {
+			return nil, err
+		} else if ok {
+			return nil, fmt.Errorf("classad: xml: attribute %s has more than one value", name)
+		}
+		attrs = append(attrs, &ast.AttributeAssignment{Name: name, Value: value})
+	}
+}
+
+// decodeXMLValue decodes the value element at start, consuming it through its
+// end tag.
+func decodeXMLValue(d *xml.Decoder, start xml.StartElement) (ast.Expr, error) {
+	switch start.Name.Local {
+	case "i":
+		text, err := xmlText(d)
+		if err != nil {
+			return nil, err
+		}
+		n, err := strconv.ParseInt(strings.TrimSpace(text), 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("classad: xml: bad integer %q", text)
+		}
+		return &ast.IntegerLiteral{Value: n}, nil
+	case "r":
+		text, err := xmlText(d)
+		if err != nil {
+			return nil, err
+		}
+		r, err := strconv.ParseFloat(strings.TrimSpace(text), 64)
+		if err != nil {
+			return nil, fmt.Errorf("classad: xml: bad real %q", text)
+		}
+		return &ast.RealLiteral{Value: r}, nil
+	case "s":
+		text, err := xmlText(d)
+		if err != nil {
+			return nil, err
+		}
+		return &ast.StringLiteral{Value: text}, nil
+	case "b":
+		v := xmlAttr(start, "v")
+		if err := d.Skip(); err != nil {
+			return nil, err
+		}
+		switch strings.ToLower(v) {
+		case "t", "true":
+			return &ast.BooleanLiteral{Value: true}, nil
+		case "f", "false":
+			return &ast.BooleanLiteral{Value: false}, nil
+		}
+		return nil, fmt.Errorf("classad: xml: bad boolean %q", v)
+	case "un":
+		return &ast.UndefinedLiteral{}, d.Skip()
+	case "er":
+		return &ast.ErrorLiteral{}, d.Skip()
+	case "at":
+		text, err := xmlText(d)
+		if err != nil {
+			return nil, err
+		}
+		secs, offset, ok := parseAbsTime(strings.TrimSpace(text))
+		if !ok {
+			return nil, fmt.Errorf("classad: xml: bad absolute time %q", text)
+		}
+		return &ast.AbsTimeLiteral{Secs: secs, Offset: offset}, nil
+	case "rt":
+		text, err := xmlText(d)
+		if err != nil {
+			return nil, err
+		}
+		secs, ok := parseRelTime(strings.TrimSpace(text))
+		if !ok {
+			return nil, fmt.Errorf("classad: xml: bad relative time %q", text)
+		}
+		return &ast.RelTimeLiteral{Secs: secs}, nil
+	case "e":
+		text, err := xmlText(d)
+		if err != nil {
+			return nil, err
+		}
+		expr, err := parser.ParseExpr(text)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse expression %q: %w", text, err)
+		}
+		return foldTimeLiteral(expr), nil
+	case "l":
+		elements := []ast.Expr{}
+		for {
+			elemStart, ok, err := nextXMLElement(d)
+			if err != nil {
+				return nil, err
+			}
+			if !ok {
+				return &ast.ListLiteral{Elements: elements}, nil
+			}
+			elem, err := decodeXMLValue(d, elemStart)
+			if err != nil {
+				return nil, err
+			}
+			elements = append(elements, elem)
+		}
+	case "c":
+		attrs, err := decodeXMLAttrs(d)
+		if err != nil {
+			return nil, err
+		}
+		sortAttributeAssignments(attrs)
+		return &ast.RecordLiteral{ClassAd: &ast.ClassAd{Attributes: attrs}}, nil
+	default:
+		return nil, fmt.Errorf("classad: xml: unknown value element <%s>", start.Name.Local)
+	}
+}
+
+// nextXMLElement returns the next child start element, skipping character
+// data, comments, processing instructions and directives. It reports false at
+// the enclosing element's end tag (consumed) and io.ErrUnexpectedEOF at EOF.
+func nextXMLElement(d *xml.Decoder) (xml.StartElement, bool, error) {
+	for {
+		tok, err := d.Token()
+		if err == io.EOF {
+			return xml.StartElement{}, false, io.ErrUnexpectedEOF
+		}
+		if err != nil {
+			return xml.StartElement{}, false, err
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			return t, true, nil
+		case xml.EndElement:
+			return xml.StartElement{}, false, nil
+		}
+	}
+}
+
+// xmlText returns the character data of the current element, consuming its end
+// tag. A child element is an error.
+func xmlText(d *xml.Decoder) (string, error) {
+	var b strings.Builder
+	for {
+		tok, err := d.Token()
+		if err == io.EOF {
+			return "", io.ErrUnexpectedEOF
+		}
+		if err != nil {
+			return "", err
+		}
+		switch t := tok.(type) {
+		case xml.CharData:
+			b.Write(t)
+		case xml.StartElement:
+			return "", fmt.Errorf("classad: xml: unexpected <%s> in a scalar value", t.Name.Local)
+		case xml.EndElement:
+			return b.String(), nil
+		}
+	}
+}
+
+func xmlAttr(start xml.StartElement, name string) string {
+	for _, a := range start.Attr {
+		if a.Name.Local == name {
+			return a.Value
+		}
+	}
+	return ""
+}
+
+// NewXMLReader creates a new Reader for parsing an XML ClassAd document: each
+// <c> element, inside a <classads> wrapper or at top level, is one ClassAd. The
+// XML declaration and DOCTYPE are optional.
+// Example format:
+//
+//	<?xml version="1.0"?>
+//	<!DOCTYPE classads SYSTEM "classads.dtd">
+//	<classads>
+//	<c><a n="Foo"><i>1</i></a></c>
+//	<c><a n="Bar"><s>two</s></a></c>
+//	</classads>
+func NewXMLReader(r io.Reader) *Reader {
+	return &Reader{
+		xml: xml.NewDecoder(r),
+	}
+}
+
+// nextXML reads the next <c> element of an XML document.
+func (r *Reader) nextXML() bool {
+	for {
+		tok, err := r.xml.Token()
+		if err == io.EOF {
+			return false
+		}
+		if err != nil {
+			r.err = err
+			return false
+		}
+		start, ok := tok.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		switch start.Name.Local {
+		case "classads":
+			continue
+		case "c":
+			ad := &ClassAd{}
+			if err := ad.UnmarshalXML(r.xml, start); err != nil {
+				r.err = err
+				return false
+			}
+			r.current = ad
+			return true
+		default:
+			r.err = fmt.Errorf("classad: xml: unexpected <%s>, want <c>", start.Name.Local)
+			return false
+		}
+	}
+}
+
+// AllXML returns an iterator over all ClassAds of an XML document.
+// This function is compatible with Go 1.23+ range-over-function syntax.
+//
+// Example usage (Go 1.23+):
+//
+//	out, _ := exec.Command("condor_q", "-xml").Output()
+//	for ad := range classad.AllXML(bytes.NewReader(out)) {
+//	    // Process ad...
+//	}
+func AllXML(r io.Reader) iter.Seq[*ClassAd] {
+	return func(yield func(*ClassAd) bool) {
+		reader := NewXMLReader(r)
+		for reader.Next() {
+			if !yield(reader.ClassAd()) {
+				return
+			}
+		}
+	}
+}
+
+// AllXMLWithIndex returns an iterator over all ClassAds of an XML document with
+// their index.
+// This function is compatible with Go 1.23+ range-over-function syntax.
+func AllXMLWithIndex(r io.Reader) iter.Seq2[int, *ClassAd] {
+	return func(yield func(int, *ClassAd) bool) {
+		reader := NewXMLReader(r)
+		index := 0
+		for reader.Next() {
+			if !yield(index, reader.ClassAd()) {
+				return
+			}
+			index++
+		}
+	}
+}
+
+// AllXMLWithError returns an iterator for an XML document that captures any error.
+func AllXMLWithError(r io.Reader, errPtr *error) iter.Seq[*ClassAd] {
+	return func(yield func(*ClassAd) bool) {
+		reader := NewXMLReader(r)
+		for reader.Next() {
+			if !yield(reader.ClassAd()) {
+				return
+			}
+		}
+		if reader.Err() != nil && errPtr != nil {
+			*errPtr = reader.Err()
+		}
+	}
+}
+
+// XMLWriter writes ClassAds as an XML document in the layout HTCondor tools
+// produce: the XML declaration and DOCTYPE, a <classads> wrapper, and one <c>
+// per ad with an attribute per line. Call Close to finish the document.
+//
+// Example:
+//
+//	w := classad.NewXMLWriter(os.Stdout)
+//	for _, ad := range ads {
+//	    if err := w.Write(ad); err != nil {
+//	        return err
+//	    }
+//	}
+//	return w.Close()
+type XMLWriter struct {
+	// IncludePrivate writes private attributes (see IsPrivateAttribute), which
+	// are omitted by default.
+	IncludePrivate bool
+
+	enc     *xml.Encoder
+	started bool
+	closed  bool
+}
+
+// NewXMLWriter returns an XMLWriter writing to w.
+func NewXMLWriter(w io.Writer) *XMLWriter {
+	return &XMLWriter{enc: xml.NewEncoder(w)}
+}
+
+func (x *XMLWriter) begin() error {
+	if x.started {
+		return nil
+	}
+	x.started = true
+	for _, tok := range []xml.Token{
+		xml.ProcInst{Target: "xml", Inst: []byte(`version="1.0"`)},
+		xml.CharData("\n"),
+		xml.Directive(xmlDoctype),
+		xml.CharData("\n"),
+		xml.StartElement{Name: xml.Name{Local: "classads"}},
+		xml.CharData("\n"),
+	} {
+		if err := x.enc.EncodeToken(tok); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Write appends ad to the document.
+func (x *XMLWriter) Write(ad *ClassAd) error {
+	if x.closed {
+		return fmt.Errorf("classad: xml: write after Close")
+	}
+	if err := x.begin(); err != nil {
+		return err
+	}
+	if err := ad.marshalXML(x.enc, x.IncludePrivate, xmlIndent); err != nil {
+		return err
+	}
+	if err := x.enc.EncodeToken(xml.CharData("\n")); err != nil {
+		return err
+	}
+	return x.enc.Flush()
+}
+
+// Close ends the document (writing an empty <classads> if nothing was
+// written) and flushes it. It does not close the underlying writer.
+func (x *XMLWriter) Close() error {
+	if x.closed {
+		return nil
+	}
+	if err := x.begin(); err != nil {
+		return err
+	}
+	x.closed = true
+	if err := x.enc.EncodeToken(xml.EndElement{Name: xml.Name{Local: "classads"}}); err != nil {
+		return err
+	}
+	if err := x.enc.EncodeToken(xml.CharData("\n")); err != nil {
+		return err
+	}
+	return x.enc.Flush()
+}
@@ -0,0 +1,191 @@
+package classad
+
+import (
+	"encoding/xml"
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestMarshalXML_Values(t *testing.T) {
+	ad, err := Parse(`[
+		Cpus = 4;
+		Load = 0.5;
+		Owner = "alice <a&b>";
+		Idle = true;
+		Gone = undefined;
+		Bad = error;
+		Start = absTime("2024-05-01T00:00:00+01:00");
+		Limit = relTime("1:30");
+		Grace = relTime(90);
+		Tags = {1, "x", Cpus * 2};
+		Inner = [A = 1; B = A + 1];
+		Requirements = TARGET.Memory >= 1024 && Cpus > 0;
+		ClaimId = "secret"
+	]`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := xml.Marshal(ad)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := string(data)
+	for _, want := range []string{
+		`<a n="Cpus"><i>4</i></a>`,
+		`<a n="Load"><r>5.000000000000000E-01</r></a>`,
+		`<a n="Owner"><s>alice &lt;a&amp;b&gt;</s></a>`,
+		`<a n="Idle"><b v="t"></b></a>`,
+		`<a n="Gone"><un></un></a>`,
+		`<a n="Bad"><er></er></a>`,
+		`<a n="Start"><at>2024-05-01T00:00:00+01:00</at></a>`,
+		`<a n="Limit"><rt>00:01:30</rt></a>`,
+		`<a n="Grace"><rt>00:01:30</rt></a>`,
+		`<a n="Tags"><l><i>1</i><s>x</s><e>Cpus * 2</e></l></a>`,
+		`<a n="Inner"><c><a n="A"><i>1</i></a><a n="B"><e>A + 1</e></a></c></a>`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("xml.Marshal output missing %s\n%s", want, out)
+		}
+	}
+	if !strings.HasPrefix(out, "<c>") || strings.Contains(out, "ClaimId") {
+		t.Errorf("unexpected xml.Marshal output: %s", out)
+	}
+
+	var back ClassAd
+	if err := xml.Unmarshal(data, &back); err != nil {
+		t.Fatal(err)
+	}
+	again, err := xml.Marshal(&back)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(again) != out {
+		t.Errorf("XML round trip:\n got %s\nwant %s", again, out)
+	}
+	if v := back.EvaluateAttr("Requirements"); v.String() != "undefined" {
+		t.Errorf("Requirements = %v, want undefined (no TARGET)", v)
+	}
+	if start := back.EvaluateAttr("Start"); !start.IsAbsTime() {
+		t.Errorf("Start = %v, want an absolute time", start)
+	}
+}
+
+func TestUnmarshalXML_NonFiniteReals(t *testing.T) {
+	var ad ClassAd
+	err := xml.Unmarshal([]byte(`<c><a n="P"><r>INF</r></a><a n="N"><r>-INF</r></a><a n="Q"><r>NaN</r></a></c>`), &ad)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r, _ := ad.EvaluateAttrReal("P"); !math.IsInf(r, 1) {
+		t.Errorf("P = %v, want +Inf", r)
+	}
+	if r, _ := ad.EvaluateAttrReal("N"); !math.IsInf(r, -1) {
+		t.Errorf("N = %v, want -Inf", r)
+	}
+	if r, _ := ad.EvaluateAttrReal("Q"); !math.IsNaN(r) {
+		t.Errorf("Q = %v, want NaN", r)
+	}
+	data, err := xml.Marshal(&ad)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), `<r>-INF</r>`) {
+		t.Errorf("non-finite reals not written bare: %s", data)
+	}
+}
+
+func TestUnmarshalXML_Errors(t *testing.T) {
+	for _, doc := range []string{
+		`<c><a n="X"><q>1</q></a></c>`,
+		`<c><a n="X"><i>one</i></a></c>`,
+		`<c><a n="X"><e>1 +</e></a></c>`,
+		`<c><a n="X"><b v="maybe"/></a></c>`,
+		`<c><a><i>1</i></a></c>`,
+		`<c><a n="X"></a></c>`,
+		`<c><a n="X"><i>1</i><i>2</i></a></c>`,
+		`<c><x/></c>`,
+	} {
+		var ad ClassAd
+		if err := xml.Unmarshal([]byte(doc), &ad); err == nil {
+			t.Errorf("xml.Unmarshal(%s) succeeded, want error", doc)
+		}
+	}
+}
+
+func TestXMLWriterAndReader(t *testing.T) {
+	ad1, _ := Parse(`[Name = "slot1"; Cpus = 4; Requirements = MY.Cpus > 2]`)
+	ad2, _ := Parse(`[Name = "slot2"; Memory = 2048]`)
+
+	var buf strings.Builder
+	w := NewXMLWriter(&buf)
+	for _, ad := range []*ClassAd{ad1, ad2} {
+		if err := w.Write(ad); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	want := `<?xml version="1.0"?>
+<!DOCTYPE classads SYSTEM "classads.dtd">
+<classads>
+<c>
+    <a n="Cpus"><i>4</i></a>
+    <a n="Name"><s>slot1</s></a>
+    <a n="Requirements"><e>MY.Cpus &gt; 2</e></a>
+</c>
+<c>
+    <a n="Memory"><i>2048</i></a>
+    <a n="Name"><s>slot2</s></a>
+</c>
+</classads>
+`
+	if buf.String() != want {
+		t.Errorf("XMLWriter output:\n%s\nwant:\n%s", buf.String(), want)
+	}
+
+	var got []*ClassAd
+	var err error
+	for ad := range AllXMLWithError(strings.NewReader(buf.String()), &err) {
+		got = append(got, ad)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 || !got[0].Equal(ad1) || !got[1].Equal(ad2) {
+		t.Fatalf("read back %d ads: %v", len(got), got)
+	}
+	if ok, _ := got[0].EvaluateAttrBool("Requirements"); !ok {
+		t.Error("Requirements did not survive the round trip")
+	}
+}
+
+func TestXMLWriter_Empty(t *testing.T) {
+	var buf strings.Builder
+	if err := NewXMLWriter(&buf).Close(); err != nil {
+		t.Fatal(err)
+	}
+	reader := NewXMLReader(strings.NewReader(buf.String()))
+	if reader.Next() || reader.Err() != nil {
+		t.Errorf("empty document: Next() = true or Err() = %v", reader.Err())
+	}
+}
+
+func TestNewXMLReader_Errors(t *testing.T) {
+	reader := NewXMLReader(strings.NewReader(`<classads><c><a n="A"><i>1</i></a></c><c><a n="B"><i>x</i></a></c></classads>`))
+	if !reader.Next() {
+		t.Fatalf("expected first ClassAd, got error: %v", reader.Err())
+	}
+	if reader.Next() {
+		t.Fatal("expected failure on second ClassAd")
+	}
+	if reader.Err() == nil {
+		t.Fatal("expected an error")
+	}
+
+	reader = NewXMLReader(strings.NewReader(`<classads><c><a n="A"><i>1</i>`))
+	if reader.Next() || reader.Err() == nil {
+		t.Error("truncated document: expected an error")
+	}
+}
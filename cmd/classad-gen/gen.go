@@ -0,0 +1,105 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"strings"
+)
+
+// genOptions controls the generated source.
+type genOptions struct {
+	Package   string
+	Type      string
+	Required  bool // tag the attributes defined in every ad ",required"
+	Accessors bool // emit the typed view
+	Source    string
+}
+
+// generate returns the formatted Go source for fields, inferred from ads ads.
+func generate(fields []field, ads int, opts genOptions) ([]byte, error) {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "// Code generated by classad-gen from %d ads", ads)
+	if opts.Source != "" {
+		fmt.Fprintf(&b, " in %s", opts.Source)
+	}
+	b.WriteString("; DO NOT EDIT.\n\n")
+	fmt.Fprintf(&b, "package %s\n\n", opts.Package)
+
+	needImport := opts.Accessors
+	for _, f := range fields {
+		if strings.Contains(f.GoType, "classad.") {
+			needImport = true
+		}
+	}
+	if needImport {
+		b.WriteString("import \"github.com/PelicanPlatform/classad/classad\"\n\n")
+	}
+
+	fmt.Fprintf(&b, "// %s holds the attributes seen in the sample, for classad.Unmarshal and\n", opts.Type)
+	b.WriteString("// classad.Marshal. An attribute missing from some ads is a pointer, nil when\n")
+	b.WriteString("// the attribute is missing or undefined.\n")
+	fmt.Fprintf(&b, "type %s struct {\n", opts.Type)
+	for _, f := range fields {
+		always := f.Defined == ads
+		var notes []string
+		if !always {
+			notes = append(notes, fmt.Sprintf("defined in %d of %d ads", f.Defined, ads))
+		}
+		if f.Minority != "" {
+			notes = append(notes, "also "+f.Minority)
+		}
+		if len(notes) > 0 {
+			fmt.Fprintf(&b, "\t// %s\n", strings.Join(notes, "; "))
+		}
+		fmt.Fprintf(&b, "\t%s %s `classad:%q`\n", f.GoName, fieldType(f, always), fieldTag(f, always, opts.Required))
+	}
+	b.WriteString("}\n")
+
+	if opts.Accessors {
+		view := opts.Type + "Ad"
+		fmt.Fprintf(&b, "\n// %s is a typed view of a %s ad, evaluating each attribute on\n", view, opts.Type)
+		b.WriteString("// demand without unmarshaling the rest. Each accessor returns false when\n")
+		b.WriteString("// the attribute is missing or does not evaluate to its type.\n")
+		fmt.Fprintf(&b, "type %s struct {\n\tad *classad.ClassAd\n}\n\n", view)
+		fmt.Fprintf(&b, "// New%s returns the typed view of ad.\n", view)
+		fmt.Fprintf(&b, "func New%s(ad *classad.ClassAd) %s {\n\treturn %s{ad: ad}\n}\n", view, view, view)
+		for _, f := range fields {
+			fmt.Fprintf(&b, "\n// %s evaluates %s.\n", f.GoName, f.Attr)
+			fmt.Fprintf(&b, "func (a %s) %s() (%s, bool) {\n\treturn classad.GetAs[%s](a.ad, %q)\n}\n",
+				view, f.GoName, f.GoType, f.GoType, f.Attr)
+		}
+	}
+
+	src, err := format.Source(b.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("formatting generated source: %w", err)
+	}
+	return src, nil
+}
+
+// fieldTag returns the classad tag of a field: ",required" if the attribute
+// is always defined and required is set, ",omitempty" if it is not always
+// defined.
+func fieldTag(f field, always, required bool) string {
+	switch {
+	case always && required:
+		return f.Attr + ",required"
+	case !always:
+		return f.Attr + ",omitempty"
+	}
+	return f.Attr
+}
+
+// fieldType returns the struct field type: a pointer to a scalar for an
+// attribute not always defined.
+func fieldType(f field, always bool) string {
+	if always {
+		return f.GoType
+	}
+	switch f.Kind {
+	case kindBool, kindInt, kindReal, kindString:
+		return "*" + f.GoType
+	}
+	return f.GoType // already nil-able
+}
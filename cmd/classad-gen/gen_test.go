@@ -0,0 +1,191 @@
+package main
+
+import (
+	"fmt"
+	"go/parser"
+	"go/token"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/PelicanPlatform/classad/classad"
+	"github.com/PelicanPlatform/classad/cmd/internal/adinput"
+)
+
+func TestGenerate(t *testing.T) {
+	sample := `[Name = "slot1"; Slots = {1, undefined}; Cpus = 4; Load = 1; Tags = {"a", "b"}; Requirements = Cpus > 2; Owner = undefined; CVMFS_NIOERR = 0]
+[name = "slot2"; Cpus = 8; Load = 0.5; Tags = {}; Requirements = true; Owner = "alice"; Extra = [A = 1]]
+[Name = "slot3"; Cpus = "many"; Load = 2; Tags = {"c"}; Requirements = false]
+`
+	in := newInferrer()
+	if err := readAds(strings.NewReader(sample), "auto", in); err != nil {
+		t.Fatal(err)
+	}
+	if in.ads != 3 {
+		t.Fatalf("read %d ads, want 3", in.ads)
+	}
+
+	src, err := generate(in.fields(0), in.ads, genOptions{Package: "pool", Type: "Machine", Required: true, Accessors: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := parser.ParseFile(token.NewFileSet(), "machine.go", src, 0); err != nil {
+		t.Fatalf("generated source does not parse: %v\n%s", err, src)
+	}
+	got := string(src)
+	for _, want := range []string{
+		"Cpus *classad.Expr `classad:\"Cpus,required\"`",
+		"// also string in 1",
+		"CvmfsNioerr *int64 `classad:\"CVMFS_NIOERR,omitempty\"`",
+		"Extra *classad.ClassAd `classad:\"Extra,omitempty\"`",
+		"Load float64 `classad:\"Load,required\"`",
+		"Name string `classad:\"Name,required\"`",
+		"// defined in 1 of 3 ads",
+		"Owner *string `classad:\"Owner,omitempty\"`",
+		"Requirements *classad.Expr `classad:\"Requirements,required\"`",
+		"// also expression in 1",
+		"Tags []string `classad:\"Tags,required\"`",
+		"Slots []*int64 `classad:\"Slots,omitempty\"`",
+		"func (a MachineAd) Load() (float64, bool) {",
+		"return classad.GetAs[[]string](a.ad, \"Tags\")",
+	} {
+		if !strings.Contains(collapseSpace(got), collapseSpace(want)) {
+			t.Errorf("generated source lacks %q:\n%s", want, got)
+		}
+	}
+
+	src, err = generate(in.fields(0.9), in.ads, genOptions{Package: "pool", Type: "Machine"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := string(src); strings.Contains(got, "Owner") || !strings.Contains(got, "import") {
+		t.Errorf("min presence 0.9 kept Owner or dropped the import for Cpus:\n%s", got)
+	}
+}
+
+// collapseSpace reduces runs of blanks, which gofmt uses to align fields.
+func collapseSpace(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
+
+func TestReadAds(t *testing.T) {
+	for doc, want := range map[string]int{
+		"[A = 1]":          1,
+		" \n[\n  A = 1\n]": 1,
+		"[{\"A\": 1}]":     1,
+		"{\"A\": 1}":       1,
+		"A = 1\nB = 2":     1,
+	} {
+		in := newInferrer()
+		if err := readAds(strings.NewReader(doc), "auto", in); err != nil || in.ads != want || in.attrs["a"] == nil {
+			t.Errorf("%q: read %d ads, %v; want %d with A", doc, in.ads, err, want)
+		}
+	}
+}
+
+func TestGoName(t *testing.T) {
+	for attr, want := range map[string]string{
+		"Cpus":                   "Cpus",
+		"CVMFS_atlas_cern_ch_ID": "CvmfsAtlasCernChId",
+		"HAS_cx16":               "HasCx16",
+		"GLIDEIN_ToDie":          "GlideinToDie",
+		"_x":                     "X",
+		"2fa":                    "X2fa",
+	} {
+		if got := goName(attr); got != want {
+			t.Errorf("goName(%q) = %s, want %s", attr, got, want)
+		}
+	}
+	fields := []field{{Attr: "Foo_Bar"}, {Attr: "FooBar"}, {Attr: "foo_bar"}}
+	assignGoNames(fields)
+	if fields[0].GoName != "FooBar" || fields[1].GoName != "FooBar2" || fields[2].GoName != "FooBar3" {
+		t.Errorf("collisions named %s, %s, %s", fields[0].GoName, fields[1].GoName, fields[2].GoName)
+	}
+	fields = []field{{Attr: "_Foo"}, {Attr: "Foo"}, {Attr: "Foo2"}}
+	assignGoNames(fields)
+	if fields[0].GoName != "Foo" || fields[1].GoName != "Foo3" || fields[2].GoName != "Foo2" {
+		t.Errorf("collisions with Foo2 named %s, %s, %s", fields[0].GoName, fields[1].GoName, fields[2].GoName)
+	}
+}
+
+// TestRoundTripSample generates the struct for the pool sample and checks
+// that every sample ad unmarshals into it and marshals back to an ad that
+// unmarshals the same.
+func TestRoundTripSample(t *testing.T) {
+	f, err := os.Open("../../collections/vm/testdata/pool_sample.ads.gz")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	in := newInferrer()
+	var ads []*classad.ClassAd
+	if err := adinput.Read(f, "auto", func(ad *classad.ClassAd) error {
+		in.add(ad)
+		ads = append(ads, ad)
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	// The generated struct, built by reflection rather than compiled.
+	var sfs []reflect.StructField
+	for _, fl := range in.fields(0) {
+		always := fl.Defined == in.ads
+		sfs = append(sfs, reflect.StructField{
+			Name: fl.GoName,
+			Type: reflectType(t, fieldType(fl, always)),
+			Tag:  reflect.StructTag(fmt.Sprintf("classad:%q", fieldTag(fl, always, true))),
+		})
+	}
+	typ := reflect.StructOf(sfs)
+
+	for i, ad := range ads {
+		v := reflect.New(typ)
+		if err := classad.Unmarshal(ad.String(), v.Interface()); err != nil {
+			t.Errorf("ad %d: Unmarshal: %v", i, err)
+			continue
+		}
+		s, err := classad.Marshal(v.Interface())
+		if err != nil {
+			t.Errorf("ad %d: Marshal: %v", i, err)
+			continue
+		}
+		again := reflect.New(typ)
+		if err := classad.Unmarshal(s, again.Interface()); err != nil {
+			t.Errorf("ad %d: Unmarshal of %s: %v", i, s, err)
+			continue
+		}
+		if s2, _ := classad.Marshal(again.Interface()); s2 != s {
+			t.Errorf("ad %d: marshals as\n%s\nthen as\n%s", i, s, s2)
+		}
+	}
+}
+
+// reflectType returns the type a generated Go type names.
+func reflectType(t *testing.T, goType string) reflect.Type {
+	switch goType {
+	case "bool":
+		return reflect.TypeOf(false)
+	case "int64":
+		return reflect.TypeOf(int64(0))
+	case "float64":
+		return reflect.TypeOf(float64(0))
+	case "string":
+		return reflect.TypeOf("")
+	case "interface{}":
+		return reflect.TypeOf((*interface{})(nil)).Elem()
+	case "*classad.ClassAd":
+		return reflect.TypeOf((*classad.ClassAd)(nil))
+	case "*classad.Expr":
+		return reflect.TypeOf((*classad.Expr)(nil))
+	}
+	switch {
+	case strings.HasPrefix(goType, "[]"):
+		return reflect.SliceOf(reflectType(t, goType[2:]))
+	case strings.HasPrefix(goType, "*"):
+		return reflect.PointerTo(reflectType(t, goType[1:]))
+	}
+	t.Fatalf("unknown generated type %s", goType)
+	return nil
+}
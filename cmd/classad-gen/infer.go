@@ -0,0 +1,282 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/PelicanPlatform/classad/ast"
+	"github.com/PelicanPlatform/classad/classad"
+)
+
+// kind is the shape of one attribute value, as far as choosing a Go type goes.
+type kind int
+
+const (
+	kindUndefined kind = iota // undefined or error: says nothing about the type
+	kindBool
+	kindInt
+	kindReal
+	kindString
+	kindList
+	kindAd
+	kindExpr // anything that is not a literal
+	numKinds
+)
+
+var kindNames = [numKinds]string{"undefined", "bool", "int", "real", "string", "list", "ad", "expression"}
+
+func (k kind) String() string { return kindNames[k] }
+
+// attrStats tallies the values one attribute takes across the sample, the way
+// the collections schema derivation tallies wire literals.
+type attrStats struct {
+	spellings map[string]int
+	kinds     [numKinds]int
+	elems     [numKinds]int // the kinds of list elements
+}
+
+// inferrer accumulates attribute statistics over a sample of ads.
+type inferrer struct {
+	ads   int
+	attrs map[string]*attrStats // by lower-cased name
+}
+
+func newInferrer() *inferrer {
+	return &inferrer{attrs: make(map[string]*attrStats)}
+}
+
+// add tallies the attributes of ad.
+func (in *inferrer) add(ad *classad.ClassAd) {
+	in.ads++
+	for _, attr := range ad.AST().Attributes {
+		name, node := attr.Name, attr.Value
+		norm := strings.ToLower(name)
+		st := in.attrs[norm]
+		if st == nil {
+			st = &attrStats{spellings: make(map[string]int)}
+			in.attrs[norm] = st
+		}
+		st.spellings[name]++
+		k := kindOf(node)
+		st.kinds[k]++
+		if list, ok := node.(*ast.ListLiteral); ok {
+			for _, el := range list.Elements {
+				st.elems[kindOf(el)]++
+			}
+		}
+	}
+}
+
+// kindOf classifies an expression. A negated number literal is a number.
+func kindOf(node ast.Expr) kind {
+	if p, ok := node.(*ast.ParenExpr); ok {
+		return kindOf(p.Inner)
+	}
+	switch n := node.(type) {
+	case *ast.UndefinedLiteral, *ast.ErrorLiteral:
+		return kindUndefined
+	case *ast.BooleanLiteral:
+		return kindBool
+	case *ast.IntegerLiteral:
+		return kindInt
+	case *ast.RealLiteral:
+		return kindReal
+	case *ast.StringLiteral:
+		return kindString
+	case *ast.ListLiteral:
+		return kindList
+	case *ast.RecordLiteral:
+		return kindAd
+	case *ast.UnaryOp:
+		if n.Op == "-" || n.Op == "+" {
+			switch n.Expr.(type) {
+			case *ast.IntegerLiteral:
+				return kindInt
+			case *ast.RealLiteral:
+				return kindReal
+			}
+		}
+	}
+	return kindExpr
+}
+
+// field is one inferred struct field.
+type field struct {
+	Attr     string // attribute name, in its most common spelling
+	GoName   string
+	GoType   string // for a field always present
+	Kind     kind
+	Defined  int    // ads in which the attribute has a defined value
+	Minority string // other kinds seen, for a comment; "" if none
+}
+
+// fields returns the inferred fields for the attributes defined in at least
+// minPresence of the ads, sorted by attribute name.
+func (in *inferrer) fields(minPresence float64) []field {
+	var out []field
+	for _, st := range in.attrs {
+		defined := 0
+		for k := kindBool; k < numKinds; k++ {
+			defined += st.kinds[k]
+		}
+		if in.ads == 0 || float64(defined)/float64(in.ads) < minPresence {
+			continue
+		}
+		k := st.dominant()
+		goType, minority := st.goType(k), st.minority(k)
+		if minority != "" {
+			// A field of the dominant kind could not hold the other values.
+			k, goType = kindExpr, "*classad.Expr"
+		}
+		out = append(out, field{
+			Attr:     st.spelling(),
+			Kind:     k,
+			GoType:   goType,
+			Defined:  defined,
+			Minority: minority,
+		})
+	}
+	sort.Slice(out, func(i, j int) bool {
+		return strings.ToLower(out[i].Attr) < strings.ToLower(out[j].Attr)
+	})
+	assignGoNames(out)
+	return out
+}
+
+// spelling returns the most common spelling of the attribute's name, the
+// first in sort order among equals.
+func (st *attrStats) spelling() string {
+	best, bestN := "", 0
+	for s, n := range st.spellings {
+		if n > bestN || n == bestN && s < best {
+			best, bestN = s, n
+		}
+	}
+	return best
+}
+
+// dominant returns the kind covering the most defined values, counting ints
+// as reals when both appear (a float64 holds either). An attribute never
+// defined is an expression, which holds anything.
+func (st *attrStats) dominant() kind {
+	counts := st.kinds
+	if counts[kindReal] > 0 {
+		counts[kindReal] += counts[kindInt]
+		counts[kindInt] = 0
+	}
+	best, bestN := kindExpr, 0
+	for k := kindBool; k < numKinds; k++ {
+		if counts[k] > bestN {
+			best, bestN = k, counts[k]
+		}
+	}
+	return best
+}
+
+// minority describes the defined values not of kind k.
+func (st *attrStats) minority(k kind) string {
+	var parts []string
+	for o := kindBool; o < numKinds; o++ {
+		if o == k || o == kindInt && k == kindReal || st.kinds[o] == 0 {
+			continue
+		}
+		parts = append(parts, fmt.Sprintf("%s in %d", o, st.kinds[o]))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// goType returns the Go type for values of kind k.
+func (st *attrStats) goType(k kind) string {
+	switch k {
+	case kindBool:
+		return "bool"
+	case kindInt:
+		return "int64"
+	case kindReal:
+		return "float64"
+	case kindString:
+		return "string"
+	case kindList:
+		return "[]" + st.elemType()
+	case kindAd:
+		return "*classad.ClassAd"
+	}
+	return "*classad.Expr"
+}
+
+// elemType returns the element type of a list attribute: the one scalar kind
+// its elements take, or interface{} when they are mixed or not scalars. A
+// scalar element type is a pointer if some element is undefined.
+func (st *attrStats) elemType() string {
+	elem := kindUndefined
+	for k := kindBool; k < numKinds; k++ {
+		if st.elems[k] == 0 {
+			continue
+		}
+		switch {
+		case elem == kindUndefined:
+			elem = k
+		case elem == kindInt && k == kindReal:
+			elem = kindReal
+		default:
+			return "interface{}"
+		}
+	}
+	switch elem {
+	case kindBool, kindInt, kindReal, kindString:
+		if st.elems[kindUndefined] > 0 {
+			return "*" + st.goType(elem)
+		}
+		return st.goType(elem)
+	}
+	return "interface{}"
+}
+
+// assignGoNames gives each field an exported Go name derived from its
+// attribute name, numbering any collisions. A numbered name skips the names
+// other attributes take unnumbered, so Foo's second spelling does not become
+// a Foo2 that attribute Foo2 also needs.
+func assignGoNames(fields []field) {
+	bases := make([]string, len(fields))
+	unnumbered := make(map[string]bool)
+	for i := range fields {
+		bases[i] = goName(fields[i].Attr)
+		unnumbered[bases[i]] = true
+	}
+	used := make(map[string]bool)
+	for i, base := range bases {
+		name := base
+		for n := 2; used[name] || name != base && unnumbered[name]; n++ {
+			name = fmt.Sprintf("%s%d", base, n)
+		}
+		used[name] = true
+		fields[i].GoName = name
+	}
+}
+
+// goName converts an attribute name to an exported Go identifier: the name
+// is split at anything but letters and digits, each part is capitalized, and
+// all-caps parts are title-cased, so CVMFS_NIOERR becomes CvmfsNioerr.
+func goName(attr string) string {
+	parts := strings.FieldsFunc(attr, func(r rune) bool {
+		return !isLetter(r) && !isDigit(r)
+	})
+	var b strings.Builder
+	for _, p := range parts {
+		if len(p) > 1 && strings.ToUpper(p) == p {
+			p = strings.ToLower(p)
+		}
+		b.WriteString(strings.ToUpper(p[:1]))
+		b.WriteString(p[1:])
+	}
+	name := b.String()
+	if name == "" || isDigit(rune(name[0])) {
+		name = "X" + name
+	}
+	return name
+}
+
+func isLetter(r rune) bool { return r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' }
+
+func isDigit(r rune) bool { return r >= '0' && r <= '9' }
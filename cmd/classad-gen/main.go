@@ -0,0 +1,108 @@
+// Package main provides classad-gen, a tool that infers a typed Go struct from
+// a sample of ClassAds.
+//
+// Each attribute's Go type is the kind of its values across the sample (ints
+// and reals together become float64; anything not a literal, or values of
+// more than one kind, become *classad.Expr), and an attribute not defined in
+// every ad becomes an optional pointer field. The output is a struct with classad tags for
+// classad.Unmarshal, plus a typed view with an accessor per attribute.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/PelicanPlatform/classad/classad"
+	"github.com/PelicanPlatform/classad/cmd/internal/adinput"
+)
+
+func main() {
+	pkg := flag.String("package", "main", "Package name of the generated file")
+	typeName := flag.String("type", "Ad", "Name of the generated struct")
+	output := flag.String("o", "", "Write the generated file here instead of standard output")
+	formatName := flag.String("format", "auto", "Input format: auto, new, old or json")
+	minPresence := flag.Float64("min-presence", 0, "Skip attributes defined in less than this fraction of the ads (0-1)")
+	required := flag.Bool("required", false, "Tag attributes defined in every ad ,required")
+	accessors := flag.Bool("accessors", true, "Also generate a typed view with an accessor per attribute")
+	help := flag.Bool("help", false, "Show usage information")
+	flag.BoolVar(help, "h", false, "Show usage information (shorthand)")
+
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s [OPTIONS] [file ...]\n\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "Generate a Go struct for classad.Unmarshal from sample ClassAds.\n")
+		fmt.Fprintf(os.Stderr, "Reads standard input when no file is given; gzipped input is detected.\n\n")
+		fmt.Fprintf(os.Stderr, "Options:\n")
+		flag.PrintDefaults()
+		fmt.Fprintf(os.Stderr, "\nExamples:\n")
+		fmt.Fprintf(os.Stderr, "  condor_status -l | %s -package pool -type Machine -o machine.go\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s -type Job -min-presence 0.5 jobs.json\n", os.Args[0])
+	}
+
+	flag.Parse()
+
+	if *help {
+		flag.Usage()
+		os.Exit(0)
+	}
+
+	in := newInferrer()
+	sources := flag.Args()
+	if len(sources) == 0 {
+		if err := readAds(os.Stdin, *formatName, in); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: <stdin>: %v\n", err)
+			os.Exit(1)
+		}
+	}
+	for _, path := range sources {
+		if err := readFile(path, *formatName, in); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %s: %v\n", path, err)
+			os.Exit(1)
+		}
+	}
+	if in.ads == 0 {
+		fmt.Fprintf(os.Stderr, "Error: no ads read\n")
+		os.Exit(1)
+	}
+
+	src, err := generate(in.fields(*minPresence), in.ads, genOptions{
+		Package:   *pkg,
+		Type:      *typeName,
+		Required:  *required,
+		Accessors: *accessors,
+		Source:    strings.Join(sources, ", "),
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	if *output == "" {
+		_, err = os.Stdout.Write(src)
+	} else {
+		err = os.WriteFile(*output, src, 0o644)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// readFile adds the ads of the file at path to in.
+func readFile(path, formatName string, in *inferrer) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return readAds(f, formatName, in)
+}
+
+// readAds adds the ads read from r to in (see adinput.Read).
+func readAds(r io.Reader, formatName string, in *inferrer) error {
+	return adinput.Read(r, formatName, func(ad *classad.ClassAd) error {
+		in.add(ad)
+		return nil
+	})
+}
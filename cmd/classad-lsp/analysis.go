@@ -0,0 +1,502 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"slices"
+	"sort"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+
+	"github.com/PelicanPlatform/classad/ast"
+	"github.com/PelicanPlatform/classad/classad"
+	"github.com/PelicanPlatform/classad/classad/lint"
+	"github.com/PelicanPlatform/classad/parser"
+)
+
+// kind is what a document holds.
+type kind int
+
+const (
+	newAd   kind = iota // "[ A = 1; ... ]"
+	oldAd               // one "A = 1" per line
+	exprDoc             // a lone expression
+)
+
+func (k kind) String() string {
+	switch k {
+	case newAd:
+		return "ClassAd"
+	case oldAd:
+		return "old-format ClassAd"
+	}
+	return "expression"
+}
+
+// detect decides what a document holds: an expression if its language or
+// file extension says so, else a new-format ad if it opens with '[', else an
+// old-format ad if a line looks like an assignment, else an expression.
+func detect(uri, languageID, text string) kind {
+	if languageID == "classad-expr" || path.Ext(uri) == ".expr" {
+		return exprDoc
+	}
+	rest := skipTrivia(text)
+	if strings.HasPrefix(rest, "[") {
+		return newAd
+	}
+	for _, line := range strings.Split(text, "\n") {
+		name, value, ok := strings.Cut(line, "=")
+		if ok && isName(strings.TrimSpace(name)) && !strings.HasPrefix(value, "=") {
+			return oldAd
+		}
+	}
+	return exprDoc
+}
+
+// skipTrivia returns s past leading blanks and comments.
+func skipTrivia(s string) string {
+	for {
+		s = strings.TrimLeft(s, " \t\r\n")
+		switch {
+		case strings.HasPrefix(s, "//"), strings.HasPrefix(s, "#"):
+			_, s, _ = strings.Cut(s, "\n")
+		case strings.HasPrefix(s, "/*"):
+			_, s, _ = strings.Cut(s, "*/")
+		default:
+			return s
+		}
+	}
+}
+
+func isName(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i, r := range s {
+		if !(r == '_' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || i > 0 && r >= '0' && r <= '9') {
+			return false
+		}
+	}
+	return true
+}
+
+// def is a top-level attribute definition; its offsets span the name.
+type def struct {
+	name       string
+	start, end int
+	assign     *ast.AttributeAssignment
+}
+
+// ref is an attribute reference; its offsets span the reference, scope
+// included.
+type ref struct {
+	name       string
+	scope      ast.AttributeScope
+	start, end int
+}
+
+// call is a function call; its offsets span the function name.
+type call struct {
+	name       string
+	start, end int
+}
+
+// analysis is everything the server knows about one version of a document.
+// An ad with syntax errors still has the attributes the parser recovered, but
+// is neither linted nor evaluated.
+type analysis struct {
+	text  string
+	lines []int // byte offsets of line starts
+	kind  kind
+	ok    bool // the document parsed without errors
+
+	ad    *classad.ClassAd // the ad to evaluate in; nil for an expression
+	defs  []def
+	refs  []ref
+	calls []call
+	diags []diagnostic
+}
+
+func analyze(uri, languageID, text string) *analysis {
+	a := &analysis{text: text, lines: []int{0}, kind: detect(uri, languageID, text)}
+	for i := 0; i < len(text); i++ {
+		if text[i] == '\n' {
+			a.lines = append(a.lines, i+1)
+		}
+	}
+
+	var (
+		root ast.Node
+		pos  ast.Positions
+		errs parser.ErrorList
+	)
+	switch a.kind {
+	case newAd:
+		root, pos, errs = parser.ParseRecovering(text)
+	case oldAd:
+		root, pos, errs = parser.ParseOldRecovering(text)
+	default:
+		var err error
+		if root, pos, err = parser.ParseExprWithPositions(text); err != nil {
+			a.parseError(err)
+			return a
+		}
+	}
+	for _, se := range errs {
+		a.parseError(se)
+	}
+	a.ok = len(errs) == 0
+
+	if ad, isAd := root.(*ast.ClassAd); isAd {
+		for _, attr := range ad.Attributes {
+			s, _ := pos.Span(attr)
+			a.defs = append(a.defs, def{name: attr.Name, start: s.Start.Offset, end: a.nameEnd(s.Start.Offset, attr.Name), assign: attr})
+		}
+		// FromAST adopts and reorders the attribute list; give it its own.
+		a.ad = classad.FromAST(&ast.ClassAd{Attributes: slices.Clone(ad.Attributes)})
+	}
+	walk(root, func(n ast.Node) {
+		s, ok := pos.Span(n)
+		if !ok {
+			return
+		}
+		switch n := n.(type) {
+		case *ast.AttributeReference:
+			a.refs = append(a.refs, ref{name: n.Name, scope: n.Scope, start: s.Start.Offset, end: s.End.Offset})
+		case *ast.FunctionCall:
+			a.calls = append(a.calls, call{name: n.Name, start: s.Start.Offset, end: s.Start.Offset + len(n.Name)})
+		}
+	})
+
+	if !a.ok {
+		// The attributes lost to syntax errors would show as undefined.
+		return a
+	}
+	linted := make(map[string]bool)
+	for _, d := range lint.Node(root, pos, nil) {
+		linted[strings.ToLower(d.Attr)] = true
+		a.diags = append(a.diags, diagnostic{
+			Range:    a.span(d.Span.Start.Offset, d.Span.End.Offset),
+			Severity: severity(d.Severity),
+			Code:     string(d.Check),
+			Source:   "classad-lint",
+			Message:  d.Message,
+		})
+	}
+	a.evalDiagnostics(linted)
+	return a
+}
+
+// nameEnd is the end of the attribute name written at start: as given, or
+// quoted.
+func (a *analysis) nameEnd(start int, name string) int {
+	if strings.HasPrefix(a.text[start:], name) {
+		return start + len(name)
+	}
+	if q := ast.QuoteAttributeName(name); strings.HasPrefix(a.text[start:], q) {
+		return start + len(q)
+	}
+	return start
+}
+
+func (a *analysis) parseError(err error) {
+	var se *parser.SyntaxError
+	if !errors.As(err, &se) {
+		a.diags = append(a.diags, diagnostic{Range: a.span(0, 0), Severity: severityError, Source: "classad", Message: err.Error()})
+		return
+	}
+	end := se.Offset
+	if se.Got != "" && se.Got != "EOF" {
+		end += len(se.Got)
+	}
+	a.diags = append(a.diags, diagnostic{
+		Range:    a.span(se.Offset, min(end, len(a.text))),
+		Severity: severityError,
+		Source:   "classad",
+		Message:  se.Msg,
+	})
+}
+
+func severity(s lint.Severity) int {
+	if s == lint.Error {
+		return severityError
+	}
+	return severityWarning
+}
+
+// evalDiagnostics warns about each attribute that evaluates to error where
+// the linter has not already said why (linted holds the lower-cased names it
+// reported on).
+func (a *analysis) evalDiagnostics(linted map[string]bool) {
+	if a.ad == nil {
+		return
+	}
+	for _, df := range a.defs {
+		if linted[strings.ToLower(df.name)] || !a.ad.EvaluateAttr(df.name).IsError() {
+			continue
+		}
+		a.diags = append(a.diags, diagnostic{
+			Range:    a.span(df.start, df.end),
+			Severity: severityWarning,
+			Code:     "eval-error",
+			Source:   "classad",
+			Message:  df.name + " evaluates to error",
+		})
+	}
+}
+
+// walk calls f for n and every node below it.
+func walk(n ast.Node, f func(ast.Node)) {
+	if n == nil {
+		return
+	}
+	f(n)
+	switch n := n.(type) {
+	case *ast.ClassAd:
+		for _, attr := range n.Attributes {
+			walk(attr, f)
+		}
+	case *ast.AttributeAssignment:
+		walk(n.Value, f)
+	case *ast.BinaryOp:
+		walk(n.Left, f)
+		walk(n.Right, f)
+	case *ast.UnaryOp:
+		walk(n.Expr, f)
+	case *ast.ListLiteral:
+		for _, e := range n.Elements {
+			walk(e, f)
+		}
+	case *ast.RecordLiteral:
+		if n.ClassAd != nil {
+			walk(n.ClassAd, f)
+		}
+	case *ast.FunctionCall:
+		for _, e := range n.Args {
+			walk(e, f)
+		}
+	case *ast.ConditionalExpr:
+		walk(n.Condition, f)
+		walk(n.TrueExpr, f)
+		walk(n.FalseExpr, f)
+	case *ast.ElvisExpr:
+		walk(n.Left, f)
+		walk(n.Right, f)
+	case *ast.SelectExpr:
+		walk(n.Record, f)
+	case *ast.SubscriptExpr:
+		walk(n.Container, f)
+		walk(n.Index, f)
+	case *ast.ParenExpr:
+		walk(n.Inner, f)
+	}
+}
+
+// position converts a byte offset to an LSP position.
+func (a *analysis) position(offset int) position {
+	offset = min(max(offset, 0), len(a.text))
+	line := sort.Search(len(a.lines), func(i int) bool { return a.lines[i] > offset }) - 1
+	n := 0
+	for _, r := range a.text[a.lines[line]:offset] {
+		n += utf16.RuneLen(r)
+	}
+	return position{Line: line, Character: n}
+}
+
+// offset converts an LSP position to a byte offset, clamped to its line.
+func (a *analysis) offset(p position) int {
+	if p.Line < 0 {
+		return 0
+	}
+	if p.Line >= len(a.lines) {
+		return len(a.text)
+	}
+	off := a.lines[p.Line]
+	for n := 0; n < p.Character && off < len(a.text) && a.text[off] != '\n'; {
+		r, size := utf8.DecodeRuneInString(a.text[off:])
+		n += utf16.RuneLen(r)
+		off += size
+	}
+	return off
+}
+
+func (a *analysis) span(start, end int) lspRange {
+	return lspRange{Start: a.position(start), End: a.position(end)}
+}
+
+// wordAt returns the extent of the identifier around offset, a scope prefix
+// ("MY.") included.
+func (a *analysis) wordAt(offset int) (start, end int) {
+	start, end = offset, offset
+	for start > 0 && isWordByte(a.text[start-1]) {
+		start--
+	}
+	for end < len(a.text) && isWordByte(a.text[end]) {
+		end++
+	}
+	return start, end
+}
+
+func isWordByte(c byte) bool {
+	return c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9'
+}
+
+func (a *analysis) lookupDef(name string) (def, bool) {
+	// The last definition is the one in effect.
+	for i := len(a.defs) - 1; i >= 0; i-- {
+		if strings.EqualFold(a.defs[i].name, name) {
+			return a.defs[i], true
+		}
+	}
+	return def{}, false
+}
+
+// refAt returns the reference at offset.
+func (a *analysis) refAt(offset int) (ref, bool) {
+	for _, r := range a.refs {
+		if r.start <= offset && offset <= r.end {
+			return r, true
+		}
+	}
+	return ref{}, false
+}
+
+func (a *analysis) defAt(offset int) (def, bool) {
+	for _, d := range a.defs {
+		if d.start <= offset && offset <= d.end {
+			return d, true
+		}
+	}
+	return def{}, false
+}
+
+func (a *analysis) callAt(offset int) (call, bool) {
+	for _, c := range a.calls {
+		if c.start <= offset && offset <= c.end {
+			return c, true
+		}
+	}
+	return call{}, false
+}
+
+// resolves reports whether r names an attribute of this ad: MY.x or an
+// unscoped x it defines.
+func (r ref) resolves() bool {
+	return r.scope == ast.NoScope || r.scope == ast.MyScope
+}
+
+// definition returns the definition the reference at offset refers to.
+func (a *analysis) definition(offset int) (def, bool) {
+	if r, ok := a.refAt(offset); ok && r.resolves() {
+		return a.lookupDef(r.name)
+	}
+	return def{}, false
+}
+
+var keywords = []string{"true", "false", "undefined", "error", "MY", "TARGET", "PARENT", "is", "isnt"}
+
+// completions returns the completion items at offset: after "MY." the ad's
+// attributes; after "TARGET." nothing this ad knows; otherwise the ad's
+// attributes, the built-in functions and the keywords. defs stands in for
+// the document's own when it does not parse, so completion keeps working
+// mid-edit.
+func (a *analysis) completions(offset int, defs []def) []completionItem {
+	start, _ := a.wordAt(offset)
+	scope := ""
+	if start > 0 && a.text[start-1] == '.' {
+		s, _ := a.wordAt(start - 1)
+		scope = strings.ToUpper(a.text[s : start-1])
+	}
+	items := []completionItem{}
+	if scope == "TARGET" || scope == "PARENT" {
+		return items
+	}
+	seen := make(map[string]bool)
+	for _, d := range defs {
+		if k := strings.ToLower(d.name); !seen[k] {
+			seen[k] = true
+			items = append(items, completionItem{Label: d.name, Kind: kindVariable, Detail: d.assign.Value.String()})
+		}
+	}
+	if scope != "" {
+		return items
+	}
+	for _, name := range classad.BuiltinFunctions() {
+		items = append(items, completionItem{Label: name, Kind: kindFunction, Detail: signature(name)})
+	}
+	for _, kw := range keywords {
+		items = append(items, completionItem{Label: kw, Kind: kindKeyword})
+	}
+	return items
+}
+
+// signature describes the arguments function name takes.
+func signature(name string) string {
+	arity, ok := classad.FunctionArity(name)
+	if !ok {
+		return ""
+	}
+	switch {
+	case arity.Max < 0:
+		return fmt.Sprintf("%s(...): at least %d arguments", name, arity.Min)
+	case arity.Min == arity.Max:
+		return fmt.Sprintf("%s(...): %d arguments", name, arity.Min)
+	}
+	return fmt.Sprintf("%s(...): %d to %d arguments", name, arity.Min, arity.Max)
+}
+
+// hover describes what is at offset: an attribute, with its expression,
+// value and references, or a function.
+func (a *analysis) hover(offset int) *hover {
+	var (
+		name       string
+		start, end int
+	)
+	if d, ok := a.defAt(offset); ok {
+		name, start, end = d.name, d.start, d.end
+	} else if r, ok := a.refAt(offset); ok {
+		if !r.resolves() {
+			return a.markdown(r.start, r.end, fmt.Sprintf("`%s`: an attribute of the %s ad", r.name, scopeName(r.scope)))
+		}
+		name, start, end = r.name, r.start, r.end
+	} else if c, ok := a.callAt(offset); ok {
+		text := "`" + c.name + "`: unknown function"
+		if sig := signature(c.name); sig != "" {
+			text = "`" + sig + "`"
+		}
+		return a.markdown(c.start, c.end, text)
+	} else {
+		return nil
+	}
+
+	d, ok := a.lookupDef(name)
+	if !ok || a.ad == nil {
+		return a.markdown(start, end, fmt.Sprintf("`%s`: not defined in this ad", name))
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "```classad\n%s = %s\n```\n", d.name, d.assign.Value)
+	fmt.Fprintf(&b, "\nValue: `%s`", classad.LiteralExpr(a.ad.EvaluateAttr(d.name)))
+	if expr, ok := a.ad.Lookup(d.name); ok {
+		if refs := a.ad.InternalRefs(expr); len(refs) > 0 {
+			fmt.Fprintf(&b, "\n\nReferences: %s", strings.Join(refs, ", "))
+		}
+		if refs := a.ad.ExternalRefs(expr); len(refs) > 0 {
+			fmt.Fprintf(&b, "\n\nExternal references: %s", strings.Join(refs, ", "))
+		}
+	}
+	return a.markdown(start, end, b.String())
+}
+
+func scopeName(s ast.AttributeScope) string {
+	if s == ast.TargetScope {
+		return "target"
+	}
+	return "parent"
+}
+
+func (a *analysis) markdown(start, end int, text string) *hover {
+	r := a.span(start, end)
+	return &hover{Contents: markupContent{Kind: "markdown", Value: text}, Range: &r}
+}
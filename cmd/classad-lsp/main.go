@@ -0,0 +1,60 @@
+// Package main provides classad-lsp, a Language Server Protocol server for
+// ClassAds. An editor runs it and talks to it over standard input and output.
+//
+// It serves new-format ad files ("[ A = 1; ... ]", usually .classad),
+// old-format ad files (one "A = 1" per line, as condor_status -l prints) and
+// lone expressions (.expr files, or the classad-expr language). It offers:
+//
+//   - diagnostics: parse errors, the classad/lint checks, and attributes that
+//     evaluate to error
+//   - completion of the ad's attributes, the built-in functions and keywords
+//   - hover: an attribute's expression, evaluated value and references, or a
+//     function's arguments
+//   - go-to-definition from an attribute reference to its definition in the ad
+//
+// Documents are synchronized in full on every change.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+)
+
+func main() {
+	logPath := flag.String("log", "", "Append a log of the session to this file")
+	help := flag.Bool("help", false, "Show usage information")
+	flag.BoolVar(help, "h", false, "Show usage information (shorthand)")
+
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s [OPTIONS]\n\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "Serve the Language Server Protocol for ClassAds over standard input and output.\n\n")
+		fmt.Fprintf(os.Stderr, "Options:\n")
+		flag.PrintDefaults()
+	}
+
+	flag.Parse()
+
+	if *help {
+		flag.Usage()
+		os.Exit(0)
+	}
+
+	logger := log.New(io.Discard, "", log.LstdFlags)
+	if *logPath != "" {
+		f, err := os.OpenFile(*logPath, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		defer f.Close()
+		logger.SetOutput(f)
+	}
+
+	if err := newServer(os.Stdin, os.Stdout, logger).run(); err != nil {
+		logger.Printf("exiting: %v", err)
+		os.Exit(1)
+	}
+}
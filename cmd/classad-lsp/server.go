@@ -0,0 +1,230 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"slices"
+)
+
+// document is an open document: its latest analysis, and the attribute
+// definitions of the last version that parsed without errors, for completion
+// while the current one has some.
+type document struct {
+	languageID string
+	cur        *analysis
+	lastDefs   []def
+}
+
+// server answers one client's requests, one at a time, in order.
+type server struct {
+	in  *bufio.Reader
+	out io.Writer
+	log *log.Logger
+
+	docs        map[string]*document
+	initialized bool
+	shutdown    bool
+	writeErr    error // the first failure to write a notification
+}
+
+func newServer(in io.Reader, out io.Writer, logger *log.Logger) *server {
+	return &server{in: bufio.NewReader(in), out: out, log: logger, docs: make(map[string]*document)}
+}
+
+// errExitWithoutShutdown is returned by run when the client sends exit
+// without shutdown first; the process then exits with status 1, as the
+// protocol asks.
+var errExitWithoutShutdown = errors.New("exit without shutdown")
+
+// run serves until the client sends exit or closes the connection.
+func (s *server) run() error {
+	for {
+		body, err := readMessage(s.in)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		var msg message
+		if err := json.Unmarshal(body, &msg); err != nil {
+			if err := s.replyError(nil, codeParseError, err.Error()); err != nil {
+				return err
+			}
+			continue
+		}
+		if msg.Method == "exit" {
+			if !s.shutdown {
+				return errExitWithoutShutdown
+			}
+			return nil
+		}
+		if err := s.handle(&msg); err != nil {
+			return err
+		}
+	}
+}
+
+// handle dispatches one message. It returns an error only when writing to
+// the client fails.
+func (s *server) handle(msg *message) error {
+	isRequest := len(msg.ID) > 0
+	if !s.initialized && msg.Method != "initialize" {
+		if isRequest {
+			return s.replyError(msg.ID, codeNotInitialized, "server not initialized")
+		}
+		return nil
+	}
+	result, rpcErr := s.dispatch(msg)
+	if s.writeErr != nil {
+		return s.writeErr
+	}
+	if !isRequest {
+		if rpcErr != nil {
+			s.log.Printf("%s: %s", msg.Method, rpcErr.Message)
+		}
+		return nil
+	}
+	if rpcErr != nil {
+		return s.replyError(msg.ID, rpcErr.Code, rpcErr.Message)
+	}
+	return writeMessage(s.out, response{JSONRPC: "2.0", ID: msg.ID, Result: result})
+}
+
+func (s *server) dispatch(msg *message) (any, *rpcError) {
+	switch msg.Method {
+	case "initialize":
+		s.initialized = true
+		return map[string]any{
+			"capabilities": map[string]any{
+				"textDocumentSync":   1, // full text on every change
+				"completionProvider": map[string]any{"triggerCharacters": []string{"."}},
+				"hoverProvider":      true,
+				"definitionProvider": true,
+			},
+			"serverInfo": map[string]string{"name": "classad-lsp"},
+		}, nil
+	case "initialized", "$/cancelRequest", "$/setTrace", "workspace/didChangeConfiguration":
+		return nil, nil
+	case "shutdown":
+		s.shutdown = true
+		return nil, nil
+
+	case "textDocument/didOpen":
+		var p didOpenParams
+		if err := json.Unmarshal(msg.Params, &p); err != nil {
+			return nil, invalidParams(err)
+		}
+		d := &document{languageID: p.TextDocument.LanguageID}
+		s.docs[p.TextDocument.URI] = d
+		s.update(p.TextDocument.URI, d, p.TextDocument.Text)
+		return nil, nil
+	case "textDocument/didChange":
+		var p didChangeParams
+		if err := json.Unmarshal(msg.Params, &p); err != nil {
+			return nil, invalidParams(err)
+		}
+		d, ok := s.docs[p.TextDocument.URI]
+		if !ok || len(p.ContentChanges) == 0 {
+			return nil, nil
+		}
+		s.update(p.TextDocument.URI, d, p.ContentChanges[len(p.ContentChanges)-1].Text)
+		return nil, nil
+	case "textDocument/didClose":
+		var p didCloseParams
+		if err := json.Unmarshal(msg.Params, &p); err != nil {
+			return nil, invalidParams(err)
+		}
+		delete(s.docs, p.TextDocument.URI)
+		s.publish(p.TextDocument.URI, nil)
+		return nil, nil
+
+	case "textDocument/completion":
+		d, _, off, rpcErr := s.at(msg)
+		if d == nil {
+			return []completionItem{}, rpcErr
+		}
+		defs := d.cur.defs
+		if !d.cur.ok {
+			// Those lost to the errors may be among the last version's.
+			defs = append(slices.Clone(defs), d.lastDefs...)
+		}
+		return d.cur.completions(off, defs), nil
+	case "textDocument/hover":
+		d, _, off, rpcErr := s.at(msg)
+		if d == nil {
+			return nil, rpcErr
+		}
+		if h := d.cur.hover(off); h != nil {
+			return h, nil
+		}
+		return nil, nil
+	case "textDocument/definition":
+		d, uri, off, rpcErr := s.at(msg)
+		if d == nil {
+			return nil, rpcErr
+		}
+		def, ok := d.cur.definition(off)
+		if !ok {
+			return nil, nil
+		}
+		return location{URI: uri, Range: d.cur.span(def.start, def.end)}, nil
+	}
+	return nil, &rpcError{Code: codeMethodNotFound, Message: "method not supported: " + msg.Method}
+}
+
+// update analyzes a new version of d and publishes its diagnostics.
+func (s *server) update(uri string, d *document, text string) {
+	d.cur = analyze(uri, d.languageID, text)
+	if d.cur.ok {
+		d.lastDefs = d.cur.defs
+	}
+	s.publish(uri, d.cur.diags)
+}
+
+// publish sends the diagnostics of uri, replacing those sent before.
+func (s *server) publish(uri string, diags []diagnostic) {
+	if diags == nil {
+		diags = []diagnostic{}
+	}
+	err := writeMessage(s.out, notification{
+		JSONRPC: "2.0",
+		Method:  "textDocument/publishDiagnostics",
+		Params:  publishDiagnosticsParams{URI: uri, Diagnostics: diags},
+	})
+	if err != nil && s.writeErr == nil {
+		s.writeErr = fmt.Errorf("writing diagnostics: %w", err)
+	}
+}
+
+// at returns the document, its URI and the byte offset a position request
+// names; a nil document with a nil error means the document is not open.
+func (s *server) at(msg *message) (*document, string, int, *rpcError) {
+	var p positionParams
+	if err := json.Unmarshal(msg.Params, &p); err != nil {
+		return nil, "", 0, invalidParams(err)
+	}
+	d, ok := s.docs[p.TextDocument.URI]
+	if !ok {
+		return nil, "", 0, nil
+	}
+	return d, p.TextDocument.URI, d.cur.offset(p.Position), nil
+}
+
+func invalidParams(err error) *rpcError {
+	return &rpcError{Code: codeInvalidParams, Message: err.Error()}
+}
+
+func (s *server) replyError(id json.RawMessage, code int, text string) error {
+	if id == nil {
+		id = json.RawMessage("null")
+	}
+	if err := writeMessage(s.out, errorResponse{JSONRPC: "2.0", ID: id, Error: rpcError{Code: code, Message: text}}); err != nil {
+		return fmt.Errorf("writing response: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,312 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestDetect(t *testing.T) {
+	tests := []struct {
+		uri, lang, text string
+		want            kind
+	}{
+		{"file:///a.ad", "", "// comment\n[ a = 1 ]", newAd},
+		{"file:///a.ad", "", "MyType = \"Job\"\nOwner = \"alice\"\n", oldAd},
+		{"file:///a.ad", "", "Memory > 1024 && Cpus >= 2", exprDoc},
+		{"file:///a.ad", "", "a == b", exprDoc},
+		{"file:///a.expr", "", "a = 1", exprDoc},
+		{"untitled:1", "classad-expr", "[ a = 1 ]", exprDoc},
+	}
+	for _, tt := range tests {
+		if got := detect(tt.uri, tt.lang, tt.text); got != tt.want {
+			t.Errorf("detect(%q, %q, %q) = %v, want %v", tt.uri, tt.lang, tt.text, got, tt.want)
+		}
+	}
+}
+
+// at returns the offset of the n'th (from 0) occurrence of sub in text.
+func at(t *testing.T, text, sub string, n int) int {
+	t.Helper()
+	off := -1
+	for i := 0; i <= n; i++ {
+		j := strings.Index(text[off+1:], sub)
+		if j < 0 {
+			t.Fatalf("%q: no occurrence %d of %q", text, n, sub)
+		}
+		off += j + 1
+	}
+	return off
+}
+
+func TestDiagnostics(t *testing.T) {
+	a := analyze("file:///a.ad", "", "[\n  a = 1;\n  b = (2\n]")
+	if a.ok || len(a.diags) != 1 || a.diags[0].Severity != severityError || a.diags[0].Range.Start.Line != 3 {
+		t.Errorf("syntax error diagnostics = %+v", a.diags)
+	}
+	a = analyze("file:///a.ad", "", "[\n  a = 1 +;\n  b = 2;\n  c = (3;\n]")
+	if len(a.diags) != 2 || a.diags[0].Range.Start.Line != 1 || a.diags[1].Range.Start.Line != 3 {
+		t.Errorf("diagnostics for two syntax errors = %+v", a.diags)
+	}
+	if _, ok := a.lookupDef("b"); !ok {
+		t.Error("the attribute between the errors was not recovered")
+	}
+
+	a = analyze("file:///a.ad", "", "[\n  a = nosuchfunc(1);\n  b = \"x\" + 1;\n  c = b\n]")
+	if !a.ok {
+		t.Fatalf("did not parse: %+v", a.diags)
+	}
+	codes := map[string]diagnostic{}
+	for _, d := range a.diags {
+		codes[d.Code] = d
+	}
+	if d, ok := codes["unknown-function"]; !ok || d.Severity != severityError || d.Range.Start.Line != 1 {
+		t.Errorf("no unknown-function diagnostic on line 1: %+v", a.diags)
+	}
+	var evalErrors []string
+	for _, d := range a.diags {
+		if d.Code == "eval-error" {
+			evalErrors = append(evalErrors, fmt.Sprintf("%d:%d %s", d.Range.Start.Line, d.Range.Start.Character, d.Message))
+		}
+	}
+	// a evaluates to error too, but the linter has said why.
+	if want := []string{"2:2 b evaluates to error", "3:2 c evaluates to error"}; !slices.Equal(evalErrors, want) {
+		t.Errorf("eval-error diagnostics = %q, want %q", evalErrors, want)
+	}
+}
+
+func TestPositions(t *testing.T) {
+	a := analyze("file:///a.ad", "", "[ s = \"é😀\"; t = s ]")
+	off := at(t, a.text, "t", 0)
+	p := a.position(off)
+	if p != (position{0, 13}) {
+		t.Errorf("position(%d) = %+v, want character 13", off, p)
+	}
+	if got := a.offset(p); got != off {
+		t.Errorf("offset(%+v) = %d, want %d", p, got, off)
+	}
+	if got := a.offset(position{0, 1000}); got != len(a.text) {
+		t.Errorf("offset past the end of the line = %d", got)
+	}
+}
+
+func TestCompletions(t *testing.T) {
+	text := "[\n  Memory = 1024;\n  Cpus = 2;\n  Start = (MY.\n]"
+	a := analyze("file:///a.ad", "", text)
+	if a.ok {
+		t.Fatal("incomplete document parsed")
+	}
+	last := analyze("file:///a.ad", "", "[\n  Memory = 1024;\n  Cpus = 2;\n]")
+	off := at(t, text, "MY.", 0) + 3
+	items := a.completions(off, last.defs)
+	if len(items) != 2 || items[0].Label != "Memory" || items[0].Detail != "1024" || items[1].Label != "Cpus" {
+		t.Errorf("completions after MY. = %+v", items)
+	}
+	if items := a.completions(off-3, last.defs); len(items) < 10 || !hasLabel(items, "strcat") || !hasLabel(items, "undefined") {
+		t.Errorf("unscoped completions lack functions or keywords: %d items", len(items))
+	}
+
+	text = "[ a = TARGET.x ]"
+	a = analyze("file:///a.ad", "", text)
+	if items := a.completions(at(t, text, "x", 0), a.defs); len(items) != 0 {
+		t.Errorf("completions after TARGET. = %+v", items)
+	}
+}
+
+func hasLabel(items []completionItem, label string) bool {
+	for _, it := range items {
+		if it.Label == label {
+			return true
+		}
+	}
+	return false
+}
+
+func TestHoverAndDefinition(t *testing.T) {
+	text := "[\n  Memory = 1024;\n  Request = Memory * 2 + Disk + TARGET.Swap;\n  n = size(\"abc\")\n]"
+	a := analyze("file:///a.ad", "", text)
+	if !a.ok {
+		t.Fatalf("did not parse: %+v", a.diags)
+	}
+
+	h := a.hover(at(t, text, "Request", 0))
+	if h == nil {
+		t.Fatal("no hover on Request")
+	}
+	for _, want := range []string{"Request = (((Memory * 2) + Disk) + TARGET.Swap)", "Value: `undefined`", "References: Memory", "External references: Disk"} {
+		if !strings.Contains(h.Contents.Value, want) {
+			t.Errorf("hover on Request = %q, lacks %q", h.Contents.Value, want)
+		}
+	}
+	if h := a.hover(at(t, text, "Memory", 1)); h == nil || !strings.Contains(h.Contents.Value, "Value: `1024`") {
+		t.Errorf("hover on a reference to Memory = %+v", h)
+	}
+	if h := a.hover(at(t, text, "size", 0)); h == nil || !strings.Contains(h.Contents.Value, "size(...): 1 arguments") {
+		t.Errorf("hover on size = %+v", h)
+	}
+	if h := a.hover(at(t, text, "Disk", 0)); h == nil || !strings.Contains(h.Contents.Value, "not defined") {
+		t.Errorf("hover on Disk = %+v", h)
+	}
+	if h := a.hover(at(t, text, "Swap", 0)); h == nil || !strings.Contains(h.Contents.Value, "target ad") {
+		t.Errorf("hover on TARGET.Swap = %+v", h)
+	}
+
+	def, ok := a.definition(at(t, text, "Memory", 1))
+	if !ok || def.start != at(t, text, "Memory", 0) {
+		t.Errorf("definition of Memory = %+v, %v", def, ok)
+	}
+	if _, ok := a.definition(at(t, text, "Swap", 0)); ok {
+		t.Error("TARGET.Swap has a definition")
+	}
+}
+
+func TestOldFormat(t *testing.T) {
+	text := "MyType = \"Job\"\n  Memory = 1024\nRequest = Memory * 2\n"
+	a := analyze("file:///job.ad", "", text)
+	if !a.ok || a.kind != oldAd {
+		t.Fatalf("old-format ad: ok=%v kind=%v diags=%+v", a.ok, a.kind, a.diags)
+	}
+	def, ok := a.definition(at(t, text, "Memory", 1))
+	if !ok || a.position(def.start) != (position{1, 2}) {
+		t.Errorf("definition of Memory = %+v at %+v", def, a.position(def.start))
+	}
+	if h := a.hover(at(t, text, "Request", 0)); h == nil || !strings.Contains(h.Contents.Value, "Value: `2048`") {
+		t.Errorf("hover on Request = %+v", h)
+	}
+}
+
+// client drives a server over in-memory pipes.
+type client struct {
+	t   *testing.T
+	w   io.Writer
+	r   *bufio.Reader
+	id  int
+	err chan error
+}
+
+func newClient(t *testing.T) *client {
+	inR, inW := io.Pipe()
+	outR, outW := io.Pipe()
+	c := &client{t: t, w: inW, r: bufio.NewReader(outR), err: make(chan error, 1)}
+	go func() {
+		err := newServer(inR, outW, log.New(io.Discard, "", 0)).run()
+		outW.Close()
+		c.err <- err
+	}()
+	return c
+}
+
+func (c *client) send(v any) {
+	c.t.Helper()
+	if err := writeMessage(c.w, v); err != nil {
+		c.t.Fatal(err)
+	}
+}
+
+func (c *client) notify(method string, params any) {
+	c.t.Helper()
+	c.send(map[string]any{"jsonrpc": "2.0", "method": method, "params": params})
+}
+
+// receive reads the next message from the server.
+func (c *client) receive() map[string]json.RawMessage {
+	c.t.Helper()
+	body, err := readMessage(c.r)
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	var m map[string]json.RawMessage
+	if err := json.Unmarshal(body, &m); err != nil {
+		c.t.Fatal(err)
+	}
+	return m
+}
+
+// call sends a request and returns its response.
+func (c *client) call(method string, params any) map[string]json.RawMessage {
+	c.t.Helper()
+	c.id++
+	c.send(map[string]any{"jsonrpc": "2.0", "id": c.id, "method": method, "params": params})
+	m := c.receive()
+	if string(m["id"]) != fmt.Sprint(c.id) {
+		c.t.Fatalf("%s: response %s, want id %d", method, m["id"], c.id)
+	}
+	return m
+}
+
+func TestServer(t *testing.T) {
+	c := newClient(t)
+	if m := c.call("textDocument/hover", map[string]any{}); !strings.Contains(string(m["error"]), fmt.Sprint(codeNotInitialized)) {
+		t.Errorf("request before initialize = %s", m["error"])
+	}
+	m := c.call("initialize", map[string]any{"capabilities": map[string]any{}})
+	if !strings.Contains(string(m["result"]), `"hoverProvider":true`) {
+		t.Errorf("initialize = %s", m["result"])
+	}
+	c.notify("initialized", map[string]any{})
+
+	uri := "file:///job.ad"
+	doc := map[string]any{"uri": uri}
+	c.notify("textDocument/didOpen", map[string]any{"textDocument": map[string]any{
+		"uri": uri, "languageId": "classad", "version": 1, "text": "[\n  a = 1;\n  b = a + 1\n]",
+	}})
+	var diags struct {
+		Method string
+		Params publishDiagnosticsParams
+	}
+	body, _ := json.Marshal(c.receive())
+	json.Unmarshal(body, &diags)
+	if diags.Method != "textDocument/publishDiagnostics" || diags.Params.URI != uri || len(diags.Params.Diagnostics) != 0 {
+		t.Errorf("diagnostics on open = %s", body)
+	}
+
+	m = c.call("textDocument/hover", map[string]any{"textDocument": doc, "position": position{2, 2}})
+	var h hover
+	json.Unmarshal(m["result"], &h)
+	if !strings.Contains(h.Contents.Value, "Value: `2`") {
+		t.Errorf("hover = %s", m["result"])
+	}
+	m = c.call("textDocument/definition", map[string]any{"textDocument": doc, "position": position{2, 6}})
+	var loc location
+	json.Unmarshal(m["result"], &loc)
+	if loc.URI != uri || loc.Range.Start != (position{1, 2}) {
+		t.Errorf("definition = %s", m["result"])
+	}
+
+	c.notify("textDocument/didChange", map[string]any{"textDocument": doc, "contentChanges": []map[string]any{{"text": "[\n  a = 1;\n  b = a +\n]"}}})
+	body, _ = json.Marshal(c.receive())
+	json.Unmarshal(body, &diags)
+	if len(diags.Params.Diagnostics) != 1 || diags.Params.Diagnostics[0].Severity != severityError {
+		t.Errorf("diagnostics after a bad change = %s", body)
+	}
+	m = c.call("textDocument/completion", map[string]any{"textDocument": doc, "position": position{2, 9}})
+	var items []completionItem
+	json.Unmarshal(m["result"], &items)
+	if !hasLabel(items, "a") || !hasLabel(items, "b") {
+		t.Errorf("completion in a document that does not parse lacks the last attributes: %s", m["result"])
+	}
+
+	if m := c.call("workspace/symbol", map[string]any{}); !strings.Contains(string(m["error"]), fmt.Sprint(codeMethodNotFound)) {
+		t.Errorf("unsupported method = %s", m)
+	}
+	c.call("shutdown", nil)
+	c.notify("exit", nil)
+	if err := <-c.err; err != nil {
+		t.Errorf("run = %v", err)
+	}
+}
+
+func TestExitWithoutShutdown(t *testing.T) {
+	var out bytes.Buffer
+	var in bytes.Buffer
+	writeMessage(&in, map[string]any{"jsonrpc": "2.0", "method": "exit"})
+	if err := newServer(&in, &out, log.New(io.Discard, "", 0)).run(); err != errExitWithoutShutdown {
+		t.Errorf("run = %v, want %v", err, errExitWithoutShutdown)
+	}
+}
@@ -1,41 +1,100 @@
-// Package main provides a command-line tool for parsing and evaluating ClassAd expressions.
+// Package main provides a command-line tool for parsing and evaluating ClassAd
+// expressions, and for evaluating, filtering, matching and converting streams
+// of ads.
 package main
 
 import (
 	"flag"
 	"fmt"
 	"os"
+	"strings"
 
+	"github.com/PelicanPlatform/classad/ast"
+	"github.com/PelicanPlatform/classad/classad"
+	"github.com/PelicanPlatform/classad/classad/format"
+	"github.com/PelicanPlatform/classad/classad/lint"
 	"github.com/PelicanPlatform/classad/parser"
 )
 
+// stringList is a flag that may be given more than once.
+type stringList []string
+
+func (l *stringList) String() string     { return strings.Join(*l, ", ") }
+func (l *stringList) Set(s string) error { *l = append(*l, s); return nil }
+
 func main() {
 	// Define command-line flags
 	oldFormat := flag.Bool("old", false, "Parse input as old ClassAd format (newline-delimited, no brackets)")
+	lintMode := flag.Bool("lint", false, "Statically check the ClassAd or expression instead of printing it")
+	from := flag.String("from", "auto", "Format of the ads read: auto, new, old or json (JSON or NDJSON)")
+	to := flag.String("to", "", "Print each ad read in this format: new, old, json or ndjson")
+	var evals stringList
+	flag.Var(&evals, "eval", "Print the value of this expression in each ad read (may be repeated)")
+	constraint := flag.String("constraint", "", "Only use the ads read for which this expression is true")
+	matchFile := flag.String("match", "", "Match the job ad in this file against the ads read, best rank first")
 	help := flag.Bool("help", false, "Show usage information")
 	flag.BoolVar(help, "h", false, "Show usage information (shorthand)")
 
 	flag.Usage = func() {
-		fmt.Fprintf(os.Stderr, "Usage: %s [OPTIONS] <classad-expression>\n\n", os.Args[0])
-		fmt.Fprintf(os.Stderr, "Parse and display ClassAd expressions in new or old format.\n\n")
+		fmt.Fprintf(os.Stderr, "Usage: %s [OPTIONS] <classad-expression>\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "       %s [OPTIONS] [-eval EXPR | -af ATTR... | -to FORMAT] [file ...]\n\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "Parse and display ClassAd expressions in new or old format, or process\n")
+		fmt.Fprintf(os.Stderr, "streams of ads. With any of -eval, -constraint, -af, -to, -match or -from,\n")
+		fmt.Fprintf(os.Stderr, "or without an argument, the arguments are files of ads (\"-\" or none is\n")
+		fmt.Fprintf(os.Stderr, "standard input) in the new, old, JSON or NDJSON format, gzipped or not.\n\n")
 		fmt.Fprintf(os.Stderr, "Options:\n")
 		flag.PrintDefaults()
+		fmt.Fprintf(os.Stderr, "  -af[:jlhnt,rV] ATTR...\n")
+		fmt.Fprintf(os.Stderr, "    \tPrint these attributes or expressions of each ad read, as condor_q -af does;\n")
+		fmt.Fprintf(os.Stderr, "    \tthey run to the next argument that begins with \"-\"\n")
 		fmt.Fprintf(os.Stderr, "\nExamples:\n")
 		fmt.Fprintf(os.Stderr, "  # New format (default)\n")
 		fmt.Fprintf(os.Stderr, "  %s '[x = 10; y = x + 5]'\n", os.Args[0])
 		fmt.Fprintf(os.Stderr, "  %s '[Machine = \"test\"; Cpus = 4]'\n\n", os.Args[0])
 		fmt.Fprintf(os.Stderr, "  # Old format\n")
 		fmt.Fprintf(os.Stderr, "  %s -old 'x = 10\ny = x + 5'\n", os.Args[0])
-		fmt.Fprintf(os.Stderr, "  %s --old 'Foo = 3\nBar = \"hello\"'\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s --old 'Foo = 3\nBar = \"hello\"'\n\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  # Lint (exit status 1 if any error is found)\n")
+		fmt.Fprintf(os.Stderr, "  %s -lint 'OpSys == undefined && size(Owner) > \"4\"'\n\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  # Streams of ads\n")
+		fmt.Fprintf(os.Stderr, "  condor_q -l | %s -constraint 'JobStatus == 2' -af ClusterId Owner\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s -eval 'Memory / Cpus' machines.ads\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s -to json jobs.ads > jobs.json\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s -match job.ad machines.ads\n", os.Args[0])
 	}
 
-	flag.Parse()
+	args, af, err := splitAutoFormat(os.Args[1:])
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	flag.CommandLine.Parse(args)
 
 	if *help {
 		flag.Usage()
 		os.Exit(0)
 	}
 
+	streaming := len(evals) > 0 || *constraint != "" || af != nil || *to != "" || *matchFile != "" || *from != "auto"
+	if streaming && *lintMode {
+		fmt.Fprintf(os.Stderr, "Error: -lint checks one ClassAd or expression argument; it does not read streams\n")
+		os.Exit(1)
+	}
+	if streaming || flag.NArg() == 0 && !*lintMode {
+		if *oldFormat && *from == "auto" {
+			*from = "old"
+		}
+		o, err := newOptions(*from, *to, evals, *constraint, af, *matchFile)
+		if err == nil {
+			err = o.run(os.Stdout, os.Stdin, flag.Args())
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
 	if flag.NArg() < 1 {
 		fmt.Fprintf(os.Stderr, "Error: missing ClassAd expression argument\n\n")
 		flag.Usage()
@@ -44,8 +103,11 @@ func main() {
 
 	input := flag.Arg(0)
 
+	if *lintMode {
+		os.Exit(runLint(input, *oldFormat))
+	}
+
 	var result interface{}
-	var err error
 
 	if *oldFormat {
 		// Parse as old ClassAd format
@@ -71,3 +133,79 @@ func main() {
 		fmt.Println("Successfully parsed (no result to display)")
 	}
 }
+
+// newOptions checks and compiles the stream flags.
+func newOptions(from, to string, evals []string, constraint string, af *format.AutoFormat, matchFile string) (*options, error) {
+	o := &options{from: from, to: to, af: af}
+	switch from {
+	case "auto", "new", "old", "json":
+	default:
+		return nil, fmt.Errorf("-from %q: want auto, new, old or json", from)
+	}
+	switch to {
+	case "", "new", "old", "json", "ndjson":
+	default:
+		return nil, fmt.Errorf("-to %q: want new, old, json or ndjson", to)
+	}
+	outputs := 0
+	for _, set := range []bool{len(evals) > 0, af != nil, to != ""} {
+		if set {
+			outputs++
+		}
+	}
+	if outputs > 1 {
+		return nil, fmt.Errorf("use only one of -eval, -af and -to")
+	}
+	for _, src := range evals {
+		e, err := classad.ParseExpr(src)
+		if err != nil {
+			return nil, fmt.Errorf("-eval %q: %w", src, err)
+		}
+		o.evals = append(o.evals, e)
+	}
+	if constraint != "" {
+		e, err := classad.ParseExpr(constraint)
+		if err != nil {
+			return nil, fmt.Errorf("-constraint %q: %w", constraint, err)
+		}
+		o.constraint = e
+	}
+	if matchFile != "" {
+		job, err := firstAd(matchFile, from)
+		if err != nil {
+			return nil, fmt.Errorf("-match: %w", err)
+		}
+		o.job = job
+	}
+	return o, nil
+}
+
+// runLint prints the lint diagnostics for input, an ad or an expression, and
+// returns the exit status: 1 if the input does not parse or has an error.
+func runLint(input string, oldFormat bool) int {
+	var diags []lint.Diagnostic
+	var err error
+	switch {
+	case oldFormat:
+		var ad *ast.ClassAd
+		if ad, err = parser.ParseOldClassAd(input); err == nil {
+			diags = lint.Node(ad, nil, nil)
+		}
+	case strings.HasPrefix(strings.TrimSpace(input), "["):
+		diags, err = lint.Ad(input, nil)
+	default:
+		diags, err = lint.Expr(input, nil)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	status := 0
+	for _, d := range diags {
+		fmt.Println(d)
+		if d.Severity == lint.Error {
+			status = 1
+		}
+	}
+	return status
+}
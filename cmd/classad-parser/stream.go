@@ -0,0 +1,222 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/PelicanPlatform/classad/classad"
+	"github.com/PelicanPlatform/classad/classad/format"
+	"github.com/PelicanPlatform/classad/cmd/internal/adinput"
+)
+
+// options is what the stream flags ask of each ad read.
+type options struct {
+	from       string // input format: auto, new, old or json
+	to         string // output format: new, old, json or ndjson
+	evals      []*classad.Expr
+	constraint *classad.Expr
+	af         *format.AutoFormat
+	job        *classad.ClassAd // -match: the job the ads read are matched against
+}
+
+// ranked is an ad that matched the -match job, with the job's rank of it.
+type ranked struct {
+	ad   *classad.ClassAd
+	rank float64
+	n    int // position in the input, from 1
+}
+
+// run reads the ads of the files at paths ("-" is stdin), or of stdin if
+// there are none, and writes what o asks for to w.
+func (o *options) run(w io.Writer, stdin io.Reader, paths []string) error {
+	bw := bufio.NewWriter(w)
+	p := &printer{w: bw, o: o}
+	if o.to == "json" || o.to == "ndjson" {
+		p.json = classad.NewJSONWriter(bw)
+		p.json.Lines = o.to == "ndjson"
+	}
+
+	var matches []ranked
+	n := 0
+	err := o.eachAd(stdin, paths, func(ad *classad.ClassAd) error {
+		n++
+		if o.constraint != nil {
+			if ok, err := o.constraint.Eval(ad).BoolValue(); err != nil || !ok {
+				return nil
+			}
+		}
+		if o.job == nil {
+			return p.print(ad)
+		}
+		m := classad.NewMatchClassAd(o.job, ad)
+		if m.Match() {
+			// As the negotiator does, a rank that is not a number is 0.
+			rank, _ := m.EvaluateRankLeft()
+			matches = append(matches, ranked{ad: ad, rank: rank, n: n})
+		}
+		return nil
+	})
+	if err == nil && o.job != nil {
+		sort.SliceStable(matches, func(i, j int) bool { return matches[i].rank > matches[j].rank })
+		for _, m := range matches {
+			if err = p.printMatch(m); err != nil {
+				break
+			}
+		}
+	}
+	if cerr := p.close(); err == nil {
+		err = cerr
+	}
+	if ferr := bw.Flush(); err == nil {
+		err = ferr
+	}
+	return err
+}
+
+// eachAd calls fn for each ad of the files at paths, in order, or of stdin
+// if there are none.
+func (o *options) eachAd(stdin io.Reader, paths []string, fn func(*classad.ClassAd) error) error {
+	if len(paths) == 0 {
+		paths = []string{"-"}
+	}
+	for _, path := range paths {
+		if err := readFile(path, o.from, stdin, fn); err != nil {
+			if path == "-" {
+				path = "<stdin>"
+			}
+			return fmt.Errorf("%s: %w", path, err)
+		}
+	}
+	return nil
+}
+
+// readFile calls fn for each ad of the file at path, or of stdin if path is
+// "-".
+func readFile(path, formatName string, stdin io.Reader, fn func(*classad.ClassAd) error) error {
+	if path == "-" {
+		return adinput.Read(stdin, formatName, fn)
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return adinput.Read(f, formatName, fn)
+}
+
+// errStop ends a readFile early.
+var errStop = errors.New("stop")
+
+// firstAd returns the first ad of the file at path.
+func firstAd(path, formatName string) (*classad.ClassAd, error) {
+	var first *classad.ClassAd
+	err := readFile(path, formatName, os.Stdin, func(ad *classad.ClassAd) error {
+		first = ad
+		return errStop
+	})
+	switch {
+	case err != nil && err != errStop:
+		return nil, fmt.Errorf("%s: %w", path, err)
+	case first == nil:
+		return nil, fmt.Errorf("%s: no ad", path)
+	}
+	return first, nil
+}
+
+// printer writes ads as the options ask: projected by -af or -eval, or whole
+// in the -to format.
+type printer struct {
+	w    *bufio.Writer
+	o    *options
+	json *classad.JSONWriter
+	n    int // ads printed
+}
+
+func (p *printer) print(ad *classad.ClassAd) error {
+	defer func() { p.n++ }()
+	switch {
+	case p.o.af != nil:
+		if p.n == 0 {
+			p.w.WriteString(p.o.af.Header())
+		}
+		p.w.WriteString(p.o.af.Sprint(ad))
+	case len(p.o.evals) > 0:
+		for i, e := range p.o.evals {
+			if i > 0 {
+				p.w.WriteByte(' ')
+			}
+			p.w.WriteString(classad.LiteralExpr(e.Eval(ad)).String())
+		}
+		p.w.WriteByte('\n')
+	case p.json != nil:
+		return p.json.Write(ad)
+	case p.o.to == "old":
+		// Old-format ads are separated by a blank line.
+		if p.n > 0 {
+			p.w.WriteByte('\n')
+		}
+		p.w.WriteString(ad.MarshalOld())
+		p.w.WriteByte('\n')
+	default:
+		p.w.WriteString(ad.String())
+		p.w.WriteByte('\n')
+	}
+	return nil
+}
+
+// printMatch prints an ad -match ranked: as print does if the output was
+// asked for, and otherwise as a line of its rank and name.
+func (p *printer) printMatch(m ranked) error {
+	if p.o.af != nil || len(p.o.evals) > 0 || p.o.to != "" {
+		return p.print(m.ad)
+	}
+	name, ok := m.ad.EvaluateAttrString("Name")
+	if !ok {
+		name = fmt.Sprintf("ad %d", m.n)
+	}
+	_, err := fmt.Fprintf(p.w, "%s\t%s\n", strconv.FormatFloat(m.rank, 'g', -1, 64), name)
+	return err
+}
+
+func (p *printer) close() error {
+	if p.json == nil {
+		return nil
+	}
+	return p.json.Close()
+}
+
+// splitAutoFormat takes -af[:opts] (or -autoformat) out of args with the
+// arguments after it, up to the next that begins with "-", as condor_q reads
+// them, and returns the other arguments and the compiled format, or a nil
+// format if args have no -af.
+func splitAutoFormat(args []string) ([]string, *format.AutoFormat, error) {
+	for i, a := range args {
+		if a == "--" {
+			break
+		}
+		name, opts, _ := strings.Cut(strings.TrimLeft(a, "-"), ":")
+		if !strings.HasPrefix(a, "-") || name != "af" && name != "autoformat" {
+			continue
+		}
+		end := i + 1
+		for end < len(args) && !strings.HasPrefix(args[end], "-") {
+			end++
+		}
+		if end == i+1 {
+			return nil, nil, fmt.Errorf("%s: no attributes", a)
+		}
+		af, err := format.ParseAutoFormat(opts, args[i+1:end]...)
+		if err != nil {
+			return nil, nil, fmt.Errorf("%s: %w", a, err)
+		}
+		rest := append(append([]string{}, args[:i]...), args[end:]...)
+		return rest, af, nil
+	}
+	return args, nil, nil
+}
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const jobs = `[ ClusterId = 1; ProcId = 0; Owner = "alice"; JobStatus = 2; RequestCpus = 2 ]
+[ ClusterId = 2; ProcId = 0; Owner = "o\"brien"; JobStatus = 1; RequestCpus = 8 ]
+`
+
+// runOptions runs the stream flags over stdin and returns the output.
+func runOptions(t *testing.T, args []string, stdin string, paths ...string) string {
+	t.Helper()
+	rest, af, err := splitAutoFormat(args)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var from, to, constraint, match string
+	var evals []string
+	for i := 0; i+1 < len(rest); i += 2 {
+		switch v := rest[i+1]; rest[i] {
+		case "-from":
+			from = v
+		case "-to":
+			to = v
+		case "-eval":
+			evals = append(evals, v)
+		case "-constraint":
+			constraint = v
+		case "-match":
+			match = v
+		default:
+			t.Fatalf("unexpected argument %q", rest[i])
+		}
+	}
+	if from == "" {
+		from = "auto"
+	}
+	o, err := newOptions(from, to, evals, constraint, af, match)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out bytes.Buffer
+	if err := o.run(&out, strings.NewReader(stdin), paths); err != nil {
+		t.Fatal(err)
+	}
+	return out.String()
+}
+
+func TestStream(t *testing.T) {
+	tests := []struct {
+		args []string
+		want string
+	}{
+		{nil, `[ClusterId = 1; ProcId = 0; Owner = "alice"; JobStatus = 2; RequestCpus = 2]
+[ClusterId = 2; ProcId = 0; Owner = "o\"brien"; JobStatus = 1; RequestCpus = 8]
+`},
+		{[]string{"-constraint", "JobStatus == 2", "-af", "ClusterId", "Owner"}, "1 alice\n"},
+		{[]string{"-af:h,", "Owner", "RequestCpus * 2"}, "Owner,RequestCpus * 2\nalice,4\no\"brien,16\n"},
+		{[]string{"-eval", "RequestCpus * 2", "-eval", "Owner", "-eval", "{ Missing }"}, "4 \"alice\" {undefined}\n16 \"o\\\"brien\" {undefined}\n"},
+		{[]string{"-constraint", "Owner == \"alice\"", "-to", "old"}, "ClusterId = 1\nProcId = 0\nOwner = \"alice\"\nJobStatus = 2\nRequestCpus = 2\n"},
+		{[]string{"-constraint", "ClusterId > 5", "-to", "json"}, "[]\n"},
+	}
+	for _, tt := range tests {
+		if got := runOptions(t, tt.args, jobs); got != tt.want {
+			t.Errorf("%q:\n%s\nwant\n%s", tt.args, got, tt.want)
+		}
+	}
+
+	for _, bad := range [][]string{{"-from", "xml"}, {"-to", "xml"}, {"-eval", "x", "-to", "json"}, {"-eval", "x +"}, {"-constraint", "("}} {
+		o := map[string]string{"-from": "auto"}
+		for i := 0; i < len(bad); i += 2 {
+			o[bad[i]] = bad[i+1]
+		}
+		var evals []string
+		if e, ok := o["-eval"]; ok {
+			evals = []string{e}
+		}
+		if _, err := newOptions(o["-from"], o["-to"], evals, o["-constraint"], nil, ""); err == nil {
+			t.Errorf("%q was accepted", bad)
+		}
+	}
+}
+
+func TestStreamConvert(t *testing.T) {
+	dir := t.TempDir()
+	// Round trip through every output format; the input format is detected.
+	// (The old and JSON readers sort attributes, so compare values.)
+	values := []string{"-af", "ClusterId", "ProcId", "Owner", "JobStatus", "RequestCpus"}
+	want := runOptions(t, values, jobs)
+	for _, to := range []string{"old", "json", "ndjson", "new"} {
+		got := runOptions(t, []string{"-to", to}, jobs)
+		path := filepath.Join(dir, "ads."+to)
+		if err := os.WriteFile(path, []byte(got), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if back := runOptions(t, values, "", path); back != want {
+			t.Errorf("-to %s did not read back:\n%s\ngot\n%s", to, got, back)
+		}
+	}
+
+	// Files are read in order, gzipped or not, "-" being stdin.
+	var gz bytes.Buffer
+	zw := gzip.NewWriter(&gz)
+	zw.Write([]byte("ClusterId = 3\n"))
+	zw.Close()
+	gzPath := filepath.Join(dir, "one.ad.gz")
+	os.WriteFile(gzPath, gz.Bytes(), 0o644)
+	got := runOptions(t, []string{"-eval", "ClusterId"}, jobs, gzPath, "-")
+	if got != "3\n1\n2\n" {
+		t.Errorf("gzipped file then stdin = %q", got)
+	}
+}
+
+func TestStreamMatch(t *testing.T) {
+	dir := t.TempDir()
+	job := filepath.Join(dir, "job.ad")
+	os.WriteFile(job, []byte("MyType = \"Job\"\nRequestCpus = 4\nRequirements = TARGET.Cpus >= RequestCpus\nRank = TARGET.Memory\n"), 0o644)
+	machines := `{"Name": "a", "Cpus": 8, "Memory": 4096, "Requirements": true}
+{"Name": "b", "Cpus": 2, "Memory": 65536, "Requirements": true}
+{"Name": "c", "Cpus": 16, "Memory": 16384, "Requirements": "/Expr(TARGET.RequestCpus <= 8)/"}
+{"Name": "d", "Cpus": 16, "Memory": 32768, "Requirements": false}
+{"Cpus": 4, "Requirements": true}
+`
+	if got, want := runOptions(t, []string{"-match", job}, machines), "16384\tc\n4096\ta\n0\tad 5\n"; got != want {
+		t.Errorf("-match:\n%s\nwant\n%s", got, want)
+	}
+	got := runOptions(t, []string{"-match", job, "-constraint", "Memory < 10000", "-af", "Name"}, machines)
+	if got != "a\n" {
+		t.Errorf("-match with -constraint and -af = %q", got)
+	}
+	if _, err := newOptions("auto", "", nil, "", nil, filepath.Join(dir, "nosuch")); err == nil {
+		t.Error("-match of a missing file was accepted")
+	}
+}
+
+func TestSplitAutoFormat(t *testing.T) {
+	rest, af, err := splitAutoFormat([]string{"-constraint", "x", "-af:t", "Owner", "Cpus * 2", "-to", "new", "--", "-af"})
+	if err != nil || af == nil {
+		t.Fatalf("splitAutoFormat: %v, %v", af, err)
+	}
+	if want := []string{"-constraint", "x", "-to", "new", "--", "-af"}; !reflect.DeepEqual(rest, want) {
+		t.Errorf("rest = %q, want %q", rest, want)
+	}
+	if rest, af, _ := splitAutoFormat([]string{"jobs.ads", "--", "-af", "x"}); af != nil || len(rest) != 4 {
+		t.Errorf("-af after -- = %v, %q", af, rest)
+	}
+	if _, _, err := splitAutoFormat([]string{"-af", "-to", "new"}); err == nil {
+		t.Error("-af without attributes was accepted")
+	}
+}
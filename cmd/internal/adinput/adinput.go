@@ -0,0 +1,68 @@
+// Package adinput reads the ad files the command-line tools take: new-format,
+// old-format or JSON ads, possibly gzipped.
+package adinput
+
+import (
+	"bufio"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/PelicanPlatform/classad/classad"
+)
+
+// Read calls fn for each ad read from r, gunzipping r if it is gzipped and
+// detecting the format if formatName is "auto"; the other formats are "new",
+// "old" and "json". It stops at the first error fn returns.
+func Read(r io.Reader, formatName string, fn func(*classad.ClassAd) error) error {
+	br := bufio.NewReader(r)
+	if magic, _ := br.Peek(2); len(magic) == 2 && magic[0] == 0x1f && magic[1] == 0x8b {
+		zr, err := gzip.NewReader(br)
+		if err != nil {
+			return err
+		}
+		defer zr.Close()
+		br = bufio.NewReader(zr)
+	}
+	if formatName == "auto" {
+		formatName = detectFormat(br)
+	}
+
+	var reader *classad.Reader
+	switch formatName {
+	case "new":
+		reader = classad.NewReader(br)
+	case "old":
+		reader = classad.NewOldReader(br)
+	case "json":
+		reader = classad.NewJSONReader(br)
+	default:
+		return fmt.Errorf("unknown format %q", formatName)
+	}
+	for reader.Next() {
+		if err := fn(reader.ClassAd()); err != nil {
+			return err
+		}
+	}
+	return reader.Err()
+}
+
+// detectFormat guesses the format from the first bytes: JSON starts with an
+// object or an array of objects, the new format with a bracket followed by
+// an attribute or, for an empty ad, the closing bracket, and anything else is
+// taken for the old format.
+func detectFormat(br *bufio.Reader) string {
+	buf, _ := br.Peek(4096)
+	s := strings.TrimLeft(string(buf), " \t\r\n")
+	switch {
+	case strings.HasPrefix(s, "{"):
+		return "json"
+	case strings.HasPrefix(s, "["):
+		if rest := strings.TrimLeft(s[1:], " \t\r\n"); strings.HasPrefix(rest, "{") {
+			return "json"
+		}
+		return "new"
+	}
+	return "old"
+}
@@ -0,0 +1,47 @@
+package adinput
+
+import (
+	"bufio"
+	"strings"
+	"testing"
+
+	"github.com/PelicanPlatform/classad/classad"
+)
+
+func TestDetectFormat(t *testing.T) {
+	for doc, want := range map[string]string{
+		"[A = 1]":          "new",
+		" \n[\n  A = 1\n]": "new",
+		"[]":               "new",
+		"[\n]":             "new",
+		"[{\"A\": 1}]":     "json",
+		"[\n  {\"A\": 1}]": "json",
+		"{\"A\": 1}":       "json",
+		"A = 1\nB = 2":     "old",
+	} {
+		if got := detectFormat(bufio.NewReader(strings.NewReader(doc))); got != want {
+			t.Errorf("detectFormat(%q) = %s, want %s", doc, got, want)
+		}
+	}
+}
+
+func TestRead(t *testing.T) {
+	for doc, want := range map[string]int{
+		"[]":                       1, // one empty ad, not an empty JSON array
+		"[A = 1][B = 2]":           2,
+		"[{\"A\": 1}, {\"B\": 2}]": 2,
+		"A = 1\nB = 2\n\nA = 3":    2,
+	} {
+		n := 0
+		err := Read(strings.NewReader(doc), "auto", func(*classad.ClassAd) error {
+			n++
+			return nil
+		})
+		if err != nil || n != want {
+			t.Errorf("Read(%q) = %d ads, %v; want %d", doc, n, err, want)
+		}
+	}
+	if err := Read(strings.NewReader("[]"), "xml", nil); err == nil {
+		t.Error("want an error for an unknown format")
+	}
+}
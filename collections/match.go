@@ -126,16 +126,7 @@ func (c *Collection) MatchSortedRanked(job *classad.ClassAd, limit int) []Ranked
 	// Defer ClassAd materialization when a limit will discard most survivors: rank
 	// records wire-native, sort, truncate, then build only the returned ads.
 	matches := c.collectMatches(job, limit > 0)
-	sort.SliceStable(matches, func(i, j int) bool {
-		a, b := matches[i], matches[j]
-		if a.hasRank != b.hasRank {
-			return a.hasRank
-		}
-		if a.hasRank && a.rank != b.rank {
-			return a.rank > b.rank
-		}
-		return false
-	})
+	sortRanked(matches)
 	if limit > 0 && limit < len(matches) {
 		matches = matches[:limit]
 	}
@@ -148,6 +139,20 @@ func (c *Collection) MatchSortedRanked(job *classad.ClassAd, limit int) []Ranked
 	return out
 }
 
+// sortRanked orders matches best (highest Rank) first, unranked ones last.
+func sortRanked(matches []rankedMatch) {
+	sort.SliceStable(matches, func(i, j int) bool {
+		a, b := matches[i], matches[j]
+		if a.hasRank != b.hasRank {
+			return a.hasRank
+		}
+		if a.hasRank && a.rank != b.rank {
+			return a.rank > b.rank
+		}
+		return false
+	})
+}
+
 // collectMatches gathers all symmetric matches, in parallel when possible. When the
 // job's Requirements yield an index-usable constraint on the slots, it visits only
 // candidate slots (A2); otherwise it scans every slot with the wire-native reject.
@@ -0,0 +1,475 @@
+package collections
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/PelicanPlatform/classad/ast"
+	"github.com/PelicanPlatform/classad/classad"
+	"github.com/PelicanPlatform/classad/collections/vm"
+)
+
+// Match analysis: condor_q -better-analyze over a resource collection. ExplainMatch
+// says how a match would execute; AnalyzeMatch says why it finds what it finds -- how
+// many slots each Requirements clause admits alone and together, how many slots turn
+// the job down with their own Requirements, and which single change to the job would
+// let it match. Each count is of the slots satisfying a conjunction of clauses; the
+// value indexes narrow the slots visited for it as they do for Match, every slot
+// visited is evaluated exactly, and the counts no index prunes share one scan. It is a
+// diagnostic, not a negotiation-path call.
+
+// maxRejectSamples caps MatchAnalysis.RejectingSlots.
+const maxRejectSamples = 10
+
+// MatchAnalysis is the result of AnalyzeMatch.
+type MatchAnalysis struct {
+	// TotalResources is the number of slots analyzed.
+	TotalResources int `json:"totalResources"`
+	// HasRequirements is false when the job has no Requirements (every slot satisfies
+	// the job side; only the slots' own Requirements can reject it).
+	HasRequirements bool `json:"hasRequirements"`
+	// Clauses are the top-level && conjuncts of the job's Requirements in the order
+	// written, followed by those of the resource-side constraint.
+	Clauses []ClauseAnalysis `json:"clauses"`
+	// JobSideMatches is how many slots satisfy every clause.
+	JobSideMatches int `json:"jobSideMatches"`
+	// RejectedBySlot is how many of those slots' own Requirements reject the job (the
+	// reverse direction of the bilateral match).
+	RejectedBySlot int `json:"rejectedBySlot"`
+	// RejectingSlots names up to maxRejectSamples of them, by their Name attribute.
+	RejectingSlots []string `json:"rejectingSlots,omitempty"`
+	// Matches is how many slots match: every clause holds and the slot accepts the job.
+	Matches int `json:"matches"`
+	// Suggestions are the minimal single-clause changes that would match more slots,
+	// most matches first.
+	Suggestions []Relaxation `json:"suggestions,omitempty"`
+}
+
+// ClauseAnalysis is one clause of the analyzed constraint.
+type ClauseAnalysis struct {
+	// Text is the clause as written.
+	Text string `json:"text"`
+	// SlotText is the clause over the slot, with the job's attributes baked to
+	// constants (as in MatchExplain).
+	SlotText string `json:"slotText"`
+	// Matches is how many slots satisfy the clause on its own.
+	Matches int `json:"matches"`
+	// Cumulative is how many slots satisfy it and every clause before it.
+	Cumulative int `json:"cumulative"`
+	// ResourceSide marks a clause of the resource-side constraint.
+	ResourceSide bool `json:"resourceSide,omitempty"`
+}
+
+// Relaxation is a change to one clause that would let the job match more slots.
+type Relaxation struct {
+	// Clause is the clause changed, as written.
+	Clause string `json:"clause"`
+	// Change is the change: a new bound on the job attribute the clause compares with
+	// ("RequestMemory <= 16384"), or else the clause rewritten ("TARGET.Memory >= 16384").
+	Change string `json:"change"`
+	// Matches is how many slots would match with the change, the job otherwise as is.
+	Matches int `json:"matches"`
+}
+
+func (r Relaxation) String() string {
+	return fmt.Sprintf("%s would match %d slots", r.Change, r.Matches)
+}
+
+// analyzedClause is a clause with the predicate over the slot its count is planned by.
+type analyzedClause struct {
+	expr     ast.Expr
+	slot     ast.Expr  // a predicate every slot satisfying expr satisfies
+	resource *vm.Query // resource-side: evaluated over the slot alone
+	relax    *relaxable
+}
+
+// relaxable is a clause of the shape `TARGET.attr op constant`, normalized so the slot
+// attribute is on the left, with the values of attr of the slots it alone keeps from
+// matching.
+type relaxable struct {
+	slotRef *ast.AttributeReference
+	op      string
+	bound   ast.Expr // the constant
+	knob    string   // the job attribute the constant came from, or ""
+	vals    []classad.Value
+}
+
+// AnalyzeMatch analyzes why job matches the slots it does (see MatchAnalysis).
+// targetConstraint, if non-empty, is a resource-side filter over the slots (a MATCH's
+// WHERE TARGET) analyzed as further clauses. Slot Requirements are evaluated against the
+// job as submitted, including for the suggestions. Errors only on a malformed
+// targetConstraint. job is not modified.
+func (c *Collection) AnalyzeMatch(job *classad.ClassAd, targetConstraint string) (MatchAnalysis, error) {
+	var an MatchAnalysis
+	var clauses []*analyzedClause
+	jobVals := jobValues(job)
+	if reqExpr := jobRequirementsExpr(job); reqExpr != nil {
+		an.HasRequirements = true
+		for _, cj := range flattenChain(reqExpr, "&&") {
+			clauses = append(clauses, &analyzedClause{expr: cj, slot: c.slotMatchExpr(cj, jobVals), relax: relaxableClause(cj, jobVals)})
+		}
+	}
+	if strings.TrimSpace(targetConstraint) != "" {
+		tq, err := vm.Parse(targetConstraint)
+		if err != nil {
+			return MatchAnalysis{}, err
+		}
+		for _, cj := range flattenChain(tq.Expr(), "&&") {
+			clauses = append(clauses, &analyzedClause{expr: cj, slot: cj, resource: vm.Compile(cj)})
+		}
+	}
+
+	orig := job.GetTarget()
+	defer job.SetTarget(orig)
+	a := &matchAnalyzer{
+		m:       classad.NewMatchClassAd(job, nil),
+		clauses: clauses,
+		pass:    make([]bool, len(clauses)),
+		vals:    make([]classad.Value, len(clauses)),
+	}
+	n := len(clauses)
+	preds := make([]ast.Expr, n)
+	for i, cl := range clauses {
+		preds[i] = cl.slot
+	}
+	alone := make([]int, n)
+	cumulative := make([]int, n)
+	sweeps := []analysisSweep{{
+		plan: preds,
+		visit: func(slot *classad.ClassAd) {
+			if !a.passes(n, -1) {
+				return
+			}
+			an.JobSideMatches++
+			if a.accept {
+				an.Matches++
+				return
+			}
+			an.RejectedBySlot++
+			if len(an.RejectingSlots) < maxRejectSamples {
+				name, _ := slot.EvaluateAttrString("Name")
+				an.RejectingSlots = append(an.RejectingSlots, name)
+			}
+		},
+	}}
+	for i := range clauses {
+		sweeps = append(sweeps, analysisSweep{
+			plan: preds[i : i+1],
+			visit: func(*classad.ClassAd) {
+				if a.pass[i] {
+					alone[i]++
+				}
+			},
+		})
+		if i == 0 || i == n-1 {
+			continue // the first clause's count is its own, the last's JobSideMatches
+		}
+		sweeps = append(sweeps, analysisSweep{
+			plan: preds[:i+1],
+			visit: func(*classad.ClassAd) {
+				if a.passes(i+1, -1) {
+					cumulative[i]++
+				}
+			},
+		})
+	}
+	for ci, cl := range clauses {
+		if cl.relax == nil {
+			continue
+		}
+		// The slots this clause alone keeps from matching.
+		plan := append(append([]ast.Expr{}, preds[:ci]...), preds[ci+1:]...)
+		if p := cl.relax.failing(); p != nil {
+			plan = append(plan, p)
+		}
+		sweeps = append(sweeps, analysisSweep{
+			plan: plan,
+			visit: func(*classad.ClassAd) {
+				if a.accept && !a.pass[ci] && a.passes(n, ci) {
+					cl.relax.vals = append(cl.relax.vals, a.vals[ci])
+				}
+			},
+		})
+	}
+	an.TotalResources = c.analyze(a, sweeps)
+
+	for i, cl := range clauses {
+		ca := ClauseAnalysis{Text: cl.expr.String(), Matches: alone[i], Cumulative: cumulative[i], ResourceSide: cl.resource != nil}
+		switch i {
+		case 0:
+			ca.Cumulative = alone[0]
+		case n - 1:
+			ca.Cumulative = an.JobSideMatches
+		}
+		if cl.resource != nil {
+			ca.SlotText = classad.FoldConstants(cl.expr).String()
+		} else {
+			ca.SlotText = classad.FoldConstants(displayRewrite(cl.expr, jobVals)).String()
+		}
+		an.Clauses = append(an.Clauses, ca)
+	}
+	for _, cl := range clauses {
+		if cl.relax == nil {
+			continue
+		}
+		if r, ok := cl.relax.suggest(cl.expr, an.Matches); ok {
+			an.Suggestions = append(an.Suggestions, r)
+		}
+	}
+	sort.SliceStable(an.Suggestions, func(i, j int) bool {
+		return an.Suggestions[i].Matches > an.Suggestions[j].Matches
+	})
+	return an, nil
+}
+
+// analysisSweep is one count AnalyzeMatch takes: it visits the slots that may satisfy
+// every predicate of plan, and visit tests the slot the analyzer has just evaluated.
+type analysisSweep struct {
+	plan  []ast.Expr
+	visit func(slot *classad.ClassAd)
+}
+
+// analyze runs the sweeps and returns the number of slots. A sweep whose plan the value
+// indexes prune visits only its candidates; the rest share one scan of every slot.
+func (c *Collection) analyze(a *matchAnalyzer, sweeps []analysisSweep) int {
+	var scan []analysisSweep
+	for _, s := range sweeps {
+		groups, ok := c.analysisPlan(s.plan)
+		if !ok {
+			scan = append(scan, s)
+			continue
+		}
+		emit := c.yieldAd(func(slot *classad.ClassAd) bool {
+			a.evaluate(slot)
+			s.visit(slot)
+			return true
+		})
+		for _, sh := range c.shards {
+			c.scanShardCandidatesGroups(sh, groups, false, emit)
+		}
+	}
+	if len(scan) == 0 {
+		return c.Len()
+	}
+	total := 0
+	for slot := range c.Scan() {
+		total++
+		a.evaluate(slot)
+		for _, s := range scan {
+			s.visit(slot)
+		}
+	}
+	return total
+}
+
+// analysisPlan plans the slots satisfying every predicate of preds as Match plans the
+// job's Requirements. ok is false when the indexes do not prune them, or barely do, or
+// the collection is chained (its ads are read through Scan's flattened view).
+func (c *Collection) analysisPlan(preds []ast.Expr) (groups [][]usableProbe, ok bool) {
+	if len(preds) == 0 || c.parentKeyFor != nil || !c.spec.Load().any() {
+		return nil, false
+	}
+	pred := preds[0]
+	for _, p := range preds[1:] {
+		pred = &ast.BinaryOp{Op: "&&", Left: pred, Right: p}
+	}
+	groups, prunable := c.planIndexGroups(vm.Compile(pred).ProbePlan())
+	if !prunable || overSelectivityGate(c, groups) {
+		return nil, false
+	}
+	return groups, true
+}
+
+// matchAnalyzer holds how the slot last evaluated fares against the analyzed clauses.
+type matchAnalyzer struct {
+	m       *classad.MatchClassAd
+	clauses []*analyzedClause
+	pass    []bool          // the slot satisfies the clause
+	vals    []classad.Value // the slot's value of a relaxable clause's attribute
+	accept  bool            // the slot's own Requirements accept the job
+}
+
+// evaluate evaluates every clause, and the slot's Requirements, on slot.
+func (a *matchAnalyzer) evaluate(slot *classad.ClassAd) {
+	a.m.ReplaceRightAd(slot)
+	for i, cl := range a.clauses {
+		if cl.resource != nil {
+			continue
+		}
+		a.pass[i] = isTrueValue(a.m.EvaluateExprLeft(cl.expr))
+		if cl.relax != nil {
+			a.vals[i] = a.m.EvaluateExprLeft(cl.relax.slotRef)
+		}
+	}
+	a.accept = isTrueValue(a.m.EvaluateAttrRight("Requirements"))
+	slot.SetTarget(nil)
+	for i, cl := range a.clauses {
+		if cl.resource != nil {
+			a.pass[i] = cl.resource.Matches(slot)
+		}
+	}
+}
+
+// passes reports whether the slot satisfies the first n clauses except clause skip.
+func (a *matchAnalyzer) passes(n, skip int) bool {
+	for i := range n {
+		if i != skip && !a.pass[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// relaxableClause recognizes `slotAttr op K` (either way round) with op a comparison
+// and K a number or string constant of the job: a literal, or a job attribute (the knob
+// a suggestion names). slotAttr is TARGET.attr, or an unscoped name the job does not
+// define (which falls through to the slot).
+func relaxableClause(cj ast.Expr, jobVals map[string]classad.Value) *relaxable {
+	b, ok := unparenExpr(cj).(*ast.BinaryOp)
+	if !ok {
+		return nil
+	}
+	op := b.Op
+	switch op {
+	case "==", "<", "<=", ">", ">=":
+	default:
+		return nil
+	}
+	isSlotRef := func(e ast.Expr) (*ast.AttributeReference, bool) {
+		ref, ok := e.(*ast.AttributeReference)
+		if !ok {
+			return nil, false
+		}
+		if ref.Scope == ast.TargetScope {
+			return ref, true
+		}
+		_, inJob := jobVals[strings.ToLower(ref.Name)]
+		return ref, ref.Scope == ast.NoScope && !inJob
+	}
+	left, jobSide := unparenExpr(b.Left), unparenExpr(b.Right)
+	ref, ok := isSlotRef(left)
+	if !ok {
+		if ref, ok = isSlotRef(jobSide); !ok {
+			return nil
+		}
+		jobSide = left
+		op = mirrorOp(op)
+	}
+	k := classad.FoldConstants(displayRewrite(jobSide, jobVals))
+	switch k.(type) {
+	case *ast.IntegerLiteral, *ast.RealLiteral:
+	case *ast.StringLiteral:
+		if op != "==" {
+			return nil
+		}
+	default:
+		return nil
+	}
+	r := &relaxable{slotRef: ref, op: op, bound: k}
+	if kr, ok := jobSide.(*ast.AttributeReference); ok && kr.Scope != ast.TargetScope {
+		r.knob = kr.Name
+	}
+	return r
+}
+
+// failing is a predicate over the slot that every slot whose numeric value of the
+// attribute fails the clause satisfies, or nil for an == clause.
+func (r *relaxable) failing() ast.Expr {
+	var op string
+	switch r.op {
+	case "<":
+		op = ">="
+	case "<=":
+		op = ">"
+	case ">":
+		op = "<="
+	case ">=":
+		op = "<"
+	default:
+		return nil
+	}
+	return &ast.BinaryOp{Op: op, Left: &ast.AttributeReference{Name: r.slotRef.Name, Scope: ast.NoScope}, Right: r.bound}
+}
+
+// suggest finds the smallest change to the clause that admits one of the slots it
+// alone keeps from matching, and counts the slots that would then match: the cur
+// matching now plus those the change admits.
+func (r *relaxable) suggest(clause ast.Expr, cur int) (Relaxation, bool) {
+	var bound classad.Value
+	found := false
+	if r.op == "==" {
+		// No ordering to relax along: propose the value most of the slots have.
+		counts := map[string]int{}
+		best := 0
+		for _, v := range r.vals {
+			if !v.IsNumber() && !v.IsString() {
+				continue
+			}
+			key := strings.ToLower(v.String())
+			counts[key]++
+			if counts[key] > best {
+				best, bound, found = counts[key], v, true
+			}
+		}
+	} else {
+		lower := r.op == ">" || r.op == ">="
+		var best float64
+		for _, v := range r.vals {
+			n, err := v.NumberValue()
+			if err != nil || !v.IsNumber() {
+				continue
+			}
+			if !found || lower && n > best || !lower && n < best {
+				best, bound, found = n, v, true
+			}
+		}
+	}
+	if !found {
+		return Relaxation{}, false
+	}
+	op := r.op
+	switch op {
+	case ">":
+		op = ">="
+	case "<":
+		op = "<="
+	}
+	matches := cur
+	for _, v := range r.vals {
+		if admits(v, op, bound) {
+			matches++
+		}
+	}
+	var change string
+	if r.knob != "" {
+		change = fmt.Sprintf("%s %s %s", r.knob, mirrorOp(op), bound.String())
+	} else {
+		change = fmt.Sprintf("%s %s %s", r.slotRef.String(), op, bound.String())
+	}
+	return Relaxation{Clause: clause.String(), Change: change, Matches: matches}, true
+}
+
+// admits reports whether a slot value v satisfies `v op bound`.
+func admits(v classad.Value, op string, bound classad.Value) bool {
+	if op == "==" {
+		if bs, err := bound.StringValue(); err == nil {
+			s, err := v.StringValue()
+			return err == nil && strings.EqualFold(s, bs)
+		}
+	}
+	n, err := v.NumberValue()
+	if err != nil || !v.IsNumber() {
+		return false
+	}
+	b, _ := bound.NumberValue()
+	switch op {
+	case "==":
+		return n == b
+	case ">=":
+		return n >= b
+	case "<=":
+		return n <= b
+	}
+	return false
+}
@@ -0,0 +1,73 @@
+package collections
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestAnalyzeMatch(t *testing.T) {
+	t.Parallel()
+	c := New(Options{Shards: 2, ValueAttrs: []string{"Memory"}})
+	for i := 0; i < 40; i++ {
+		// Memory 1..8 GiB; every tenth slot only runs jobs of owner "bob".
+		req := "true"
+		if i%10 == 0 {
+			req = `TARGET.Owner == "bob"`
+		}
+		arch := "X86_64"
+		if i%4 == 0 {
+			arch = "ARM"
+		}
+		c.Put([]byte(fmt.Sprintf("s%d", i)), mustAd(t, fmt.Sprintf(
+			`[ Name="slot%d"; Memory=%d; Arch=%q; Requirements=%s ]`, i, (i%8+1)*1024, arch, req)))
+	}
+	job := mustAd(t, `[ Owner="alice"; RequestMemory=16384;
+		Requirements = TARGET.Arch == "X86_64" && TARGET.Memory >= RequestMemory ]`)
+
+	an, err := c.AnalyzeMatch(job, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// With the Memory index built the Memory counts come from it, and agree.
+	c.Reindex()
+	if indexed, err := c.AnalyzeMatch(job, ""); err != nil || !reflect.DeepEqual(indexed, an) {
+		t.Errorf("indexed analysis = %+v, %v; want %+v", indexed, err, an)
+	}
+	if an.TotalResources != 40 || an.Matches != 0 || len(an.Clauses) != 2 {
+		t.Fatalf("analysis = %+v", an)
+	}
+	if cl := an.Clauses[0]; cl.Matches != 30 || cl.Cumulative != 30 {
+		t.Errorf("Arch clause = %+v, want 30 alone and cumulative", cl)
+	}
+	if cl := an.Clauses[1]; cl.Matches != 0 || cl.Cumulative != 0 || cl.SlotText != "(Memory >= 16384)" {
+		t.Errorf("Memory clause = %+v, want 0 slots", cl)
+	}
+	// The largest Memory among X86_64 slots that accept alice is 8 GiB: slots
+	// 7, 15, 23, 31, 39 (slot 39 % 10 != 0, so all accept).
+	if len(an.Suggestions) != 1 || an.Suggestions[0].String() != "RequestMemory <= 8192 would match 5 slots" {
+		t.Errorf("Suggestions = %v", an.Suggestions)
+	}
+
+	// Reverse direction: a small request passes the job side on slots that reject alice.
+	job = mustAd(t, `[ Owner="alice"; RequestMemory=1024;
+		Requirements = TARGET.Arch == "X86_64" && TARGET.Memory >= RequestMemory ]`)
+	an, err = c.AnalyzeMatch(job, `Memory >= 4096`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(an.Clauses) != 3 || !an.Clauses[2].ResourceSide {
+		t.Fatalf("Clauses = %+v", an.Clauses)
+	}
+	// X86_64 (i%4 != 0) with Memory >= 4 GiB (i%8 >= 3): i%8 in {3,5,6,7} -> 20 slots;
+	// of those, i = 30 is the only one with i%10 == 0 (i=10 and i=20 have i%8 = 2, 4).
+	if an.JobSideMatches != 20 || an.RejectedBySlot != 1 || an.Matches != 19 {
+		t.Errorf("JobSide=%d RejectedBySlot=%d Matches=%d", an.JobSideMatches, an.RejectedBySlot, an.Matches)
+	}
+	if len(an.RejectingSlots) != 1 || an.RejectingSlots[0] != "slot30" {
+		t.Errorf("RejectingSlots = %v", an.RejectingSlots)
+	}
+	if _, err := c.AnalyzeMatch(job, "Memory >="); err == nil {
+		t.Error("want an error for a malformed target constraint")
+	}
+}
@@ -1,7 +1,6 @@
 package collections
 
 import (
-	"sort"
 	"strings"
 
 	"github.com/PelicanPlatform/classad/ast"
@@ -46,16 +45,7 @@ func (c *Collection) MatchSortedRankedFiltered(job *classad.ClassAd, targetConst
 			kept = append(kept, matches[i])
 		}
 	}
-	sort.SliceStable(kept, func(i, j int) bool {
-		a, b := kept[i], kept[j]
-		if a.hasRank != b.hasRank {
-			return a.hasRank
-		}
-		if a.hasRank && a.rank != b.rank {
-			return a.rank > b.rank
-		}
-		return false
-	})
+	sortRanked(kept)
 	if limit > 0 && limit < len(kept) {
 		kept = kept[:limit]
 	}
@@ -68,6 +58,65 @@ func (c *Collection) MatchSortedRankedFiltered(job *classad.ClassAd, targetConst
 	return out, nil
 }
 
+// MatchSortedRankedWithOptions is MatchSortedRankedFiltered for a job from an untrusted
+// source: every evaluation -- both Requirements, the job's Rank and targetConstraint -- is
+// bounded by opts. It matches over Scan, one decoded slot at a time, because the index
+// pushdown and the wire-native pre-filter evaluate the job side unbounded. A slot whose
+// evaluation exceeds a limit does not match; the first *classad.LimitError is returned
+// along with the matches. It errors otherwise only if targetConstraint fails to parse.
+func (c *Collection) MatchSortedRankedWithOptions(job *classad.ClassAd, targetConstraint string, limit int, opts classad.EvalOptions) ([]RankedMatch, error) {
+	if job == nil {
+		return nil, nil
+	}
+	var tq *vm.Query
+	if strings.TrimSpace(targetConstraint) != "" {
+		q, err := vm.Parse(targetConstraint)
+		if err != nil {
+			return nil, err
+		}
+		tq = q.WithOptions(opts)
+	}
+	orig := job.GetTarget()
+	defer job.SetTarget(orig)
+	m := classad.NewMatchClassAd(job, nil)
+	m.SetOptions(opts)
+	var matches []rankedMatch
+	var limitErr error
+	for ad := range c.Scan() {
+		if opts.Context != nil && opts.Context.Err() != nil {
+			if limitErr == nil {
+				limitErr = &classad.LimitError{Err: opts.Context.Err()}
+			}
+			break // every further evaluation would fail the same way
+		}
+		if tq != nil && !tq.Matches(ad) {
+			continue
+		}
+		ok, r, hr := matchOne(m, ad)
+		if err := m.LimitErr(); err != nil {
+			if limitErr == nil {
+				limitErr = err
+			}
+			continue
+		}
+		if ok {
+			matches = append(matches, rankedMatch{ad: ad, rank: r, hasRank: hr})
+		}
+	}
+	if limitErr == nil && tq != nil {
+		limitErr = tq.Err()
+	}
+	sortRanked(matches)
+	if limit > 0 && limit < len(matches) {
+		matches = matches[:limit]
+	}
+	out := make([]RankedMatch, len(matches))
+	for i, rm := range matches {
+		out[i] = RankedMatch{Ad: rm.ad, Rank: rm.rank, HasRank: rm.hasRank}
+	}
+	return out, limitErr
+}
+
 // collectMatchesFiltered gathers matches with the resource-side constraint's probes
 // pushed into the candidate plan. It mirrors collectMatches but combines the job's DNF
 // candidate groups with the constraint's, so the indexed scan visits the intersection.
@@ -1,9 +1,12 @@
 package collections
 
 import (
+	"errors"
 	"fmt"
+	"strings"
 	"testing"
 
+	"github.com/PelicanPlatform/classad/classad"
 	"github.com/PelicanPlatform/classad/collections/vm"
 )
 
@@ -114,3 +117,46 @@ func TestMatchFilteredEqualsBrute(t *testing.T) {
 		}
 	}
 }
+
+// TestMatchWithOptions: the bounded match returns what MatchSortedRankedFiltered does
+// within generous limits, and under tight ones drops the slot whose Requirements exceeds
+// them, reporting the limit.
+func TestMatchWithOptions(t *testing.T) {
+	t.Parallel()
+	c := New(Options{Shards: 4})
+	for i := 0; i < 200; i++ {
+		c.Put([]byte(fmt.Sprintf("m%d", i)), mustAd(t, fmt.Sprintf(
+			`[ Id=%d; Memory=%d; State=%q; Requirements=true ]`, i, (i%8+1)*1024, []string{"Claimed", "Unclaimed"}[i%2])))
+	}
+	heavy := "Memory > 0" + strings.Repeat(" && Memory + Memory > 0", 20)
+	c.Put([]byte("heavy"), mustAd(t, `[ Id=-1; Memory=8192; State="Unclaimed"; Requirements = `+heavy+` ]`))
+	job := mustAd(t, `[ Requirements = TARGET.Memory >= 4096; Rank = TARGET.Memory ]`)
+	tw := `State == "Unclaimed"`
+
+	want, err := c.MatchSortedRankedFiltered(job, tw, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := c.MatchSortedRankedWithOptions(job, tw, 10, classad.EvalOptions{MaxSteps: 10000})
+	if err != nil || len(got) != len(want) {
+		t.Fatalf("generous limits: %d matches, %v; want %d", len(got), err, len(want))
+	}
+	for i := range got {
+		if got[i].Rank != want[i].Rank {
+			t.Errorf("match %d: Rank %v, want %v", i, got[i].Rank, want[i].Rank)
+		}
+	}
+
+	got, err = c.MatchSortedRankedWithOptions(job, tw, 0, classad.EvalOptions{MaxSteps: 20})
+	if !errors.Is(err, classad.ErrMaxSteps) {
+		t.Errorf("tight limits: err = %v, want ErrMaxSteps", err)
+	}
+	for _, rm := range got {
+		if id, _ := rm.Ad.EvaluateAttrInt("Id"); id < 0 {
+			t.Error("tight limits matched the slot over them")
+		}
+	}
+	if len(got) != 75 {
+		t.Errorf("tight limits: %d matches, want 75", len(got))
+	}
+}
@@ -324,58 +324,44 @@ func scanPlanName(queryPar int) string {
 // exception disjunct. Other function calls / lists / records remain undefined (opaque
 // to the index).
 func rewriteForSlot(e ast.Expr, jobVals map[string]classad.Value, assumed map[string]bool, inCtrl bool) ast.Expr {
-	switch n := e.(type) {
-	case *ast.AttributeReference:
-		if n.Scope == ast.TargetScope {
-			return &ast.AttributeReference{Name: n.Name, Scope: ast.NoScope}
-		}
-		if v, ok := jobVals[strings.ToLower(n.Name)]; ok {
-			if lit := valueToLiteral(v); lit != nil {
-				return lit
+	return ast.Rewrite(e, func(e ast.Expr) ast.Expr {
+		switch n := e.(type) {
+		case *ast.AttributeReference:
+			if n.Scope == ast.TargetScope {
+				return &ast.AttributeReference{Name: n.Name, Scope: ast.NoScope}
 			}
+			if v, ok := jobVals[strings.ToLower(n.Name)]; ok {
+				if lit := valueToLiteral(v); lit != nil {
+					return lit
+				}
+			}
+			if n.Scope == ast.NoScope && inCtrl && assumed != nil {
+				assumed[n.Name] = true // unscoped + absent inside a guard: assumed undefined
+			}
+			return &ast.UndefinedLiteral{}
+		case *ast.ConditionalExpr, *ast.ElvisExpr:
+			if !inCtrl {
+				return rewriteForSlot(e, jobVals, assumed, true)
+			}
+			return nil
+		case *ast.FunctionCall:
+			// Keep the function (arguments baked) rather than collapsing it to undefined:
+			// it yields no index probe on its own, but finite-domain materialization can
+			// turn a pure function of one low-cardinality indexed attribute into a
+			// membership probe. ifThenElse's arguments are in a control-flow context.
+			if !inCtrl && strings.EqualFold(n.Name, "ifThenElse") {
+				return rewriteForSlot(e, jobVals, assumed, true)
+			}
+			return nil
+		case *ast.BinaryOp, *ast.UnaryOp, *ast.ParenExpr, *ast.SubscriptExpr, *ast.SelectExpr:
+			return nil
+		case *ast.IntegerLiteral, *ast.RealLiteral, *ast.StringLiteral, *ast.BooleanLiteral,
+			*ast.UndefinedLiteral, *ast.ErrorLiteral:
+			return e
+		default:
+			return &ast.UndefinedLiteral{}
 		}
-		if n.Scope == ast.NoScope && inCtrl && assumed != nil {
-			assumed[n.Name] = true // unscoped + absent inside a guard: assumed undefined
-		}
-		return &ast.UndefinedLiteral{}
-	case *ast.BinaryOp:
-		return &ast.BinaryOp{Op: n.Op, Left: rewriteForSlot(n.Left, jobVals, assumed, inCtrl), Right: rewriteForSlot(n.Right, jobVals, assumed, inCtrl)}
-	case *ast.UnaryOp:
-		return &ast.UnaryOp{Op: n.Op, Expr: rewriteForSlot(n.Expr, jobVals, assumed, inCtrl)}
-	case *ast.ParenExpr:
-		return &ast.ParenExpr{Inner: rewriteForSlot(n.Inner, jobVals, assumed, inCtrl)}
-	case *ast.ConditionalExpr:
-		return &ast.ConditionalExpr{
-			Condition: rewriteForSlot(n.Condition, jobVals, assumed, true),
-			TrueExpr:  rewriteForSlot(n.TrueExpr, jobVals, assumed, true),
-			FalseExpr: rewriteForSlot(n.FalseExpr, jobVals, assumed, true),
-		}
-	case *ast.ElvisExpr:
-		return &ast.ElvisExpr{
-			Left:  rewriteForSlot(n.Left, jobVals, assumed, true),
-			Right: rewriteForSlot(n.Right, jobVals, assumed, true),
-		}
-	case *ast.FunctionCall:
-		// Keep the function (arguments baked) rather than collapsing it to undefined:
-		// it yields no index probe on its own, but finite-domain materialization can
-		// turn a pure function of one low-cardinality indexed attribute into a
-		// membership probe. ifThenElse's arguments are in a control-flow context.
-		ctrl := inCtrl || strings.EqualFold(n.Name, "ifThenElse")
-		args := make([]ast.Expr, len(n.Args))
-		for i := range n.Args {
-			args[i] = rewriteForSlot(n.Args[i], jobVals, assumed, ctrl)
-		}
-		return &ast.FunctionCall{Name: n.Name, Args: args}
-	case *ast.SubscriptExpr:
-		return &ast.SubscriptExpr{Container: rewriteForSlot(n.Container, jobVals, assumed, inCtrl), Index: rewriteForSlot(n.Index, jobVals, assumed, inCtrl)}
-	case *ast.SelectExpr:
-		return &ast.SelectExpr{Record: rewriteForSlot(n.Record, jobVals, assumed, inCtrl), Attr: n.Attr}
-	case *ast.IntegerLiteral, *ast.RealLiteral, *ast.StringLiteral, *ast.BooleanLiteral,
-		*ast.UndefinedLiteral, *ast.ErrorLiteral:
-		return e
-	default:
-		return &ast.UndefinedLiteral{}
-	}
+	})
 }
 
 // slotMatchExpr rewrites the job's Requirements over the slot and returns the sound
@@ -490,7 +476,9 @@ func dedupConjuncts(e ast.Expr) []ast.Expr {
 // impureFuncs are functions whose value is not a pure function of their arguments
 // (nondeterministic or context-dependent), so finite-domain materialization -- which
 // evaluates an expression over an attribute's known values -- must not touch them.
-var impureFuncs = map[string]bool{"random": true, "time": true, "eval": true, "unparse": true}
+var impureFuncs = map[string]bool{
+	"random": true, "time": true, "abstime": true, "daytime": true, "eval": true, "unparse": true,
+}
 
 // maxMaterializeCard bounds how many distinct values an attribute may have to be worth
 // materializing a predicate over (evaluate the predicate once per value).
@@ -103,3 +103,35 @@ func BenchmarkCompile(b *testing.B) {
 		_ = CompileProgram(expr)
 	}
 }
+
+// BenchmarkEvaluateAttr compares ClassAd.EvaluateAttr of a constraint attribute
+// tree-walked and through the ad's cache of compiled programs.
+func BenchmarkEvaluateAttr(b *testing.B) {
+	for _, cached := range []bool{false, true} {
+		name := "TreeWalk"
+		if cached {
+			name = "Cached"
+		}
+		b.Run(name, func(b *testing.B) {
+			ad, err := classad.Parse(benchScope)
+			if err != nil {
+				b.Fatal(err)
+			}
+			expr, err := parser.ParseExpr(benchConstraint)
+			if err != nil {
+				b.Fatal(err)
+			}
+			ad.Insert("Constraint", expr)
+			if cached {
+				ad.EnableCache(CompileAttr)
+			}
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if !ad.EvaluateAttr("Constraint").IsBool() {
+					b.Fatal("expected bool")
+				}
+			}
+		})
+	}
+}
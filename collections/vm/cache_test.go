@@ -0,0 +1,66 @@
+package vm
+
+import (
+	"testing"
+
+	"github.com/PelicanPlatform/classad/classad"
+	"github.com/PelicanPlatform/classad/parser"
+)
+
+// TestDifferentialEnableCache asserts that an ad evaluating through its cache of
+// compiled programs gives the tree-walker's values, on first use and once the
+// programs are cached.
+func TestDifferentialEnableCache(t *testing.T) {
+	exprs := append(append([]string(nil), exprSources...), parityCorpusExprs...)
+	same, _ := parser.ParseExpr(`X__ =?= X__`)
+	for _, ss := range scopeSources {
+		for _, es := range exprs {
+			expr, err := parser.ParseExpr(es)
+			if err != nil {
+				t.Fatalf("parse expr %q: %v", es, err)
+			}
+			ad, err := classad.Parse(ss)
+			if err != nil {
+				t.Fatalf("parse scope %q: %v", ss, err)
+			}
+			ad.Insert("X__", expr)
+			ad.Insert("Y__", same)
+			want, wantY := ad.EvaluateAttr("X__"), ad.EvaluateAttr("Y__")
+			ad.EnableCache(CompileAttr)
+			for pass := 0; pass < 2; pass++ {
+				if got := ad.EvaluateAttr("X__"); !valuesEqual(want, got) {
+					t.Errorf("pass %d: mismatch expr=%q scope=%q\n  want=%s\n   got=%s",
+						pass, es, ss, describe(want), describe(got))
+				}
+			}
+			if got := ad.EvaluateAttr("Y__"); !valuesEqual(wantY, got) {
+				t.Errorf("X__ =?= X__: mismatch expr=%q scope=%q\n  want=%s\n   got=%s",
+					es, ss, describe(wantY), describe(got))
+			}
+		}
+	}
+}
+
+func TestEnableCache_Invalidate(t *testing.T) {
+	job, _ := classad.Parse(`[RequestMemory = 2048; Requirements = TARGET.Memory >= RequestMemory; Rank = TARGET.Memory / 1024]`)
+	slot, _ := classad.Parse(`[Memory = 4096]`)
+	job.EnableCache(CompileAttr)
+	job.SetTarget(slot)
+	if ok, _ := job.EvaluateAttrBool("Requirements"); !ok {
+		t.Fatal("Requirements: want true")
+	}
+	if err := job.Set("RequestMemory", 8192); err != nil {
+		t.Fatal(err)
+	}
+	if ok, _ := job.EvaluateAttrBool("Requirements"); ok {
+		t.Error("Requirements after Set: want false")
+	}
+	slot.InsertAttr("Memory", 16384)
+	if r, _ := job.EvaluateAttrInt("Rank"); r != 16 {
+		t.Errorf("Rank against the changed slot = %d, want 16", r)
+	}
+	job.Delete("RequestMemory")
+	if v := job.EvaluateAttr("Requirements"); !v.IsUndefined() {
+		t.Errorf("Requirements after Delete = %v, want undefined", v)
+	}
+}
@@ -7,11 +7,13 @@ import (
 
 // CompileProgram lowers a ClassAd expression to a Program. Node types with
 // subtle scope/short-circuit/laziness semantics (conditional, list, record,
-// function call, select, subscript) are emitted as OpEvalNode and delegated to
-// the tree-walking evaluator at run time; the rest are native instructions.
+// built-in function call, select, subscript) are emitted as OpEvalNode and
+// delegated to the tree-walking evaluator at run time; the rest -- including
+// calls to user-defined (non-built-in) functions -- are native instructions.
 func CompileProgram(expr ast.Expr) *Program {
 	c := &compiler{p: &Program{}, seen: map[string]bool{}}
-	if astHeight(expr) >= maxNativeDepth {
+	c.p.height = astHeight(expr)
+	if c.p.height >= maxNativeDepth {
 		// Pathologically deep expression: delegate the whole thing to the
 		// tree-walking evaluator so its recursion-depth guard (maxEvalDepth)
 		// governs the result identically. The flat interpreter accumulates no
@@ -136,6 +138,9 @@ func (c *compiler) emit(expr ast.Expr) {
 		c.pushConst(classad.NewRealValue(v.Value))
 	case *ast.StringLiteral:
 		c.pushConst(classad.NewStringValue(v.Value))
+	case *ast.AbsTimeLiteral, *ast.RelTimeLiteral:
+		val, _ := classad.TimeLiteralValue(v)
+		c.pushConst(val)
 	case *ast.AttributeReference:
 		c.ins(OpLoadRef, c.addRef(v.Name, v.Scope))
 	case *ast.ParenExpr:
@@ -155,6 +160,12 @@ func (c *compiler) emit(expr ast.Expr) {
 			c.emit(v.Right)
 			c.ins(OpBinop, c.addOp(v.Op))
 		}
+	case *ast.FunctionCall:
+		if classad.IsBuiltinFunction(v.Name) {
+			c.ins(OpEvalNode, c.addNode(expr))
+			return
+		}
+		c.emitCall(v)
 	case *ast.ElvisExpr:
 		// left ?: right  ==  (left is undefined) ? right : left
 		c.emit(v.Left)
@@ -162,12 +173,35 @@ func (c *compiler) emit(expr ast.Expr) {
 		c.emit(v.Right)
 		c.patch(j, len(c.p.code))
 	default:
-		// ConditionalExpr, ListLiteral, RecordLiteral, FunctionCall, SelectExpr,
-		// SubscriptExpr, or any future node: delegate to the evaluator.
+		// ConditionalExpr, ListLiteral, RecordLiteral, SelectExpr, SubscriptExpr,
+		// or any future node: delegate to the evaluator.
 		c.ins(OpEvalNode, c.addNode(expr))
 	}
 }
 
+// emitCall compiles a call to a user-defined function. The function is resolved
+// at run time (it may be registered after compilation, or supplied by a scoped
+// registry on the Matcher), so an unresolvable call compiles too and evaluates to
+// error, as in the tree-walker:
+//
+//	check   k         ; unresolvable or wrong arity: push error, jump L_end
+//	<args...>
+//	call    k         ; pop argc args, push the function's result
+//	L_end:
+//
+// Evaluating the arguments natively is what keeps the program Native, so a
+// constraint calling a site function still runs wire-native.
+func (c *compiler) emitCall(v *ast.FunctionCall) {
+	c.p.calls = append(c.p.calls, callInfo{name: v.Name, argc: len(v.Args)})
+	k := int32(len(c.p.calls) - 1)
+	c.ins(OpCallCheck, k)
+	for _, a := range v.Args {
+		c.emit(a)
+	}
+	c.ins(OpCall, k)
+	c.p.calls[k].end = len(c.p.code)
+}
+
 // emitLogical compiles a short-circuiting logical operator (&& or ||):
 //
 //	<left>
@@ -1,6 +1,9 @@
 package vm
 
-import "github.com/PelicanPlatform/classad/classad"
+import (
+	"github.com/PelicanPlatform/classad/ast"
+	"github.com/PelicanPlatform/classad/classad"
+)
 
 // Run executes p against scope and returns the resulting Value. It produces the
 // same value that classad evaluation of the source expression would: value
@@ -21,14 +24,43 @@ func Run(p *Program, scope *classad.ClassAd) (result classad.Value) {
 	return result
 }
 
+// RunWithOptions is Run bounded by opts, counting a step per instruction as well
+// as per node of every subtree and attribute the program hands to the evaluator.
+// When a limit stops the run the value is error and the error the
+// *classad.LimitError; otherwise the error is nil.
+func RunWithOptions(p *Program, scope *classad.ClassAd, opts classad.EvalOptions) (classad.Value, error) {
+	ev := classad.NewEvaluator(scope)
+	ev.SetOptions(opts)
+	result := runBounded(p, ev, make([]classad.Value, 0, 16))
+	if err := ev.LimitErr(); err != nil {
+		return classad.NewErrorValue(), err
+	}
+	return result, nil
+}
+
+// runBounded is exec under the cyclic-reference (and budget) recovery of the
+// entry points, discarding the stack.
+func runBounded(p *Program, ev *classad.Evaluator, stack []classad.Value) (result classad.Value) {
+	defer classad.RecoverCyclic(&result)
+	result, _ = exec(p, ev, stack)
+	return result
+}
+
 // exec runs p against ev, using stack as scratch (stack[:0] is taken first). It
 // returns the result and the final stack slice, whose backing array the caller
 // may retain to amortize allocation across evaluations (see Matcher).
 func exec(p *Program, ev *classad.Evaluator, stack []classad.Value) (classad.Value, []classad.Value) {
 	stack = stack[:0]
 	code := p.code
+	bounded := ev.Bounded()
+	if bounded {
+		ev.Step(p.height - 1) // the depth the tree-walker would reach
+	}
 	for ip := 0; ip < len(code); {
 		in := code[ip]
+		if bounded {
+			ev.Step(0)
+		}
 		switch in.Op {
 		case OpPushConst:
 			stack = append(stack, p.consts[in.A])
@@ -92,6 +124,22 @@ func exec(p *Program, ev *classad.Evaluator, stack []classad.Value) (classad.Val
 				stack = stack[:len(stack)-1] // discard undefined; fall through to fallback
 				ip++
 			}
+		case OpCallCheck:
+			ci := &p.calls[in.A]
+			if !ev.CanCallFunction(ci.name, ci.argc) {
+				stack = append(stack, classad.NewErrorValue())
+				ip = ci.end
+			} else {
+				ip++
+			}
+		case OpCall:
+			ci := &p.calls[in.A]
+			n := len(stack) - ci.argc
+			// The implementation may retain its argument slice, so hand it a copy
+			// rather than a window onto the reused stack.
+			args := append([]classad.Value(nil), stack[n:]...)
+			stack = append(stack[:n], ev.CallFunction(ci.name, args))
+			ip++
 		case OpEvalNode:
 			stack = append(stack, ev.Evaluate(p.nodes[in.A]))
 			ip++
@@ -105,3 +153,20 @@ func exec(p *Program, ev *classad.Evaluator, stack []classad.Value) (classad.Val
 	}
 	return stack[len(stack)-1], stack
 }
+
+// Exec runs p in ev's current scope, implementing classad.Program so a ClassAd's
+// evaluation cache can run attribute programs (see CompileAttr). Unlike Run it
+// does not recover: it runs inside an evaluation, whose entry point does.
+func (p *Program) Exec(ev *classad.Evaluator) classad.Value {
+	var buf [16]classad.Value
+	v, _ := exec(p, ev, buf[:0])
+	return v
+}
+
+// CompileAttr is a classad.ProgramCompiler over CompileProgram, for
+// ClassAd.EnableCache:
+//
+//	ad.EnableCache(vm.CompileAttr)
+func CompileAttr(expr ast.Expr) classad.Program {
+	return CompileProgram(expr)
+}
@@ -0,0 +1,93 @@
+package vm
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/PelicanPlatform/classad/classad"
+)
+
+func TestRunWithOptions(t *testing.T) {
+	ad, _ := classad.Parse(`[A = 1; B = A + A; C = B + B; S = strcat("ab", "cd")]`)
+	q, _ := Parse(`C > 1 && size(S) == 4`)
+
+	v, err := RunWithOptions(q.Program(), ad, classad.EvalOptions{MaxSteps: 1000})
+	if err != nil || v.String() != "true" {
+		t.Fatalf("within limits: %v, %v", v, err)
+	}
+	for _, tt := range []struct {
+		opts classad.EvalOptions
+		want error
+	}{
+		{classad.EvalOptions{MaxSteps: 5}, classad.ErrMaxSteps},
+		{classad.EvalOptions{MaxDepth: 2}, classad.ErrMaxDepth},
+		{classad.EvalOptions{MaxAllocBytes: 2}, classad.ErrMaxAllocBytes},
+	} {
+		v, err := RunWithOptions(q.Program(), ad, tt.opts)
+		if !v.IsError() || !errors.Is(err, tt.want) {
+			t.Errorf("%+v: %v, %v; want error, %v", tt.opts, v, err, tt.want)
+		}
+	}
+}
+
+func TestQueryWithOptions_Matcher(t *testing.T) {
+	small, _ := classad.Parse(`[X = 1]`)
+	big, _ := classad.Parse(`[X = 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1]`)
+	q, _ := Parse(`X > 0`)
+	bq := q.WithOptions(classad.EvalOptions{MaxSteps: 10})
+
+	m := bq.Matcher()
+	if !m.Matches(small) || bq.Err() != nil {
+		t.Fatalf("small ad: want a match and no limit error, got %v", bq.Err())
+	}
+	if m.Matches(big) {
+		t.Error("an ad over the step limit matched")
+	}
+	if !m.Matches(small) {
+		t.Error("the budget was not reset for the next ad")
+	}
+	if !errors.Is(bq.Err(), classad.ErrMaxSteps) {
+		t.Errorf("Err() = %v, want ErrMaxSteps", bq.Err())
+	}
+	if !q.Matches(big) || q.Err() != nil {
+		t.Error("WithOptions changed the unbounded query")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	cq := q.WithOptions(classad.EvalOptions{Context: ctx})
+	if cq.Matches(small) || !errors.Is(cq.Err(), context.Canceled) {
+		t.Errorf("cancelled context: Err() = %v", cq.Err())
+	}
+}
+
+func TestQueryWithOptions_VecEval(t *testing.T) {
+	src := newTestSource([]map[string]string{{"A": "1"}, {"A": "5"}})
+	q, _ := Parse(`A > 2 && A < 10`)
+
+	vec, ok := q.WithOptions(classad.EvalOptions{MaxSteps: 100}).VecEval(src, 2, nil)
+	if !ok || vec.IsTrue(0) || !vec.IsTrue(1) {
+		t.Fatalf("within limits: ok=%v", ok)
+	}
+	bq := q.WithOptions(classad.EvalOptions{MaxSteps: 3})
+	vec, ok = bq.VecEval(src, 2, nil)
+	if !ok || vec.IsTrue(0) || vec.IsTrue(1) {
+		t.Errorf("over the step limit: ok=%v, want no matches", ok)
+	}
+	if !errors.Is(bq.Err(), classad.ErrMaxSteps) {
+		t.Errorf("Err() = %v, want ErrMaxSteps", bq.Err())
+	}
+
+	// A batch the executor declines is left to the scalar interpreter, limits
+	// and all, rather than failing here.
+	lists := newTestSource([]map[string]string{{"L": "{1}"}, {"L": "{2}"}})
+	lq, _ := Parse(`L == 1`)
+	bq = lq.WithOptions(classad.EvalOptions{MaxSteps: 1})
+	if _, ok := bq.VecEval(lists, 2, nil); ok || bq.Err() != nil {
+		t.Errorf("list column over the step limit: ok=%v, Err() = %v; want declined", ok, bq.Err())
+	}
+	if _, ok := q.WithOptions(classad.EvalOptions{MaxAllocBytes: 1 << 20}).VecEval(src, 2, nil); ok {
+		t.Error("a query limiting allocations was not left to the scalar interpreter")
+	}
+}
@@ -11,19 +11,25 @@ import "github.com/PelicanPlatform/classad/classad"
 // A Matcher holds mutable state (the reused evaluator and stack) and is NOT safe
 // for concurrent use. A parallel scan should use one Matcher per goroutine.
 type Matcher struct {
-	prog  *Program
-	ev    *classad.Evaluator
-	stack []classad.Value
+	prog   *Program
+	ev     *classad.Evaluator
+	stack  []classad.Value
+	limits *queryLimits // the query's, when it is bounded
 }
 
 // Matcher returns a reusable Matcher for the query. Create one per scanning
 // goroutine.
 func (q *Query) Matcher() *Matcher {
-	return &Matcher{
-		prog:  q.prog,
-		ev:    classad.NewEvaluator(nil),
-		stack: make([]classad.Value, 0, 16),
+	m := &Matcher{
+		prog:   q.prog,
+		ev:     classad.NewEvaluator(nil),
+		stack:  make([]classad.Value, 0, 16),
+		limits: q.limits,
 	}
+	if q.limits != nil {
+		m.ev.SetOptions(q.limits.opts)
+	}
+	return m
 }
 
 // Eval evaluates the query against scope and returns the raw Value, reusing the
@@ -31,6 +37,9 @@ func (q *Query) Matcher() *Matcher {
 // reference resolves to an error value.
 func (m *Matcher) Eval(scope *classad.ClassAd) (result classad.Value) {
 	m.ev.SetScope(scope)
+	if m.limits != nil {
+		defer m.checkLimits(&result)
+	}
 	defer classad.RecoverCyclic(&result)
 	result, m.stack = exec(m.prog, m.ev, m.stack)
 	return result
@@ -43,3 +52,21 @@ func (m *Matcher) Matches(scope *classad.ClassAd) bool {
 	b, err := v.BoolValue()
 	return err == nil && b
 }
+
+// checkLimits makes the result of an evaluation that exceeded the query's limits
+// error -- even one whose budget ran out inside a lazy list element, which
+// recovers locally -- and records the cause on the query. Deferred ahead of
+// RecoverCyclic, so it runs after it.
+func (m *Matcher) checkLimits(result *classad.Value) {
+	if err := m.ev.LimitErr(); err != nil {
+		m.limits.record(err)
+		*result = classad.NewErrorValue()
+	}
+}
+
+// SetFunctions installs a scoped registry of user-defined functions, consulted
+// before the default registry by every subsequent evaluation through this
+// Matcher (including EvalResolved). nil clears it.
+func (m *Matcher) SetFunctions(r *classad.FunctionRegistry) {
+	m.ev.SetFunctions(r)
+}
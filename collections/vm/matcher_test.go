@@ -3,6 +3,7 @@ package vm
 import (
 	"testing"
 
+	"github.com/PelicanPlatform/classad/ast"
 	"github.com/PelicanPlatform/classad/classad"
 	"github.com/PelicanPlatform/classad/parser"
 )
@@ -87,3 +88,73 @@ func reversed(n int) []int {
 	}
 	return out
 }
+
+// TestUserFunctionNative checks that a call to a user-defined function compiles
+// to native instructions (so it can run wire-native via EvalResolved) and agrees
+// with the tree-walker, including the unresolvable and wrong-arity cases and a
+// Matcher-scoped registry.
+func TestUserFunctionNative(t *testing.T) {
+	if err := classad.RegisterFunction("vmTestScale", classad.ExactArity(2), func(args []classad.Value) classad.Value {
+		a, err1 := args[0].NumberValue()
+		b, err2 := args[1].NumberValue()
+		if err1 != nil || err2 != nil {
+			return classad.NewErrorValue()
+		}
+		return classad.NewRealValue(a * b)
+	}); err != nil {
+		t.Fatal(err)
+	}
+	defer classad.UnregisterFunction("vmTestScale")
+
+	scope, err := classad.Parse(`[Memory = 1024; A0 = 0 =!= vmTestScale(A0)]`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, src := range []string{
+		`vmTestScale(Memory, 2) > 2000`,
+		`vmTestScale(Memory, 2)`,
+		`vmTestScale(Memory)`,
+		`noSuchVmFunc(Memory)`,
+		`0 =!= vmTestScale(A0)`,
+		`Memory > 1 && vmTestScale(Missing, 1) =?= undefined`,
+	} {
+		expr, err := parser.ParseExpr(src)
+		if err != nil {
+			t.Fatalf("parse %q: %v", src, err)
+		}
+		q := Compile(expr)
+		if !q.Native() {
+			t.Errorf("%q: not native", src)
+		}
+		want := refEval(scope, expr)
+		if got := Run(q.prog, scope); !valuesEqual(want, got) {
+			t.Errorf("%q: Run=%s, tree-walker=%s", src, describe(got), describe(want))
+		}
+		m := q.Matcher()
+		got := m.EvalResolved(func(name string, _ ast.AttributeScope) classad.Value {
+			return scope.EvaluateAttr(name)
+		})
+		if !valuesEqual(want, got) {
+			t.Errorf("%q: EvalResolved=%s, tree-walker=%s", src, describe(got), describe(want))
+		}
+	}
+
+	scoped := classad.NewFunctionRegistry()
+	if err := scoped.Register("vmTestOnlyScoped", classad.ExactArity(0), func([]classad.Value) classad.Value {
+		return classad.NewIntValue(7)
+	}); err != nil {
+		t.Fatal(err)
+	}
+	q, err := Parse(`vmTestOnlyScoped() + 1`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := q.Matcher()
+	if v := m.Eval(scope); !v.IsError() {
+		t.Errorf("unscoped matcher = %s, want error", describe(v))
+	}
+	m.SetFunctions(scoped)
+	if v, _ := m.Eval(scope).IntValue(); v != 8 {
+		t.Errorf("scoped matcher = %s, want 8", describe(m.Eval(scope)))
+	}
+}
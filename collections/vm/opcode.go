@@ -48,6 +48,16 @@ const (
 	// and fall through to the compiled fallback expression.
 	OpJmpIfNotUndef
 
+	// OpCallCheck guards a call to a user-defined function (calls[A]): if the
+	// name does not resolve through the evaluator's registries with the call's
+	// argument count, it pushes error and jumps past the call's OpCall without
+	// evaluating the arguments (the tree-walker's arity-before-arguments rule);
+	// otherwise it falls through to the compiled arguments.
+	OpCallCheck
+	// OpCall pops calls[A].argc argument values and pushes the result of the
+	// user-defined function calls[A].name applied to them (Evaluator.CallFunction).
+	OpCall
+
 	// OpEvalNode delegates nodes[A] (an ast.Expr subtree) to the tree-walking
 	// evaluator and pushes its value. The escape hatch for node types the
 	// compiler does not lower to native instructions.
@@ -16,6 +16,15 @@ type refInfo struct {
 	scope ast.AttributeScope
 }
 
+// callInfo is a pooled call to a user-defined function, targeted by OpCallCheck
+// and OpCall. end is the instruction index just past the OpCall, where a failed
+// OpCallCheck resumes.
+type callInfo struct {
+	name string
+	argc int
+	end  int
+}
+
 // Program is a compiled expression: a flat instruction stream plus the constant
 // pools its instructions index into.
 type Program struct {
@@ -24,6 +33,7 @@ type Program struct {
 	ops    []string        // OpBinop / OpUnop operator strings
 	refs   []refInfo       // OpLoadRef
 	nodes  []ast.Expr      // OpEvalNode (delegated subtrees)
+	calls  []callInfo      // OpCallCheck / OpCall (user-defined functions)
 
 	// readAttrs is the set of distinct unscoped attribute names the program may
 	// read, in first-seen order. Used by the store's query planner (M3) to pick
@@ -33,6 +43,11 @@ type Program struct {
 	// expr is the source expression, retained so the store can compute a read
 	// plan (see ReadPlan) for partial-decode evaluation.
 	expr ast.Expr
+
+	// height is the nesting depth of expr, which the tree-walker would reach
+	// evaluating it; a bounded run checks it against EvalOptions.MaxDepth up
+	// front, since the flat interpreter has no depth of its own.
+	height int
 }
 
 // ReadAttrs returns the distinct unscoped attribute names the program may read.
@@ -2,6 +2,7 @@ package vm
 
 import (
 	"strings"
+	"sync"
 
 	"github.com/PelicanPlatform/classad/ast"
 	"github.com/PelicanPlatform/classad/classad"
@@ -12,6 +13,45 @@ import (
 // convenience of a match predicate). The store uses ReadAttrs for planning.
 type Query struct {
 	prog *Program
+	// limits, when non-nil, bounds every evaluation of the query (see
+	// WithOptions).
+	limits *queryLimits
+}
+
+// queryLimits is the EvalOptions of a bounded query and the first limit one of
+// its evaluations hit, shared by every Matcher of the query.
+type queryLimits struct {
+	opts classad.EvalOptions
+	mu   sync.Mutex
+	err  error
+}
+
+func (l *queryLimits) record(err error) {
+	l.mu.Lock()
+	if l.err == nil {
+		l.err = err
+	}
+	l.mu.Unlock()
+}
+
+// WithOptions returns the query bounded by opts: every evaluation of the
+// returned query -- Eval, Matches, its Matchers and VecEval -- runs under the
+// limits, each with its own budget, and one that exceeds them is error (a
+// constraint non-match). Err reports the first limit hit. The compiled program is
+// shared with q, which stays unbounded.
+func (q *Query) WithOptions(opts classad.EvalOptions) *Query {
+	return &Query{prog: q.prog, limits: &queryLimits{opts: opts}}
+}
+
+// Err returns the *classad.LimitError of the first evaluation of the query that
+// exceeded its WithOptions limits, or nil if none has.
+func (q *Query) Err() error {
+	if q == nil || q.limits == nil {
+		return nil
+	}
+	q.limits.mu.Lock()
+	defer q.limits.mu.Unlock()
+	return q.limits.err
 }
 
 // Compile compiles an already-parsed expression into a Query.
@@ -28,6 +68,20 @@ func Compile(expr ast.Expr) *Query {
 	return &Query{prog: CompileProgram(expr)}
 }
 
+// CompileExpr compiles a *classad.Expr into a Query, taking its tree as it is --
+// one built with the classad expression builder never becomes text:
+//
+//	q := vm.CompileExpr(classad.Attr("Owner").Eq(classad.Str(user)))
+//
+// A nil or empty Expr compiles as undefined, matching no ad.
+func CompileExpr(e *classad.Expr) *Query {
+	expr := e.AST()
+	if expr == nil {
+		expr = &ast.UndefinedLiteral{}
+	}
+	return Compile(expr)
+}
+
 // referencesCurrentTime reports whether expr reads the magic CurrentTime attribute (an
 // unscoped reference by that name). It reuses the read-collection walk, so it sees the same
 // unscoped references the planner does.
@@ -64,13 +118,22 @@ func (q *Query) Expr() ast.Expr {
 func (q *Query) ReadAttrs() []string { return q.prog.readAttrs }
 
 // Eval evaluates the query against scope and returns the raw Value.
-func (q *Query) Eval(scope *classad.ClassAd) classad.Value { return Run(q.prog, scope) }
+func (q *Query) Eval(scope *classad.ClassAd) classad.Value {
+	if q.limits == nil {
+		return Run(q.prog, scope)
+	}
+	v, err := RunWithOptions(q.prog, scope, q.limits.opts)
+	if err != nil {
+		q.limits.record(err)
+	}
+	return v
+}
 
 // Matches reports whether the query evaluates to boolean true against scope.
 // Undefined, error, and non-boolean results are treated as non-matches, matching
 // how a ClassAd requirement/constraint is applied.
 func (q *Query) Matches(scope *classad.ClassAd) bool {
-	v := Run(q.prog, scope)
+	v := q.Eval(scope)
 	b, err := v.BoolValue()
 	return err == nil && b
 }
@@ -266,10 +266,49 @@ func (q *Query) VecEval(src ColumnSource, n int, scratch *VecScratch) (*Vec, boo
 	if len(q.prog.nodes) != 0 {
 		return nil, false // a delegated subtree needs a real per-record scope
 	}
+	if q.limits != nil && q.limits.opts.MaxAllocBytes > 0 {
+		return nil, false // what a record allocates is the scalar interpreter's to count
+	}
 	if scratch == nil {
 		scratch = &VecScratch{}
 	}
-	return execVec(q.prog, scratch.evaluator(), src, n, scratch)
+	v, ok := execVec(q.prog, scratch.evaluator(), src, n, scratch)
+	if !ok || q.limits == nil {
+		return v, ok
+	}
+	if err := vecLimit(q.prog, q.limits.opts); err != nil {
+		// Every record runs the same instructions, so a program over its
+		// limits is over them for the whole batch: all error.
+		q.limits.record(err)
+		scratch.reset(n)
+		v = scratch.push()
+		fillState(v, VsError)
+	}
+	return v, true
+}
+
+// vecLimit checks a batch the vector executor has run against opts. It runs
+// only native instructions over literal column values, so what the scalar
+// interpreter would spend per record is fixed by the program: a step on entry,
+// at the program's height, and one per instruction. (Loading a literal
+// attribute costs the scalar interpreter a step more against a ClassAd; that
+// is not counted here.) The Context is polled once per batch; a query limiting
+// allocations never gets here.
+func vecLimit(p *Program, opts classad.EvalOptions) error {
+	if opts.Context != nil {
+		if err := opts.Context.Err(); err != nil {
+			return &classad.LimitError{Err: err}
+		}
+	}
+	if opts.MaxDepth > 0 && p.height-1 >= opts.MaxDepth {
+		return &classad.LimitError{Err: classad.ErrMaxDepth, Limit: int64(opts.MaxDepth)}
+	}
+	if opts.MaxSteps > 0 {
+		if steps := int64(1 + len(p.code)); steps > opts.MaxSteps {
+			return &classad.LimitError{Err: classad.ErrMaxSteps, Limit: opts.MaxSteps}
+		}
+	}
+	return nil
 }
 
 func execVec(p *Program, ev *classad.Evaluator, src ColumnSource, n int, s *VecScratch) (*Vec, bool) {
@@ -260,6 +260,36 @@ func TestQueryMatches(t *testing.T) {
 	}
 }
 
+func TestCompileExpr(t *testing.T) {
+	ad, _ := classad.Parse(`[Owner = "o\"brien"; RequestMemory = 4096; Cpus = 4]`)
+	owner := classad.Attr("Owner")
+	built := classad.Attr("RequestMemory").Gt(2048).And(owner.Eq(classad.Str(`o"brien`)))
+	q := CompileExpr(built)
+	if !q.Matches(ad) {
+		t.Errorf("%s does not match %s", built.Unparse(), ad)
+	}
+	if got := q.ReadAttrs(); len(got) != 2 {
+		t.Errorf("ReadAttrs = %v, want [RequestMemory Owner]", got)
+	}
+	// A quote in the value stays in the literal.
+	if q := CompileExpr(owner.Eq(classad.Str(`x" || true || "`))); q.Matches(ad) {
+		t.Error("a quoted value escaped its literal")
+	}
+	for _, e := range []*classad.Expr{
+		classad.Cond(classad.MyAttr("Cpus").Ge(4), classad.List(1, 2), classad.List()).Index(1).Eq(2),
+		classad.Call("member", "o\"brien", classad.List(owner, "bob")),
+		classad.Record(map[string]any{"m": classad.Attr("Cpus").Mul(2)}).Attr("m").Eq(8),
+		classad.Attr("Missing").Elvis(classad.TargetAttr("Cpus")).Elvis(4).Eq(4),
+	} {
+		if !CompileExpr(e).Matches(ad) {
+			t.Errorf("%s does not match", e.Unparse())
+		}
+	}
+	if CompileExpr(nil).Matches(ad) {
+		t.Error("a nil expression matched")
+	}
+}
+
 func FuzzDifferential(f *testing.F) {
 	for _, ss := range scopeSources {
 		for _, es := range exprSources {
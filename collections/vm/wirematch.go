@@ -23,6 +23,9 @@ func (m *Matcher) EvalResolved(resolver func(name string, scope ast.AttributeSco
 	m.ev.SetScope(nil)
 	m.ev.SetResolver(resolver)
 	defer m.ev.SetResolver(nil)
+	if m.limits != nil {
+		defer m.checkLimits(&result)
+	}
 	defer classad.RecoverCyclic(&result)
 	result, m.stack = exec(m.prog, m.ev, m.stack)
 	return result
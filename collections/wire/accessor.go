@@ -42,6 +42,19 @@ func (c *cursor) uvarint() uint64 {
 	return v
 }
 
+func (c *cursor) varint() int64 {
+	if !c.ok {
+		return 0
+	}
+	v, n := binary.Varint(c.b[c.pos:])
+	if n <= 0 {
+		c.ok = false
+		return 0
+	}
+	c.pos += n
+	return v
+}
+
 func (c *cursor) byteAt() byte {
 	if !c.ok || c.pos >= len(c.b) {
 		c.ok = false
@@ -627,10 +640,13 @@ func skipNode(c *cursor, depth int) {
 			return
 		}
 		c.pos += n
-	case nReal:
+	case nReal, nRelTime:
 		c.skip(8)
 	case nString:
 		c.skip(int(c.uvarint()))
+	case nAbsTime:
+		c.varint()
+		c.varint()
 	case nAttrRef:
 		c.skip(1) // scope byte
 		c.uvarint()
@@ -257,6 +257,22 @@ func (d *decoder) node(depth int) (ast.Expr, error) {
 			return nil, err
 		}
 		return &ast.StringLiteral{Value: s}, nil
+	case nAbsTime:
+		secs, err := d.varint()
+		if err != nil {
+			return nil, err
+		}
+		off, err := d.varint()
+		if err != nil {
+			return nil, err
+		}
+		return &ast.AbsTimeLiteral{Secs: secs, Offset: int(off)}, nil
+	case nRelTime:
+		bits, err := d.uint64()
+		if err != nil {
+			return nil, err
+		}
+		return &ast.RelTimeLiteral{Secs: math.Float64frombits(bits)}, nil
 	case nAttrRef:
 		scope, err := d.byteAt()
 		if err != nil {
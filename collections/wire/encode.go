@@ -275,6 +275,13 @@ func (e *encoder) node(expr ast.Expr) {
 	case *ast.StringLiteral:
 		e.buf = append(e.buf, nString)
 		e.putString(v.Value)
+	case *ast.AbsTimeLiteral:
+		e.buf = append(e.buf, nAbsTime)
+		e.buf = binary.AppendVarint(e.buf, v.Secs)
+		e.buf = binary.AppendVarint(e.buf, int64(v.Offset))
+	case *ast.RelTimeLiteral:
+		e.buf = append(e.buf, nRelTime)
+		e.buf = binary.LittleEndian.AppendUint64(e.buf, math.Float64bits(v.Secs))
 	case *ast.AttributeReference:
 		if e.inline {
 			e.buf = append(e.buf, nAttrRefStr, byte(v.Scope))
@@ -74,6 +74,9 @@ const (
 	// Sealer (the fast index/match path) it is opaque -- encrypted attributes are never
 	// indexed. Layout: + uvarint(len nonce) + nonce + uvarint(len ct) + ct.
 	nEncrypted = 0x17
+	// Time literals (the classad absolute/relative time values).
+	nAbsTime = 0x18 // + zigzag varint Unix seconds + zigzag varint UTC offset seconds
+	nRelTime = 0x19 // + 8 bytes IEEE-754 little-endian float64 seconds
 )
 
 // nameHash32 is a case-insensitive 32-bit hash of an attribute name, used in the
@@ -66,6 +66,17 @@ func AppendRealNode(dst []byte, f float64) []byte {
 	return binary.LittleEndian.AppendUint64(append(dst, nReal), math.Float64bits(f))
 }
 
+// AppendAbsTimeNode and AppendRelTimeNode append a time literal node. Time literals are
+// not scalar Literals (LiteralValue reports false for them): they decode through DecodeNode
+// to an *ast.AbsTimeLiteral / *ast.RelTimeLiteral and evaluate like any other expression.
+func AppendAbsTimeNode(dst []byte, secs int64, offset int) []byte {
+	return binary.AppendVarint(binary.AppendVarint(append(dst, nAbsTime), secs), int64(offset))
+}
+
+func AppendRelTimeNode(dst []byte, secs float64) []byte {
+	return binary.LittleEndian.AppendUint64(append(dst, nRelTime), math.Float64bits(secs))
+}
+
 func AppendStringNode(dst []byte, s string) []byte {
 	dst = binary.AppendUvarint(append(dst, nString), uint64(len(s)))
 	return append(dst, s...)
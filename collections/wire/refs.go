@@ -59,10 +59,13 @@ func refNode(c *cursor, depth int, dst []uint32) []uint32 {
 			return dst
 		}
 		c.pos += n
-	case nReal:
+	case nReal, nRelTime:
 		c.skip(8)
 	case nString:
 		c.skip(int(c.uvarint()))
+	case nAbsTime:
+		c.varint()
+		c.varint()
 	case nAttrRef:
 		scope := c.byteAt()
 		id := c.uvarint()
@@ -165,10 +168,13 @@ func refNodeNames(c *cursor, depth int, dst [][]byte) [][]byte {
 			return dst
 		}
 		c.pos += n
-	case nReal:
+	case nReal, nRelTime:
 		c.skip(8)
 	case nString:
 		c.skip(int(c.uvarint()))
+	case nAbsTime:
+		c.varint()
+		c.varint()
 	case nAttrRef:
 		c.skip(1)   // scope byte
 		c.uvarint() // interned id: an inline-name ad resolves by name, nothing to collect
@@ -97,6 +97,22 @@ func (d *decoder) appendNode(dst []byte, depth int) ([]byte, error) {
 			return dst, err
 		}
 		return strconv.AppendFloat(dst, math.Float64frombits(bits), 'g', -1, 64), nil
+	case nAbsTime:
+		secs, err := d.varint()
+		if err != nil {
+			return dst, err
+		}
+		off, err := d.varint()
+		if err != nil {
+			return dst, err
+		}
+		return append(dst, (&ast.AbsTimeLiteral{Secs: secs, Offset: int(off)}).String()...), nil
+	case nRelTime:
+		bits, err := d.uint64()
+		if err != nil {
+			return dst, err
+		}
+		return append(dst, (&ast.RelTimeLiteral{Secs: math.Float64frombits(bits)}).String()...), nil
 	case nString:
 		s, err := d.readStringBytes()
 		if err != nil {
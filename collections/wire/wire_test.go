@@ -79,6 +79,42 @@ func TestRoundTripStandalone(t *testing.T) {
 	}
 }
 
+// TestRoundTripTimeLiterals covers the time literal nodes, which have no source
+// syntax and so are not in roundTripCases: encode/decode, skipping (NodeLen), and
+// rendering back to the absTime()/relTime() text.
+func TestRoundTripTimeLiterals(t *testing.T) {
+	orig := &ast.ClassAd{Attributes: []*ast.AttributeAssignment{
+		{Name: "Start", Value: &ast.AbsTimeLiteral{Secs: -86400, Offset: -5 * 3600}},
+		{Name: "Span", Value: &ast.RelTimeLiteral{Secs: 90.5}},
+		{Name: "List", Value: &ast.ListLiteral{Elements: []ast.Expr{&ast.RelTimeLiteral{Secs: -1}, &ast.IntegerLiteral{Value: 7}}}},
+	}}
+	enc := EncodeStandalone(orig)
+	got, err := DecodeStandalone(enc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !toClassAd(orig).Equal(toClassAd(got)) {
+		t.Fatalf("round-trip mismatch:\n orig=%s\n  got=%s", orig.String(), got.String())
+	}
+
+	node := AppendAbsTimeNode(nil, 1714521600, 7200)
+	node = append(node, nUndefined) // trailing bytes must not be consumed
+	if n, ok := NodeLen(node); !ok || n != len(node)-1 {
+		t.Errorf("NodeLen(abstime) = %d, %v", n, ok)
+	}
+	text, err := AppendNodeTextInline(nil, node[:len(node)-1])
+	if err != nil || string(text) != `absTime("2024-05-01T02:00:00+02:00")` {
+		t.Errorf("render abstime = %q, %v", text, err)
+	}
+	text, err = AppendNodeTextInline(nil, AppendRelTimeNode(nil, 3600))
+	if err != nil || string(text) != `relTime("01:00:00")` {
+		t.Errorf("render reltime = %q, %v", text, err)
+	}
+	if _, ok := LiteralValue(AppendRelTimeNode(nil, 1)); ok {
+		t.Error("LiteralValue accepted a time node")
+	}
+}
+
 // TestEncodeDeterministic checks that encoding is stable and that re-encoding a
 // decoded ad reproduces identical bytes (important for content-addressing).
 func TestEncodeDeterministic(t *testing.T) {
@@ -180,13 +180,18 @@ func compileFilters(attrs []string, aggs []AggSpec) ([]*aggFilter, bool, error)
 // keeps reports whether a row passes this filter. The scope ad is reused across rows and
 // filters, so a filtered aggregate costs one rebind plus one evaluation per row rather than
 // an allocation. A filter that does not evaluate to true excludes the row, so undefined and
-// error behave as they do everywhere else in ClassAd.
-func (f *aggFilter) keeps(scope *classad.ClassAd, vals []classad.Value) bool {
+// error behave as they do everywhere else in ClassAd. The evaluation is bounded by opts;
+// the error is the *classad.LimitError of one over its limits.
+func (f *aggFilter) keeps(scope *classad.ClassAd, vals []classad.Value, opts classad.EvalOptions) (bool, error) {
 	for k, c := range f.cols {
 		bindValue(scope, f.names[k], vals[c])
 	}
-	b, err := f.expr.Eval(scope).BoolValue()
-	return err == nil && b
+	v, err := f.expr.EvalWithOptions(scope, nil, opts)
+	if err != nil {
+		return false, err
+	}
+	b, err := v.BoolValue()
+	return err == nil && b, nil
 }
 
 // bindValue binds one projected value into the reused filter scope. Every attribute the
@@ -235,6 +240,13 @@ func bindValue(ad *classad.ClassAd, name string, v classad.Value) {
 // the scan halts early with the groups accumulated so far -- used to abandon a
 // scan whose client has gone away.
 func AggregateValues(seq iter.Seq[[]classad.Value], attrs []string, groupCols []GroupCol, aggs []AggSpec, groupCol, aggCol []int, stop func() bool) ([]AggRow, error) {
+	return AggregateValuesWithOptions(seq, attrs, groupCols, aggs, groupCol, aggCol, stop, classad.EvalOptions{})
+}
+
+// AggregateValuesWithOptions is AggregateValues with every per-aggregate filter evaluation
+// bounded by opts, for filters from an untrusted client. A filter evaluation over the limits
+// fails the aggregate with its *classad.LimitError.
+func AggregateValuesWithOptions(seq iter.Seq[[]classad.Value], attrs []string, groupCols []GroupCol, aggs []AggSpec, groupCol, aggCol []int, stop func() bool, opts classad.EvalOptions) ([]AggRow, error) {
 	nGroup := len(groupCols)
 	for _, a := range aggs {
 		if a.Func == AggCountDistinct && a.Arg == "*" {
@@ -286,8 +298,14 @@ func AggregateValues(seq iter.Seq[[]classad.Value], attrs []string, groupCols []
 			order = append(order, key)
 		}
 		for i, a := range aggs {
-			if filters[i] != nil && !filters[i].keeps(scope, vals) {
-				continue // this row is outside THIS aggregate's filter, not the group
+			if filters[i] != nil {
+				keep, err := filters[i].keeps(scope, vals, opts)
+				if err != nil {
+					return nil, err
+				}
+				if !keep {
+					continue // this row is outside THIS aggregate's filter, not the group
+				}
 			}
 			var v classad.Value
 			if aggCol[i] >= 0 {
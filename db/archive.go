@@ -211,6 +211,12 @@ func (t *ArchiveTable) QueryLimit(constraint string, limit int) (iter.Seq[*class
 	return t.a.QueryLimit(q, limit), nil
 }
 
+// QueryLimitConstraint is QueryLimit for an already-compiled constraint; see
+// DB.QueryConstraint.
+func (t *ArchiveTable) QueryLimitConstraint(c *Constraint, limit int) iter.Seq[*classad.ClassAd] {
+	return t.a.QueryLimit(c.q, limit)
+}
+
 // QueryProject scans the matching ads and yields each projected to just attrs' values, read
 // wire-native where possible -- so an aggregate reads only the attributes it needs instead
 // of fully decoding every record. Errors only on a malformed constraint.
@@ -222,6 +228,11 @@ func (t *ArchiveTable) QueryProject(constraint string, attrs []string) (iter.Seq
 	return t.a.QueryProject(q, attrs), nil
 }
 
+// QueryProjectConstraint is QueryProject for an already-compiled constraint.
+func (t *ArchiveTable) QueryProjectConstraint(c *Constraint, attrs []string) iter.Seq[[]classad.Value] {
+	return t.a.QueryProject(c.q, attrs)
+}
+
 // QueryRawProjected yields each matching ad as a raw projected subset (only the projection
 // attributes, rendered from the stored representation), newest first — the archive-side of the
 // server-side projection op. redact strips private attributes. It mirrors db.DB.QueryRawProjected
@@ -235,6 +246,11 @@ func (t *ArchiveTable) QueryRawProjected(constraint string, projection []string,
 	return t.a.QueryRawProjected(q, projection, false, redact), nil
 }
 
+// QueryRawProjectedConstraint is QueryRawProjected for an already-compiled constraint.
+func (t *ArchiveTable) QueryRawProjectedConstraint(c *Constraint, projection []string, redact bool) iter.Seq[collections.RawAd] {
+	return t.a.QueryRawProjected(c.q, projection, false, redact)
+}
+
 // QueryRawProjectedStats is QueryRawProjected that also fills stats (may be nil) with the
 // per-scan work breakdown for EXPLAIN ANALYZE (segments scanned/pruned, records decided from
 // columns vs reassembled, rows matched).
@@ -257,6 +273,12 @@ func (t *ArchiveTable) QueryRawProjectedRefs(constraint string, projection []str
 	return t.a.QueryRawProjected(q, projection, true, redact), nil
 }
 
+// QueryRawProjectedRefsConstraint is QueryRawProjectedRefs for an already-compiled
+// constraint.
+func (t *ArchiveTable) QueryRawProjectedRefsConstraint(c *Constraint, projection []string, redact bool) iter.Seq[collections.RawAd] {
+	return t.a.QueryRawProjected(c.q, projection, true, redact)
+}
+
 // QueryRawProjectedRefsStats is QueryRawProjectedRefs that also fills stats (may be nil) with the
 // per-scan work breakdown for EXPLAIN ANALYZE.
 func (t *ArchiveTable) QueryRawProjectedRefsStats(constraint string, projection []string, redact bool, stats *collections.ScanStats) (iter.Seq[collections.RawAd], error) {
@@ -267,6 +289,12 @@ func (t *ArchiveTable) QueryRawProjectedRefsStats(constraint string, projection
 	return t.a.QueryRawProjectedStats(q, projection, true, redact, stats), nil
 }
 
+// QueryRawProjectedRefsStatsConstraint is QueryRawProjectedRefsStats for an
+// already-compiled constraint.
+func (t *ArchiveTable) QueryRawProjectedRefsStatsConstraint(c *Constraint, projection []string, redact bool, stats *collections.ScanStats) iter.Seq[collections.RawAd] {
+	return t.a.QueryRawProjectedStats(c.q, projection, true, redact, stats)
+}
+
 // Aggregate runs a server-side GROUP BY over the archive's matches: it applies the
 // constraint (using the archive's zone-map pruning, so segments no matching record can
 // fall in are never scanned), groups by the raw group columns, and reduces each group
@@ -337,6 +365,15 @@ func (t *ArchiveTable) AggregateCols(constraint string, groupCols []GroupCol, ag
 	return AggregateValues(seq, attrs, groupCols, aggs, groupCol, aggCol, nil)
 }
 
+// AggregateColsConstraint is AggregateCols for an already-compiled constraint, typically a
+// bounded one (see DB.QueryConstraint). It always scans: the index and columnar fast paths
+// take the constraint as text and evaluate it unbounded. The aggregates' filters are bounded
+// as c is, and one over the limits fails the aggregate; check c.Err for the constraint's.
+func (t *ArchiveTable) AggregateColsConstraint(c *Constraint, groupCols []GroupCol, aggs []AggSpec) ([]AggRow, error) {
+	attrs, groupCol, aggCol := AggProjection(groupCols, aggs)
+	return AggregateValuesWithOptions(t.QueryProjectConstraint(c, attrs), attrs, groupCols, aggs, groupCol, aggCol, nil, c.opts)
+}
+
 // aggregateFromIndex answers the aggregate without reading records where the per-segment
 // indexes already contain the answer, returning ok=false when they do not so the caller
 // scans. Every path here is restricted to an unconstrained COUNT(*): a WHERE clause would
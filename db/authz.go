@@ -1,7 +1,6 @@
 package db
 
 import (
-	"reflect"
 	"strings"
 
 	"github.com/PelicanPlatform/classad/ast"
@@ -20,10 +19,9 @@ import (
 // So authorization gets its own traversal, with two properties the planner's does not need:
 //
 //   - It collects every reference regardless of scope.
-//   - It walks by REFLECTION over the AST rather than a type switch per node kind. A type switch that
-//     misses a node kind is a silent authorization hole, and the AST has 19 node types with more
-//     possible later; reflection covers a new one the day it is added. This runs once per query, never
-//     per record, so the cost does not matter.
+//   - It walks with ast.Inspect rather than a type switch per node kind. A type switch that misses a
+//     node kind is a silent authorization hole; ast.Walk knows every node type and panics on one it
+//     does not, so a kind added to the AST without a traversal is a loud failure, not a bypass.
 
 // ConstraintRefs reports every attribute name an expression references, scoped or not, and whether the
 // expression makes a reference this analysis cannot resolve statically (see dynamic below).
@@ -34,9 +32,8 @@ func ConstraintRefs(expr string) (refs []string, dynamic bool) {
 	if strings.TrimSpace(expr) == "" {
 		return nil, false
 	}
-	// vm.Parse rather than classad.ParseExpr: classad.Expr wraps the AST in an unexported field, so a
-	// reflective walk cannot see through it (it silently found NOTHING, in every case, until this was
-	// caught). vm.Query.Expr() hands back the ast.Expr itself.
+	// vm.Parse rather than classad.ParseExpr: classad.Expr wraps the AST in an unexported field, and
+	// vm.Query.Expr() hands back the ast.Expr itself.
 	q, err := vm.Parse(expr)
 	if err != nil {
 		return nil, false
@@ -52,68 +49,35 @@ func ConstraintRefs(expr string) (refs []string, dynamic bool) {
 			refs = append(refs, name)
 		}
 	}
-	walkExpr(reflect.ValueOf(e), add, &dynamic, 0)
-	return refs, dynamic
-}
-
-// maxWalkDepth bounds the traversal. A parsed expression is already depth-bounded by the parser, so
-// this only guards against a cyclic AST -- which would otherwise hang an authorization check.
-const maxWalkDepth = 512
-
-// walkExpr collects attribute references from any AST value reachable from v.
-//
-// dynamic is set when the expression can name an attribute at RUNTIME, which no static walk can see:
-// eval() of anything but a constant string. eval("ClaimId") is resolved here (the constant is checked as
-// if it were written as a reference); eval(SomeAttr) is not resolvable and marks the expression dynamic
-// so the caller can refuse it rather than pass a reference it cannot see.
-func walkExpr(v reflect.Value, add func(string), dynamic *bool, depth int) {
-	if depth > maxWalkDepth || !v.IsValid() {
-		return
-	}
-	switch v.Kind() {
-	case reflect.Interface, reflect.Pointer:
-		if v.IsNil() {
-			return
-		}
-		if v.CanInterface() {
-			switch n := v.Interface().(type) {
-			case *ast.AttributeReference:
-				// Every reference, whatever its scope: MY.X, TARGET.X and X all name X.
-				add(n.Name)
-			case *ast.FunctionCall:
-				if strings.EqualFold(n.Name, "eval") {
-					if len(n.Args) == 1 {
-						if lit, ok := n.Args[0].(*ast.StringLiteral); ok {
-							// eval("Attr") is a reference to Attr, written indirectly.
-							add(lit.Value)
-						} else {
-							*dynamic = true
-						}
-					} else {
-						*dynamic = true
-					}
+	ast.Inspect(e, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.AttributeReference:
+			// Every reference, whatever its scope: MY.X, TARGET.X and X all name X.
+			add(n.Name)
+		case *ast.FunctionCall:
+			// eval() of anything but a constant string names an attribute at RUNTIME, which no
+			// static walk can see. eval("ClaimId") is a reference to ClaimId, written indirectly;
+			// eval(SomeAttr) marks the expression dynamic so the caller can refuse it rather than
+			// pass a reference it cannot see.
+			if strings.EqualFold(n.Name, "eval") {
+				if lit, ok := onlyArg(n).(*ast.StringLiteral); ok {
+					add(lit.Value)
+				} else {
+					dynamic = true
 				}
 			}
 		}
-		walkExpr(v.Elem(), add, dynamic, depth+1)
-	case reflect.Struct:
-		for i := 0; i < v.NumField(); i++ {
-			f := v.Field(i)
-			// Unexported fields cannot be read through reflection; the AST's expression-bearing
-			// fields are all exported, and skipping the rest keeps this from panicking.
-			if f.CanInterface() {
-				walkExpr(f, add, dynamic, depth+1)
-			}
-		}
-	case reflect.Slice, reflect.Array:
-		for i := 0; i < v.Len(); i++ {
-			walkExpr(v.Index(i), add, dynamic, depth+1)
-		}
-	case reflect.Map:
-		for _, k := range v.MapKeys() {
-			walkExpr(v.MapIndex(k), add, dynamic, depth+1)
-		}
+		return true
+	})
+	return refs, dynamic
+}
+
+// onlyArg returns the argument of a one-argument call, or nil.
+func onlyArg(call *ast.FunctionCall) ast.Expr {
+	if len(call.Args) != 1 {
+		return nil
 	}
+	return call.Args[0]
 }
 
 // PrivateConstraintRef reports the first private attribute an expression references, or "" if it
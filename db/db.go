@@ -48,6 +48,9 @@ type DB struct {
 	// Truncate/Restore blocks every writer for the whole reload); surfaced via OpStats.
 	snapLockCount atomic.Int64
 	snapLockNanos atomic.Int64
+
+	// onNewClassAd is Config.OnNewClassAd.
+	onNewClassAd func(key string, ad *classad.ClassAd) error
 }
 
 // lockSnapExclusive takes the DB-wide snapshot lock exclusively and returns a release
@@ -111,6 +114,13 @@ type Config struct {
 	// than leaving an operator to wonder why the first open after an upgrade took longer.
 	OnSealMigration func(segments int)
 
+	// OnNewClassAd, if set, is a write hook run on every ad stored with Txn.NewClassAd before the
+	// write is buffered. It may modify the ad in place, so ads are normalized as they enter the table
+	// (see transform.Ruleset.Hook) instead of in every producer. An error fails the transaction:
+	// Commit returns it and writes nothing. With a hook set NewClassAdOld declines, so old-format
+	// text is parsed and passes through the hook too.
+	OnNewClassAd func(key string, ad *classad.ClassAd) error
+
 	// SegmentSize overrides the arena segment size in bytes (see collections.Options).
 	// 0 uses the default (8 MiB). A smaller value seals segments sooner -- useful for
 	// tests and for tuning the sealed-segment accelerators (columnar scan, sealed indexes).
@@ -171,7 +181,7 @@ func OpenConfig(cfg Config) (*DB, error) {
 			return nil, err
 		}
 	}
-	db := &DB{c: c, id: loadOrCreateDBID(cfg.Dir), instance: randID(), dir: cfg.Dir, enc: enc}
+	db := &DB{c: c, id: loadOrCreateDBID(cfg.Dir), instance: randID(), dir: cfg.Dir, enc: enc, onNewClassAd: cfg.OnNewClassAd}
 	// Reapply any index/hot-set configuration persisted by a previous run's
 	// runtime changes (AddIndex/AddHotAttrs/...), so they survive a restart.
 	db.loadIndexConfig()
@@ -444,6 +454,9 @@ type (
 	QueryExplain    = collections.QueryExplain
 	ProbeExplain    = collections.ProbeExplain
 	MatchExplain    = collections.MatchExplain
+	MatchAnalysis   = collections.MatchAnalysis
+	ClauseAnalysis  = collections.ClauseAnalysis
+	Relaxation      = collections.Relaxation
 )
 
 // CodecStats reports the storage codec's state and effectiveness (name, dictionary
@@ -463,6 +476,20 @@ func (db *DB) ExplainMatch(job *classad.ClassAd, targetConstraint string) MatchE
 	return db.c.ExplainMatch(job, targetConstraint)
 }
 
+// AnalyzeMatch explains why job matches the slots it does, condor_q -better-analyze
+// style: how many slots each Requirements clause admits alone and cumulatively, how many
+// slots' own Requirements reject the job, and the minimal single-clause relaxations that
+// would match more ("RequestMemory <= 16384 would match 412 slots"). targetConstraint,
+// if non-empty, is the MATCH resource-side filter, analyzed as further clauses. The value
+// indexes narrow the slots it visits; errors only on a malformed targetConstraint.
+func (db *DB) AnalyzeMatch(job *classad.ClassAd, targetConstraint string) (MatchAnalysis, error) {
+	an, err := db.c.AnalyzeMatch(job, targetConstraint)
+	if err != nil {
+		return MatchAnalysis{}, fmt.Errorf("classad-db: bad target constraint %q: %w", targetConstraint, err)
+	}
+	return an, nil
+}
+
 // Stats returns a snapshot of the store's storage (ad count, segment/arena/dead
 // bytes) for observability.
 func (db *DB) Stats() Stats { return db.c.Stats() }
@@ -655,6 +682,19 @@ func (db *DB) Query(constraint string) (iter.Seq[*classad.ClassAd], error) {
 	return db.c.Query(q), nil
 }
 
+// QueryConstraint is Query for an already-compiled constraint, typically a
+// bounded one (ParseConstraintWithOptions): check c.Err after ranging to learn
+// whether a limit cut ads out of the result.
+func (db *DB) QueryConstraint(c *Constraint) iter.Seq[*classad.ClassAd] {
+	return db.c.Query(c.q)
+}
+
+// QueryConstraintRedacted is QueryConstraint for a caller not entitled to sealed values; see
+// QueryRedacted.
+func (db *DB) QueryConstraintRedacted(c *Constraint) iter.Seq[*classad.ClassAd] {
+	return db.c.QueryRedacted(c.q)
+}
+
 // QueryRedacted is Query for a caller NOT entitled to sealed values: the ads are decoded with no key, so
 // a sealed attribute arrives undefined rather than opened. See collections.Collection.QueryRedacted.
 //
@@ -681,11 +721,16 @@ func (db *DB) BeginRedacted() *Txn { return &Txn{tx: db.c.BeginRedacted(), db: d
 // constraint as they were at time t. It errors on a malformed constraint, when time
 // travel is not enabled on this table, or when t is older than the retained window.
 func (db *DB) QueryAsOf(constraint string, t time.Time) (iter.Seq[*classad.ClassAd], error) {
-	q, err := vm.Parse(constraint)
+	c, err := ParseConstraint(constraint)
 	if err != nil {
-		return nil, fmt.Errorf("classad-db: bad constraint %q: %w", constraint, err)
+		return nil, err
 	}
-	return db.c.QueryAsOf(q, t)
+	return db.QueryAsOfConstraint(c, t)
+}
+
+// QueryAsOfConstraint is QueryAsOf for an already-compiled constraint; see QueryConstraint.
+func (db *DB) QueryAsOfConstraint(c *Constraint, t time.Time) (iter.Seq[*classad.ClassAd], error) {
+	return db.c.QueryAsOf(c.q, t)
 }
 
 // SetTimeTravel enables (with a positive maxDistance), retunes, or disables (maxDistance
@@ -713,11 +758,17 @@ func (db *DB) TimeTravel() (maxDistance, checkpoint time.Duration, enabled bool)
 // yielded slice is reused across iterations; copy any value to retain it past the
 // next step. Errors only on a malformed constraint.
 func (db *DB) QueryProject(constraint string, attrs []string) (iter.Seq[[]classad.Value], error) {
-	q, err := vm.Parse(constraint)
+	c, err := ParseConstraint(constraint)
 	if err != nil {
-		return nil, fmt.Errorf("classad-db: bad constraint %q: %w", constraint, err)
+		return nil, err
 	}
-	return db.c.QueryProject(q, attrs), nil
+	return db.QueryProjectConstraint(c, attrs), nil
+}
+
+// QueryProjectConstraint is QueryProject for an already-compiled constraint; see
+// QueryConstraint.
+func (db *DB) QueryProjectConstraint(c *Constraint, attrs []string) iter.Seq[[]classad.Value] {
+	return db.c.QueryProject(c.q, attrs)
 }
 
 // Match returns the ads that symmetrically match job (bilateral Requirements), pushed
@@ -764,6 +815,15 @@ func (db *DB) MatchSortedRankedFiltered(job *classad.ClassAd, targetConstraint s
 	return db.c.MatchSortedRankedFiltered(job, targetConstraint, limit)
 }
 
+// MatchSortedRankedWithOptions is MatchSortedRankedFiltered for a job ad from an
+// untrusted client: every evaluation of the match is bounded by opts, at the cost of
+// the index pushdown. A slot over the limits does not match, and the first
+// *classad.LimitError is returned along with the matches. See
+// collections.Collection.MatchSortedRankedWithOptions.
+func (db *DB) MatchSortedRankedWithOptions(job *classad.ClassAd, targetConstraint string, limit int, opts classad.EvalOptions) ([]RankedMatch, error) {
+	return db.c.MatchSortedRankedWithOptions(job, targetConstraint, limit, opts)
+}
+
 // MatchSignature is HTCondor's autocluster key: a 64-bit checksum over the given
 // significant attributes' expression text in ad. Two ads with textually identical
 // significant attributes (same Requirements, same RequestCpus literal, ...) hash
@@ -775,7 +835,11 @@ func MatchSignature(ad *classad.ClassAd, significantAttrs []string) uint64 {
 
 // Constraint is a compiled ClassAd boolean expression, for evaluating the same
 // filter against many ads without re-parsing.
-type Constraint struct{ q *vm.Query }
+type Constraint struct {
+	q    *vm.Query
+	all  bool                // the expression is "true": a raw scan need not evaluate it
+	opts classad.EvalOptions // the bounds of ParseConstraintWithOptions, for an aggregate's filters
+}
 
 // ParseConstraint compiles a ClassAd boolean expression.
 func ParseConstraint(expr string) (*Constraint, error) {
@@ -783,12 +847,29 @@ func ParseConstraint(expr string) (*Constraint, error) {
 	if err != nil {
 		return nil, fmt.Errorf("classad-db: bad constraint %q: %w", expr, err)
 	}
-	return &Constraint{q: q}, nil
+	return &Constraint{q: q, all: strings.EqualFold(strings.TrimSpace(expr), "true")}, nil
+}
+
+// ParseConstraintWithOptions compiles a ClassAd boolean expression whose every
+// evaluation is bounded by opts -- for a constraint from an untrusted client. An
+// ad whose evaluation exceeds a limit does not match; Err reports the first limit
+// hit.
+func ParseConstraintWithOptions(expr string, opts classad.EvalOptions) (*Constraint, error) {
+	c, err := ParseConstraint(expr)
+	if err != nil {
+		return nil, err
+	}
+	c.q, c.opts = c.q.WithOptions(opts), opts
+	return c, nil
 }
 
 // Matches reports whether ad satisfies the constraint.
 func (c *Constraint) Matches(ad *classad.ClassAd) bool { return c.q.Matches(ad) }
 
+// Err returns the *classad.LimitError of the first evaluation of the constraint
+// that exceeded its limits (see ParseConstraintWithOptions), or nil.
+func (c *Constraint) Err() error { return c.q.Err() }
+
 // Ordered iterates one partition of the index-th configured ordered index in sort
 // order (Config.Ordered), yielding each member ad with a resume cursor and its cluster
 // signature (for run-length folding into resource-request lists). partition selects the
@@ -819,6 +900,7 @@ type Txn struct {
 	tx   *collections.Txn
 	db   *DB
 	done bool
+	err  error // the first Config.OnNewClassAd failure, returned by Commit
 }
 
 // Begin starts a new independent transaction.
@@ -826,9 +908,13 @@ func (db *DB) Begin() *Txn { return &Txn{tx: db.c.Begin(), db: db} }
 
 // Commit applies the buffered operations. It returns a *ConflictError if any key was
 // modified by another committer since this transaction's snapshot (the non-conflicted
-// operations still committed), or nil on full success.
+// operations still committed), or nil on full success. If a Config.OnNewClassAd hook
+// failed, nothing is committed and Commit returns the hook's error.
 func (t *Txn) Commit() error {
 	t.done = true
+	if t.err != nil {
+		return t.err
+	}
 	// The DB-wide lock, held shared: many commits proceed concurrently, but a Truncate
 	// or Restore (exclusive) is atomic against them. A transaction whose snapshot predates
 	// a Truncate additionally conflicts via the shard gcFloor, so a stale write cannot land
@@ -858,8 +944,18 @@ func (t *Txn) CommitNondurable() error {
 func (t *Txn) Abort() { t.done = true }
 
 // NewClassAd stores ad under key (classad_log.h LogNewClassAd). An existing ad at
-// key is replaced.
+// key is replaced. Config.OnNewClassAd, if set, runs on ad first; if it fails, the
+// transaction fails at Commit.
 func (t *Txn) NewClassAd(key string, ad *classad.ClassAd) {
+	if hook := t.db.onNewClassAd; hook != nil {
+		if t.err != nil {
+			return
+		}
+		if err := hook(key, ad); err != nil {
+			t.err = fmt.Errorf("classad-db: NewClassAd %s: %w", key, err)
+			return
+		}
+	}
 	t.tx.Put([]byte(key), ad)
 }
 
@@ -871,8 +967,12 @@ func (t *Txn) NewClassAd(key string, ad *classad.ClassAd) {
 // It reports whether the wire-native path was taken. False means the caller must parse
 // the text and use NewClassAd -- an encrypted store seals its values and the streaming
 // encoder does not seal, and a few ad shapes (a repeated attribute name, an escape the
-// fast lexer would read differently) defer to the reference parser by design.
+// fast lexer would read differently) defer to the reference parser by design. So does a
+// DB with a Config.OnNewClassAd hook, which needs the parsed ad.
 func (t *Txn) NewClassAdOld(key, text string) bool {
+	if t.db.onNewClassAd != nil {
+		return false
+	}
 	return t.tx.PutOld([]byte(key), text)
 }
 
@@ -911,6 +1011,25 @@ func (t *Txn) DeleteAttribute(key, name string) {
 	}
 }
 
+// ApplyPatch applies p (see classad.Diff) to key's ad: the batch of SetAttribute and
+// DeleteAttribute calls it stands for, as a single read-modify-write within the
+// transaction. The ad is created if absent and p sets an attribute; an empty patch is a
+// no-op.
+func (t *Txn) ApplyPatch(key string, p *classad.Patch) {
+	if p.Empty() {
+		return
+	}
+	ad, ok := t.tx.Get([]byte(key))
+	if !ok {
+		if len(p.Set) == 0 {
+			return
+		}
+		ad = classad.New()
+	}
+	ad.Apply(p)
+	t.tx.Put([]byte(key), ad)
+}
+
 // LookupClassAd returns key's ad as the transaction sees it: its own buffered writes
 // (read-your-writes) merged over the snapshot (classad_log.h Lookup + the
 // LookupInTransaction overlay in one call).
@@ -931,11 +1050,16 @@ func (t *Txn) LookupClassAd(key string) (*classad.ClassAd, bool) {
 // per-record walk the indexed query path skips. See collections.Txn.Query. Errors only on
 // a malformed constraint.
 func (t *Txn) Query(constraint string) (iter.Seq[*classad.ClassAd], error) {
-	q, err := vm.Parse(constraint)
+	c, err := ParseConstraint(constraint)
 	if err != nil {
-		return nil, fmt.Errorf("classad-db: bad constraint %q: %w", constraint, err)
+		return nil, err
 	}
-	return t.tx.Query(q), nil
+	return t.QueryConstraint(c), nil
+}
+
+// QueryConstraint is Query for an already-compiled constraint; see DB.QueryConstraint.
+func (t *Txn) QueryConstraint(c *Constraint) iter.Seq[*classad.ClassAd] {
+	return t.tx.Query(c.q)
 }
 
 // KeysWhere returns the storage keys of the rows matching the constraint as the
@@ -943,11 +1067,17 @@ func (t *Txn) Query(constraint string) (iter.Seq[*classad.ClassAd], error) {
 // what lets an UPDATE or DELETE inside a transaction address a row the transaction
 // itself created. Errors only on a malformed constraint.
 func (t *Txn) KeysWhere(constraint string) (iter.Seq[string], error) {
-	q, err := vm.Parse(constraint)
+	c, err := ParseConstraint(constraint)
 	if err != nil {
-		return nil, fmt.Errorf("classad-db: bad constraint %q: %w", constraint, err)
+		return nil, err
 	}
-	return t.tx.KeysWhere(q), nil
+	return t.KeysWhereConstraint(c), nil
+}
+
+// KeysWhereConstraint is KeysWhere for an already-compiled constraint; see
+// DB.QueryConstraint.
+func (t *Txn) KeysWhereConstraint(c *Constraint) iter.Seq[string] {
+	return t.tx.KeysWhere(c.q)
 }
 
 // LookupAttr returns the unparsed expression of one attribute as the transaction
@@ -1,6 +1,9 @@
 package db
 
 import (
+	"errors"
+	"fmt"
+	"iter"
 	"testing"
 
 	"github.com/PelicanPlatform/classad/classad"
@@ -180,6 +183,112 @@ Rank = TARGET.Cpus`)
 	}
 }
 
+func TestQueryConstraintWithOptions(t *testing.T) {
+	d, _ := Open("")
+	defer d.Close()
+	tx := d.Begin()
+	tx.NewClassAd("a", mustAd(t, "Cpus = 4"))
+	tx.NewClassAd("b", mustAd(t, "Cpus = 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1"))
+	if err := tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+
+	c, err := ParseConstraintWithOptions("Cpus > 2", classad.EvalOptions{MaxSteps: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var keys []string
+	for ad := range d.QueryConstraint(c) {
+		cpus, _ := ad.EvaluateAttrInt("Cpus")
+		keys = append(keys, fmt.Sprint(cpus))
+	}
+	if len(keys) != 1 || keys[0] != "4" {
+		t.Errorf("matched %v, want only the ad within the step limit", keys)
+	}
+	if !errors.Is(c.Err(), classad.ErrMaxSteps) {
+		t.Errorf("Err() = %v, want ErrMaxSteps", c.Err())
+	}
+}
+
+// TestConstraintVariantsWithOptions: every ...Constraint query form applies the bounds
+// of ParseConstraintWithOptions.
+func TestConstraintVariantsWithOptions(t *testing.T) {
+	d, _ := Open("")
+	defer d.Close()
+	tx := d.Begin()
+	tx.NewClassAd("a", mustAd(t, "Cpus = 4\nRequirements = true"))
+	tx.NewClassAd("b", mustAd(t, "Cpus = 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1\nRequirements = true"))
+	if err := tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	for name, run := range map[string]func(c *Constraint) int{
+		"QueryProject": func(c *Constraint) int { return seqLen(d.QueryProjectConstraint(c, []string{"Cpus"})) },
+		"QueryRaw":     func(c *Constraint) int { return seqLen(d.QueryRawConstraint(c)) },
+		"QueryRawRedacted": func(c *Constraint) int {
+			return seqLen(d.QueryRawConstraintRedacted(c))
+		},
+		"QueryRawProjected": func(c *Constraint) int {
+			return seqLen(d.QueryRawProjectedConstraint(c, []string{"Cpus"}, false))
+		},
+		"QueryRawProjectedRefs": func(c *Constraint) int {
+			return seqLen(d.QueryRawProjectedRefsConstraint(c, []string{"Cpus"}, false))
+		},
+		"KeysWhere": func(c *Constraint) int { return seqLen(d.KeysWhereConstraint(c)) },
+		"TopK":      func(c *Constraint) int { return len(d.TopKConstraint(c, []string{"Cpus"}, "Cpus", true, 5)) },
+		"Txn.Query": func(c *Constraint) int {
+			tx := d.Begin()
+			defer tx.Abort()
+			return seqLen(tx.QueryConstraint(c))
+		},
+		"Txn.KeysWhere": func(c *Constraint) int {
+			tx := d.Begin()
+			defer tx.Abort()
+			return seqLen(tx.KeysWhereConstraint(c))
+		},
+	} {
+		c, err := ParseConstraintWithOptions("Cpus > 2", classad.EvalOptions{MaxSteps: 10})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n := run(c); n != 1 || !errors.Is(c.Err(), classad.ErrMaxSteps) {
+			t.Errorf("%s: %d rows, Err() = %v; want 1 and ErrMaxSteps", name, n, c.Err())
+		}
+	}
+
+	// A filter over the limits fails a bounded aggregate.
+	aggs := []AggSpec{{Func: AggCount, Arg: "*", Filter: "Cpus + Cpus + Cpus + Cpus > 2"}}
+	attrs, groupCol, aggCol := AggProjection(nil, aggs)
+	seq, _ := d.QueryProject("true", attrs)
+	opts := classad.EvalOptions{MaxSteps: 3}
+	if _, err := AggregateValuesWithOptions(seq, attrs, nil, aggs, groupCol, aggCol, nil, opts); !errors.Is(err, classad.ErrMaxSteps) {
+		t.Errorf("AggregateValuesWithOptions: err = %v, want ErrMaxSteps", err)
+	}
+
+	// The slot over the limits does not match a bounded job.
+	job := mustAd(t, "Requirements = TARGET.Cpus > 2\nRank = TARGET.Cpus")
+	if got, err := d.MatchSortedRankedWithOptions(job, "", 0, classad.EvalOptions{MaxSteps: 10}); len(got) != 1 || !errors.Is(err, classad.ErrMaxSteps) {
+		t.Errorf("MatchSortedRankedWithOptions: %d matches, %v; want 1 and ErrMaxSteps", len(got), err)
+	}
+
+	// DeleteWhere spares it.
+	c, _ := ParseConstraintWithOptions("Cpus > 2", classad.EvalOptions{MaxSteps: 10})
+	if n, err := d.DeleteWhereConstraint(c); err != nil || n != 1 || !errors.Is(c.Err(), classad.ErrMaxSteps) {
+		t.Errorf("DeleteWhereConstraint = %d, %v, Err() = %v; want 1 and ErrMaxSteps", n, err, c.Err())
+	}
+	if d.Len() != 1 {
+		t.Errorf("%d ads left, want the one over the limits", d.Len())
+	}
+}
+
+// seqLen ranges over seq and returns the number of elements.
+func seqLen[T any](seq iter.Seq[T]) int {
+	n := 0
+	for range seq {
+		n++
+	}
+	return n
+}
+
 func TestOrdered(t *testing.T) {
 	d, err := OpenConfig(Config{Ordered: []OrderSpec{{
 		Partition: "Owner",
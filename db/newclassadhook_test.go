@@ -0,0 +1,68 @@
+package db
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/PelicanPlatform/classad/classad"
+	"github.com/PelicanPlatform/classad/classad/transform"
+)
+
+func TestNewClassAdHook(t *testing.T) {
+	tr, err := transform.Parse("REQUIREMENTS JobUniverse == 5\nDEFAULT RequestMemory 2048\nSET Normalized true")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var keys []string
+	hook := transform.Ruleset{tr}.Hook()
+	d, err := OpenConfig(Config{OnNewClassAd: func(key string, ad *classad.ClassAd) error {
+		keys = append(keys, key)
+		return hook(key, ad)
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer d.Close()
+
+	tx := d.Begin()
+	tx.NewClassAd("1.0", mustAd(t, "JobUniverse = 5"))
+	tx.NewClassAd("2.0", mustAd(t, "JobUniverse = 7"))
+	if tx.NewClassAdOld("3.0", "JobUniverse = 5") {
+		t.Error("NewClassAdOld took the wire-native path past a hook")
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(keys, " ") != "1.0 2.0" {
+		t.Errorf("hook saw keys %v", keys)
+	}
+	ad, _ := d.LookupClassAd("1.0")
+	if want := mustAd(t, "JobUniverse = 5\nRequestMemory = 2048\nNormalized = true"); !ad.Equal(want) {
+		t.Errorf("1.0 = %s, want %s", ad, want)
+	}
+	if ad, _ := d.LookupClassAd("2.0"); ad.Size() != 1 {
+		t.Errorf("2.0 = %s: transformed despite REQUIREMENTS", ad)
+	}
+
+	bad, err := transform.Parse("SET X $(MY.Y)")
+	if err != nil {
+		t.Fatal(err)
+	}
+	hook = transform.Ruleset{bad}.Hook()
+	tx = d.Begin()
+	tx.NewClassAd("4.0", mustAd(t, "Y = \"1\""))
+	tx.NewClassAd("5.0", mustAd(t, `Y = "("`))
+	tx.DestroyClassAd("1.0")
+	err = tx.Commit()
+	if err == nil || !strings.HasPrefix(err.Error(), "classad-db: NewClassAd 5.0: transform: line 1") {
+		t.Errorf("Commit error = %v", err)
+	}
+	for _, key := range []string{"4.0", "5.0"} {
+		if _, ok := d.LookupClassAd(key); ok {
+			t.Errorf("%s stored by a failed transaction", key)
+		}
+	}
+	if _, ok := d.LookupClassAd("1.0"); !ok {
+		t.Error("a failed transaction destroyed 1.0")
+	}
+}
@@ -0,0 +1,86 @@
+package db
+
+import (
+	"context"
+	"testing"
+
+	"github.com/PelicanPlatform/classad/classad"
+)
+
+func TestTxnApplyPatch(t *testing.T) {
+	d, _ := Open("")
+	defer d.Close()
+	tx := d.Begin()
+	tx.NewClassAd("slot1", mustAd(t, "Name = \"slot1\"\nState = \"Idle\"\nLastHeard = 10"))
+	if err := tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+
+	p, err := classad.ParsePatch("State = \"Busy\"\nActivity = \"Busy\"\n-LastHeard")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tx = d.Begin()
+	tx.ApplyPatch("slot1", p)
+	tx.ApplyPatch("slot2", &classad.Patch{Delete: []string{"X"}}) // nothing to delete from
+	if err := tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	ad, _ := d.LookupClassAd("slot1")
+	want := mustAd(t, "Name = \"slot1\"\nState = \"Busy\"\nActivity = \"Busy\"")
+	if !ad.Equal(want) {
+		t.Errorf("patched ad = %s, want %s", ad, want)
+	}
+	if _, ok := d.LookupClassAd("slot2"); ok {
+		t.Error("a delete-only patch created an ad")
+	}
+}
+
+func TestWatchPatches(t *testing.T) {
+	d, _ := Open("")
+	defer d.Close()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	seq, err := d.WatchPatches(ctx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	events := make(chan WatchEvent, 16)
+	go func() {
+		for ev := range seq {
+			events <- ev
+		}
+	}()
+	next := func(kind WatchKind) WatchEvent {
+		t.Helper()
+		for ev := range events {
+			if ev.Kind == kind {
+				return ev
+			}
+		}
+		t.Fatal("watch ended")
+		return WatchEvent{}
+	}
+	next(WatchSynced)
+
+	// One commit at a time, so the watch cannot coalesce two into one event.
+	for _, step := range []struct {
+		update func(*Txn)
+		kind   WatchKind
+		patch  string
+	}{
+		{func(tx *Txn) { tx.NewClassAd("k", mustAd(t, "A = 1\nB = 2")) }, WatchUpsert, "A = 1\nB = 2\n"},
+		{func(tx *Txn) { _ = tx.SetAttribute("k", "B", "3") }, WatchUpsert, "B = 3\n"},
+		{func(tx *Txn) { tx.DeleteAttribute("k", "A") }, WatchUpsert, "-A\n"},
+		{func(tx *Txn) { tx.DestroyClassAd("k") }, WatchDelete, "-B\n"},
+	} {
+		tx := d.Begin()
+		step.update(tx)
+		if err := tx.Commit(); err != nil {
+			t.Fatal(err)
+		}
+		if ev := next(step.kind); ev.Patch.String() != step.patch {
+			t.Errorf("%v event of %s: Patch =\n%s\nwant\n%s", step.kind, ev.Key, ev.Patch, step.patch)
+		}
+	}
+}
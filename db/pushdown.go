@@ -91,10 +91,22 @@ func (db *DB) withWriteRetry(stage func(*Txn)) error {
 // sweep fails to converge within maxDeleteRounds (only reachable under relentless
 // churn of the match set); the returned count reflects what was removed so far.
 func (db *DB) DeleteWhere(constraint string) (int, error) {
-	q, err := vm.Parse(constraint)
+	c, err := ParseConstraint(constraint)
 	if err != nil {
-		return 0, fmt.Errorf("classad-db: bad constraint %q: %w", constraint, err)
+		return 0, err
 	}
+	return db.deleteWhere(c.q, constraint)
+}
+
+// DeleteWhereConstraint is DeleteWhere for an already-compiled constraint. An ad whose
+// evaluation exceeds the limits of a bounded constraint does not match, so it is spared;
+// check c.Err afterwards.
+func (db *DB) DeleteWhereConstraint(c *Constraint) (int, error) {
+	return db.deleteWhere(c.q, c.q.Expr().String())
+}
+
+// deleteWhere is DeleteWhere's sweep; constraint names q in the non-convergence error.
+func (db *DB) deleteWhere(q *vm.Query, constraint string) (int, error) {
 	total := 0
 	for round := 0; round < maxDeleteRounds; round++ {
 		keys := db.matchingKeys(q, deleteBatch)
@@ -116,18 +128,23 @@ func (db *DB) DeleteWhere(constraint string) (int, error) {
 // parsed eagerly (a parse error returns before any scan); the scan is lazy and stops early if the
 // caller's yield returns false. It matches against decoded ads (a full scan, like DeleteWhere).
 func (db *DB) KeysWhere(constraint string) (iter.Seq[string], error) {
-	q, err := vm.Parse(constraint)
+	c, err := ParseConstraint(constraint)
 	if err != nil {
-		return nil, fmt.Errorf("classad-db: bad constraint %q: %w", constraint, err)
+		return nil, err
 	}
+	return db.KeysWhereConstraint(c), nil
+}
+
+// KeysWhereConstraint is KeysWhere for an already-compiled constraint; see QueryConstraint.
+func (db *DB) KeysWhereConstraint(c *Constraint) iter.Seq[string] {
 	return func(yield func(string) bool) {
 		db.c.ForEachAd(func(key string, ad *classad.ClassAd) bool {
-			if q.Matches(ad) {
+			if c.q.Matches(ad) {
 				return yield(key)
 			}
 			return true
 		})
-	}, nil
+	}
 }
 
 // matchingKeys collects up to limit keys whose ads currently match q.
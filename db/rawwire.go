@@ -2,12 +2,10 @@ package db
 
 import (
 	"errors"
-	"fmt"
 	"iter"
 	"strings"
 
 	"github.com/PelicanPlatform/classad/collections"
-	"github.com/PelicanPlatform/classad/collections/vm"
 )
 
 // ErrRawWireUnsupported reports that a table cannot serve the wire-form relay scan --
@@ -63,14 +61,28 @@ func (db *DB) UpdateOldBatch(items []OldAdText) error {
 // result set can be relayed without materializing and re-encoding each ad. Errors
 // only on a malformed constraint.
 func (db *DB) QueryRaw(constraint string) (iter.Seq[collections.RawAd], error) {
-	if s := strings.TrimSpace(constraint); s == "" || strings.EqualFold(s, "true") {
-		return db.c.ScanRaw(), nil // match-all: full raw scan
-	}
-	q, err := vm.Parse(constraint)
+	c, err := parseRawConstraint(constraint)
 	if err != nil {
-		return nil, fmt.Errorf("classad-db: bad constraint %q: %w", constraint, err)
+		return nil, err
+	}
+	return db.QueryRawConstraint(c), nil
+}
+
+// QueryRawConstraint is QueryRaw for an already-compiled constraint; see QueryConstraint.
+func (db *DB) QueryRawConstraint(c *Constraint) iter.Seq[collections.RawAd] {
+	if c.all {
+		return db.c.ScanRaw() // match-all: full raw scan
+	}
+	return db.c.QueryRaw(c.q)
+}
+
+// parseRawConstraint compiles the constraint of a raw query, where an empty one matches
+// every ad.
+func parseRawConstraint(constraint string) (*Constraint, error) {
+	if strings.TrimSpace(constraint) == "" {
+		constraint = "true"
 	}
-	return db.c.QueryRaw(q), nil
+	return ParseConstraint(constraint)
 }
 
 // QueryRawRedacted is QueryRaw with private (secret) attributes stripped inside
@@ -78,14 +90,19 @@ func (db *DB) QueryRaw(constraint string) (iter.Seq[collections.RawAd], error) {
 // no per-attribute re-classification and never renders a private value (see
 // collections.ScanRawRedacted).
 func (db *DB) QueryRawRedacted(constraint string) (iter.Seq[collections.RawAd], error) {
-	if s := strings.TrimSpace(constraint); s == "" || strings.EqualFold(s, "true") {
-		return db.c.ScanRawRedacted(), nil
-	}
-	q, err := vm.Parse(constraint)
+	c, err := parseRawConstraint(constraint)
 	if err != nil {
-		return nil, fmt.Errorf("classad-db: bad constraint %q: %w", constraint, err)
+		return nil, err
+	}
+	return db.QueryRawConstraintRedacted(c), nil
+}
+
+// QueryRawConstraintRedacted is QueryRawRedacted for an already-compiled constraint.
+func (db *DB) QueryRawConstraintRedacted(c *Constraint) iter.Seq[collections.RawAd] {
+	if c.all {
+		return db.c.ScanRawRedacted()
 	}
-	return db.c.QueryRawRedacted(q), nil
+	return db.c.QueryRawRedacted(c.q)
 }
 
 // QueryRawProjected is QueryRaw restricted to the projected attribute names,
@@ -95,14 +112,19 @@ func (db *DB) QueryRawRedacted(constraint string) (iter.Seq[collections.RawAd],
 // collections.ScanRawProjected). redact additionally strips private attributes.
 // An empty projection means no attribute filter.
 func (db *DB) QueryRawProjected(constraint string, projection []string, redact bool) (iter.Seq[collections.RawAd], error) {
-	if s := strings.TrimSpace(constraint); s == "" || strings.EqualFold(s, "true") {
-		return db.c.ScanRawProjected(projection, false, redact), nil
-	}
-	q, err := vm.Parse(constraint)
+	c, err := parseRawConstraint(constraint)
 	if err != nil {
-		return nil, fmt.Errorf("classad-db: bad constraint %q: %w", constraint, err)
+		return nil, err
+	}
+	return db.QueryRawProjectedConstraint(c, projection, redact), nil
+}
+
+// QueryRawProjectedConstraint is QueryRawProjected for an already-compiled constraint.
+func (db *DB) QueryRawProjectedConstraint(c *Constraint, projection []string, redact bool) iter.Seq[collections.RawAd] {
+	if c.all {
+		return db.c.ScanRawProjected(projection, false, redact)
 	}
-	return db.c.QueryRawProjected(q, projection, false, redact), nil
+	return db.c.QueryRawProjected(c.q, projection, false, redact)
 }
 
 // QueryRawProjectedRefs is QueryRawProjected that also carries the attributes the
@@ -119,28 +141,37 @@ func (db *DB) QueryRawProjected(constraint string, projection []string, redact b
 // query protocol, which specifies exactly the requested attributes and nothing more. Use
 // this one when the recipient is going to EVALUATE what it receives.
 func (db *DB) QueryRawProjectedRefs(constraint string, projection []string, redact bool) (iter.Seq[collections.RawAd], error) {
-	if s := strings.TrimSpace(constraint); s == "" || strings.EqualFold(s, "true") {
-		return db.c.ScanRawProjected(projection, true, redact), nil
-	}
-	q, err := vm.Parse(constraint)
+	c, err := parseRawConstraint(constraint)
 	if err != nil {
-		return nil, fmt.Errorf("classad-db: bad constraint %q: %w", constraint, err)
+		return nil, err
 	}
-	return db.c.QueryRawProjected(q, projection, true, redact), nil
+	return db.QueryRawProjectedRefsConstraint(c, projection, redact), nil
+}
+
+// QueryRawProjectedRefsConstraint is QueryRawProjectedRefs for an already-compiled
+// constraint.
+func (db *DB) QueryRawProjectedRefsConstraint(c *Constraint, projection []string, redact bool) iter.Seq[collections.RawAd] {
+	return db.QueryRawProjectedRefsStatsConstraint(c, projection, redact, nil)
 }
 
 // QueryRawProjectedRefsStats is QueryRawProjectedRefs that also fills stats (may be nil) with the
 // per-scan work breakdown, for EXPLAIN ANALYZE. An empty/true constraint takes the no-WHERE scan
 // path and leaves stats zero.
 func (db *DB) QueryRawProjectedRefsStats(constraint string, projection []string, redact bool, stats *collections.ScanStats) (iter.Seq[collections.RawAd], error) {
-	if s := strings.TrimSpace(constraint); s == "" || strings.EqualFold(s, "true") {
-		return db.c.ScanRawProjected(projection, true, redact), nil
-	}
-	q, err := vm.Parse(constraint)
+	c, err := parseRawConstraint(constraint)
 	if err != nil {
-		return nil, fmt.Errorf("classad-db: bad constraint %q: %w", constraint, err)
+		return nil, err
+	}
+	return db.QueryRawProjectedRefsStatsConstraint(c, projection, redact, stats), nil
+}
+
+// QueryRawProjectedRefsStatsConstraint is QueryRawProjectedRefsStats for an
+// already-compiled constraint.
+func (db *DB) QueryRawProjectedRefsStatsConstraint(c *Constraint, projection []string, redact bool, stats *collections.ScanStats) iter.Seq[collections.RawAd] {
+	if c.all {
+		return db.c.ScanRawProjected(projection, true, redact)
 	}
-	return db.c.QueryRawProjectedStats(q, projection, true, redact, stats), nil
+	return db.c.QueryRawProjectedStats(c.q, projection, true, redact, stats)
 }
 
 // QueryRawWire yields each matching ad as a self-contained WIRE-FORM ROW (an
@@ -156,15 +187,20 @@ func (db *DB) QueryRawProjectedRefsStats(constraint string, projection []string,
 // nothing is indistinguishable from a query that matched nothing, so returning one
 // would turn every RAM-table query into a silent empty result at the consumer.
 func (db *DB) QueryRawWire(constraint string, projection []string, redact bool) (iter.Seq[[]byte], error) {
+	c, err := parseRawConstraint(constraint)
+	if err != nil {
+		return nil, err
+	}
+	return db.QueryRawWireConstraint(c, projection, redact)
+}
+
+// QueryRawWireConstraint is QueryRawWire for an already-compiled constraint.
+func (db *DB) QueryRawWireConstraint(c *Constraint, projection []string, redact bool) (iter.Seq[[]byte], error) {
 	if !db.c.SupportsRawWire() {
 		return nil, ErrRawWireUnsupported
 	}
-	if s := strings.TrimSpace(constraint); s == "" || strings.EqualFold(s, "true") {
+	if c.all {
 		return db.c.ScanRawWire(projection, redact), nil
 	}
-	q, err := vm.Parse(constraint)
-	if err != nil {
-		return nil, fmt.Errorf("classad-db: bad constraint %q: %w", constraint, err)
-	}
-	return db.c.QueryRawWire(q, projection, redact), nil
+	return db.c.QueryRawWire(c.q, projection, redact), nil
 }
@@ -26,6 +26,12 @@ func (t *ArchiveTable) TopK(constraint string, attrs []string, orderAttr string,
 	return topKResult(seq, orderIdx, desc, k, added), nil
 }
 
+// TopKConstraint is TopK for an already-compiled constraint; see DB.QueryConstraint.
+func (t *ArchiveTable) TopKConstraint(c *Constraint, attrs []string, orderAttr string, desc bool, k int) [][]classad.Value {
+	proj, orderIdx, added := withOrderAttr(attrs, orderAttr)
+	return topKResult(t.QueryProjectConstraint(c, proj), orderIdx, desc, k, added)
+}
+
 // TopK is ArchiveTable.TopK for a mutable table.
 func (db *DB) TopK(constraint string, attrs []string, orderAttr string, desc bool, k int) ([][]classad.Value, error) {
 	proj, orderIdx, added := withOrderAttr(attrs, orderAttr)
@@ -36,6 +42,12 @@ func (db *DB) TopK(constraint string, attrs []string, orderAttr string, desc boo
 	return topKResult(seq, orderIdx, desc, k, added), nil
 }
 
+// TopKConstraint is TopK for an already-compiled constraint; see QueryConstraint.
+func (db *DB) TopKConstraint(c *Constraint, attrs []string, orderAttr string, desc bool, k int) [][]classad.Value {
+	proj, orderIdx, added := withOrderAttr(attrs, orderAttr)
+	return topKResult(db.QueryProjectConstraint(c, proj), orderIdx, desc, k, added)
+}
+
 // withOrderAttr returns the projection to fetch (attrs, plus orderAttr if it was not already
 // requested), the index of orderAttr within it, and whether it was appended (so the caller trims
 // it from the results).
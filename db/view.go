@@ -728,6 +728,18 @@ func (cat *Catalog) ViewSealed(name, constraint string) (seq iter.Seq[*classad.C
 	return seq, true, err
 }
 
+// ViewSealedConstraint is ViewSealed for an already-compiled constraint; see
+// DB.QueryConstraint.
+func (cat *Catalog) ViewSealedConstraint(name string, c *Constraint) (seq iter.Seq[*classad.ClassAd], ok bool) {
+	cat.mu.Lock()
+	v, found := cat.views[name]
+	cat.mu.Unlock()
+	if !found {
+		return nil, false
+	}
+	return v.SealedQueryConstraint(c), true
+}
+
 // recoverViews reconstructs views from <dir>/views on catalog open. Data is not persisted,
 // so each view is rebuilt from its base table. A view whose base table is absent loads
 // stale (rebound on a later restart once the table exists); a view whose rebuild fails
@@ -161,6 +161,17 @@ func (v *View) SealedQuery(constraint string) (iter.Seq[*classad.ClassAd], error
 	return arch.Query(constraint)
 }
 
+// SealedQueryConstraint is SealedQuery for an already-compiled constraint.
+func (v *View) SealedQueryConstraint(c *Constraint) iter.Seq[*classad.ClassAd] {
+	v.mu.Lock()
+	arch := v.archive
+	v.mu.Unlock()
+	if arch == nil {
+		return func(yield func(*classad.ClassAd) bool) {}
+	}
+	return arch.QueryLimitConstraint(c, 0)
+}
+
 // LateDrops reports how many base rows were dropped because their time bucket was already
 // sealed (out-of-window late data).
 func (v *View) LateDrops() int64 {
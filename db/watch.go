@@ -38,6 +38,9 @@ type WatchEvent struct {
 	Key    string
 	Ad     *classad.ClassAd // nil for a delete
 	Cursor []byte
+	// Patch, on an Upsert or Delete from WatchPatches, is what changed in the key's ad
+	// since its previous event on the watch.
+	Patch *classad.Patch
 }
 
 // Watch streams changes committed after the given cursor (nil = from now). Cancel via
@@ -88,6 +91,42 @@ func (db *DB) watchAs(ctx context.Context, cursor []byte, redact bool) (iter.Seq
 	}, nil
 }
 
+// WatchPatches is Watch with each Upsert and Delete carrying a Patch of what changed:
+// the classad.Diff of the key's ad from the one its previous event delivered. A key with
+// no previous event since the watch began or last Reset diffs from the empty ad, so its
+// Patch sets every attribute; resume from a cursor and the first event of each key is
+// such a full Patch. The watch keeps the last ad of every key it has seen, so consumers
+// must not modify event ads.
+func (db *DB) WatchPatches(ctx context.Context, cursor []byte) (iter.Seq[WatchEvent], error) {
+	seq, err := db.Watch(ctx, cursor)
+	if err != nil {
+		return nil, err
+	}
+	return withPatches(seq), nil
+}
+
+// withPatches sets the Patch of each event of seq.
+func withPatches(seq iter.Seq[WatchEvent]) iter.Seq[WatchEvent] {
+	return func(yield func(WatchEvent) bool) {
+		last := map[string]*classad.ClassAd{}
+		for ev := range seq {
+			switch ev.Kind {
+			case WatchReset:
+				clear(last)
+			case WatchUpsert:
+				ev.Patch = classad.Diff(last[ev.Key], ev.Ad)
+				last[ev.Key] = ev.Ad
+			case WatchDelete:
+				ev.Patch = classad.Diff(last[ev.Key], nil)
+				delete(last, ev.Key)
+			}
+			if !yield(ev) {
+				return
+			}
+		}
+	}
+}
+
 // Watcher is a watch whose readiness is signalled on a file descriptor -- for the C
 // (DaemonCore) side, which registers the fd in its poll loop and, on wakeup, drains
 // events with Next. Go writes a single wakeup byte when the queue goes non-empty
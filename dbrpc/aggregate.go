@@ -180,7 +180,7 @@ func (c *Client) AggregateBucketedTable(ctx context.Context, table, constraint s
 
 // streamAggregate performs a server-side GROUP BY (raw group columns) and streams
 // one frame per group.
-func (s *Server) streamAggregate(ctx context.Context, reqID uint64, r *reader, includePrivate bool, write func([]byte)) {
+func (s *Server) streamAggregate(ctx context.Context, reqID uint64, r *reader, includePrivate bool, write func([]byte), eval *classad.EvalOptions) {
 	table := r.str()
 	constraint := r.str()
 	nGroup := int(r.i32())
@@ -196,23 +196,23 @@ func (s *Server) streamAggregate(ctx context.Context, reqID uint64, r *reader, i
 	if !ok {
 		return
 	}
-	s.aggregate(ctx, reqID, table, constraint, groups, aggs, includePrivate, write)
+	s.aggregate(ctx, reqID, table, constraint, groups, aggs, includePrivate, write, eval)
 }
 
 // streamAggregateBucketed is streamAggregate where each group column may carry a
 // bucket width (opAggregateBucketed).
-func (s *Server) streamAggregateBucketed(ctx context.Context, reqID uint64, r *reader, includePrivate bool, write func([]byte)) {
-	s.streamAggregateWidths(ctx, reqID, r, includePrivate, false, write)
+func (s *Server) streamAggregateBucketed(ctx context.Context, reqID uint64, r *reader, includePrivate bool, write func([]byte), eval *classad.EvalOptions) {
+	s.streamAggregateWidths(ctx, reqID, r, includePrivate, false, write, eval)
 }
 
 // streamAggregateFiltered is streamAggregateBucketed whose specs carry a per-aggregate
 // filter (opAggregateFiltered). The request shape is otherwise identical, so a filtered
 // plain aggregate rides the bucketed frame with every width zero.
-func (s *Server) streamAggregateFiltered(ctx context.Context, reqID uint64, r *reader, includePrivate bool, write func([]byte)) {
-	s.streamAggregateWidths(ctx, reqID, r, includePrivate, true, write)
+func (s *Server) streamAggregateFiltered(ctx context.Context, reqID uint64, r *reader, includePrivate bool, write func([]byte), eval *classad.EvalOptions) {
+	s.streamAggregateWidths(ctx, reqID, r, includePrivate, true, write, eval)
 }
 
-func (s *Server) streamAggregateWidths(ctx context.Context, reqID uint64, r *reader, includePrivate, filtered bool, write func([]byte)) {
+func (s *Server) streamAggregateWidths(ctx context.Context, reqID uint64, r *reader, includePrivate, filtered bool, write func([]byte), eval *classad.EvalOptions) {
 	table := r.str()
 	constraint := r.str()
 	nGroup := int(r.i32())
@@ -229,7 +229,7 @@ func (s *Server) streamAggregateWidths(ctx context.Context, reqID uint64, r *rea
 	if !ok {
 		return
 	}
-	s.aggregate(ctx, reqID, table, constraint, groups, aggs, includePrivate, write)
+	s.aggregate(ctx, reqID, table, constraint, groups, aggs, includePrivate, write, eval)
 }
 
 // readAggSpecs reads the [nAgg]{[func u8][arg]} tail shared by the aggregate opcodes,
@@ -299,8 +299,9 @@ var ErrFilteredAggregateUnsupported = ErrExtendedAggregateUnsupported
 // aggregate is the shared GROUP BY core for both aggregate opcodes: it refuses
 // private attributes for an unprivileged connection, projects only the attributes
 // the aggregation reads (so the scan stays wire-native), reduces via the db module's
-// shared aggregate engine, and streams one frame per group.
-func (s *Server) aggregate(ctx context.Context, reqID uint64, table, constraint string, groupCols []GroupCol, aggs []AggSpec, includePrivate bool, write func([]byte)) {
+// shared aggregate engine, and streams one frame per group. A connection with
+// evaluation limits (eval) always scans, bounded by them.
+func (s *Server) aggregate(ctx context.Context, reqID uint64, table, constraint string, groupCols []GroupCol, aggs []AggSpec, includePrivate bool, write func([]byte), eval *classad.EvalOptions) {
 	if !includePrivate {
 		// The WHERE clause reads attributes too, and whether a row matches is itself the answer.
 		if refusePrivateConstraint(reqID, constraint, includePrivate, write) {
@@ -339,6 +340,29 @@ func (s *Server) aggregate(ctx context.Context, reqID uint64, table, constraint
 		return
 	}
 
+	// The fast paths below take the constraint as text and evaluate it -- or fold it, if
+	// constant -- unbounded, so a bounded connection skips them for the projected scan.
+	if eval != nil {
+		c, err := parseConstraint(orTrue(constraint), eval)
+		if err != nil {
+			write(respErr(reqID, err.Error()))
+			return
+		}
+		rows, err := db.AggregateValuesWithOptions(d.QueryProjectConstraint(c, attrs), attrs, groupCols, aggs, groupCol, aggCol, func() bool { return cancelled(ctx) }, *eval)
+		if err == nil {
+			err = c.Err() // a group short the rows the limits cut out is a wrong answer
+		}
+		if err != nil {
+			write(respErr(reqID, err.Error()))
+			return
+		}
+		if cancelled(ctx) {
+			return
+		}
+		writeAggRows(reqID, rows, write)
+		return
+	}
+
 	// Fast path: an unconstrained COUNT(*) with no grouping is the collection's live row
 	// count, which it tracks in O(shards) via Len() -- no scan of every ad. This is the common
 	// `SELECT COUNT(*) FROM <table>`. Only when the collection has no hidden structural
@@ -15,7 +15,7 @@ import (
 // non-privileged reader, as everywhere.
 
 // streamArchiveQuery streams an archive's newest-first, limit-capped matches.
-func (sc *serverConn) streamArchiveQuery(reqID uint64, r *reader) {
+func (sc *serverConn) streamArchiveQuery(reqID uint64, r *reader, eval *classad.EvalOptions) {
 	name := r.str()
 	limit := int(r.i32())
 	constraint := r.str()
@@ -31,18 +31,18 @@ func (sc *serverConn) streamArchiveQuery(reqID uint64, r *reader) {
 		sc.write(respErr(reqID, "no such archive: "+name))
 		return
 	}
-	seq, err := a.QueryLimit(constraint, limit) // limit pushed down (newest-first)
+	c, err := parseConstraint(constraint, eval)
 	if err != nil {
 		sc.write(respErr(reqID, err.Error()))
 		return
 	}
-	for ad := range seq {
+	for ad := range a.QueryLimitConstraint(c, limit) { // limit pushed down (newest-first)
 		if cancelled(sc.ctx) {
 			return // client gone
 		}
 		sc.write(putStr(respHead(reqID, stStream), adString(ad, sc.opts.IncludePrivate)))
 	}
-	sc.write(respHead(reqID, stStreamEnd))
+	endStream(reqID, c, sc.write)
 }
 
 // streamArchiveAggregate runs a server-side GROUP BY over a history table and streams one
@@ -50,20 +50,21 @@ func (sc *serverConn) streamArchiveQuery(reqID uint64, r *reader) {
 // columns and aggregate specs, resolves the archive, and reduces via db's shared aggregate
 // engine so the result is identical to the same aggregate over a mutable table. Private
 // attributes are refused for an unprivileged reader, as with the mutable aggregate.
-func (sc *serverConn) streamArchiveAggregate(reqID uint64, r *reader) {
-	sc.archiveAggregate(reqID, r, false)
+func (sc *serverConn) streamArchiveAggregate(reqID uint64, r *reader, eval *classad.EvalOptions) {
+	sc.archiveAggregate(reqID, r, false, eval)
 }
 
 // streamArchiveAggregateFiltered is streamArchiveAggregate's extended form: each group
 // column carries a bucket width and each spec a filter (opArchiveAggregateFiltered).
-func (sc *serverConn) streamArchiveAggregateFiltered(reqID uint64, r *reader) {
-	sc.archiveAggregate(reqID, r, true)
+func (sc *serverConn) streamArchiveAggregateFiltered(reqID uint64, r *reader, eval *classad.EvalOptions) {
+	sc.archiveAggregate(reqID, r, true, eval)
 }
 
 // archiveAggregate is the body shared by both archive aggregate opcodes. extended says
 // whether the frame is the wide form -- each group column followed by a bucket width, each
-// aggregate spec by a filter expression -- rather than the base opcode's narrow one.
-func (sc *serverConn) archiveAggregate(reqID uint64, r *reader, extended bool) {
+// aggregate spec by a filter expression -- rather than the base opcode's narrow one. A
+// connection with evaluation limits (eval) scans under them.
+func (sc *serverConn) archiveAggregate(reqID uint64, r *reader, extended bool, eval *classad.EvalOptions) {
 	name := r.str()
 	constraint := r.str()
 	nGroup := int(r.i32())
@@ -113,7 +114,18 @@ func (sc *serverConn) archiveAggregate(reqID uint64, r *reader, extended bool) {
 		sc.write(respErr(reqID, "no such archive: "+name))
 		return
 	}
-	rows, err := a.AggregateCols(constraint, groupCols, aggs)
+	var rows []db.AggRow
+	var err error
+	if eval == nil {
+		rows, err = a.AggregateCols(constraint, groupCols, aggs)
+	} else {
+		var c *db.Constraint
+		if c, err = parseConstraint(orTrue(constraint), eval); err == nil {
+			if rows, err = a.AggregateColsConstraint(c, groupCols, aggs); err == nil {
+				err = c.Err()
+			}
+		}
+	}
 	if err != nil {
 		sc.write(respErr(reqID, err.Error()))
 		return
@@ -0,0 +1,161 @@
+package dbrpc
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/PelicanPlatform/classad/classad"
+	"github.com/PelicanPlatform/classad/db"
+)
+
+func TestServeOptions_EvalLimits(t *testing.T) {
+	c, cleanup := serveOptsPair(t, ServeOptions{Eval: classad.EvalOptions{MaxSteps: 50}})
+	defer cleanup()
+
+	ctx := context.Background()
+	tx, err := c.Begin(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = tx.NewClassAd(ctx, "a", `Name = "a"`+"\n"+`N = 1`)
+	_ = tx.NewClassAd(ctx, "b", `Name = "b"`+"\n"+`N = 2`)
+	if err := tx.Commit(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	if rows, err := c.Query(ctx, `N > 1`); err != nil || len(rows) != 1 {
+		t.Fatalf("within limits: rows=%d err=%v, want 1 row", len(rows), err)
+	}
+	heavy := `N > 0` + strings.Repeat(` && N + N + N + N > 0`, 20)
+	if _, err := c.Query(ctx, heavy); err == nil || !strings.Contains(err.Error(), "step limit") {
+		t.Errorf("over the step limit: err=%v, want a step-limit error", err)
+	}
+}
+
+// TestServeOptions_EvalLimitsEveryOp sends every op that evaluates a client's
+// constraint (or job ad) one over the connection's step limit, and expects each
+// to fail with the limit rather than answer as if the expensive ads did not match.
+func TestServeOptions_EvalLimitsEveryOp(t *testing.T) {
+	// A persistent catalog, so the wire-row and archive ops are served too; privileged,
+	// to turn on time travel.
+	c, cleanup := catServerPair(t, ServeOptions{Privileged: true, Eval: classad.EvalOptions{MaxSteps: 50}})
+	defer cleanup()
+
+	ctx := context.Background()
+	if err := c.CreateTable(ctx, DefaultTable); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.AdminTable(ctx, DefaultTable, "timetravel.enable", "3600", "1"); err != nil {
+		t.Fatal(err)
+	}
+	tx, err := c.Begin(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range []string{"a", "b"} {
+		if err := tx.NewClassAd(ctx, key, "Name = \""+key+"\"\nN = 1\nRequirements = true"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tx.Commit(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.CreateArchiveTable(ctx, "hist", db.ArchiveConfig{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.ArchiveAppend(ctx, "hist", "Name = \"h\"\nN = 1"); err != nil {
+		t.Fatal(err)
+	}
+
+	heavy := `N > 0` + strings.Repeat(` && N + N + N + N > 0`, 20)
+	count := []AggSpec{{Func: AggCount, Arg: "*"}}
+	filtered := []AggSpec{{Func: AggCount, Arg: "*", Filter: heavy}}
+	rows := func(_ string) bool { return true }
+	for op, run := range map[string]func() error{
+		"Query": func() error { _, err := c.Query(ctx, heavy); return err },
+		"QueryAsOf": func() error {
+			_, err := c.QueryAsOfTable(ctx, DefaultTable, heavy, 0, time.Now())
+			return err
+		},
+		"QueryRaw": func() error { _, err := c.QueryRaw(ctx, heavy); return err },
+		"QueryRawProject": func() error {
+			_, err := c.QueryRawProject(ctx, DefaultTable, heavy, []string{"Name"}, 0)
+			return err
+		},
+		"QueryRawProjectRefs": func() error {
+			_, err := c.QueryRawProjectRefs(ctx, DefaultTable, heavy, []string{"Name"}, 0)
+			return err
+		},
+		"QueryRawProjectRefsStats": func() error {
+			return c.QueryRawProjectRefsStreamStats(ctx, DefaultTable, heavy, []string{"Name"}, 0, rows, nil)
+		},
+		"QueryRawWire": func() error {
+			return c.QueryRawWireStream(ctx, DefaultTable, heavy, nil, 0, false, func([]byte) bool { return true })
+		},
+		"QueryKeys": func() error { _, err := c.QueryKeysTable(ctx, DefaultTable, heavy); return err },
+		"TopK": func() error {
+			_, err := c.TopK(ctx, DefaultTable, heavy, []string{"Name"}, "N", true, 5)
+			return err
+		},
+		"MatchSorted": func() error {
+			_, err := c.MatchSorted(ctx, "Requirements = "+strings.ReplaceAll(heavy, "N", "TARGET.N"), 0)
+			return err
+		},
+		"Aggregate":         func() error { _, err := c.Aggregate(ctx, heavy, nil, count); return err },
+		"AggregateBucketed": func() error { _, err := c.AggregateBucketed(ctx, heavy, []GroupCol{{Attr: "N"}}, count); return err },
+		"AggregateFiltered": func() error { _, err := c.Aggregate(ctx, "true", nil, filtered); return err },
+		"MatchTables": func() error {
+			_, err := c.MatchTables(ctx, DefaultTable, DefaultTable, "Name", heavy, "", 0, nil)
+			return err
+		},
+		"MatchTablesTarget": func() error {
+			_, err := c.MatchTables(ctx, DefaultTable, DefaultTable, "Name", "", heavy, 0, nil)
+			return err
+		},
+		"MatchExplain": func() error { _, err := c.MatchExplain(ctx, DefaultTable, heavy, DefaultTable, ""); return err },
+		"ArchiveQuery": func() error { _, err := c.ArchiveQuery(ctx, "hist", heavy, 0); return err },
+		"ArchiveAggregate": func() error {
+			_, err := c.ArchiveAggregate(ctx, "hist", heavy, nil, count)
+			return err
+		},
+		"ArchiveAggregateFiltered": func() error {
+			_, err := c.ArchiveAggregateBucketed(ctx, "hist", "true", nil, filtered)
+			return err
+		},
+		"TxnQuery": func() error {
+			tx, err := c.Begin(ctx)
+			if err != nil {
+				return err
+			}
+			defer tx.Abort(ctx)
+			_, err = tx.Query(ctx, heavy, 0)
+			return err
+		},
+		"TxnQueryKeys": func() error {
+			tx, err := c.Begin(ctx)
+			if err != nil {
+				return err
+			}
+			defer tx.Abort(ctx)
+			_, err = tx.KeysWhere(ctx, heavy)
+			return err
+		},
+		"DeleteWhere": func() error { _, err := c.DeleteWhere(ctx, heavy); return err },
+	} {
+		if err := run(); err == nil || !strings.Contains(err.Error(), "step limit") {
+			t.Errorf("%s over the step limit: err=%v, want a step-limit error", op, err)
+		}
+	}
+	if keys, err := c.QueryKeysTable(ctx, DefaultTable, `N > 0`); err != nil || len(keys) != 2 {
+		t.Errorf("after the bounded ops: keys=%v err=%v, want both rows kept", keys, err)
+	}
+	// Within the limits the bounded paths answer as the unbounded ones do.
+	if rows, err := c.Aggregate(ctx, "", nil, count); err != nil || len(rows) != 1 || rows[0].Values[0] != "2" {
+		t.Errorf("bounded COUNT(*): rows=%v err=%v, want 2", rows, err)
+	}
+	if rows, err := c.MatchSorted(ctx, "Requirements = TARGET.N > 0", 0); err != nil || len(rows) != 2 {
+		t.Errorf("bounded MatchSorted: rows=%d err=%v, want 2", len(rows), err)
+	}
+}
@@ -87,8 +87,10 @@ type matchResult struct {
 // MatchSortedRanked); the resource-side filter (targetWhere) is applied to the
 // ranked candidates. limit caps the number of requests assigned. With significant
 // attributes supplied, identical requests (by signature) reuse a single cached
-// ranked-candidate list (assignment still consumes resources per request).
-func (s *Server) streamMatchTables(ctx context.Context, reqID uint64, r *reader, write func([]byte)) {
+// ranked-candidate list (assignment still consumes resources per request). On a
+// connection with evaluation limits (eval), the request selector and every match are
+// bounded by them, and going over ends the stream with the error after the rows.
+func (s *Server) streamMatchTables(ctx context.Context, reqID uint64, r *reader, write func([]byte), eval *classad.EvalOptions) {
 	reqTable := r.str()
 	resTable := r.str()
 	keyAttr := r.str()
@@ -134,9 +136,16 @@ func (s *Server) streamMatchTables(ctx context.Context, reqID uint64, r *reader,
 	// consumes machines, so a later job may need one ranked past every machine an earlier
 	// job claimed. Under autoclustering this list is computed once per signature and
 	// shared, so the full-list cost is paid once for a whole autocluster.
+	var limitErr error // the first match the evaluation limits cut short
 	candidatesFor := func(reqAd *classad.ClassAd) []matchResult {
-		matches, err := resDB.MatchSortedRankedFiltered(reqAd, targetWhere, 0)
-		if err != nil {
+		var matches []db.RankedMatch
+		var err error
+		if eval != nil {
+			matches, err = resDB.MatchSortedRankedWithOptions(reqAd, targetWhere, 0, *eval)
+			if err != nil && limitErr == nil {
+				limitErr = err
+			}
+		} else if matches, err = resDB.MatchSortedRankedFiltered(reqAd, targetWhere, 0); err != nil {
 			return nil // a bad filter was already reported above
 		}
 		out := make([]matchResult, 0, len(matches))
@@ -157,13 +166,13 @@ func (s *Server) streamMatchTables(ctx context.Context, reqID uint64, r *reader,
 	// removing it from the pool. limit bounds the number of *jobs* assigned.
 	claimed := map[string]bool{}
 
-	seq, err := reqDB.Query(orTrue(reqWhere))
+	c, err := parseConstraint(orTrue(reqWhere), eval)
 	if err != nil {
 		write(respErr(reqID, err.Error()))
 		return
 	}
 	jobs := 0
-	for reqAd := range seq {
+	for reqAd := range reqDB.QueryConstraint(c) {
 		if cancelled(ctx) {
 			return // client gone: stop assigning the remaining jobs
 		}
@@ -203,7 +212,11 @@ func (s *Server) streamMatchTables(ctx context.Context, reqID uint64, r *reader,
 		b = putStr(b, got.rank)
 		write(b)
 	}
-	write(respHead(reqID, stStreamEnd))
+	if limitErr != nil {
+		write(respErr(reqID, limitErr.Error()))
+		return
+	}
+	endStream(reqID, c, write)
 }
 
 func orTrue(constraint string) string {
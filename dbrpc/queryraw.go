@@ -8,6 +8,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/PelicanPlatform/classad/classad"
 	"github.com/PelicanPlatform/classad/collections"
 	"github.com/PelicanPlatform/classad/db"
 )
@@ -173,7 +174,7 @@ func (c *Client) QueryRawProjectStream(ctx context.Context, table, constraint st
 
 // streamQueryRaw streams matching ads as old-ClassAd wire text, rendered from the
 // db QueryRaw pushdown (no AST decode), one frame per ad like streamQuery.
-func (s *Server) streamQueryRaw(ctx context.Context, reqID uint64, r *reader, includePrivate bool, write func([]byte), qlog func(QueryLog)) {
+func (s *Server) streamQueryRaw(ctx context.Context, reqID uint64, r *reader, includePrivate bool, write func([]byte), qlog func(QueryLog), eval *classad.EvalOptions) {
 	start := time.Now()
 	table := r.str()
 	limit := int(r.i32())
@@ -195,20 +196,18 @@ func (s *Server) streamQueryRaw(ctx context.Context, reqID uint64, r *reader, in
 	if !ok {
 		return
 	}
-	// Redaction is pushed into the collection's decode walk: an unprivileged
-	// stream never renders a private value, and no per-attribute name
-	// re-classification happens here.
-	var seq iter.Seq[collections.RawAd]
-	var err error
-	if includePrivate {
-		seq, err = d.QueryRaw(constraint)
-	} else {
-		seq, err = d.QueryRawRedacted(constraint)
-	}
+	c, err := parseConstraint(orTrue(constraint), eval)
 	if err != nil {
 		write(respErr(reqID, err.Error()))
 		return
 	}
+	// Redaction is pushed into the collection's decode walk: an unprivileged
+	// stream never renders a private value, and no per-attribute name
+	// re-classification happens here.
+	seq := d.QueryRawConstraint(c)
+	if !includePrivate {
+		seq = d.QueryRawConstraintRedacted(c)
+	}
 	for ra := range seq {
 		if cancelled(ctx) {
 			return
@@ -219,7 +218,7 @@ func (s *Server) streamQueryRaw(ctx context.Context, reqID uint64, r *reader, in
 			break
 		}
 	}
-	write(respHead(reqID, stStreamEnd))
+	endStream(reqID, c, write)
 }
 
 // rawAdText renders a RawAd as old-ClassAd wire text: the type tags as their own
@@ -250,26 +249,26 @@ func rawAdText(ra collections.RawAd) string {
 // so a client that needs a handful of attributes does not pull every attribute of
 // every ad across the wire. The projection is applied server-side; matching is
 // case-insensitive (ClassAd attribute names are).
-func (s *Server) streamQueryRawProject(ctx context.Context, reqID uint64, r *reader, includePrivate bool, write func([]byte), qlog func(QueryLog)) {
-	s.streamQueryRawProjectOpt(ctx, reqID, r, includePrivate, write, qlog, false, false)
+func (s *Server) streamQueryRawProject(ctx context.Context, reqID uint64, r *reader, includePrivate bool, write func([]byte), qlog func(QueryLog), eval *classad.EvalOptions) {
+	s.streamQueryRawProjectOpt(ctx, reqID, r, includePrivate, write, qlog, eval, false, false)
 }
 
 // streamQueryRawProjectRefs is streamQueryRawProject whose projection also carries the
 // attributes the projected expressions reference, so each streamed ad evaluates
 // self-contained at the far end. See db.DB.QueryRawProjectedRefs.
-func (s *Server) streamQueryRawProjectRefs(ctx context.Context, reqID uint64, r *reader, includePrivate bool, write func([]byte), qlog func(QueryLog)) {
-	s.streamQueryRawProjectOpt(ctx, reqID, r, includePrivate, write, qlog, true, false)
+func (s *Server) streamQueryRawProjectRefs(ctx context.Context, reqID uint64, r *reader, includePrivate bool, write func([]byte), qlog func(QueryLog), eval *classad.EvalOptions) {
+	s.streamQueryRawProjectOpt(ctx, reqID, r, includePrivate, write, qlog, eval, true, false)
 }
 
 // streamQueryRawProjectRefsStats is streamQueryRawProjectRefs that also streams a ScanStats
 // trailer (stStreamStats) before the terminator, for EXPLAIN ANALYZE.
-func (s *Server) streamQueryRawProjectRefsStats(ctx context.Context, reqID uint64, r *reader, includePrivate bool, write func([]byte), qlog func(QueryLog)) {
-	s.streamQueryRawProjectOpt(ctx, reqID, r, includePrivate, write, qlog, true, true)
+func (s *Server) streamQueryRawProjectRefsStats(ctx context.Context, reqID uint64, r *reader, includePrivate bool, write func([]byte), qlog func(QueryLog), eval *classad.EvalOptions) {
+	s.streamQueryRawProjectOpt(ctx, reqID, r, includePrivate, write, qlog, eval, true, true)
 }
 
 // streamQueryRawProjectOpt is the shared body of the projection ops; chaseRefs picks which db
 // projection they use, and wantStats (chaseRefs only) appends a scan-stats trailer.
-func (s *Server) streamQueryRawProjectOpt(ctx context.Context, reqID uint64, r *reader, includePrivate bool, write func([]byte), qlog func(QueryLog), chaseRefs, wantStats bool) {
+func (s *Server) streamQueryRawProjectOpt(ctx context.Context, reqID uint64, r *reader, includePrivate bool, write func([]byte), qlog func(QueryLog), eval *classad.EvalOptions, chaseRefs, wantStats bool) {
 	start := time.Now()
 	table := r.str()
 	limit := int(r.i32())
@@ -296,11 +295,15 @@ func (s *Server) streamQueryRawProjectOpt(ctx context.Context, reqID uint64, r *
 	// append-only archive (history) table -- so the one projection op serves them all
 	// (an archive streams newest-first, like its plain query).
 	var seq iter.Seq[collections.RawAd]
-	var err error
 	redact := !includePrivate
 	if refusePrivateConstraint(reqID, constraint, includePrivate, write) {
 		return
 	}
+	c, err := parseConstraint(orTrue(constraint), eval)
+	if err != nil {
+		write(respErr(reqID, err.Error()))
+		return
+	}
 	// stats is filled by the *RefsStats db methods during iteration when wantStats (which the
 	// caller only sets on the chaseRefs path). nil pointer otherwise, so the normal ops pay
 	// nothing.
@@ -310,36 +313,32 @@ func (s *Server) streamQueryRawProjectOpt(ctx context.Context, reqID uint64, r *
 	}
 	if d, ok := s.cat.Table(table); ok {
 		if wantStats {
-			seq, err = d.QueryRawProjectedRefsStats(constraint, attrs, redact, stats)
+			seq = d.QueryRawProjectedRefsStatsConstraint(c, attrs, redact, stats)
 		} else if chaseRefs {
-			seq, err = d.QueryRawProjectedRefs(constraint, attrs, redact)
+			seq = d.QueryRawProjectedRefsConstraint(c, attrs, redact)
 		} else {
-			seq, err = d.QueryRawProjected(constraint, attrs, redact)
+			seq = d.QueryRawProjectedConstraint(c, attrs, redact)
 		}
 	} else if d, ok := s.cat.ViewBacking(table); ok {
 		if wantStats {
-			seq, err = d.QueryRawProjectedRefsStats(constraint, attrs, redact, stats)
+			seq = d.QueryRawProjectedRefsStatsConstraint(c, attrs, redact, stats)
 		} else if chaseRefs {
-			seq, err = d.QueryRawProjectedRefs(constraint, attrs, redact)
+			seq = d.QueryRawProjectedRefsConstraint(c, attrs, redact)
 		} else {
-			seq, err = d.QueryRawProjected(constraint, attrs, redact)
+			seq = d.QueryRawProjectedConstraint(c, attrs, redact)
 		}
 	} else if a, ok := s.cat.ArchiveTable(table); ok {
 		if wantStats {
-			seq, err = a.QueryRawProjectedRefsStats(constraint, attrs, redact, stats)
+			seq = a.QueryRawProjectedRefsStatsConstraint(c, attrs, redact, stats)
 		} else if chaseRefs {
-			seq, err = a.QueryRawProjectedRefs(constraint, attrs, redact)
+			seq = a.QueryRawProjectedRefsConstraint(c, attrs, redact)
 		} else {
-			seq, err = a.QueryRawProjected(constraint, attrs, redact)
+			seq = a.QueryRawProjectedConstraint(c, attrs, redact)
 		}
 	} else {
 		write(respErr(reqID, "no such table: "+table))
 		return
 	}
-	if err != nil {
-		write(respErr(reqID, err.Error()))
-		return
-	}
 	for ra := range seq {
 		if cancelled(ctx) {
 			return
@@ -353,7 +352,7 @@ func (s *Server) streamQueryRawProjectOpt(ctx context.Context, reqID uint64, r *
 	if wantStats {
 		write(putScanStats(respHead(reqID, stStreamStats), *stats))
 	}
-	write(respHead(reqID, stStreamEnd))
+	endStream(reqID, c, write)
 }
 
 // WireBatchBudget caps one wire-row batch frame's payload bytes. It bounds the
@@ -451,7 +450,7 @@ func (c *Client) QueryRawWireStream(ctx context.Context, table, constraint strin
 // slice-copy subset scan, batched into frames of up to WireBatchBudget payload
 // bytes (a single over-budget row still gets its own frame -- exactly the old
 // one-frame-per-ad behavior, so a jumbo ad is never unshippable).
-func (s *Server) streamQueryRawWire(ctx context.Context, reqID uint64, r *reader, includePrivate bool, write func([]byte), qlog func(QueryLog)) {
+func (s *Server) streamQueryRawWire(ctx context.Context, reqID uint64, r *reader, includePrivate bool, write func([]byte), qlog func(QueryLog), eval *classad.EvalOptions) {
 	start := time.Now()
 	table := r.str()
 	limit := int(r.i32())
@@ -479,7 +478,12 @@ func (s *Server) streamQueryRawWire(ctx context.Context, reqID uint64, r *reader
 	if !ok {
 		return
 	}
-	seq, err := d.QueryRawWire(constraint, attrs, redact)
+	c, err := parseConstraint(orTrue(constraint), eval)
+	if err != nil {
+		write(respErr(reqID, err.Error()))
+		return
+	}
+	seq, err := d.QueryRawWireConstraint(c, attrs, redact)
 	if errors.Is(err, db.ErrRawWireUnsupported) {
 		// Not a failure: this table cannot produce self-contained rows (it is in
 		// memory). Reject the request the same way an older server rejects the
@@ -531,7 +535,7 @@ func (s *Server) streamQueryRawWire(ctx context.Context, reqID uint64, r *reader
 		}
 	}
 	flush()
-	write(respHead(reqID, stStreamEnd))
+	endStream(reqID, c, write)
 }
 
 // projectOpName labels a projection query in the query log by which contract it ran under.
@@ -191,6 +191,46 @@ type ServeOptions struct {
 	// query pattern). Called from the connection's read-loop goroutine, so it must
 	// not block.
 	QueryLog func(QueryLog)
+
+	// Eval bounds the evaluation of every query constraint this connection sends
+	// (classad.EvalOptions: steps, depth, allocation), so one untrusted peer's
+	// pathological constraint cannot monopolize the server. An ad over the limits
+	// does not match, and the query ends with an error after the rows that did.
+	// A nil Eval.Context defaults to the connection's. The zero value is unbounded.
+	Eval classad.EvalOptions
+}
+
+// evalOptions returns the evaluation bounds for one query on a connection whose
+// context is ctx, or nil when Eval sets none.
+func (o ServeOptions) evalOptions(ctx context.Context) *classad.EvalOptions {
+	e := o.Eval
+	if e.MaxSteps <= 0 && e.MaxDepth <= 0 && e.MaxAllocBytes <= 0 && e.Context == nil {
+		return nil
+	}
+	if e.Context == nil {
+		e.Context = ctx
+	}
+	return &e
+}
+
+// parseConstraint compiles a client's constraint, bounded by eval when the connection
+// sets limits (see evalOptions).
+func parseConstraint(constraint string, eval *classad.EvalOptions) (*db.Constraint, error) {
+	if eval == nil {
+		return db.ParseConstraint(constraint)
+	}
+	return db.ParseConstraintWithOptions(constraint, *eval)
+}
+
+// endStream ends a query stream over c. An ad the evaluation limits cut out is not a
+// non-match the client can trust, so a stream they cut short ends with the cause,
+// after the rows already delivered, instead of the terminator.
+func endStream(reqID uint64, c *db.Constraint, write func([]byte)) {
+	if err := c.Err(); err != nil {
+		write(respErr(reqID, err.Error()))
+		return
+	}
+	write(respHead(reqID, stStreamEnd))
 }
 
 // QueryLog is one observed query, passed to ServeOptions.QueryLog.
@@ -413,35 +453,38 @@ func (sc *serverConn) dispatch(frame []byte) {
 		return
 	}
 	priv := sc.opts.IncludePrivate
+	// Every op that evaluates a client's constraint (or job ad) does so under the
+	// connection's limits.
+	eval := sc.opts.evalOptions(sc.ctx)
 	switch o {
 	case opQuery:
-		sc.s.streamQuery(sc.ctx, reqID, body, priv, sc.write, sc.opts.QueryLog)
+		sc.s.streamQuery(sc.ctx, reqID, body, priv, sc.write, sc.opts.QueryLog, eval)
 	case opQueryAsOf:
-		sc.s.streamQueryAsOf(sc.ctx, reqID, body, priv, sc.write, sc.opts.QueryLog)
+		sc.s.streamQueryAsOf(sc.ctx, reqID, body, priv, sc.write, sc.opts.QueryLog, eval)
 	case opQueryRaw:
-		sc.s.streamQueryRaw(sc.ctx, reqID, body, priv, sc.write, sc.opts.QueryLog)
+		sc.s.streamQueryRaw(sc.ctx, reqID, body, priv, sc.write, sc.opts.QueryLog, eval)
 	case opQueryRawProj:
-		sc.s.streamQueryRawProject(sc.ctx, reqID, body, priv, sc.write, sc.opts.QueryLog)
+		sc.s.streamQueryRawProject(sc.ctx, reqID, body, priv, sc.write, sc.opts.QueryLog, eval)
 	case opQueryRawProjRefs:
-		sc.s.streamQueryRawProjectRefs(sc.ctx, reqID, body, priv, sc.write, sc.opts.QueryLog)
+		sc.s.streamQueryRawProjectRefs(sc.ctx, reqID, body, priv, sc.write, sc.opts.QueryLog, eval)
 	case opQueryRawProjRefsStats:
-		sc.s.streamQueryRawProjectRefsStats(sc.ctx, reqID, body, priv, sc.write, sc.opts.QueryLog)
+		sc.s.streamQueryRawProjectRefsStats(sc.ctx, reqID, body, priv, sc.write, sc.opts.QueryLog, eval)
 	case opTopK:
-		sc.s.streamTopK(sc.ctx, reqID, body, priv, sc.write, sc.opts.QueryLog)
+		sc.s.streamTopK(sc.ctx, reqID, body, priv, sc.write, sc.opts.QueryLog, eval)
 	case opQueryRawWire:
-		sc.s.streamQueryRawWire(sc.ctx, reqID, body, priv, sc.write, sc.opts.QueryLog)
+		sc.s.streamQueryRawWire(sc.ctx, reqID, body, priv, sc.write, sc.opts.QueryLog, eval)
 	case opMatchSorted:
-		sc.s.streamMatchSorted(sc.ctx, reqID, body, priv, sc.write)
+		sc.s.streamMatchSorted(sc.ctx, reqID, body, priv, sc.write, eval)
 	case opOrdered:
 		sc.s.streamOrdered(sc.ctx, reqID, body, priv, sc.write)
 	case opAggregate:
-		sc.s.streamAggregate(sc.ctx, reqID, body, priv, sc.write)
+		sc.s.streamAggregate(sc.ctx, reqID, body, priv, sc.write, eval)
 	case opAggregateFiltered:
-		sc.s.streamAggregateFiltered(sc.ctx, reqID, body, priv, sc.write)
+		sc.s.streamAggregateFiltered(sc.ctx, reqID, body, priv, sc.write, eval)
 	case opAggregateBucketed:
-		sc.s.streamAggregateBucketed(sc.ctx, reqID, body, priv, sc.write)
+		sc.s.streamAggregateBucketed(sc.ctx, reqID, body, priv, sc.write, eval)
 	case opMatchTables:
-		sc.s.streamMatchTables(sc.ctx, reqID, body, sc.write)
+		sc.s.streamMatchTables(sc.ctx, reqID, body, sc.write, eval)
 	case opWatch:
 		sc.streamWatch(reqID, body, false)
 	case opWatchWire:
@@ -449,17 +492,17 @@ func (sc *serverConn) dispatch(frame []byte) {
 	case opSnapshot:
 		sc.streamSnapshot(reqID, body)
 	case opArchiveQuery:
-		sc.streamArchiveQuery(reqID, body)
+		sc.streamArchiveQuery(reqID, body, eval)
 	case opArchiveAggregate:
-		sc.streamArchiveAggregate(reqID, body)
+		sc.streamArchiveAggregate(reqID, body, eval)
 	case opArchiveAggregateFiltered:
-		sc.streamArchiveAggregateFiltered(reqID, body)
+		sc.streamArchiveAggregateFiltered(reqID, body, eval)
 	case opQueryKeys:
-		sc.s.streamQueryKeys(sc.ctx, reqID, body, priv, sc.write)
+		sc.s.streamQueryKeys(sc.ctx, reqID, body, priv, sc.write, eval)
 	case opTxnQuery:
-		sc.s.streamTxnQuery(sc.ctx, reqID, body, priv, sc.write)
+		sc.s.streamTxnQuery(sc.ctx, reqID, body, priv, sc.write, eval)
 	case opTxnQueryKeys:
-		sc.s.streamTxnQueryKeys(sc.ctx, reqID, body, priv, sc.write)
+		sc.s.streamTxnQueryKeys(sc.ctx, reqID, body, priv, sc.write, eval)
 	case opWatchStop:
 		sc.stopWatch(body.u64())
 		sc.write(resp(reqID, stOK))
@@ -563,7 +606,7 @@ func (sc *serverConn) stopWatch(watchReqID uint64) {
 // union sealed buckets with the live backing; catalogs without it (single-table) simply
 // don't, so this is additive and non-breaking.
 type viewSealer interface {
-	ViewSealed(name, constraint string) (iter.Seq[*classad.ClassAd], bool, error)
+	ViewSealedConstraint(name string, c *db.Constraint) (iter.Seq[*classad.ClassAd], bool)
 }
 
 // streamQuery streams the committed ads matching a constraint. Each result is its own
@@ -573,7 +616,7 @@ type viewSealer interface {
 // caller can address matched rows for UPDATE/DELETE by their real db key regardless of any
 // self-reported key attribute. Read-only; no private-attribute exposure (keys are returned, not ad
 // bodies). The constraint is still evaluated server-side, so it may reference any attribute.
-func (s *Server) streamQueryKeys(ctx context.Context, reqID uint64, r *reader, includePrivate bool, write func([]byte)) {
+func (s *Server) streamQueryKeys(ctx context.Context, reqID uint64, r *reader, includePrivate bool, write func([]byte), eval *classad.EvalOptions) {
 	table := r.str()
 	constraint := r.str()
 	if r.err != nil {
@@ -588,21 +631,21 @@ func (s *Server) streamQueryKeys(ctx context.Context, reqID uint64, r *reader, i
 	if !ok {
 		return
 	}
-	seq, err := d.KeysWhere(constraint)
+	c, err := parseConstraint(constraint, eval)
 	if err != nil {
 		write(respErr(reqID, err.Error()))
 		return
 	}
-	for key := range seq {
+	for key := range d.KeysWhereConstraint(c) {
 		if cancelled(ctx) {
 			return // client gone: stop the scan
 		}
 		write(putStr(respHead(reqID, stStream), key))
 	}
-	write(respHead(reqID, stStreamEnd))
+	endStream(reqID, c, write)
 }
 
-func (s *Server) streamQuery(ctx context.Context, reqID uint64, r *reader, includePrivate bool, write func([]byte), qlog func(QueryLog)) {
+func (s *Server) streamQuery(ctx context.Context, reqID uint64, r *reader, includePrivate bool, write func([]byte), qlog func(QueryLog), eval *classad.EvalOptions) {
 	start := time.Now()
 	table := r.str()
 	limit := int(r.i32())
@@ -626,32 +669,22 @@ func (s *Server) streamQuery(ctx context.Context, reqID uint64, r *reader, inclu
 	}
 	// An unprivileged session reads with NO key, so a sealed attribute arrives undefined instead of
 	// being decrypted here and dropped by the serializer afterwards (see db.QueryRedacted).
-	var seq iter.Seq[*classad.ClassAd]
-	var err error
-	if includePrivate {
-		seq, err = d.Query(constraint)
-	} else {
-		seq, err = d.QueryRedacted(constraint)
-	}
+	c, err := parseConstraint(constraint, eval)
 	if err != nil {
 		write(respErr(reqID, err.Error()))
 		return
 	}
-	// A continuous aggregate's sealed history lives in its archive; resolve it up front
-	// (so a bad constraint errors before any frame) and union it after the live backing, so
-	// a read of the view returns the full series, not just the unsealed buckets.
+	seq := d.QueryConstraint(c)
+	if !includePrivate {
+		seq = d.QueryConstraintRedacted(c)
+	}
+	// A continuous aggregate's sealed history lives in its archive; union it after the live
+	// backing, so a read of the view returns the full series, not just the unsealed buckets.
 	var sealed iter.Seq[*classad.ClassAd]
 	if vs, ok := s.cat.(viewSealer); ok {
-		var serr error
-		if sq, has, e := vs.ViewSealed(table, constraint); e != nil {
-			serr = e
-		} else if has {
+		if sq, has := vs.ViewSealedConstraint(table, c); has {
 			sealed = sq
 		}
-		if serr != nil {
-			write(respErr(reqID, serr.Error()))
-			return
-		}
 	}
 	// Push LIMIT down: stopping the range stops the underlying scan, so a small
 	// LIMIT does proportionally less work instead of scanning everything.
@@ -677,14 +710,14 @@ func (s *Server) streamQuery(ctx context.Context, reqID uint64, r *reader, inclu
 			}
 		}
 	}
-	write(respHead(reqID, stStreamEnd))
+	endStream(reqID, c, write)
 }
 
 // streamQueryAsOf is streamQuery for a point-in-time ("AS OF") query: it reads a
 // wall-clock instant (unix nanos) and streams the ads that matched the constraint as
 // they were then. It errors cleanly if time travel is disabled or the instant is
 // outside the retained window.
-func (s *Server) streamQueryAsOf(ctx context.Context, reqID uint64, r *reader, includePrivate bool, write func([]byte), qlog func(QueryLog)) {
+func (s *Server) streamQueryAsOf(ctx context.Context, reqID uint64, r *reader, includePrivate bool, write func([]byte), qlog func(QueryLog), eval *classad.EvalOptions) {
 	start := time.Now()
 	table := r.str()
 	limit := int(r.i32())
@@ -707,7 +740,12 @@ func (s *Server) streamQueryAsOf(ctx context.Context, reqID uint64, r *reader, i
 	if !ok {
 		return
 	}
-	seq, err := d.QueryAsOf(constraint, asOf)
+	c, err := parseConstraint(constraint, eval)
+	if err != nil {
+		write(respErr(reqID, err.Error()))
+		return
+	}
+	seq, err := d.QueryAsOfConstraint(c, asOf)
 	if err != nil {
 		write(respErr(reqID, err.Error()))
 		return
@@ -722,11 +760,13 @@ func (s *Server) streamQueryAsOf(ctx context.Context, reqID uint64, r *reader, i
 			break
 		}
 	}
-	write(respHead(reqID, stStreamEnd))
+	endStream(reqID, c, write)
 }
 
-// streamMatchSorted streams job's ranked matches (best first, up to limit).
-func (s *Server) streamMatchSorted(ctx context.Context, reqID uint64, r *reader, includePrivate bool, write func([]byte)) {
+// streamMatchSorted streams job's ranked matches (best first, up to limit). On a
+// connection with evaluation limits the match is bounded by them, and a slot over them
+// ends the stream with the error after the matches.
+func (s *Server) streamMatchSorted(ctx context.Context, reqID uint64, r *reader, includePrivate bool, write func([]byte), eval *classad.EvalOptions) {
 	_ = ctx
 	table := r.str()
 	limit := r.i32()
@@ -744,6 +784,18 @@ func (s *Server) streamMatchSorted(ctx context.Context, reqID uint64, r *reader,
 		write(respErr(reqID, err.Error()))
 		return
 	}
+	if eval != nil {
+		matches, err := d.MatchSortedRankedWithOptions(job, "", int(limit), *eval)
+		for _, m := range matches {
+			write(putStr(respHead(reqID, stStream), adString(m.Ad, includePrivate)))
+		}
+		if err != nil {
+			write(respErr(reqID, err.Error()))
+			return
+		}
+		write(respHead(reqID, stStreamEnd))
+		return
+	}
 	for _, ad := range d.MatchSorted(job, int(limit)) {
 		write(putStr(respHead(reqID, stStream), adString(ad, includePrivate)))
 	}
@@ -1246,7 +1298,14 @@ func (s *Server) handle(sc *serverConn, reqID uint64, o op, r *reader, includePr
 		if !ok {
 			return respErr(reqID, "no such table: "+table)
 		}
-		removed, err := d.DeleteWhere(constraint)
+		c, err := parseConstraint(constraint, sc.opts.evalOptions(sc.ctx))
+		if err != nil {
+			return respErr(reqID, err.Error())
+		}
+		removed, err := d.DeleteWhereConstraint(c)
+		if err == nil {
+			err = c.Err() // the ads over the limits were spared
+		}
 		if err != nil {
 			return respErr(reqID, err.Error())
 		}
@@ -1306,15 +1365,18 @@ func (s *Server) handle(sc *serverConn, reqID uint64, o op, r *reader, includePr
 		if !ok {
 			return respErr(reqID, "no such table: "+resTable)
 		}
-		seq, err := reqDB.Query(orTrue(selector))
+		c, err := parseConstraint(orTrue(selector), sc.opts.evalOptions(sc.ctx))
 		if err != nil {
 			return respErr(reqID, err.Error())
 		}
 		var job *classad.ClassAd
-		for ad := range seq {
+		for ad := range reqDB.QueryConstraint(c) {
 			job = ad
 			break // explain plans one specific request
 		}
+		if err := c.Err(); err != nil {
+			return respErr(reqID, err.Error())
+		}
 		if job == nil {
 			return respErr(reqID, "no request ad matches "+selector)
 		}
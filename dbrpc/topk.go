@@ -57,7 +57,7 @@ func topKErr(err error) error {
 
 // streamTopK serves opTopK: it resolves the table (mutable, view backing, or archive), runs the
 // server-side top-K, and streams the k projected rows as old-ClassAd text, best-first.
-func (s *Server) streamTopK(ctx context.Context, reqID uint64, r *reader, includePrivate bool, write func([]byte), qlog func(QueryLog)) {
+func (s *Server) streamTopK(ctx context.Context, reqID uint64, r *reader, includePrivate bool, write func([]byte), qlog func(QueryLog), eval *classad.EvalOptions) {
 	start := time.Now()
 	table := r.str()
 	constraint := r.str()
@@ -91,19 +91,24 @@ func (s *Server) streamTopK(ctx context.Context, reqID uint64, r *reader, includ
 			return
 		}
 	}
+	c, err := parseConstraint(constraint, eval)
+	if err != nil {
+		write(respErr(reqID, err.Error()))
+		return
+	}
 	var rows [][]classad.Value
-	var err error
 	if d, ok := s.cat.Table(table); ok {
-		rows, err = d.TopK(constraint, attrs, orderAttr, desc, k)
+		rows = d.TopKConstraint(c, attrs, orderAttr, desc, k)
 	} else if d, ok := s.cat.ViewBacking(table); ok {
-		rows, err = d.TopK(constraint, attrs, orderAttr, desc, k)
+		rows = d.TopKConstraint(c, attrs, orderAttr, desc, k)
 	} else if a, ok := s.cat.ArchiveTable(table); ok {
-		rows, err = a.TopK(constraint, attrs, orderAttr, desc, k)
+		rows = a.TopKConstraint(c, attrs, orderAttr, desc, k)
 	} else {
 		write(respErr(reqID, "no such table: "+table))
 		return
 	}
-	if err != nil {
+	// The top k of a scan the limits cut short are not the top k of the table.
+	if err := c.Err(); err != nil {
 		write(respErr(reqID, err.Error()))
 		return
 	}
@@ -3,6 +3,8 @@ package dbrpc
 import (
 	"context"
 	"errors"
+
+	"github.com/PelicanPlatform/classad/classad"
 )
 
 // ErrTxnReadUnsupported is returned by the Tx read methods against a server too old to
@@ -62,7 +64,7 @@ func txnReadErr(err error) error {
 
 // streamTxnQuery is the server side of opTxnQuery: the transaction's own view of a
 // constraint query, streamed like opQuery.
-func (s *Server) streamTxnQuery(ctx context.Context, reqID uint64, r *reader, includePrivate bool, write func([]byte)) {
+func (s *Server) streamTxnQuery(ctx context.Context, reqID uint64, r *reader, includePrivate bool, write func([]byte), eval *classad.EvalOptions) {
 	id := r.u64()
 	limit := int(r.i32())
 	constraint := r.str()
@@ -74,13 +76,13 @@ func (s *Server) streamTxnQuery(ctx context.Context, reqID uint64, r *reader, in
 		return
 	}
 	s.withTxnStream(reqID, id, write, func(st *serverTxn) {
-		seq, err := st.tx.Query(constraint)
+		c, err := parseConstraint(constraint, eval)
 		if err != nil {
 			write(respErr(reqID, err.Error()))
 			return
 		}
 		n := 0
-		for ad := range seq {
+		for ad := range st.tx.QueryConstraint(c) {
 			if cancelled(ctx) {
 				return // client gone: stop the scan
 			}
@@ -90,12 +92,12 @@ func (s *Server) streamTxnQuery(ctx context.Context, reqID uint64, r *reader, in
 				break
 			}
 		}
-		write(respHead(reqID, stStreamEnd))
+		endStream(reqID, c, write)
 	})
 }
 
 // streamTxnQueryKeys is the server side of opTxnQueryKeys.
-func (s *Server) streamTxnQueryKeys(ctx context.Context, reqID uint64, r *reader, includePrivate bool, write func([]byte)) {
+func (s *Server) streamTxnQueryKeys(ctx context.Context, reqID uint64, r *reader, includePrivate bool, write func([]byte), eval *classad.EvalOptions) {
 	id := r.u64()
 	constraint := r.str()
 	if r.err != nil {
@@ -106,18 +108,18 @@ func (s *Server) streamTxnQueryKeys(ctx context.Context, reqID uint64, r *reader
 		return
 	}
 	s.withTxnStream(reqID, id, write, func(st *serverTxn) {
-		seq, err := st.tx.KeysWhere(constraint)
+		c, err := parseConstraint(constraint, eval)
 		if err != nil {
 			write(respErr(reqID, err.Error()))
 			return
 		}
-		for key := range seq {
+		for key := range st.tx.KeysWhereConstraint(c) {
 			if cancelled(ctx) {
 				return
 			}
 			write(putStr(respHead(reqID, stStream), key))
 		}
-		write(respHead(reqID, stStreamEnd))
+		endStream(reqID, c, write)
 	})
 }
 
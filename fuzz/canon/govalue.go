@@ -95,9 +95,21 @@ func fromGoValue(v classad.Value, depth int) Value {
 			return Value{Kind: KClassad}
 		}
 		return fromGoClassAd(ad, depth)
+	case classad.AbsTimeValue:
+		t, err := v.AbsTimeValue()
+		if err != nil {
+			return Value{Kind: KError}
+		}
+		_, off := t.Zone()
+		return Value{Kind: KAbstime, R: float64(t.Unix()), Off: int64(off)}
+	case classad.RelTimeValue:
+		secs, err := v.RelTimeValue()
+		if err != nil {
+			return Value{Kind: KError}
+		}
+		return Value{Kind: KReltime, R: secs}
 	default:
-		// The Go engine has no dedicated absolute/relative-time value kind;
-		// any unmapped type is reported as error so a divergence is visible.
+		// Any unmapped type is reported as error so a divergence is visible.
 		return Value{Kind: KError}
 	}
 }
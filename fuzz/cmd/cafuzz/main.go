@@ -17,6 +17,7 @@
 //	cafuzz -n 1000 -seed 42 -v       # verbose: print every divergence
 //	cafuzz -corpus seeds.txt         # run a file of ads, one per line
 //	cafuzz -ad '[ a = 1/2 ]'         # one ad, print both engines' results
+//	cafuzz -n 100000 -strict-cpp     # Go side in classad.StrictCppDialect()
 package main
 
 import (
@@ -27,6 +28,7 @@ import (
 	"sort"
 	"strings"
 
+	classad "github.com/PelicanPlatform/classad/classad"
 	"github.com/PelicanPlatform/classad/fuzz/canon"
 	"github.com/PelicanPlatform/classad/fuzz/differ"
 	"github.com/PelicanPlatform/classad/fuzz/gen"
@@ -44,11 +46,16 @@ func run() int {
 		maxReport   = flag.Int("max-per-bucket", 3, "max example inputs to keep per divergence bucket")
 		ignoreParse = flag.Bool("ignore-parse", false, "ignore parse-only divergences (focus on evaluation)")
 		journal     = flag.String("journal", "", "write each input here before evaluating (crash reproducer)")
+		strictCpp   = flag.Bool("strict-cpp", false, "run the Go engine in strict C++ mode (classad.StrictCppDialect)")
 	)
 	flag.Parse()
 
 	opts := differ.DefaultOptions()
 	opts.IgnoreParseDivergence = *ignoreParse
+	if *strictCpp {
+		d := classad.StrictCppDialect()
+		opts.Dialect = &d
+	}
 
 	if *oneAd != "" {
 		runOne(*oneAd, opts)
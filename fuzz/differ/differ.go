@@ -38,7 +38,8 @@ const (
 	CppTimeout
 	// KnownQuirk: the engines disagree, but entirely because of a documented
 	// libclassad quirk the Go engine deliberately does not mirror (see
-	// fuzz/CPP_QUIRKS.md). Treated as a non-divergence.
+	// fuzz/CPP_QUIRKS.md). Treated as a non-divergence. Never reported under
+	// Options.Dialect, which mirrors the quirks.
 	KnownQuirk
 )
 
@@ -99,6 +100,11 @@ type Options struct {
 	// advertise, whose string literals keep escapes literal. This path was previously
 	// untested differentially, which is how the OSIssue = "\S" divergence shipped.
 	OldClassAd bool
+	// Dialect, when non-nil, parses and evaluates the Go side in that dialect
+	// -- classad.StrictCppDialect() for strict C++ mode. The dialect mirrors
+	// the quirks a KnownQuirk excuses, so a divergence is then never
+	// downgraded to one: it is reported as what it is.
+	Dialect *classad.Dialect
 }
 
 // DefaultOptions is the standard comparison configuration.
@@ -108,12 +114,19 @@ func DefaultOptions() Options {
 
 // goEval parses and evaluates src with the native Go engine. When old is set it uses the
 // old-ClassAd parser (classad.ParseOld) instead of the new-ClassAd one, so the differ can
-// exercise the wire format daemons advertise -- whose string-escape semantics differ.
-func goEval(src string, old bool) (val canon.Value, raw string, parsed bool, err error) {
+// exercise the wire format daemons advertise -- whose string-escape semantics differ. A
+// non-nil dialect parses (and so evaluates) in that dialect.
+func goEval(src string, old bool, dialect *classad.Dialect) (val canon.Value, raw string, parsed bool, err error) {
 	parse := classad.Parse
 	if old {
 		parse = classad.ParseOld
 	}
+	if dialect != nil {
+		parse = dialect.Parse
+		if old {
+			parse = dialect.ParseOld
+		}
+	}
 	ad, perr := parse(src)
 	if perr != nil {
 		return canon.Value{}, "", false, perr
@@ -124,13 +137,13 @@ func goEval(src string, old bool) (val canon.Value, raw string, parsed bool, err
 
 // goEvalSafe wraps goEval, converting a panic in the Go engine into a reported
 // finding rather than crashing the fuzzer.
-func goEvalSafe(src string, old bool) (val canon.Value, raw string, parsed bool, panicked string, err error) {
+func goEvalSafe(src string, old bool, dialect *classad.Dialect) (val canon.Value, raw string, parsed bool, panicked string, err error) {
 	defer func() {
 		if rec := recover(); rec != nil {
 			panicked = fmt.Sprintf("%v", rec)
 		}
 	}()
-	val, raw, parsed, err = goEval(src, old)
+	val, raw, parsed, err = goEval(src, old, dialect)
 	return
 }
 
@@ -138,7 +151,7 @@ func goEvalSafe(src string, old bool) (val canon.Value, raw string, parsed bool,
 func Compare(src string, opts Options) Result {
 	var r Result
 
-	goVal, goRaw, goParsed, goPanic, goErr := goEvalSafe(src, opts.OldClassAd)
+	goVal, goRaw, goParsed, goPanic, goErr := goEvalSafe(src, opts.OldClassAd, opts.Dialect)
 	r.GoParsed = goParsed
 	r.GoCanon = goVal
 	r.GoRaw = goRaw
@@ -201,8 +214,9 @@ func Compare(src string, opts Options) Result {
 	}
 
 	// A divergence explained entirely by a documented libclassad quirk the Go
-	// engine does not mirror is not a real divergence.
-	if explainedByListIsQuirk(src, goVal, cppVal) {
+	// engine does not mirror is not a real divergence -- unless the dialect
+	// was meant to mirror it.
+	if opts.Dialect == nil && explainedByListIsQuirk(src, goVal, cppVal) {
 		r.Category = KnownQuirk
 		r.Detail = "CPP_QUIRKS #9: =?=/=!= on a list literal vs a function-produced list"
 		return r
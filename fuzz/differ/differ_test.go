@@ -2,7 +2,12 @@
 
 package differ
 
-import "testing"
+import (
+	"runtime"
+	"testing"
+
+	classad "github.com/PelicanPlatform/classad/classad"
+)
 
 // TestCppTimeoutOnCyclicHang guards that a cyclic self-reference that makes
 // libclassad infinite-loop (a known C++ bug; the Go engine resolves it to
@@ -21,3 +26,33 @@ func TestCppTimeoutOnCyclicHang(t *testing.T) {
 		t.Errorf("Go should parse the input")
 	}
 }
+
+// TestStrictCppDialect guards strict C++ mode: on a reproducer of each quirk
+// classad.StrictCppDialect mirrors, the default comparison disagrees (or
+// excuses the disagreement as a KnownQuirk), while strict mode matches.
+func TestStrictCppDialect(t *testing.T) {
+	d := classad.StrictCppDialect()
+	strict := DefaultOptions()
+	strict.Dialect = &d
+
+	for _, src := range []string{
+		`[ x = 9223372036854775808 ]`,                                  // CPP_QUIRKS #1
+		`[ a = versiongt("2.0", "1.0") ]`,                              // #6
+		`[ L = splitSlotName("a@b"); a = ({1} =?= L); b = (L =?= L) ]`, // #9
+		`[ x = ..5 ]`, // #10
+	} {
+		if r := Compare(src, DefaultOptions()); r.Category == Match {
+			t.Errorf("%s: the default comparison matched; the quirk is gone from libclassad", src)
+		}
+		if r := Compare(src, strict); r.Category != Match {
+			t.Errorf("%s: strict C++ mode is %v (%s)", src, r.Category, r.Detail)
+		}
+	}
+
+	// #8 is the x86-64 result of undefined behavior.
+	if runtime.GOARCH == "amd64" {
+		if r := Compare(`[ a = int(real("nan")); b = floor(1e30) ]`, strict); r.Category != Match {
+			t.Errorf("int conversion in strict C++ mode is %v (%s)", r.Category, r.Detail)
+		}
+	}
+}
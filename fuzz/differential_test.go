@@ -26,6 +26,7 @@ import (
 	"testing"
 	"unicode/utf8"
 
+	classad "github.com/PelicanPlatform/classad/classad"
 	"github.com/PelicanPlatform/classad/fuzz/differ"
 )
 
@@ -98,14 +99,31 @@ func loadCorpus(f *testing.F) {
 	}
 }
 
+// strictCppOpts returns o with the Go engine in strict C++ mode.
+func strictCppOpts(o differ.Options) differ.Options {
+	d := classad.StrictCppDialect()
+	o.Dialect = &d
+	return o
+}
+
 func FuzzDifferential(f *testing.F) {
+	fuzzDifferential(f, seedOpts)
+}
+
+// FuzzDifferentialStrictCpp is FuzzDifferential with the Go engine in strict
+// C++ mode (classad.StrictCppDialect). The dialect mirrors the quirks the
+// differ would otherwise excuse, so no divergence is a KnownQuirk here: each
+// one fails the target.
+func FuzzDifferentialStrictCpp(f *testing.F) {
+	fuzzDifferential(f, strictCppOpts(seedOpts))
+}
+
+func fuzzDifferential(f *testing.F, opts differ.Options) {
 	for _, s := range seeds {
 		addIfMatch(f, s)
 	}
 	loadCorpus(f)
 
-	opts := seedOpts
-
 	f.Fuzz(func(t *testing.T, src string) {
 		// ClassAd source is text. Skip non-UTF-8 mutations: the Go lexer
 		// decodes string literals as UTF-8 (so an invalid byte like 0xa2
@@ -124,6 +142,10 @@ func FuzzDifferential(f *testing.F) {
 				src, r.Detail, r.GoRaw, r.CppRaw)
 		case differ.EncodingError:
 			t.Fatalf("canonical decode error\n  input: %s\n  %s", src, r.Detail)
+		case differ.KnownQuirk:
+			if opts.Dialect != nil {
+				t.Fatalf("known quirk in strict C++ mode\n  input: %s\n  %s", src, r.Detail)
+			}
 		}
 	})
 }
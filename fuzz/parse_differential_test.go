@@ -23,21 +23,23 @@ import (
 
 // knownParseDelta reports whether a parse-level disagreement is one of the
 // intentional, documented differences between the Go grammar/lexer and
-// libclassad's -- i.e. not a Go parser bug.
-func knownParseDelta(src string, r differ.Result) bool {
+// libclassad's -- i.e. not a Go parser bug. In strict C++ mode the lexer
+// mirrors the integer-overflow and doubled-dot quirks, so they are no longer
+// excused.
+func knownParseDelta(src string, r differ.Result, strict bool) bool {
 	// Integer-literal overflow: Go rejects a decimal integer that does not fit
 	// int64, while libclassad silently wraps it (CPP_QUIRKS #1). Deliberately
 	// not mirrored. (The lexer's "invalid integer" error is reported by yacc as
 	// a generic "syntax error", so detect the overflowing literal in the source
 	// instead.)
-	if !r.GoParsed && r.CppParsed && containsOverflowingInt(src) {
+	if !strict && !r.GoParsed && r.CppParsed && containsOverflowingInt(src) {
 		return true
 	}
 	// libclassad's number lexer is strtod-lenient and accepts a doubled leading
 	// dot ("..5" -> 0.0) that the Go lexer rejects. CPP_QUIRKS #10; not
 	// mirrored. (".e5" is a leading-dot reference to e5, handled by the parser,
 	// not a float quirk.)
-	if !r.GoParsed && r.CppParsed && strings.Contains(src, "..") {
+	if !strict && !r.GoParsed && r.CppParsed && strings.Contains(src, "..") {
 		return true
 	}
 	// libclassad's parser leniently recovers from unbalanced or mismatched
@@ -193,13 +195,23 @@ func containsOverflowingInt(src string) bool {
 }
 
 func FuzzParseDifferential(f *testing.F) {
+	fuzzParseDifferential(f, differ.DefaultOptions()) // IgnoreParseDivergence = false
+}
+
+// FuzzParseDifferentialStrictCpp is FuzzParseDifferential with the Go parser in
+// strict C++ mode (classad.StrictCppDialect), which must also accept what
+// libclassad's lenient number lexer does.
+func FuzzParseDifferentialStrictCpp(f *testing.F) {
+	fuzzParseDifferential(f, strictCppOpts(differ.DefaultOptions()))
+}
+
+func fuzzParseDifferential(f *testing.F, opts differ.Options) {
 	for _, s := range seeds {
 		addIfMatch(f, s)
 	}
 	loadCorpus(f)
 
-	opts := differ.DefaultOptions() // IgnoreParseDivergence = false
-
+	strict := opts.Dialect != nil
 	f.Fuzz(func(t *testing.T, src string) {
 		// Non-UTF-8 string-literal scanning is a known byte-vs-rune lexer delta
 		// (see README); out of scope for parser-agreement fuzzing. An embedded
@@ -213,7 +225,7 @@ func FuzzParseDifferential(f *testing.F) {
 		case differ.GoPanic:
 			t.Fatalf("Go engine panicked\n  input: %q\n  %s", src, r.Detail)
 		case differ.ParseDivergence:
-			if knownParseDelta(src, r) {
+			if knownParseDelta(src, r, strict) {
 				return
 			}
 			t.Fatalf("parser disagreement (%s)\n  input: %q\n  go-err: %v",
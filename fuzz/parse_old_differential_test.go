@@ -46,7 +46,7 @@ var oldStringSeeds = []string{
 // new ClassAds share the expression grammar and number lexer; only the string tokenizer
 // differs, and that is exactly what this target is meant to flag rather than excuse.
 func knownOldParseDelta(src string, r differ.Result) bool {
-	return knownParseDelta(src, r)
+	return knownParseDelta(src, r, false)
 }
 
 // hasUnescapedQuote reports whether s contains a double quote that would terminate the
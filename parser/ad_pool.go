@@ -37,13 +37,14 @@ var adParserPool = sync.Pool{
 // reset points the pooled instance at a new input and clears every field the previous parse
 // touched. The whole-ad entry points parse one complete ad from a string, so unlike the
 // streaming lexer this one must not stop early or carry state across calls.
-func (ap *adParser) reset(input string, lenientEscapes bool) {
+func (ap *adParser) reset(input string, lenientEscapes bool, opts Options) {
 	ap.sr.Reset(input)
 	ap.br.Reset(&ap.sr)
 	ap.slx.resetForNext()
-	ap.slx.pos = 0
+	ap.slx.resetPos()
 	ap.slx.stopAfterClassAd = false
 	ap.slx.lenientEscapes = lenientEscapes
+	ap.slx.quirks = opts
 	ap.lex.input = input
 	ap.lex.pos = 0
 	ap.lex.result = nil
@@ -51,13 +52,14 @@ func (ap *adParser) reset(input string, lenientEscapes bool) {
 }
 
 // parsePooled parses one whole ClassAd from input with a pooled parser. lenientEscapes
-// selects the old-ClassAd string semantics (see StreamingLexer.lenientEscapes).
-func parsePooled(input string, lenientEscapes bool) (ast.Node, error) {
+// selects the old-ClassAd string semantics (see StreamingLexer.lenientEscapes), and
+// opts the reference lexer's number leniencies.
+func parsePooled(input string, lenientEscapes bool, opts Options) (ast.Node, error) {
 	ap, ok := adParserPool.Get().(*adParser)
 	if !ok {
 		panic("adParserPool held an unexpected type") // pool's New only makes *adParser
 	}
-	ap.reset(input, lenientEscapes)
+	ap.reset(input, lenientEscapes, opts)
 	ap.p.Parse(&ap.lex)
 	node, err := ap.lex.Result()
 	// Return nothing to the pool that outlives this call: the parsed AST is the caller's,
@@ -493,3 +493,51 @@ func TestParseExprType(t *testing.T) {
 		})
 	}
 }
+
+func TestParseWithOptions(t *testing.T) {
+	lenient := Options{WrapIntOverflow: true, LenientDoubleDot: true}
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"9223372036854775808", "0"},
+		{"99999999999999999999 + 1", "(0 + 1)"},
+		{"..5", "0"},
+		{"1 + ..25", "(1 + 0)"},
+	}
+	for _, tt := range tests {
+		if _, err := ParseExpr(tt.input); err == nil {
+			t.Errorf("ParseExpr(%q) accepted it without options", tt.input)
+		}
+		expr, err := ParseExprWithOptions(tt.input, lenient)
+		if err != nil {
+			t.Errorf("ParseExprWithOptions(%q): %v", tt.input, err)
+			continue
+		}
+		if got := expr.String(); got != tt.want {
+			t.Errorf("ParseExprWithOptions(%q) = %s, want %s", tt.input, got, tt.want)
+		}
+	}
+	if expr, _ := ParseExprWithOptions("..5", lenient); fmt.Sprintf("%T", expr) != "*ast.RealLiteral" {
+		t.Errorf("..5 parsed to %T, want a real", expr)
+	}
+	if expr, err := ParseExprWithOptions("-9223372036854775808", lenient); err != nil || expr.String() != "-9223372036854775808" {
+		t.Errorf("INT64_MIN = %v, %v", expr, err)
+	}
+	for _, input := range []string{"..", "..x", "1..5", "010"} {
+		if _, err := ParseExprWithOptions(input, lenient); err == nil {
+			t.Errorf("ParseExprWithOptions(%q) accepted it", input)
+		}
+	}
+
+	ad, err := ParseClassAdWithOptions("[a = 18446744073709551616; b = ..5]", lenient)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := ad.String(); got != "[a = 0; b = 0]" {
+		t.Errorf("ParseClassAdWithOptions = %s", got)
+	}
+	if _, err := ParseOldClassAdWithOptions("a = 18446744073709551616\nb = ..5", lenient); err != nil {
+		t.Errorf("ParseOldClassAdWithOptions: %v", err)
+	}
+}
@@ -18,6 +18,11 @@ import (
 	integer   int64
 	real      float64
 	boolean   bool
+	/* Byte extent of the symbol in the source, [pos, end): set by the lexer
+	   for a token; a reduction inherits $1's pos and an action extends end
+	   to its last symbol's (via markSpan). */
+	pos       int
+	end       int
 }
 
 %token <str> IDENTIFIER STRING_LITERAL
@@ -79,12 +84,12 @@ start
 
 classad
 	: '[' attr_list ']'
-		{ $$ = &ast.ClassAd{Attributes: $2} }
+		{ $$ = &ast.ClassAd{Attributes: $2}; $<end>$ = markSpan(yylex, $$, $<pos>1, $<end>3) }
 	;
 
 record_literal
 	: '[' attr_list ']'
-		{ $$ = &ast.ClassAd{Attributes: $2} }
+		{ $$ = &ast.ClassAd{Attributes: $2}; $<end>$ = markSpan(yylex, $$, $<pos>1, $<end>3) }
 	;
 
 /* Assignments are separated by ';', but empty statements are allowed anywhere
@@ -107,7 +112,7 @@ attr_stmt
 
 attr_assign
 	: IDENTIFIER '=' expr
-		{ $$ = &ast.AttributeAssignment{Name: $1, Value: $3} }
+		{ $$ = &ast.AttributeAssignment{Name: $1, Value: $3}; $<end>$ = markSpan(yylex, $$, $<pos>1, $<end>3) }
 	;
 
 expr
@@ -119,129 +124,129 @@ cond_expr
 	: logical_or_expr
 		{ $$ = $1 }
 	| logical_or_expr '?' expr ':' cond_expr
-		{ $$ = &ast.ConditionalExpr{Condition: $1, TrueExpr: $3, FalseExpr: $5} }
+		{ $$ = &ast.ConditionalExpr{Condition: $1, TrueExpr: $3, FalseExpr: $5}; $<end>$ = markSpan(yylex, $$, $<pos>1, $<end>5) }
 	| logical_or_expr '?' ':' cond_expr
-		{ $$ = &ast.ElvisExpr{Left: $1, Right: $4} }
+		{ $$ = &ast.ElvisExpr{Left: $1, Right: $4}; $<end>$ = markSpan(yylex, $$, $<pos>1, $<end>4) }
 	;
 
 logical_or_expr
 	: logical_and_expr
 		{ $$ = $1 }
 	| logical_or_expr OR logical_and_expr
-		{ $$ = &ast.BinaryOp{Op: "||", Left: $1, Right: $3} }
+		{ $$ = &ast.BinaryOp{Op: "||", Left: $1, Right: $3}; $<end>$ = markSpan(yylex, $$, $<pos>1, $<end>3) }
 	;
 
 logical_and_expr
 	: or_expr
 		{ $$ = $1 }
 	| logical_and_expr AND or_expr
-		{ $$ = &ast.BinaryOp{Op: "&&", Left: $1, Right: $3} }
+		{ $$ = &ast.BinaryOp{Op: "&&", Left: $1, Right: $3}; $<end>$ = markSpan(yylex, $$, $<pos>1, $<end>3) }
 	;
 
 or_expr
 	: xor_expr
 		{ $$ = $1 }
 	| or_expr '|' xor_expr
-		{ $$ = &ast.BinaryOp{Op: "|", Left: $1, Right: $3} }
+		{ $$ = &ast.BinaryOp{Op: "|", Left: $1, Right: $3}; $<end>$ = markSpan(yylex, $$, $<pos>1, $<end>3) }
 	;
 
 xor_expr
 	: and_expr
 		{ $$ = $1 }
 	| xor_expr '^' and_expr
-		{ $$ = &ast.BinaryOp{Op: "^", Left: $1, Right: $3} }
+		{ $$ = &ast.BinaryOp{Op: "^", Left: $1, Right: $3}; $<end>$ = markSpan(yylex, $$, $<pos>1, $<end>3) }
 	;
 
 and_expr
 	: eq_expr
 		{ $$ = $1 }
 	| and_expr '&' eq_expr
-		{ $$ = &ast.BinaryOp{Op: "&", Left: $1, Right: $3} }
+		{ $$ = &ast.BinaryOp{Op: "&", Left: $1, Right: $3}; $<end>$ = markSpan(yylex, $$, $<pos>1, $<end>3) }
 	;
 
 eq_expr
 	: rel_expr
 		{ $$ = $1 }
 	| eq_expr EQ rel_expr
-		{ $$ = &ast.BinaryOp{Op: "==", Left: $1, Right: $3} }
+		{ $$ = &ast.BinaryOp{Op: "==", Left: $1, Right: $3}; $<end>$ = markSpan(yylex, $$, $<pos>1, $<end>3) }
 	| eq_expr NE rel_expr
-		{ $$ = &ast.BinaryOp{Op: "!=", Left: $1, Right: $3} }
+		{ $$ = &ast.BinaryOp{Op: "!=", Left: $1, Right: $3}; $<end>$ = markSpan(yylex, $$, $<pos>1, $<end>3) }
 	| eq_expr IS rel_expr
-		{ $$ = &ast.BinaryOp{Op: "is", Left: $1, Right: $3} }
+		{ $$ = &ast.BinaryOp{Op: "is", Left: $1, Right: $3}; $<end>$ = markSpan(yylex, $$, $<pos>1, $<end>3) }
 	| eq_expr ISNT rel_expr
-		{ $$ = &ast.BinaryOp{Op: "isnt", Left: $1, Right: $3} }
+		{ $$ = &ast.BinaryOp{Op: "isnt", Left: $1, Right: $3}; $<end>$ = markSpan(yylex, $$, $<pos>1, $<end>3) }
 	;
 
 rel_expr
 	: shift_expr
 		{ $$ = $1 }
 	| rel_expr '<' shift_expr
-		{ $$ = &ast.BinaryOp{Op: "<", Left: $1, Right: $3} }
+		{ $$ = &ast.BinaryOp{Op: "<", Left: $1, Right: $3}; $<end>$ = markSpan(yylex, $$, $<pos>1, $<end>3) }
 	| rel_expr '>' shift_expr
-		{ $$ = &ast.BinaryOp{Op: ">", Left: $1, Right: $3} }
+		{ $$ = &ast.BinaryOp{Op: ">", Left: $1, Right: $3}; $<end>$ = markSpan(yylex, $$, $<pos>1, $<end>3) }
 	| rel_expr LE shift_expr
-		{ $$ = &ast.BinaryOp{Op: "<=", Left: $1, Right: $3} }
+		{ $$ = &ast.BinaryOp{Op: "<=", Left: $1, Right: $3}; $<end>$ = markSpan(yylex, $$, $<pos>1, $<end>3) }
 	| rel_expr GE shift_expr
-		{ $$ = &ast.BinaryOp{Op: ">=", Left: $1, Right: $3} }
+		{ $$ = &ast.BinaryOp{Op: ">=", Left: $1, Right: $3}; $<end>$ = markSpan(yylex, $$, $<pos>1, $<end>3) }
 	;
 
 shift_expr
 	: add_expr
 		{ $$ = $1 }
 	| shift_expr LSHIFT add_expr
-		{ $$ = &ast.BinaryOp{Op: "<<", Left: $1, Right: $3} }
+		{ $$ = &ast.BinaryOp{Op: "<<", Left: $1, Right: $3}; $<end>$ = markSpan(yylex, $$, $<pos>1, $<end>3) }
 	| shift_expr RSHIFT add_expr
-		{ $$ = &ast.BinaryOp{Op: ">>", Left: $1, Right: $3} }
+		{ $$ = &ast.BinaryOp{Op: ">>", Left: $1, Right: $3}; $<end>$ = markSpan(yylex, $$, $<pos>1, $<end>3) }
 	| shift_expr URSHIFT add_expr
-		{ $$ = &ast.BinaryOp{Op: ">>>", Left: $1, Right: $3} }
+		{ $$ = &ast.BinaryOp{Op: ">>>", Left: $1, Right: $3}; $<end>$ = markSpan(yylex, $$, $<pos>1, $<end>3) }
 	;
 
 add_expr
 	: mult_expr
 		{ $$ = $1 }
 	| add_expr '+' mult_expr
-		{ $$ = &ast.BinaryOp{Op: "+", Left: $1, Right: $3} }
+		{ $$ = &ast.BinaryOp{Op: "+", Left: $1, Right: $3}; $<end>$ = markSpan(yylex, $$, $<pos>1, $<end>3) }
 	| add_expr '-' mult_expr
-		{ $$ = &ast.BinaryOp{Op: "-", Left: $1, Right: $3} }
+		{ $$ = &ast.BinaryOp{Op: "-", Left: $1, Right: $3}; $<end>$ = markSpan(yylex, $$, $<pos>1, $<end>3) }
 	;
 
 mult_expr
 	: unary_expr
 		{ $$ = $1 }
 	| mult_expr '*' unary_expr
-		{ $$ = &ast.BinaryOp{Op: "*", Left: $1, Right: $3} }
+		{ $$ = &ast.BinaryOp{Op: "*", Left: $1, Right: $3}; $<end>$ = markSpan(yylex, $$, $<pos>1, $<end>3) }
 	| mult_expr '/' unary_expr
-		{ $$ = &ast.BinaryOp{Op: "/", Left: $1, Right: $3} }
+		{ $$ = &ast.BinaryOp{Op: "/", Left: $1, Right: $3}; $<end>$ = markSpan(yylex, $$, $<pos>1, $<end>3) }
 	| mult_expr '%' unary_expr
-		{ $$ = &ast.BinaryOp{Op: "%", Left: $1, Right: $3} }
+		{ $$ = &ast.BinaryOp{Op: "%", Left: $1, Right: $3}; $<end>$ = markSpan(yylex, $$, $<pos>1, $<end>3) }
 	;
 
 unary_expr
 	: postfix_expr
 		{ $$ = $1 }
 	| '-' unary_expr %prec UNARY
-		{ $$ = &ast.UnaryOp{Op: "-", Expr: $2} }
+		{ $$ = &ast.UnaryOp{Op: "-", Expr: $2}; $<end>$ = markSpan(yylex, $$, $<pos>1, $<end>2) }
 	| '-' INT64_MIN_MAGNITUDE %prec UNARY
-		{ $$ = &ast.IntegerLiteral{Value: -9223372036854775808} }
+		{ $$ = &ast.IntegerLiteral{Value: -9223372036854775808}; $<end>$ = markSpan(yylex, $$, $<pos>1, $<end>2) }
 	| '+' unary_expr %prec UNARY
-		{ $$ = &ast.UnaryOp{Op: "+", Expr: $2} }
+		{ $$ = &ast.UnaryOp{Op: "+", Expr: $2}; $<end>$ = markSpan(yylex, $$, $<pos>1, $<end>2) }
 	| '!' unary_expr
-		{ $$ = &ast.UnaryOp{Op: "!", Expr: $2} }
+		{ $$ = &ast.UnaryOp{Op: "!", Expr: $2}; $<end>$ = markSpan(yylex, $$, $<pos>1, $<end>2) }
 	| '~' unary_expr
-		{ $$ = &ast.UnaryOp{Op: "~", Expr: $2} }
+		{ $$ = &ast.UnaryOp{Op: "~", Expr: $2}; $<end>$ = markSpan(yylex, $$, $<pos>1, $<end>2) }
 	;
 
 postfix_expr
 	: primary_expr
 		{ $$ = $1 }
 	| postfix_expr '.' IDENTIFIER
-		{ $$ = &ast.SelectExpr{Record: $1, Attr: $3} }
+		{ $$ = &ast.SelectExpr{Record: $1, Attr: $3}; $<end>$ = markSpan(yylex, $$, $<pos>1, $<end>3) }
 	| postfix_expr '[' expr ']'
-		{ $$ = &ast.SubscriptExpr{Container: $1, Index: $3} }
+		{ $$ = &ast.SubscriptExpr{Container: $1, Index: $3}; $<end>$ = markSpan(yylex, $$, $<pos>1, $<end>4) }
 	| postfix_expr ELVIS postfix_expr
-		{ $$ = &ast.ElvisExpr{Left: $1, Right: $3} }
+		{ $$ = &ast.ElvisExpr{Left: $1, Right: $3}; $<end>$ = markSpan(yylex, $$, $<pos>1, $<end>3) }
 	| IDENTIFIER '(' opt_arg_list ')'
-		{ $$ = &ast.FunctionCall{Name: $1, Args: $3} }
+		{ $$ = &ast.FunctionCall{Name: $1, Args: $3}; $<end>$ = markSpan(yylex, $$, $<pos>1, $<end>4) }
 	;
 
 primary_expr
@@ -251,6 +256,7 @@ primary_expr
 		{
 			name, scope := ParseScopedIdentifier($1)
 			$$ = ast.NewAttributeReference(name, scope)
+			markSpan(yylex, $$, $<pos>1, $<end>1)
 		}
 	| '.' IDENTIFIER
 		{
@@ -258,28 +264,29 @@ primary_expr
 			// reference engine (".A" resolves identically to "A").
 			name, scope := ParseScopedIdentifier($2)
 			$$ = ast.NewAttributeReference(name, scope)
+			$<end>$ = markSpan(yylex, $$, $<pos>1, $<end>2)
 		}
 	| '(' expr ')'
-		{ $$ = ast.Parenthesize($2) }
+		{ $$ = ast.Parenthesize($2); $<end>$ = markSpan(yylex, $$, $<pos>1, $<end>3) }
 	| '{' opt_expr_list '}'
-		{ $$ = &ast.ListLiteral{Elements: $2} }
+		{ $$ = &ast.ListLiteral{Elements: $2}; $<end>$ = markSpan(yylex, $$, $<pos>1, $<end>3) }
 	| record_literal
-		{ $$ = &ast.RecordLiteral{ClassAd: $1} }
+		{ $$ = &ast.RecordLiteral{ClassAd: $1}; markSpan(yylex, $$, $<pos>1, $<end>1) }
 	;
 
 literal
 	: INTEGER_LITERAL
-		{ $$ = &ast.IntegerLiteral{Value: $1} }
+		{ $$ = &ast.IntegerLiteral{Value: $1}; markSpan(yylex, $$, $<pos>1, $<end>1) }
 	| REAL_LITERAL
-		{ $$ = &ast.RealLiteral{Value: $1} }
+		{ $$ = &ast.RealLiteral{Value: $1}; markSpan(yylex, $$, $<pos>1, $<end>1) }
 	| strings
-		{ $$ = &ast.StringLiteral{Value: $1} }
+		{ $$ = &ast.StringLiteral{Value: $1}; markSpan(yylex, $$, $<pos>1, $<end>1) }
 	| BOOLEAN_LITERAL
-		{ $$ = &ast.BooleanLiteral{Value: $1} }
+		{ $$ = &ast.BooleanLiteral{Value: $1}; markSpan(yylex, $$, $<pos>1, $<end>1) }
 	| UNDEFINED
-		{ $$ = &ast.UndefinedLiteral{} }
+		{ $$ = &ast.UndefinedLiteral{}; markSpan(yylex, $$, $<pos>1, $<end>1) }
 	| ERROR
-		{ $$ = &ast.ErrorLiteral{} }
+		{ $$ = &ast.ErrorLiteral{}; markSpan(yylex, $$, $<pos>1, $<end>1) }
 	;
 
 /* Adjacent string literals concatenate, C-style: "a" "b" is "ab". */
@@ -287,7 +294,7 @@ strings
 	: STRING_LITERAL
 		{ $$ = $1 }
 	| strings STRING_LITERAL
-		{ $$ = $1 + $2 }
+		{ $$ = $1 + $2; $<end>$ = $<end>2 }
 	;
 
 opt_expr_list
@@ -72,10 +72,11 @@ var exprParserPool = sync.Pool{
 	},
 }
 
-func (ep *exprParser) reset(input string) {
+func (ep *exprParser) reset(input string, opts Options) {
 	ep.wr.reset(input)
 	ep.br.Reset(&ep.wr)
 	ep.lex.resetForNext()
-	ep.lex.pos = 0
+	ep.lex.resetPos()
 	ep.lex.stopAfterClassAd = false
+	ep.lex.quirks = opts
 }
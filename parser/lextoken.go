@@ -47,7 +47,7 @@ func NewExprLexer(r io.Reader) *StreamingLexer {
 // pool a lexer across many small parses instead of allocating one per parse.
 func (l *StreamingLexer) ResetForExpr() {
 	l.resetForNext()
-	l.pos = 0
+	l.resetPos()
 	l.stopAfterClassAd = false
 }
 
@@ -0,0 +1,340 @@
+package parser
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/PelicanPlatform/classad/ast"
+)
+
+// Document is ClassAd source text parsed for editing. Parsing into an
+// ast.ClassAd keeps only the attributes -- and a classad.ClassAd then sorts
+// and deduplicates them -- so writing an edited ad back rewrites the whole
+// file. A Document keeps the text: comments, blank lines, attribute order and
+// each expression as it was written. String re-emits it with only what Set
+// and Delete touched changed.
+//
+//	doc, err := parser.ParseLosslessOld(text)
+//	doc.Set("Memory", "4096")
+//	doc.Delete("Scratch")
+//	os.WriteFile(path, []byte(doc.String()), 0o644)
+type Document struct {
+	src   string
+	old   bool
+	attrs []*docAttr // source order, then those Set added
+
+	// open and close are the offsets of a new-format ad's brackets.
+	open, close int
+}
+
+// docAttr is one assignment of a Document. Offsets are into Document.src:
+// the assignment is [start, end), its value [vstart, end) and Delete removes
+// [cutStart, cutEnd). An attribute Set added has none.
+type docAttr struct {
+	name             string
+	value            ast.Expr
+	text             string // the value's source text, or the text Set gave it
+	start, vstart    int
+	end              int
+	cutStart, cutEnd int
+	ownLine          bool // nothing but blanks precedes the assignment on its line
+	added            bool
+	edited, deleted  bool
+}
+
+// ParseLossless parses a new-format ClassAd ("[ a = 1; ... ]") into a
+// Document.
+func ParseLossless(src string) (*Document, error) {
+	node, pos, err := ParseWithPositions(src)
+	if err != nil {
+		return nil, err
+	}
+	ad, ok := node.(*ast.ClassAd)
+	if !ok {
+		return nil, fmt.Errorf("parsed input is not a ClassAd, got %T", node)
+	}
+	span, _ := pos.Span(ad)
+	d := &Document{src: src, open: span.Start.Offset, close: span.End.Offset - 1}
+	for _, attr := range ad.Attributes {
+		s, _ := pos.Span(attr)
+		vs, _ := pos.Span(attr.Value)
+		d.add(attr, s.Start.Offset, vs.Start.Offset, s.End.Offset)
+	}
+	for i, a := range d.attrs {
+		a.cutStart, a.cutEnd = d.cutNew(a, i == len(d.attrs)-1)
+	}
+	return d, nil
+}
+
+// ParseLosslessOld parses an old-format ClassAd (one "Name = Value" per line)
+// into a Document.
+func ParseLosslessOld(src string) (*Document, error) {
+	ad, pos, err := ParseOldWithPositions(src)
+	if err != nil {
+		return nil, err
+	}
+	d := &Document{src: src, old: true}
+	for _, attr := range ad.Attributes {
+		s, _ := pos.Span(attr)
+		vs, _ := pos.Span(attr.Value)
+		a := d.add(attr, s.Start.Offset, vs.Start.Offset, s.End.Offset)
+		// An old-format assignment has its lines to itself.
+		a.cutStart = strings.LastIndexByte(src[:a.start], '\n') + 1
+		a.cutEnd = len(src)
+		if nl := strings.IndexByte(src[a.end:], '\n'); nl >= 0 {
+			a.cutEnd = a.end + nl + 1
+		}
+	}
+	return d, nil
+}
+
+func (d *Document) add(attr *ast.AttributeAssignment, start, vstart, end int) *docAttr {
+	a := &docAttr{name: attr.Name, value: attr.Value, text: d.src[vstart:end], start: start, vstart: vstart, end: end}
+	ls := strings.LastIndexByte(d.src[:start], '\n') + 1
+	a.ownLine = strings.TrimSpace(d.src[ls:start]) == ""
+	d.attrs = append(d.attrs, a)
+	return a
+}
+
+// cutNew is the range deleting new-format assignment a removes: its whole
+// line, with the separator and a trailing // comment, when it has the line
+// to itself or shares it only with the separator before it; otherwise the
+// assignment and the separator after it -- or, for the last one, the one
+// before it on its line. A separator on another line is left alone, so no
+// line but a's changes.
+func (d *Document) cutNew(a *docAttr, last bool) (int, int) {
+	src := d.src
+	after := skipBlanks(src, a.end)
+	sep := after < len(src) && src[after] == ';'
+	if sep {
+		after = skipBlanks(src, after+1)
+	}
+	lineStart := strings.LastIndexByte(src[:a.start], '\n') + 1
+	// A separator leading the line, as in "  ; b = 2", is the assignment's own.
+	leadSep := !sep && strings.TrimSpace(src[lineStart:a.start]) == ";"
+	if a.ownLine || leadSep {
+		rest := after
+		if strings.HasPrefix(src[rest:], "//") {
+			rest += strings.IndexByte(src[rest:]+"\n", '\n')
+		}
+		if rest < len(src) && src[rest] == '\n' {
+			return lineStart, rest + 1
+		}
+	}
+	if sep || !last {
+		return a.start, after
+	}
+	// No separator follows the last assignment: take the one before, if any.
+	before := a.start
+	for before > d.open+1 && (src[before-1] == ' ' || src[before-1] == '\t') {
+		before--
+	}
+	if src[before-1] == ';' {
+		return before - 1, a.end
+	}
+	return a.start, a.end
+}
+
+func skipBlanks(s string, i int) int {
+	for i < len(s) && (s[i] == ' ' || s[i] == '\t') {
+		i++
+	}
+	return i
+}
+
+// Old reports whether d is an old-format ad.
+func (d *Document) Old() bool { return d.old }
+
+// find returns the attribute named name that is in effect -- the last
+// assignment, as for a parsed ad -- or nil.
+func (d *Document) find(name string) *docAttr {
+	for i := len(d.attrs) - 1; i >= 0; i-- {
+		if a := d.attrs[i]; !a.deleted && strings.EqualFold(a.name, name) {
+			return a
+		}
+	}
+	return nil
+}
+
+// Names returns the attribute names in document order, each once.
+func (d *Document) Names() []string {
+	var names []string
+	seen := map[string]bool{}
+	for _, a := range d.attrs {
+		if k := strings.ToLower(a.name); !a.deleted && !seen[k] {
+			seen[k] = true
+			names = append(names, a.name)
+		}
+	}
+	return names
+}
+
+// Lookup returns the expression of attribute name.
+func (d *Document) Lookup(name string) (ast.Expr, bool) {
+	if a := d.find(name); a != nil {
+		return a.value, true
+	}
+	return nil, false
+}
+
+// Text returns the source text of attribute name's expression, as written or
+// as Set gave it.
+func (d *Document) Text(name string) (string, bool) {
+	if a := d.find(name); a != nil {
+		return a.text, true
+	}
+	return "", false
+}
+
+// Set gives attribute name the expression text, kept as written. An existing
+// attribute's value is replaced in place; a new one is added after the last
+// attribute, laid out like it.
+func (d *Document) Set(name, text string) error {
+	text = strings.TrimSpace(text)
+	expr, err := ParseExpr(text)
+	if err != nil {
+		return err
+	}
+	if d.old && strings.Contains(text, "\n") {
+		text = expr.String() // an old-format attribute is one line
+	}
+	d.set(name, expr, text)
+	return nil
+}
+
+// SetExpr is Set with an expression tree, written unparsed.
+func (d *Document) SetExpr(name string, expr ast.Expr) {
+	d.set(name, expr, expr.String())
+}
+
+func (d *Document) set(name string, expr ast.Expr, text string) {
+	if a := d.find(name); a != nil {
+		a.value, a.text, a.edited = expr, text, true
+		return
+	}
+	d.attrs = append(d.attrs, &docAttr{name: name, value: expr, text: text, added: true})
+}
+
+// Delete removes every assignment of attribute name, reporting whether there
+// was one.
+func (d *Document) Delete(name string) bool {
+	found := false
+	for _, a := range d.attrs {
+		if !a.deleted && strings.EqualFold(a.name, name) {
+			a.deleted, found = true, true
+		}
+	}
+	return found
+}
+
+// ClassAd returns the ad d now describes, its attributes in document order.
+func (d *Document) ClassAd() *ast.ClassAd {
+	ad := &ast.ClassAd{Attributes: []*ast.AttributeAssignment{}}
+	for _, a := range d.attrs {
+		if !a.deleted {
+			ad.Attributes = append(ad.Attributes, &ast.AttributeAssignment{Name: a.name, Value: a.value})
+		}
+	}
+	return ad
+}
+
+// edit replaces src[start:end] with text.
+type edit struct {
+	start, end int
+	text       string
+}
+
+// String returns d's text: the source with the edits made since parsing.
+func (d *Document) String() string {
+	var edits []edit
+	var added []*docAttr
+	var last *docAttr // the last remaining source attribute
+	for _, a := range d.attrs {
+		switch {
+		case a.added && !a.deleted:
+			added = append(added, a)
+		case a.added:
+		case a.deleted:
+			edits = append(edits, edit{a.cutStart, a.cutEnd, ""})
+		default:
+			last = a
+			if a.edited {
+				edits = append(edits, edit{a.vstart, a.end, a.text})
+			}
+		}
+	}
+	if len(added) > 0 {
+		edits = append(edits, d.insert(added, last)...)
+	}
+	sort.SliceStable(edits, func(i, j int) bool { return edits[i].start < edits[j].start })
+
+	var b strings.Builder
+	cur := 0
+	for _, e := range edits {
+		start := max(e.start, cur) // a deleted neighbour's cut may have taken it
+		b.WriteString(d.src[cur:start])
+		b.WriteString(e.text)
+		cur = max(e.end, start)
+	}
+	b.WriteString(d.src[cur:])
+	return b.String()
+}
+
+// insert returns the edits adding the attributes Set added, after last.
+func (d *Document) insert(added []*docAttr, last *docAttr) []edit {
+	src := d.src
+	if d.old {
+		var b strings.Builder
+		if src != "" && !strings.HasSuffix(src, "\n") {
+			b.WriteByte('\n')
+		}
+		for _, a := range added {
+			b.WriteString(a.name + " = " + a.text + "\n")
+		}
+		return []edit{{len(src), len(src), b.String()}}
+	}
+
+	assigns := make([]string, len(added))
+	for i, a := range added {
+		assigns[i] = ast.QuoteAttributeName(a.name) + " = " + a.text
+	}
+	if last == nil {
+		body := src[d.open+1 : d.close]
+		if strings.Contains(body, "\n") {
+			var b strings.Builder
+			for _, s := range assigns {
+				b.WriteString("\n  " + s + ";")
+			}
+			return []edit{{d.open + 1, d.open + 1, b.String()}}
+		}
+		text := " " + strings.Join(assigns, "; ")
+		if !strings.HasPrefix(body, " ") {
+			text += " "
+		}
+		return []edit{{d.open + 1, d.open + 1, text}}
+	}
+	if !last.ownLine {
+		return []edit{{last.end, last.end, "; " + strings.Join(assigns, "; ")}}
+	}
+	// One assignment per line, indented like last, after the rest of its line.
+	after := skipBlanks(src, last.end)
+	sep := after < len(src) && src[after] == ';'
+	var edits []edit
+	if !sep {
+		edits = append(edits, edit{last.end, last.end, ";"})
+	}
+	eol := last.end + strings.IndexByte(src[last.end:d.close]+"\n", '\n')
+	if eol > d.close {
+		eol = d.close
+	}
+	indent := src[strings.LastIndexByte(src[:last.start], '\n')+1 : last.start]
+	var b strings.Builder
+	for i, s := range assigns {
+		b.WriteString("\n" + indent + s)
+		if sep || i < len(assigns)-1 {
+			b.WriteByte(';')
+		}
+	}
+	return append(edits, edit{eol, eol, b.String()})
+}
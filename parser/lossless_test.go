@@ -0,0 +1,195 @@
+package parser
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const losslessNew = `// execute node 17
+[
+  Name    = "slot1@node17";   // the slot
+  Memory  = 2048;
+
+  /* policy */
+  Start   = (KeyboardIdle > 15 * 60) &&
+            LoadAvg < 0.3;
+  Rank    = 0
+]
+`
+
+func TestLosslessUnchanged(t *testing.T) {
+	for _, src := range []string{losslessNew, "[]", "[ a = 1; b = { 1,2 } ]"} {
+		d, err := ParseLossless(src)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := d.String(); got != src {
+			t.Errorf("unedited document =\n%s\nwant\n%s", got, src)
+		}
+	}
+	d, _ := ParseLossless(losslessNew)
+	if got := d.Names(); !reflect.DeepEqual(got, []string{"Name", "Memory", "Start", "Rank"}) {
+		t.Errorf("Names = %v", got)
+	}
+	if text, _ := d.Text("start"); text != "(KeyboardIdle > 15 * 60) &&\n            LoadAvg < 0.3" {
+		t.Errorf("Text(start) = %q", text)
+	}
+}
+
+func TestLosslessEditNew(t *testing.T) {
+	d, err := ParseLossless(losslessNew)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Set("memory", "4096"); err != nil {
+		t.Fatal(err)
+	}
+	if !d.Delete("Name") || d.Delete("Nope") {
+		t.Error("Delete reported wrongly")
+	}
+	d.Set("Cpus", "4 * 2")
+	d.Set("Disk", `"100 GB"`)
+	want := `// execute node 17
+[
+  Memory  = 4096;
+
+  /* policy */
+  Start   = (KeyboardIdle > 15 * 60) &&
+            LoadAvg < 0.3;
+  Rank    = 0;
+  Cpus = 4 * 2;
+  Disk = "100 GB"
+]
+`
+	if got := d.String(); got != want {
+		t.Errorf("edited document =\n%s\nwant\n%s", got, want)
+	}
+	if _, err := ParseLossless(d.String()); err != nil {
+		t.Errorf("edited document does not parse: %v", err)
+	}
+	ad := d.ClassAd()
+	if got := ad.String(); got != `[Memory = 4096; Start = ((KeyboardIdle > (15 * 60)) && (LoadAvg < 0.3)); Rank = 0; Cpus = (4 * 2); Disk = "100 GB"]` {
+		t.Errorf("ClassAd = %s", got)
+	}
+}
+
+func TestLosslessInline(t *testing.T) {
+	tests := []struct {
+		src  string
+		edit func(d *Document)
+		want string
+	}{
+		{"[a = 1; b = 2; c = 3]", func(d *Document) { d.Delete("b") }, "[a = 1; c = 3]"},
+		{"[a = 1; b = 2; c = 3]", func(d *Document) { d.Delete("c") }, "[a = 1; b = 2]"},
+		{"[a = 1; b = 2; c = 3;]", func(d *Document) { d.Delete("c") }, "[a = 1; b = 2; ]"},
+		{"[a = 1; b = 2]", func(d *Document) { d.Delete("a"); d.Delete("b") }, "[]"},
+		{"[a = 1]", func(d *Document) { d.Set("b", "a+1") }, "[a = 1; b = a+1]"},
+		{"[a = 1; b = 2]", func(d *Document) { d.Delete("b"); d.Set("c", "3") }, "[a = 1; c = 3]"},
+		{"[]", func(d *Document) { d.Set("x", "1"); d.Set("y", "2") }, "[ x = 1; y = 2 ]"},
+		{"[\n]", func(d *Document) { d.Set("x", "1") }, "[\n  x = 1;\n]"},
+		{"[\n  a = 1\n]", func(d *Document) { d.Set("b", "2") }, "[\n  a = 1;\n  b = 2\n]"},
+		{"[a = 1; A = 2]", func(d *Document) { d.Set("a", "3") }, "[a = 1; A = 3]"},
+		{"[x = 1]", func(d *Document) { d.Set("my attr", `"v"`) }, "[x = 1; 'my attr' = \"v\"]"},
+	}
+	for _, tt := range tests {
+		d, err := ParseLossless(tt.src)
+		if err != nil {
+			t.Fatalf("ParseLossless(%q): %v", tt.src, err)
+		}
+		tt.edit(d)
+		got := d.String()
+		if got != tt.want {
+			t.Errorf("%q edited = %q, want %q", tt.src, got, tt.want)
+		}
+		if _, err := ParseClassAd(got); err != nil {
+			t.Errorf("%q edited to %q, which does not parse: %v", tt.src, got, err)
+		}
+	}
+}
+
+// TestLosslessDeleteLast deletes the last attribute of ads laid out one
+// attribute per line: only its own line may go, every other line stays
+// byte for byte.
+func TestLosslessDeleteLast(t *testing.T) {
+	for _, src := range []string{
+		"[\n  a = 1;\n  b = 2;\n  c = 3\n]",
+		"[\n  a = 1;\n  b = 2;\n  c = 3;\n]",
+		"[\n  a = 1; // one\n  b = 2; // two\n  c = 3 // three\n]",
+		"[\n  a = 1\n  ; b = 2\n  ; c = 3\n]",
+	} {
+		d, err := ParseLossless(src)
+		if err != nil {
+			t.Fatalf("ParseLossless(%q): %v", src, err)
+		}
+		d.Delete("c")
+		lines := strings.Split(src, "\n")
+		want := strings.Join(append(lines[:3:3], lines[4:]...), "\n")
+		if got := d.String(); got != want {
+			t.Errorf("Delete(c) of %q = %q, want %q", src, got, want)
+		}
+	}
+}
+
+func TestLosslessOld(t *testing.T) {
+	src := `// startd ad
+MyType = "Machine"
+Name = "slot1@node17"
+  Memory   =  2048
+Start = KeyboardIdle > 900 && LoadAvg < 0.3
+
+OSIssue = "\S"
+Rank = 0`
+	d, err := ParseLosslessOld(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !d.Old() || d.String() != src {
+		t.Fatalf("unedited old document = %q", d.String())
+	}
+	if text, _ := d.Text("Start"); text != "KeyboardIdle > 900 && LoadAvg < 0.3" {
+		t.Errorf("Text(Start) = %q", text)
+	}
+	if text, _ := d.Text("OSIssue"); text != `"\S"` {
+		t.Errorf("Text(OSIssue) = %q", text)
+	}
+
+	d.Set("Memory", "4096")
+	d.Delete("Start")
+	d.Set("Cpus", "8")
+	d.Set("Rank", "Memory\n  / 1024")
+	want := `// startd ad
+MyType = "Machine"
+Name = "slot1@node17"
+  Memory   =  4096
+
+OSIssue = "\S"
+Rank = (Memory / 1024)
+Cpus = 8
+`
+	if got := d.String(); got != want {
+		t.Errorf("edited old document =\n%s\nwant\n%s", got, want)
+	}
+	if _, err := ParseOldClassAd(d.String()); err != nil {
+		t.Errorf("edited old document does not parse: %v", err)
+	}
+}
+
+func TestLosslessErrors(t *testing.T) {
+	if _, err := ParseLossless("[a = ]"); err == nil {
+		t.Error("ParseLossless accepted a syntax error")
+	}
+	_, err := ParseLosslessOld("A = 1\nB = (2")
+	var se *SyntaxError
+	if err == nil || !strings.Contains(err.Error(), "error parsing old ClassAd format") || !errors.As(err, &se) || se.Line != 2 {
+		t.Errorf("ParseLosslessOld error = %v", err)
+	}
+	d, _ := ParseLossless("[a = 1]")
+	if err := d.Set("b", "1 +"); err == nil {
+		t.Error("Set accepted a malformed expression")
+	}
+	if d.String() != "[a = 1]" {
+		t.Errorf("a failed Set changed the document: %s", d)
+	}
+}
@@ -3,6 +3,7 @@ package parser
 import (
 	"fmt"
 	"strings"
+	"unicode/utf8"
 
 	"github.com/PelicanPlatform/classad/ast"
 )
@@ -18,6 +19,12 @@ import (
 // This implementation converts the old format to new format and reuses
 // the existing parser.
 func ParseOldClassAd(input string) (*ast.ClassAd, error) {
+	return ParseOldClassAdWithOptions(input, Options{})
+}
+
+// ParseOldClassAdWithOptions is ParseOldClassAd with the lexer leniencies of
+// opts.
+func ParseOldClassAdWithOptions(input string, opts Options) (*ast.ClassAd, error) {
 	// Convert old format to new format
 	newFormat := convertOldToNewFormat(input)
 
@@ -25,8 +32,13 @@ func ParseOldClassAd(input string) (*ast.ClassAd, error) {
 	// "\S", the agetty escapes /etc/issue carries) is kept literally rather than rejected,
 	// matching the C++ old-ClassAd tokenizer. Otherwise one such attribute would fail the
 	// whole ad -- which silently drops every startd ad a collector forwards.
-	result, err := parsePooled(newFormat, true)
+	result, err := parsePooled(newFormat, true, opts)
 	if err != nil {
+		if se, ok := err.(*SyntaxError); ok {
+			var lines []oldLineMap
+			convertOld(input, &lines)
+			err = mapOldSyntaxError(se, input, lines)
+		}
 		return nil, fmt.Errorf("error parsing old ClassAd format: %w", err)
 	}
 
@@ -52,18 +64,39 @@ func ParseOldClassAd(input string) (*ast.ClassAd, error) {
 //	Bar = "hello"
 //	]
 func convertOldToNewFormat(input string) string {
+	return convertOld(input, nil)
+}
+
+// oldLineMap records where a line of the converted text came from: its 0-based
+// input line and the rune shift from an output column back to an input column.
+type oldLineMap struct {
+	line  int
+	shift int
+}
+
+// convertOld is convertOldToNewFormat that, given a non-nil mapping, also maps
+// each output line back to the input, so a syntax error in the converted text
+// can be reported against the caller's source. (Only the error path asks.)
+func convertOld(input string, mapping *[]oldLineMap) string {
 	var result strings.Builder
 	result.WriteString("[\n")
 
 	lines := strings.Split(input, "\n")
+	note := func(m oldLineMap) {
+		if mapping != nil {
+			*mapping = append(*mapping, m)
+		}
+	}
+	note(oldLineMap{line: 0})
 	inBlockComment := false
 
-	for _, line := range lines {
+	for i, line := range lines {
 		// Handle block comments
 		if strings.Contains(line, "/*") {
 			inBlockComment = true
 		}
 		if inBlockComment {
+			note(oldLineMap{line: i})
 			result.WriteString(line)
 			result.WriteString("\n")
 			if strings.Contains(line, "*/") {
@@ -81,6 +114,7 @@ func convertOldToNewFormat(input string) string {
 
 		// Skip comment-only lines
 		if strings.HasPrefix(trimmed, "//") || strings.HasPrefix(trimmed, "#") {
+			note(oldLineMap{line: i})
 			result.WriteString(line)
 			result.WriteString("\n")
 			continue
@@ -97,10 +131,18 @@ func convertOldToNewFormat(input string) string {
 			// parser, which stores the literal name). A bare name is returned unchanged, so
 			// this is byte-identical for the common case.
 			stmt := trimmed
-			if eq := strings.IndexByte(trimmed, '='); eq > 0 {
+			eq := strings.IndexByte(trimmed, '=')
+			if eq > 0 {
 				name := strings.TrimSpace(trimmed[:eq])
 				stmt = ast.QuoteAttributeName(name) + " " + trimmed[eq:]
 			}
+			if mapping != nil {
+				// Columns from '=' on are shifted by the rewritten name and the
+				// dropped indentation.
+				lead := strings.Index(line, trimmed)
+				eqOut := strings.IndexByte(stmt, '=')
+				note(oldLineMap{line: i, shift: utf8.RuneCountInString(line[:lead+eq]) - utf8.RuneCountInString(stmt[:eqOut])})
+			}
 			// Add the line with a semicolon if it doesn't already have one
 			if !strings.HasSuffix(stmt, ";") {
 				result.WriteString(stmt)
@@ -111,11 +153,66 @@ func convertOldToNewFormat(input string) string {
 			}
 		} else {
 			// Non-assignment line, keep as is (might be part of multi-line expression)
+			note(oldLineMap{line: i})
 			result.WriteString(line)
 			result.WriteString("\n")
 		}
 	}
 
 	result.WriteString("]")
+	note(oldLineMap{line: len(lines) - 1, shift: utf8.RuneCountInString(lines[len(lines)-1])})
 	return result.String()
 }
+
+// mapOldSyntaxError re-expresses a syntax error in the converted text in terms
+// of the old-format input. A ";" or "]" that the conversion added is rejected
+// as the end of the expression.
+func mapOldSyntaxError(se *SyntaxError, input string, mapping []oldLineMap) *SyntaxError {
+	idx := se.Line - 1
+	if idx < 0 || idx >= len(mapping) {
+		return se
+	}
+	m := mapping[idx]
+	x := newPositionIndex(input)
+	off, lineStart, lineEnd := x.oldOffset(m, se.Col)
+	out := *se
+	out.Line = m.line + 1
+	out.Col = max(se.Col+m.shift, 1)
+	out.Offset = off
+	out.lineText = input[lineStart:lineEnd]
+	switch {
+	case se.Got == "":
+		// A lexical error: what its message cites moves with it.
+		cx := newPositionIndex(convertOldToNewFormat(input))
+		out.recite(func(at int) int {
+			p := cx.pos(at)
+			if p.Line > len(mapping) {
+				return at
+			}
+			off, _, _ := x.oldOffset(mapping[p.Line-1], p.Col)
+			return off
+		})
+	case (se.Got == ";" || se.Got == "]") && strings.TrimSpace(input[off:lineEnd]) == "":
+		return endOfExpr(out.Line, out.Col, out.Offset, se.Expected, out.lineText)
+	}
+	return &out
+}
+
+// oldOffset maps column col of a converted-text line that m describes to a
+// byte offset in x's old-format input, returning also the extent of the input
+// line, without its newline.
+func (x positionIndex) oldOffset(m oldLineMap, col int) (off, lineStart, lineEnd int) {
+	lineStart = x.lineStarts[m.line]
+	lineEnd = len(x.src)
+	if m.line+1 < len(x.lineStarts) {
+		lineEnd = x.lineStarts[m.line+1] - 1
+	}
+	col = max(col+m.shift, 1)
+	// Walk col-1 runes into the line for the byte offset, stopping at its end.
+	off = lineStart
+	for n := 1; n < col && off < lineEnd; n++ {
+		_, size := utf8.DecodeRuneInString(x.src[off:])
+		off += size
+	}
+	return off, lineStart, lineEnd
+}
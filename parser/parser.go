@@ -14,7 +14,32 @@ import (
 // returns its AST. It does not accept a bare expression; to parse a standalone
 // expression such as "a + 1" or "{1, 2}", use ParseExpr.
 func Parse(input string) (ast.Node, error) {
-	return parsePooled(input, false)
+	return parsePooled(input, false, Options{})
+}
+
+// Options enables leniencies of the reference (libclassad) lexer that the
+// parser rejects by default. The zero Options is the default grammar.
+type Options struct {
+	// WrapIntOverflow reads a decimal integer literal too large for int64 as 0,
+	// as libclassad does, instead of rejecting it. The magnitude of INT64_MIN
+	// after a '-' still folds to INT64_MIN.
+	WrapIntOverflow bool
+	// LenientDoubleDot reads a number with a doubled leading dot ("..5") as the
+	// real 0.0, as libclassad's strtod-based scanner does, instead of rejecting
+	// it.
+	LenientDoubleDot bool
+}
+
+// ParseClassAdWithOptions is ParseClassAd with the lexer leniencies of opts.
+func ParseClassAdWithOptions(input string, opts Options) (*ast.ClassAd, error) {
+	node, err := parsePooled(input, false, opts)
+	if err != nil {
+		return nil, err
+	}
+	if classad, ok := node.(*ast.ClassAd); ok {
+		return classad, nil
+	}
+	return nil, fmt.Errorf("parsed input is not a ClassAd, got %T", node)
 }
 
 // ParseClassAd parses a ClassAd and returns a ClassAd AST node. It returns an
@@ -40,18 +65,28 @@ func ParseClassAd(input string) (*ast.ClassAd, error) {
 // a record literal and a bare expression -- while still giving callers direct
 // expression access.
 func ParseExpr(input string) (ast.Expr, error) {
+	return ParseExprWithOptions(input, Options{})
+}
+
+// ParseExprWithOptions is ParseExpr with the lexer leniencies of opts.
+func ParseExprWithOptions(input string, opts Options) (ast.Expr, error) {
 	ep, ok := exprParserPool.Get().(*exprParser)
 	if !ok {
 		panic("exprParserPool held an unexpected type") // pool's New only makes *exprParser
 	}
-	ep.reset(input)
+	ep.reset(input, opts)
 	ep.p.Parse(ep.lex)
 	node, err := ep.lex.Result()
 	ep.lex.result = nil // do not retain the parsed AST in the pooled instance
 	exprParserPool.Put(ep)
 	if err != nil {
-		return nil, err
+		return nil, exprError(err, input)
 	}
+	return unwrapExpr(node)
+}
+
+// unwrapExpr returns the expression of the single-attribute wrapper record.
+func unwrapExpr(node ast.Node) (ast.Expr, error) {
 	ad, ok := node.(*ast.ClassAd)
 	if !ok || len(ad.Attributes) != 1 {
 		return nil, fmt.Errorf("input is not a single expression")
@@ -59,6 +94,46 @@ func ParseExpr(input string) (ast.Expr, error) {
 	return ad.Attributes[0].Value, nil
 }
 
+// exprError maps a syntax error in the wrapped expression back onto input: the
+// position, and an offset a lexical error's message cites, lose the wrapper
+// prefix, a token rejected in the wrapper's closing bracket is the end of the
+// expression, and the wrapper's own continuations ("]" or ";" after a complete
+// expression) mean end of input.
+func exprError(err error, input string) error {
+	se, ok := err.(*SyntaxError)
+	if !ok {
+		return err
+	}
+	out := *se
+	offset := se.Offset - len(exprWrapPrefix)
+	p := newPositionIndex(input).pos(offset)
+	out.Line, out.Col, out.Offset = p.Line, p.Col, p.Offset
+	if se.Line == 1 {
+		out.lineText = strings.TrimPrefix(out.lineText, exprWrapPrefix)
+	}
+	if offset >= len(input) {
+		out.lineText = strings.TrimSuffix(out.lineText, exprWrapSuffix)
+	}
+	if se.Got == "" {
+		// A lexical error: what its message cites moves with it.
+		out.recite(func(at int) int { return at - len(exprWrapPrefix) })
+		return &out
+	}
+	got, expected := se.Got, se.Expected
+	switch {
+	case se.Got == "EOF":
+		// The wrapper's bracket was taken to close an unterminated "[" of the
+		// input, which is what is really missing.
+		expected = []string{"]"}
+	case offset >= len(input):
+		// The wrapper's bracket: the expression ended early.
+		return endOfExpr(out.Line, out.Col, out.Offset, expected, out.lineText)
+	case len(expected) == 2 && expected[0] == "]" && expected[1] == ";":
+		expected = []string{"EOF"}
+	}
+	return newSyntaxError(out.Line, out.Col, out.Offset, got, expected, out.lineText)
+}
+
 // ReaderParser parses consecutive ClassAds from a buffered reader without
 // requiring delimiters between ads. It reuses a single streaming lexer instance
 // for efficiency.
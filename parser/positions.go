@@ -0,0 +1,181 @@
+package parser
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/PelicanPlatform/classad/ast"
+)
+
+// nodeSpan is a node's byte extent [start, end) in the lexer's input, as
+// recorded by the grammar actions.
+type nodeSpan struct {
+	node       ast.Node
+	start, end int
+}
+
+// markSpan records n's source extent when the lexer is recording positions and
+// returns end, so an action can extend its symbol's extent in the same
+// statement. It is called for every node the grammar builds; with recording
+// off it costs a type switch and a branch.
+func markSpan(yylex yyLexer, n ast.Node, start, end int) int {
+	var l *StreamingLexer
+	switch x := yylex.(type) {
+	case *StreamingLexer:
+		l = x
+	case *Lexer:
+		l = x.lex
+	}
+	if l != nil && l.recordSpans {
+		l.spans = append(l.spans, nodeSpan{node: n, start: start, end: end})
+	}
+	return end
+}
+
+// ParseWithPositions is Parse that also returns the source span of every node
+// of the tree. Recording positions costs a table entry per node, so the plain
+// entry points do not.
+func ParseWithPositions(input string) (ast.Node, ast.Positions, error) {
+	return parseWithPositions(input, false)
+}
+
+// parseWithPositions is ParseWithPositions with the old-ClassAd string
+// semantics selectable, as for parsePooled.
+func parseWithPositions(input string, lenientEscapes bool) (ast.Node, ast.Positions, error) {
+	return parseWithPositionsIn(input, lenientEscapes, newPositionIndex(input), 0)
+}
+
+// parseWithPositionsIn parses input, which starts base bytes into the text x
+// indexes, and reports its spans in terms of that text. A syntax error keeps
+// its position in input.
+func parseWithPositionsIn(input string, lenientEscapes bool, x positionIndex, base int) (ast.Node, ast.Positions, error) {
+	ap, ok := adParserPool.Get().(*adParser)
+	if !ok {
+		panic("adParserPool held an unexpected type") // pool's New only makes *adParser
+	}
+	ap.reset(input, lenientEscapes, Options{})
+	ap.slx.recordSpans = true
+	ap.p.Parse(&ap.lex)
+	node, err := ap.lex.Result()
+	var pos ast.Positions
+	if err == nil {
+		pos = x.positions(ap.slx.spans, -base)
+	}
+	ap.slx.recordSpans = false
+	ap.slx.spans = ap.slx.spans[:0]
+	ap.slx.result = nil
+	ap.lex.result = nil
+	ap.lex.input = ""
+	ap.sr.Reset("")
+	adParserPool.Put(ap)
+	if err != nil {
+		return nil, nil, err
+	}
+	return node, pos, nil
+}
+
+// ParseOldWithPositions is ParseOldClassAd that also returns the source span
+// of every node, in terms of the old-format input.
+func ParseOldWithPositions(input string) (*ast.ClassAd, ast.Positions, error) {
+	var lines []oldLineMap
+	node, pos, err := parseWithPositions(convertOld(input, &lines), true)
+	if err != nil {
+		if se, ok := err.(*SyntaxError); ok {
+			err = mapOldSyntaxError(se, input, lines)
+		}
+		return nil, nil, fmt.Errorf("error parsing old ClassAd format: %w", err)
+	}
+	ad, ok := node.(*ast.ClassAd)
+	if !ok {
+		return nil, nil, fmt.Errorf("expected ClassAd, got %T", node)
+	}
+	// The conversion rewrites an assignment's line up to its '=', so only
+	// columns from there on map back by the line's shift; an assignment starts
+	// at its line's first non-blank.
+	x := newPositionIndex(input)
+	at := func(p ast.Pos) ast.Pos {
+		off, _, _ := x.oldOffset(lines[p.Line-1], p.Col)
+		return x.pos(off)
+	}
+	for n, s := range pos {
+		s.Start, s.End = at(s.Start), at(s.End)
+		if _, ok := n.(*ast.AttributeAssignment); ok {
+			line := x.lineStarts[s.Start.Line-1]
+			s.Start = x.pos(line + len(input[line:]) - len(strings.TrimLeft(input[line:], " \t")))
+		}
+		pos[n] = s
+	}
+	return ad, pos, nil
+}
+
+// ParseExprWithPositions is ParseExpr that also returns the source span of
+// every node of the expression, relative to input.
+func ParseExprWithPositions(input string) (ast.Expr, ast.Positions, error) {
+	ep, ok := exprParserPool.Get().(*exprParser)
+	if !ok {
+		panic("exprParserPool held an unexpected type") // pool's New only makes *exprParser
+	}
+	ep.reset(input, Options{})
+	ep.lex.recordSpans = true
+	ep.p.Parse(ep.lex)
+	node, err := ep.lex.Result()
+	var spans []nodeSpan
+	if err == nil {
+		spans = append(spans, ep.lex.spans...)
+	}
+	ep.lex.recordSpans = false
+	ep.lex.spans = ep.lex.spans[:0]
+	ep.lex.result = nil
+	exprParserPool.Put(ep)
+	if err != nil {
+		return nil, nil, exprError(err, input)
+	}
+	expr, err := unwrapExpr(node)
+	if err != nil {
+		return nil, nil, err
+	}
+	// Drop the wrapper's own record and assignment; shift the rest back onto input.
+	inner := spans[:0]
+	for _, s := range spans {
+		if s.start >= len(exprWrapPrefix) && s.end <= len(exprWrapPrefix)+len(input) {
+			inner = append(inner, s)
+		}
+	}
+	return expr, newPositionIndex(input).positions(inner, len(exprWrapPrefix)), nil
+}
+
+// positionIndex converts byte offsets in a source text to line/column
+// positions.
+type positionIndex struct {
+	src        string
+	lineStarts []int
+}
+
+func newPositionIndex(src string) positionIndex {
+	starts := []int{0}
+	for i := 0; i < len(src); i++ {
+		if src[i] == '\n' {
+			starts = append(starts, i+1)
+		}
+	}
+	return positionIndex{src: src, lineStarts: starts}
+}
+
+func (x positionIndex) pos(offset int) ast.Pos {
+	offset = min(max(offset, 0), len(x.src))
+	line := sort.Search(len(x.lineStarts), func(i int) bool { return x.lineStarts[i] > offset }) - 1
+	col := utf8.RuneCountInString(x.src[x.lineStarts[line]:offset]) + 1
+	return ast.Pos{Line: line + 1, Col: col, Offset: offset}
+}
+
+// positions builds the table for spans whose offsets are base bytes past the
+// start of x.src.
+func (x positionIndex) positions(spans []nodeSpan, base int) ast.Positions {
+	out := make(ast.Positions, len(spans))
+	for _, s := range spans {
+		out[s.node] = ast.Span{Start: x.pos(s.start - base), End: x.pos(s.end - base)}
+	}
+	return out
+}
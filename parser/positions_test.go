@@ -0,0 +1,236 @@
+package parser
+
+import (
+	"bufio"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/PelicanPlatform/classad/ast"
+)
+
+func TestParseExprWithPositions(t *testing.T) {
+	src := "a +\n  f(b, \"x\")[0]"
+	expr, pos, err := ParseExprWithPositions(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sum := expr.(*ast.BinaryOp)
+	sub := sum.Right.(*ast.SubscriptExpr)
+	call := sub.Container.(*ast.FunctionCall)
+	tests := []struct {
+		node ast.Node
+		text string
+		want ast.Span
+	}{
+		{sum, src, ast.Span{Start: ast.Pos{Line: 1, Col: 1, Offset: 0}, End: ast.Pos{Line: 2, Col: 15, Offset: 18}}},
+		{sum.Left, "a", ast.Span{Start: ast.Pos{Line: 1, Col: 1, Offset: 0}, End: ast.Pos{Line: 1, Col: 2, Offset: 1}}},
+		{sub, `f(b, "x")[0]`, ast.Span{Start: ast.Pos{Line: 2, Col: 3, Offset: 6}, End: ast.Pos{Line: 2, Col: 15, Offset: 18}}},
+		{call, `f(b, "x")`, ast.Span{Start: ast.Pos{Line: 2, Col: 3, Offset: 6}, End: ast.Pos{Line: 2, Col: 12, Offset: 15}}},
+		{call.Args[1], `"x"`, ast.Span{Start: ast.Pos{Line: 2, Col: 8, Offset: 11}, End: ast.Pos{Line: 2, Col: 11, Offset: 14}}},
+		{sub.Index, "0", ast.Span{Start: ast.Pos{Line: 2, Col: 13, Offset: 16}, End: ast.Pos{Line: 2, Col: 14, Offset: 17}}},
+	}
+	for _, tt := range tests {
+		got, ok := pos.Span(tt.node)
+		if !ok {
+			t.Errorf("no span for %s", tt.text)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("span of %s = %v, want %v", tt.text, got, tt.want)
+		}
+		if text := src[got.Start.Offset:got.End.Offset]; text != tt.text {
+			t.Errorf("span text = %q, want %q", text, tt.text)
+		}
+	}
+	if len(pos) != 7 { // the six above and b
+		t.Errorf("got %d spans, want 7 (wrapper nodes must not leak)", len(pos))
+	}
+}
+
+func TestParseWithPositions(t *testing.T) {
+	src := "[\n  Name = \"ünï\";\n  Cpus = (1 + 2) * 3;\n  Inner = [x = {1, 2}]\n]"
+	node, pos, err := ParseWithPositions(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ad := node.(*ast.ClassAd)
+	span := func(n ast.Node) string {
+		s, ok := pos.Span(n)
+		if !ok {
+			t.Fatalf("no span for %v", n)
+		}
+		return src[s.Start.Offset:s.End.Offset]
+	}
+	if got := span(ad); got != src {
+		t.Errorf("ad span = %q", got)
+	}
+	if got := span(ad.Attributes[1]); got != "Cpus = (1 + 2) * 3" {
+		t.Errorf("assignment span = %q", got)
+	}
+	mul := ad.Attributes[1].Value.(*ast.BinaryOp)
+	if got := span(mul.Left); got != "(1 + 2)" {
+		t.Errorf("paren span = %q", got)
+	}
+	if got := span(ad.Attributes[2].Value); got != "[x = {1, 2}]" {
+		t.Errorf("record span = %q", got)
+	}
+	// Columns count runes, offsets bytes: each two-byte letter is one column.
+	if s, _ := pos.Span(ad.Attributes[0].Value); s.End.Col != 15 || s.End.Offset != 18 {
+		t.Errorf("string literal end = %+v, want col 15, offset 18", s.End)
+	}
+
+	// The plain entry point records nothing and parses the same tree.
+	if plain, err := Parse(src); err != nil || plain.String() != node.String() {
+		t.Errorf("Parse = %v, %v", plain, err)
+	}
+}
+
+func TestSyntaxError(t *testing.T) {
+	tests := []struct {
+		name     string
+		parse    func(string) error
+		src      string
+		line     int
+		col      int
+		offset   int
+		got      string
+		expected []string
+	}{
+		{"missing operand", parseErr, "[a = 1 +]", 1, 9, 8, "]", nil},
+		{"missing separator", parseErr, "[a = 1;\n b = 2 2]", 2, 8, 15, "2", []string{"]", ";"}},
+		{"unclosed paren", parseErr, "[a = (1]", 1, 8, 7, "]", []string{")"}},
+		{"expr at end", parseExprErr, "2 +", 1, 4, 3, "EOF", nil},
+		{"expr trailing token", parseExprErr, "1 2", 1, 3, 2, "2", []string{"EOF"}},
+		{"expr unclosed subscript", parseExprErr, "a[1", 1, 4, 3, "EOF", []string{"]"}},
+		{"lexical", parseErr, `[a = "foo`, 1, 9, 8, "", nil},
+		{"old format", parseOldErr, "A = 1\n\n   B = 2 3\n", 3, 10, 16, "3", nil},
+		{"reader second ad", parseReaderErr, "[a = 1]\n[b = 2\n c = 3]", 3, 2, 16, "c", []string{"]", ";"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.parse(tt.src)
+			var se *SyntaxError
+			if !errors.As(err, &se) {
+				t.Fatalf("error %v (%T) is not a *SyntaxError", err, err)
+			}
+			if se.Line != tt.line || se.Col != tt.col || se.Offset != tt.offset {
+				t.Errorf("position = %d:%d@%d, want %d:%d@%d", se.Line, se.Col, se.Offset, tt.line, tt.col, tt.offset)
+			}
+			if se.Got != tt.got {
+				t.Errorf("Got = %q, want %q", se.Got, tt.got)
+			}
+			if tt.expected != nil && !reflect.DeepEqual(se.Expected, tt.expected) {
+				t.Errorf("Expected = %q, want %q", se.Expected, tt.expected)
+			}
+			if !strings.Contains(err.Error(), "^") {
+				t.Errorf("Error() has no caret line: %q", err.Error())
+			}
+		})
+	}
+
+	// A missing operand expects the start of an expression.
+	var se *SyntaxError
+	errors.As(parseErr("[a = 1 +]"), &se)
+	for _, want := range []string{"identifier", "integer", "(", "{", "!"} {
+		if !contains(se.Expected, want) {
+			t.Errorf("Expected %q lacks %q", se.Expected, want)
+		}
+	}
+}
+
+// TestSyntaxErrorMessage checks that the wrappers ParseExpr and
+// ParseOldClassAd parse in leave no trace in the message.
+func TestSyntaxErrorMessage(t *testing.T) {
+	tests := []struct {
+		parse func(string) error
+		src   string
+		msg   string
+	}{
+		{parseExprErr, `"abc`, "unterminated string starting at byte 0"},
+		{parseExprErr, `1 + 'a b`, "unterminated quoted attribute name starting at byte 4"},
+		{parseExprErr, `x + "a\q"`, `invalid escape sequence \q at position 6`},
+		{parseExprErr, "2 +", "syntax error: unexpected end of expression"},
+		{parseOldErr, "A = 1\nB = (\nC = 2", "syntax error: unexpected end of expression"},
+		{parseOldErr, "A = 1\nB = 1 +", "syntax error: unexpected end of expression"},
+		{parseOldErr, "A = 1\n  B = (;", `syntax error: unexpected ";"`},
+		{parseOldErr, "A = 1\n  B = \"abc", "unterminated string starting at byte 12"},
+	}
+	for _, tt := range tests {
+		var se *SyntaxError
+		if !errors.As(tt.parse(tt.src), &se) {
+			t.Errorf("%q: no *SyntaxError", tt.src)
+		} else if se.Msg != tt.msg {
+			t.Errorf("%q: Msg = %q, want %q", tt.src, se.Msg, tt.msg)
+		}
+	}
+}
+
+func parseErr(src string) error {
+	_, err := Parse(src)
+	return err
+}
+
+func parseExprErr(src string) error {
+	_, err := ParseExpr(src)
+	return err
+}
+
+func parseOldErr(src string) error {
+	_, err := ParseOldClassAd(src)
+	return err
+}
+
+func parseReaderErr(src string) error {
+	rp := NewReaderParser(bufio.NewReader(strings.NewReader(src)))
+	for {
+		if _, err := rp.ParseClassAd(); err != nil {
+			return err
+		}
+	}
+}
+
+func contains(list []string, s string) bool {
+	for _, x := range list {
+		if x == s {
+			return true
+		}
+	}
+	return false
+}
+
+func TestParseOldWithPositions(t *testing.T) {
+	src := "MyType = \"Machine\"\n\n  'odd:name'   =  Cpus * 2\nCpus=4"
+	ad, pos, err := ParseOldWithPositions(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		node ast.Node
+		want string
+		line int
+	}{
+		{ad.Attributes[0], `MyType = "Machine"`, 1},
+		{ad.Attributes[1], "'odd:name'   =  Cpus * 2", 3},
+		{ad.Attributes[1].Value, "Cpus * 2", 3},
+		{ad.Attributes[1].Value.(*ast.BinaryOp).Left, "Cpus", 3},
+		{ad.Attributes[2].Value, "4", 4},
+	}
+	for _, tt := range tests {
+		s, ok := pos.Span(tt.node)
+		if !ok {
+			t.Errorf("no span for %s", tt.want)
+			continue
+		}
+		if got := src[s.Start.Offset:s.End.Offset]; got != tt.want || s.Start.Line != tt.line {
+			t.Errorf("span %s = %q, want %q on line %d", s, got, tt.want, tt.line)
+		}
+	}
+
+	_, _, err = ParseOldWithPositions("A = 1\nB = (2")
+	var se *SyntaxError
+	if !errors.As(err, &se) || se.Line != 2 {
+		t.Errorf("error = %v, want a syntax error on line 2", err)
+	}
+}
@@ -0,0 +1,332 @@
+package parser
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/PelicanPlatform/classad/ast"
+)
+
+// ErrorList is the syntax errors of a recovering parse, in source order.
+type ErrorList []*SyntaxError
+
+// Error describes the first error and counts the rest.
+func (l ErrorList) Error() string {
+	switch len(l) {
+	case 0:
+		return "no errors"
+	case 1:
+		return l[0].Error()
+	case 2:
+		return fmt.Sprintf("%s\n(and 1 more error)", l[0])
+	}
+	return fmt.Sprintf("%s\n(and %d more errors)", l[0], len(l)-1)
+}
+
+// Err returns l as an error, or nil if it is empty.
+func (l ErrorList) Err() error {
+	if len(l) == 0 {
+		return nil
+	}
+	return l
+}
+
+// ParseRecovering is ParseWithPositions for source that may hold several
+// mistakes. Where Parse stops at the first syntax error, ParseRecovering
+// skips to the ';' or ']' that ends the attribute holding it and carries on,
+// so it returns every attribute it could parse, with their spans, and every
+// error. The ad is never nil; without errors it is what ParseClassAd returns.
+//
+// An attribute with an error in it is left out. The ';' and ']' of a nested
+// record do not end an attribute, so a mistake inside one loses the whole
+// attribute holding the record.
+func ParseRecovering(input string) (*ast.ClassAd, ast.Positions, ErrorList) {
+	node, pos, err := ParseWithPositions(input)
+	if ad, ok := node.(*ast.ClassAd); ok && err == nil {
+		return ad, pos, nil
+	}
+	if err == nil {
+		err = fmt.Errorf("parsed input is not a ClassAd, got %T", node)
+	}
+	r := &recovery{input: input, x: newPositionIndex(input), ad: &ast.ClassAd{Attributes: []*ast.AttributeAssignment{}}, pos: ast.Positions{}}
+	open := skipTrivia(input, 0)
+	if open == len(input) || input[open] != '[' {
+		return r.ad, r.pos, ErrorList{asSyntaxError(err)}
+	}
+
+	start := open // the '[' or ';' before the attribute
+	end := len(input)
+	for i := open + 1; ; {
+		end = attributeEnd(input, i)
+		r.attribute(start, end)
+		if end == len(input) || input[end] == ']' {
+			break
+		}
+		start, i = end, end+1
+	}
+	switch {
+	case end == len(input):
+		if n := len(r.errs); n == 0 || r.errs[n-1].Offset != len(input) {
+			r.errs = append(r.errs, r.newError(len(input), "", []string{"]", ";"}))
+		}
+	default:
+		if k := skipTrivia(input, end+1); k < len(input) {
+			got, _, _ := strings.Cut(strings.Fields(input[k:])[0], "\n")
+			r.errs = append(r.errs, r.newError(k, got, []string{"EOF"}))
+		}
+		end++
+	}
+	r.pos[r.ad] = ast.Span{Start: r.x.pos(open), End: r.x.pos(end)}
+	if len(r.errs) == 0 {
+		r.errs = ErrorList{asSyntaxError(err)} // no resynchronization point finds it
+	}
+	return r.ad, r.pos, r.errs
+}
+
+// ParseOldRecovering is ParseRecovering for an old-format ad: it
+// resynchronizes at each line that starts an assignment. The errors are not
+// wrapped as ParseOldClassAd wraps its error.
+func ParseOldRecovering(input string) (*ast.ClassAd, ast.Positions, ErrorList) {
+	ad, pos, err := ParseOldWithPositions(input)
+	if err == nil {
+		return ad, pos, nil
+	}
+	x := newPositionIndex(input)
+	r := &recovery{input: input, x: x, ad: &ast.ClassAd{Attributes: []*ast.AttributeAssignment{}}, pos: ast.Positions{}}
+	starts := oldAssignmentLines(input)
+	for i, first := range starts {
+		start, end := x.lineStarts[first], len(input)
+		if i+1 < len(starts) {
+			end = x.lineStarts[starts[i+1]]
+		}
+		// The part starts a line, so only line numbers and offsets move.
+		shift := func(p ast.Pos) ast.Pos {
+			p.Line += first
+			p.Offset += start
+			return p
+		}
+		part, ppos, perr := ParseOldWithPositions(input[start:end])
+		if perr != nil {
+			// An unterminated string runs to the end of the part; report it
+			// on the part's last line rather than the next one.
+			se := *asSyntaxError(perr)
+			off := start + min(se.Offset, len(strings.TrimRight(input[start:end], "\n")))
+			p := x.pos(off)
+			se.Line, se.Col, se.Offset, se.lineText = p.Line, p.Col, p.Offset, x.lineText(off)
+			se.recite(func(at int) int { return start + at })
+			r.errs = append(r.errs, &se)
+			continue
+		}
+		for n, s := range ppos {
+			if n != part {
+				r.pos[n] = ast.Span{Start: shift(s.Start), End: shift(s.End)}
+			}
+		}
+		r.ad.Attributes = append(r.ad.Attributes, part.Attributes...)
+	}
+	r.pos[r.ad] = ast.Span{Start: x.pos(0), End: x.pos(len(input))}
+	if len(r.errs) == 0 {
+		r.errs = ErrorList{asSyntaxError(err)}
+	}
+	return r.ad, r.pos, r.errs
+}
+
+// recovery is the state of a recovering parse.
+type recovery struct {
+	input string
+	x     positionIndex
+	ad    *ast.ClassAd
+	pos   ast.Positions
+	errs  ErrorList
+}
+
+// attribute parses input[start+1:end], the text between the separators at
+// start and end, as the attributes of a record of their own, and adds them
+// or its error.
+func (r *recovery) attribute(start, end int) {
+	body := r.input[start+1 : end]
+	if skipTrivia(body, 0) == len(body) {
+		return
+	}
+	// Brackets stand in for the separators, so offsets into the text parsed
+	// are offsets from start.
+	node, pos, err := parseWithPositionsIn("["+body+"]", false, r.x, start)
+	if err != nil {
+		r.errs = append(r.errs, r.mapError(err, start, end))
+		return
+	}
+	ad, ok := node.(*ast.ClassAd)
+	if !ok {
+		return
+	}
+	delete(pos, ad)
+	for n, s := range pos {
+		r.pos[n] = s
+	}
+	r.ad.Attributes = append(r.ad.Attributes, ad.Attributes...)
+}
+
+// mapError re-expresses an error parsing the attribute text between start and
+// end in terms of the input.
+func (r *recovery) mapError(err error, start, end int) *SyntaxError {
+	se := asSyntaxError(err)
+	off := min(start+se.Offset, end)
+	if se.Got == "" {
+		out := *se // lexical error
+		p := r.x.pos(off)
+		out.Line, out.Col, out.Offset, out.lineText = p.Line, p.Col, p.Offset, r.x.lineText(off)
+		out.recite(func(at int) int { return start + at })
+		return &out
+	}
+	got := se.Got
+	if off >= end {
+		// Rejected at the closing bracket, which stood in for the separator.
+		off, got = end, r.input[end:min(end+1, len(r.input))]
+	}
+	return r.newError(off, got, se.Expected)
+}
+
+func (r *recovery) newError(off int, got string, expected []string) *SyntaxError {
+	p := r.x.pos(off)
+	return newSyntaxError(p.Line, p.Col, p.Offset, got, expected, r.x.lineText(off))
+}
+
+// lineText returns the line of x's text holding offset, without its newline.
+func (x positionIndex) lineText(offset int) string {
+	line := x.pos(offset).Line
+	start, end := x.lineStarts[line-1], len(x.src)
+	if line < len(x.lineStarts) {
+		end = x.lineStarts[line] - 1
+	}
+	return x.src[start:end]
+}
+
+func asSyntaxError(err error) *SyntaxError {
+	var se *SyntaxError
+	if errors.As(err, &se) {
+		return se
+	}
+	return &SyntaxError{Line: 1, Col: 1, Msg: err.Error()}
+}
+
+// attributeEnd returns the offset of the ';' or ']' at or after i that ends an
+// attribute of the outermost record, or len(s) if none does. Strings, quoted
+// names and comments are skipped. Only an open '[' shields a separator: an
+// unclosed '(' or '{' is taken to be a mistake the separator ends.
+func attributeEnd(s string, i int) int {
+	var open []byte // brackets opened within the attribute
+	for i < len(s) {
+		switch c := s[i]; c {
+		case '"', '\'':
+			i = skipQuoted(s, i)
+			continue
+		case '/':
+			if j := skipComment(s, i); j > i {
+				i = j
+				continue
+			}
+		case '(', '{', '[':
+			open = append(open, c)
+		case ')', '}':
+			want := byte('(')
+			if c == '}' {
+				want = '{'
+			}
+			if n := len(open); n > 0 && open[n-1] == want {
+				open = open[:n-1]
+			}
+		case ']':
+			n := bytes.LastIndexByte(open, '[')
+			if n < 0 {
+				return i
+			}
+			open = open[:n]
+		case ';':
+			if bytes.IndexByte(open, '[') < 0 {
+				return i
+			}
+		}
+		i++
+	}
+	return i
+}
+
+// skipQuoted returns the offset past the string or quoted name opening at i.
+// An unterminated one ends at the end of its line.
+func skipQuoted(s string, i int) int {
+	q := s[i]
+	for i++; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case q:
+			return i + 1
+		case '\n':
+			return i
+		}
+	}
+	return len(s)
+}
+
+// skipComment returns the offset past the comment opening at i, or i if none
+// does.
+func skipComment(s string, i int) int {
+	switch {
+	case strings.HasPrefix(s[i:], "//"):
+		if n := strings.IndexByte(s[i:], '\n'); n >= 0 {
+			return i + n
+		}
+		return len(s)
+	case strings.HasPrefix(s[i:], "/*"):
+		if n := strings.Index(s[i+2:], "*/"); n >= 0 {
+			return i + 2 + n + 2
+		}
+		return len(s)
+	}
+	return i
+}
+
+// skipTrivia returns the offset of the first character at or after i that is
+// neither a blank nor in a comment.
+func skipTrivia(s string, i int) int {
+	for i < len(s) {
+		switch s[i] {
+		case ' ', '\t', '\r', '\n':
+			i++
+		case '/':
+			j := skipComment(s, i)
+			if j == i {
+				return i
+			}
+			i = j
+		default:
+			return i
+		}
+	}
+	return i
+}
+
+// oldAssignmentLines returns the 0-based lines of an old-format input that
+// start an assignment, as convertOld reads them, always with line 0 first.
+func oldAssignmentLines(input string) []int {
+	starts := []int{0}
+	inBlockComment := false
+	for i, line := range strings.Split(input, "\n") {
+		if strings.Contains(line, "/*") {
+			inBlockComment = true
+		}
+		if inBlockComment {
+			if strings.Contains(line, "*/") {
+				inBlockComment = false
+			}
+			continue
+		}
+		trimmed := strings.TrimSpace(line)
+		if i > 0 && strings.Contains(trimmed, "=") && !strings.HasPrefix(trimmed, "//") && !strings.HasPrefix(trimmed, "#") {
+			starts = append(starts, i)
+		}
+	}
+	return starts
+}
@@ -0,0 +1,147 @@
+package parser
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/PelicanPlatform/classad/ast"
+)
+
+func attrNames(ad *ast.ClassAd) []string {
+	names := []string{}
+	for _, a := range ad.Attributes {
+		names = append(names, a.Name)
+	}
+	return names
+}
+
+// errorLines renders each error as "line:col got".
+func errorLines(errs ErrorList) []string {
+	out := []string{}
+	for _, e := range errs {
+		out = append(out, fmt.Sprintf("%d:%d %s", e.Line, e.Col, e.Got))
+	}
+	return out
+}
+
+func TestParseRecovering(t *testing.T) {
+	tests := []struct {
+		src    string
+		names  []string
+		errors []string
+	}{
+		{"[a = 1; b = 2]", []string{"a", "b"}, []string{}},
+		{"[\n  a = 1;\n  b = (2;\n  c = 3 +;\n  d = \"x\";\n  e = * 4\n]", []string{"a", "d"}, []string{"3:9 ;", "4:10 ;", "6:7 *"}},
+		{"[a = 1; b = ; c = 3]", []string{"a", "c"}, []string{"1:13 ;"}},
+		{"[a = 1; b = [x = 1; y = ]; c = 3]", []string{"a", "c"}, []string{"1:25 ]"}},
+		{"[a = \"x;\n b = 2;\n c = 3]", []string{"c"}, []string{"2:7 "}},
+		{"[a = 1; b = 2", []string{"a", "b"}, []string{"1:14 EOF"}},
+		{"[a = 1; b = 2 +", []string{"a"}, []string{"1:16 EOF"}},
+		{"[a = 1; b = 2] c", []string{"a", "b"}, []string{"1:16 c"}},
+		{"[a = 1 b = 2; c = 3]", []string{"c"}, []string{"1:8 b"}},
+		{"a = 1", []string{}, []string{"1:1 a"}},
+	}
+	for _, tt := range tests {
+		ad, pos, errs := ParseRecovering(tt.src)
+		if got := attrNames(ad); !reflect.DeepEqual(got, tt.names) {
+			t.Errorf("ParseRecovering(%q) attributes = %v, want %v", tt.src, got, tt.names)
+		}
+		if got := errorLines(errs); !reflect.DeepEqual(got, tt.errors) {
+			t.Errorf("ParseRecovering(%q) errors = %q, want %q", tt.src, got, tt.errors)
+		}
+		if len(errs) == 0 != (errs.Err() == nil) {
+			t.Errorf("ParseRecovering(%q): Err() = %v", tt.src, errs.Err())
+		}
+		if _, err := Parse(tt.src); (err == nil) != (len(errs) == 0) {
+			t.Errorf("ParseRecovering(%q) = %d errors, Parse = %v", tt.src, len(errs), err)
+		}
+		for _, a := range ad.Attributes {
+			s, ok := pos.Span(a)
+			if !ok || !strings.HasPrefix(tt.src[s.Start.Offset:], a.Name) {
+				t.Errorf("ParseRecovering(%q): span of %s = %+v", tt.src, a.Name, s)
+			}
+		}
+	}
+}
+
+func TestParseRecoveringMatchesParse(t *testing.T) {
+	// The first error is the one Parse reports.
+	for _, src := range []string{"[a = 1; b = (2; c = 3]", "[\n  x = 1 +;\n]", "[a = 1; b = 2"} {
+		_, err := Parse(src)
+		_, _, errs := ParseRecovering(src)
+		se, ok := err.(*SyntaxError)
+		if !ok || len(errs) == 0 || errs[0].Offset != se.Offset || errs[0].Col != se.Col || errs[0].Msg != se.Msg {
+			t.Errorf("%q: first recovered error\n%v\nParse error\n%v", src, errs, err)
+		}
+	}
+}
+
+func TestParseRecoveringPositions(t *testing.T) {
+	src := "[\n  a = 1;\n  b = (;\n  c = a + 1\n]"
+	ad, pos, errs := ParseRecovering(src)
+	if len(errs) != 1 {
+		t.Fatalf("errors = %v", errs)
+	}
+	c := ad.Attributes[1]
+	s, _ := pos.Span(c.Value)
+	if s.Start != (ast.Pos{Line: 4, Col: 7, Offset: strings.Index(src, "a + 1")}) || s.End.Offset != strings.Index(src, "\n]") {
+		t.Errorf("span of c's value = %+v", s)
+	}
+	if s, ok := pos.Span(ad); !ok || s.Start.Offset != 0 || s.End.Offset != len(src) {
+		t.Errorf("span of the ad = %+v", s)
+	}
+	want := "parse error at line 3, col 8: syntax error: unexpected \";\""
+	if got := errs.Error(); !strings.HasPrefix(got, want) || !strings.Contains(got, "\n  b = (;\n       ^") {
+		t.Errorf("Error() = %q", got)
+	}
+
+	// A lexical error cites offsets in the input, not in the attribute's text.
+	_, _, errs = ParseRecovering(`[A=1; B="unterminated; C=3]`)
+	if len(errs) != 1 || errs[0].Msg != "unterminated string starting at byte 8" {
+		t.Errorf("errors = %v", errs)
+	}
+	_, _, errs = ParseRecovering("[a = (; b = (; c = (]")
+	if got := errs.Error(); !strings.HasSuffix(got, "\n(and 2 more errors)") {
+		t.Errorf("Error() = %q", got)
+	}
+}
+
+func TestParseOldRecovering(t *testing.T) {
+	src := `MyType = "Job"
+Owner = "alice
+// comment
+Cpus = 4
+Memory = Cpus *
+Disk = 1024
+Rank = Memory + Disk`
+	ad, pos, errs := ParseOldRecovering(src)
+	if got := attrNames(ad); !reflect.DeepEqual(got, []string{"MyType", "Cpus", "Disk", "Rank"}) {
+		t.Errorf("attributes = %v", got)
+	}
+	if got := errorLines(errs); len(got) != 2 || !strings.HasPrefix(got[0], "3:") || !strings.HasPrefix(got[1], "5:") {
+		t.Errorf("errors = %q", got)
+	}
+	if errs[1].Offset != strings.Index(src, "\nDisk") && errs[1].Offset != strings.Index(src, "Cpus *")+6 {
+		t.Errorf("offset of the second error = %d", errs[1].Offset)
+	}
+	if want := fmt.Sprintf("unterminated string starting at byte %d", strings.Index(src, `"alice`)); errs[0].Msg != want {
+		t.Errorf("first error = %q, want %q", errs[0].Msg, want)
+	}
+	if want := "syntax error: unexpected end of expression"; errs[1].Msg != want {
+		t.Errorf("second error = %q, want %q", errs[1].Msg, want)
+	}
+	if got := errs.Error(); !strings.HasSuffix(got, "\n(and 1 more error)") {
+		t.Errorf("Error() = %q", got)
+	}
+	s, _ := pos.Span(ad.Attributes[3])
+	if s.Start != (ast.Pos{Line: 7, Col: 1, Offset: strings.Index(src, "Rank")}) {
+		t.Errorf("span of Rank = %+v", s)
+	}
+
+	ad, _, errs = ParseOldRecovering("A = 1\nB = 2\n")
+	if errs != nil || len(ad.Attributes) != 2 {
+		t.Errorf("clean input: %v, %v", attrNames(ad), errs)
+	}
+}
@@ -35,6 +35,26 @@ type StreamingLexer struct {
 	// processing at all -- so a value like OSIssue = "\S" (agetty escapes from /etc/issue)
 	// round-trips instead of failing the whole ad. Set only on the old-ClassAd parse path.
 	lenientEscapes bool
+	// quirks enables the reference lexer's number leniencies (see Options).
+	quirks Options
+
+	// line and col track the position of pos (newlines consumed, and runes
+	// since the last one) for error reporting; lastSize is the byte size of the
+	// last rune consumed.
+	line     int
+	col      int
+	lastSize int
+	// tok* describe the token Lex most recently returned (its kind, start
+	// offset, line/col and index in seen), so a syntax error can point at it.
+	tokKind int
+	tokPos  int
+	tokLine int
+	tokCol  int
+	tokSeen int
+	// recordSpans, when set, makes the grammar actions append each node's
+	// source extent to spans (see ParseWithPositions).
+	recordSpans bool
+	spans       []nodeSpan
 }
 
 // NewStreamingLexer creates a lexer that consumes tokens directly from a reader.
@@ -58,6 +78,14 @@ func (l *StreamingLexer) resetForNext() {
 	l.pendingSize = 0
 	l.hasPending = false
 	l.seen = l.seen[:0]
+	l.spans = l.spans[:0]
+}
+
+// resetPos restarts position tracking at offset 0, line 1, for a new input.
+func (l *StreamingLexer) resetPos() {
+	l.pos = 0
+	l.line = 0
+	l.col = 0
 }
 
 // isASCIILetter and isASCIIDigit define the character classes for identifiers
@@ -72,13 +100,34 @@ func isASCIIDigit(r rune) bool {
 	return r >= '0' && r <= '9'
 }
 
-// Lex implements the goyacc Lexer interface.
+// Lex implements the goyacc Lexer interface. Besides the token's value it
+// records the token's byte extent in lval (pos, end), from which the grammar
+// actions derive node spans.
 func (l *StreamingLexer) Lex(lval *yySymType) int {
+	tok := l.lex(lval)
+	l.tokKind = tok
+	lval.end = l.pos
+	return tok
+}
+
+// markToken notes that the next token starts at the current position.
+func (l *StreamingLexer) markToken(lval *yySymType) {
+	lval.pos = l.pos
+	l.tokPos = l.pos
+	l.tokLine = l.line + 1
+	l.tokCol = l.col + 1
+	l.tokSeen = len(l.seen)
+}
+
+func (l *StreamingLexer) lex(lval *yySymType) int {
 	if l.done {
+		l.markToken(lval)
 		return 0
 	}
 
-	if err := l.skipTrivia(); err != nil {
+	err := l.skipTrivia()
+	l.markToken(lval)
+	if err != nil {
 		if err == io.EOF {
 			l.done = true
 			return 0
@@ -92,7 +141,7 @@ func (l *StreamingLexer) Lex(lval *yySymType) int {
 		if err == io.EOF {
 			l.done = true
 			if l.started && l.depth > 0 {
-				l.Error("unexpected EOF while parsing ClassAd")
+				l.lexError("unexpected EOF while parsing ClassAd")
 			}
 			return 0
 		}
@@ -157,9 +206,13 @@ func (l *StreamingLexer) Lex(lval *yySymType) int {
 		// A '.' immediately before a digit begins a fractional float literal
 		// (".5"), which the reference accepts; otherwise it is the selection
 		// operator.
-		if next, err := l.peekRune(); err == nil && isASCIIDigit(next) {
+		next, err := l.peekRune()
+		if err == nil && isASCIIDigit(next) {
 			return l.scanNumber('.', lval)
 		}
+		if err == nil && next == '.' && l.quirks.LenientDoubleDot {
+			return l.scanDoubleDot(lval)
+		}
 		return int('.')
 	case '"':
 		str := l.scanString()
@@ -297,7 +350,7 @@ func (l *StreamingLexer) Lex(lval *yySymType) int {
 					l.err = err
 					return 0
 				}
-				return l.Lex(lval)
+				return l.lex(lval)
 			case '*':
 				if err := l.discardRune(); err != nil {
 					l.err = err
@@ -307,7 +360,7 @@ func (l *StreamingLexer) Lex(lval *yySymType) int {
 					l.err = err
 					return 0
 				}
-				return l.Lex(lval)
+				return l.lex(lval)
 			}
 		}
 		return int('/')
@@ -326,13 +379,90 @@ func (l *StreamingLexer) Lex(lval *yySymType) int {
 	// Unknown character: report the error and stop (return EOF) rather than
 	// silently skipping it and lexing on, which would accept malformed input
 	// like "[#]" that the reference parser rejects.
-	l.Error(fmt.Sprintf("unexpected character: %c", ch))
+	l.lexError(fmt.Sprintf("unexpected character: %c", ch))
 	return 0
 }
 
-// Error implements the goyacc Lexer interface.
-func (l *StreamingLexer) Error(s string) {
-	l.err = errors.New(l.formatError(s))
+// Error implements the goyacc Lexer interface: the parser rejected the token
+// Lex last returned. A lexical error or read failure already recorded is kept
+// -- the parser only saw the lexer stop, and the recorded error says why.
+func (l *StreamingLexer) Error(string) {
+	if l.err != nil {
+		return
+	}
+	l.err = l.syntaxError()
+}
+
+// lexError records a lexical error at the last character scanned.
+func (l *StreamingLexer) lexError(msg string) {
+	l.err = &SyntaxError{
+		Line:     l.line + 1,
+		Col:      l.col,
+		Offset:   max(l.pos-l.lastSize, 0),
+		Msg:      msg,
+		lineText: l.lineText(len(l.seen)),
+	}
+}
+
+// lexErrorAt is lexError for a message citing the byte offset at, the %d of
+// format.
+func (l *StreamingLexer) lexErrorAt(format string, at int) {
+	l.lexError(fmt.Sprintf(format, at))
+	se := l.err.(*SyntaxError)
+	se.msgFormat, se.cited = format, at
+}
+
+// syntaxError builds the error for the rejected token: its position and text,
+// and the tokens the grammar would have accepted instead.
+func (l *StreamingLexer) syntaxError() *SyntaxError {
+	tok := l.seen[l.tokSeen:]
+	got := string(tok)
+	if i := strings.IndexByte(got, '\n'); i >= 0 {
+		got = got[:i]
+	}
+	if l.tokKind == 0 {
+		got = ""
+	}
+	return newSyntaxError(l.tokLine, l.tokCol, l.tokPos, got, l.expected(), l.lineText(l.tokSeen))
+}
+
+// expected recomputes the tokens acceptable where the parse failed by
+// re-lexing the text consumed so far and replaying the automaton over it.
+func (l *StreamingLexer) expected() []string {
+	re := &StreamingLexer{
+		r:              bufio.NewReader(strings.NewReader(string(l.seen[:l.tokSeen]))),
+		lenientEscapes: l.lenientEscapes,
+		quirks:         l.quirks,
+	}
+	var chars []int
+	var lval yySymType
+	for {
+		tok := re.Lex(&lval)
+		if tok == 0 || re.err != nil {
+			break
+		}
+		chars = append(chars, tok)
+	}
+	chars = append(chars, l.tokKind)
+	state := errorState(chars)
+	if state < 0 {
+		return nil
+	}
+	return expectedTokens(state)
+}
+
+// lineText returns the source line holding seen[i], from its start through the
+// last rune consumed (or the line's end, if the line ends earlier).
+func (l *StreamingLexer) lineText(i int) string {
+	start := i
+	for start > 0 && l.seen[start-1] != '\n' {
+		start--
+	}
+	end := i
+	for end < len(l.seen) && l.seen[end] != '\n' {
+		end++
+	}
+	return string(l.seen[start:end])
 }
 
 // Result returns the parsed result and any error.
@@ -369,6 +499,7 @@ func (l *StreamingLexer) unreadRune(size int) {
 	last := l.seen[len(l.seen)-1]
 	l.seen = l.seen[:len(l.seen)-1]
 	l.pos -= size
+	l.col-- // only an operator character is ever pushed back, never a newline
 	l.pendingRune = last
 	l.pendingSize = size
 	l.hasPending = true
@@ -460,6 +591,7 @@ func (l *StreamingLexer) skipTrivia() error {
 		// We recorded this rune in readRune, so roll back the position and seen to
 		// reflect that it is not yet consumed by the parser.
 		l.pos -= size
+		l.col--
 		if len(l.seen) > 0 {
 			l.seen = l.seen[:len(l.seen)-1]
 		}
@@ -487,7 +619,7 @@ func (l *StreamingLexer) skipBlockComment() error {
 		ch, _, err := l.readRune()
 		if err != nil {
 			if err == io.EOF {
-				l.Error("unterminated block comment")
+				l.lexError("unterminated block comment")
 			}
 			return err
 		}
@@ -511,7 +643,7 @@ func (l *StreamingLexer) scanString() string {
 		ch, _, err := l.readRune()
 		if err != nil {
 			if err == io.EOF {
-				l.Error(fmt.Sprintf("unterminated string starting at byte %d", startPos))
+				l.lexErrorAt("unterminated string starting at byte %d", startPos)
 			}
 			return result.String()
 		}
@@ -541,7 +673,7 @@ func (l *StreamingLexer) scanString() string {
 			}
 			escaped, _, err := l.readRune()
 			if err != nil {
-				l.Error(fmt.Sprintf("unterminated escape sequence in string starting at position %d", startPos))
+				l.lexErrorAt("unterminated escape sequence in string starting at position %d", startPos)
 				return result.String()
 			}
 			switch escaped {
@@ -587,16 +719,16 @@ func (l *StreamingLexer) scanString() string {
 
 				val, err := strconv.ParseInt(octalStr.String(), 8, 64)
 				if err != nil {
-					l.Error(fmt.Sprintf("invalid octal escape %s at position %d", octalStr.String(), l.pos))
+					l.lexErrorAt("invalid octal escape "+octalStr.String()+" at position %d", l.pos)
 					return result.String()
 				}
 				if val == 0 {
-					l.Error(fmt.Sprintf("null character (\\%s) not allowed in string at position %d", octalStr.String(), l.pos))
+					l.lexErrorAt("null character (\\"+octalStr.String()+") not allowed in string at position %d", l.pos)
 					return result.String()
 				}
 				result.WriteRune(rune(val))
 			default:
-				l.Error(fmt.Sprintf("invalid escape sequence \\%c at position %d", escaped, l.pos-2))
+				l.lexErrorAt("invalid escape sequence \\"+strings.ReplaceAll(string(escaped), "%", "%%")+" at position %d", l.pos-2)
 				result.WriteRune(escaped)
 			}
 			continue
@@ -619,7 +751,7 @@ func (l *StreamingLexer) scanQuotedIdentifier(lval *yySymType) int {
 	for {
 		ch, _, err := l.readRune()
 		if err != nil {
-			l.Error(fmt.Sprintf("unterminated quoted attribute name starting at byte %d", startPos))
+			l.lexErrorAt("unterminated quoted attribute name starting at byte %d", startPos)
 			return 0
 		}
 		switch ch {
@@ -627,12 +759,12 @@ func (l *StreamingLexer) scanQuotedIdentifier(lval *yySymType) int {
 			lval.str = sb.String()
 			return IDENTIFIER
 		case '\n':
-			l.Error(fmt.Sprintf("newline in quoted attribute name starting at byte %d", startPos))
+			l.lexErrorAt("newline in quoted attribute name starting at byte %d", startPos)
 			return 0
 		case '\\':
 			escaped, _, eerr := l.readRune()
 			if eerr != nil {
-				l.Error(fmt.Sprintf("unterminated quoted attribute name starting at byte %d", startPos))
+				l.lexErrorAt("unterminated quoted attribute name starting at byte %d", startPos)
 				return 0
 			}
 			sb.WriteRune(escaped)
@@ -644,33 +776,14 @@ func (l *StreamingLexer) scanQuotedIdentifier(lval *yySymType) int {
 
 func (l *StreamingLexer) recordRune(ch rune, size int) {
 	l.pos += size
-	l.seen = append(l.seen, ch)
-}
-
-func (l *StreamingLexer) formatError(msg string) string {
-	line, col := 1, 0
-	for _, r := range l.seen {
-		if r == '\n' {
-			line++
-			col = 0
-		} else {
-			col++
-		}
+	l.lastSize = size
+	if ch == '\n' {
+		l.line++
+		l.col = 0
+	} else {
+		l.col++
 	}
-
-	runes := l.seen
-	lastNL := -1
-	for i := len(runes) - 1; i >= 0; i-- {
-		if runes[i] == '\n' {
-			lastNL = i
-			break
-		}
-	}
-	start := lastNL + 1
-	lineText := string(runes[start:])
-	caret := strings.Repeat(" ", max(col-1, 0)) + "^"
-
-	return fmt.Sprintf("parse error at line %d, col %d: %s\n%s\n%s", line, col, msg, lineText, caret)
+	l.seen = append(l.seen, ch)
 }
 
 func (l *StreamingLexer) scanNumber(first rune, lval *yySymType) int {
@@ -705,7 +818,7 @@ func (l *StreamingLexer) scanNumber(first rune, lval *yySymType) int {
 			// "5.", and "1.e5" are rejected (a leading-dot ".5" is fine because
 			// the caller only starts a number on '.' when a digit follows).
 			if next, perr := l.peekRune(); perr != nil || !isASCIIDigit(next) {
-				l.Error(fmt.Sprintf("expected digit after decimal point in %q", sb.String()))
+				l.lexError(fmt.Sprintf("expected digit after decimal point in %q", sb.String()))
 				return 0
 			}
 			continue
@@ -740,7 +853,7 @@ func (l *StreamingLexer) scanNumber(first rune, lval *yySymType) int {
 		// to 0, exactly as the reference's strtod does (e.g. "1e1000" is
 		// real(inf)). Only a genuine syntax error is fatal.
 		if err != nil && !errors.Is(err, strconv.ErrRange) {
-			l.Error(fmt.Sprintf("invalid real number: %s", text))
+			l.lexError(fmt.Sprintf("invalid real number: %s", text))
 			return 0
 		}
 		lval.real = val
@@ -751,7 +864,7 @@ func (l *StreamingLexer) scanNumber(first rune, lval *yySymType) int {
 	// a bare "0" is allowed); it is not read as octal. Match that rather than
 	// silently treating "010" as decimal 10.
 	if len(text) > 1 && text[0] == '0' {
-		l.Error(fmt.Sprintf("leading zero in integer literal: %s", text))
+		l.lexError(fmt.Sprintf("leading zero in integer literal: %s", text))
 		return 0
 	}
 
@@ -762,15 +875,40 @@ func (l *StreamingLexer) scanNumber(first rune, lval *yySymType) int {
 		// bare 2^63 has no grammar rule and stays a syntax error (positive
 		// overflow, which the Go engine rejects rather than wrapping).
 		if u, uerr := strconv.ParseUint(text, 10, 64); uerr == nil && u == 1<<63 {
-			return INT64_MIN_MAGNITUDE
+			if !l.quirks.WrapIntOverflow || l.tokKind == '-' {
+				return INT64_MIN_MAGNITUDE
+			}
+		}
+		if l.quirks.WrapIntOverflow {
+			lval.integer = 0
+			return INTEGER_LITERAL
 		}
-		l.Error(fmt.Sprintf("invalid integer: %s", text))
+		l.lexError(fmt.Sprintf("invalid integer: %s", text))
 		return 0
 	}
 	lval.integer = val
 	return INTEGER_LITERAL
 }
 
+// scanDoubleDot scans a literal that begins with two dots, which
+// Options.LenientDoubleDot reads as the reference's strtod-lenient scanner
+// does: "..5" is the real 0.0. The first '.' has been consumed; a digit must
+// follow the second.
+func (l *StreamingLexer) scanDoubleDot(lval *yySymType) int {
+	if err := l.discardRune(); err != nil {
+		return 0
+	}
+	if next, err := l.peekRune(); err != nil || !isASCIIDigit(next) {
+		l.lexError(`expected digit after ".."`)
+		return 0
+	}
+	if tok := l.scanNumber('.', lval); tok != REAL_LITERAL {
+		return tok
+	}
+	lval.real = 0
+	return REAL_LITERAL
+}
+
 func (l *StreamingLexer) scanIdentifierOrKeyword(first rune, lval *yySymType) int {
 	var sb strings.Builder
 	sb.WriteRune(first)
@@ -0,0 +1,257 @@
+package parser
+
+import (
+	"fmt"
+	"strings"
+)
+
+// SyntaxError describes malformed ClassAd source: a lexical error (an
+// unterminated string, a bad escape, a stray character) or a token the grammar
+// cannot accept. Parse, ParseExpr, ParseOldClassAd and ReaderParser report
+// failures of the source text as a *SyntaxError (ParseOldClassAd wraps it; use
+// errors.As). A failure to read the input is returned as the reader's error.
+//
+// For a grammar error the position is the start of the offending token, Got is
+// its text ("EOF" at end of input, or where the text of an expression ended
+// early -- "unexpected end of expression" -- for ParseExpr and an old-format
+// attribute) and Expected lists the tokens that would
+// have been accepted there, as spelled in source ("]", "==") or by class
+// ("identifier", "integer"). For a lexical error the position is the last
+// character scanned, and Expected and Got are empty.
+type SyntaxError struct {
+	Line     int // 1-based
+	Col      int // 1-based, in runes
+	Offset   int // 0-based byte offset into the input
+	Expected []string
+	Got      string
+	Msg      string // the description, without position
+
+	lineText string // the source line up to the error, for the caret display
+
+	// A lexical error's message may cite a byte offset, such as where an
+	// unterminated string starts. msgFormat renders Msg from cited, so that
+	// an error moved onto other text can cite the offset there.
+	msgFormat string
+	cited     int
+}
+
+// Error renders the error with its position and the offending source line,
+// with a caret under the column:
+//
+//	parse error at line 1, col 9: syntax error: unexpected "]"
+//	[a = 1 +]
+//	        ^
+func (e *SyntaxError) Error() string {
+	caret := strings.Repeat(" ", max(e.Col-1, 0)) + "^"
+	return fmt.Sprintf("parse error at line %d, col %d: %s\n%s\n%s", e.Line, e.Col, e.Msg, e.lineText, caret)
+}
+
+// newSyntaxError builds the error for a token the grammar rejected. got is the
+// token's source text, expected the accepted token names.
+func newSyntaxError(line, col, offset int, got string, expected []string, lineText string) *SyntaxError {
+	if got == "" {
+		got = "EOF"
+	}
+	msg := "syntax error: unexpected " + describeToken(got)
+	// Like bison, only spell out a short list; the full set is in Expected.
+	if n := len(expected); n > 0 && n <= 4 {
+		msg += ", expecting " + strings.Join(expected[:n-1], ", ")
+		if n > 1 {
+			msg += " or "
+		}
+		msg += expected[n-1]
+	}
+	return &SyntaxError{
+		Line:     line,
+		Col:      col,
+		Offset:   offset,
+		Expected: expected,
+		Got:      got,
+		Msg:      msg,
+		lineText: lineText,
+	}
+}
+
+// endOfExpr is newSyntaxError for a token the parser wrapped an expression's
+// text in, which it rejected because that text ended early.
+func endOfExpr(line, col, offset int, expected []string, lineText string) *SyntaxError {
+	se := newSyntaxError(line, col, offset, "EOF", expected, lineText)
+	se.Msg = strings.Replace(se.Msg, describeToken("EOF"), "end of expression", 1)
+	return se
+}
+
+// recite renders the message of e, a copy being moved onto other text, with
+// the offset it cites mapped by at. A message citing no offset is kept.
+func (e *SyntaxError) recite(at func(int) int) {
+	if e.msgFormat == "" {
+		return
+	}
+	e.cited = at(e.cited)
+	e.Msg = fmt.Sprintf(e.msgFormat, e.cited)
+}
+
+func describeToken(text string) string {
+	if text == "EOF" {
+		return "end of input"
+	}
+	return fmt.Sprintf("%q", text)
+}
+
+// tokenDisplayNames spells the grammar's named terminals for Expected. A
+// single-character token is its own character.
+var tokenDisplayNames = map[string]string{
+	"IDENTIFIER":          "identifier",
+	"STRING_LITERAL":      "string",
+	"INTEGER_LITERAL":     "integer",
+	"REAL_LITERAL":        "real",
+	"BOOLEAN_LITERAL":     "boolean",
+	"UNDEFINED":           "undefined",
+	"ERROR":               "error",
+	"INT64_MIN_MAGNITUDE": "integer",
+	"ELVIS":               "?:",
+	"OR":                  "||",
+	"AND":                 "&&",
+	"EQ":                  "==",
+	"NE":                  "!=",
+	"IS":                  "=?=",
+	"ISNT":                "=!=",
+	"LE":                  "<=",
+	"GE":                  ">=",
+	"LSHIFT":              "<<",
+	"RSHIFT":              ">>",
+	"URSHIFT":             ">>>",
+	"$end":                "EOF",
+}
+
+// grammarToken maps a lexer token (the value Lex returns) to the parser's
+// internal token number, as the generated yylex1 does.
+func grammarToken(char int) int {
+	switch {
+	case char <= 0:
+		return int(yyTok1[0])
+	case char < len(yyTok1):
+		return int(yyTok1[char])
+	case char >= yyPrivate && char < yyPrivate+len(yyTok2):
+		return int(yyTok2[char-yyPrivate])
+	}
+	for i := 0; i+1 < len(yyTok3); i += 2 {
+		if int(yyTok3[i]) == char {
+			return int(yyTok3[i+1])
+		}
+	}
+	return int(yyTok2[1]) // unknown char
+}
+
+// errorState replays the parse automaton (the tables of the generated parser,
+// without its actions) over chars, the lexer tokens of a failed parse ending
+// with the rejected one, and returns the state in which that token was
+// rejected -- the state the generated parser reported the error in -- or -1 if
+// the replay accepts or runs out of input.
+//
+// The generated parser does not expose its state to the lexer's Error, so this
+// recomputes it; it runs only on the error path.
+func errorState(chars []int) int {
+	stack := make([]int, 0, 32)
+	state := 0
+	next := 0
+	token := -1
+	for {
+		stack = append(stack, state)
+		for {
+			n := int(yyPact[state])
+			if n > yyFlag {
+				if token < 0 {
+					if next >= len(chars) {
+						return -1
+					}
+					token = grammarToken(chars[next])
+					next++
+				}
+				if n += token; n >= 0 && n < yyLast {
+					if s := int(yyAct[n]); int(yyChk[s]) == token {
+						state = s // shift
+						token = -1
+						break
+					}
+				}
+			}
+			n = int(yyDef[state])
+			if n == -2 {
+				if token < 0 {
+					if next >= len(chars) {
+						return -1
+					}
+					token = grammarToken(chars[next])
+					next++
+				}
+				xi := 0
+				for int(yyExca[xi]) != -1 || int(yyExca[xi+1]) != state {
+					xi += 2
+				}
+				for xi += 2; ; xi += 2 {
+					if t := int(yyExca[xi]); t < 0 || t == token {
+						break
+					}
+				}
+				if n = int(yyExca[xi+1]); n < 0 {
+					return -1 // accept
+				}
+			}
+			if n == 0 {
+				return state
+			}
+			// Reduce by production n and take the goto.
+			stack = stack[:len(stack)-int(yyR2[n])]
+			lhs := int(yyR1[n])
+			g := int(yyPgo[lhs])
+			top := stack[len(stack)-1]
+			if j := g + top + 1; j >= yyLast {
+				state = int(yyAct[g])
+			} else if state = int(yyAct[j]); int(yyChk[state]) != -lhs {
+				state = int(yyAct[g])
+			}
+			stack = append(stack, state)
+		}
+	}
+}
+
+// expectedTokens lists the terminals the parser can shift or reduce on in
+// state, by display name, without duplicates.
+func expectedTokens(state int) []string {
+	const tokStart = 4 // the first real terminal; below are $end, error, $unk
+	var out []string
+	seen := map[string]bool{}
+	add := func(tok int) {
+		name := yyTokname(tok)
+		if disp, ok := tokenDisplayNames[name]; ok {
+			name = disp
+		} else {
+			name = strings.Trim(name, "'")
+		}
+		if !seen[name] {
+			seen[name] = true
+			out = append(out, name)
+		}
+	}
+	base := int(yyPact[state])
+	for tok := 1; tok-1 < len(yyToknames); tok++ {
+		if tok > 1 && tok < tokStart {
+			continue
+		}
+		if n := base + tok; base > yyFlag && n >= 0 && n < yyLast && int(yyChk[int(yyAct[n])]) == tok {
+			add(tok)
+		}
+	}
+	if int(yyDef[state]) == -2 {
+		i := 0
+		for int(yyExca[i]) != -1 || int(yyExca[i+1]) != state {
+			i += 2
+		}
+		for i += 2; yyExca[i] >= 0; i += 2 {
+			if tok := int(yyExca[i]); (tok == 1 || tok >= tokStart) && yyExca[i+1] != 0 {
+				add(tok)
+			}
+		}
+	}
+	return out
+}
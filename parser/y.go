@@ -3,11 +3,13 @@
 //line classad.y:2
 package parser
 
-import (
-	__yyfmt__ "fmt"
+import __yyfmt__ "fmt"
+
+//line classad.y:2
 
+import (
 	"github.com/PelicanPlatform/classad/ast"
-) //line classad.y:2
+)
 
 //line classad.y:10
 type yySymType struct {
@@ -22,6 +24,11 @@ type yySymType struct {
 	integer  int64
 	real     float64
 	boolean  bool
+	/* Byte extent of the symbol in the source, [pos, end): set by the lexer
+	   for a token; a reduction inherits $1's pos and an action extends end
+	   to its last symbol's (via markSpan). */
+	pos int
+	end int
 }
 
 const IDENTIFIER = 57346
@@ -103,7 +110,7 @@ const yyEofCode = 1
 const yyErrCode = 2
 const yyInitialStackSize = 16
 
-//line classad.y:327
+//line classad.y:334
 
 //line yacctab:1
 var yyExca = [...]int8{
@@ -579,7 +586,7 @@ yydefault:
 
 	case 1:
 		yyDollar = yyS[yypt-1 : yypt+1]
-//line classad.y:73
+//line classad.y:78
 		{
 			if lex, ok := yylex.(interface{ SetResult(ast.Node) }); ok {
 				lex.SetResult(yyDollar[1].classad)
@@ -587,19 +594,21 @@ yydefault:
 		}
 	case 2:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line classad.y:82
+//line classad.y:87
 		{
 			yyVAL.classad = &ast.ClassAd{Attributes: yyDollar[2].attrs}
+			yyVAL.end = markSpan(yylex, yyVAL.classad, yyDollar[1].pos, yyDollar[3].end)
 		}
 	case 3:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line classad.y:87
+//line classad.y:92
 		{
 			yyVAL.classad = &ast.ClassAd{Attributes: yyDollar[2].attrs}
+			yyVAL.end = markSpan(yylex, yyVAL.classad, yyDollar[1].pos, yyDollar[3].end)
 		}
 	case 4:
 		yyDollar = yyS[yypt-1 : yypt+1]
-//line classad.y:96
+//line classad.y:101
 		{
 			if yyDollar[1].attr != nil {
 				yyVAL.attrs = []*ast.AttributeAssignment{yyDollar[1].attr}
@@ -609,7 +618,7 @@ yydefault:
 		}
 	case 5:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line classad.y:98
+//line classad.y:103
 		{
 			if yyDollar[3].attr != nil {
 				yyVAL.attrs = append(yyDollar[1].attrs, yyDollar[3].attr)
@@ -619,437 +628,482 @@ yydefault:
 		}
 	case 6:
 		yyDollar = yyS[yypt-0 : yypt+1]
-//line classad.y:103
+//line classad.y:108
 		{
 			yyVAL.attr = nil
 		}
 	case 7:
 		yyDollar = yyS[yypt-1 : yypt+1]
-//line classad.y:105
+//line classad.y:110
 		{
 			yyVAL.attr = yyDollar[1].attr
 		}
 	case 8:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line classad.y:110
+//line classad.y:115
 		{
 			yyVAL.attr = &ast.AttributeAssignment{Name: yyDollar[1].str, Value: yyDollar[3].expr}
+			yyVAL.end = markSpan(yylex, yyVAL.attr, yyDollar[1].pos, yyDollar[3].end)
 		}
 	case 9:
 		yyDollar = yyS[yypt-1 : yypt+1]
-//line classad.y:115
+//line classad.y:120
 		{
 			yyVAL.expr = yyDollar[1].expr
 		}
 	case 10:
 		yyDollar = yyS[yypt-1 : yypt+1]
-//line classad.y:120
+//line classad.y:125
 		{
 			yyVAL.expr = yyDollar[1].expr
 		}
 	case 11:
 		yyDollar = yyS[yypt-5 : yypt+1]
-//line classad.y:122
+//line classad.y:127
 		{
 			yyVAL.expr = &ast.ConditionalExpr{Condition: yyDollar[1].expr, TrueExpr: yyDollar[3].expr, FalseExpr: yyDollar[5].expr}
+			yyVAL.end = markSpan(yylex, yyVAL.expr, yyDollar[1].pos, yyDollar[5].end)
 		}
 	case 12:
 		yyDollar = yyS[yypt-4 : yypt+1]
-//line classad.y:124
+//line classad.y:129
 		{
 			yyVAL.expr = &ast.ElvisExpr{Left: yyDollar[1].expr, Right: yyDollar[4].expr}
+			yyVAL.end = markSpan(yylex, yyVAL.expr, yyDollar[1].pos, yyDollar[4].end)
 		}
 	case 13:
 		yyDollar = yyS[yypt-1 : yypt+1]
-//line classad.y:129
+//line classad.y:134
 		{
 			yyVAL.expr = yyDollar[1].expr
 		}
 	case 14:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line classad.y:131
+//line classad.y:136
 		{
 			yyVAL.expr = &ast.BinaryOp{Op: "||", Left: yyDollar[1].expr, Right: yyDollar[3].expr}
+			yyVAL.end = markSpan(yylex, yyVAL.expr, yyDollar[1].pos, yyDollar[3].end)
 		}
 	case 15:
 		yyDollar = yyS[yypt-1 : yypt+1]
-//line classad.y:136
+//line classad.y:141
 		{
 			yyVAL.expr = yyDollar[1].expr
 		}
 	case 16:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line classad.y:138
+//line classad.y:143
 		{
 			yyVAL.expr = &ast.BinaryOp{Op: "&&", Left: yyDollar[1].expr, Right: yyDollar[3].expr}
+			yyVAL.end = markSpan(yylex, yyVAL.expr, yyDollar[1].pos, yyDollar[3].end)
 		}
 	case 17:
 		yyDollar = yyS[yypt-1 : yypt+1]
-//line classad.y:143
+//line classad.y:148
 		{
 			yyVAL.expr = yyDollar[1].expr
 		}
 	case 18:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line classad.y:145
+//line classad.y:150
 		{
 			yyVAL.expr = &ast.BinaryOp{Op: "|", Left: yyDollar[1].expr, Right: yyDollar[3].expr}
+			yyVAL.end = markSpan(yylex, yyVAL.expr, yyDollar[1].pos, yyDollar[3].end)
 		}
 	case 19:
 		yyDollar = yyS[yypt-1 : yypt+1]
-//line classad.y:150
+//line classad.y:155
 		{
 			yyVAL.expr = yyDollar[1].expr
 		}
 	case 20:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line classad.y:152
+//line classad.y:157
 		{
 			yyVAL.expr = &ast.BinaryOp{Op: "^", Left: yyDollar[1].expr, Right: yyDollar[3].expr}
+			yyVAL.end = markSpan(yylex, yyVAL.expr, yyDollar[1].pos, yyDollar[3].end)
 		}
 	case 21:
 		yyDollar = yyS[yypt-1 : yypt+1]
-//line classad.y:157
+//line classad.y:162
 		{
 			yyVAL.expr = yyDollar[1].expr
 		}
 	case 22:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line classad.y:159
+//line classad.y:164
 		{
 			yyVAL.expr = &ast.BinaryOp{Op: "&", Left: yyDollar[1].expr, Right: yyDollar[3].expr}
+			yyVAL.end = markSpan(yylex, yyVAL.expr, yyDollar[1].pos, yyDollar[3].end)
 		}
 	case 23:
 		yyDollar = yyS[yypt-1 : yypt+1]
-//line classad.y:164
+//line classad.y:169
 		{
 			yyVAL.expr = yyDollar[1].expr
 		}
 	case 24:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line classad.y:166
+//line classad.y:171
 		{
 			yyVAL.expr = &ast.BinaryOp{Op: "==", Left: yyDollar[1].expr, Right: yyDollar[3].expr}
+			yyVAL.end = markSpan(yylex, yyVAL.expr, yyDollar[1].pos, yyDollar[3].end)
 		}
 	case 25:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line classad.y:168
+//line classad.y:173
 		{
 			yyVAL.expr = &ast.BinaryOp{Op: "!=", Left: yyDollar[1].expr, Right: yyDollar[3].expr}
+			yyVAL.end = markSpan(yylex, yyVAL.expr, yyDollar[1].pos, yyDollar[3].end)
 		}
 	case 26:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line classad.y:170
+//line classad.y:175
 		{
 			yyVAL.expr = &ast.BinaryOp{Op: "is", Left: yyDollar[1].expr, Right: yyDollar[3].expr}
+			yyVAL.end = markSpan(yylex, yyVAL.expr, yyDollar[1].pos, yyDollar[3].end)
 		}
 	case 27:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line classad.y:172
+//line classad.y:177
 		{
 			yyVAL.expr = &ast.BinaryOp{Op: "isnt", Left: yyDollar[1].expr, Right: yyDollar[3].expr}
+			yyVAL.end = markSpan(yylex, yyVAL.expr, yyDollar[1].pos, yyDollar[3].end)
 		}
 	case 28:
 		yyDollar = yyS[yypt-1 : yypt+1]
-//line classad.y:177
+//line classad.y:182
 		{
 			yyVAL.expr = yyDollar[1].expr
 		}
 	case 29:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line classad.y:179
+//line classad.y:184
 		{
 			yyVAL.expr = &ast.BinaryOp{Op: "<", Left: yyDollar[1].expr, Right: yyDollar[3].expr}
+			yyVAL.end = markSpan(yylex, yyVAL.expr, yyDollar[1].pos, yyDollar[3].end)
 		}
 	case 30:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line classad.y:181
+//line classad.y:186
 		{
 			yyVAL.expr = &ast.BinaryOp{Op: ">", Left: yyDollar[1].expr, Right: yyDollar[3].expr}
+			yyVAL.end = markSpan(yylex, yyVAL.expr, yyDollar[1].pos, yyDollar[3].end)
 		}
 	case 31:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line classad.y:183
+//line classad.y:188
 		{
 			yyVAL.expr = &ast.BinaryOp{Op: "<=", Left: yyDollar[1].expr, Right: yyDollar[3].expr}
+			yyVAL.end = markSpan(yylex, yyVAL.expr, yyDollar[1].pos, yyDollar[3].end)
 		}
 	case 32:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line classad.y:185
+//line classad.y:190
 		{
 			yyVAL.expr = &ast.BinaryOp{Op: ">=", Left: yyDollar[1].expr, Right: yyDollar[3].expr}
+			yyVAL.end = markSpan(yylex, yyVAL.expr, yyDollar[1].pos, yyDollar[3].end)
 		}
 	case 33:
 		yyDollar = yyS[yypt-1 : yypt+1]
-//line classad.y:190
+//line classad.y:195
 		{
 			yyVAL.expr = yyDollar[1].expr
 		}
 	case 34:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line classad.y:192
+//line classad.y:197
 		{
 			yyVAL.expr = &ast.BinaryOp{Op: "<<", Left: yyDollar[1].expr, Right: yyDollar[3].expr}
+			yyVAL.end = markSpan(yylex, yyVAL.expr, yyDollar[1].pos, yyDollar[3].end)
 		}
 	case 35:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line classad.y:194
+//line classad.y:199
 		{
 			yyVAL.expr = &ast.BinaryOp{Op: ">>", Left: yyDollar[1].expr, Right: yyDollar[3].expr}
+			yyVAL.end = markSpan(yylex, yyVAL.expr, yyDollar[1].pos, yyDollar[3].end)
 		}
 	case 36:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line classad.y:196
+//line classad.y:201
 		{
 			yyVAL.expr = &ast.BinaryOp{Op: ">>>", Left: yyDollar[1].expr, Right: yyDollar[3].expr}
+			yyVAL.end = markSpan(yylex, yyVAL.expr, yyDollar[1].pos, yyDollar[3].end)
 		}
 	case 37:
 		yyDollar = yyS[yypt-1 : yypt+1]
-//line classad.y:201
+//line classad.y:206
 		{
 			yyVAL.expr = yyDollar[1].expr
 		}
 	case 38:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line classad.y:203
+//line classad.y:208
 		{
 			yyVAL.expr = &ast.BinaryOp{Op: "+", Left: yyDollar[1].expr, Right: yyDollar[3].expr}
+			yyVAL.end = markSpan(yylex, yyVAL.expr, yyDollar[1].pos, yyDollar[3].end)
 		}
 	case 39:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line classad.y:205
+//line classad.y:210
 		{
 			yyVAL.expr = &ast.BinaryOp{Op: "-", Left: yyDollar[1].expr, Right: yyDollar[3].expr}
+			yyVAL.end = markSpan(yylex, yyVAL.expr, yyDollar[1].pos, yyDollar[3].end)
 		}
 	case 40:
 		yyDollar = yyS[yypt-1 : yypt+1]
-//line classad.y:210
+//line classad.y:215
 		{
 			yyVAL.expr = yyDollar[1].expr
 		}
 	case 41:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line classad.y:212
+//line classad.y:217
 		{
 			yyVAL.expr = &ast.BinaryOp{Op: "*", Left: yyDollar[1].expr, Right: yyDollar[3].expr}
+			yyVAL.end = markSpan(yylex, yyVAL.expr, yyDollar[1].pos, yyDollar[3].end)
 		}
 	case 42:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line classad.y:214
+//line classad.y:219
 		{
 			yyVAL.expr = &ast.BinaryOp{Op: "/", Left: yyDollar[1].expr, Right: yyDollar[3].expr}
+			yyVAL.end = markSpan(yylex, yyVAL.expr, yyDollar[1].pos, yyDollar[3].end)
 		}
 	case 43:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line classad.y:216
+//line classad.y:221
 		{
 			yyVAL.expr = &ast.BinaryOp{Op: "%", Left: yyDollar[1].expr, Right: yyDollar[3].expr}
+			yyVAL.end = markSpan(yylex, yyVAL.expr, yyDollar[1].pos, yyDollar[3].end)
 		}
 	case 44:
 		yyDollar = yyS[yypt-1 : yypt+1]
-//line classad.y:221
+//line classad.y:226
 		{
 			yyVAL.expr = yyDollar[1].expr
 		}
 	case 45:
 		yyDollar = yyS[yypt-2 : yypt+1]
-//line classad.y:223
+//line classad.y:228
 		{
 			yyVAL.expr = &ast.UnaryOp{Op: "-", Expr: yyDollar[2].expr}
+			yyVAL.end = markSpan(yylex, yyVAL.expr, yyDollar[1].pos, yyDollar[2].end)
 		}
 	case 46:
 		yyDollar = yyS[yypt-2 : yypt+1]
-//line classad.y:225
+//line classad.y:230
 		{
 			yyVAL.expr = &ast.IntegerLiteral{Value: -9223372036854775808}
+			yyVAL.end = markSpan(yylex, yyVAL.expr, yyDollar[1].pos, yyDollar[2].end)
 		}
 	case 47:
 		yyDollar = yyS[yypt-2 : yypt+1]
-//line classad.y:227
+//line classad.y:232
 		{
 			yyVAL.expr = &ast.UnaryOp{Op: "+", Expr: yyDollar[2].expr}
+			yyVAL.end = markSpan(yylex, yyVAL.expr, yyDollar[1].pos, yyDollar[2].end)
 		}
 	case 48:
 		yyDollar = yyS[yypt-2 : yypt+1]
-//line classad.y:229
+//line classad.y:234
 		{
 			yyVAL.expr = &ast.UnaryOp{Op: "!", Expr: yyDollar[2].expr}
+			yyVAL.end = markSpan(yylex, yyVAL.expr, yyDollar[1].pos, yyDollar[2].end)
 		}
 	case 49:
 		yyDollar = yyS[yypt-2 : yypt+1]
-//line classad.y:231
+//line classad.y:236
 		{
 			yyVAL.expr = &ast.UnaryOp{Op: "~", Expr: yyDollar[2].expr}
+			yyVAL.end = markSpan(yylex, yyVAL.expr, yyDollar[1].pos, yyDollar[2].end)
 		}
 	case 50:
 		yyDollar = yyS[yypt-1 : yypt+1]
-//line classad.y:236
+//line classad.y:241
 		{
 			yyVAL.expr = yyDollar[1].expr
 		}
 	case 51:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line classad.y:238
+//line classad.y:243
 		{
 			yyVAL.expr = &ast.SelectExpr{Record: yyDollar[1].expr, Attr: yyDollar[3].str}
+			yyVAL.end = markSpan(yylex, yyVAL.expr, yyDollar[1].pos, yyDollar[3].end)
 		}
 	case 52:
 		yyDollar = yyS[yypt-4 : yypt+1]
-//line classad.y:240
+//line classad.y:245
 		{
 			yyVAL.expr = &ast.SubscriptExpr{Container: yyDollar[1].expr, Index: yyDollar[3].expr}
+			yyVAL.end = markSpan(yylex, yyVAL.expr, yyDollar[1].pos, yyDollar[4].end)
 		}
 	case 53:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line classad.y:242
+//line classad.y:247
 		{
 			yyVAL.expr = &ast.ElvisExpr{Left: yyDollar[1].expr, Right: yyDollar[3].expr}
+			yyVAL.end = markSpan(yylex, yyVAL.expr, yyDollar[1].pos, yyDollar[3].end)
 		}
 	case 54:
 		yyDollar = yyS[yypt-4 : yypt+1]
-//line classad.y:244
+//line classad.y:249
 		{
 			yyVAL.expr = &ast.FunctionCall{Name: yyDollar[1].str, Args: yyDollar[3].exprlist}
+			yyVAL.end = markSpan(yylex, yyVAL.expr, yyDollar[1].pos, yyDollar[4].end)
 		}
 	case 55:
 		yyDollar = yyS[yypt-1 : yypt+1]
-//line classad.y:249
+//line classad.y:254
 		{
 			yyVAL.expr = yyDollar[1].expr
 		}
 	case 56:
 		yyDollar = yyS[yypt-1 : yypt+1]
-//line classad.y:251
+//line classad.y:256
 		{
 			name, scope := ParseScopedIdentifier(yyDollar[1].str)
 			yyVAL.expr = ast.NewAttributeReference(name, scope)
+			markSpan(yylex, yyVAL.expr, yyDollar[1].pos, yyDollar[1].end)
 		}
 	case 57:
 		yyDollar = yyS[yypt-2 : yypt+1]
-//line classad.y:256
+//line classad.y:262
 		{
 			// A leading-dot reference (".A") is a plain reference in the
 			// reference engine (".A" resolves identically to "A").
 			name, scope := ParseScopedIdentifier(yyDollar[2].str)
 			yyVAL.expr = ast.NewAttributeReference(name, scope)
+			yyVAL.end = markSpan(yylex, yyVAL.expr, yyDollar[1].pos, yyDollar[2].end)
 		}
 	case 58:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line classad.y:263
+//line classad.y:270
 		{
 			yyVAL.expr = ast.Parenthesize(yyDollar[2].expr)
+			yyVAL.end = markSpan(yylex, yyVAL.expr, yyDollar[1].pos, yyDollar[3].end)
 		}
 	case 59:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line classad.y:265
+//line classad.y:272
 		{
 			yyVAL.expr = &ast.ListLiteral{Elements: yyDollar[2].exprlist}
+			yyVAL.end = markSpan(yylex, yyVAL.expr, yyDollar[1].pos, yyDollar[3].end)
 		}
 	case 60:
 		yyDollar = yyS[yypt-1 : yypt+1]
-//line classad.y:267
+//line classad.y:274
 		{
 			yyVAL.expr = &ast.RecordLiteral{ClassAd: yyDollar[1].classad}
+			markSpan(yylex, yyVAL.expr, yyDollar[1].pos, yyDollar[1].end)
 		}
 	case 61:
 		yyDollar = yyS[yypt-1 : yypt+1]
-//line classad.y:272
+//line classad.y:279
 		{
 			yyVAL.expr = &ast.IntegerLiteral{Value: yyDollar[1].integer}
+			markSpan(yylex, yyVAL.expr, yyDollar[1].pos, yyDollar[1].end)
 		}
 	case 62:
 		yyDollar = yyS[yypt-1 : yypt+1]
-//line classad.y:274
+//line classad.y:281
 		{
 			yyVAL.expr = &ast.RealLiteral{Value: yyDollar[1].real}
+			markSpan(yylex, yyVAL.expr, yyDollar[1].pos, yyDollar[1].end)
 		}
 	case 63:
 		yyDollar = yyS[yypt-1 : yypt+1]
-//line classad.y:276
+//line classad.y:283
 		{
 			yyVAL.expr = &ast.StringLiteral{Value: yyDollar[1].str}
+			markSpan(yylex, yyVAL.expr, yyDollar[1].pos, yyDollar[1].end)
 		}
 	case 64:
 		yyDollar = yyS[yypt-1 : yypt+1]
-//line classad.y:278
+//line classad.y:285
 		{
 			yyVAL.expr = &ast.BooleanLiteral{Value: yyDollar[1].boolean}
+			markSpan(yylex, yyVAL.expr, yyDollar[1].pos, yyDollar[1].end)
 		}
 	case 65:
 		yyDollar = yyS[yypt-1 : yypt+1]
-//line classad.y:280
+//line classad.y:287
 		{
 			yyVAL.expr = &ast.UndefinedLiteral{}
+			markSpan(yylex, yyVAL.expr, yyDollar[1].pos, yyDollar[1].end)
 		}
 	case 66:
 		yyDollar = yyS[yypt-1 : yypt+1]
-//line classad.y:282
+//line classad.y:289
 		{
 			yyVAL.expr = &ast.ErrorLiteral{}
+			markSpan(yylex, yyVAL.expr, yyDollar[1].pos, yyDollar[1].end)
 		}
 	case 67:
 		yyDollar = yyS[yypt-1 : yypt+1]
-//line classad.y:288
+//line classad.y:295
 		{
 			yyVAL.str = yyDollar[1].str
 		}
 	case 68:
 		yyDollar = yyS[yypt-2 : yypt+1]
-//line classad.y:290
+//line classad.y:297
 		{
 			yyVAL.str = yyDollar[1].str + yyDollar[2].str
+			yyVAL.end = yyDollar[2].end
 		}
 	case 69:
 		yyDollar = yyS[yypt-0 : yypt+1]
-//line classad.y:295
+//line classad.y:302
 		{
 			yyVAL.exprlist = []ast.Expr{}
 		}
 	case 70:
 		yyDollar = yyS[yypt-1 : yypt+1]
-//line classad.y:297
+//line classad.y:304
 		{
 			yyVAL.exprlist = yyDollar[1].exprlist
 		}
 	case 71:
 		yyDollar = yyS[yypt-1 : yypt+1]
-//line classad.y:302
+//line classad.y:309
 		{
 			yyVAL.exprlist = []ast.Expr{yyDollar[1].expr}
 		}
 	case 72:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line classad.y:304
+//line classad.y:311
 		{
 			yyVAL.exprlist = append(yyDollar[1].exprlist, yyDollar[3].expr)
 		}
 	case 73:
 		yyDollar = yyS[yypt-0 : yypt+1]
-//line classad.y:313
+//line classad.y:320
 		{
 			yyVAL.exprlist = []ast.Expr{}
 		}
 	case 74:
 		yyDollar = yyS[yypt-1 : yypt+1]
-//line classad.y:315
+//line classad.y:322
 		{
 			yyVAL.exprlist = yyDollar[1].exprlist
 		}
 	case 75:
 		yyDollar = yyS[yypt-1 : yypt+1]
-//line classad.y:320
+//line classad.y:327
 		{
 			yyVAL.exprlist = []ast.Expr{yyDollar[1].expr}
 		}
 	case 76:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line classad.y:322
+//line classad.y:329
 		{
 			yyVAL.exprlist = append(yyDollar[1].exprlist, yyDollar[3].expr)
 		}
 	case 77:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line classad.y:324
+//line classad.y:331
 		{
 			yyVAL.exprlist = append(yyDollar[1].exprlist, yyDollar[3].expr)
 		}